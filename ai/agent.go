@@ -0,0 +1,497 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+// maxAgentTurns is the number of requests an agent run may make before it
+// is stopped.
+const maxAgentTurns = 25
+
+// maxToolOutput is the size in bytes above which tool output is truncated.
+const maxToolOutput = 64 * 1024
+
+// maxGrepMatches is the number of matching lines grep returns at most.
+const maxGrepMatches = 200
+
+// agentTool describes a tool the model can call in agent mode, with the
+// JSON schema of its input.
+type agentTool struct {
+	name        string
+	description string
+	parameters  map[string]interface{}
+}
+
+func pathParameter(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+var agentTools = []agentTool{
+	{
+		name:        "list_dir",
+		description: "List the files and directories in a directory of the repository. Directories end with a slash.",
+		parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"path": pathParameter(`Directory relative to the repository root, "." for the root.`)},
+			"required":   []string{"path"},
+		},
+	},
+	{
+		name:        "read_file",
+		description: "Read a file of the repository.",
+		parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"path": pathParameter("File relative to the repository root.")},
+			"required":   []string{"path"},
+		},
+	},
+	{
+		name:        "grep",
+		description: "Search the files under a directory of the repository for a regular expression (RE2 syntax) and return the matching lines as path:line: text.",
+		parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"pattern": map[string]interface{}{"type": "string", "description": "Regular expression to search for."},
+				"path":    pathParameter(`Directory relative to the repository root to search, "." by default.`),
+			},
+			"required": []string{"pattern"},
+		},
+	},
+}
+
+// toolDefinitions returns the agent's tools in the provider's format.
+func toolDefinitions(clientType string) []map[string]interface{} {
+	var tools []map[string]interface{}
+	for _, tool := range agentTools {
+		if clientType == "chatgpt" {
+			tools = append(tools, map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        tool.name,
+					"description": tool.description,
+					"parameters":  tool.parameters,
+				},
+			})
+			continue
+		}
+		tools = append(tools, map[string]interface{}{
+			"name":         tool.name,
+			"description":  tool.description,
+			"input_schema": tool.parameters,
+		})
+	}
+	return tools
+}
+
+// RunAgent analyzes the IaC code like RunAI, but instead of sending the whole
+// repository up front, lets the model request only the files it needs with
+// the list_dir, read_file, and grep tools. kado-ai runs the tools locally
+// within the IaC path, skipping paths in .kado/ignore, and sanitizes every
+// tool output before it is sent. Everything sent is saved to the AI input
+// file, and consent is asked for again whenever the files read hold secrets.
+func (c *AIClient) RunAgent() (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+	if c.anonymizePaths {
+		return "", fmt.Errorf("agent mode does not support anonymized paths")
+	}
+
+	data, redactions, err := c.basePromptData(c.question)
+	if err != nil {
+		return "", err
+	}
+	prompt, err := c.renderPrompt("agent", data)
+	if err != nil {
+		return "", err
+	}
+
+	var transcript strings.Builder
+	transcript.WriteString(prompt)
+	if err := c.saveAIInput(transcript.String(), redactions); err != nil {
+		return "", fmt.Errorf("failed to save AI input: %v", err)
+	}
+	if c.deleteInput {
+		defer c.removeAIInput()
+	}
+	fmt.Fprintf(c.messages(), "AI input has been saved to %s\n", c.AIInputPath())
+	fmt.Fprintf(c.messages(), "In agent mode, the AI reads the files it needs from %s as it goes. Everything it reads is sanitized first and appended to the AI input.\n", c.iacPath)
+	sanitize.Summarize(c.messages(), redactions)
+	if err := c.blockSecrets(redactions); err != nil {
+		return "", err
+	}
+	if !c.consent(redactions) {
+		return "", fmt.Errorf("operation cancelled by user")
+	}
+
+	messages := []Message{{Role: RoleUser, Content: prompt, Cache: true}}
+	for turn := 0; turn < maxAgentTurns; turn++ {
+		reply, err := c.agentStep(messages)
+		if err != nil {
+			return "", fmt.Errorf("failed to get recommendations: %v", err)
+		}
+		if len(reply.ToolCalls) == 0 {
+			return c.finishAgent(prompt, transcript.String(), reply.Content)
+		}
+
+		var results []ToolResult
+		var round []sanitize.Redaction
+		for _, call := range reply.ToolCalls {
+			output, found := c.runTool(call)
+			round = append(round, found...)
+			fmt.Fprintf(&transcript, "\n\n----- %s %s -----\n\n%s", call.Name, call.Input, output)
+			results = append(results, ToolResult{CallID: call.ID, Content: output})
+		}
+		redactions = append(redactions, round...)
+		if err := c.saveAIInput(transcript.String(), redactions); err != nil {
+			return "", fmt.Errorf("failed to save AI input: %v", err)
+		}
+		if err := c.blockSecrets(redactions); err != nil {
+			return "", err
+		}
+		// Consent was given before any file was read, so it is asked for
+		// again when the files read hold secrets.
+		if sanitize.SecretsFound(round) {
+			sanitize.Summarize(c.messages(), round)
+			if !c.consent(round) {
+				return "", fmt.Errorf("operation cancelled by user")
+			}
+		}
+		messages = append(messages, reply, Message{Role: RoleUser, ToolResults: results})
+	}
+	return "", fmt.Errorf("the agent did not finish its analysis within %d requests", maxAgentTurns)
+}
+
+// finishAgent delivers the final response of an agent run like run does.
+func (c *AIClient) finishAgent(prompt string, input string, recommendations string) (string, error) {
+	if c.noise != nil {
+		recommendations = c.noise.filterReport(recommendations)
+	}
+	updateSinks(c.messages(), c.outputSinks(), recommendations, true)
+	c.recordConversation([]Message{{Role: RoleUser, Content: prompt}, {Role: RoleAssistant, Content: recommendations}})
+	if c.manifestSigner != nil {
+		if err := c.writeRunManifest("agent", input, recommendations); err != nil {
+			return "", err
+		}
+	}
+	return c.deanonymize(recommendations), nil
+}
+
+// agentStep sends the conversation of an agent run with the tool
+// definitions and returns the model's next turn.
+func (c *AIClient) agentStep(messages []Message) (Message, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	url, body, err := c.requestBody(messages, false)
+	if err != nil {
+		return Message{}, err
+	}
+	body["tools"] = toolDefinitions(c.clientType)
+	resp, err := c.sendProviderRequest(url, body)
+	if err != nil {
+		return Message{}, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Message{}, err
+	}
+	return parseAgentReply(c.clientType, data)
+}
+
+// parseAgentReply extracts the text and tool calls of a provider response.
+func parseAgentReply(clientType string, body []byte) (Message, error) {
+	reply := Message{Role: RoleAssistant}
+	if clientType == "chatgpt" {
+		var response struct {
+			Choices []struct {
+				Message struct {
+					Content   string `json:"content"`
+					ToolCalls []struct {
+						ID       string `json:"id"`
+						Function struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"message"`
+			} `json:"choices"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return reply, fmt.Errorf("failed to parse response: %v", err)
+		}
+		if response.Error != nil {
+			return reply, fmt.Errorf("provider returned an error: %s", response.Error.Message)
+		}
+		if len(response.Choices) == 0 {
+			return reply, fmt.Errorf("no content found in the response")
+		}
+		message := response.Choices[0].Message
+		reply.Content = message.Content
+		for _, call := range message.ToolCalls {
+			input := json.RawMessage(call.Function.Arguments)
+			if !json.Valid(input) {
+				input = json.RawMessage("{}")
+			}
+			reply.ToolCalls = append(reply.ToolCalls, ToolCall{ID: call.ID, Name: call.Function.Name, Input: input})
+		}
+		return reply, nil
+	}
+
+	var response struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return reply, fmt.Errorf("failed to parse response: %v", err)
+	}
+	if response.Error != nil {
+		return reply, fmt.Errorf("provider returned an error: %s", response.Error.Message)
+	}
+	var text []string
+	for _, block := range response.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			reply.ToolCalls = append(reply.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Input: block.Input})
+		}
+	}
+	reply.Content = strings.Join(text, "\n")
+	return reply, nil
+}
+
+// runTool runs a tool call within the IaC path and returns its sanitized
+// output, or the error for the model to see, and the redactions made in it.
+func (c *AIClient) runTool(call ToolCall) (string, []sanitize.Redaction) {
+	var args struct {
+		Path    string `json:"path"`
+		Pattern string `json:"pattern"`
+	}
+	var output string
+	err := json.Unmarshal(call.Input, &args)
+	if err == nil {
+		switch call.Name {
+		case "list_dir":
+			output, err = c.listDir(args.Path)
+		case "read_file":
+			output, err = c.readRepoFile(args.Path)
+		case "grep":
+			output, err = c.grepRepo(args.Pattern, args.Path)
+		default:
+			err = fmt.Errorf("unknown tool %q", call.Name)
+		}
+	}
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	output, found := c.redact(output)
+	for i := range found {
+		found[i].Section = call.Name + " " + args.Path
+	}
+	if len(output) > maxToolOutput {
+		output = output[:maxToolOutput] + "\n(output truncated)"
+	}
+	return output, found
+}
+
+// repoPath resolves a path requested by the model, refusing paths outside
+// the IaC path, in .kado/ignore, or in the .kado and .git directories.
+// Unless symbolic links are followed everywhere, the path is also refused if
+// a link takes it outside the IaC path or into a hidden path, such as a
+// committed link to ~/.aws/credentials.
+func (c *AIClient) repoPath(rel string, dir bool) (string, error) {
+	if rel == "" {
+		rel = "."
+	}
+	root, err := filepath.Abs(c.iacPath)
+	if err != nil {
+		return "", err
+	}
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if filepath.IsAbs(rel) {
+		p = filepath.Clean(rel)
+	}
+	r, err := filepath.Rel(root, p)
+	if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the repository", rel)
+	}
+	if c.hiddenFromAgent(root, p, dir) {
+		return "", fmt.Errorf("%s is not available", rel)
+	}
+	if c.symlinks == SymlinksFollow {
+		return p, nil
+	}
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := resolveSymlinks(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %v", rel, err)
+	}
+	r, err = filepath.Rel(resolvedRoot, resolved)
+	if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the repository", rel)
+	}
+	if c.hiddenFromAgent(resolvedRoot, resolved, dir) {
+		return "", fmt.Errorf("%s is not available", rel)
+	}
+	return p, nil
+}
+
+// resolveSymlinks returns p with its symbolic links resolved. The part of p
+// that does not exist yet, such as a file about to be created, is appended
+// to its nearest existing directory, resolved.
+func resolveSymlinks(p string) (string, error) {
+	missing := ""
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(resolved, missing), nil
+		}
+		parent := filepath.Dir(p)
+		if !os.IsNotExist(err) || parent == p {
+			return "", err
+		}
+		missing = filepath.Join(filepath.Base(p), missing)
+		p = parent
+	}
+}
+
+func (c *AIClient) hiddenFromAgent(root string, p string, dir bool) bool {
+	rel, _ := filepath.Rel(root, p)
+	first := strings.Split(filepath.ToSlash(rel), "/")[0]
+	return first == projectDir || first == ".git" || (!dir && isStateFile(filepath.Base(p))) || c.ignoreRules().ignored(root, p, dir)
+}
+
+func (c *AIClient) listDir(rel string) (string, error) {
+	dir, err := c.repoPath(rel, true)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to list %s: %v", rel, err)
+	}
+	root, _ := filepath.Abs(c.iacPath)
+	var out strings.Builder
+	for _, entry := range entries {
+		if c.hiddenFromAgent(root, filepath.Join(dir, entry.Name()), entry.IsDir()) {
+			continue
+		}
+		out.WriteString(entry.Name())
+		if entry.IsDir() {
+			out.WriteString("/")
+		}
+		out.WriteString("\n")
+	}
+	if out.Len() == 0 {
+		return "(empty directory)", nil
+	}
+	return out.String(), nil
+}
+
+func (c *AIClient) readRepoFile(rel string) (string, error) {
+	path, err := c.repoPath(rel, false)
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", rel, err)
+	}
+	if bytes.IndexByte(content, 0) >= 0 {
+		return "", fmt.Errorf("%s is a binary file", rel)
+	}
+	if isVaultEncrypted(string(content)) {
+		if c.vaultFiles == VaultFilesExclude {
+			return "", fmt.Errorf("%s is encrypted with Ansible Vault", rel)
+		}
+		return vaultNotice, nil
+	}
+	return string(content), nil
+}
+
+func (c *AIClient) grepRepo(pattern string, rel string) (string, error) {
+	if pattern == "" {
+		return "", fmt.Errorf("pattern must not be empty")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %v", err)
+	}
+	dir, err := c.repoPath(rel, true)
+	if err != nil {
+		return "", err
+	}
+	root, _ := filepath.Abs(c.iacPath)
+
+	var out strings.Builder
+	matches := 0
+	err = c.walk(nil, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || matches >= maxGrepMatches {
+			return nil
+		}
+		if path != dir && c.hiddenFromAgent(root, path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || info.Size() > maxToolOutput*16 {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer file.Close()
+		name, _ := filepath.Rel(root, path)
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan() && matches < maxGrepMatches; line++ {
+			text := scanner.Text()
+			if strings.IndexByte(text, 0) >= 0 || (line == 1 && isVaultEncrypted(text)) {
+				return nil
+			}
+			if re.MatchString(text) {
+				fmt.Fprintf(&out, "%s:%d: %s\n", filepath.ToSlash(name), line, text)
+				matches++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if matches == 0 {
+		return "(no matches)", nil
+	}
+	if matches >= maxGrepMatches {
+		fmt.Fprintf(&out, "(stopped after %d matches)\n", maxGrepMatches)
+	}
+	return out.String(), nil
+}
@@ -13,267 +13,1033 @@
 package ai
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"os/user"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/janpreet/kado-ai/config"
+	"github.com/janpreet/kado-ai/sanitize"
 )
 
 type AIClient struct {
-	apiKey     string
-	model      string
-	clientType string
-	iacPath    string
+	// mu guards the settings that can change when the config file is
+	// reloaded: apiKey, keys, model, clientType, baseURL, prompt,
+	// promptContext, analysisType, complianceFramework, language, tone, and
+	// the sanitization level and profiles, with the sanitizer.
+	mu sync.RWMutex
+
+	apiKey      string
+	keys        *keyRing
+	keyRotation string
+	model       string
+	clientType  string
+	baseURL     string
+	iacPath     string
+	prompt      string
+	httpClient  *http.Client
+
+	// iacPaths are further IaC paths scanned instead of iacPath itself, and
+	// scanMu serializes their scans, which switch iacPath to each in turn.
+	iacPaths       []string
+	perPathReports bool
+	diffRange      string
+	diffContext    DiffContext
+	scanMu         sync.Mutex
+	// cloneDir is the clone of the remote repository being analyzed, which
+	// Close removes.
+	cloneDir string
+
+	promptContext string
+	analysisType  AnalysisType
+
+	complianceFramework string
+	question            string
+
+	language string
+	tone     Tone
+
+	terraformDirs     []string
+	ansibleDirs       []string
+	planPaths         []string
+	ansibleCheckPaths []string
+	discover          bool
+	pruneAnsible      bool
+	projects          bool
+
+	symlinks         Symlinks
+	binaryExtensions []string
+	archives         bool
+	// files caches the content of the files read by scans while watching.
+	files *fileCache
+
+	terraformBinary    string
+	terraformWorkspace string
+	terraformPlan      bool
+	terraformVarFiles  []string
+	policyEvaluation   bool
+	scannerResults     []string
+	infracost          bool
+	lint               bool
+	driftReport        string
+	versionAudit       bool
+	kubernetesCluster  bool
+	kubernetesContext  string
+	awsAccountHints    bool
+	allowWrites        bool
+	cdkSynth           bool
+	kubernetesRender   bool
+	pulumiPreview      bool
+	azureResourceGroup string
+	pipelines          bool
+	includeGlobs       []string
+	ignoreGitignore    bool
+	excludeGlobs       []string
+	vaultFiles         VaultFiles
+	stateInventory     bool
+
+	consentPolicy  ConsentPolicy
+	consentInput   io.Reader
+	blockOnSecrets bool
+
+	// messageOutput is where progress and warnings are printed, standard
+	// output by default. servingStdout counts the Serve calls writing to
+	// standard output, during which they go to standard error instead.
+	messageOutput io.Writer
+	servingStdout int32
+
+	explainRedactions bool
+
+	inputPath       string
+	inputRecipients []string
+	deleteInput     bool
+
+	streaming bool
+	sinks     []Sink
+
+	embedder Embedder
+
+	manifestSigner ManifestSigner
+
+	extraPatterns    []string
+	sanitizeLevel    sanitize.Level
+	sanitizeProfiles map[string]sanitize.Level
+	scrubPII         bool
+	maskCloudIDs     bool
+	sanitizer        *sanitize.Sanitizer
+	placeholders     *sanitize.Placeholders
+	hostnames        *sanitize.Hostnames
+	cloudIDs         *sanitize.CloudIDs
+
+	contextWindow  int
+	maxTokens      int
+	maxFileTokens  int
+	maxScanTokens  int
+	oversizedFiles OversizedFiles
+	planFormat     PlanFormat
+
+	conversation []Message
+
+	anonymizePaths    bool
+	compress          bool
+	structured        bool
+	refine            bool
+	reversible        bool
+	placeholderFormat sanitize.PlaceholderFormat
+	anonymizer        *pathAnonymizer
+
+	noisePhrases []string
+	noise        *noiseFilter
+
+	standards string
+	examples  []Example
 }
 
+const defaultPrompt = "Please provide comprehensive infrastructure recommendations based on the following:"
+
+// NewAIClient creates an AIClient for the IaC code at iacPath from the user
+// config at configPath and the project config. iacPath can also be the URL
+// of a remote Git repository, which is cloned first; call Close to remove
+// the clone.
 func NewAIClient(iacPath string, configPath string) (*AIClient, error) {
-	config, err := loadConfig(configPath)
+	cloneDir, iacPath, err := cloneIaCPath(iacPath)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newAIClient(iacPath, configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %v", err)
+		if cloneDir != "" {
+			os.RemoveAll(cloneDir)
+		}
+		return nil, err
 	}
+	c.cloneDir = cloneDir
+	return c, nil
+}
 
-	apiKey, apiKeyExists := config["AI_API_KEY"]
-	model, modelExists := config["AI_MODEL"]
-	clientType, clientTypeExists := config["AI_CLIENT"]
+func newAIClient(iacPath string, configPath string) (*AIClient, error) {
+	values, err := loadConfig(iacPath, configPath)
+	if err != nil {
+		return nil, err
+	}
 
-	if !apiKeyExists || !modelExists || !clientTypeExists {
-		return nil, fmt.Errorf("AI_API_KEY, AI_MODEL, or AI_CLIENT is not set in config")
+	opts, err := optionsFromConfig(values)
+	if err != nil {
+		return nil, err
 	}
+	return NewAIClientWithOptions(append(opts, WithIaCPath(iacPath))...)
+}
 
-	return &AIClient{
-		apiKey:     apiKey,
-		model:      model,
-		clientType: clientType,
-		iacPath:    iacPath,
-	}, nil
+// providerBlocks maps each provider to the config section holding its own
+// api_key and model, which take precedence over AI_API_KEY and AI_MODEL.
+var providerBlocks = map[string]string{
+	"chatgpt":            "openai",
+	"anthropic_messages": "anthropic",
 }
 
-func loadConfig(configPath string) (map[string]string, error) {
-	if configPath == "" {
-		usr, err := user.Current()
-		if err != nil {
-			return nil, err
+func optionsFromConfig(values map[string]string) ([]Option, error) {
+	if problems := configProblems(values); len(problems) > 0 {
+		return nil, &ConfigError{Problems: problems}
+	}
+
+	apiKey, apiKeyExists := values["AI_API_KEY"]
+	model, modelExists := values["AI_MODEL"]
+	clientType, clientTypeExists := values["AI_CLIENT"]
+	baseURL, baseURLExists := values["AI_BASE_URL"]
+	if block, ok := providerBlocks[clientType]; ok {
+		if value, ok := values[block+".api_key"]; ok {
+			apiKey, apiKeyExists = value, true
+		}
+		if value, ok := values[block+".model"]; ok {
+			model, modelExists = value, true
+		}
+		if value, ok := values[block+".base_url"]; ok {
+			baseURL, baseURLExists = value, true
 		}
-		configPath = filepath.Join(usr.HomeDir, ".kdconfig")
 	}
 
-	file, err := os.Open(configPath)
-	if err != nil {
-		return nil, err
+	if !apiKeyExists || !modelExists || !clientTypeExists {
+		return nil, fmt.Errorf("AI_API_KEY, AI_MODEL, or AI_CLIENT is not set in config%s", misspelledKeys(values))
 	}
-	defer file.Close()
 
-	config := make(map[string]string)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
+	opts := []Option{
+		WithAPIKey(apiKey),
+		WithModel(model),
+		WithProvider(clientType),
+	}
+	if baseURLExists {
+		opts = append(opts, WithBaseURL(baseURL))
+	}
+	if strategy, ok := values["AI_KEY_ROTATION"]; ok {
+		opts = append(opts, WithKeyRotation(strategy))
+	}
+	if prompt, ok := values["AI_PROMPT"]; ok {
+		opts = append(opts, WithPrompt(prompt))
+	}
+	if analysisType, ok := values["ANALYSIS_TYPE"]; ok {
+		opts = append(opts, WithAnalysisType(AnalysisType(analysisType)))
+	}
+	if framework, ok := values["AI_COMPLIANCE_FRAMEWORK"]; ok {
+		opts = append(opts, WithComplianceFramework(framework))
+	}
+	if language, ok := values["AI_LANGUAGE"]; ok {
+		opts = append(opts, WithLanguage(language))
+	}
+	if tone, ok := values["AI_TONE"]; ok {
+		opts = append(opts, WithTone(Tone(tone)))
+	}
+	if promptContext, ok := values["AI_CONTEXT"]; ok {
+		opts = append(opts, WithPromptContext(promptContext))
+	}
+	if value, ok := values["AI_CONTEXT_WINDOW"]; ok {
+		tokens, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AI_CONTEXT_WINDOW value %q: %v", value, err)
+		}
+		opts = append(opts, WithContextWindow(tokens))
+	}
+	if value, ok := values["AI_MAX_TOKENS"]; ok {
+		tokens, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AI_MAX_TOKENS value %q: %v", value, err)
+		}
+		opts = append(opts, WithMaxTokens(tokens))
+	}
+	if value, ok := values["AI_MAX_FILE_TOKENS"]; ok {
+		tokens, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AI_MAX_FILE_TOKENS value %q: %v", value, err)
+		}
+		opts = append(opts, WithMaxFileTokens(tokens))
+	}
+	if value, ok := values["AI_MAX_SCAN_TOKENS"]; ok {
+		tokens, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AI_MAX_SCAN_TOKENS value %q: %v", value, err)
+		}
+		opts = append(opts, WithMaxScanTokens(tokens))
+	}
+	if oversized, ok := values["AI_OVERSIZED_FILES"]; ok {
+		opts = append(opts, WithOversizedFiles(OversizedFiles(oversized)))
+	}
+	if format, ok := values["AI_PLAN_FORMAT"]; ok {
+		opts = append(opts, WithPlanFormat(PlanFormat(format)))
+	}
+	if level, ok := values["AI_SANITIZE_LEVEL"]; ok {
+		opts = append(opts, WithSanitizeLevel(sanitize.Level(level)))
+	}
+	for provider, block := range providerBlocks {
+		if level, ok := values[block+".sanitize_level"]; ok {
+			opts = append(opts, WithSanitizeProfile(provider, sanitize.Level(level)))
+		}
+	}
+	if level, ok := values["local.sanitize_level"]; ok {
+		opts = append(opts, WithSanitizeProfile(DestinationLocal, sanitize.Level(level)))
+	}
+	if symlinks, ok := values["AI_SYMLINKS"]; ok {
+		opts = append(opts, WithSymlinks(Symlinks(symlinks)))
+	}
+	if extensions, ok := values["AI_BINARY_EXTENSIONS"]; ok {
+		opts = append(opts, WithBinaryExtensions(splitList(extensions)...))
+	}
+	if vaultFiles, ok := values["AI_VAULT_FILES"]; ok {
+		opts = append(opts, WithVaultFiles(VaultFiles(vaultFiles)))
+	}
+	if policy, ok := values["AI_CONSENT_POLICY"]; ok {
+		opts = append(opts, WithConsentPolicy(ConsentPolicy(policy)))
+	}
+	boolOptions := map[string]func(bool) Option{
+		"AI_CDK_SYNTH":            WithCDKSynth,
+		"AI_KUBERNETES_RENDER":    WithKubernetesRender,
+		"AI_PULUMI_PREVIEW":       WithPulumiPreview,
+		"AI_CI_PIPELINES":         WithCIPipelines,
+		"AI_RESPECT_GITIGNORE":    WithGitignore,
+		"AI_EXPLAIN_REDACTIONS":   WithExplainRedactions,
+		"AI_STREAM":               WithStreaming,
+		"AI_ANONYMIZE_PATHS":      WithAnonymizePaths,
+		"AI_COMPRESS":             WithCompression,
+		"AI_STRUCTURED_TERRAFORM": WithStructuredTerraform,
+		"AI_REFINE":               WithRefinement,
+		"AI_REVERSIBLE_REDACTION": WithReversibleRedaction,
+		"AI_BLOCK_ON_SECRETS":     WithBlockOnSecrets,
+		"AI_SCRUB_PII":            WithPIIScrubbing,
+		"AI_MASK_CLOUD_IDS":       WithCloudIDMasking,
+		"AI_DELETE_INPUT":         WithInputDeletion,
+		"AI_STATE_INVENTORY":      WithStateInventory,
+		"AI_DISCOVER":             WithDiscovery,
+		"AI_ANSIBLE_PRUNE":        WithAnsiblePruning,
+		"AI_SCAN_ARCHIVES":        WithArchives,
+		"AI_PER_PATH_REPORTS":     WithPerPathReports,
+		"AI_PROJECTS":             WithProjectReports,
+		"AI_TERRAFORM_PLAN":       WithTerraformPlan,
+		"AI_POLICY_EVALUATION":    WithPolicyEvaluation,
+		"AI_INFRACOST":            WithInfracost,
+		"AI_LINT":                 WithLinting,
+		"AI_VERSION_AUDIT":        WithVersionAudit,
+		"AI_KUBERNETES_CLUSTER":   WithKubernetesCluster,
+		"AI_AWS_ACCOUNT_HINTS":    WithAWSAccountHints,
+	}
+	for key, option := range boolOptions {
+		value, ok := values[key]
+		if !ok {
 			continue
 		}
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) == 2 {
-			config[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %v", key, value, err)
+		}
+		opts = append(opts, option(enabled))
+	}
+	if _, ok := values["AI_EMBEDDING_PROVIDER"]; ok {
+		embedder, err := embedderFromConfig(values)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithEmbedder(embedder))
+	}
+	if gitRange, ok := values["AI_DIFF"]; ok {
+		opts = append(opts, WithDiff(gitRange))
+	}
+	if diffContext, ok := values["AI_DIFF_CONTEXT"]; ok {
+		opts = append(opts, WithDiffContext(DiffContext(diffContext)))
+	}
+	if paths, ok := values["AI_IAC_PATHS"]; ok {
+		opts = append(opts, WithIaCPaths(splitList(paths)...))
+	}
+	if roots, ok := values["AI_TERRAFORM_ROOTS"]; ok {
+		opts = append(opts, WithTerraformRoots(splitList(roots)...))
+	}
+	if roots, ok := values["AI_ANSIBLE_ROOTS"]; ok {
+		opts = append(opts, WithAnsibleRoots(splitList(roots)...))
+	}
+	if globs, ok := values["AI_INCLUDE"]; ok {
+		opts = append(opts, WithInclude(splitList(globs)...))
+	}
+	if globs, ok := values["AI_EXCLUDE"]; ok {
+		opts = append(opts, WithExclude(splitList(globs)...))
+	}
+	if paths, ok := values["AI_PLAN_FILES"]; ok {
+		opts = append(opts, WithPlanFiles(splitList(paths)...))
+	}
+	if paths, ok := values["AI_ANSIBLE_CHECK_FILES"]; ok {
+		opts = append(opts, WithAnsibleCheckFiles(splitList(paths)...))
+	}
+	if binary, ok := values["AI_TERRAFORM_BINARY"]; ok {
+		opts = append(opts, WithTerraformBinary(binary))
+	}
+	if workspace, ok := values["AI_TERRAFORM_WORKSPACE"]; ok {
+		opts = append(opts, WithTerraformWorkspace(workspace))
+	}
+	if paths, ok := values["AI_TERRAFORM_VAR_FILES"]; ok {
+		opts = append(opts, WithTerraformVarFiles(splitList(paths)...))
+	}
+	if kubeContext, ok := values["AI_KUBERNETES_CONTEXT"]; ok {
+		opts = append(opts, WithKubernetesContext(kubeContext))
+	}
+	if path, ok := values["AI_DRIFT_REPORT"]; ok {
+		opts = append(opts, WithDriftReport(path))
+	}
+	if paths, ok := values["AI_SCANNER_RESULTS"]; ok {
+		opts = append(opts, WithScannerResults(splitList(paths)...))
+	}
+	if group, ok := values["AI_AZURE_RESOURCE_GROUP"]; ok {
+		opts = append(opts, WithAzureResourceGroup(group))
+	}
+	if format, ok := values["AI_PLACEHOLDER_FORMAT"]; ok {
+		opts = append(opts, WithPlaceholderFormat(sanitize.PlaceholderFormat(format)))
+	}
+	if path, ok := values["AI_INPUT_PATH"]; ok {
+		opts = append(opts, WithInputPath(path))
+	}
+	if recipients, ok := values["AI_INPUT_RECIPIENTS"]; ok {
+		opts = append(opts, WithInputEncryption(splitList(recipients)...))
+	}
+	if path, ok := values["AI_OUTPUT_FILE"]; ok {
+		opts = append(opts, WithSinks(NewFileSink(path)))
+	}
+	if pr, ok := values["AI_GITHUB_PR"]; ok {
+		sink, err := githubSinkFromConfig(pr)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithSinks(sink))
+	}
+	if phrases, ok := values["noise_filter.phrases"]; ok {
+		opts = append(opts, WithNoiseFilter(config.List(phrases)...))
+	} else if value, ok := values["AI_NOISE_FILTER"]; ok {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AI_NOISE_FILTER value %q: %v", value, err)
+		}
+		if enabled {
+			opts = append(opts, WithNoiseFilter())
 		}
 	}
+	if patterns, ok := values["sanitizer.extra_patterns"]; ok {
+		opts = append(opts, WithExtraPatterns(config.List(patterns)...))
+	}
+	if value, ok := values["AI_MANIFEST_SIGNER"]; ok {
+		signer, err := manifestSignerFromConfig(value)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithManifestSigner(signer))
+	}
+	return opts, nil
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+// splitList splits a comma-separated config value, such as a list of
+// directories or age recipients, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	return config, nil
+	return items
 }
 
 func (c *AIClient) RunAI() (string, error) {
-	terraformAndRegoCode := c.scanDirectory(filepath.Join(c.iacPath, "terraform"), []string{".tf", ".rego"})
-	ansibleAndRegoCode := c.scanDirectory(filepath.Join(c.iacPath, "ansible"), []string{".yml", ".yaml", ".rego"})
+	return c.run("analyze", c.question)
+}
 
-	terraformPlanPath := filepath.Join(c.iacPath, "terraform", "plan.json")
-	terraformPlan, err := c.extractFileContent(terraformPlanPath)
+// RunAIWithQuestion analyzes the IaC code like RunAI, but focuses the
+// response on answering question, such as "why is my NAT gateway bill so
+// high?".
+func (c *AIClient) RunAIWithQuestion(question string) (string, error) {
+	if strings.TrimSpace(question) == "" {
+		return "", fmt.Errorf("question must not be empty")
+	}
+	return c.run("analyze", question)
+}
+
+// run analyzes the IaC code with the named prompt template, focused on
+// question when it is not empty, and returns the provider's response.
+func (c *AIClient) run(template string, question string) (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+
+	p, err := c.preparePrompt(context.Background(), template, question, true)
 	if err != nil {
-		terraformPlan = "Terraform plan not found"
+		return "", err
+	}
+	return c.send(template, question, p)
+}
+
+// send saves the prepared prompt p, asks for consent, and sends it with the
+// named prompt template, returning the provider's response.
+func (c *AIClient) send(template string, question string, p *preparedPrompt) (string, error) {
+	if c.explainRedactions {
+		sanitize.Explain(c.messages(), p.redactions)
+	}
+
+	if err := c.saveAIInput(p.text(), p.redactions); err != nil {
+		return "", fmt.Errorf("failed to save AI input: %v", err)
+	}
+	if c.deleteInput {
+		defer c.removeAIInput()
+	}
+	if err := c.saveScanManifest(p.scan, p.text(), p.redactions); err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(c.messages(), "AI input has been saved to %s\n", c.AIInputPath())
+	fmt.Fprintf(c.messages(), "%d redacted values are listed in %s\n", len(p.redactions), c.RedactionReportPath())
+	fmt.Fprintf(c.messages(), "%d files in the input are listed in %s\n", len(p.scan.Files), c.ScanManifestPath())
+	sanitize.Summarize(c.messages(), p.redactions)
+	if err := c.blockSecrets(p.redactions); err != nil {
+		return "", err
+	}
+	if !c.consent(p.redactions) {
+		return "", fmt.Errorf("operation cancelled by user")
+	}
+	if len(p.chunks) > 1 {
+		return c.runChunked(template, p)
+	}
+	input := p.chunks[0]
+
+	var err error
+	messages := withExamples(p.examples, input)
+	if c.refine {
+		if messages, err = c.refineTurns(messages, question); err != nil {
+			return "", err
+		}
+	}
+	var recommendations string
+	if template == "analyze" {
+		recommendations, err = c.report(messages)
 	} else {
-		terraformPlan = c.sanitizeContent(terraformPlan)
+		recommendations, err = c.respond(messages)
+	}
+	if err != nil {
+		return "", err
+	}
+	c.recordConversation([]Message{{Role: RoleUser, Content: input}, {Role: RoleAssistant, Content: recommendations}})
+	recommendations = c.deanonymize(recommendations)
+	if c.manifestSigner != nil {
+		if err := c.writeRunManifest(template, p.text(), recommendations); err != nil {
+			return "", err
+		}
 	}
+	return recommendations, nil
+}
+
+// BuildPrompt assembles the prompt RunAI would send and returns it without
+// contacting the provider or writing ai_input.txt, so you can inspect
+// exactly what would be sent. The prompt is sanitized and, if it exceeds the
+// context window, split into chunks separated as in ai_input.txt.
+func (c *AIClient) BuildPrompt() (string, error) {
+	p, err := c.preparePrompt(context.Background(), "analyze", c.question, false)
+	if err != nil {
+		return "", err
+	}
+	return p.text(), nil
+}
 
-	input := fmt.Sprintf(`Please provide comprehensive infrastructure recommendations based on the following:
+// preparedPrompt is the sanitized input of a run, split into several chunks
+// when it does not fit in the context window.
+type preparedPrompt struct {
+	chunks     []string
+	redactions []sanitize.Redaction
+	// examples are the few-shot turns sent ahead of every chunk.
+	examples []Message
+	// base holds the prompt data of a chunked run without any code, and
+	// overhead is base rendered.
+	base     promptData
+	overhead string
+	// headings are the headings of the reports on the chunks when each IaC
+	// path or project is reported on separately, and summary the roll-up
+	// of the projects put ahead of them.
+	headings []string
+	summary  string
+	// scan is the scan the input was rendered from.
+	scan *ScanResult
+}
 
-Terraform Code and OPA Rego Policies:
-%s
+// text returns the input as it is saved to ai_input.txt, preceded by the
+// few-shot examples.
+func (p *preparedPrompt) text() string {
+	input := strings.Join(p.chunks, chunkSeparator)
+	if len(p.examples) == 0 {
+		return input
+	}
+	var out strings.Builder
+	for _, m := range p.examples {
+		fmt.Fprintf(&out, "----- example %s turn -----\n\n%s\n\n", m.Role, m.Content)
+	}
+	out.WriteString("----- prompt -----\n\n")
+	out.WriteString(input)
+	return out.String()
+}
 
-Ansible Code and OPA Rego Policies:
-%s
+// preparePrompt scans and sanitizes the IaC code and renders it with the
+// named template, focused on question when it is not empty. Oversized files
+// are summarized by the provider when that is enabled and summarize is set,
+// and otherwise stay truncated.
+func (c *AIClient) preparePrompt(ctx context.Context, template string, question string, summarize bool) (*preparedPrompt, error) {
+	scan, err := c.scan(ctx, c.cdkSynth, question)
+	if err != nil {
+		return nil, err
+	}
+	return c.prepareScan(ctx, scan, template, question, summarize)
+}
+
+// prepareScan sanitizes the code of scan and renders it with the named
+// template like preparePrompt.
+func (c *AIClient) prepareScan(ctx context.Context, scan *ScanResult, template string, question string, summarize bool) (*preparedPrompt, error) {
+	if summarize && c.oversizedFiles == OversizedFilesSummarize {
+		if err := c.summarizeOversized(scan); err != nil {
+			return nil, err
+		}
+	}
+	if c.structured && !exactLineTemplates[template] {
+		scan = structureScan(scan)
+	}
+	if c.compress {
+		scan = compressScanFor(c.messages(), template, scan)
+	}
+	data, redactions := c.promptData(scan)
+	data.Question = question
+	input, err := c.renderPrompt(template, data)
+	if err != nil {
+		return nil, err
+	}
+	examples, err := c.fewShot(template)
+	if err != nil {
+		return nil, err
+	}
+	p := &preparedPrompt{chunks: []string{input}, redactions: redactions}
+	if c.projects && len(scan.Projects) > 1 {
+		perProject, err := c.projectPrompt(template, question, scan)
+		if err != nil {
+			return nil, err
+		}
+		if perProject != nil {
+			perProject.examples = examples
+			perProject.scan = scan
+			return perProject, nil
+		}
+	}
+	if c.perPathReports && len(c.iacPaths) > 1 {
+		perPath, err := c.perPathPrompt(template, question, scan)
+		if err != nil {
+			return nil, err
+		}
+		if perPath != nil {
+			perPath.examples = examples
+			perPath.scan = scan
+			return perPath, nil
+		}
+	}
+	if c.contextWindow > 0 && estimateTokens(input) > c.contextWindow {
+		p, err = c.chunkPrompt(template, question, scan)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.examples = examples
+	p.scan = scan
+	return p, nil
+}
 
-Terraform Plan:
-%s
+// basePromptData returns the prompt data of everything but the code: the
+// instruction, the sanitized standards, the context, the guidance, and
+// question, together with the redactions made in the standards.
+func (c *AIClient) basePromptData(question string) (promptData, []sanitize.Redaction, error) {
+	standards, err := c.standardsDocument()
+	if err != nil {
+		return promptData{}, nil, err
+	}
+	standards, redactions := c.redact(standards)
+	for i := range redactions {
+		redactions[i].Section = "standards"
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return promptData{
+		Instruction: c.prompt,
+		Standards:   standards,
+		Context:     c.promptContext,
+		Guidance:    c.persona().guidance,
+		Question:    question,
+	}, redactions, nil
+}
 
-Consider all aspects including infrastructure provisioning, configuration management, security policies, and best practices.`, 
-		c.sanitizeContent(terraformAndRegoCode),
-		c.sanitizeContent(ansibleAndRegoCode),
-		terraformPlan)
+// collectPromptData scans the IaC path and returns the sanitized code to
+// analyze, together with the redactions made. CDK projects are synthesized
+// first when synth is set.
+func (c *AIClient) collectPromptData(ctx context.Context, synth bool) (promptData, []sanitize.Redaction, error) {
+	scan, err := c.scan(ctx, synth, c.question)
+	if err != nil {
+		return promptData{}, nil, err
+	}
+	data, redactions := c.promptData(scan)
+	return data, redactions, nil
+}
 
-	if err := c.saveAIInput(input); err != nil {
-		return "", fmt.Errorf("failed to save AI input: %v", err)
+// promptData sanitizes the code collected by scan into prompt data, and
+// returns it together with the redactions made.
+func (c *AIClient) promptData(scan *ScanResult) (promptData, []sanitize.Redaction) {
+	var redactions []sanitize.Redaction
+	sanitize := func(section string, content string) string {
+		sanitized, found := c.redact(content)
+		for i := range found {
+			found[i].Section = section
+		}
+		redactions = append(redactions, found...)
+		return sanitized
 	}
 
-	fmt.Printf("AI input has been saved to %s\n", filepath.Join(c.iacPath, "ai_input.txt"))
-	fmt.Print("Do you want to proceed with sending this data to the AI for analysis? (yes/no): ")
-	var response string
-	fmt.Scanln(&response)
-	if strings.ToLower(response) != "yes" {
-		return "", fmt.Errorf("operation cancelled by user")
+	// A single plan is included as is, and several each under a
+	// "File: <path>" header.
+	terraformPlan := "Terraform plan not found"
+	if plans := scan.Section(SectionTerraformPlan); len(plans) == 1 {
+		terraformPlan = sanitize(SectionTerraformPlan, c.planContent(plans[0], &redactions))
+	} else if len(plans) > 1 {
+		var content strings.Builder
+		for _, plan := range plans {
+			fmt.Fprintf(&content, "File: %s\n%s\n\n", scan.displayName(plan), c.planContent(plan, &redactions))
+		}
+		terraformPlan = sanitize(SectionTerraformPlan, content.String())
 	}
 
-	recommendations, err := c.getRecommendations(input)
+	standards, err := c.standardsDocument()
 	if err != nil {
-		return "", fmt.Errorf("failed to get recommendations: %v", err)
+		fmt.Fprintf(c.messages(), "Warning: %v\n", err)
 	}
 
-	var aiResponse map[string]interface{}
-	err = json.Unmarshal([]byte(recommendations), &aiResponse)
+	c.mu.RLock()
+	prompt := c.prompt
+	promptContext := c.promptContext
+	guidance := c.persona().guidance
+	c.mu.RUnlock()
+
+	return promptData{
+		Instruction:         prompt,
+		TerraformCode:       sanitize(SectionTerraform, scan.Content(SectionTerraform)),
+		AnsibleCode:         sanitize(SectionAnsible, scan.Content(SectionAnsible)),
+		AnsibleProject:      sanitize(SectionAnsibleProject, scan.AnsibleProject()),
+		AnsibleCheck:        sanitize(SectionAnsibleCheck, scan.Content(SectionAnsibleCheck)),
+		TerraformPlan:       terraformPlan,
+		TerraformValidation: sanitize(SectionTerraformValidation, scan.Content(SectionTerraformValidation)),
+		PolicyResults:       sanitize(SectionPolicyResults, scan.Content(SectionPolicyResults)),
+		PolicyCoverage:      sanitize("policy coverage", policyCoverage(scan)),
+		ScannerFindings:     sanitize(SectionScannerFindings, scan.Content(SectionScannerFindings)),
+		Costs:               sanitize(SectionCosts, scan.Content(SectionCosts)),
+		LintFindings:        sanitize(SectionLintFindings, scan.Content(SectionLintFindings)),
+		Drift:               sanitize(SectionTerraformDrift, scan.Content(SectionTerraformDrift)),
+		Versions:            sanitize(SectionVersions, scan.Content(SectionVersions)),
+		CDKCode:             sanitize(SectionCDK, scan.Content(SectionCDK)),
+		CDKTemplates:        sanitize(SectionCDKTemplates, scan.Content(SectionCDKTemplates)),
+		TerragruntCode:      sanitize(SectionTerragrunt, scan.Content(SectionTerragrunt)),
+		TerragruntStacks:    sanitize(SectionTerragruntStacks, scan.TerragruntStacks()),
+		BicepCode:           sanitize(SectionBicep, scan.Content(SectionBicep)),
+		AzureWhatIf:         sanitize(SectionAzureWhatIf, scan.Content(SectionAzureWhatIf)),
+		PulumiCode:          sanitize(SectionPulumi, scan.Content(SectionPulumi)),
+		PulumiPreview:       sanitize(SectionPulumiPreview, scan.Content(SectionPulumiPreview)),
+		KubernetesManifests: sanitize(SectionKubernetes, scan.Content(SectionKubernetes)),
+		KubernetesRendered:  sanitize(SectionKubernetesRendered, scan.Content(SectionKubernetesRendered)),
+		KubernetesCluster:   sanitize(SectionKubernetesCluster, scan.Content(SectionKubernetesCluster)),
+		AWSAccount:          sanitize(SectionAWSAccount, scan.Content(SectionAWSAccount)),
+		Crossplane:          sanitize(SectionCrossplane, scan.Content(SectionCrossplane)),
+		DockerFiles:         sanitize(SectionDocker, scan.Content(SectionDocker)),
+		PackerTemplates:     sanitize(SectionPacker, scan.Content(SectionPacker)),
+		NomadJobs:           sanitize(SectionNomad, scan.Content(SectionNomad)),
+		Vagrantfiles:        sanitize(SectionVagrant, scan.Content(SectionVagrant)),
+		ModuleGraph:         sanitize(SectionModuleGraph, scan.ModuleGraph()),
+		Pipelines:           sanitize(SectionPipelines, scan.Content(SectionPipelines)),
+		TerraformState:      sanitize(SectionTerraformState, scan.Content(SectionTerraformState)),
+		Diff:                sanitize(SectionDiff, scan.Diff),
+		Omitted:             sanitize(SectionOmitted, scan.OmittedFiles()),
+		Standards:           sanitize("standards", standards),
+		Context:             promptContext,
+		Guidance:            guidance,
+
+		UncoveredResources: uncoveredResources(scan),
+	}, redactions
+}
+
+// recommend sends input to the provider, streaming the response when
+// enabled, and delivers the recommendations to the configured sinks.
+func (c *AIClient) recommend(input string) (string, error) {
+	return c.respond(userTurn(input))
+}
+
+// respond sends a conversation to the provider like recommend, and returns
+// the provider's next turn.
+func (c *AIClient) respond(messages []Message) (string, error) {
+	if c.streaming {
+		flusher := &sectionFlusher{sinks: c.outputSinks(), out: c.messages()}
+		recommendations, err := c.streamRecommendations(messages, flusher.write)
+		if err != nil {
+			return "", fmt.Errorf("failed to get recommendations: %v", err)
+		}
+		flusher.finish()
+		return recommendations, nil
+	}
+
+	recommendations, err := c.getResponse(messages)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse response: %v", err)
+		return "", fmt.Errorf("failed to get recommendations: %v", err)
 	}
+	updateSinks(c.messages(), c.outputSinks(), recommendations, true)
+	return recommendations, nil
+}
 
-	content, ok := aiResponse["content"].([]interface{})
-	if !ok || len(content) == 0 {
-		return "", fmt.Errorf("no content found in the response")
+// pickKey returns the API key for the next request.
+func (c *AIClient) pickKey() string {
+	if c.keys == nil {
+		return c.apiKey
 	}
+	return c.keys.pick()
+}
 
-	textContent, ok := content[0].(map[string]interface{})["text"].(string)
-	if !ok {
-		return "", fmt.Errorf("unable to extract text content from the response")
+// doProviderRequest sends messages to the provider. When the provider
+// rate-limits the request and more API keys are configured, the request is
+// retried with the next key. The caller must hold c.mu.
+func (c *AIClient) doProviderRequest(messages []Message, stream bool) (*http.Response, error) {
+	url, body, err := c.requestBody(messages, stream)
+	if err != nil {
+		return nil, err
 	}
+	return c.sendProviderRequest(url, body)
+}
 
-	return textContent, nil
+// sendProviderRequest posts body to the provider's url like
+// doProviderRequest. The caller must hold c.mu.
+func (c *AIClient) sendProviderRequest(url string, body map[string]interface{}) (*http.Response, error) {
+	attempts := 1
+	if c.keys != nil {
+		attempts = c.keys.len()
+	}
+	for attempt := 1; ; attempt++ {
+		apiKey := c.pickKey()
+		req, err := c.newProviderRequest(url, body, apiKey)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < attempts {
+			resp.Body.Close()
+			c.keys.rateLimited(apiKey)
+			continue
+		}
+		return resp, nil
+	}
 }
 
-func (c *AIClient) getRecommendations(input string) (string, error) {
+// requestBody returns the endpoint and JSON body of a completion request
+// for the conversation in messages.
+func (c *AIClient) requestBody(messages []Message, stream bool) (string, map[string]interface{}, error) {
 	var url string
-	var requestBody []byte
-	var err error
+	var body map[string]interface{}
 
 	switch c.clientType {
 	case "chatgpt":
-		url = "https://api.openai.com/v1/chat/completions"
-		requestBody, err = json.Marshal(map[string]interface{}{
+		url = c.endpoint("https://api.openai.com/v1", "/chat/completions")
+		if system := c.systemPrompt(); system != "" {
+			messages = append([]Message{{Role: "system", Content: system}}, messages...)
+		}
+		body = map[string]interface{}{
 			"model":    c.model,
-			"messages": []map[string]string{{"role": "user", "content": input}},
-		})
+			"messages": openAIMessages(messages),
+		}
+		if c.maxTokens > 0 {
+			body["max_completion_tokens"] = c.maxTokens
+		}
 	case "anthropic_messages":
-		url = "https://api.anthropic.com/v1/messages"
-		requestBody, err = json.Marshal(map[string]interface{}{
-			"model": c.model,
-			"max_tokens": 1024,
-			"messages": []map[string]string{
-				{"role": "user", "content": input},
-			},
-		})
+		url = c.endpoint("https://api.anthropic.com/v1", "/messages")
+		maxTokens := c.maxTokens
+		if maxTokens == 0 {
+			maxTokens = defaultMaxTokens
+		}
+		body = map[string]interface{}{
+			"model":      c.model,
+			"max_tokens": maxTokens,
+			"messages":   anthropicMessages(messages),
+		}
+		if system := c.systemPrompt(); system != "" {
+			body["system"] = system
+		}
 	default:
-		return "", fmt.Errorf("unsupported AI client: %s", c.clientType)
+		return "", nil, fmt.Errorf("unsupported AI client: %s", c.clientType)
 	}
 
-	if err != nil {
-		return "", err
+	if stream {
+		body["stream"] = true
+	}
+	return url, body, nil
+}
+
+// endpoint returns the URL of path under the client's base URL, or under
+// base, the provider's public API, if none is set.
+func (c *AIClient) endpoint(base string, path string) string {
+	if c.baseURL != "" {
+		base = strings.TrimSuffix(c.baseURL, "/")
 	}
+	return base + path
+}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return "", err
+// messages returns where progress and warnings are printed.
+func (c *AIClient) messages() io.Writer {
+	out := c.messageOutput
+	if out == nil {
+		out = os.Stdout
+	}
+	if out == io.Writer(os.Stdout) && atomic.LoadInt32(&c.servingStdout) > 0 {
+		return os.Stderr
 	}
+	return out
+}
 
-	req.Header.Set("Content-Type", "application/json")
+func (c *AIClient) setAuthHeaders(req *http.Request, apiKey string) {
 	if c.clientType == "chatgpt" {
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		req.Header.Set("Authorization", "Bearer "+apiKey)
 	} else if c.clientType == "anthropic_messages" {
-		req.Header.Set("x-api-key", c.apiKey)
+		req.Header.Set("x-api-key", apiKey)
 		req.Header.Set("anthropic-version", "2023-06-01")
 	}
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func (c *AIClient) newProviderRequest(url string, body map[string]interface{}, apiKey string) (*http.Request, error) {
+	requestBody, err := json.Marshal(body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return string(body), nil
+	req.Header.Set("Content-Type", "application/json")
+	c.setAuthHeaders(req, apiKey)
+	return req, nil
 }
 
-func (c *AIClient) extractFileContent(path string) (string, error) {
-	data, err := os.ReadFile(path)
+func (c *AIClient) getRecommendations(input string) (string, error) {
+	return c.getResponse(userTurn(input))
+}
+
+// getResponse sends messages to the provider and returns the text of its
+// reply, continuing it when it is cut off at the output token limit.
+func (c *AIClient) getResponse(messages []Message) (string, error) {
+	return continueResponse(c.messages(), messages, c.getResponsePart)
+}
+
+// getResponsePart sends messages to the provider and returns the text of
+// its reply and whether it was cut off at the output token limit.
+func (c *AIClient) getResponsePart(messages []Message) (string, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	resp, err := c.doProviderRequest(messages, false)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
-	return string(data), nil
-}
+	defer resp.Body.Close()
 
-func (c *AIClient) scanDirectory(dir string, extensions []string) string {
-	var content strings.Builder
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			for _, ext := range extensions {
-				if strings.HasSuffix(info.Name(), ext) {
-					fileContent, err := c.extractFileContent(path)
-					if err == nil {
-						content.WriteString(fmt.Sprintf("File: %s\n%s\n\n", path, fileContent))
-					}
-					break
-				}
-			}
-		}
-		return nil
-	})
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Sprintf("Error scanning directory %s: %v", dir, err)
+		return "", false, err
 	}
-	return content.String()
+
+	return extractResponse(c.clientType, body)
 }
 
-func (c *AIClient) sanitizeContent(content string) string {
-	sensitivePatterns := []string{
-		`(?i)(aws_access_key|aws_secret_key|password|token|secret|api_key)(\s*[=:]\s*)['"]?[^\s'",]+['"]?`,
-		`(?i)(private_key)(\s*[=:]\s*)['"]?-----BEGIN[^'",]*-----END[^'",]*['"]?`,
-		`(?i)(connection_string)(\s*[=:]\s*)['"]?[^\s'",]+['"]?`,
-		`(?i)(bearer\s+)['"]?[^\s'",]+['"]?`,
-		`(?i)("?\w*password"?\s*[:=]?\s*\{?\s*"?value"?\s*[:=]?\s*)['"]?[^\s'",}]+['"]?`,
-		`(?i)("?\w*user"?\s*[:=]?\s*\{?\s*"?value"?\s*[:=]?\s*)['"]?[^\s'",}]+['"]?`,
-		`(?i)("?\w*(password|secret|key|token)"?\s*[:=]?\s*["'])[^"']+["']`,
-		`(?i)("?\w*(password|secret|key|token)"?\s*[:=]?\s*\{?\s*"?value"?\s*[:=]?\s*)['"]?[^\s'",}]+['"]?`,
-		`\b(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\b`,
-		`\b(?:(?:[0-9a-fA-F]{1,4}:){7,7}[0-9a-fA-F]{1,4}|(?:[0-9a-fA-F]{1,4}:){1,7}:|(?:[0-9a-fA-F]{1,4}:){1,6}:[0-9a-fA-F]{1,4}|(?:[0-9a-fA-F]{1,4}:){1,5}(?::[0-9a-fA-F]{1,4}){1,2}|(?:[0-9a-fA-F]{1,4}:){1,4}(?::[0-9a-fA-F]{1,4}){1,3}|(?:[0-9a-fA-F]{1,4}:){1,3}(?::[0-9a-fA-F]{1,4}){1,4}|(?:[0-9a-fA-F]{1,4}:){1,2}(?::[0-9a-fA-F]{1,4}){1,5}|[0-9a-fA-F]{1,4}:(?:(?::[0-9a-fA-F]{1,4}){1,6})|:(?:(?::[0-9a-fA-F]{1,4}){1,7}|:)|fe80:(?::[0-9a-fA-F]{0,4}){0,4}%[0-9a-zA-Z]{1,}|::(?:ffff(?::0{1,4}){0,1}:){0,1}(?:(?:25[0-5]|(?:2[0-4]|1{0,1}[0-9]){0,1}[0-9])\.){3,3}(?:25[0-5]|(?:2[0-4]|1{0,1}[0-9]){0,1}[0-9])|(?:[0-9a-fA-F]{1,4}:){1,4}:(?:(?:25[0-5]|(?:2[0-4]|1{0,1}[0-9]){0,1}[0-9])\.){3,3}(?:25[0-5]|(?:2[0-4]|1{0,1}[0-9]){0,1}[0-9]))\b`,
+// extractText returns the generated text from a provider response body.
+func extractText(clientType string, body []byte) (string, error) {
+	text, _, err := extractResponse(clientType, body)
+	return text, err
+}
+
+// extractResponse returns the generated text from a provider response body
+// and whether the provider stopped because it reached the output token
+// limit.
+func extractResponse(clientType string, body []byte) (string, bool, error) {
+	var aiResponse map[string]interface{}
+	err := json.Unmarshal(body, &aiResponse)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to parse response: %v", err)
 	}
 
-	for _, pattern := range sensitivePatterns {
-		re := regexp.MustCompile(pattern)
-		content = re.ReplaceAllString(content, "[REDACTED]")
+	if apiError, ok := aiResponse["error"].(map[string]interface{}); ok {
+		return "", false, fmt.Errorf("provider returned an error: %v", apiError["message"])
 	}
 
-	urlPattern := `(https?://)([\w.-]+)(\/?\S*)`
-	re := regexp.MustCompile(urlPattern)
-	content = re.ReplaceAllString(content, "${1}[REDACTED]${3}")
+	if clientType == "chatgpt" {
+		choices, ok := aiResponse["choices"].([]interface{})
+		if !ok || len(choices) == 0 {
+			return "", false, fmt.Errorf("no content found in the response")
+		}
+		choice, _ := choices[0].(map[string]interface{})
+		message, _ := choice["message"].(map[string]interface{})
+		textContent, ok := message["content"].(string)
+		if !ok {
+			return "", false, fmt.Errorf("unable to extract text content from the response")
+		}
+		return textContent, choice["finish_reason"] == "length", nil
+	}
+
+	content, ok := aiResponse["content"].([]interface{})
+	if !ok || len(content) == 0 {
+		return "", false, fmt.Errorf("no content found in the response")
+	}
+
+	textContent, ok := content[0].(map[string]interface{})["text"].(string)
+	if !ok {
+		return "", false, fmt.Errorf("unable to extract text content from the response")
+	}
 
-	return content
+	return textContent, aiResponse["stop_reason"] == "max_tokens", nil
 }
 
-func (c *AIClient) saveAIInput(input string) error {
-	inputFilePath := filepath.Join(c.iacPath, "ai_input.txt")
-	err := os.WriteFile(inputFilePath, []byte(input), 0644)
+func (c *AIClient) extractFileContent(path string) (string, error) {
+	if c.files != nil {
+		return c.files.read(path)
+	}
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("failed to save AI input to file: %v", err)
+		return "", err
 	}
-	return nil
-}
\ No newline at end of file
+	return string(data), nil
+}
+
+// RedactionReportPath returns the path of the report of the values redacted
+// from the last AI input, ai_redactions.json in the IaC path.
+func (c *AIClient) RedactionReportPath() string {
+	return filepath.Join(c.iacPath, "ai_redactions.json")
+}
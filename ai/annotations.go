@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Annotation formats supported by WriteAnnotations.
+const (
+	// AnnotationsText writes one "file:line: severity: message" line per
+	// finding, which editors and reviewdog's -efm option can parse.
+	AnnotationsText = "text"
+	// AnnotationsJSON writes a JSON array of annotations.
+	AnnotationsJSON = "json"
+	// AnnotationsRDJSONL writes reviewdog's rdjsonl diagnostic format, one
+	// JSON object per line, for use with reviewdog -f=rdjsonl.
+	AnnotationsRDJSONL = "rdjsonl"
+)
+
+// Annotation marks a line range of a file with a message, for editor
+// gutters and pull request annotations.
+type Annotation struct {
+	ID        string `json:"id,omitempty"`
+	File      string `json:"file"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+}
+
+// Annotations converts findings into annotations, dropping findings that do
+// not point at a file and line.
+func Annotations(findings []Finding) []Annotation {
+	annotations := []Annotation{}
+	for _, f := range findings {
+		if f.File == "" || f.StartLine < 1 {
+			continue
+		}
+		message := f.Message
+		if f.Title != "" {
+			message = f.Title + ": " + f.Message
+		}
+		annotations = append(annotations, Annotation{
+			ID:        f.ID,
+			File:      f.File,
+			StartLine: f.StartLine,
+			EndLine:   f.EndLine,
+			Severity:  f.Severity,
+			Message:   message,
+		})
+	}
+	return annotations
+}
+
+// rdjsonlSeverities maps finding severities to reviewdog severities.
+var rdjsonlSeverities = map[string]string{
+	SeverityCritical: "ERROR",
+	SeverityHigh:     "ERROR",
+	SeverityMedium:   "WARNING",
+	SeverityLow:      "INFO",
+	SeverityInfo:     "INFO",
+}
+
+// WriteAnnotations writes the annotations derived from findings to w in the
+// given format.
+func WriteAnnotations(w io.Writer, findings []Finding, format string) error {
+	annotations := Annotations(findings)
+	switch format {
+	case AnnotationsText:
+		for _, a := range annotations {
+			message := strings.Join(strings.Fields(a.Message), " ")
+			if a.ID != "" {
+				message = "[" + a.ID + "] " + message
+			}
+			if _, err := fmt.Fprintf(w, "%s:%d: %s: %s\n", a.File, a.StartLine, a.Severity, message); err != nil {
+				return err
+			}
+		}
+		return nil
+	case AnnotationsJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(annotations)
+	case AnnotationsRDJSONL:
+		encoder := json.NewEncoder(w)
+		for _, a := range annotations {
+			diagnostic := map[string]interface{}{
+				"message": a.Message,
+				"location": map[string]interface{}{
+					"path": a.File,
+					"range": map[string]interface{}{
+						"start": map[string]int{"line": a.StartLine},
+						"end":   map[string]int{"line": a.EndLine},
+					},
+				},
+				"severity": rdjsonlSeverities[a.Severity],
+				"source":   map[string]string{"name": "kado-ai"},
+			}
+			if a.ID != "" {
+				diagnostic["code"] = map[string]string{"value": a.ID}
+			}
+			if err := encoder.Encode(diagnostic); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown annotation format %q; expected %s, %s, or %s", format, AnnotationsText, AnnotationsJSON, AnnotationsRDJSONL)
+}
@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteAnnotations(t *testing.T) {
+	findings := []Finding{
+		{ID: "KADO-101", File: "terraform/main.tf", StartLine: 3, EndLine: 5, Severity: SeverityHigh, Title: "Open SSH", Message: "Restrict\nthe CIDR."},
+		{Title: "General advice", Severity: SeverityInfo, Message: "Pin provider versions."},
+	}
+
+	var text bytes.Buffer
+	if err := WriteAnnotations(&text, findings, AnnotationsText); err != nil {
+		t.Fatalf("WriteAnnotations failed: %v", err)
+	}
+	if got := text.String(); got != "terraform/main.tf:3: high: [KADO-101] Open SSH: Restrict the CIDR.\n" {
+		t.Errorf("Unexpected text annotations: %q", got)
+	}
+
+	var rdjsonl bytes.Buffer
+	if err := WriteAnnotations(&rdjsonl, findings, AnnotationsRDJSONL); err != nil {
+		t.Fatalf("WriteAnnotations failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(rdjsonl.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one rdjsonl diagnostic, got %d", len(lines))
+	}
+	var diagnostic struct {
+		Severity string `json:"severity"`
+		Location struct {
+			Path  string `json:"path"`
+			Range struct {
+				End struct {
+					Line int `json:"line"`
+				} `json:"end"`
+			} `json:"range"`
+		} `json:"location"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &diagnostic); err != nil {
+		t.Fatalf("Failed to parse rdjsonl diagnostic: %v", err)
+	}
+	if diagnostic.Severity != "ERROR" || diagnostic.Location.Path != "terraform/main.tf" || diagnostic.Location.Range.End.Line != 5 {
+		t.Errorf("Unexpected rdjsonl diagnostic: %s", lines[0])
+	}
+
+	var empty bytes.Buffer
+	if err := WriteAnnotations(&empty, nil, AnnotationsJSON); err != nil || strings.TrimSpace(empty.String()) != "[]" {
+		t.Errorf("Expected an empty JSON array, got %q (%v)", empty.String(), err)
+	}
+
+	if err := WriteAnnotations(&empty, findings, "sarif"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
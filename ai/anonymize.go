@@ -0,0 +1,190 @@
+package ai
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// sectionRoots are the top-level directories whose names are kado-ai
+// conventions rather than project information, so they are never
+// anonymized.
+var sectionRoots = map[string]bool{
+	"terraform": true,
+	"ansible":   true,
+}
+
+// pathAnonymizer replaces the directory and file names of scanned files
+// with pseudonyms derived from a per-project secret salt, so the same path
+// always gets the same pseudonym without the pseudonym revealing the name.
+// Names maps every pseudonym back to the name it replaces.
+type pathAnonymizer struct {
+	Salt  string            `json:"salt"`
+	Names map[string]string `json:"names"`
+}
+
+// PathMapPath returns the path of the local mapping from anonymized path
+// names back to the real ones, .kado/paths.json in the IaC path.
+func (c *AIClient) PathMapPath() string {
+	return c.projectPath("paths.json")
+}
+
+// anonymize sets the pseudonym of every file in result, extending and
+// saving the project's path mapping.
+func (c *AIClient) anonymize(result *ScanResult) error {
+	a, err := c.pathAnonymizer()
+	if err != nil {
+		return err
+	}
+	for i := range result.Files {
+		result.Files[i].Pseudonym = a.pseudonym(result.Files[i].Path)
+	}
+	if err := a.save(c.PathMapPath()); err != nil {
+		return fmt.Errorf("failed to save path mapping: %v", err)
+	}
+	return nil
+}
+
+// pathAnonymizer returns the project's path mapping, loading it or creating
+// one with a new salt on first use.
+func (c *AIClient) pathAnonymizer() (*pathAnonymizer, error) {
+	if c.anonymizer != nil {
+		return c.anonymizer, nil
+	}
+	a, err := loadPathAnonymizer(c.PathMapPath())
+	if os.IsNotExist(err) {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate path salt: %v", err)
+		}
+		a, err = &pathAnonymizer{Salt: hex.EncodeToString(salt), Names: map[string]string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.anonymizer = a
+	return a, nil
+}
+
+// deanonymize restores the values behind redaction placeholders, the
+// hostnames and cloud IDs behind their pseudonyms, and the real path names in text
+// produced from an anonymized prompt. Text is returned unchanged when none
+// is enabled.
+func (c *AIClient) deanonymize(text string) string {
+	if c.placeholders != nil {
+		text = c.placeholders.Restore(text)
+	}
+	if c.hostnames != nil {
+		text = c.hostnames.Restore(text)
+	}
+	if c.cloudIDs != nil {
+		text = c.cloudIDs.Restore(text)
+	}
+	if !c.anonymizePaths {
+		return text
+	}
+	a, err := c.pathAnonymizer()
+	if err != nil {
+		fmt.Fprintf(c.messages(), "Warning: failed to restore anonymized paths: %v\n", err)
+		return text
+	}
+	return a.restore(text)
+}
+
+// outputSinks returns the sinks to deliver a response to, restoring
+// placeholders, hostnames, cloud IDs, and anonymized paths on the way.
+func (c *AIClient) outputSinks() []Sink {
+	if (!c.anonymizePaths && c.placeholders == nil && c.hostnames == nil && c.cloudIDs == nil) || len(c.sinks) == 0 {
+		return c.sinks
+	}
+	return []Sink{&deanonymizingSink{c: c, sinks: c.sinks}}
+}
+
+type deanonymizingSink struct {
+	c     *AIClient
+	sinks []Sink
+}
+
+func (s *deanonymizingSink) Update(text string, final bool) error {
+	updateSinks(s.c.messages(), s.sinks, s.c.deanonymize(text), final)
+	return nil
+}
+
+// pseudonym returns the anonymized form of a slash- or OS-separated path
+// relative to the IaC path. Every directory becomes "dir-<hash>" and the
+// file "file-<hash>" with its extension kept, since the language of a file
+// is not sensitive and helps the analysis.
+func (a *pathAnonymizer) pseudonym(path string) string {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i, part := range parts {
+		if i == 0 && sectionRoots[part] && len(parts) > 1 {
+			continue
+		}
+		prefix := strings.Join(parts[:i], "/")
+		mac := hmac.New(sha256.New, []byte(a.Salt))
+		mac.Write([]byte(prefix + "/" + part))
+		sum := hex.EncodeToString(mac.Sum(nil))[:10]
+
+		if i < len(parts)-1 {
+			parts[i] = "dir-" + sum
+			a.Names[parts[i]] = part
+			continue
+		}
+		ext := filepath.Ext(part)
+		parts[i] = "file-" + sum + ext
+		a.Names["file-"+sum] = strings.TrimSuffix(part, ext)
+	}
+	return strings.Join(parts, "/")
+}
+
+// restore replaces every pseudonym in text with the name it stands for.
+func (a *pathAnonymizer) restore(text string) string {
+	if len(a.Names) == 0 {
+		return text
+	}
+	pseudonyms := make([]string, 0, len(a.Names))
+	for pseudonym := range a.Names {
+		pseudonyms = append(pseudonyms, pseudonym)
+	}
+	sort.Strings(pseudonyms)
+	var pairs []string
+	for _, pseudonym := range pseudonyms {
+		pairs = append(pairs, pseudonym, a.Names[pseudonym])
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func loadPathAnonymizer(path string) (*pathAnonymizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var a pathAnonymizer
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse path mapping %s: %v", path, err)
+	}
+	if a.Names == nil {
+		a.Names = map[string]string{}
+	}
+	return &a, nil
+}
+
+// save writes the mapping to path, readable only by its owner since it
+// reveals the real names and holds the salt.
+func (a *pathAnonymizer) save(path string) error {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
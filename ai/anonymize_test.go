@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPathAnonymizer(t *testing.T) {
+	a := &pathAnonymizer{Salt: "salt", Names: map[string]string{}}
+	pseudonym := a.pseudonym("terraform/project-phoenix/main.tf")
+	parts := strings.Split(pseudonym, "/")
+	if len(parts) != 3 || parts[0] != "terraform" || !strings.HasPrefix(parts[1], "dir-") || !strings.HasPrefix(parts[2], "file-") || !strings.HasSuffix(parts[2], ".tf") {
+		t.Fatalf("Unexpected pseudonym %q", pseudonym)
+	}
+	if strings.Contains(pseudonym, "phoenix") || strings.Contains(pseudonym, "main") {
+		t.Errorf("Expected the pseudonym to hide the real names, got %q", pseudonym)
+	}
+	if again := a.pseudonym("terraform/project-phoenix/main.tf"); again != pseudonym {
+		t.Errorf("Expected a stable pseudonym, got %q and %q", pseudonym, again)
+	}
+	if other := a.pseudonym("terraform/project-atlas/main.tf"); strings.Split(other, "/")[2] == parts[2] {
+		t.Errorf("Expected files in different directories to get different pseudonyms")
+	}
+	other := &pathAnonymizer{Salt: "other", Names: map[string]string{}}
+	if other.pseudonym("terraform/project-phoenix/main.tf") == pseudonym {
+		t.Errorf("Expected pseudonyms to depend on the salt")
+	}
+
+	answer := fmt.Sprintf("In %s, the module under %s/ opens SSH.", pseudonym, parts[0]+"/"+parts[1])
+	expected := "In terraform/project-phoenix/main.tf, the module under terraform/project-phoenix/ opens SSH."
+	if restored := a.restore(answer); restored != expected {
+		t.Errorf("Expected %q, got %q", expected, restored)
+	}
+}
+
+func TestRunAIAnonymizedPaths(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/project-phoenix/main.tf": "resource \"aws_vpc\" \"main\" {}\n",
+	})
+
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+		file := strings.TrimPrefix(strings.SplitN(prompt[strings.Index(prompt, "File: "):], "\n", 2)[0], "File: ")
+		fmt.Fprintf(w, `{"content":[{"type":"text","text":"Enable flow logs in %s."}]}`, file)
+	}))
+	defer server.Close()
+
+	sink := &recordingSink{}
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+		WithAnonymizePaths(true),
+		WithSinks(sink),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	recommendations, err := client.RunAI()
+	if err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	if strings.Contains(prompt, "phoenix") || strings.Contains(prompt, tmpDir) {
+		t.Errorf("Expected the prompt to hide the real paths, got %q", prompt)
+	}
+	expected := "Enable flow logs in terraform/project-phoenix/main.tf."
+	if recommendations != expected {
+		t.Errorf("Expected %q, got %q", expected, recommendations)
+	}
+	if len(sink.updates) == 0 || sink.updates[len(sink.updates)-1] != expected {
+		t.Errorf("Expected sinks to receive the restored paths, got %v", sink.updates)
+	}
+	if info, err := os.Stat(client.PathMapPath()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private path mapping, got %v (%v)", info, err)
+	}
+}
@@ -0,0 +1,653 @@
+package ai
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SectionAnsibleProject names the Ansible project structure in redaction
+// reports.
+const SectionAnsibleProject = "ansible project"
+
+// AnsibleProject is the structure of the Ansible code: the plays of each
+// playbook and the roles they apply, the roles and the parts they have, the
+// groups of the inventories, and the variables defined for groups and hosts.
+// Paths are relative to the IaC path.
+type AnsibleProject struct {
+	Playbooks   []AnsiblePlaybook  `json:"playbooks,omitempty"`
+	Roles       []AnsibleRole      `json:"roles,omitempty"`
+	Inventories []AnsibleInventory `json:"inventories,omitempty"`
+	Vars        []AnsibleVars      `json:"vars,omitempty"`
+
+	// used are the files the playbooks use, directly or through the roles
+	// and task files they include, along with inventories and variables.
+	used map[string]bool
+}
+
+// AnsiblePlaybook is a playbook and the playbooks it imports.
+type AnsiblePlaybook struct {
+	Path    string        `json:"path"`
+	Plays   []AnsiblePlay `json:"plays,omitempty"`
+	Imports []string      `json:"imports,omitempty"`
+}
+
+// AnsiblePlay is a play of a playbook: the hosts it targets, the roles it
+// applies, with roles or include_role, and its number of tasks.
+type AnsiblePlay struct {
+	Name  string   `json:"name,omitempty"`
+	Hosts string   `json:"hosts"`
+	Roles []string `json:"roles,omitempty"`
+	Tasks int      `json:"tasks"`
+}
+
+// AnsibleRole is a role under a roles directory. Parts are its
+// subdirectories, such as tasks, handlers, and defaults, and Dependencies the
+// roles of its meta/main.yml and those its tasks include. Used is set for
+// roles a play applies, directly or as a dependency.
+type AnsibleRole struct {
+	Name         string   `json:"name"`
+	Path         string   `json:"path"`
+	Parts        []string `json:"parts"`
+	Dependencies []string `json:"dependencies,omitempty"`
+	Used         bool     `json:"used"`
+}
+
+// AnsibleInventory is a YAML or INI inventory and its groups.
+type AnsibleInventory struct {
+	Path   string         `json:"path"`
+	Groups []AnsibleGroup `json:"groups"`
+}
+
+// AnsibleGroup is a group of an inventory, with the number of hosts listed
+// in it directly and its child groups.
+type AnsibleGroup struct {
+	Name     string   `json:"name"`
+	Hosts    int      `json:"hosts"`
+	Children []string `json:"children,omitempty"`
+}
+
+// AnsibleVars is a group_vars or host_vars file: Scope is "group" or
+// "host", Target the group or host it applies to, and Keys the variables it
+// defines, which are unknown when it is encrypted.
+type AnsibleVars struct {
+	Path      string   `json:"path"`
+	Scope     string   `json:"scope"`
+	Target    string   `json:"target"`
+	Keys      []string `json:"keys,omitempty"`
+	Encrypted bool     `json:"encrypted,omitempty"`
+}
+
+// iniInventoryNames are the usual names of INI inventories, which have no
+// YAML extension.
+var iniInventoryNames = map[string]bool{
+	"hosts": true, "hosts.ini": true, "inventory": true, "inventory.ini": true,
+}
+
+// isINIInventory reports whether path is named like an INI inventory, or
+// is a file without extension or with .ini in an inventory directory.
+func isINIInventory(path string) bool {
+	ext := filepath.Ext(path)
+	if ext != "" && ext != ".ini" {
+		return false
+	}
+	if iniInventoryNames[filepath.Base(path)] {
+		return true
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		if dir == "inventory" || dir == "inventories" {
+			return !isInventoryVars(path)
+		}
+	}
+	return false
+}
+
+// ansibleKey returns the value of the first of the keys of a task or play
+// found in m, under its short name or its ansible.builtin name.
+func ansibleKey(m map[string]interface{}, names ...string) (interface{}, bool) {
+	for _, name := range names {
+		for _, key := range []string{name, "ansible.builtin." + name} {
+			if v, ok := m[key]; ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// roleReference returns the name of the role referred to by an entry of
+// roles, dependencies, or include_role: a string, or a map with a role or
+// name. Roles referred to by path are known by their directory name.
+func roleReference(ref interface{}) string {
+	name, _ := ref.(string)
+	if m, ok := ref.(map[string]interface{}); ok {
+		if name, ok = m["role"].(string); !ok {
+			name, _ = m["name"].(string)
+		}
+	}
+	if strings.Contains(name, "{{") {
+		return ""
+	}
+	return filepath.Base(strings.TrimSpace(name))
+}
+
+// ansibleRoleOf returns the name and directory of the role path belongs to,
+// and the part of the role, such as tasks, it is in.
+func ansibleRoleOf(path string) (string, string, string, bool) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i := len(parts) - 4; i >= 0; i-- {
+		if parts[i] == "roles" {
+			return parts[i+1], filepath.FromSlash(strings.Join(parts[:i+2], "/")), parts[i+2], true
+		}
+	}
+	return "", "", "", false
+}
+
+// ansibleVarsOf returns the scope and target of a group_vars or host_vars
+// file, whether it is named after them or in a directory named after them.
+func ansibleVarsOf(path string) (string, string, bool) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i := len(parts) - 2; i >= 0; i-- {
+		if parts[i] == "group_vars" || parts[i] == "host_vars" {
+			target := parts[i+1]
+			if i+2 == len(parts) {
+				target = strings.TrimSuffix(target, filepath.Ext(target))
+			}
+			return strings.TrimSuffix(parts[i], "_vars"), target, true
+		}
+	}
+	return "", "", false
+}
+
+// ansibleTasks walks a list of tasks, with the tasks of their blocks, and
+// returns how many there are, the task files they import or include, and the
+// roles they include.
+func ansibleTasks(tasks interface{}) (int, []string, []string) {
+	list, _ := tasks.([]interface{})
+	count := 0
+	var files, roles []string
+	for _, item := range list {
+		task, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok := task["block"]; ok {
+			for _, key := range []string{"block", "rescue", "always"} {
+				n, f, r := ansibleTasks(task[key])
+				count, files, roles = count+n, append(files, f...), append(roles, r...)
+			}
+			continue
+		}
+		count++
+		if ref, ok := ansibleKey(task, "import_tasks", "include_tasks"); ok {
+			file, _ := ref.(string)
+			if m, ok := ref.(map[string]interface{}); ok {
+				file, _ = m["file"].(string)
+			}
+			if file != "" && !strings.Contains(file, "{{") {
+				files = append(files, file)
+			}
+		}
+		if ref, ok := ansibleKey(task, "import_role", "include_role"); ok {
+			if role := roleReference(ref); role != "" {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return count, files, roles
+}
+
+// buildAnsibleProject records the structure of the Ansible code of result
+// in result.Ansible, unless it has no playbooks, roles, inventories, or
+// variables.
+func buildAnsibleProject(result *ScanResult) {
+	project := &AnsibleProject{used: map[string]bool{}}
+	files := map[string]ScannedFile{}
+	for _, f := range result.Section(SectionAnsible) {
+		files[f.Path] = f
+	}
+	parse := func(f ScannedFile) interface{} {
+		var doc interface{}
+		if f.Encrypted || yaml.Unmarshal([]byte(f.Content), &doc) != nil {
+			return nil
+		}
+		return doc
+	}
+	// taskFiles marks the task files included from dir as used, and
+	// returns the roles they include, following their own includes.
+	var taskFiles func(dir string, refs []string) []string
+	taskFiles = func(dir string, refs []string) []string {
+		var roles []string
+		for _, ref := range refs {
+			path := filepath.Join(dir, filepath.FromSlash(ref))
+			f, ok := files[path]
+			if !ok || project.used[path] {
+				continue
+			}
+			project.used[path] = true
+			_, more, included := ansibleTasks(parse(f))
+			roles = append(append(roles, included...), taskFiles(filepath.Dir(path), more)...)
+		}
+		return roles
+	}
+
+	roles := map[string]*AnsibleRole{}
+	roleFiles := map[string][]string{}
+	for _, f := range result.Section(SectionAnsible) {
+		if name, dir, part, ok := ansibleRoleOf(f.Path); ok {
+			// Roles of the same name in several roles directories are
+			// described by the first, but used together.
+			roleFiles[name] = append(roleFiles[name], f.Path)
+			role, ok := roles[name]
+			if !ok {
+				role = &AnsibleRole{Name: name, Path: dir}
+				roles[name] = role
+			} else if role.Path != dir {
+				continue
+			}
+			if !containsString(role.Parts, part) {
+				role.Parts = append(role.Parts, part)
+			}
+			doc := parse(f)
+			if base := filepath.Base(f.Path); part == "meta" && strings.TrimSuffix(base, filepath.Ext(base)) == "main" {
+				if meta, ok := doc.(map[string]interface{}); ok {
+					deps, _ := meta["dependencies"].([]interface{})
+					for _, dep := range deps {
+						if dep := roleReference(dep); dep != "" {
+							role.Dependencies = append(role.Dependencies, dep)
+						}
+					}
+				}
+			}
+			if part == "tasks" || part == "handlers" {
+				_, _, included := ansibleTasks(doc)
+				role.Dependencies = append(role.Dependencies, included...)
+			}
+			continue
+		}
+		if scope, target, ok := ansibleVarsOf(f.Path); ok {
+			vars := AnsibleVars{Path: f.Path, Scope: scope, Target: target, Encrypted: f.Encrypted}
+			if m, ok := parse(f).(map[string]interface{}); ok {
+				for key := range m {
+					vars.Keys = append(vars.Keys, key)
+				}
+				sort.Strings(vars.Keys)
+			}
+			project.Vars = append(project.Vars, vars)
+			project.used[f.Path] = true
+			continue
+		}
+		if isINIInventory(f.Path) {
+			if groups := parseINIInventory(f.Content); len(groups) > 0 {
+				project.Inventories = append(project.Inventories, AnsibleInventory{Path: f.Path, Groups: groups})
+				project.used[f.Path] = true
+			}
+			continue
+		}
+		doc := parse(f)
+		if groups := yamlInventoryGroups(doc); len(groups) > 0 {
+			project.Inventories = append(project.Inventories, AnsibleInventory{Path: f.Path, Groups: groups})
+			project.used[f.Path] = true
+			continue
+		}
+		if playbook, ok := parsePlaybook(f.Path, doc); ok {
+			project.used[f.Path] = true
+			dir := filepath.Dir(f.Path)
+			for i, refs := range playbook.taskFiles {
+				playbook.Plays[i].Roles = append(playbook.Plays[i].Roles, taskFiles(dir, refs)...)
+			}
+			for _, path := range playbook.varsFiles {
+				project.used[filepath.Join(dir, filepath.FromSlash(path))] = true
+			}
+			project.Playbooks = append(project.Playbooks, playbook.AnsiblePlaybook)
+		}
+	}
+
+	// A role is used if a play applies it, or a used role depends on it.
+	var use func(name string)
+	use = func(name string) {
+		role, ok := roles[name]
+		if !ok || role.Used {
+			return
+		}
+		role.Used = true
+		for _, path := range roleFiles[name] {
+			project.used[path] = true
+		}
+		for _, dep := range role.Dependencies {
+			use(dep)
+		}
+	}
+	for _, playbook := range project.Playbooks {
+		for _, play := range playbook.Plays {
+			for _, name := range play.Roles {
+				use(name)
+			}
+		}
+	}
+	var names []string
+	for name := range roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		project.Roles = append(project.Roles, *roles[name])
+	}
+
+	if len(project.Playbooks)+len(project.Roles)+len(project.Inventories)+len(project.Vars) == 0 {
+		return
+	}
+	result.Ansible = project
+}
+
+// parsedPlaybook is a playbook with the task files each of its plays
+// includes and the variable files they load, relative to its directory.
+type parsedPlaybook struct {
+	AnsiblePlaybook
+	taskFiles [][]string
+	varsFiles []string
+}
+
+// parsePlaybook parses doc as a playbook, a list of plays with hosts or of
+// import_playbook entries, and reports whether it is one.
+func parsePlaybook(path string, doc interface{}) (parsedPlaybook, bool) {
+	list, ok := doc.([]interface{})
+	if !ok {
+		return parsedPlaybook{}, false
+	}
+	playbook := parsedPlaybook{AnsiblePlaybook: AnsiblePlaybook{Path: path}}
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if ref, ok := ansibleKey(m, "import_playbook"); ok {
+			if ref, ok := ref.(string); ok && !strings.Contains(ref, "{{") {
+				playbook.Imports = append(playbook.Imports, filepath.Join(filepath.Dir(path), filepath.FromSlash(ref)))
+			}
+			continue
+		}
+		hosts, ok := m["hosts"]
+		if !ok {
+			continue
+		}
+		play := AnsiblePlay{Hosts: fmt.Sprint(hosts)}
+		if list, ok := hosts.([]interface{}); ok {
+			var names []string
+			for _, host := range list {
+				names = append(names, fmt.Sprint(host))
+			}
+			play.Hosts = strings.Join(names, ",")
+		}
+		play.Name, _ = m["name"].(string)
+		roles, _ := m["roles"].([]interface{})
+		for _, ref := range roles {
+			if role := roleReference(ref); role != "" {
+				play.Roles = append(play.Roles, role)
+			}
+		}
+		var taskFiles []string
+		for _, key := range []string{"pre_tasks", "tasks", "post_tasks", "handlers"} {
+			n, files, included := ansibleTasks(m[key])
+			if key != "handlers" {
+				play.Tasks += n
+			}
+			play.Roles = append(play.Roles, included...)
+			taskFiles = append(taskFiles, files...)
+		}
+		varsFiles, _ := m["vars_files"].([]interface{})
+		for _, ref := range varsFiles {
+			if ref, ok := ref.(string); ok && !strings.Contains(ref, "{{") {
+				playbook.varsFiles = append(playbook.varsFiles, ref)
+			}
+		}
+		playbook.Plays = append(playbook.Plays, play)
+		playbook.taskFiles = append(playbook.taskFiles, taskFiles)
+	}
+	return playbook, len(playbook.Plays)+len(playbook.Imports) > 0
+}
+
+// yamlInventoryGroups returns the groups of doc if it is a YAML inventory:
+// a map of groups with nothing but hosts, children, and vars, at least one
+// of which has hosts or children.
+func yamlInventoryGroups(doc interface{}) []AnsibleGroup {
+	top, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	inventory := false
+	for _, value := range top {
+		group, ok := value.(map[string]interface{})
+		if value != nil && !ok {
+			return nil
+		}
+		for key := range group {
+			if key != "hosts" && key != "children" && key != "vars" {
+				return nil
+			}
+			inventory = inventory || key != "vars"
+		}
+	}
+	if !inventory {
+		return nil
+	}
+	groups := map[string]*AnsibleGroup{}
+	var walk func(name string, value interface{})
+	walk = func(name string, value interface{}) {
+		group, ok := groups[name]
+		if !ok {
+			group = &AnsibleGroup{Name: name}
+			groups[name] = group
+		}
+		m, _ := value.(map[string]interface{})
+		if hosts, ok := m["hosts"].(map[string]interface{}); ok {
+			group.Hosts += len(hosts)
+		}
+		children, _ := m["children"].(map[string]interface{})
+		for child, value := range children {
+			if !containsString(group.Children, child) {
+				group.Children = append(group.Children, child)
+			}
+			walk(child, value)
+		}
+		sort.Strings(group.Children)
+	}
+	for name, value := range top {
+		walk(name, value)
+	}
+	return sortedGroups(groups)
+}
+
+// parseINIInventory returns the groups of an INI inventory. Hosts listed
+// before any group are in the ungrouped group.
+func parseINIInventory(content string) []AnsibleGroup {
+	groups := map[string]*AnsibleGroup{}
+	group := func(name string) *AnsibleGroup {
+		if _, ok := groups[name]; !ok {
+			groups[name] = &AnsibleGroup{Name: name}
+		}
+		return groups[name]
+	}
+	section, kind := "ungrouped", "hosts"
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section, kind = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]"), "hosts"
+			if i := strings.Index(section, ":"); i >= 0 {
+				section, kind = section[:i], section[i+1:]
+			}
+			group(section)
+			continue
+		}
+		switch kind {
+		case "hosts":
+			group(section).Hosts++
+		case "children":
+			child := strings.Fields(line)[0]
+			group(child)
+			if g := group(section); !containsString(g.Children, child) {
+				g.Children = append(g.Children, child)
+			}
+		}
+	}
+	for _, g := range groups {
+		sort.Strings(g.Children)
+	}
+	return sortedGroups(groups)
+}
+
+func sortedGroups(groups map[string]*AnsibleGroup) []AnsibleGroup {
+	var sorted []AnsibleGroup
+	for _, g := range groups {
+		sorted = append(sorted, *g)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneAnsible leaves out the YAML files of the Ansible section no playbook
+// uses, such as roles no play applies, CI configuration, and Molecule
+// scenarios, recording them as skipped. Nothing is left out of a project
+// without playbooks, whose roles may be used elsewhere.
+func pruneAnsible(result *ScanResult) {
+	if result.Ansible == nil || len(result.Ansible.Playbooks) == 0 {
+		return
+	}
+	var kept []ScannedFile
+	for _, f := range result.Files {
+		if f.Section == SectionAnsible && f.Language == "yaml" && !result.Ansible.used[f.Path] {
+			result.Skipped = append(result.Skipped, SkippedEntry{Path: f.Path, Reason: "not used by any playbook"})
+			continue
+		}
+		kept = append(kept, f)
+	}
+	result.Files = kept
+}
+
+// AnsibleProject renders the structure of the Ansible code as the prompt
+// presents it: the plays of each playbook, the roles with the groups they
+// are applied to, the groups of each inventory, and the variables defined
+// for each group and host, without their values.
+func (r *ScanResult) AnsibleProject() string {
+	p := r.Ansible
+	if p == nil {
+		return ""
+	}
+	var out strings.Builder
+	if len(p.Playbooks) > 0 {
+		out.WriteString("Playbooks:\n")
+	}
+	applied := map[string][]string{}
+	for _, playbook := range p.Playbooks {
+		fmt.Fprintf(&out, "- %s\n", r.displayPath(playbook.Path))
+		for _, play := range playbook.Plays {
+			out.WriteString("  - play")
+			if play.Name != "" {
+				fmt.Fprintf(&out, " %q", play.Name)
+			}
+			fmt.Fprintf(&out, " on %s: %d tasks", play.Hosts, play.Tasks)
+			if len(play.Roles) > 0 {
+				fmt.Fprintf(&out, ", roles %s", strings.Join(play.Roles, ", "))
+			}
+			out.WriteString("\n")
+			for _, name := range p.roleClosure(play.Roles) {
+				target := fmt.Sprintf("%s by %s", play.Hosts, r.displayPath(playbook.Path))
+				if !containsString(applied[name], target) {
+					applied[name] = append(applied[name], target)
+				}
+			}
+		}
+		for _, path := range playbook.Imports {
+			fmt.Fprintf(&out, "  - imports %s\n", r.displayPath(path))
+		}
+	}
+	if len(p.Roles) > 0 {
+		out.WriteString("Roles:\n")
+	}
+	for _, role := range p.Roles {
+		fmt.Fprintf(&out, "- %s (%s)", role.Name, strings.Join(role.Parts, ", "))
+		if len(role.Dependencies) > 0 {
+			fmt.Fprintf(&out, ", depends on %s", strings.Join(role.Dependencies, ", "))
+		}
+		if targets := applied[role.Name]; len(targets) > 0 {
+			fmt.Fprintf(&out, ": applied to %s\n", strings.Join(targets, "; "))
+		} else {
+			out.WriteString(": not applied by any playbook\n")
+		}
+	}
+	if len(p.Inventories) > 0 {
+		out.WriteString("Inventories:\n")
+	}
+	for _, inventory := range p.Inventories {
+		var groups []string
+		for _, g := range inventory.Groups {
+			group := g.Name
+			var details []string
+			if g.Hosts == 1 {
+				details = append(details, "1 host")
+			} else if g.Hosts > 1 {
+				details = append(details, fmt.Sprintf("%d hosts", g.Hosts))
+			}
+			if len(g.Children) > 0 {
+				details = append(details, "children "+strings.Join(g.Children, ", "))
+			}
+			if len(details) > 0 {
+				group += " (" + strings.Join(details, "; ") + ")"
+			}
+			groups = append(groups, group)
+		}
+		fmt.Fprintf(&out, "- %s: %s\n", r.displayPath(inventory.Path), strings.Join(groups, ", "))
+	}
+	if len(p.Vars) > 0 {
+		out.WriteString("Variables:\n")
+	}
+	for _, vars := range p.Vars {
+		keys := strings.Join(vars.Keys, ", ")
+		if vars.Encrypted {
+			keys = "encrypted with Ansible Vault"
+		}
+		fmt.Fprintf(&out, "- %s %s (%s): %s\n", vars.Scope, vars.Target, r.displayPath(vars.Path), keys)
+	}
+	return out.String()
+}
+
+// roleClosure returns the roles in names with the roles they depend on,
+// transitively, in order.
+func (p *AnsibleProject) roleClosure(names []string) []string {
+	dependencies := map[string][]string{}
+	for _, role := range p.Roles {
+		dependencies[role.Name] = role.Dependencies
+	}
+	var closure []string
+	var add func(name string)
+	add = func(name string) {
+		if containsString(closure, name) {
+			return
+		}
+		closure = append(closure, name)
+		for _, dep := range dependencies[name] {
+			add(dep)
+		}
+	}
+	for _, name := range names {
+		add(name)
+	}
+	return closure
+}
@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnsibleProject(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"ansible/site.yml": `- import_playbook: db.yml
+- name: Web servers
+  hosts: webservers
+  vars_files:
+    - vars/common.yml
+  roles:
+    - common
+    - role: nginx
+  tasks:
+    - import_tasks: tasks/extra.yml
+    - name: Restart
+      ansible.builtin.service:
+        name: nginx
+`,
+		"ansible/db.yml":                        "- hosts: dbservers\n  tasks:\n    - include_role:\n        name: postgres\n",
+		"ansible/tasks/extra.yml":               "- name: Extra\n  debug:\n    msg: hi\n",
+		"ansible/vars/common.yml":               "ntp_server: pool.ntp.org\n",
+		"ansible/roles/common/tasks/main.yml":   "- name: Packages\n  apt:\n    name: vim\n",
+		"ansible/roles/nginx/tasks/main.yml":    "- name: Install\n  apt:\n    name: nginx\n",
+		"ansible/roles/nginx/meta/main.yml":     "dependencies:\n  - role: certs\n",
+		"ansible/roles/certs/tasks/main.yml":    "- name: Certs\n  debug: {}\n",
+		"ansible/roles/postgres/tasks/main.yml": "- name: Install\n  apt:\n    name: postgresql\n",
+		"ansible/roles/legacy/tasks/main.yml":   "- name: Old\n  debug: {}\n",
+		"ansible/inventory/production":          "[webservers]\nweb1\nweb2\n\n[dbservers]\ndb1\n\n[prod:children]\nwebservers\ndbservers\n",
+		"ansible/inventory/staging.yml":         "all:\n  children:\n    webservers:\n      hosts:\n        staging-web1:\n",
+		"ansible/group_vars/webservers.yml":     "http_port: 80\nserver_name: example.com\n",
+		"ansible/host_vars/web1/main.yml":       "ansible_host: 10.0.0.1\n",
+		"ansible/.github/workflows/lint.yml":    "on: push\njobs: {}\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.Ansible == nil {
+		t.Fatalf("Expected an Ansible project")
+	}
+	for _, role := range result.Ansible.Roles {
+		if role.Used != (role.Name != "legacy") {
+			t.Errorf("Expected only the legacy role to be unused, got %s used: %v", role.Name, role.Used)
+		}
+	}
+
+	project := strings.ReplaceAll(result.AnsibleProject(), tmpDir+string(filepath.Separator), "")
+	project = filepath.ToSlash(project)
+	for _, want := range []string{
+		"- ansible/site.yml\n  - play \"Web servers\" on webservers: 2 tasks, roles common, nginx\n  - imports ansible/db.yml\n",
+		"- ansible/db.yml\n  - play on dbservers: 1 tasks, roles postgres\n",
+		"- certs (tasks): applied to webservers by ansible/site.yml\n",
+		"- nginx (meta, tasks), depends on certs: applied to webservers by ansible/site.yml\n",
+		"- legacy (tasks): not applied by any playbook\n",
+		"- ansible/inventory/production: dbservers (1 host), prod (children dbservers, webservers), webservers (2 hosts)\n",
+		"- ansible/inventory/staging.yml: all (children webservers), webservers (1 host)\n",
+		"- group webservers (ansible/group_vars/webservers.yml): http_port, server_name\n",
+		"- host web1 (ansible/host_vars/web1/main.yml): ansible_host\n",
+	} {
+		if !strings.Contains(project, want) {
+			t.Errorf("Expected %q in the project structure, got\n%s", want, project)
+		}
+	}
+	if strings.Contains(project, "pool.ntp.org") || strings.Contains(project, "example.com") {
+		t.Errorf("Expected no variable values in the project structure, got\n%s", project)
+	}
+
+	client.pruneAnsible = true
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var skipped []string
+	for _, s := range result.Skipped {
+		if s.Reason == "not used by any playbook" {
+			skipped = append(skipped, filepath.ToSlash(s.Path))
+		}
+	}
+	expected := "ansible/.github/workflows/lint.yml, ansible/roles/legacy/tasks/main.yml"
+	if got := strings.Join(skipped, ", "); got != expected {
+		t.Errorf("Expected %s to be left out, got %s", expected, got)
+	}
+	if len(result.Section(SectionAnsible)) != 13 {
+		t.Errorf("Expected 13 Ansible files to be kept, got %d", len(result.Section(SectionAnsible)))
+	}
+}
@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SectionAnsibleCheck holds the output of ansible-playbook --check --diff,
+// the Ansible analog of a Terraform plan.
+const SectionAnsibleCheck = "ansible check"
+
+// ansibleCheckNames are the names a saved check mode run is looked up by in
+// each Ansible root.
+var ansibleCheckNames = []string{"ansible-check.txt", "ansible-check.log"}
+
+// ansiEscape matches the color codes ansible-playbook writes to a terminal.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// ansibleCheckHeader matches the header of a play, task, or handler, or the
+// recap, of ansible-playbook's output.
+var ansibleCheckHeader = regexp.MustCompile(`^(PLAY \[|TASK \[|RUNNING HANDLER \[|PLAY RECAP)`)
+
+// ansibleCheckFiles returns the check mode runs to include: the check files
+// if set, and otherwise the first file named one of ansibleCheckNames in
+// each Ansible root.
+func (c *AIClient) ansibleCheckFiles() []string {
+	if len(c.ansibleCheckPaths) > 0 {
+		return c.layoutPaths(c.ansibleCheckPaths, nil)
+	}
+	var checks []string
+	for _, root := range c.ansibleRoots() {
+		for _, name := range ansibleCheckNames {
+			if info, err := os.Stat(filepath.Join(root, name)); err == nil && !info.IsDir() {
+				checks = append(checks, filepath.Join(root, name))
+				break
+			}
+		}
+	}
+	return checks
+}
+
+// addAnsibleCheck adds the output of ansible-playbook --check --diff saved
+// at path to result, condensed to the tasks that would change or fail.
+func (c *AIClient) addAnsibleCheck(result *ScanResult, path string) {
+	content, err := c.extractFileContent(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			result.skip(path, fmt.Sprintf("unreadable: %v", err))
+		}
+		return
+	}
+	condensed, ok := condenseAnsibleCheck(content)
+	if !ok {
+		result.skip(path, "not the output of ansible-playbook")
+		fmt.Fprintf(c.messages(), "Warning: %s is left out since it is not the output of ansible-playbook\n", path)
+		return
+	}
+	addOutput(result, SectionAnsibleCheck, path, condensed)
+	result.Files[len(result.Files)-1].Language = "diff"
+}
+
+// condenseAnsibleCheck returns the plays of the output of ansible-playbook
+// with only the tasks and handlers that would change or fail a host, with
+// their diffs, followed by the recap and the number of tasks left out. It
+// reports false if content is not the output of ansible-playbook.
+func condenseAnsibleCheck(content string) (string, bool) {
+	var out strings.Builder
+	var block []string
+	var unchanged int
+	found := false
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		if strings.HasPrefix(block[0], "TASK") || strings.HasPrefix(block[0], "RUNNING HANDLER") {
+			if !ansibleTaskChanged(block[1:]) {
+				unchanged++
+				block = nil
+				return
+			}
+		}
+		for _, line := range block {
+			out.WriteString(line)
+			out.WriteString("\n")
+		}
+		out.WriteString("\n")
+		block = nil
+	}
+	for _, line := range strings.Split(ansiEscape.ReplaceAllString(content, ""), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if ansibleCheckHeader.MatchString(line) {
+			flush()
+			found = true
+			line = strings.TrimRight(strings.TrimRight(line, " *"), " ")
+		} else if len(block) == 0 || strings.TrimSpace(line) == "" {
+			continue
+		}
+		block = append(block, line)
+	}
+	flush()
+	if !found {
+		return "", false
+	}
+	if unchanged > 0 {
+		fmt.Fprintf(&out, "%d tasks without changes are left out.\n", unchanged)
+	}
+	return out.String(), true
+}
+
+// ansibleTaskChanged reports whether the output lines of a task show that
+// it would change or fail a host, or hold a diff.
+func ansibleTaskChanged(lines []string) bool {
+	for _, line := range lines {
+		for _, prefix := range []string{"changed:", "failed:", "fatal:", "--- before", "+++ after"} {
+			if strings.HasPrefix(line, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testAnsibleCheck = "\x1b[0;32m\nPLAY [web] *********************************************************************\n\n" +
+	"TASK [Gathering Facts] *********************************************************\nok: [web1]\n\n" +
+	"TASK [nginx : Install nginx] ***************************************************\nok: [web1]\n\n" +
+	"TASK [nginx : Write nginx.conf] ************************************************\n" +
+	"--- before: /etc/nginx/nginx.conf\n+++ after: /home/ops/.ansible/tmp/nginx.conf.j2\n@@ -1,3 +1,3 @@\n-worker_processes 1;\n+worker_processes auto;\n\n" +
+	"changed: [web1]\n\n" +
+	"RUNNING HANDLER [nginx : Restart nginx] ****************************************\nchanged: [web1]\n\n" +
+	"TASK [nginx : Start nginx] *****************************************************\nskipping: [web1]\n\n" +
+	"PLAY RECAP *********************************************************************\n" +
+	"web1                       : ok=4    changed=2    unreachable=0    failed=0    skipped=1\x1b[0m\n"
+
+func TestScanAnsibleCheck(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"ansible/site.yml":          "- hosts: web\n  roles:\n    - nginx\n",
+		"ansible/ansible-check.txt": testAnsibleCheck,
+		"checks/not-a-check.txt":    "nothing to see here\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	checks := result.Section(SectionAnsibleCheck)
+	if len(checks) != 1 || checks[0].Path != "ansible/ansible-check.txt" {
+		t.Fatalf("Expected the check mode output of the Ansible root, got %+v", checks)
+	}
+	content := checks[0].Content
+	for _, expected := range []string{
+		"PLAY [web]\n",
+		"TASK [nginx : Write nginx.conf]\n--- before: /etc/nginx/nginx.conf",
+		"+worker_processes auto;",
+		"RUNNING HANDLER [nginx : Restart nginx]\nchanged: [web1]",
+		"PLAY RECAP\nweb1",
+		"3 tasks without changes are left out.",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected %q in the check mode output, got:\n%s", expected, content)
+		}
+	}
+	for _, unexpected := range []string{"Gathering Facts", "Install nginx", "Start nginx", "\x1b[", "*****"} {
+		if strings.Contains(content, unexpected) {
+			t.Errorf("Expected no %q in the check mode output, got:\n%s", unexpected, content)
+		}
+	}
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.AnsibleCheck, "worker_processes auto") {
+		t.Errorf("Expected the check mode output in the prompt, got %q", data.AnsibleCheck)
+	}
+
+	client = &AIClient{iacPath: tmpDir, ansibleCheckPaths: []string{"checks/not-a-check.txt"}}
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if checks := result.Section(SectionAnsibleCheck); len(checks) != 0 {
+		t.Errorf("Expected no check mode output, got %+v", checks)
+	}
+	if reason := skipReason(result, "checks/not-a-check.txt"); reason != "not the output of ansible-playbook" {
+		t.Errorf("Expected the file to be skipped, got %q", reason)
+	}
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	archiveFormatVersion = 1
+	archiveManifestName  = "manifest.json"
+	archiveIndexName     = "index.json"
+)
+
+// ArchiveManifest describes the contents of an index archive.
+type ArchiveManifest struct {
+	FormatVersion int       `json:"format_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Provider      string    `json:"provider,omitempty"`
+	Model         string    `json:"model,omitempty"`
+	Chunks        int       `json:"chunks"`
+	IndexSHA256   string    `json:"index_sha256"`
+}
+
+// WriteIndexArchive writes ix to w as a portable gzip-compressed tar archive
+// holding a manifest and the index itself.
+func WriteIndexArchive(w io.Writer, ix *Index) error {
+	indexData, err := json.Marshal(ix)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(indexData)
+	manifestData, err := json.MarshalIndent(ArchiveManifest{
+		FormatVersion: archiveFormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Provider:      ix.Provider,
+		Model:         ix.Model,
+		Chunks:        len(ix.Chunks),
+		IndexSHA256:   hex.EncodeToString(sum[:]),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{archiveManifestName, manifestData},
+		{archiveIndexName, indexData},
+	} {
+		header := &tar.Header{Name: entry.name, Mode: 0600, Size: int64(len(entry.data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadIndexArchive reads an archive written by WriteIndexArchive, verifying
+// the index against the checksum in its manifest.
+func ReadIndexArchive(r io.Reader) (*Index, *ArchiveManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not an index archive: %v", err)
+	}
+	defer gz.Close()
+
+	var manifestData, indexData []byte
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read index archive: %v", err)
+		}
+		switch header.Name {
+		case archiveManifestName:
+			manifestData, err = io.ReadAll(tr)
+		case archiveIndexName:
+			indexData, err = io.ReadAll(tr)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s from index archive: %v", header.Name, err)
+		}
+	}
+	if manifestData == nil || indexData == nil {
+		return nil, nil, fmt.Errorf("index archive is missing %s or %s", archiveManifestName, archiveIndexName)
+	}
+
+	manifest := &ArchiveManifest{}
+	if err := json.Unmarshal(manifestData, manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse archive manifest: %v", err)
+	}
+	if manifest.FormatVersion > archiveFormatVersion {
+		return nil, nil, fmt.Errorf("index archive format %d is newer than this version of kado-ai supports", manifest.FormatVersion)
+	}
+	sum := sha256.Sum256(indexData)
+	if hex.EncodeToString(sum[:]) != manifest.IndexSHA256 {
+		return nil, nil, fmt.Errorf("index archive is corrupt: checksum mismatch")
+	}
+
+	ix := &Index{}
+	if err := json.Unmarshal(indexData, ix); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse index: %v", err)
+	}
+	return ix, manifest, nil
+}
+
+// ExportIndex writes the client's saved index to w as a portable archive, so
+// a teammate can pick it up without re-indexing the repository.
+func (c *AIClient) ExportIndex(w io.Writer) error {
+	ix, err := LoadIndex(c.IndexPath())
+	if err != nil {
+		return fmt.Errorf("failed to load index: %v", err)
+	}
+	return WriteIndexArchive(w, ix)
+}
+
+// ImportIndex reads an archive written by ExportIndex and saves the index as
+// the client's own. An index built with a different embedding provider or
+// model than the client's is rejected, since its vectors would not be
+// comparable with the client's queries.
+func (c *AIClient) ImportIndex(r io.Reader) (*Index, error) {
+	ix, manifest, err := ReadIndexArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	if provider, model := describeEmbedder(c.embedder); provider != "" && (provider != manifest.Provider || model != manifest.Model) {
+		return nil, fmt.Errorf("index was built with %s/%s but this client embeds with %s/%s", manifest.Provider, manifest.Model, provider, model)
+	}
+	if err := ix.Save(c.IndexPath()); err != nil {
+		return nil, fmt.Errorf("failed to save imported index: %v", err)
+	}
+	return ix, nil
+}
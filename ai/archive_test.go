@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIndexArchiveRoundTrip(t *testing.T) {
+	srcDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(srcDir)
+	dstDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(dstDir)
+
+	index := &Index{
+		Provider: EmbeddingOllama,
+		Model:    "nomic-embed-text",
+		Chunks:   []IndexChunk{{Path: "terraform/main.tf", StartLine: 1, EndLine: 3, Text: "resource {}", Vector: []float32{1, 0}}},
+	}
+	source := &AIClient{iacPath: srcDir}
+	if err := index.Save(source.IndexPath()); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	var archive bytes.Buffer
+	if err := source.ExportIndex(&archive); err != nil {
+		t.Fatalf("ExportIndex failed: %v", err)
+	}
+	exported := archive.Bytes()
+
+	target := &AIClient{iacPath: dstDir, embedder: NewOllamaEmbedder("", "")}
+	imported, err := target.ImportIndex(bytes.NewReader(exported))
+	if err != nil {
+		t.Fatalf("ImportIndex failed: %v", err)
+	}
+	if len(imported.Chunks) != 1 || imported.Chunks[0].Path != "terraform/main.tf" {
+		t.Errorf("Expected the exported chunk, got %+v", imported.Chunks)
+	}
+	if _, err := LoadIndex(target.IndexPath()); err != nil {
+		t.Errorf("Expected the imported index to be saved, got %v", err)
+	}
+
+	mismatched := &AIClient{iacPath: dstDir, embedder: NewOpenAIEmbedder("sk-test", "")}
+	if _, err := mismatched.ImportIndex(bytes.NewReader(exported)); err == nil || !strings.Contains(err.Error(), "embeds with openai") {
+		t.Errorf("Expected an embedder mismatch error, got %v", err)
+	}
+
+	if _, _, err := ReadIndexArchive(strings.NewReader("not an archive")); err == nil {
+		t.Errorf("Expected an error for a non-archive input")
+	}
+}
@@ -0,0 +1,206 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SectionAWSAccount holds the account-level settings read from the AWS
+// account.
+const SectionAWSAccount = "aws account"
+
+// awsCheck is a read-only AWS CLI call and the rendering of its output as a
+// line of the account settings.
+type awsCheck struct {
+	name   string
+	args   []string
+	render func(out []byte) string
+}
+
+// awsChecks are the account-level settings looked up for account hints.
+// They only describe or list, never change anything, and their rendering
+// leaves out identifiers such as the account ID and ARNs.
+var awsChecks = []awsCheck{
+	{"GuardDuty", []string{"guardduty", "list-detectors"}, func(out []byte) string {
+		var body struct {
+			DetectorIds []string `json:"DetectorIds"`
+		}
+		if json.Unmarshal(out, &body) != nil {
+			return ""
+		}
+		if len(body.DetectorIds) == 0 {
+			return "not enabled"
+		}
+		return "enabled"
+	}},
+	{"CloudTrail", []string{"cloudtrail", "describe-trails"}, func(out []byte) string {
+		var body struct {
+			TrailList []struct {
+				IsMultiRegionTrail       bool   `json:"IsMultiRegionTrail"`
+				LogFileValidationEnabled bool   `json:"LogFileValidationEnabled"`
+				KmsKeyID                 string `json:"KmsKeyId"`
+			} `json:"trailList"`
+		}
+		if json.Unmarshal(out, &body) != nil {
+			return ""
+		}
+		if len(body.TrailList) == 0 {
+			return "no trails"
+		}
+		var multiRegion, validated, encrypted int
+		for _, trail := range body.TrailList {
+			if trail.IsMultiRegionTrail {
+				multiRegion++
+			}
+			if trail.LogFileValidationEnabled {
+				validated++
+			}
+			if trail.KmsKeyID != "" {
+				encrypted++
+			}
+		}
+		return fmt.Sprintf("%d trails, %d multi-region, %d with log file validation, %d encrypted with KMS", len(body.TrailList), multiRegion, validated, encrypted)
+	}},
+	{"AWS Config", []string{"configservice", "describe-configuration-recorder-status"}, func(out []byte) string {
+		var body struct {
+			Recorders []struct {
+				Recording bool `json:"recording"`
+			} `json:"ConfigurationRecordersStatus"`
+		}
+		if json.Unmarshal(out, &body) != nil {
+			return ""
+		}
+		for _, recorder := range body.Recorders {
+			if recorder.Recording {
+				return "recording"
+			}
+		}
+		if len(body.Recorders) > 0 {
+			return "recorder configured but not recording"
+		}
+		return "no recorder"
+	}},
+	{"Security Hub", []string{"securityhub", "describe-hub"}, func(out []byte) string {
+		return "enabled"
+	}},
+	{"IAM Access Analyzer", []string{"accessanalyzer", "list-analyzers"}, func(out []byte) string {
+		var body struct {
+			Analyzers []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"analyzers"`
+		}
+		if json.Unmarshal(out, &body) != nil {
+			return ""
+		}
+		var analyzers []string
+		for _, analyzer := range body.Analyzers {
+			analyzers = append(analyzers, strings.ToLower(analyzer.Type+" "+analyzer.Status))
+		}
+		if len(analyzers) == 0 {
+			return "no analyzers"
+		}
+		return strings.Join(analyzers, ", ")
+	}},
+	{"IAM password policy", []string{"iam", "get-account-password-policy"}, func(out []byte) string {
+		var body struct {
+			PasswordPolicy struct {
+				MinimumPasswordLength int  `json:"MinimumPasswordLength"`
+				RequireSymbols        bool `json:"RequireSymbols"`
+				RequireNumbers        bool `json:"RequireNumbers"`
+				MaxPasswordAge        int  `json:"MaxPasswordAge"`
+				PasswordReusePrevent  int  `json:"PasswordReusePrevention"`
+			} `json:"PasswordPolicy"`
+		}
+		if json.Unmarshal(out, &body) != nil {
+			return ""
+		}
+		p := body.PasswordPolicy
+		return fmt.Sprintf("minimum length %d, symbols required: %t, numbers required: %t, maximum age %d days, %d previous passwords prevented",
+			p.MinimumPasswordLength, p.RequireSymbols, p.RequireNumbers, p.MaxPasswordAge, p.PasswordReusePrevent)
+	}},
+	{"EBS encryption by default", []string{"ec2", "get-ebs-encryption-by-default"}, func(out []byte) string {
+		var body struct {
+			Enabled bool `json:"EbsEncryptionByDefault"`
+		}
+		if json.Unmarshal(out, &body) != nil {
+			return ""
+		}
+		if body.Enabled {
+			return "enabled"
+		}
+		return "disabled"
+	}},
+}
+
+// scanAWSAccount adds the account-level settings of the AWS account of the
+// AWS CLI's credentials to result, in the CLI's default region: whether
+// GuardDuty, CloudTrail, AWS Config, Security Hub, and IAM Access Analyzer
+// are set up, the IAM password policy, EBS encryption by default, and S3
+// Block Public Access. Every lookup is read-only, and a setting that cannot
+// be read is reported as such.
+func (c *AIClient) scanAWSAccount(ctx context.Context, result *ScanResult) {
+	if _, err := lookPath("aws"); err != nil {
+		fmt.Fprintf(c.messages(), "Warning: the AWS account is not read since aws is not on the PATH\n")
+		return
+	}
+	out, err := runCommand(ctx, c.iacPath, nil, "aws", "sts", "get-caller-identity", "--output", "json")
+	var identity struct {
+		Account string `json:"Account"`
+	}
+	if err == nil {
+		err = json.Unmarshal(out, &identity)
+	}
+	if err != nil || identity.Account == "" {
+		fmt.Fprintf(c.messages(), "Warning: the AWS account is not read since its credentials could not be verified: %v\n", err)
+		return
+	}
+
+	var settings strings.Builder
+	for _, check := range awsChecks {
+		fmt.Fprintf(&settings, "- %s: %s\n", check.name, c.awsLookup(ctx, check))
+	}
+	blockPublicAccess := awsCheck{"S3 Block Public Access", []string{"s3control", "get-public-access-block", "--account-id", identity.Account}, func(out []byte) string {
+		var body struct {
+			Configuration map[string]bool `json:"PublicAccessBlockConfiguration"`
+		}
+		if json.Unmarshal(out, &body) != nil {
+			return ""
+		}
+		var off []string
+		for _, setting := range []string{"BlockPublicAcls", "IgnorePublicAcls", "BlockPublicPolicy", "RestrictPublicBuckets"} {
+			if !body.Configuration[setting] {
+				off = append(off, setting)
+			}
+		}
+		if len(off) == 0 {
+			return "all four settings on for the account"
+		}
+		return "off for the account: " + strings.Join(off, ", ")
+	}}
+	fmt.Fprintf(&settings, "- %s: %s\n", blockPublicAccess.name, c.awsLookup(ctx, blockPublicAccess))
+	addOutput(result, SectionAWSAccount, filepath.Join(c.iacPath, "aws-account.txt"), settings.String())
+}
+
+// awsLookup runs check and renders its output, or reports that the setting
+// could not be read, such as when it is not enabled or not permitted.
+func (c *AIClient) awsLookup(ctx context.Context, check awsCheck) string {
+	out, err := runCommand(ctx, c.iacPath, nil, "aws", append(append([]string{}, check.args...), "--output", "json")...)
+	if err != nil {
+		if strings.Contains(err.Error(), "NoSuchPublicAccessBlockConfiguration") {
+			return "not configured for the account"
+		}
+		if strings.Contains(err.Error(), "NoSuchEntity") || strings.Contains(err.Error(), "InvalidAccessException") {
+			return "not configured"
+		}
+		fmt.Fprintf(c.messages(), "Warning: failed to read the %s settings of the AWS account: %v\n", check.name, err)
+		return "could not be read"
+	}
+	if rendered := check.render(out); rendered != "" {
+		return rendered
+	}
+	return "could not be read"
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestScanAWSAccount(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	outputs := map[string]string{
+		"sts":            `{"Account": "123456789012", "Arn": "arn:aws:iam::123456789012:user/ops"}`,
+		"guardduty":      `{"DetectorIds": []}`,
+		"cloudtrail":     `{"trailList": [{"Name": "org", "IsMultiRegionTrail": true, "LogFileValidationEnabled": true, "TrailARN": "arn:aws:cloudtrail:us-east-1:123456789012:trail/org"}]}`,
+		"configservice":  `{"ConfigurationRecordersStatus": [{"name": "default", "recording": true}]}`,
+		"accessanalyzer": `{"analyzers": [{"type": "ACCOUNT", "status": "ACTIVE"}]}`,
+		"ec2":            `{"EbsEncryptionByDefault": false}`,
+		"s3control":      `{"PublicAccessBlockConfiguration": {"BlockPublicAcls": true, "IgnorePublicAcls": true, "BlockPublicPolicy": false, "RestrictPublicBuckets": true}}`,
+	}
+	var calls [][]string
+	originalRunCommand := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		switch args[0] {
+		case "securityhub":
+			return nil, fmt.Errorf("An error occurred (InvalidAccessException) when calling the DescribeHub operation")
+		case "iam":
+			return nil, fmt.Errorf("An error occurred (AccessDenied) when calling the GetAccountPasswordPolicy operation")
+		}
+		return []byte(outputs[args[0]]), nil
+	}
+	defer func() { runCommand = originalRunCommand }()
+	originalLookPath := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { lookPath = originalLookPath }()
+
+	client := &AIClient{iacPath: tmpDir, awsAccountHints: true}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	for _, call := range calls {
+		if call[0] != "aws" {
+			continue
+		}
+		operation := call[2]
+		if !strings.HasPrefix(operation, "get-") && !strings.HasPrefix(operation, "describe-") && !strings.HasPrefix(operation, "list-") {
+			t.Errorf("Expected only read-only aws calls, got %v", call)
+		}
+	}
+
+	settings := result.Section(SectionAWSAccount)
+	if len(settings) != 1 {
+		t.Fatalf("Expected the account settings, got %+v", settings)
+	}
+	content := settings[0].Content
+	for _, expected := range []string{
+		"- GuardDuty: not enabled",
+		"- CloudTrail: 1 trails, 1 multi-region, 1 with log file validation, 0 encrypted with KMS",
+		"- AWS Config: recording",
+		"- Security Hub: not configured",
+		"- IAM Access Analyzer: account active",
+		"- IAM password policy: could not be read",
+		"- EBS encryption by default: disabled",
+		"- S3 Block Public Access: off for the account: BlockPublicPolicy",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected %q in the account settings, got:\n%s", expected, content)
+		}
+	}
+	if strings.Contains(content, "123456789012") {
+		t.Errorf("Expected no account ID in the account settings, got:\n%s", content)
+	}
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.AWSAccount, "GuardDuty: not enabled") {
+		t.Errorf("Expected the account settings in the prompt, got %q", data.AWSAccount)
+	}
+}
@@ -0,0 +1,429 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+// BatchStatus is the provider-independent state of a batch job.
+type BatchStatus string
+
+const (
+	BatchInProgress BatchStatus = "in_progress"
+	BatchCompleted  BatchStatus = "completed"
+	BatchFailed     BatchStatus = "failed"
+	BatchCancelled  BatchStatus = "cancelled"
+	BatchExpired    BatchStatus = "expired"
+)
+
+// BatchRequest is one input of a batch, identified by a caller-chosen ID
+// that is echoed back in its BatchResult. Inputs are sanitized before they
+// are sent, as prompts are.
+type BatchRequest struct {
+	ID    string
+	Input string
+}
+
+// BatchJob is a batch submitted to the provider's asynchronous batch API. It
+// can be serialized and polled or collected later by another process.
+type BatchJob struct {
+	ID       string      `json:"id"`
+	Provider string      `json:"provider"`
+	Status   BatchStatus `json:"status"`
+	// ResultsRef locates the results once the batch has completed: the
+	// results URL for Anthropic or the output file ID for OpenAI.
+	ResultsRef string `json:"results_ref,omitempty"`
+}
+
+// Done reports whether the job has stopped processing.
+func (j *BatchJob) Done() bool {
+	return j.Status != BatchInProgress
+}
+
+// BatchResult is the outcome of one BatchRequest.
+type BatchResult struct {
+	ID    string
+	Text  string
+	Error string
+}
+
+// batchBackend implements the batch API of one provider.
+type batchBackend interface {
+	submit(ctx context.Context, c *AIClient, requests []BatchRequest) (*BatchJob, error)
+	poll(ctx context.Context, c *AIClient, job *BatchJob) error
+	collect(ctx context.Context, c *AIClient, job *BatchJob) ([]BatchResult, error)
+}
+
+var batchBackends = map[string]batchBackend{
+	"chatgpt":            openAIBatches{},
+	"anthropic_messages": anthropicBatches{},
+}
+
+func (c *AIClient) batchBackend(provider string) (batchBackend, error) {
+	backend, ok := batchBackends[provider]
+	if !ok {
+		return nil, fmt.Errorf("batches are not supported for AI client: %s", provider)
+	}
+	return backend, nil
+}
+
+// SubmitBatch sanitizes requests, asks for consent as RunAI does, and
+// submits them to the provider's batch API under the client's base URL,
+// which processes them asynchronously at a lower cost. It returns the job
+// to poll. Local servers, such as Ollama, have no batch API, so batches
+// are refused when the base URL is on this machine.
+func (c *AIClient) SubmitBatch(ctx context.Context, requests []BatchRequest) (*BatchJob, error) {
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no batch requests given")
+	}
+	c.mu.RLock()
+	_, err := c.batchBackend(c.clientType)
+	if err == nil && c.destination() == DestinationLocal {
+		err = fmt.Errorf("batches are not supported by the local server at %s", c.baseURL)
+	}
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Sanitizing takes the lock itself.
+	sanitized := make([]BatchRequest, len(requests))
+	var redactions []sanitize.Redaction
+	for i, request := range requests {
+		input, found := c.redact(request.Input)
+		sanitized[i] = BatchRequest{ID: request.ID, Input: input}
+		redactions = append(redactions, found...)
+	}
+	sanitize.Summarize(c.messages(), redactions)
+	if err := c.blockSecrets(redactions); err != nil {
+		return nil, err
+	}
+	if !c.consent(redactions) {
+		return nil, fmt.Errorf("operation cancelled by user")
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	backend, err := c.batchBackend(c.clientType)
+	if err != nil {
+		return nil, err
+	}
+	return backend.submit(ctx, c, sanitized)
+}
+
+// PollBatch refreshes the status of job.
+func (c *AIClient) PollBatch(ctx context.Context, job *BatchJob) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	backend, err := c.batchBackend(job.Provider)
+	if err != nil {
+		return err
+	}
+	return backend.poll(ctx, c, job)
+}
+
+// CollectBatch downloads the results of a completed job.
+func (c *AIClient) CollectBatch(ctx context.Context, job *BatchJob) ([]BatchResult, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if job.Status != BatchCompleted {
+		return nil, fmt.Errorf("batch %s is %s, not completed", job.ID, job.Status)
+	}
+	backend, err := c.batchBackend(job.Provider)
+	if err != nil {
+		return nil, err
+	}
+	return backend.collect(ctx, c, job)
+}
+
+// batchCall performs an authenticated request against the provider API and
+// decodes a JSON response into out, unless out is nil.
+func (c *AIClient) batchCall(ctx context.Context, method, url, contentType string, body io.Reader, out interface{}) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.setAuthHeaders(req, c.pickKey())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, url, resp.Status, bytes.TrimSpace(data))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("failed to parse batch response: %v", err)
+		}
+	}
+	return data, nil
+}
+
+// eachJSONLine calls fn with every non-empty line of JSONL data.
+func eachJSONLine(data []byte, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// anthropicBatches implements the Anthropic Message Batches API.
+type anthropicBatches struct{}
+
+const anthropicAPIURL = "https://api.anthropic.com/v1"
+
+type anthropicBatch struct {
+	ID               string `json:"id"`
+	ProcessingStatus string `json:"processing_status"`
+	ResultsURL       string `json:"results_url"`
+	RequestCounts    struct {
+		Processing int `json:"processing"`
+		Succeeded  int `json:"succeeded"`
+		Errored    int `json:"errored"`
+		Canceled   int `json:"canceled"`
+		Expired    int `json:"expired"`
+	} `json:"request_counts"`
+}
+
+func (b *anthropicBatch) job() *BatchJob {
+	job := &BatchJob{ID: b.ID, Provider: "anthropic_messages", Status: BatchInProgress, ResultsRef: b.ResultsURL}
+	if b.ProcessingStatus == "ended" {
+		job.Status = BatchCompleted
+	}
+	return job
+}
+
+func (anthropicBatches) submit(ctx context.Context, c *AIClient, requests []BatchRequest) (*BatchJob, error) {
+	var items []map[string]interface{}
+	for _, request := range requests {
+		_, params, err := c.requestBody(userTurn(request.Input), false)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, map[string]interface{}{"custom_id": request.ID, "params": params})
+	}
+	payload, err := json.Marshal(map[string]interface{}{"requests": items})
+	if err != nil {
+		return nil, err
+	}
+
+	var batch anthropicBatch
+	if _, err := c.batchCall(ctx, "POST", c.endpoint(anthropicAPIURL, "/messages/batches"), "application/json", bytes.NewReader(payload), &batch); err != nil {
+		return nil, err
+	}
+	return batch.job(), nil
+}
+
+func (anthropicBatches) poll(ctx context.Context, c *AIClient, job *BatchJob) error {
+	var batch anthropicBatch
+	if _, err := c.batchCall(ctx, "GET", c.endpoint(anthropicAPIURL, "/messages/batches/"+job.ID), "", nil, &batch); err != nil {
+		return err
+	}
+	*job = *batch.job()
+	return nil
+}
+
+func (anthropicBatches) collect(ctx context.Context, c *AIClient, job *BatchJob) ([]BatchResult, error) {
+	// The results are read from the base URL rather than from ResultsRef,
+	// so that the API key only goes where the client sends requests.
+	data, err := c.batchCall(ctx, "GET", c.endpoint(anthropicAPIURL, "/messages/batches/"+job.ID+"/results"), "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []BatchResult
+	err = eachJSONLine(data, func(line []byte) error {
+		var item struct {
+			CustomID string `json:"custom_id"`
+			Result   struct {
+				Type    string          `json:"type"`
+				Message json.RawMessage `json:"message"`
+				Error   struct {
+					Error struct {
+						Message string `json:"message"`
+					} `json:"error"`
+				} `json:"error"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return fmt.Errorf("failed to parse batch result: %v", err)
+		}
+		result := BatchResult{ID: item.CustomID}
+		switch item.Result.Type {
+		case "succeeded":
+			text, err := extractText("anthropic_messages", item.Result.Message)
+			if err != nil {
+				result.Error = err.Error()
+			}
+			result.Text = text
+		case "errored":
+			result.Error = item.Result.Error.Error.Message
+		default:
+			result.Error = "request " + item.Result.Type
+		}
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+// openAIBatches implements the OpenAI Batch API, which takes its input as an
+// uploaded JSONL file and returns its output the same way.
+type openAIBatches struct{}
+
+const openAIAPIURL = "https://api.openai.com/v1"
+
+type openAIBatch struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	OutputFileID string `json:"output_file_id"`
+}
+
+func (b *openAIBatch) job() *BatchJob {
+	job := &BatchJob{ID: b.ID, Provider: "chatgpt", ResultsRef: b.OutputFileID}
+	switch b.Status {
+	case "completed":
+		job.Status = BatchCompleted
+	case "failed":
+		job.Status = BatchFailed
+	case "expired":
+		job.Status = BatchExpired
+	case "cancelling", "cancelled":
+		job.Status = BatchCancelled
+	default:
+		job.Status = BatchInProgress
+	}
+	return job
+}
+
+func (openAIBatches) submit(ctx context.Context, c *AIClient, requests []BatchRequest) (*BatchJob, error) {
+	var input bytes.Buffer
+	encoder := json.NewEncoder(&input)
+	for _, request := range requests {
+		_, body, err := c.requestBody(userTurn(request.Input), false)
+		if err != nil {
+			return nil, err
+		}
+		line := map[string]interface{}{
+			"custom_id": request.ID,
+			"method":    "POST",
+			"url":       "/v1/chat/completions",
+			"body":      body,
+		}
+		if err := encoder.Encode(line); err != nil {
+			return nil, err
+		}
+	}
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	if err := writer.WriteField("purpose", "batch"); err != nil {
+		return nil, err
+	}
+	part, err := writer.CreateFormFile("file", "kado-ai-batch.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(input.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		ID string `json:"id"`
+	}
+	if _, err := c.batchCall(ctx, "POST", c.endpoint(openAIAPIURL, "/files"), writer.FormDataContentType(), &form, &file); err != nil {
+		return nil, fmt.Errorf("failed to upload batch input: %v", err)
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"input_file_id":     file.ID,
+		"endpoint":          "/v1/chat/completions",
+		"completion_window": "24h",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var batch openAIBatch
+	if _, err := c.batchCall(ctx, "POST", c.endpoint(openAIAPIURL, "/batches"), "application/json", bytes.NewReader(payload), &batch); err != nil {
+		return nil, err
+	}
+	return batch.job(), nil
+}
+
+func (openAIBatches) poll(ctx context.Context, c *AIClient, job *BatchJob) error {
+	var batch openAIBatch
+	if _, err := c.batchCall(ctx, "GET", c.endpoint(openAIAPIURL, "/batches/"+job.ID), "", nil, &batch); err != nil {
+		return err
+	}
+	*job = *batch.job()
+	return nil
+}
+
+func (openAIBatches) collect(ctx context.Context, c *AIClient, job *BatchJob) ([]BatchResult, error) {
+	if job.ResultsRef == "" {
+		return nil, fmt.Errorf("batch %s has no output file", job.ID)
+	}
+	data, err := c.batchCall(ctx, "GET", c.endpoint(openAIAPIURL, "/files/"+job.ResultsRef+"/content"), "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []BatchResult
+	err = eachJSONLine(data, func(line []byte) error {
+		var item struct {
+			CustomID string `json:"custom_id"`
+			Response struct {
+				StatusCode int             `json:"status_code"`
+				Body       json.RawMessage `json:"body"`
+			} `json:"response"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return fmt.Errorf("failed to parse batch result: %v", err)
+		}
+		result := BatchResult{ID: item.CustomID}
+		if item.Error != nil {
+			result.Error = item.Error.Message
+		} else if text, err := extractText("chatgpt", item.Response.Body); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Text = text
+		}
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Sections of Azure content: Bicep files and ARM templates, and the output
+// of what-if, Azure's counterpart of a Terraform plan.
+const (
+	SectionBicep       = "bicep code"
+	SectionAzureWhatIf = "azure what-if"
+)
+
+var (
+	// armSchema matches the $schema of ARM deployment templates and
+	// parameter files.
+	armSchema = regexp.MustCompile(`"\$schema"\s*:\s*"https?://schema\.management\.azure\.com/schemas/[^"]*deployment(Template|Parameters)\.json#?"`)
+	// bicepModule matches the path of a module a Bicep file deploys.
+	bicepModule = regexp.MustCompile(`(?m)^\s*module\s+\w+\s+'([^']+\.bicep)'`)
+)
+
+// isWhatIfFile reports whether name is saved what-if output, such as
+// what-if.json or main.what-if.json.
+func isWhatIfFile(name string) bool {
+	name = strings.ToLower(name)
+	return name == "what-if.json" || name == "whatif.json" || strings.HasSuffix(name, ".what-if.json") || strings.HasSuffix(name, ".whatif.json")
+}
+
+// azureTemplates are the Bicep files and ARM templates found under the IaC
+// path, and which of them are parameter files.
+type azureTemplates struct {
+	files      []string
+	parameters map[string]bool
+}
+
+// scanBicep adds the Bicep files, ARM templates and parameter files, and
+// saved what-if output under the IaC path to result. With a resource group
+// set, it also runs what-if for every entry template: a Bicep file that no
+// other one deploys as a module, or an ARM template.
+func (c *AIClient) scanBicep(ctx context.Context, result *ScanResult) error {
+	templates := azureTemplates{parameters: map[string]bool{}}
+	var whatIf []string
+	ignore := c.ignoreRules()
+	err := c.walk(result, c.iacPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != c.iacPath && (discoverySkipDirs[info.Name()] || ignore.ignored(c.iacPath, path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := info.Name()
+		if ignore.ignored(c.iacPath, path, false) {
+			return nil
+		}
+		switch {
+		case isWhatIfFile(name):
+			whatIf = append(whatIf, path)
+		case strings.HasSuffix(name, ".bicep"):
+			templates.files = append(templates.files, path)
+		case strings.HasSuffix(name, ".bicepparam"):
+			templates.files = append(templates.files, path)
+			templates.parameters[path] = true
+		case strings.HasSuffix(name, ".json"):
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			if m := armSchema.FindSubmatch(content); m != nil {
+				templates.files = append(templates.files, path)
+				templates.parameters[path] = string(m[1]) == "Parameters"
+			}
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to look for Bicep files and ARM templates: %v", err)
+	}
+
+	for _, path := range templates.files {
+		c.addFile(result, SectionBicep, path)
+	}
+	for _, path := range whatIf {
+		c.addFile(result, SectionAzureWhatIf, path)
+	}
+
+	if c.azureResourceGroup == "" {
+		return nil
+	}
+	for _, template := range templates.entries() {
+		args := []string{"deployment", "group", "what-if", "--resource-group", c.azureResourceGroup,
+			"--template-file", filepath.Base(template), "--no-pretty-print", "--output", "json"}
+		if parameters := templates.parameterFile(template); parameters != "" {
+			args = append(args, "--parameters", filepath.Base(parameters))
+		}
+		out, err := runCommand(ctx, filepath.Dir(template), nil, "az", args...)
+		if err != nil {
+			fmt.Fprintf(c.messages(), "Warning: failed to run what-if for %s: %v\n", template, err)
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(template), filepath.Ext(template))
+		addOutput(result, SectionAzureWhatIf, filepath.Join(filepath.Dir(template), name+".what-if.json"), string(out))
+	}
+	return nil
+}
+
+// entries returns the templates to deploy: the Bicep files no other Bicep
+// file uses as a module, and the ARM templates.
+func (t azureTemplates) entries() []string {
+	modules := map[string]bool{}
+	for _, path := range t.files {
+		if !strings.HasSuffix(path, ".bicep") {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, m := range bicepModule.FindAllStringSubmatch(string(content), -1) {
+			modules[filepath.Join(filepath.Dir(path), filepath.FromSlash(m[1]))] = true
+		}
+	}
+	var entries []string
+	for _, path := range t.files {
+		if !t.parameters[path] && !modules[path] {
+			entries = append(entries, path)
+		}
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// parameterFile returns the parameter file next to template, by the naming
+// conventions main.bicepparam, main.parameters.json, and
+// azuredeploy.parameters.json, or the empty string if there is none.
+func (t azureTemplates) parameterFile(template string) string {
+	base := strings.TrimSuffix(template, filepath.Ext(template))
+	for _, candidate := range []string{base + ".bicepparam", base + ".parameters.json"} {
+		if t.parameters[candidate] {
+			return candidate
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanBicep(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"azure/main.bicep":                "module storage './modules/storage.bicep' = {\n  name: 'storage'\n}\n",
+		"azure/main.bicepparam":           "using './main.bicep'\nparam location = 'westeurope'\n",
+		"azure/modules/storage.bicep":     "resource sa 'Microsoft.Storage/storageAccounts@2023-01-01' = {}\n",
+		"azure/what-if.json":              `{"changes":[]}`,
+		"arm/azuredeploy.json":            `{"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#", "resources": []}`,
+		"arm/azuredeploy.parameters.json": `{"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentParameters.json#", "parameters": {}}`,
+		"arm/package.json":                `{"name": "not-a-template"}`,
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var files []string
+	for _, f := range result.Section(SectionBicep) {
+		files = append(files, filepath.ToSlash(f.Path))
+	}
+	expected := "arm/azuredeploy.json,arm/azuredeploy.parameters.json,azure/main.bicep,azure/main.bicepparam,azure/modules/storage.bicep"
+	if got := strings.Join(files, ","); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	if whatIf := result.Section(SectionAzureWhatIf); len(whatIf) != 1 || whatIf[0].Path != filepath.Join("azure", "what-if.json") {
+		t.Errorf("Expected the saved what-if output, got %+v", whatIf)
+	}
+
+	var calls []string
+	original := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		rel, _ := filepath.Rel(tmpDir, dir)
+		calls = append(calls, filepath.ToSlash(rel)+": "+name+" "+strings.Join(args, " "))
+		return []byte(`{"changes":[{"changeType":"Modify"}]}`), nil
+	}
+	defer func() { runCommand = original }()
+
+	client.azureResourceGroup = "rg-prod"
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	expected = "arm: az deployment group what-if --resource-group rg-prod --template-file azuredeploy.json --no-pretty-print --output json --parameters azuredeploy.parameters.json; " +
+		"azure: az deployment group what-if --resource-group rg-prod --template-file main.bicep --no-pretty-print --output json --parameters main.bicepparam"
+	if got := strings.Join(calls, "; "); got != expected {
+		t.Errorf("Expected what-if to run for the entry templates only, got %s", got)
+	}
+	whatIf := result.Section(SectionAzureWhatIf)
+	if len(whatIf) != 3 || whatIf[1].Path != filepath.Join("arm", "azuredeploy.what-if.json") {
+		t.Fatalf("Expected the saved and generated what-if output, got %+v", whatIf)
+	}
+
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.BicepCode, "Microsoft.Storage/storageAccounts") || !strings.Contains(data.AzureWhatIf, "Modify") {
+		t.Errorf("Expected the Bicep code and what-if output in the prompt data, got %q and %q", data.BicepCode, data.AzureWhatIf)
+	}
+}
@@ -0,0 +1,198 @@
+package ai
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// binaryExtensions are the extensions of files that are never read, such
+// as provider bundles, compiled plans, and images, which would only waste
+// tokens. Files with a NUL byte near their start are binary too, whatever
+// their extension.
+var binaryExtensions = []string{
+	".zip", ".tar", ".gz", ".tgz", ".bz2", ".xz", ".7z", ".jar", ".war", ".whl",
+	".exe", ".dll", ".so", ".dylib", ".bin", ".pyc", ".class", ".tfplan",
+	".png", ".jpg", ".jpeg", ".gif", ".ico", ".pdf",
+}
+
+// binarySniffSize is how much of a file is checked for NUL bytes.
+const binarySniffSize = 8000
+
+// isBinary reports whether the file at path is binary: its extension is
+// one of binaryExtensions or of extra, or its content has a NUL byte.
+func isBinary(path string, content string, extra []string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	if hasExtension(name, binaryExtensions) || hasExtension(name, extra) {
+		return true
+	}
+	if len(content) > binarySniffSize {
+		content = content[:binarySniffSize]
+	}
+	return strings.IndexByte(content, 0) >= 0
+}
+
+// Limits on archives, so a large or malicious archive cannot exhaust memory:
+// larger archives are skipped, and larger entries left out.
+const (
+	maxArchiveSize      = 100 << 20
+	maxArchiveEntrySize = 1 << 20
+)
+
+// archiveExtensions are the archives scanned for IaC code.
+var archiveExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// scanArchives adds the IaC code inside the zip and tar archives under the
+// IaC path, such as bundled modules, as if the archives were directories:
+// main.tf in modules.zip is collected as modules.zip/main.tf. Terraform and
+// Rego files are Terraform code, and YAML files are Ansible content if they
+// would be outside of an archive.
+func (c *AIClient) scanArchives(result *ScanResult) {
+	ignore := c.ignoreRules()
+	c.walk(result, c.iacPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != c.iacPath && (discoverySkipDirs[info.Name()] || ignore.ignored(c.iacPath, path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !hasExtension(strings.ToLower(info.Name()), archiveExtensions) || ignore.ignored(c.iacPath, path, false) {
+			return nil
+		}
+		if info.Size() > maxArchiveSize {
+			result.skip(path, fmt.Sprintf("archive larger than %d MB", maxArchiveSize>>20))
+			return nil
+		}
+		entries, err := readArchive(path)
+		if err != nil {
+			result.skip(path, fmt.Sprintf("unreadable archive: %v", err))
+			return nil
+		}
+		for _, entry := range entries {
+			// Entries are kept inside the archive whatever their name.
+			name := filepath.Join(path, filepath.Clean(string(filepath.Separator)+filepath.FromSlash(entry.name)))
+			section := ""
+			switch {
+			case hasExtension(entry.name, terraformExtensions):
+				section = SectionTerraform
+			case hasExtension(entry.name, []string{".yml", ".yaml"}) && archivedAnsible(entry):
+				section = SectionAnsible
+			}
+			if section == "" || ignore.ignored(c.iacPath, name, false) {
+				continue
+			}
+			if entry.truncated {
+				result.skip(name, fmt.Sprintf("archive entry larger than %d MB", maxArchiveEntrySize>>20))
+				continue
+			}
+			if isBinary(entry.name, entry.content, c.binaryExtensions) {
+				result.skip(name, "binary file")
+				continue
+			}
+			addOutput(result, section, name, entry.content)
+		}
+		return nil
+	})
+}
+
+// archiveEntry is a file inside an archive. Truncated is set for entries
+// larger than maxArchiveEntrySize, whose content is not read.
+type archiveEntry struct {
+	name      string
+	content   string
+	truncated bool
+}
+
+// archivedAnsible reports whether an archived YAML file is Ansible content,
+// judged like discovered files by its directories and content.
+func archivedAnsible(entry archiveEntry) bool {
+	for _, dir := range strings.Split(strings.Trim(filepath.ToSlash(filepath.Dir(entry.name)), "/"), "/") {
+		if ansibleDirs[dir] {
+			return true
+		}
+	}
+	return ansiblePlaybook.MatchString(entry.content)
+}
+
+// archivedExtensions are the extensions of the files read from archives.
+var archivedExtensions = append(append([]string(nil), terraformExtensions...), ".yml", ".yaml")
+
+// readArchive returns the Terraform, Rego, and YAML files of the zip or tar
+// archive at path, gzip-compressed or not.
+func readArchive(path string) ([]archiveEntry, error) {
+	var entries []archiveEntry
+	read := func(name string, size int64, r io.Reader) error {
+		entry := archiveEntry{name: name, truncated: size > maxArchiveEntrySize}
+		if !entry.truncated {
+			content, err := io.ReadAll(io.LimitReader(r, maxArchiveEntrySize))
+			if err != nil {
+				return err
+			}
+			entry.content = string(content)
+		}
+		entries = append(entries, entry)
+		return nil
+	}
+
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		defer archive.Close()
+		for _, f := range archive.File {
+			if f.FileInfo().IsDir() || !hasExtension(f.Name, archivedExtensions) {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			err = read(f.Name, int64(f.UncompressedSize64), r)
+			r.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if !strings.HasSuffix(strings.ToLower(path), ".tar") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || !hasExtension(header.Name, archivedExtensions) {
+			continue
+		}
+		if err := read(header.Name, header.Size, archive); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestBinaryFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf":     "resource \"aws_vpc\" \"main\" {}\n",
+		"terraform/corrupt.tf":  "resource \x00\x01\x02",
+		"ansible/files/key.yml": "encoded: true\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir, binaryExtensions: []string{"key.yml"}}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Files) != 1 || result.Files[0].Path != filepath.Join("terraform", "main.tf") {
+		t.Errorf("Expected only main.tf to be collected, got %+v", result.Files)
+	}
+	var skipped []string
+	for _, s := range result.Skipped {
+		if s.Reason == "binary file" {
+			skipped = append(skipped, filepath.ToSlash(s.Path))
+		}
+	}
+	if got := strings.Join(skipped, ", "); got != "terraform/corrupt.tf, ansible/files/key.yml" {
+		t.Errorf("Expected the binary files to be skipped, got %s", got)
+	}
+}
+
+func TestScanArchives(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	zipFile, err := os.Create(filepath.Join(tmpDir, "modules.zip"))
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	zw := zip.NewWriter(zipFile)
+	for name, content := range map[string]string{
+		"vpc/main.tf":                "resource \"aws_vpc\" \"main\" {}\n",
+		"../escape.tf":               "resource \"aws_eip\" \"nat\" {}\n",
+		"terraform-provider-aws_5.0": "\x7fELF\x00\x00",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to write archive: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	zipFile.Close()
+
+	tarFile, err := os.Create(filepath.Join(tmpDir, "playbooks.tar.gz"))
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	gz := gzip.NewWriter(tarFile)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		"site.yml":       "- hosts: all\n  roles:\n    - nginx\n",
+		".gitlab-ci.yml": "stages: [test]\n",
+	} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	tarFile.Close()
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Files) != 0 {
+		t.Errorf("Expected archives not to be scanned by default, got %+v", result.Files)
+	}
+
+	client.archives = true
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var files []string
+	for _, f := range result.Files {
+		files = append(files, f.Section+": "+filepath.ToSlash(f.Path))
+	}
+	sort.Strings(files)
+	expected := "ansible code: playbooks.tar.gz/site.yml, terraform code: modules.zip/escape.tf, terraform code: modules.zip/vpc/main.tf"
+	if got := strings.Join(files, ", "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	if content := result.Content(SectionAnsible); !strings.Contains(content, "- hosts: all") {
+		t.Errorf("Expected the archived playbook in the prompt, got %q", content)
+	}
+}
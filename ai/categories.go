@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FindingCategory is a documented category of finding. IDs are stable:
+// they are never renumbered or reused, so suppression files, tickets, and
+// dashboards can refer to them across runs and kado-ai versions.
+type FindingCategory struct {
+	ID          string
+	Name        string
+	Description string
+}
+
+// CategoryUncategorized is assigned to findings the model could not place
+// in any other category.
+const CategoryUncategorized = "KADO-999"
+
+// FindingCategories lists every finding category, grouped by hundreds:
+// 1xx security, 2xx cost, 3xx reliability, 4xx compliance, and 5xx
+// maintainability.
+var FindingCategories = []FindingCategory{
+	{"KADO-101", "network-exposure", "Resources reachable from networks that should not reach them, such as open security groups or public endpoints."},
+	{"KADO-102", "identity-access", "Overly broad IAM roles, policies, or credentials."},
+	{"KADO-103", "encryption", "Data not encrypted at rest or in transit."},
+	{"KADO-104", "secrets-management", "Secrets stored or passed insecurely."},
+	{"KADO-105", "logging-monitoring", "Missing audit logs, flow logs, metrics, or alerts."},
+	{"KADO-201", "right-sizing", "Over-provisioned compute, storage, or databases."},
+	{"KADO-202", "idle-resources", "Resources that are unused or could be scheduled off."},
+	{"KADO-203", "pricing-model", "Workloads that would be cheaper on reserved, savings plan, or spot capacity."},
+	{"KADO-204", "data-transfer", "Avoidable data transfer, NAT gateway, or egress charges."},
+	{"KADO-205", "storage-lifecycle", "Storage without lifecycle or retention policies."},
+	{"KADO-301", "redundancy", "Single points of failure and missing multi-AZ or multi-region redundancy."},
+	{"KADO-302", "backup-recovery", "Missing or untested backups and restore procedures."},
+	{"KADO-303", "scaling", "Missing or misconfigured autoscaling, limits, and health checks."},
+	{"KADO-304", "deployment-safety", "Changes that risk downtime or data loss when applied."},
+	{"KADO-401", "compliance-control", "Gaps against a compliance framework control."},
+	{"KADO-501", "version-pinning", "Unpinned provider, module, role, or image versions."},
+	{"KADO-502", "code-structure", "Duplication, hardcoded values, and structure that hinders maintenance."},
+	{"KADO-503", "policy-coverage", "Risks not covered by the OPA policies."},
+	{CategoryUncategorized, "uncategorized", "Findings that fit no other category."},
+}
+
+// LookupFindingCategory returns the category with the given ID.
+func LookupFindingCategory(id string) (FindingCategory, bool) {
+	for _, category := range FindingCategories {
+		if category.ID == id {
+			return category, true
+		}
+	}
+	return FindingCategory{}, false
+}
+
+// categoryList describes the categories for the findings prompt.
+func categoryList() string {
+	var lines []string
+	for _, category := range FindingCategories {
+		lines = append(lines, fmt.Sprintf("- %s %s: %s", category.ID, category.Name, category.Description))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
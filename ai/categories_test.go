@@ -0,0 +1,28 @@
+package ai
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestFindingCategories(t *testing.T) {
+	idPattern := regexp.MustCompile(`^KADO-\d{3}$`)
+	seenIDs := make(map[string]bool)
+	seenNames := make(map[string]bool)
+	for _, category := range FindingCategories {
+		if !idPattern.MatchString(category.ID) {
+			t.Errorf("Category ID %q does not match KADO-xxx", category.ID)
+		}
+		if seenIDs[category.ID] || seenNames[category.Name] {
+			t.Errorf("Duplicate category %s %s", category.ID, category.Name)
+		}
+		seenIDs[category.ID] = true
+		seenNames[category.Name] = true
+		if category.Description == "" {
+			t.Errorf("Category %s has no description", category.ID)
+		}
+	}
+	if _, ok := LookupFindingCategory(CategoryUncategorized); !ok {
+		t.Errorf("Expected %s to be registered", CategoryUncategorized)
+	}
+}
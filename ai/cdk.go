@@ -0,0 +1,141 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cdkProject is an AWS CDK or CDKTF application found under the IaC path.
+type cdkProject struct {
+	dir  string
+	kind string
+}
+
+const (
+	cdkKindAWS = "cdk"
+	cdkKindTF  = "cdktf"
+)
+
+// cdkSkipDirs are never descended into while looking for CDK projects or
+// their sources: dependency trees, virtualenvs, and synthesized output.
+var cdkSkipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"cdk.out":      true,
+	"cdktf.out":    true,
+	".venv":        true,
+	"venv":         true,
+	"__pycache__":  true,
+}
+
+func findCDKProjects(root string) ([]cdkProject, error) {
+	var projects []cdkProject
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && cdkSkipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch info.Name() {
+		case "cdk.json":
+			projects = append(projects, cdkProject{dir: filepath.Dir(path), kind: cdkKindAWS})
+		case "cdktf.json":
+			projects = append(projects, cdkProject{dir: filepath.Dir(path), kind: cdkKindTF})
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return projects, err
+}
+
+// scanCDKSources collects the TypeScript and Python program files of a CDK
+// project, skipping type declarations and dependency directories.
+func (c *AIClient) scanCDKSources(result *ScanResult, dir string) {
+	ignore := c.ignoreRules()
+	c.walk(result, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != dir && cdkSkipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			if path != dir && ignore.ignored(c.iacPath, path, true) {
+				result.skip(path, ignoredReason)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ignore.ignored(c.iacPath, path, false) {
+			result.skip(path, ignoredReason)
+			return nil
+		}
+		name := info.Name()
+		if strings.HasSuffix(name, ".d.ts") || !(strings.HasSuffix(name, ".ts") || strings.HasSuffix(name, ".py")) {
+			return nil
+		}
+		c.addFile(result, SectionCDK, path)
+		return nil
+	})
+}
+
+// synthesizeCDK runs the project's synth command so the generated
+// CloudFormation or terraform JSON reflects the current source.
+func synthesizeCDK(ctx context.Context, project cdkProject) error {
+	name, args := "cdk", []string{"synth", "--quiet"}
+	if project.kind == cdkKindTF {
+		name, args = "cdktf", []string{"synth"}
+	}
+	if _, err := lookPath(name); err != nil {
+		args = append([]string{name}, args...)
+		name = "npx"
+	}
+	_, err := runCommand(ctx, project.dir, nil, name, args...)
+	return err
+}
+
+// synthesizedTemplates returns the paths of the synthesized output of a CDK
+// project: CloudFormation templates for AWS CDK and terraform JSON for CDKTF.
+func synthesizedTemplates(project cdkProject) []string {
+	var matches []string
+	if project.kind == cdkKindTF {
+		matches, _ = filepath.Glob(filepath.Join(project.dir, "cdktf.out", "stacks", "*", "cdk.tf.json"))
+	} else {
+		matches, _ = filepath.Glob(filepath.Join(project.dir, "cdk.out", "*.template.json"))
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// scanCDK adds the CDK application code and synthesized templates found
+// under the IaC path to result, synthesizing first when synth is set.
+func (c *AIClient) scanCDK(ctx context.Context, result *ScanResult, synth bool) error {
+	projects, err := findCDKProjects(c.iacPath)
+	if err != nil {
+		return fmt.Errorf("failed to look for CDK projects: %v", err)
+	}
+
+	for _, project := range projects {
+		c.scanCDKSources(result, project.dir)
+
+		if synth {
+			if err := synthesizeCDK(ctx, project); err != nil {
+				fmt.Fprintf(c.messages(), "Warning: failed to synthesize %s project %s: %v\n", project.kind, project.dir, err)
+			}
+		}
+		for _, path := range synthesizedTemplates(project) {
+			c.addFile(result, SectionCDKTemplates, path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestScanCDK(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"cdk/cdk.json":                           `{"app": "npx ts-node bin/app.ts"}`,
+		"cdk/bin/app.ts":                         "new Bucket(this, 'Logs', { versioned: false });",
+		"cdk/lib/types.d.ts":                     "declare const x: number;",
+		"cdk/node_modules/aws-cdk-lib/index.ts":  "export const vendored = true;",
+		"cdk/cdk.out/AppStack.template.json":     `{"Resources": {"LogsBucket": {"Type": "AWS::S3::Bucket"}}}`,
+		"cdktf/cdktf.json":                       `{"language": "python"}`,
+		"cdktf/main.py":                          "S3Bucket(self, 'assets')",
+		"cdktf/cdktf.out/stacks/dev/cdk.tf.json": `{"resource": {"aws_s3_bucket": {"assets": {}}}}`,
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result := &ScanResult{Root: tmpDir}
+	if err := client.scanCDK(context.Background(), result, client.cdkSynth); err != nil {
+		t.Fatalf("scanCDK failed: %v", err)
+	}
+	sources, templates := result.Content(SectionCDK), result.Content(SectionCDKTemplates)
+
+	for _, want := range []string{"bin/app.ts", "main.py"} {
+		if !strings.Contains(sources, want) {
+			t.Errorf("Expected CDK sources to include %s", want)
+		}
+	}
+	for _, unwanted := range []string{"types.d.ts", "node_modules", "cdk.out"} {
+		if strings.Contains(sources, unwanted) {
+			t.Errorf("Expected CDK sources to exclude %s", unwanted)
+		}
+	}
+	for _, want := range []string{"AWS::S3::Bucket", "aws_s3_bucket"} {
+		if !strings.Contains(templates, want) {
+			t.Errorf("Expected synthesized templates to include %s", want)
+		}
+	}
+}
+
+func TestScanCDKSynth(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"app/cdktf.json": `{"language": "typescript"}`,
+		"app/main.ts":    "new S3Bucket(this, 'assets');",
+	})
+
+	calls := fakeRunCommand(t, "")
+	client := &AIClient{iacPath: tmpDir, cdkSynth: true}
+	if err := client.scanCDK(context.Background(), &ScanResult{Root: tmpDir}, client.cdkSynth); err != nil {
+		t.Fatalf("scanCDK failed: %v", err)
+	}
+	if len(*calls) != 1 || !strings.Contains(strings.Join((*calls)[0], " "), "cdktf synth") {
+		t.Errorf("Expected a single cdktf synth invocation, got %v", *calls)
+	}
+}
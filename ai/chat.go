@@ -0,0 +1,169 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+// chatGreeting is the assistant's fixed reply to the IaC context, so the
+// first question starts a new user turn without a request to the provider.
+const chatGreeting = "I have read the infrastructure code. What would you like to know?"
+
+const chatHelp = `Commands:
+  /history       show the questions and answers so far
+  /reset         forget the questions and answers, keeping the code
+  /save [path]   save the conversation as Markdown (default kado-chat.md)
+  /help          show this help
+  /exit          end the session
+`
+
+// Chat runs an interactive session over the IaC code, reading questions
+// from in and writing answers to out until in is exhausted or /exit is
+// entered. The code is scanned, sanitized, and confirmed once, then sent as
+// the cached start of every request, so follow-up questions are cheap.
+// Lines starting with "/" are commands; /help lists them.
+func (c *AIClient) Chat(ctx context.Context, in io.Reader, out io.Writer) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	scan, err := c.scan(ctx, c.cdkSynth, c.question)
+	if err != nil {
+		return err
+	}
+	data, redactions := c.promptData(scan)
+	input, err := c.renderPrompt("chat", data)
+	if err != nil {
+		return err
+	}
+	if c.explainRedactions {
+		sanitize.Explain(out, redactions)
+	}
+	if err := c.saveAIInput(input, redactions); err != nil {
+		return fmt.Errorf("failed to save AI input: %v", err)
+	}
+	if err := c.saveScanManifest(scan, input, redactions); err != nil {
+		return err
+	}
+	if c.deleteInput {
+		defer c.removeAIInput()
+	}
+	fmt.Fprintf(out, "AI input has been saved to %s\n", c.AIInputPath())
+	fmt.Fprintf(out, "%d redacted values are listed in %s\n", len(redactions), c.RedactionReportPath())
+	fmt.Fprintf(out, "%d files in the input are listed in %s\n", len(scan.Files), c.ScanManifestPath())
+	sanitize.Summarize(out, redactions)
+	if err := c.blockSecrets(redactions); err != nil {
+		return err
+	}
+	// The chat reads its questions from in, so consent is asked there too
+	// rather than on the client's consent input.
+	if !c.autoApproved(redactions) && !c.askConsent(in) {
+		return fmt.Errorf("operation cancelled by user")
+	}
+
+	start := []Message{
+		{Role: RoleUser, Content: input, Cache: true},
+		{Role: RoleAssistant, Content: chatGreeting},
+	}
+	messages := start
+	fmt.Fprintf(out, "%s Type /help for commands.\n", chatGreeting)
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "/") {
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "/exit", "/quit":
+				return nil
+			case "/help":
+				fmt.Fprint(out, chatHelp)
+			case "/reset":
+				messages = start
+				fmt.Fprintln(out, "The conversation was reset.")
+			case "/history":
+				fmt.Fprint(out, c.chatTranscript(messages[len(start):]))
+			case "/save":
+				path := filepath.Join(c.iacPath, "kado-chat.md")
+				if len(fields) > 1 {
+					path = fields[1]
+				}
+				if err := writePrivateFile(path, []byte(c.chatTranscript(messages[len(start):]))); err != nil {
+					fmt.Fprintf(out, "Failed to save the conversation: %v\n", err)
+				} else {
+					fmt.Fprintf(out, "The conversation was saved to %s\n", path)
+				}
+			default:
+				fmt.Fprintf(out, "Unknown command %s. Type /help for commands.\n", fields[0])
+			}
+			continue
+		}
+
+		question, _ := c.redact(line)
+		turn := append(append([]Message(nil), messages...), Message{Role: RoleUser, Content: question})
+		answer, err := c.chatAnswer(turn, out)
+		if err != nil {
+			fmt.Fprintf(out, "Failed to get an answer: %v\n", err)
+			continue
+		}
+		messages = append(turn, Message{Role: RoleAssistant, Content: answer})
+		c.recordConversation(messages)
+	}
+}
+
+// chatAnswer gets the provider's answer to the last question in messages
+// and writes it to out, as it arrives when streaming is enabled.
+func (c *AIClient) chatAnswer(messages []Message, out io.Writer) (string, error) {
+	if !c.streaming {
+		answer, err := c.getResponse(messages)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintln(out, c.deanonymize(answer))
+		return answer, nil
+	}
+	// Pseudonyms can be split across deltas, so with anonymized paths the
+	// answer is only written once it is complete.
+	answer, err := c.streamRecommendations(messages, func(delta string) {
+		if !c.anonymizePaths {
+			fmt.Fprint(out, delta)
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if c.anonymizePaths {
+		fmt.Fprint(out, c.deanonymize(answer))
+	}
+	fmt.Fprintln(out)
+	return answer, nil
+}
+
+// chatTranscript formats the questions and answers in messages as
+// Markdown.
+func (c *AIClient) chatTranscript(messages []Message) string {
+	var transcript strings.Builder
+	for _, m := range messages {
+		if m.Role == RoleUser {
+			fmt.Fprintf(&transcript, "## %s\n\n", m.Content)
+		} else {
+			fmt.Fprintf(&transcript, "%s\n\n", c.deanonymize(m.Content))
+		}
+	}
+	return transcript.String()
+}
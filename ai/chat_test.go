@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChat(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_nat_gateway\" \"main\" {}\n",
+	})
+
+	var requests []json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages json.RawMessage `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body.Messages)
+		fmt.Fprintf(w, `{"content":[{"type":"text","text":"answer %d"}]}`, len(requests))
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	transcript := filepath.Join(tmpDir, "chat.md")
+	in := strings.NewReader("yes\nwhy is the NAT gateway needed?\nhow much does it cost?\n/unknown\n/save " + transcript + "\n/reset\nanything else?\n/exit\nnever asked\n")
+	var out bytes.Buffer
+	if err := client.Chat(context.Background(), in, &out); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 questions to be sent, got %d", len(requests))
+	}
+	var first []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	json.Unmarshal(requests[1], &first)
+	if len(first) != 5 || !strings.Contains(string(first[0].Content), `"cache_control"`) || !strings.Contains(string(first[0].Content), "aws_nat_gateway") {
+		t.Errorf("Expected the cached code followed by the earlier turns, got %s", requests[1])
+	}
+	var afterReset []Message
+	json.Unmarshal(requests[2], &afterReset)
+	if len(afterReset) != 3 {
+		t.Errorf("Expected /reset to drop earlier questions, got %d messages", len(afterReset))
+	}
+
+	for _, want := range []string{"answer 1", "answer 2", "answer 3", "Unknown command /unknown", "saved to " + transcript} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the output to contain %q, got %q", want, out.String())
+		}
+	}
+	saved, err := os.ReadFile(transcript)
+	if err != nil {
+		t.Fatalf("Expected the transcript to be saved: %v", err)
+	}
+	expected := "## why is the NAT gateway needed?\n\nanswer 1\n\n## how much does it cost?\n\nanswer 2\n\n"
+	if string(saved) != expected {
+		t.Errorf("Expected transcript %q, got %q", expected, string(saved))
+	}
+	if info, err := os.Stat(transcript); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the transcript to be readable only by its owner: %v", err)
+	}
+}
+
+func TestChatDeclined(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	client, err := NewAIClientWithOptions(WithAPIKey("sk-abc"), WithModel("gpt-4"), WithProvider("chatgpt"), WithIaCPath(tmpDir))
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+	if err := client.Chat(context.Background(), strings.NewReader("no\n"), &bytes.Buffer{}); err == nil {
+		t.Errorf("Expected an error when consent is declined")
+	}
+}
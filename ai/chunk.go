@@ -0,0 +1,230 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultContextWindow is the prompt size, in estimated tokens, above which
+// an analysis is split into chunks. It leaves room for the response within
+// the context window of current OpenAI and Anthropic models.
+const defaultContextWindow = 100000
+
+// chunkConcurrency is the number of chunks analyzed at the same time.
+const chunkConcurrency = 4
+
+// estimateTokens estimates the number of tokens in text at four characters
+// per token, which is close enough for code to decide when to split.
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// chunkScan splits the files of scan into parts whose content fits in
+// budget bytes. Files of the same directory, which usually form a module,
+// are kept together unless the directory alone exceeds the budget.
+func chunkScan(scan *ScanResult, budget int) []*ScanResult {
+	var dirs []string
+	groups := map[string][]ScannedFile{}
+	for _, f := range scan.Files {
+		dir := filepath.Dir(f.Path)
+		if _, ok := groups[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		groups[dir] = append(groups[dir], f)
+	}
+
+	var chunks []*ScanResult
+	current := &ScanResult{Root: scan.Root}
+	size := 0
+	add := func(files []ScannedFile, filesSize int) {
+		if size > 0 && size+filesSize > budget {
+			chunks = append(chunks, current)
+			current = &ScanResult{Root: scan.Root}
+			size = 0
+		}
+		current.Files = append(current.Files, files...)
+		size += filesSize
+	}
+	for _, dir := range dirs {
+		files := groups[dir]
+		dirSize := 0
+		for _, f := range files {
+			dirSize += chunkSize(scan.Root, f)
+		}
+		if dirSize <= budget {
+			add(files, dirSize)
+			continue
+		}
+		for _, f := range files {
+			add([]ScannedFile{f}, chunkSize(scan.Root, f))
+		}
+	}
+	if len(current.Files) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// chunkSize is the number of bytes f takes up in a prompt, including its
+// "File: " header.
+func chunkSize(root string, f ScannedFile) int {
+	return len("File: \n\n\n") + len(filepath.Join(root, f.Path)) + len(f.Pseudonym) + len(f.Content)
+}
+
+// chunkSeparator separates the chunks of a chunked input in ai_input.txt.
+const chunkSeparator = "\n\n----- next chunk -----\n\n"
+
+// chunkPrompt splits a scan too large for a single prompt by directory into
+// chunks that each fit in the context window.
+func (c *AIClient) chunkPrompt(template string, question string, scan *ScanResult) (*preparedPrompt, error) {
+	// The standards are sanitized again with every chunk, which reports
+	// their redactions.
+	base, _, err := c.basePromptData(question)
+	if err != nil {
+		return nil, err
+	}
+	p := &preparedPrompt{base: base}
+	p.overhead, err = c.renderPrompt(template, p.base)
+	if err != nil {
+		return nil, err
+	}
+	budget := (c.contextWindow - estimateTokens(p.overhead)) * 4
+	if budget <= 0 {
+		return nil, fmt.Errorf("the context window of %d tokens is too small for the prompt", c.contextWindow)
+	}
+
+	for _, chunk := range chunkScan(scan, budget) {
+		data, found := c.promptData(chunk)
+		data.Question = question
+		input, err := c.renderPrompt(template, data)
+		if err != nil {
+			return nil, err
+		}
+		p.chunks = append(p.chunks, input)
+		p.redactions = append(p.redactions, found...)
+	}
+	fmt.Fprintf(c.messages(), "The AI input is too large for one request and was split into %d chunks.\n", len(p.chunks))
+	return p, nil
+}
+
+// runChunked analyzes the chunks of a prompt too large for a single
+// request in parallel, and merges the partial results: findings are
+// deduplicated directly, patches and policies are concatenated, and reports
+// are combined by a final reduce request to the provider, or placed under
+// a heading for each IaC path when they are reported on separately.
+func (c *AIClient) runChunked(template string, p *preparedPrompt) (string, error) {
+	combined := p.text()
+	base := p.base
+	results, err := c.analyzeChunks(p)
+	if err != nil {
+		return "", err
+	}
+
+	// Follow-up questions continue from the reduce prompt, or for findings
+	// from the prompt without code, since the full input does not fit.
+	prompt := p.overhead
+	var recommendations string
+	if template == "fixes" || template == "policies" {
+		// Patches and policy files of different chunks are independent.
+		recommendations = strings.Join(results, "\n")
+		updateSinks(c.messages(), c.outputSinks(), recommendations, true)
+	} else if template == "findings" {
+		recommendations, err = mergeFindings(results)
+		if err == nil {
+			updateSinks(c.messages(), c.outputSinks(), recommendations, true)
+		}
+	} else if len(p.headings) > 0 {
+		// Each IaC path or project gets a report of its own.
+		var reports []string
+		if p.summary != "" {
+			reports = append(reports, strings.TrimSuffix(p.summary, "\n"))
+		}
+		for i, result := range results {
+			reports = append(reports, fmt.Sprintf("## %s\n\n%s", p.headings[i], result))
+		}
+		recommendations = strings.Join(reports, "\n\n")
+		updateSinks(c.messages(), c.outputSinks(), recommendations, true)
+	} else {
+		base.Reports = results
+		prompt, err = c.renderPrompt("reduce", base)
+		if err != nil {
+			return "", err
+		}
+		combined += "\n\n----- reduce -----\n\n" + prompt
+		recommendations, err = c.report(userTurn(prompt))
+	}
+	if err != nil {
+		return "", err
+	}
+	c.recordConversation([]Message{{Role: RoleUser, Content: prompt}, {Role: RoleAssistant, Content: recommendations}})
+	recommendations = c.deanonymize(recommendations)
+	if c.manifestSigner != nil {
+		if err := c.writeRunManifest(template, combined, recommendations); err != nil {
+			return "", err
+		}
+	}
+	return recommendations, nil
+}
+
+// analyzeChunks sends every chunk of p to the provider, at most
+// chunkConcurrency at a time, and returns the responses in the order of the
+// chunks.
+func (c *AIClient) analyzeChunks(p *preparedPrompt) ([]string, error) {
+	inputs := p.chunks
+	results := make([]string, len(inputs))
+	errs := make([]error, len(inputs))
+	sem := make(chan struct{}, chunkConcurrency)
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		wg.Add(1)
+		go func(i int, input string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			messages := withExamples(p.examples, input)
+			if c.refine {
+				if messages, errs[i] = c.refineTurns(messages, p.base.Question); errs[i] != nil {
+					return
+				}
+			}
+			results[i], errs[i] = c.getResponse(messages)
+		}(i, input)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze chunk %d of %d: %v", i+1, len(inputs), err)
+		}
+	}
+	return results, nil
+}
+
+// mergeFindings combines the findings responses of several chunks into a
+// single JSON array, dropping findings reported more than once for the same
+// category and location.
+func mergeFindings(responses []string) (string, error) {
+	merged := []Finding{}
+	seen := map[string]bool{}
+	for i, response := range responses {
+		findings, err := ParseFindings(response)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse findings of chunk %d: %v", i+1, err)
+		}
+		for _, f := range findings {
+			key := fmt.Sprintf("%s\x00%s\x00%d\x00%d", f.ID, f.File, f.StartLine, f.EndLine)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, f)
+		}
+	}
+	out, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestChunkScan(t *testing.T) {
+	scan := &ScanResult{Root: "/iac", Files: []ScannedFile{
+		{Path: "terraform/network/vpc.tf", Content: strings.Repeat("a", 40)},
+		{Path: "terraform/network/nat.tf", Content: strings.Repeat("b", 40)},
+		{Path: "terraform/storage/s3.tf", Content: strings.Repeat("c", 40)},
+		{Path: "terraform/big/one.tf", Content: strings.Repeat("d", 150)},
+		{Path: "terraform/big/two.tf", Content: strings.Repeat("e", 150)},
+	}}
+
+	chunks := chunkScan(scan, 250)
+	var got []string
+	for _, chunk := range chunks {
+		var paths []string
+		for _, f := range chunk.Files {
+			paths = append(paths, f.Path)
+		}
+		got = append(got, strings.Join(paths, ","))
+	}
+	expected := []string{
+		"terraform/network/vpc.tf,terraform/network/nat.tf,terraform/storage/s3.tf",
+		"terraform/big/one.tf",
+		"terraform/big/two.tf",
+	}
+	if strings.Join(got, " | ") != strings.Join(expected, " | ") {
+		t.Errorf("Expected chunks %v, got %v", expected, got)
+	}
+}
+
+func TestMergeFindings(t *testing.T) {
+	merged, err := mergeFindings([]string{
+		`[{"id":"KADO-101","file":"main.tf","start_line":3,"end_line":5,"severity":"high","title":"Open SSH"}]`,
+		"```json\n" + `[{"id":"KADO-101","file":"main.tf","start_line":3,"end_line":5,"severity":"high","title":"Public SSH"},
+		{"id":"KADO-201","file":"s3.tf","start_line":1,"end_line":1,"severity":"low","title":"No versioning"}]` + "\n```",
+	})
+	if err != nil {
+		t.Fatalf("mergeFindings failed: %v", err)
+	}
+	findings, err := ParseFindings(merged)
+	if err != nil {
+		t.Fatalf("ParseFindings failed: %v", err)
+	}
+	if len(findings) != 2 || findings[0].Title != "Open SSH" || findings[1].File != "s3.tf" {
+		t.Errorf("Expected 2 deduplicated findings, got %+v", findings)
+	}
+
+	if _, err := mergeFindings([]string{"no findings here"}); err == nil {
+		t.Errorf("Expected an error for a response without findings")
+	}
+}
+
+func TestRunAIChunked(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/network/main.tf": "resource \"aws_nat_gateway\" \"main\" {}\n" + strings.Repeat("# network\n", 300),
+		"terraform/storage/main.tf": "resource \"aws_s3_bucket\" \"logs\" {}\n" + strings.Repeat("# storage\n", 300),
+	})
+
+	var mu sync.Mutex
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt := body.Messages[0].Content
+		mu.Lock()
+		prompts = append(prompts, prompt)
+		mu.Unlock()
+
+		text := "merged report"
+		switch {
+		case strings.Contains(prompt, "partial report"):
+		case strings.Contains(prompt, "aws_nat_gateway"):
+			text = "use VPC endpoints"
+		case strings.Contains(prompt, "aws_s3_bucket"):
+			text = "enable versioning"
+		}
+		fmt.Fprintf(w, `{"content":[{"type":"text","text":%q}]}`, text)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+		WithContextWindow(1200),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	recommendations, err := client.RunAI()
+	if err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	if recommendations != "merged report" {
+		t.Errorf("Expected the reduced report, got %q", recommendations)
+	}
+	if len(prompts) != 3 {
+		t.Fatalf("Expected 2 chunk requests and a reduce request, got %d", len(prompts))
+	}
+	reduce := prompts[2]
+	for _, want := range []string{"use VPC endpoints", "enable versioning"} {
+		if !strings.Contains(reduce, want) {
+			t.Errorf("Expected the reduce prompt to include %q, got %q", want, reduce)
+		}
+	}
+	for _, prompt := range prompts[:2] {
+		if strings.Contains(prompt, "aws_nat_gateway") && strings.Contains(prompt, "aws_s3_bucket") {
+			t.Errorf("Expected each chunk to hold a single directory, got %q", prompt)
+		}
+	}
+
+	if _, err := NewAIClientWithOptions(WithAPIKey("k"), WithModel("m"), WithProvider("chatgpt"), WithContextWindow(-1)); err == nil {
+		t.Errorf("Expected an error for a negative context window")
+	}
+}
@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SectionKubernetesCluster holds the snapshot of the objects running in the
+// live Kubernetes cluster.
+const SectionKubernetesCluster = "kubernetes cluster"
+
+// clusterKinds are the kinds of objects read from the cluster: workloads,
+// services, network policies, and RBAC.
+const clusterKinds = "deployments,statefulsets,daemonsets,services,networkpolicies,roles,rolebindings,clusterroles,clusterrolebindings"
+
+// clusterSystemNamespaces hold the cluster's own components, which are left
+// out of the snapshot.
+var clusterSystemNamespaces = map[string]bool{"kube-system": true, "kube-public": true, "kube-node-lease": true}
+
+// clusterFields are the top-level fields kept of each object, besides its
+// name and namespace: the spec, and the rules, role, and subjects of RBAC
+// objects, which have no spec.
+var clusterFields = []string{"spec", "rules", "roleRef", "subjects", "aggregationRule"}
+
+// scanCluster adds a snapshot of the objects running in the Kubernetes
+// cluster of the kubeconfig's current context, or of the configured
+// context, to result. Only kubectl get runs, and only the names, namespaces,
+// and specs of the objects are kept: their status, annotations, and labels
+// are dropped, and the values of container environment variables are
+// redacted.
+func (c *AIClient) scanCluster(ctx context.Context, result *ScanResult) {
+	if _, err := lookPath("kubectl"); err != nil {
+		fmt.Fprintf(c.messages(), "Warning: the Kubernetes cluster is not read since kubectl is not on the PATH\n")
+		return
+	}
+	args := []string{"get", clusterKinds, "--all-namespaces", "--output", "json"}
+	if c.kubernetesContext != "" {
+		args = append(args, "--context", c.kubernetesContext)
+	}
+	// kubectl exits with an error when it may not list some of the kinds,
+	// but still prints the others.
+	out, runErr := runCommand(ctx, c.iacPath, nil, "kubectl", args...)
+	var list struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		if runErr == nil {
+			runErr = err
+		}
+		fmt.Fprintf(c.messages(), "Warning: failed to read the Kubernetes cluster: %v\n", runErr)
+		return
+	}
+	if runErr != nil {
+		fmt.Fprintf(c.messages(), "Warning: some objects of the Kubernetes cluster could not be read: %v\n", runErr)
+	}
+
+	var objects []map[string]interface{}
+	for _, item := range list.Items {
+		if object := clusterObject(item); object != nil {
+			objects = append(objects, object)
+		}
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return clusterObjectKey(objects[i]) < clusterObjectKey(objects[j])
+	})
+	var snapshot strings.Builder
+	for _, object := range objects {
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			continue
+		}
+		snapshot.WriteString("---\n")
+		snapshot.Write(data)
+	}
+	addOutput(result, SectionKubernetesCluster, filepath.Join(c.iacPath, "kubernetes-cluster.yaml"), snapshot.String())
+}
+
+// clusterObject returns the parts of item kept in the snapshot, or nil if
+// item is left out: objects of the system namespaces and the cluster's own
+// roles and bindings.
+func clusterObject(item map[string]interface{}) map[string]interface{} {
+	metadata, _ := item["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+	if clusterSystemNamespaces[namespace] || strings.HasPrefix(name, "system:") {
+		return nil
+	}
+	kept := map[string]interface{}{}
+	for _, field := range []string{"name", "namespace"} {
+		if value, ok := metadata[field]; ok {
+			kept[field] = value
+		}
+	}
+	object := map[string]interface{}{
+		"apiVersion": item["apiVersion"],
+		"kind":       item["kind"],
+		"metadata":   kept,
+	}
+	for _, field := range clusterFields {
+		if value, ok := item[field]; ok {
+			object[field] = value
+		}
+	}
+	redactEnvValues(object["spec"])
+	return object
+}
+
+// redactEnvValues replaces the values of the environment variables of the
+// containers anywhere in value, such as in the pod template of a workload.
+func redactEnvValues(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if env, ok := child.([]interface{}); ok && key == "env" {
+				for _, variable := range env {
+					if variable, ok := variable.(map[string]interface{}); ok {
+						if _, ok := variable["value"]; ok {
+							variable["value"] = "[REDACTED]"
+						}
+					}
+				}
+				continue
+			}
+			redactEnvValues(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			redactEnvValues(child)
+		}
+	}
+}
+
+// clusterObjectKey orders the objects of the snapshot by kind, namespace,
+// and name.
+func clusterObjectKey(object map[string]interface{}) string {
+	metadata := object["metadata"].(map[string]interface{})
+	return fmt.Sprintf("%v/%v/%v", object["kind"], metadata["namespace"], metadata["name"])
+}
@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestScanCluster(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	calls := fakeRunCommand(t, `{"kind": "List", "items": [
+  {"apiVersion": "apps/v1", "kind": "Deployment",
+   "metadata": {"name": "web", "namespace": "shop", "labels": {"app": "web"}, "annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}"}, "managedFields": []},
+   "spec": {"replicas": 3, "template": {"spec": {"containers": [{"name": "web", "image": "nginx:1.25", "env": [{"name": "DB_PASSWORD", "value": "hunter2"}, {"name": "DB_HOST", "valueFrom": {"configMapKeyRef": {"name": "db", "key": "host"}}}]}]}}},
+   "status": {"readyReplicas": 3}},
+  {"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "ClusterRoleBinding",
+   "metadata": {"name": "ci-admin"},
+   "roleRef": {"kind": "ClusterRole", "name": "cluster-admin"},
+   "subjects": [{"kind": "ServiceAccount", "name": "ci", "namespace": "ci"}]},
+  {"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "ClusterRole", "metadata": {"name": "system:node"}, "rules": []},
+  {"apiVersion": "apps/v1", "kind": "DaemonSet", "metadata": {"name": "kube-proxy", "namespace": "kube-system"}, "spec": {}}
+]}`)
+	originalLookPath := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { lookPath = originalLookPath }()
+
+	client := &AIClient{iacPath: tmpDir, kubernetesCluster: true, kubernetesContext: "prod"}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var kubectl string
+	for _, call := range *calls {
+		if call[0] == "kubectl" {
+			kubectl = strings.Join(call, " ")
+		}
+	}
+	if kubectl != "kubectl get "+clusterKinds+" --all-namespaces --output json --context prod" {
+		t.Errorf("Expected a read-only kubectl get, got %v", *calls)
+	}
+
+	snapshot := result.Section(SectionKubernetesCluster)
+	if len(snapshot) != 1 {
+		t.Fatalf("Expected the cluster snapshot, got %+v", snapshot)
+	}
+	content := snapshot[0].Content
+	for _, expected := range []string{"kind: ClusterRoleBinding", "name: cluster-admin", "kind: Deployment", "replicas: 3", "value: '[REDACTED]'", "configMapKeyRef"} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected %q in the snapshot, got:\n%s", expected, content)
+		}
+	}
+	for _, unexpected := range []string{"hunter2", "last-applied", "readyReplicas", "app: web", "system:node", "kube-proxy"} {
+		if strings.Contains(content, unexpected) {
+			t.Errorf("Expected no %q in the snapshot, got:\n%s", unexpected, content)
+		}
+	}
+	if strings.Index(content, "ClusterRoleBinding") > strings.Index(content, "Deployment") {
+		t.Errorf("Expected the objects ordered by kind, got:\n%s", content)
+	}
+}
@@ -0,0 +1,30 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// lookPath finds an external tool in PATH. It is a variable so tests can
+// pretend a tool is installed.
+var lookPath = exec.LookPath
+
+// runCommand executes an external tool in dir and returns its stdout. Extra
+// environment variables are appended to the current environment. It is a
+// variable so tests can substitute a fake.
+var runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return out, fmt.Errorf("%s %s failed: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,229 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// exactLineTemplates are the prompt templates whose responses point at line
+// numbers or quote the code verbatim, so their input is never compressed.
+var exactLineTemplates = map[string]bool{
+	"findings": true,
+	"fixes":    true,
+}
+
+// heredocStart matches the opening of a Terraform heredoc, such as <<-EOT.
+var heredocStart = regexp.MustCompile(`^<<-?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// compressScan returns a copy of scan with the content of every file
+// compressed: comments are stripped, whitespace is collapsed, JSON is
+// minified, and top-level Terraform blocks repeated verbatim, such as the
+// same provider stanza in every module, are replaced by a reference to
+// their first occurrence. The original scan is left untouched.
+func compressScan(scan *ScanResult) *ScanResult {
+	compressed := *scan
+	compressed.Files = make([]ScannedFile, len(scan.Files))
+	seen := map[string]string{}
+	for i, f := range scan.Files {
+		switch f.Language {
+		case "terraform", "rego":
+			lines := compressHCL(f.Language, f.Content)
+			if f.Language == "terraform" {
+				lines = dedupBlocks(lines, scan.displayName(f), seen)
+			}
+			var content []string
+			for _, line := range lines {
+				content = append(content, line.text)
+			}
+			f.Content = strings.Join(content, "\n")
+		case "json":
+			var out bytes.Buffer
+			if err := json.Compact(&out, []byte(f.Content)); err == nil {
+				f.Content = out.String()
+			} else {
+				f.Content = collapseBlankLines(f.Content)
+			}
+		case "yaml":
+			f.Content = collapseBlankLines(stripYAMLComments(f.Content))
+		default:
+			f.Content = collapseBlankLines(f.Content)
+		}
+		compressed.Files[i] = f
+	}
+	return &compressed
+}
+
+// compressScanFor compresses scan for template, unless the template needs
+// the code as written, and reports the savings to out.
+func compressScanFor(out io.Writer, template string, scan *ScanResult) *ScanResult {
+	if exactLineTemplates[template] {
+		return scan
+	}
+	compressed := compressScan(scan)
+	before, after := 0, 0
+	for i := range scan.Files {
+		before += estimateTokens(scan.Files[i].Content)
+		after += estimateTokens(compressed.Files[i].Content)
+	}
+	if before > 0 {
+		fmt.Fprintf(out, "Compression reduced the code from %d to %d estimated tokens (%d%% smaller).\n", before, after, 100*(before-after)/before)
+	}
+	return compressed
+}
+
+// hclLine is a compressed line of HCL code and the nesting depth of braces
+// before and after it.
+type hclLine struct {
+	text        string
+	depthBefore int
+	depthAfter  int
+}
+
+// compressHCL strips comments, indentation, and repeated spaces from
+// Terraform or Rego code, leaving strings and heredocs intact, and drops
+// blank lines. Rego only has # comments.
+func compressHCL(language string, content string) []hclLine {
+	var lines []hclLine
+	depth := 0
+	inComment := false
+	heredoc := ""
+	for _, line := range strings.Split(content, "\n") {
+		if heredoc != "" {
+			lines = append(lines, hclLine{text: line, depthBefore: depth, depthAfter: depth})
+			if strings.TrimSpace(line) == heredoc {
+				heredoc = ""
+			}
+			continue
+		}
+
+		before := depth
+		var out strings.Builder
+		inString := false
+	chars:
+		for i := 0; i < len(line); i++ {
+			ch := line[i]
+			var next byte
+			if i+1 < len(line) {
+				next = line[i+1]
+			}
+			switch {
+			case inComment:
+				if ch == '*' && next == '/' {
+					inComment = false
+					i++
+				}
+				continue
+			case inString:
+				out.WriteByte(ch)
+				if ch == '\\' && next != 0 {
+					out.WriteByte(next)
+					i++
+				} else if ch == '"' {
+					inString = false
+				}
+				continue
+			case ch == '#':
+				break chars
+			case language == "terraform" && ch == '/' && next == '/':
+				break chars
+			case language == "terraform" && ch == '/' && next == '*':
+				inComment = true
+				i++
+				continue
+			case ch == '"':
+				inString = true
+			case ch == '{':
+				depth++
+			case ch == '}':
+				depth--
+			case ch == ' ' || ch == '\t':
+				if out.Len() == 0 || strings.HasSuffix(out.String(), " ") {
+					continue
+				}
+				ch = ' '
+			case language == "terraform" && ch == '<':
+				if m := heredocStart.FindStringSubmatch(line[i:]); m != nil {
+					heredoc = m[1]
+				}
+			}
+			out.WriteByte(ch)
+		}
+
+		text := strings.TrimSpace(out.String())
+		if text == "" {
+			continue
+		}
+		lines = append(lines, hclLine{text: text, depthBefore: before, depthAfter: depth})
+	}
+	return lines
+}
+
+// dedupBlocks replaces every top-level block of lines already seen, in this
+// or an earlier file, by its opening line and a reference to the file it
+// first appeared in. seen maps the blocks seen so far to that file.
+func dedupBlocks(lines []hclLine, name string, seen map[string]string) []hclLine {
+	var out []hclLine
+	for i := 0; i < len(lines); i++ {
+		if lines[i].depthBefore != 0 || lines[i].depthAfter == 0 {
+			out = append(out, lines[i])
+			continue
+		}
+		end := i
+		for end < len(lines) && lines[end].depthAfter != 0 {
+			end++
+		}
+		if end == len(lines) {
+			out = append(out, lines[i:]...)
+			break
+		}
+		var block []string
+		for _, line := range lines[i : end+1] {
+			block = append(block, line.text)
+		}
+		key := strings.Join(block, "\n")
+		if first, ok := seen[key]; ok {
+			header := strings.TrimSuffix(strings.TrimSpace(lines[i].text), "{")
+			out = append(out, hclLine{text: fmt.Sprintf("%s{ ... } (identical to the block in %s)", header, first)})
+		} else {
+			seen[key] = name
+			out = append(out, lines[i:end+1]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// stripYAMLComments removes comment lines, and trailing comments from lines
+// without quotes, where a # cannot be part of a string.
+func stripYAMLComments(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			lines[i] = ""
+			continue
+		}
+		if !strings.ContainsAny(line, `"'`) {
+			if j := strings.Index(line, " #"); j >= 0 {
+				lines[i] = line[:j]
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// collapseBlankLines trims trailing whitespace and drops blank lines.
+func collapseBlankLines(content string) string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCompressScan(t *testing.T) {
+	provider := "provider \"aws\" {\n  region = \"us-east-1\" # primary\n}\n"
+	scan := &ScanResult{Root: "/iac", Files: []ScannedFile{
+		{Path: "terraform/a/main.tf", Language: "terraform", Content: provider + `
+# Network
+resource "aws_vpc" "main" {
+  cidr_block    = "10.0.0.0/16"   // the VPC
+  tags = { Name = "a  #b" }
+  /* legacy
+     setting */
+  user_data = <<-EOT
+    #!/bin/bash
+    echo   "hi"
+  EOT
+}
+`},
+		{Path: "terraform/b/main.tf", Language: "terraform", Content: provider + "\n\nresource \"aws_s3_bucket\" \"b\" {}\n"},
+		{Path: "ansible/site.yml", Language: "yaml", Content: "# Playbook\n- hosts: all # every host\n\n  vars:\n    motd: \"no #comment\"\n"},
+		{Path: "terraform/plan.json", Language: "json", Content: "{\n  \"resource_changes\": [\n    {\"address\": \"aws_vpc.main\"}\n  ]\n}\n"},
+		{Path: "policies/deny.rego", Language: "rego", Content: "package main\n\n# deny public buckets\ndeny[msg] {\n    input.acl == \"public-read\"  # bad\n    msg := \"public\"\n}\n"},
+	}}
+
+	compressed := compressScan(scan)
+
+	expected := []string{
+		"provider \"aws\" {\nregion = \"us-east-1\"\n}\nresource \"aws_vpc\" \"main\" {\ncidr_block = \"10.0.0.0/16\"\ntags = { Name = \"a  #b\" }\nuser_data = <<-EOT\n    #!/bin/bash\n    echo   \"hi\"\n  EOT\n}",
+		"provider \"aws\" { ... } (identical to the block in /iac/terraform/a/main.tf)\nresource \"aws_s3_bucket\" \"b\" {}",
+		"- hosts: all\n  vars:\n    motd: \"no #comment\"",
+		`{"resource_changes":[{"address":"aws_vpc.main"}]}`,
+		"package main\ndeny[msg] {\ninput.acl == \"public-read\"\nmsg := \"public\"\n}",
+	}
+	for i, want := range expected {
+		if got := compressed.Files[i].Content; got != want {
+			t.Errorf("Expected %s to compress to\n%s\ngot\n%s", scan.Files[i].Path, want, got)
+		}
+	}
+	if !strings.Contains(scan.Files[0].Content, "# Network") {
+		t.Errorf("Expected the original scan to be left untouched")
+	}
+}
+
+func TestCompressScanForExactLines(t *testing.T) {
+	scan := &ScanResult{Files: []ScannedFile{{Path: "main.tf", Language: "terraform", Content: "# comment\nresource \"a\" \"b\" {}\n"}}}
+	if compressScanFor(io.Discard, "findings", scan) != scan {
+		t.Errorf("Expected findings input not to be compressed")
+	}
+	if compressScanFor(io.Discard, "analyze", scan) == scan {
+		t.Errorf("Expected analyze input to be compressed")
+	}
+}
@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SectionPolicyResults holds the results of evaluating the Rego policies of
+// the IaC path against each Terraform plan.
+const SectionPolicyResults = "policy results"
+
+// conftestResult is one namespace's result for one file in the output of
+// conftest test --output json.
+type conftestResult struct {
+	Filename   string           `json:"filename"`
+	Namespace  string           `json:"namespace"`
+	Successes  int              `json:"successes"`
+	Failures   []conftestResult `json:"failures"`
+	Warnings   []conftestResult `json:"warnings"`
+	Exceptions []conftestResult `json:"exceptions"`
+	Msg        string           `json:"msg"`
+}
+
+// evaluatePolicies runs conftest with the Rego policies collected in result
+// against every Terraform plan in JSON format, adding the failures and
+// warnings for each plan to result. Policies and plans are copied to a
+// temporary directory, since plans may have been produced by kado-ai and
+// policies read from archives.
+func (c *AIClient) evaluatePolicies(ctx context.Context, result *ScanResult) {
+	policies := map[string]string{}
+	for _, f := range result.Files {
+		if filepath.Ext(f.Path) == ".rego" {
+			policies[f.Path] = f.Content
+		}
+	}
+	plans := result.Section(SectionTerraformPlan)
+	if len(policies) == 0 || len(plans) == 0 {
+		return
+	}
+	if _, err := lookPath("conftest"); err != nil {
+		fmt.Fprintf(c.messages(), "Warning: policies are not evaluated since conftest is not on the PATH\n")
+		return
+	}
+
+	tmpDir, err := os.MkdirTemp("", "kado-ai-policies")
+	if err != nil {
+		fmt.Fprintf(c.messages(), "Warning: failed to create temporary directory for policies: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+	policyDir := filepath.Join(tmpDir, "policy")
+	if err := os.Mkdir(policyDir, 0700); err != nil {
+		fmt.Fprintf(c.messages(), "Warning: failed to create temporary directory for policies: %v\n", err)
+		return
+	}
+	i := 0
+	for _, content := range policies {
+		i++
+		if err := os.WriteFile(filepath.Join(policyDir, fmt.Sprintf("policy%d.rego", i)), []byte(content), 0600); err != nil {
+			fmt.Fprintf(c.messages(), "Warning: failed to write policy: %v\n", err)
+			return
+		}
+	}
+
+	for _, plan := range plans {
+		path := plan.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(result.Root, path)
+		}
+		planPath := filepath.Join(tmpDir, "plan.json")
+		if err := os.WriteFile(planPath, []byte(plan.Content), 0600); err != nil {
+			fmt.Fprintf(c.messages(), "Warning: failed to write plan: %v\n", err)
+			return
+		}
+		// conftest exits with an error when a policy fails, but still
+		// prints its results.
+		out, runErr := runCommand(ctx, tmpDir, nil, "conftest", "test", "--all-namespaces", "--no-color", "--output", "json", "--policy", policyDir, planPath)
+		var results []conftestResult
+		if err := json.Unmarshal(out, &results); err != nil {
+			if runErr == nil {
+				runErr = err
+			}
+			fmt.Fprintf(c.messages(), "Warning: failed to evaluate policies against %s: %v\n", path, runErr)
+			continue
+		}
+		addOutput(result, SectionPolicyResults, path+".conftest", renderPolicyResults(results))
+		result.Files[len(result.Files)-1].Language = ""
+	}
+}
+
+// renderPolicyResults renders the results of conftest as a count of the
+// rules that passed, failed, warned, or were excepted, followed by one line
+// per failure and warning with the namespace of its policy.
+func renderPolicyResults(results []conftestResult) string {
+	var passed, failed, warned, excepted int
+	var lines []string
+	for _, r := range results {
+		passed += r.Successes
+		failed += len(r.Failures)
+		warned += len(r.Warnings)
+		excepted += len(r.Exceptions)
+		for _, f := range r.Failures {
+			lines = append(lines, fmt.Sprintf("failure (%s): %s", r.Namespace, f.Msg))
+		}
+		for _, w := range r.Warnings {
+			lines = append(lines, fmt.Sprintf("warning (%s): %s", r.Namespace, w.Msg))
+		}
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, "%d passed, %d failed, %d warnings, %d exceptions\n", passed, failed, warned, excepted)
+	if len(lines) > 0 {
+		out.WriteString("\n" + strings.Join(lines, "\n") + "\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanEvaluatesPolicies(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf":         "resource \"aws_s3_bucket\" \"logs\" {}\n",
+		"terraform/plan.json":       `{"resource_changes":[{"address":"aws_s3_bucket.logs"}]}`,
+		"terraform/policy/s3.rego":  "package terraform.s3\n\ndeny[msg] { msg := \"public bucket\" }\n",
+		"terraform/policy/tag.rego": "package terraform.tags\n\nwarn[msg] { msg := \"missing tags\" }\n",
+	})
+	var calls [][]string
+	var policies []string
+	originalRunCommand := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		names, _ := readDirNames(filepath.Join(dir, "policy"))
+		policies = names
+		return []byte(`[
+  {"filename": "plan.json", "namespace": "terraform.s3", "successes": 2, "failures": [{"msg": "S3 bucket aws_s3_bucket.logs is public"}]},
+  {"filename": "plan.json", "namespace": "terraform.tags", "successes": 0, "warnings": [{"msg": "aws_s3_bucket.logs has no owner tag"}]}
+]`), fmt.Errorf("conftest failed: exit status 1")
+	}
+	defer func() { runCommand = originalRunCommand }()
+	originalLookPath := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { lookPath = originalLookPath }()
+
+	client := &AIClient{iacPath: tmpDir, policyEvaluation: true}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var conftest []string
+	for _, call := range calls {
+		if call[0] == "conftest" {
+			conftest = call
+		}
+	}
+	if len(conftest) == 0 || !strings.Contains(strings.Join(conftest, " "), "test --all-namespaces --no-color --output json --policy") {
+		t.Fatalf("Expected conftest to run, got %v", calls)
+	}
+	if len(policies) != 2 {
+		t.Errorf("Expected both policies to be evaluated, got %v", policies)
+	}
+
+	results := result.Section(SectionPolicyResults)
+	expected := "2 passed, 1 failed, 1 warnings, 0 exceptions\n\nfailure (terraform.s3): S3 bucket aws_s3_bucket.logs is public\nwarning (terraform.tags): aws_s3_bucket.logs has no owner tag\n"
+	if len(results) != 1 || results[0].Content != expected || results[0].Path != filepath.Join("terraform", "plan.json.conftest") {
+		t.Fatalf("Expected the policy results, got %+v", results)
+	}
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.PolicyResults, "S3 bucket aws_s3_bucket.logs is public") {
+		t.Errorf("Expected the policy results in the prompt, got %q", data.PolicyResults)
+	}
+
+	// Without conftest, nothing is evaluated.
+	lookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Section(SectionPolicyResults)) != 0 {
+		t.Errorf("Expected no policy results without conftest")
+	}
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+// ConsentPolicy controls whether RunAI asks before sending data to the AI
+// provider.
+type ConsentPolicy string
+
+const (
+	// ConsentAlwaysAsk prompts for confirmation before every request.
+	ConsentAlwaysAsk ConsentPolicy = "always-ask"
+	// ConsentAutoApprove sends data without asking, for CI and library use.
+	ConsentAutoApprove ConsentPolicy = "auto-approve"
+	// ConsentAutoApproveIfNoSecrets sends data without asking unless the
+	// sanitizer had to redact credentials, in which case it asks.
+	ConsentAutoApproveIfNoSecrets ConsentPolicy = "auto-approve-if-no-secrets-found"
+)
+
+func (p ConsentPolicy) valid() bool {
+	switch p {
+	case ConsentAlwaysAsk, ConsentAutoApprove, ConsentAutoApproveIfNoSecrets:
+		return true
+	}
+	return false
+}
+
+// SecretsBlockedError is returned instead of sending data to the AI provider
+// when WithBlockOnSecrets is set and the sanitizer found high-confidence
+// secrets. Redactions locates every one of them.
+type SecretsBlockedError struct {
+	Redactions []sanitize.Redaction
+}
+
+func (e *SecretsBlockedError) Error() string {
+	var locations []string
+	for _, r := range e.Redactions {
+		location := r.Section
+		if r.File != "" {
+			location = r.File
+		}
+		locations = append(locations, fmt.Sprintf("%s at %s:%d", r.Rule, location, r.Line))
+	}
+	return fmt.Sprintf("%d high-confidence secrets were found, so nothing was sent to the AI provider:\n  - %s",
+		len(e.Redactions), strings.Join(locations, "\n  - "))
+}
+
+// blockSecrets returns a *SecretsBlockedError if the client blocks on
+// secrets and redactions include high-confidence ones.
+func (c *AIClient) blockSecrets(redactions []sanitize.Redaction) error {
+	if !c.blockOnSecrets {
+		return nil
+	}
+	if secrets := sanitize.HighConfidenceSecrets(redactions); len(secrets) > 0 {
+		return &SecretsBlockedError{Redactions: secrets}
+	}
+	return nil
+}
+
+// consent applies the client's consent policy to a prepared input whose
+// sanitization produced redactions, asking on the consent input if needed.
+func (c *AIClient) consent(redactions []sanitize.Redaction) bool {
+	if c.autoApproved(redactions) {
+		return true
+	}
+	if c.consentPolicy == ConsentAutoApproveIfNoSecrets {
+		fmt.Fprintln(c.messages(), "Secrets were redacted from the AI input, so it will not be sent automatically.")
+	}
+	return c.askConsent(c.consentInput)
+}
+
+// autoApproved reports whether the consent policy lets an input with the
+// given redactions be sent without asking.
+func (c *AIClient) autoApproved(redactions []sanitize.Redaction) bool {
+	switch c.consentPolicy {
+	case ConsentAutoApprove:
+		return true
+	case ConsentAutoApproveIfNoSecrets:
+		return !sanitize.SecretsFound(redactions)
+	}
+	return false
+}
+
+func (c *AIClient) askConsent(in io.Reader) bool {
+	return confirm(in, c.messages(), "Do you want to proceed with sending this data to the AI for analysis?")
+}
+
+// confirm asks a yes/no question on in, standard input if nil, and reports
+// whether the answer was yes.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	if in == nil {
+		in = os.Stdin
+	}
+	fmt.Fprintf(out, "%s (yes/no): ", question)
+	var response string
+	fmt.Fscanln(in, &response)
+	return strings.ToLower(response) == "yes"
+}
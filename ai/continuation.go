@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// defaultMaxTokens is the output token limit of Anthropic requests, which
+// require one, when none is configured.
+const defaultMaxTokens = 1024
+
+// maxContinuations is the number of times a response cut off at the output
+// token limit is continued before it is returned incomplete.
+const maxContinuations = 5
+
+// continuePrompt asks the provider to resume a response that was cut off.
+const continuePrompt = "Your response was cut off. Continue exactly where it stopped, without repeating anything and without an introduction."
+
+// continueResponse sends messages with request and, as long as the reply is
+// cut off at the output token limit, asks the provider to continue it. It
+// returns the parts stitched together, warning on out if the reply is still
+// cut off after maxContinuations.
+func continueResponse(out io.Writer, messages []Message, request func([]Message) (string, bool, error)) (string, error) {
+	var text strings.Builder
+	turns := messages
+	for continuation := 0; ; continuation++ {
+		part, truncated, err := request(turns)
+		if err != nil {
+			return "", err
+		}
+		text.WriteString(part)
+		if !truncated {
+			return text.String(), nil
+		}
+		if continuation == maxContinuations {
+			fmt.Fprintf(out, "Warning: the response is still incomplete after %d continuations; raise AI_MAX_TOKENS to get it whole\n", maxContinuations)
+			return text.String(), nil
+		}
+		turns = append(append([]Message(nil), messages...),
+			Message{Role: RoleAssistant, Content: text.String()},
+			Message{Role: RoleUser, Content: continuePrompt})
+	}
+}
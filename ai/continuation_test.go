@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContinueResponse(t *testing.T) {
+	testCases := []struct {
+		clientType string
+		streaming  bool
+		responses  []string
+	}{
+		{
+			clientType: "chatgpt",
+			responses: []string{
+				`{"choices":[{"message":{"content":"# Summary\nOpen "},"finish_reason":"length"}]}`,
+				`{"choices":[{"message":{"content":"SSH."},"finish_reason":"stop"}]}`,
+			},
+		},
+		{
+			clientType: "anthropic_messages",
+			responses: []string{
+				`{"content":[{"type":"text","text":"# Summary\nOpen "}],"stop_reason":"max_tokens"}`,
+				`{"content":[{"type":"text","text":"SSH."}],"stop_reason":"end_turn"}`,
+			},
+		},
+		{
+			clientType: "chatgpt",
+			streaming:  true,
+			responses: []string{
+				"data: {\"choices\":[{\"delta\":{\"content\":\"# Summary\\nOpen \"}}]}\n\ndata: {\"choices\":[{\"delta\":{},\"finish_reason\":\"length\"}]}\n\ndata: [DONE]\n\n",
+				"data: {\"choices\":[{\"delta\":{\"content\":\"SSH.\"}}]}\n\ndata: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n",
+			},
+		},
+		{
+			clientType: "anthropic_messages",
+			streaming:  true,
+			responses: []string{
+				"data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"# Summary\\nOpen \"}}\n\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"max_tokens\"}}\n\n",
+				"data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"SSH.\"}}\n\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"}}\n\n",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		var bodies []map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			bodies = append(bodies, body)
+			if len(bodies) <= len(tc.responses) {
+				fmt.Fprint(w, tc.responses[len(bodies)-1])
+			}
+		}))
+
+		sink := &recordingSink{}
+		client := &AIClient{
+			apiKey:     "test-api-key",
+			model:      "test-model",
+			clientType: tc.clientType,
+			httpClient: testHTTPClient(t, server),
+			streaming:  tc.streaming,
+			sinks:      []Sink{sink},
+			maxTokens:  200,
+		}
+		recommendations, err := client.recommend("analyze this")
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: recommend failed: %v", tc.clientType, err)
+		}
+
+		if recommendations != "# Summary\nOpen SSH." || sink.updates[len(sink.updates)-1] != recommendations {
+			t.Errorf("%s: expected the parts stitched together, got %q", tc.clientType, recommendations)
+		}
+		if len(bodies) != 2 {
+			t.Fatalf("%s: expected a continuation request, got %d requests", tc.clientType, len(bodies))
+		}
+		messages, _ := bodies[1]["messages"].([]interface{})
+		if len(messages) != 3 {
+			t.Errorf("%s: expected the partial response and a request to continue, got %v", tc.clientType, messages)
+		}
+		limit := bodies[0]["max_tokens"]
+		if tc.clientType == "chatgpt" {
+			limit = bodies[0]["max_completion_tokens"]
+		}
+		if limit != float64(200) {
+			t.Errorf("%s: expected the configured output limit, got %v", tc.clientType, limit)
+		}
+	}
+}
+
+func TestContinueResponseGivesUp(t *testing.T) {
+	requests := 0
+	text, err := continueResponse(io.Discard, userTurn("analyze this"), func(messages []Message) (string, bool, error) {
+		requests++
+		return "more ", true, nil
+	})
+	if err != nil {
+		t.Fatalf("continueResponse failed: %v", err)
+	}
+	if requests != maxContinuations+1 || len(text) != len("more ")*requests {
+		t.Errorf("Expected %d requests, got %d with %q", maxContinuations+1, requests, text)
+	}
+}
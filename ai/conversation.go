@@ -0,0 +1,194 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Roles of the turns of a conversation.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a conversation with the provider.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// Cache marks the end of a prefix of the conversation that is sent
+	// unchanged with every request, so providers that support prompt
+	// caching can reuse it.
+	Cache bool `json:"cache,omitempty"`
+	// ToolCalls are the tools an assistant turn asks to run, and
+	// ToolResults the outputs a user turn answers them with.
+	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
+}
+
+// ToolCall is a request of the model to run one of the agent's tools.
+type ToolCall struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// ToolResult is the output of a tool call, sent back to the model.
+type ToolResult struct {
+	CallID  string `json:"call_id"`
+	Content string `json:"content"`
+}
+
+func userTurn(input string) []Message {
+	return []Message{{Role: RoleUser, Content: input}}
+}
+
+// ConversationPath returns the path of the saved conversation that Ask
+// follows up on, .kado/conversation.json in the IaC path.
+func (c *AIClient) ConversationPath() string {
+	return c.projectPath("conversation.json")
+}
+
+// Conversation returns the turns of the current conversation: the prompt
+// and response of the last analysis, followed by any follow-up questions
+// and answers.
+func (c *AIClient) Conversation() []Message {
+	return append([]Message(nil), c.conversation...)
+}
+
+// Ask sends a follow-up question about the last analysis, such as "show me
+// the exact Terraform change for finding #3". The question is answered in
+// the context of the saved conversation, so the codebase is not scanned or
+// uploaded again. If this client has not run an analysis, the conversation
+// saved by the last run in the IaC path is continued. Ask is not safe for
+// concurrent use.
+func (c *AIClient) Ask(followup string) (string, error) {
+	if strings.TrimSpace(followup) == "" {
+		return "", fmt.Errorf("follow-up question must not be empty")
+	}
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+	if len(c.conversation) == 0 {
+		conversation, err := loadConversation(c.ConversationPath())
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("there is no analysis to follow up on; run an analysis first")
+		}
+		if err != nil {
+			return "", err
+		}
+		c.conversation = conversation
+	}
+
+	question, _ := c.redact(followup)
+	messages := append(c.Conversation(), Message{Role: RoleUser, Content: question})
+	answer, err := c.respond(messages)
+	if err != nil {
+		return "", err
+	}
+	c.recordConversation(append(messages, Message{Role: RoleAssistant, Content: answer}))
+	return c.deanonymize(answer), nil
+}
+
+// ResetConversation forgets the current conversation and deletes the saved
+// one, so the next Ask requires a new analysis.
+func (c *AIClient) ResetConversation() error {
+	c.conversation = nil
+	if err := os.Remove(c.ConversationPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove conversation: %v", err)
+	}
+	return nil
+}
+
+// recordConversation makes messages the current conversation and saves it
+// for later follow-ups. A conversation that cannot be saved only affects
+// follow-ups from other processes, so the error is reported as a warning.
+func (c *AIClient) recordConversation(messages []Message) {
+	c.conversation = messages
+	if err := saveConversation(c.ConversationPath(), messages); err != nil {
+		fmt.Fprintf(c.messages(), "Warning: failed to save conversation: %v\n", err)
+	}
+}
+
+func loadConversation(path string) ([]Message, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var messages []Message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation %s: %v", path, err)
+	}
+	return messages, nil
+}
+
+// saveConversation writes messages to path. The file is only readable by
+// its owner because it holds the (sanitized) code that was analyzed.
+func saveConversation(path string, messages []Message) error {
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// openAIMessages returns messages in the OpenAI chat format. OpenAI caches
+// long prompt prefixes automatically, so Cache needs no marker. Tool results
+// become one "tool" message each.
+func openAIMessages(messages []Message) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(messages))
+	for _, m := range messages {
+		if len(m.ToolResults) > 0 {
+			for _, result := range m.ToolResults {
+				out = append(out, map[string]interface{}{"role": "tool", "tool_call_id": result.CallID, "content": result.Content})
+			}
+			continue
+		}
+		message := map[string]interface{}{"role": m.Role, "content": m.Content}
+		if len(m.ToolCalls) > 0 {
+			var calls []map[string]interface{}
+			for _, call := range m.ToolCalls {
+				calls = append(calls, map[string]interface{}{
+					"id":       call.ID,
+					"type":     "function",
+					"function": map[string]string{"name": call.Name, "arguments": string(call.Input)},
+				})
+			}
+			message["tool_calls"] = calls
+		}
+		out = append(out, message)
+	}
+	return out
+}
+
+// anthropicMessages returns messages in the Anthropic Messages format,
+// marking cached prefixes with cache_control.
+func anthropicMessages(messages []Message) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(messages))
+	for _, m := range messages {
+		var content interface{} = m.Content
+		if m.Cache || len(m.ToolCalls) > 0 || len(m.ToolResults) > 0 {
+			var blocks []map[string]interface{}
+			if m.Content != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				blocks = append(blocks, map[string]interface{}{"type": "tool_use", "id": call.ID, "name": call.Name, "input": call.Input})
+			}
+			for _, result := range m.ToolResults {
+				blocks = append(blocks, map[string]interface{}{"type": "tool_result", "tool_use_id": result.CallID, "content": result.Content})
+			}
+			if m.Cache && len(blocks) > 0 {
+				blocks[len(blocks)-1]["cache_control"] = map[string]string{"type": "ephemeral"}
+			}
+			content = blocks
+		}
+		out = append(out, map[string]interface{}{"role": m.Role, "content": content})
+	}
+	return out
+}
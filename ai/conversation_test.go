@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAsk(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_security_group\" \"web\" {}\n",
+	})
+
+	var requests [][]Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body.Messages)
+		fmt.Fprintf(w, `{"content":[{"type":"text","text":"answer %d"}]}`, len(requests))
+	}))
+	defer server.Close()
+
+	newClient := func() *AIClient {
+		client, err := NewAIClientWithOptions(
+			WithAPIKey("sk-ant-abc"),
+			WithModel("claude-3-haiku-20240307"),
+			WithProvider("anthropic_messages"),
+			WithIaCPath(tmpDir),
+			WithConsentPolicy(ConsentAutoApprove),
+			WithHTTPClient(testHTTPClient(t, server)),
+		)
+		if err != nil {
+			t.Fatalf("NewAIClientWithOptions failed: %v", err)
+		}
+		return client
+	}
+
+	client := newClient()
+	if _, err := client.Ask("what about finding #3?"); err == nil {
+		t.Errorf("Expected an error when there is no analysis to follow up on")
+	}
+	if _, err := client.RunAI(); err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	answer, err := client.Ask("show me the exact change for finding #1, password = hunter2")
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if answer != "answer 2" {
+		t.Errorf("Expected the provider's answer, got %q", answer)
+	}
+	followup := requests[1]
+	if len(followup) != 3 || followup[0].Role != RoleUser || followup[1].Content != "answer 1" || followup[2].Role != RoleUser {
+		t.Fatalf("Expected the follow-up to carry the conversation, got %+v", followup)
+	}
+	if !strings.Contains(followup[0].Content, "aws_security_group") || strings.Contains(followup[2].Content, "hunter2") {
+		t.Errorf("Expected the original prompt and a sanitized question, got %+v", followup)
+	}
+
+	info, err := os.Stat(client.ConversationPath())
+	if err != nil {
+		t.Fatalf("Expected the conversation to be saved: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the conversation to be private, got %v", info.Mode().Perm())
+	}
+
+	resumed := newClient()
+	if _, err := resumed.Ask("and finding #2?"); err != nil {
+		t.Fatalf("Ask from a new client failed: %v", err)
+	}
+	if len(requests[2]) != 5 {
+		t.Errorf("Expected the saved conversation to be continued, got %d messages", len(requests[2]))
+	}
+
+	if err := resumed.ResetConversation(); err != nil {
+		t.Fatalf("ResetConversation failed: %v", err)
+	}
+	if _, err := resumed.Ask("anything else?"); err == nil {
+		t.Errorf("Expected an error after resetting the conversation")
+	}
+}
@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SectionCrossplane holds the Kubernetes manifests that define or use
+// Crossplane APIs: XRDs, Compositions, composite resources, and claims.
+const SectionCrossplane = "crossplane"
+
+// crossplaneGroup is the API group of XRDs and Compositions.
+const crossplaneGroup = "apiextensions.crossplane.io"
+
+// kubernetesObject is the part of a Kubernetes object that identifies
+// Crossplane definitions and the resources they define.
+type kubernetesObject struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Spec       struct {
+		Group string `yaml:"group"`
+		Names struct {
+			Kind string `yaml:"kind"`
+		} `yaml:"names"`
+		ClaimNames struct {
+			Kind string `yaml:"kind"`
+		} `yaml:"claimNames"`
+	} `yaml:"spec"`
+}
+
+// kubernetesObjects decodes the documents of a manifest, stopping at the
+// first that is not valid YAML.
+func kubernetesObjects(content string) []kubernetesObject {
+	var objects []kubernetesObject
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var object kubernetesObject
+		if err := decoder.Decode(&object); err != nil {
+			return objects
+		}
+		objects = append(objects, object)
+	}
+}
+
+// crossplaneKinds returns the group and kind, as "group/kind", of every
+// composite resource and claim defined by the XRDs in manifests.
+func crossplaneKinds(manifests map[string]string) map[string]bool {
+	kinds := map[string]bool{}
+	for _, content := range manifests {
+		for _, object := range kubernetesObjects(content) {
+			if object.Kind != "CompositeResourceDefinition" || !strings.HasPrefix(object.APIVersion, crossplaneGroup+"/") {
+				continue
+			}
+			for _, kind := range []string{object.Spec.Names.Kind, object.Spec.ClaimNames.Kind} {
+				if kind != "" {
+					kinds[object.Spec.Group+"/"+kind] = true
+				}
+			}
+		}
+	}
+	return kinds
+}
+
+// isCrossplane reports whether a manifest holds an XRD or Composition, or a
+// composite resource or claim of one of kinds.
+func isCrossplane(content string, kinds map[string]bool) bool {
+	for _, object := range kubernetesObjects(content) {
+		group := object.APIVersion
+		if i := strings.LastIndex(group, "/"); i >= 0 {
+			group = group[:i]
+		}
+		if group == crossplaneGroup || kinds[group+"/"+object.Kind] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanCrossplane(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"platform/xrd.yaml": `apiVersion: apiextensions.crossplane.io/v1
+kind: CompositeResourceDefinition
+metadata:
+  name: xpostgres.platform.example.org
+spec:
+  group: platform.example.org
+  names:
+    kind: XPostgres
+  claimNames:
+    kind: Postgres
+`,
+		"platform/composition.yaml": `apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+metadata:
+  name: xpostgres-aws
+`,
+		"apps/team-a/database.yaml": `apiVersion: v1
+kind: Namespace
+metadata:
+  name: team-a
+---
+apiVersion: platform.example.org/v1alpha1
+kind: Postgres
+metadata:
+  name: orders
+spec:
+  storageGB: 20
+`,
+		"apps/team-a/other.yaml": `apiVersion: other.example.org/v1alpha1
+kind: Postgres
+metadata:
+  name: unrelated
+`,
+		"apps/team-a/deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	sections := map[string][]string{}
+	for _, f := range result.Files {
+		sections[f.Section] = append(sections[f.Section], filepath.ToSlash(f.Path))
+	}
+	if got := strings.Join(sections[SectionCrossplane], ","); got != "apps/team-a/database.yaml,platform/composition.yaml,platform/xrd.yaml" {
+		t.Errorf("Expected the XRD, the composition, and the claim, got %s", got)
+	}
+	if got := strings.Join(sections[SectionKubernetes], ","); got != "apps/team-a/deployment.yaml,apps/team-a/other.yaml" {
+		t.Errorf("Expected the other manifests to stay in the Kubernetes section, got %s", got)
+	}
+
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.Crossplane, "kind: Composition") || strings.Contains(data.KubernetesManifests, "kind: Composition") {
+		t.Errorf("Expected the Crossplane manifests in their own section, got %q", data.Crossplane)
+	}
+}
@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SectionDiff names the diff of a diff-scoped analysis in redaction reports.
+const SectionDiff = "git diff"
+
+// DiffContext says how much of the code around the changed files a
+// diff-scoped analysis includes.
+type DiffContext string
+
+const (
+	// DiffContextModule includes the other files of the directories of the
+	// changed files, which for Terraform are the modules they belong to, so
+	// the variables, providers, and resources they refer to are known.
+	DiffContextModule DiffContext = "module"
+	// DiffContextFiles includes the changed files only.
+	DiffContextFiles DiffContext = "files"
+)
+
+func (d DiffContext) valid() bool {
+	return d == DiffContextModule || d == DiffContextFiles
+}
+
+// diffOutputSections hold the output of commands, such as plans and
+// previews, and the state inventory rather than files that can change, so
+// a diff-scoped analysis keeps them: they show the effect of the change.
+var diffOutputSections = map[string]bool{
+	SectionTerraformPlan:       true,
+	SectionAnsibleCheck:        true,
+	SectionTerraformValidation: true,
+	SectionPolicyResults:       true,
+	SectionScannerFindings:     true,
+	SectionCosts:               true,
+	SectionLintFindings:        true,
+	SectionTerraformDrift:      true,
+	SectionVersions:            true,
+	SectionKubernetesCluster:   true,
+	SectionAWSAccount:          true,
+	SectionTerraformState:      true,
+	SectionCDKTemplates:        true,
+	SectionAzureWhatIf:         true,
+	SectionPulumiPreview:       true,
+	SectionKubernetesRendered:  true,
+}
+
+// changedFiles returns the files under the IaC path changed in gitRange,
+// such as main...HEAD, relative to the IaC path, and the diff itself.
+func (c *AIClient) changedFiles(ctx context.Context, gitRange string) ([]string, string, error) {
+	out, err := runCommand(ctx, c.iacPath, nil, "git", "diff", "--relative", "--name-only", gitRange)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list the files changed in %s: %v", gitRange, err)
+	}
+	var files []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, filepath.FromSlash(line))
+		}
+	}
+	diff, err := runCommand(ctx, c.iacPath, nil, "git", "diff", "--relative", gitRange)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to diff %s: %v", gitRange, err)
+	}
+	return files, string(diff), nil
+}
+
+// scopeToDiff keeps the files of result changed in the diff range, with the
+// other files of their directories unless DiffContextFiles is set, and the
+// output of commands. The changed files and the diff are recorded in
+// result, though the diff is left out when paths are anonymized, since it
+// names the files.
+func (c *AIClient) scopeToDiff(ctx context.Context, result *ScanResult) error {
+	changed, diff, err := c.changedFiles(ctx, c.diffRange)
+	if err != nil {
+		return err
+	}
+	result.Changed = changed
+	if !c.anonymizePaths {
+		result.Diff = diff
+	}
+
+	files, dirs := map[string]bool{}, map[string]bool{}
+	for _, path := range changed {
+		files[path] = true
+		dirs[filepath.Dir(path)] = true
+	}
+	var kept []ScannedFile
+	for _, f := range result.Files {
+		if files[f.Path] || diffOutputSections[f.Section] || (c.diffContext != DiffContextFiles && dirs[filepath.Dir(f.Path)]) {
+			kept = append(kept, f)
+		}
+	}
+	var stacks []TerragruntStack
+	for _, stack := range result.Stacks {
+		if dirs[stack.Dir] {
+			stacks = append(stacks, stack)
+		}
+	}
+	fmt.Fprintf(c.messages(), "%d files changed in %s; analyzing %d of the %d files scanned.\n", len(changed), c.diffRange, len(kept), len(result.Files))
+	result.Files, result.Stacks = kept, stacks
+	return nil
+}
@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScopeToDiff(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/network/main.tf":      "resource \"aws_vpc\" \"main\" {}\n",
+		"terraform/network/variables.tf": "variable \"cidr\" {}\n",
+		"terraform/compute/main.tf":      "resource \"aws_instance\" \"web\" {}\n",
+		"terraform/plan.json":            `{"resource_changes":[]}`,
+	})
+
+	diff := "diff --git a/terraform/network/main.tf b/terraform/network/main.tf\n+  cidr_block = var.cidr\n"
+	var calls []string
+	original := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		if args[2] == "--name-only" {
+			return []byte("terraform/network/main.tf\nterraform/removed.tf\n"), nil
+		}
+		return []byte(diff), nil
+	}
+	defer func() { runCommand = original }()
+
+	scan := func(client *AIClient) (*ScanResult, string) {
+		result, err := client.Scan(context.Background())
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		var paths []string
+		for _, f := range result.Files {
+			paths = append(paths, filepath.ToSlash(f.Path))
+		}
+		return result, strings.Join(paths, ",")
+	}
+
+	client := &AIClient{iacPath: tmpDir, diffRange: "main...HEAD"}
+	result, got := scan(client)
+	if got != "terraform/network/main.tf,terraform/network/variables.tf,terraform/plan.json" {
+		t.Errorf("Expected the changed module and the plan, got %s", got)
+	}
+	if calls[0] != "git diff --relative --name-only main...HEAD" || calls[1] != "git diff --relative main...HEAD" {
+		t.Errorf("Expected the changed files and the diff of the range, got %v", calls)
+	}
+	if len(result.Changed) != 2 || result.Diff != diff {
+		t.Errorf("Expected the changed files and the diff to be recorded, got %v and %q", result.Changed, result.Diff)
+	}
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.Diff, "cidr_block = var.cidr") {
+		t.Errorf("Expected the diff in the prompt data, got %q", data.Diff)
+	}
+
+	client.diffContext = DiffContextFiles
+	if _, got := scan(client); got != "terraform/network/main.tf,terraform/plan.json" {
+		t.Errorf("Expected only the changed file and the plan, got %s", got)
+	}
+
+	client.anonymizePaths = true
+	if result, _ := scan(client); result.Diff != "" {
+		t.Errorf("Expected the diff to be left out with anonymized paths, got %q", result.Diff)
+	}
+}
@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SectionDocker holds the Dockerfiles and Compose files of a scan.
+const SectionDocker = "docker files"
+
+// dockerfileName matches the names of Dockerfiles: Dockerfile and
+// Containerfile, with or without a suffix such as .prod, and *.dockerfile.
+var dockerfileName = regexp.MustCompile(`(?i)^((docker|container)file([.-][\w.-]+)?|[\w.-]+\.dockerfile)$`)
+
+// composeFileName matches the names of Compose files, such as compose.yaml
+// and docker-compose.override.yml.
+var composeFileName = regexp.MustCompile(`^(docker-)?compose(\.[\w.-]+)?\.ya?ml$`)
+
+func isDockerfile(name string) bool {
+	return dockerfileName.MatchString(name) && !strings.HasSuffix(strings.ToLower(name), ".dockerignore")
+}
+
+func isComposeFile(name string) bool {
+	return composeFileName.MatchString(name)
+}
+
+// scanDocker adds the Dockerfiles and Compose files under the IaC path to
+// result, other than those already collected.
+func (c *AIClient) scanDocker(result *ScanResult) {
+	collected := map[string]bool{}
+	for _, f := range result.Files {
+		collected[f.Path] = true
+	}
+	ignore := c.ignoreRules()
+	c.walk(result, c.iacPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != c.iacPath && (discoverySkipDirs[info.Name()] || ignore.ignored(c.iacPath, path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !(isDockerfile(info.Name()) || isComposeFile(info.Name())) || collected[result.rel(path)] {
+			return nil
+		}
+		if ignore.ignored(c.iacPath, path, false) {
+			result.skip(path, ignoredReason)
+			return nil
+		}
+		c.addFile(result, SectionDocker, path)
+		return nil
+	})
+}
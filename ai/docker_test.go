@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDockerFileNames(t *testing.T) {
+	testCases := []struct {
+		name       string
+		dockerfile bool
+		compose    bool
+	}{
+		{"Dockerfile", true, false},
+		{"Dockerfile.prod", true, false},
+		{"dockerfile", true, false},
+		{"Containerfile", true, false},
+		{"api.dockerfile", true, false},
+		{"Dockerfile.dockerignore", false, false},
+		{".dockerignore", false, false},
+		{"docker-compose.yml", false, true},
+		{"docker-compose.override.yaml", false, true},
+		{"compose.yaml", false, true},
+		{"compose.md", false, false},
+		{"my-compose.yml", false, false},
+	}
+	for _, tc := range testCases {
+		if got := isDockerfile(tc.name); got != tc.dockerfile {
+			t.Errorf("Expected isDockerfile(%q) to be %v, got %v", tc.name, tc.dockerfile, got)
+		}
+		if got := isComposeFile(tc.name); got != tc.compose {
+			t.Errorf("Expected isComposeFile(%q) to be %v, got %v", tc.name, tc.compose, got)
+		}
+	}
+}
+
+func TestScanDocker(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"Dockerfile":                     "FROM node:latest\nENV API_TOKEN=abc123def456ghi789\n",
+		"docker-compose.yml":             "services:\n  web:\n    privileged: true\n",
+		"services/api/Dockerfile.prod":   "FROM golang:1.21\n",
+		"node_modules/pkg/Dockerfile":    "FROM scratch\n",
+		"services/api/docker/notes.yaml": "services: {}\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var paths []string
+	for _, f := range result.Section(SectionDocker) {
+		paths = append(paths, filepath.ToSlash(f.Path)+" ("+f.Language+")")
+	}
+	expected := "Dockerfile (dockerfile), docker-compose.yml (yaml), services/api/Dockerfile.prod (dockerfile)"
+	if got := strings.Join(paths, ", "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.DockerFiles, "privileged: true") || strings.Contains(data.DockerFiles, "abc123def456ghi789") {
+		t.Errorf("Expected the sanitized Docker files in the prompt data, got %q", data.DockerFiles)
+	}
+}
@@ -0,0 +1,148 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SectionTerraformDrift holds the resources whose real state differs from
+// the Terraform state, from refresh-only plans or drift reports.
+const SectionTerraformDrift = "terraform drift"
+
+// driftedResource is a resource that drifted: changed or deleted outside
+// Terraform, or created outside it and so unmanaged.
+type driftedResource struct {
+	Address    string
+	Drift      string
+	Attributes []string
+}
+
+// driftOrder orders drifted resources from the most to the least likely to
+// break the next apply.
+var driftOrder = map[string]int{"deleted": 0, "changed": 1, "unmanaged": 2}
+
+// addDrift adds the drift of every Terraform plan in result, the changes
+// terraform found outside Terraform when it refreshed the state, most
+// useful from terraform plan -refresh-only. Plans without drift add nothing.
+func addDrift(result *ScanResult) {
+	for _, plan := range result.Section(SectionTerraformPlan) {
+		drift, err := planDrift(plan.Content)
+		if err != nil || len(drift) == 0 {
+			continue
+		}
+		addOutput(result, SectionTerraformDrift, result.displayName(plan)+".drift", renderDrift(drift))
+		result.Files[len(result.Files)-1].Language = "markdown"
+	}
+}
+
+// addDriftReport adds the drift report at path to result: a plan in JSON
+// format, as written by terraform show -json for a refresh-only plan, or the
+// JSON output of driftctl scan.
+func (c *AIClient) addDriftReport(result *ScanResult, path string) {
+	content, err := c.extractFileContent(path)
+	if err != nil {
+		result.skip(path, fmt.Sprintf("unreadable: %v", err))
+		fmt.Fprintf(c.messages(), "Warning: drift report %s is left out: %v\n", path, err)
+		return
+	}
+	var drift []driftedResource
+	if isPlanJSON(content) {
+		drift, err = planDrift(content)
+	} else {
+		drift, err = driftctlDrift(content)
+	}
+	if err != nil {
+		result.skip(path, err.Error())
+		fmt.Fprintf(c.messages(), "Warning: drift report %s is left out: %v\n", path, err)
+		return
+	}
+	addOutput(result, SectionTerraformDrift, path, renderDrift(drift))
+	result.Files[len(result.Files)-1].Language = "markdown"
+}
+
+// planDrift returns the resource drift of a plan in JSON format.
+func planDrift(content string) ([]driftedResource, error) {
+	var plan struct {
+		ResourceDrift []planChange `json:"resource_drift"`
+	}
+	if err := json.Unmarshal([]byte(content), &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %v", err)
+	}
+	var drift []driftedResource
+	for _, rc := range plan.ResourceDrift {
+		switch rc.action() {
+		case "update":
+			drift = append(drift, driftedResource{Address: rc.Address, Drift: "changed", Attributes: rc.attributes()})
+		case "delete":
+			drift = append(drift, driftedResource{Address: rc.Address, Drift: "deleted"})
+		}
+	}
+	return drift, nil
+}
+
+// driftctlDrift returns the drift in the JSON output of driftctl scan:
+// managed resources that changed or are missing, and unmanaged resources.
+func driftctlDrift(content string) ([]driftedResource, error) {
+	type driftctlResource struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}
+	var report struct {
+		Summary     *struct{}          `json:"summary"`
+		Missing     []driftctlResource `json:"missing"`
+		Unmanaged   []driftctlResource `json:"unmanaged"`
+		Differences []struct {
+			Res       driftctlResource `json:"res"`
+			Changelog []struct {
+				Path []string `json:"path"`
+			} `json:"changelog"`
+		} `json:"differences"`
+	}
+	if err := json.Unmarshal([]byte(content), &report); err != nil || report.Summary == nil {
+		return nil, fmt.Errorf("not a refresh-only plan in JSON format or a driftctl report")
+	}
+	var drift []driftedResource
+	for _, d := range report.Differences {
+		attributes := map[string]bool{}
+		for _, change := range d.Changelog {
+			if len(change.Path) > 0 {
+				attributes[change.Path[0]] = true
+			}
+		}
+		var names []string
+		for name := range attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		drift = append(drift, driftedResource{Address: d.Res.Type + "." + d.Res.ID, Drift: "changed", Attributes: names})
+	}
+	for _, r := range report.Missing {
+		drift = append(drift, driftedResource{Address: r.Type + "." + r.ID, Drift: "deleted"})
+	}
+	for _, r := range report.Unmanaged {
+		drift = append(drift, driftedResource{Address: r.Type + "." + r.ID, Drift: "unmanaged"})
+	}
+	return drift, nil
+}
+
+// renderDrift renders drift as a table of the drifted resources, deleted
+// ones first, with the attributes that changed.
+func renderDrift(drift []driftedResource) string {
+	if len(drift) == 0 {
+		return "No drift.\n"
+	}
+	sort.SliceStable(drift, func(i, j int) bool {
+		if drift[i].Drift != drift[j].Drift {
+			return driftOrder[drift[i].Drift] < driftOrder[drift[j].Drift]
+		}
+		return drift[i].Address < drift[j].Address
+	})
+	var out strings.Builder
+	fmt.Fprintf(&out, "%d resources drifted.\n\n| Resource | Drift | Changed attributes |\n| --- | --- | --- |\n", len(drift))
+	for _, d := range drift {
+		fmt.Fprintf(&out, "| %s | %s | %s |\n", d.Address, d.Drift, strings.Join(d.Attributes, ", "))
+	}
+	return out.String()
+}
@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanDrift(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_security_group\" \"web\" {}\n",
+		"terraform/plan.json": `{"planned_values":{},"resource_drift":[
+  {"address":"aws_security_group.web","change":{"actions":["update"],"before":{"ingress":[],"name":"web"},"after":{"ingress":[{"cidr_blocks":["0.0.0.0/0"]}],"name":"web"}}},
+  {"address":"aws_instance.old","change":{"actions":["delete"],"before":{"id":"i-1"},"after":null}}
+]}`,
+		"drift.json": `{"summary":{"total_resources":3},"unmanaged":[{"id":"manual-bucket","type":"aws_s3_bucket"}],"missing":[],"differences":[{"res":{"id":"web","type":"aws_security_group"},"changelog":[{"type":"update","path":["tags","Owner"]}]}]}`,
+	})
+
+	client := &AIClient{iacPath: tmpDir, analysisType: AnalysisDrift, driftReport: "drift.json"}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	drift := result.Section(SectionTerraformDrift)
+	if len(drift) != 2 {
+		t.Fatalf("Expected the drift of the plan and the report, got %+v", drift)
+	}
+	expected := `2 resources drifted.
+
+| Resource | Drift | Changed attributes |
+| --- | --- | --- |
+| aws_instance.old | deleted |  |
+| aws_security_group.web | changed | ingress |
+`
+	if drift[0].Content != expected || drift[0].Path != filepath.Join("terraform", "plan.json.drift") {
+		t.Errorf("Expected the plan's drift:\n%s\ngot %s:\n%s", expected, drift[0].Path, drift[0].Content)
+	}
+	if !strings.Contains(drift[1].Content, "| aws_security_group.web | changed | tags |\n| aws_s3_bucket.manual-bucket | unmanaged |  |") {
+		t.Errorf("Expected the driftctl report's drift, got %q", drift[1].Content)
+	}
+
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.Drift, "aws_s3_bucket.manual-bucket") {
+		t.Errorf("Expected the drift in the prompt, got %q", data.Drift)
+	}
+	if !strings.Contains(personas[AnalysisDrift].guidance, "import block") {
+		t.Errorf("Expected the drift persona to propose imports")
+	}
+
+	// Outside of drift analyses, only the drift report is presented.
+	client.analysisType = AnalysisComprehensive
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if drift := result.Section(SectionTerraformDrift); len(drift) != 1 || drift[0].Path != "drift.json" {
+		t.Errorf("Expected only the drift report, got %+v", drift)
+	}
+}
@@ -0,0 +1,237 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Embedder turns text into embedding vectors for the local RAG index. It
+// follows the same pluggable-provider pattern as completions, so air-gapped
+// users can index with a local model.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Embedding providers accepted by AI_EMBEDDING_PROVIDER.
+const (
+	EmbeddingOpenAI = "openai"
+	EmbeddingVertex = "vertex"
+	EmbeddingOllama = "ollama"
+)
+
+const (
+	defaultOpenAIEmbeddingModel = "text-embedding-3-small"
+	defaultVertexEmbeddingModel = "text-embedding-004"
+	defaultOllamaEmbeddingModel = "nomic-embed-text"
+	defaultOllamaURL            = "http://localhost:11434"
+)
+
+// postJSON sends payload to url and decodes the JSON response into out.
+func postJSON(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, bytes.TrimSpace(data))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse embedding response: %v", err)
+	}
+	return nil
+}
+
+// OpenAIEmbedder uses the OpenAI embeddings API.
+type OpenAIEmbedder struct {
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+}
+
+// NewOpenAIEmbedder returns an Embedder using the OpenAI embeddings API.
+func NewOpenAIEmbedder(apiKey string, model string) *OpenAIEmbedder {
+	if model == "" {
+		model = defaultOpenAIEmbeddingModel
+	}
+	return &OpenAIEmbedder{APIKey: apiKey, Model: model}
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	err := postJSON(ctx, e.HTTPClient, "https://api.openai.com/v1/embeddings",
+		map[string]string{"Authorization": "Bearer " + e.APIKey},
+		map[string]interface{}{"model": e.Model, "input": texts}, &response)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Data))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
+
+// VertexEmbedder uses the Vertex AI text embedding models. When no access
+// token is set, one is obtained from gcloud for every call.
+type VertexEmbedder struct {
+	Project     string
+	Location    string
+	Model       string
+	AccessToken string
+	HTTPClient  *http.Client
+}
+
+// NewVertexEmbedder returns an Embedder using Vertex AI in project and
+// location, authenticating with the gcloud CLI.
+func NewVertexEmbedder(project string, location string, model string) *VertexEmbedder {
+	if location == "" {
+		location = "us-central1"
+	}
+	if model == "" {
+		model = defaultVertexEmbeddingModel
+	}
+	return &VertexEmbedder{Project: project, Location: location, Model: model}
+}
+
+func (e *VertexEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	token := e.AccessToken
+	if token == "" {
+		out, err := runCommand(ctx, "", nil, "gcloud", "auth", "print-access-token")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get a Google Cloud access token: %v", err)
+		}
+		token = strings.TrimSpace(string(out))
+	}
+
+	var instances []map[string]string
+	for _, text := range texts {
+		instances = append(instances, map[string]string{"content": text})
+	}
+	var response struct {
+		Predictions []struct {
+			Embeddings struct {
+				Values []float32 `json:"values"`
+			} `json:"embeddings"`
+		} `json:"predictions"`
+	}
+	url := fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/publishers/google/models/%s:predict",
+		e.Location, e.Project, e.Location, e.Model)
+	err := postJSON(ctx, e.HTTPClient, url, map[string]string{"Authorization": "Bearer " + token},
+		map[string]interface{}{"instances": instances}, &response)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Predictions) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Predictions))
+	}
+	vectors := make([][]float32, len(texts))
+	for i, prediction := range response.Predictions {
+		vectors[i] = prediction.Embeddings.Values
+	}
+	return vectors, nil
+}
+
+// OllamaEmbedder uses a local Ollama server, so no code leaves the machine.
+type OllamaEmbedder struct {
+	BaseURL    string
+	Model      string
+	HTTPClient *http.Client
+}
+
+// NewOllamaEmbedder returns an Embedder using the Ollama server at baseURL
+// (http://localhost:11434 when empty).
+func NewOllamaEmbedder(baseURL string, model string) *OllamaEmbedder {
+	if baseURL == "" {
+		baseURL = defaultOllamaURL
+	}
+	if model == "" {
+		model = defaultOllamaEmbeddingModel
+	}
+	return &OllamaEmbedder{BaseURL: strings.TrimRight(baseURL, "/"), Model: model}
+}
+
+func (e *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var response struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	err := postJSON(ctx, e.HTTPClient, e.BaseURL+"/api/embed", nil,
+		map[string]interface{}{"model": e.Model, "input": texts}, &response)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Embeddings))
+	}
+	return response.Embeddings, nil
+}
+
+// embedderFromConfig builds the Embedder selected by AI_EMBEDDING_PROVIDER.
+func embedderFromConfig(values map[string]string) (Embedder, error) {
+	model := values["AI_EMBEDDING_MODEL"]
+	switch provider := values["AI_EMBEDDING_PROVIDER"]; provider {
+	case EmbeddingOpenAI:
+		apiKey := values["AI_EMBEDDING_API_KEY"]
+		if apiKey == "" {
+			apiKey = values["openai.api_key"]
+		}
+		if apiKey == "" && values["AI_CLIENT"] == "chatgpt" {
+			apiKey = values["AI_API_KEY"]
+		}
+		apiKey, err := resolveSecret(context.Background(), apiKey)
+		if err != nil {
+			return nil, err
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("AI_EMBEDDING_API_KEY is required for the openai embedding provider")
+		}
+		return NewOpenAIEmbedder(apiKey, model), nil
+	case EmbeddingVertex:
+		project := values["AI_EMBEDDING_PROJECT"]
+		if project == "" {
+			return nil, fmt.Errorf("AI_EMBEDDING_PROJECT is required for the vertex embedding provider")
+		}
+		return NewVertexEmbedder(project, values["AI_EMBEDDING_LOCATION"], model), nil
+	case EmbeddingOllama:
+		return NewOllamaEmbedder(values["AI_EMBEDDING_URL"], model), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q; expected %s, %s, or %s", provider, EmbeddingOpenAI, EmbeddingVertex, EmbeddingOllama)
+	}
+}
@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEmbedders(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		var request map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&request)
+		switch r.URL.Path {
+		case "/v1/embeddings":
+			fmt.Fprint(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`)
+		case "/api/embed":
+			fmt.Fprint(w, `{"embeddings":[[1,0],[0,1]]}`)
+		default:
+			fmt.Fprint(w, `{"predictions":[{"embeddings":{"values":[1,0]}},{"embeddings":{"values":[0,1]}}]}`)
+		}
+	}))
+	defer server.Close()
+
+	openAI := NewOpenAIEmbedder("sk-proj-abc", "")
+	openAI.HTTPClient = testHTTPClient(t, server)
+	vertex := NewVertexEmbedder("infra-prod", "", "")
+	vertex.AccessToken = "test-token"
+	vertex.HTTPClient = testHTTPClient(t, server)
+	ollama := NewOllamaEmbedder(server.URL, "")
+
+	for _, embedder := range []Embedder{openAI, vertex, ollama} {
+		vectors, err := embedder.Embed(context.Background(), []string{"first", "second"})
+		if err != nil {
+			t.Fatalf("%T: Embed failed: %v", embedder, err)
+		}
+		if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+			t.Errorf("%T: unexpected vectors %v", embedder, vectors)
+		}
+	}
+
+	expected := []string{
+		"/v1/embeddings",
+		"/v1/projects/infra-prod/locations/us-central1/publishers/google/models/text-embedding-004:predict",
+		"/api/embed",
+	}
+	for i, want := range expected {
+		if paths[i] != want {
+			t.Errorf("Expected request to %s, got %s", want, paths[i])
+		}
+	}
+}
+
+func TestEmbedderFromConfig(t *testing.T) {
+	embedder, err := embedderFromConfig(map[string]string{
+		"AI_CLIENT":             "chatgpt",
+		"AI_API_KEY":            "sk-proj-abc",
+		"AI_EMBEDDING_PROVIDER": "openai",
+	})
+	if err != nil {
+		t.Fatalf("embedderFromConfig failed: %v", err)
+	}
+	if openAI, ok := embedder.(*OpenAIEmbedder); !ok || openAI.APIKey != "sk-proj-abc" {
+		t.Errorf("Expected an OpenAI embedder reusing AI_API_KEY, got %#v", embedder)
+	}
+
+	if _, err := embedderFromConfig(map[string]string{"AI_EMBEDDING_PROVIDER": "vertex"}); err == nil {
+		t.Errorf("Expected an error when the vertex project is missing")
+	}
+}
@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Example is an input and the ideal response to it, sent ahead of the prompt
+// as a few-shot example so the model follows the expected format. Examples
+// help most with structured output from weaker or local models.
+type Example struct {
+	// Template is the prompt template the example applies to, such as
+	// "findings". An empty template applies to every template.
+	Template string `json:"template,omitempty"`
+	Input    string `json:"input"`
+	Output   string `json:"output"`
+}
+
+// loadExamples returns the examples set with WithExamples, or else those in
+// .kado/examples.json, which is optional.
+func (c *AIClient) loadExamples() ([]Example, error) {
+	if c.examples != nil {
+		return c.examples, nil
+	}
+	path := c.projectPath("examples.json")
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	var examples []Example
+	if err := json.Unmarshal(content, &examples); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	for i, example := range examples {
+		if example.Input == "" || example.Output == "" {
+			return nil, fmt.Errorf("example %d in %s needs both an input and an output", i+1, path)
+		}
+	}
+	return examples, nil
+}
+
+// fewShot returns the examples for template, sanitized like the code, as
+// alternating user and assistant turns.
+func (c *AIClient) fewShot(template string) ([]Message, error) {
+	examples, err := c.loadExamples()
+	if err != nil {
+		return nil, err
+	}
+	var messages []Message
+	for _, example := range examples {
+		if example.Template != "" && example.Template != template {
+			continue
+		}
+		input, _ := c.redact(example.Input)
+		output, _ := c.redact(example.Output)
+		messages = append(messages, Message{Role: RoleUser, Content: input}, Message{Role: RoleAssistant, Content: output})
+	}
+	return messages, nil
+}
+
+// withExamples returns the conversation sending input after examples.
+func withExamples(examples []Message, input string) []Message {
+	return append(append([]Message(nil), examples...), userTurn(input)...)
+}
@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFewShot(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kado/examples.json": `[
+			{"template": "findings", "input": "password = \"hunter2hunter2\"", "output": "[{\"id\":\"KADO-101\"}]"},
+			{"template": "analyze", "input": "resource \"aws_s3_bucket\" \"b\" {}", "output": "## Security"},
+			{"input": "any input", "output": "any output"}
+		]`,
+	})
+	client := &AIClient{iacPath: tmpDir}
+
+	messages, err := client.fewShot("findings")
+	if err != nil {
+		t.Fatalf("fewShot failed: %v", err)
+	}
+	if len(messages) != 4 {
+		t.Fatalf("Expected 2 examples as 4 turns, got %+v", messages)
+	}
+	if messages[0].Role != RoleUser || messages[1].Role != RoleAssistant || messages[1].Content != `[{"id":"KADO-101"}]` {
+		t.Errorf("Expected alternating user and assistant turns, got %+v", messages)
+	}
+	if strings.Contains(messages[0].Content, "hunter2hunter2") {
+		t.Errorf("Expected the example input to be sanitized, got %q", messages[0].Content)
+	}
+	if messages[2].Content != "any input" {
+		t.Errorf("Expected the example without a template to apply, got %q", messages[2].Content)
+	}
+
+	client.examples = []Example{{Input: "in", Output: "out"}}
+	if messages, err := client.fewShot("findings"); err != nil || len(messages) != 2 || messages[0].Content != "in" {
+		t.Errorf("Expected WithExamples to replace the file, got %+v, %v", messages, err)
+	}
+
+	testCases := []string{`not json`, `[{"input": "no output"}]`}
+	for _, content := range testCases {
+		writeTestFiles(t, tmpDir, map[string]string{".kado/examples.json": content})
+		client := &AIClient{iacPath: tmpDir}
+		if _, err := client.fewShot("analyze"); err == nil {
+			t.Errorf("Expected an error for examples %s", content)
+		}
+	}
+}
+
+func TestRunWithExamples(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_vpc\" \"main\" {}\n",
+	})
+
+	var messages []Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		messages = body.Messages
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"[]"}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+		WithExamples(Example{Template: "findings", Input: "example code", Output: "[]"}),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	if _, err := client.RunFindings(); err != nil {
+		t.Fatalf("RunFindings failed: %v", err)
+	}
+	if len(messages) != 3 || messages[0].Content != "example code" || messages[1].Role != RoleAssistant || !strings.Contains(messages[2].Content, "aws_vpc") {
+		t.Errorf("Expected the example ahead of the prompt, got %+v", messages)
+	}
+
+	input, err := os.ReadFile(filepath.Join(tmpDir, "ai_input.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read the AI input: %v", err)
+	}
+	if !strings.HasPrefix(string(input), "----- example user turn -----\n\nexample code") {
+		t.Errorf("Expected the example in the saved AI input, got %q", input)
+	}
+}
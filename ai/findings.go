@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Severities of a finding, from most to least severe.
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityInfo     = "info"
+)
+
+// Finding is a single structured issue reported by the analysis. ID is the
+// finding's category in FindingCategories, and Fingerprint identifies this
+// instance of it across runs, even when the code around it moves.
+type Finding struct {
+	ID          string `json:"id"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	File        string `json:"file"`
+	StartLine   int    `json:"start_line"`
+	EndLine     int    `json:"end_line"`
+	Severity    string `json:"severity"`
+	Title       string `json:"title"`
+	Message     string `json:"message"`
+}
+
+// RunFindings analyzes the IaC code like RunAI, but asks the provider for
+// structured findings with file and line locations instead of prose. File
+// paths are made relative to the IaC path where possible, every finding is
+// fingerprinted, and findings matched by .kado/suppressions or recorded in
+// .kado/baseline.json are left out.
+func (c *AIClient) RunFindings() ([]Finding, error) {
+	text, err := c.run("findings", c.question)
+	if err != nil {
+		return nil, err
+	}
+	findings, err := ParseFindings(text)
+	if err != nil {
+		return nil, err
+	}
+	for i := range findings {
+		if rel, err := filepath.Rel(c.iacPath, findings[i].File); err == nil && !strings.HasPrefix(rel, "..") {
+			findings[i].File = filepath.ToSlash(rel)
+		}
+		findings[i].Fingerprint = fingerprint(findings[i], c.flaggedCode(findings[i]))
+	}
+	if c.noise != nil {
+		findings = c.noise.deprioritizeFindings(findings)
+	}
+	return c.filterFindings(findings)
+}
+
+// ParseFindings extracts the JSON array of findings from a provider
+// response, tolerating a surrounding Markdown code fence or prose. Severities
+// are mapped onto the scale of Severities, with synonyms such as "moderate"
+// recognized and unknown severities becoming SeverityInfo. Unknown category
+// IDs become CategoryUncategorized, and a missing end line defaults to the
+// start line.
+func ParseFindings(text string) ([]Finding, error) {
+	start := strings.Index(text, "[")
+	end := strings.LastIndex(text, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("response does not contain a JSON array of findings")
+	}
+	var findings []Finding
+	if err := json.Unmarshal([]byte(text[start:end+1]), &findings); err != nil {
+		return nil, fmt.Errorf("failed to parse findings: %v", err)
+	}
+	for i := range findings {
+		f := &findings[i]
+		f.Severity, _ = normalizeSeverity(f.Severity)
+		f.ID = strings.ToUpper(strings.TrimSpace(f.ID))
+		if _, ok := LookupFindingCategory(f.ID); !ok {
+			f.ID = CategoryUncategorized
+		}
+		if f.EndLine < f.StartLine {
+			f.EndLine = f.StartLine
+		}
+	}
+	return findings, nil
+}
+
+// numberLines prefixes every line of scanned content with its line number
+// within its file, restarting at each "File: " header, so the provider can
+// refer to exact locations. Blank lines are counted but left unprefixed.
+func numberLines(content string) string {
+	var out strings.Builder
+	line := 0
+	for _, text := range strings.SplitAfter(content, "\n") {
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "File: ") {
+			line = 0
+			out.WriteString(text)
+			continue
+		}
+		line++
+		if strings.TrimSpace(text) == "" {
+			out.WriteString(text)
+			continue
+		}
+		fmt.Fprintf(&out, "%d: %s", line, text)
+	}
+	return out.String()
+}
+
+// flaggedCode returns the sanitized lines a finding points at, or an empty
+// string when they cannot be read.
+func (c *AIClient) flaggedCode(f Finding) string {
+	if f.File == "" || f.StartLine < 1 {
+		return ""
+	}
+	file := f.File
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(c.iacPath, filepath.FromSlash(file))
+	}
+	content, err := c.extractFileContent(file)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(content, "\n")
+	if f.StartLine > len(lines) {
+		return ""
+	}
+	end := f.EndLine
+	if end > len(lines) {
+		end = len(lines)
+	}
+	code, _ := c.redact(strings.Join(lines[f.StartLine-1:end], "\n"))
+	return code
+}
+
+// fingerprint identifies a finding by its category, file, and the code it
+// points at with whitespace collapsed, so it survives the code moving within
+// the file and reformatting. Without code, the title stands in for it.
+func fingerprint(f Finding, code string) string {
+	subject := strings.Join(strings.Fields(code), " ")
+	if subject == "" {
+		subject = strings.ToLower(strings.Join(strings.Fields(f.Title), " "))
+	}
+	sum := sha256.Sum256([]byte(f.ID + "\x00" + f.File + "\x00" + subject))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFindings(t *testing.T) {
+	text := "Here are the findings:\n```json\n" +
+		`[{"file":"terraform/main.tf","start_line":3,"end_line":5,"severity":"HIGH","title":"Open SSH","message":"Restrict the CIDR."},` +
+		`{"file":"terraform/main.tf","start_line":9,"severity":"urgent","title":"No tags","message":"Add tags."}]` +
+		"\n```\n"
+	findings, err := ParseFindings(text)
+	if err != nil {
+		t.Fatalf("ParseFindings failed: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d", len(findings))
+	}
+	if findings[0].Severity != SeverityHigh || findings[0].EndLine != 5 {
+		t.Errorf("Expected a high finding ending at line 5, got %+v", findings[0])
+	}
+	if findings[0].ID != CategoryUncategorized {
+		t.Errorf("Expected a finding without an ID to be uncategorized, got %s", findings[0].ID)
+	}
+	if findings[1].Severity != SeverityInfo || findings[1].EndLine != 9 {
+		t.Errorf("Expected an unknown severity to become info and the end line to default to the start, got %+v", findings[1])
+	}
+
+	if _, err := ParseFindings("I could not find any issues."); err == nil {
+		t.Errorf("Expected an error for a response without findings")
+	}
+}
+
+func TestRunFindings(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_security_group\" \"web\" {\n  ingress {\n    from_port = 22\n  }\n}\n",
+	})
+	mainTF := filepath.Join(tmpDir, "terraform", "main.tf")
+
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+		findings, _ := json.Marshal(fmt.Sprintf(`[{"id":"kado-101","file":%q,"start_line":2,"end_line":4,"severity":"high","title":"Open SSH","message":"Restrict ingress."}]`, mainTF))
+		fmt.Fprintf(w, `{"content":[{"type":"text","text":%s}]}`, findings)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	findings, err := client.RunFindings()
+	if err != nil {
+		t.Fatalf("RunFindings failed: %v", err)
+	}
+	if !strings.Contains(prompt, "2:   ingress {") {
+		t.Errorf("Expected the prompt to number the code lines, got %q", prompt)
+	}
+	if len(findings) != 1 || findings[0].File != "terraform/main.tf" || findings[0].ID != "KADO-101" {
+		t.Fatalf("Expected one KADO-101 finding relative to the IaC path, got %+v", findings)
+	}
+
+	// Moving the flagged code down the file keeps the fingerprint stable.
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "# Web tier\n\nresource \"aws_security_group\" \"web\" {\n  ingress {\n    from_port   =   22\n  }\n}\n",
+	})
+	moved := findings[0]
+	moved.StartLine, moved.EndLine = 4, 6
+	if got := fingerprint(moved, client.flaggedCode(moved)); got != findings[0].Fingerprint {
+		t.Errorf("Expected the fingerprint to survive the code moving, got %s and %s", got, findings[0].Fingerprint)
+	}
+}
@@ -0,0 +1,331 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fixesDir is the directory in the IaC path that RunFixes writes patches to.
+const fixesDir = "kado-fixes"
+
+// Hunk is one contiguous change of a Patch. Lines hold the hunk's body,
+// each prefixed with ' ' for context, '-' for a removed line, or '+' for an
+// added line.
+type Hunk struct {
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Lines    []string `json:"lines"`
+}
+
+// Patch is a unified diff of a single file. File is relative to the IaC
+// path. Error explains why the patch does not apply cleanly, and is empty
+// for a valid patch.
+type Patch struct {
+	File  string `json:"file"`
+	Hunks []Hunk `json:"hunks"`
+	Error string `json:"error,omitempty"`
+}
+
+// String renders the patch as a unified diff.
+func (p Patch) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", filepath.ToSlash(p.File), filepath.ToSlash(p.File))
+	for _, h := range p.Hunks {
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+		for _, line := range h.Lines {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatches extracts the unified diffs from a provider response,
+// tolerating Markdown code fences and prose around them. File names are
+// returned as written, including any a/ or b/ prefix.
+func ParsePatches(text string) ([]Patch, error) {
+	var patches []Patch
+	var hunk *Hunk
+	oldLeft, newLeft := 0, 0
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			if line == "" {
+				line = " "
+			}
+			switch line[0] {
+			case ' ':
+				oldLeft--
+				newLeft--
+			case '-':
+				oldLeft--
+			case '+':
+				newLeft--
+			case '\\':
+				continue
+			default:
+				return nil, fmt.Errorf("hunk of %s ends early at %q", patches[len(patches)-1].File, line)
+			}
+			hunk.Lines = append(hunk.Lines, line)
+			continue
+		}
+
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			file := diffFileName(lines[i+1][4:])
+			if file == "/dev/null" {
+				file = diffFileName(line[4:])
+			}
+			patches = append(patches, Patch{File: file})
+			hunk = nil
+			i++
+			continue
+		}
+		m := hunkHeader.FindStringSubmatch(line)
+		if m == nil || len(patches) == 0 {
+			continue
+		}
+		p := &patches[len(patches)-1]
+		p.Hunks = append(p.Hunks, Hunk{
+			OldStart: atoi(m[1]),
+			OldLines: atoiDefault(m[2], 1),
+			NewStart: atoi(m[3]),
+			NewLines: atoiDefault(m[4], 1),
+		})
+		hunk = &p.Hunks[len(p.Hunks)-1]
+		oldLeft, newLeft = hunk.OldLines, hunk.NewLines
+	}
+	if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+		return nil, fmt.Errorf("hunk of %s is truncated", patches[len(patches)-1].File)
+	}
+	var valid []Patch
+	for _, p := range patches {
+		if len(p.Hunks) > 0 {
+			valid = append(valid, p)
+		}
+	}
+	if len(valid) == 0 {
+		return nil, fmt.Errorf("response does not contain a unified diff")
+	}
+	return valid, nil
+}
+
+// diffFileName returns the path of a ---/+++ line, without a timestamp.
+func diffFileName(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	return atoi(s)
+}
+
+// applyHunks applies hunks to content. A hunk whose lines have moved is
+// applied at the nearest position where its context and removed lines
+// match exactly, and a hunk that matches nowhere is an error.
+func applyHunks(content string, hunks []Hunk) (string, error) {
+	trailingNewline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	var out []string
+	next := 0
+	for n, h := range hunks {
+		var old, replacement []string
+		for _, line := range h.Lines {
+			switch line[0] {
+			case ' ':
+				old = append(old, line[1:])
+				replacement = append(replacement, line[1:])
+			case '-':
+				old = append(old, line[1:])
+			case '+':
+				replacement = append(replacement, line[1:])
+			}
+		}
+		pos := findLines(lines, old, next, h.OldStart-1)
+		if pos < 0 {
+			return "", fmt.Errorf("hunk %d (@@ -%d,%d @@) does not match the file", n+1, h.OldStart, h.OldLines)
+		}
+		out = append(out, lines[next:pos]...)
+		out = append(out, replacement...)
+		next = pos + len(old)
+	}
+	out = append(out, lines[next:]...)
+
+	result := strings.Join(out, "\n")
+	if trailingNewline || (content == "" && len(out) > 0) {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// findLines returns the position at or after from where want occurs in
+// lines, choosing the one nearest to near, or -1.
+func findLines(lines []string, want []string, from int, near int) int {
+	matches := func(pos int) bool {
+		if pos < from || pos+len(want) > len(lines) {
+			return false
+		}
+		for i, line := range want {
+			if lines[pos+i] != line {
+				return false
+			}
+		}
+		return true
+	}
+	if near < from {
+		near = from
+	}
+	for offset := 0; near-offset >= from || near+offset <= len(lines); offset++ {
+		if matches(near - offset) {
+			return near - offset
+		}
+		if matches(near + offset) {
+			return near + offset
+		}
+	}
+	return -1
+}
+
+// RunFixes asks the provider for unified diffs that fix the issues it finds
+// in the Terraform, Ansible, and Rego code. Every patch is checked against
+// the current files: patches that apply cleanly are written to kado-fixes/
+// in the IaC path, one <file>.patch per file, and the others are returned
+// with Error set. Code the sanitizer redacted cannot be patched.
+func (c *AIClient) RunFixes() ([]Patch, error) {
+	text, err := c.run("fixes", c.question)
+	if err != nil {
+		return nil, err
+	}
+	patches, err := ParsePatches(text)
+	if err != nil {
+		return nil, err
+	}
+
+	written := 0
+	for i := range patches {
+		p := &patches[i]
+		path, err := c.patchTarget(p.File)
+		if err != nil {
+			p.Error = err.Error()
+			continue
+		}
+		p.File = path
+		if _, err := c.patchedContent(*p); err != nil {
+			p.Error = err.Error()
+			continue
+		}
+		out := filepath.Join(c.iacPath, fixesDir, p.File+".patch")
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %v", filepath.Dir(out), err)
+		}
+		if err := os.WriteFile(out, []byte(p.String()), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", out, err)
+		}
+		written++
+	}
+	fmt.Fprintf(c.messages(), "%d of %d fixes apply cleanly and were written to %s\n", written, len(patches), filepath.Join(c.iacPath, fixesDir))
+	return patches, nil
+}
+
+// patchTarget returns the path relative to the IaC path of the file a diff
+// names. Names carry an a/ or b/ prefix before the path the prompt showed,
+// which is absolute unless paths are anonymized. Files the agent may not
+// read either, such as those in .git or .kado and state files, are refused.
+func (c *AIClient) patchTarget(name string) (string, error) {
+	if strings.HasPrefix(name, "a/") || strings.HasPrefix(name, "b/") {
+		name = name[2:]
+	}
+	name = filepath.FromSlash(name)
+	if !filepath.IsAbs(name) {
+		if _, err := os.Stat(filepath.Join(c.iacPath, name)); err != nil && filepath.IsAbs(c.iacPath) {
+			// The leading slash of an absolute path is often lost after
+			// the a/ prefix.
+			name = string(filepath.Separator) + name
+		}
+	}
+	if filepath.IsAbs(name) {
+		rel, err := filepath.Rel(c.iacPath, name)
+		if err != nil {
+			return "", fmt.Errorf("%s is outside the IaC path", name)
+		}
+		name = rel
+	}
+	name = filepath.Clean(name)
+	if name == "." || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the IaC path", name)
+	}
+	if _, err := c.repoPath(name, false); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// patchedContent returns the content of p's file with p applied.
+func (c *AIClient) patchedContent(p Patch) (string, error) {
+	content, err := c.extractFileContent(filepath.Join(c.iacPath, p.File))
+	if err != nil && !(os.IsNotExist(err) && len(p.Hunks) == 1 && p.Hunks[0].OldLines == 0) {
+		return "", err
+	}
+	return applyHunks(content, p.Hunks)
+}
+
+// ApplyFixes applies valid patches, such as those returned by RunFixes, to
+// the files in the IaC path. Each patch is shown and applied only if the
+// user confirms it on the consent input. Patches of files outside the IaC
+// path or hidden from the agent are refused, however they were built.
+func (c *AIClient) ApplyFixes(patches []Patch) (int, error) {
+	applied := 0
+	for _, p := range patches {
+		if p.Error != "" {
+			continue
+		}
+		if filepath.IsAbs(p.File) {
+			return applied, fmt.Errorf("fix for %s is refused: the path must be relative to the IaC path", p.File)
+		}
+		if _, err := c.repoPath(p.File, false); err != nil {
+			return applied, fmt.Errorf("fix for %s is refused: %v", p.File, err)
+		}
+		content, err := c.patchedContent(p)
+		if err != nil {
+			return applied, fmt.Errorf("fix for %s no longer applies: %v", p.File, err)
+		}
+		fmt.Print(p)
+		if !confirm(c.consentInput, c.messages(), fmt.Sprintf("Apply this fix to %s?", p.File)) {
+			continue
+		}
+		path := filepath.Join(c.iacPath, p.File)
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return applied, err
+		}
+		if err := os.WriteFile(path, []byte(content), mode); err != nil {
+			return applied, fmt.Errorf("failed to apply fix to %s: %v", p.File, err)
+		}
+		applied++
+	}
+	return applied, nil
+}
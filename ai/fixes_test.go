@@ -0,0 +1,180 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePatches(t *testing.T) {
+	text := "Here are the fixes:\n```diff\n" +
+		"--- a/terraform/main.tf\n" +
+		"+++ b/terraform/main.tf\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" resource \"aws_s3_bucket\" \"logs\" {\n" +
+		"-  acl = \"public-read\"\n" +
+		"+  acl = \"private\"\n" +
+		" }\n" +
+		"--- a/terraform/new.tf\t2024-01-01\n" +
+		"+++ b/terraform/new.tf\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+terraform {}\n" +
+		"```\n"
+	patches, err := ParsePatches(text)
+	if err != nil {
+		t.Fatalf("ParsePatches failed: %v", err)
+	}
+	if len(patches) != 2 || patches[0].File != "b/terraform/main.tf" || len(patches[0].Hunks[0].Lines) != 4 {
+		t.Fatalf("Unexpected patches %+v", patches)
+	}
+	if h := patches[1].Hunks[0]; h.OldLines != 0 || h.NewLines != 1 {
+		t.Errorf("Unexpected hunk %+v", h)
+	}
+
+	testCases := []struct {
+		name string
+		text string
+	}{
+		{"no diff", "Everything looks fine."},
+		{"truncated", "--- a/x.tf\n+++ b/x.tf\n@@ -1,3 +1,3 @@\n a\n-b\n"},
+	}
+	for _, tc := range testCases {
+		if _, err := ParsePatches(tc.text); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+func TestApplyHunks(t *testing.T) {
+	content := "a\nb\nc\nd\ne\n"
+	hunks := []Hunk{{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2, Lines: []string{" c", "-d", "+D"}}}
+	got, err := applyHunks(content, hunks)
+	if err != nil {
+		t.Fatalf("applyHunks failed: %v", err)
+	}
+	if got != "a\nb\nc\nD\ne\n" {
+		t.Errorf("Expected the hunk to apply at its moved position, got %q", got)
+	}
+
+	hunks[0].Lines = []string{" c", "-x"}
+	if _, err := applyHunks(content, hunks); err == nil {
+		t.Errorf("Expected an error for a hunk that does not match")
+	}
+}
+
+func TestRunFixes(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_s3_bucket\" \"logs\" {\n  acl = \"public-read\"\n}\n",
+	})
+	mainPath := filepath.Join(tmpDir, "terraform", "main.tf")
+
+	diff := fmt.Sprintf("--- a/%[1]s\n+++ b/%[1]s\n@@ -1,3 +1,3 @@\n resource \"aws_s3_bucket\" \"logs\" {\n-  acl = \"public-read\"\n+  acl = \"private\"\n }\n"+
+		"--- a/terraform/other.tf\n+++ b/terraform/other.tf\n@@ -1 +1 @@\n-missing\n+line\n"+
+		"--- a/../../etc/passwd\n+++ b/../../etc/passwd\n@@ -1 +1 @@\n-root\n+evil\n", mainPath)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, _ := json.Marshal(map[string]interface{}{"content": []map[string]string{{"type": "text", "text": diff}}})
+		w.Write(response)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithConsentInput(strings.NewReader("yes\n")),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	patches, err := client.RunFixes()
+	if err != nil {
+		t.Fatalf("RunFixes failed: %v", err)
+	}
+	if len(patches) != 3 {
+		t.Fatalf("Expected 3 patches, got %+v", patches)
+	}
+	if patches[0].File != filepath.Join("terraform", "main.tf") || patches[0].Error != "" {
+		t.Errorf("Expected a valid patch of terraform/main.tf, got %+v", patches[0])
+	}
+	if patches[1].Error == "" || patches[2].Error == "" {
+		t.Errorf("Expected patches of missing or outside files to be invalid, got %+v", patches[1:])
+	}
+	written, err := os.ReadFile(filepath.Join(tmpDir, fixesDir, "terraform", "main.tf.patch"))
+	if err != nil || !strings.Contains(string(written), "+  acl = \"private\"") {
+		t.Errorf("Expected the valid patch to be written, got %q (%v)", written, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, fixesDir, "terraform", "other.tf.patch")); err == nil {
+		t.Errorf("Expected invalid patches not to be written")
+	}
+
+	applied, err := client.ApplyFixes(patches)
+	if err != nil || applied != 1 {
+		t.Fatalf("Expected 1 fix to be applied, got %d (%v)", applied, err)
+	}
+	content, _ := os.ReadFile(mainPath)
+	if string(content) != "resource \"aws_s3_bucket\" \"logs\" {\n  acl = \"private\"\n}\n" {
+		t.Errorf("Unexpected patched content %q", content)
+	}
+}
+
+func TestApplyFixesRefusesHiddenAndOutsideFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	outside, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(outside)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_s3_bucket\" \"logs\" {}\n",
+		".git/HEAD":         "ref: refs/heads/main\n",
+	})
+	if err := os.Symlink(outside, filepath.Join(tmpDir, "linked")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	client := &AIClient{iacPath: tmpDir, consentInput: strings.NewReader("yes\nyes\nyes\nyes\nyes\nyes\n")}
+	for _, name := range []string{"b/.git/hooks/post-checkout", "b/.kado/config", "b/terraform/terraform.tfstate", "b/linked/evil.sh"} {
+		if target, err := client.patchTarget(name); err == nil {
+			t.Errorf("Expected %s to be refused, got %s", name, target)
+		}
+	}
+
+	newFile := []Hunk{{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1, Lines: []string{"+#!/bin/sh"}}}
+	for _, file := range []string{
+		filepath.Join(".git", "hooks", "post-checkout"),
+		filepath.Join(".kado", "config"),
+		filepath.Join("..", "evil.sh"),
+		filepath.Join("linked", "evil.sh"),
+		filepath.Join(outside, "evil.sh"),
+	} {
+		applied, err := client.ApplyFixes([]Patch{{File: file, Hunks: newFile}})
+		if err == nil || applied != 0 {
+			t.Errorf("Expected the fix for %s to be refused, got %d (%v)", file, applied, err)
+		}
+	}
+	for _, path := range []string{filepath.Join(tmpDir, ".git", "hooks", "post-checkout"), filepath.Join(outside, "evil.sh")} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be written, got %v", path, err)
+		}
+	}
+}
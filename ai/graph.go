@@ -0,0 +1,282 @@
+package ai
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SectionModuleGraph names the module dependency graph in redaction
+// reports.
+const SectionModuleGraph = "module graph"
+
+// Kinds of the nodes of a ModuleGraph.
+const (
+	// GraphNodeModule is a directory of Terraform code under the IaC path.
+	GraphNodeModule = "module"
+	// GraphNodeExternal is a module source outside of the IaC path, such as
+	// a registry module or a Git repository.
+	GraphNodeExternal = "external"
+	// GraphNodeState is a remote state no directory under the IaC path was
+	// found to write.
+	GraphNodeState = "state"
+)
+
+// Kinds of the edges of a ModuleGraph.
+const (
+	GraphEdgeModule      = "module"
+	GraphEdgeRemoteState = "remote_state"
+)
+
+// ModuleGraph is the dependency graph of the Terraform code: which
+// directories call which modules, and which read the state of others
+// through terraform_remote_state. Node IDs are directories relative to the
+// IaC path, module sources, or remote state descriptions.
+type ModuleGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a node of a ModuleGraph.
+type GraphNode struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+}
+
+// GraphEdge is a module call or remote state reference. Name is the label
+// of the module or data block, and Version the version constraint of a
+// registry module.
+type GraphEdge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// stateKeys are the backend settings that identify a state, compared to
+// match the config of a terraform_remote_state to the backend of the
+// directory that writes it.
+var stateKeys = []string{"bucket", "key", "prefix", "path", "container_name", "storage_account_name", "organization", "name"}
+
+// terraformState is the backend type and identifying settings of a state.
+type terraformState struct {
+	backend  string
+	settings map[string]string
+}
+
+// stateSettings returns the identifying settings among items.
+func stateSettings(items []hclItem) map[string]string {
+	settings := map[string]string{}
+	for _, key := range stateKeys {
+		if value, ok := hclString(hclAttribute(items, key)); ok {
+			settings[key] = value
+		}
+	}
+	for _, workspaces := range hclFind(items, "workspaces") {
+		if name, ok := hclString(hclAttribute(workspaces.Body, "name")); ok {
+			settings["name"] = name
+		}
+	}
+	return settings
+}
+
+// matches reports whether the state read by a terraform_remote_state is s:
+// the backend is the same and every identifying setting both have is equal.
+func (s terraformState) matches(read terraformState) bool {
+	if s.backend != read.backend {
+		return false
+	}
+	common := 0
+	for key, value := range read.settings {
+		if own, ok := s.settings[key]; ok {
+			if own != value {
+				return false
+			}
+			common++
+		}
+	}
+	return common > 0 || (s.backend == "local" && len(read.settings) == 0)
+}
+
+func (s terraformState) String() string {
+	var keys []string
+	for key := range s.settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var settings []string
+	for _, key := range keys {
+		settings = append(settings, fmt.Sprintf("%s=%s", key, s.settings[key]))
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s", s.backend, strings.Join(settings, " ")))
+}
+
+// buildModuleGraph records the dependency graph of the Terraform code of
+// result in result.Graph, unless it has no module calls or remote state
+// references.
+func buildModuleGraph(result *ScanResult) {
+	type reference struct {
+		from  string
+		name  string
+		state terraformState
+	}
+	graph := &ModuleGraph{}
+	nodes := map[string]string{}
+	addNode := func(id string, kind string) {
+		if _, ok := nodes[id]; !ok {
+			nodes[id] = kind
+		}
+	}
+	states := map[string]terraformState{}
+	var references []reference
+
+	for _, f := range result.Section(SectionTerraform) {
+		if filepath.Ext(f.Path) != ".tf" {
+			continue
+		}
+		dir := filepath.Dir(f.Path)
+		items := parseHCL(f.Content)
+		for _, terraform := range hclFind(items, "terraform") {
+			for _, backend := range hclFind(terraform.Body, "backend") {
+				if len(backend.Labels) == 1 {
+					states[dir] = terraformState{backend: backend.Labels[0], settings: stateSettings(backend.Body)}
+				}
+			}
+		}
+		for _, module := range hclFind(items, "module") {
+			source, ok := hclString(hclAttribute(module.Body, "source"))
+			if !ok || len(module.Labels) != 1 {
+				continue
+			}
+			edge := GraphEdge{From: dir, To: source, Kind: GraphEdgeModule, Name: module.Labels[0]}
+			edge.Version, _ = hclString(hclAttribute(module.Body, "version"))
+			if local := filepath.Join(dir, filepath.FromSlash(source)); (strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")) && !strings.HasPrefix(local, "..") {
+				edge.To = local
+				addNode(local, GraphNodeModule)
+			} else {
+				addNode(source, GraphNodeExternal)
+			}
+			addNode(dir, GraphNodeModule)
+			graph.Edges = append(graph.Edges, edge)
+		}
+		for _, data := range hclFind(items, "data", "terraform_remote_state") {
+			if len(data.Labels) != 2 {
+				continue
+			}
+			backend, _ := hclString(hclAttribute(data.Body, "backend"))
+			references = append(references, reference{
+				from:  dir,
+				name:  data.Labels[1],
+				state: terraformState{backend: backend, settings: stateSettings(hclObject(hclAttribute(data.Body, "config")))},
+			})
+		}
+	}
+
+	var dirs []string
+	for dir := range states {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, ref := range references {
+		to := ""
+		for _, dir := range dirs {
+			if states[dir].matches(ref.state) {
+				to = dir
+				addNode(dir, GraphNodeModule)
+				break
+			}
+		}
+		if to == "" {
+			to = ref.state.String()
+			addNode(to, GraphNodeState)
+		}
+		addNode(ref.from, GraphNodeModule)
+		graph.Edges = append(graph.Edges, GraphEdge{From: ref.from, To: to, Kind: GraphEdgeRemoteState, Name: ref.name})
+	}
+	if len(graph.Edges) == 0 {
+		return
+	}
+
+	for id, kind := range nodes {
+		graph.Nodes = append(graph.Nodes, GraphNode{ID: id, Kind: kind})
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	sort.SliceStable(graph.Edges, func(i, j int) bool { return graph.Edges[i].From < graph.Edges[j].From })
+	result.Graph = graph
+}
+
+// DOT renders the graph in the Graphviz DOT language, with external modules
+// and unresolved remote states drawn as boxes and remote state references
+// dashed.
+func (g *ModuleGraph) DOT() string {
+	var out strings.Builder
+	out.WriteString("digraph modules {\n  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		shape := "ellipse"
+		if n.Kind != GraphNodeModule {
+			shape = "box"
+		}
+		fmt.Fprintf(&out, "  %q [shape=%s];\n", filepath.ToSlash(n.ID), shape)
+	}
+	for _, e := range g.Edges {
+		style := "solid"
+		if e.Kind == GraphEdgeRemoteState {
+			style = "dashed"
+		}
+		fmt.Fprintf(&out, "  %q -> %q [label=%q, style=%s];\n", filepath.ToSlash(e.From), filepath.ToSlash(e.To), e.Name, style)
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// ModuleGraph renders the module dependency graph as the prompt presents
+// it: the module calls and remote state references of each directory, under
+// the names the directories are presented under.
+func (r *ScanResult) ModuleGraph() string {
+	if r.Graph == nil {
+		return ""
+	}
+	kinds := map[string]string{}
+	for _, n := range r.Graph.Nodes {
+		kinds[n.ID] = n.Kind
+	}
+	target := func(id string) string {
+		if kinds[id] == GraphNodeModule {
+			return r.displayModuleDir(id)
+		}
+		return id
+	}
+
+	var out strings.Builder
+	from := ""
+	for _, e := range r.Graph.Edges {
+		if e.From != from {
+			fmt.Fprintf(&out, "%s\n", r.displayModuleDir(e.From))
+			from = e.From
+		}
+		switch e.Kind {
+		case GraphEdgeModule:
+			fmt.Fprintf(&out, "  module %q -> %s", e.Name, target(e.To))
+			if e.Version != "" {
+				fmt.Fprintf(&out, " (version %s)", e.Version)
+			}
+		case GraphEdgeRemoteState:
+			fmt.Fprintf(&out, "  reads the remote state %q of %s", e.Name, target(e.To))
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// displayModuleDir returns the name a directory of Terraform code is
+// presented under, which is anonymized along with its files.
+func (r *ScanResult) displayModuleDir(dir string) string {
+	for _, f := range r.Files {
+		if filepath.Dir(f.Path) == dir {
+			return filepath.Dir(r.displayName(f))
+		}
+	}
+	return filepath.Join(r.Root, dir)
+}
@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModuleGraph(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/envs/network/main.tf": `terraform {
+  backend "s3" {
+    bucket = "acme-state"
+    key    = "network/terraform.tfstate"
+  }
+}
+
+module "vpc" {
+  source = "../../modules/vpc"
+}
+`,
+		"terraform/envs/prod/main.tf": `module "eks" {
+  source  = "terraform-aws-modules/eks/aws"
+  version = "19.21.0"
+}
+
+data "terraform_remote_state" "network" {
+  backend = "s3"
+  config = {
+    bucket = "acme-state"
+    key    = "network/terraform.tfstate"
+  }
+}
+
+data "terraform_remote_state" "dns" {
+  backend = "gcs"
+  config = {
+    bucket = "acme-dns"
+  }
+}
+`,
+		"terraform/modules/vpc/main.tf": "resource \"aws_vpc\" \"main\" {}\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.Graph == nil {
+		t.Fatalf("Expected a module graph")
+	}
+
+	var edges []string
+	for _, e := range result.Graph.Edges {
+		edges = append(edges, filepath.ToSlash(e.From)+" -"+e.Kind+"-> "+filepath.ToSlash(e.To))
+	}
+	expected := "terraform/envs/network -module-> terraform/modules/vpc, " +
+		"terraform/envs/prod -module-> terraform-aws-modules/eks/aws, " +
+		"terraform/envs/prod -remote_state-> terraform/envs/network, " +
+		"terraform/envs/prod -remote_state-> gcs bucket=acme-dns"
+	if got := strings.Join(edges, ", "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	var nodes []string
+	for _, n := range result.Graph.Nodes {
+		nodes = append(nodes, filepath.ToSlash(n.ID)+" ("+n.Kind+")")
+	}
+	expected = "gcs bucket=acme-dns (state), terraform-aws-modules/eks/aws (external), terraform/envs/network (module), terraform/envs/prod (module), terraform/modules/vpc (module)"
+	if got := strings.Join(nodes, ", "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	graph := result.ModuleGraph()
+	prod := filepath.Join(tmpDir, "terraform", "envs", "prod")
+	network := filepath.Join(tmpDir, "terraform", "envs", "network")
+	if !strings.Contains(graph, prod+"\n  module \"eks\" -> terraform-aws-modules/eks/aws (version 19.21.0)\n  reads the remote state \"network\" of "+network+"\n") {
+		t.Errorf("Expected the graph of each directory in the prompt, got %q", graph)
+	}
+	dot := result.Graph.DOT()
+	if !strings.Contains(dot, `"terraform/envs/prod" -> "terraform/envs/network" [label="network", style=dashed];`) ||
+		!strings.Contains(dot, `"terraform-aws-modules/eks/aws" [shape=box];`) {
+		t.Errorf("Expected the graph in DOT, got %s", dot)
+	}
+}
@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Sections of the other HashiCorp tools: Packer templates, Nomad job
+// specifications, and Vagrantfiles.
+const (
+	SectionPacker  = "packer templates"
+	SectionNomad   = "nomad jobs"
+	SectionVagrant = "vagrantfiles"
+)
+
+// nomadJob matches the job block of a Nomad job specification.
+var nomadJob = regexp.MustCompile(`(?m)^job\s+"[^"]+"\s*\{`)
+
+// hashicorpSection returns the section of the file at path, reading HCL
+// files that could be Nomad jobs, or the empty string if it is none of
+// them.
+func hashicorpSection(path string) string {
+	name := filepath.Base(path)
+	switch {
+	case name == "Vagrantfile":
+		return SectionVagrant
+	case strings.HasSuffix(name, ".pkr.hcl") || strings.HasSuffix(name, ".pkr.json") || strings.HasSuffix(name, ".pkrvars.hcl"):
+		return SectionPacker
+	case strings.HasSuffix(name, ".nomad") || strings.HasSuffix(name, ".nomad.hcl"):
+		return SectionNomad
+	case strings.HasSuffix(name, ".hcl") && name != terragruntFile:
+		if content, err := os.ReadFile(path); err == nil && nomadJob.Match(content) {
+			return SectionNomad
+		}
+	}
+	return ""
+}
+
+// scanHashiCorp adds the Packer templates and variable files, Nomad job
+// specifications, and Vagrantfiles under the IaC path to result, other than
+// files already collected.
+func (c *AIClient) scanHashiCorp(result *ScanResult) {
+	collected := map[string]bool{}
+	for _, f := range result.Files {
+		collected[f.Path] = true
+	}
+	ignore := c.ignoreRules()
+	c.walk(result, c.iacPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != c.iacPath && (discoverySkipDirs[info.Name()] || info.Name() == ".vagrant" || ignore.ignored(c.iacPath, path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if collected[result.rel(path)] {
+			return nil
+		}
+		section := hashicorpSection(path)
+		if section == "" {
+			return nil
+		}
+		if ignore.ignored(c.iacPath, path, false) {
+			result.skip(path, ignoredReason)
+			return nil
+		}
+		c.addFile(result, section, path)
+		return nil
+	})
+}
@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanHashiCorp(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"packer/ami.pkr.hcl":            "source \"amazon-ebs\" \"base\" {}\n",
+		"packer/prod.pkrvars.hcl":       "region = \"us-east-1\"\n",
+		"packer/legacy.pkr.json":        `{"builders": []}`,
+		"nomad/web.nomad":               "job \"web\" {}\n",
+		"nomad/api.hcl":                 "# API\njob \"api\" {\n  datacenters = [\"dc1\"]\n}\n",
+		"nomad/agent.hcl":               "datacenter = \"dc1\"\n",
+		"live/prod/terragrunt.hcl":      "job \"not-nomad\" {}\n",
+		"Vagrantfile":                   "Vagrant.configure(\"2\") do |config|\nend\n",
+		".vagrant/machines/Vagrantfile": "generated\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	sections := map[string][]string{}
+	for _, f := range result.Files {
+		sections[f.Section] = append(sections[f.Section], filepath.ToSlash(f.Path)+" ("+f.Language+")")
+	}
+	testCases := []struct {
+		section  string
+		expected string
+	}{
+		{SectionPacker, "packer/ami.pkr.hcl (hcl), packer/legacy.pkr.json (json), packer/prod.pkrvars.hcl (hcl)"},
+		{SectionNomad, "nomad/api.hcl (hcl), nomad/web.nomad (hcl)"},
+		{SectionVagrant, "Vagrantfile (ruby)"},
+	}
+	for _, tc := range testCases {
+		if got := strings.Join(sections[tc.section], ", "); got != tc.expected {
+			t.Errorf("Expected %s in %s, got %s", tc.expected, tc.section, got)
+		}
+	}
+
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.PackerTemplates, "amazon-ebs") || !strings.Contains(data.NomadJobs, `job "api"`) || !strings.Contains(data.Vagrantfiles, "Vagrant.configure") {
+		t.Errorf("Expected the files in the prompt data, got %+v", data)
+	}
+}
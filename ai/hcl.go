@@ -0,0 +1,263 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+)
+
+// hclItem is an attribute or a block of HCL source. Value is the source of
+// an attribute's expression; Body holds the items of a block.
+type hclItem struct {
+	Name   string
+	Labels []string
+	Block  bool
+	Value  string
+	Body   []hclItem
+	// Line is the 1-based line the item starts on.
+	Line int
+}
+
+var hclHeredoc = regexp.MustCompile(`^<<-?([A-Za-z_][A-Za-z0-9_]*)[ \t]*\r?\n`)
+
+// parseHCL reads the attributes and blocks of HCL source. It is tolerant
+// rather than strict: what it does not understand is skipped up to the end
+// of the line. It also reads the inside of an object expression, where keys
+// may be quoted, be followed by a colon, and be separated by commas.
+func parseHCL(src string) []hclItem {
+	p := &hclParser{src: src}
+	return p.body(false)
+}
+
+// hclObject returns the items of an object expression such as the value
+// of a Terraform map or of Terragrunt inputs, or nil if value is not one.
+func hclObject(value string) []hclItem {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "{") || !strings.HasSuffix(value, "}") {
+		return nil
+	}
+	return parseHCL(value[1 : len(value)-1])
+}
+
+// hclString returns the text of a string literal without interpolations,
+// and whether value is one.
+func hclString(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", false
+	}
+	inner := value[1 : len(value)-1]
+	if strings.Contains(inner, "${") || strings.Contains(inner, "%{") || strings.Contains(inner, `"`) {
+		return "", false
+	}
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\t`, "\t").Replace(inner), true
+}
+
+// hclFind returns the items named name, and with the given labels if any.
+func hclFind(items []hclItem, name string, labels ...string) []hclItem {
+	var found []hclItem
+	for _, item := range items {
+		if item.Name != name || len(item.Labels) < len(labels) {
+			continue
+		}
+		match := true
+		for i, label := range labels {
+			if item.Labels[i] != label {
+				match = false
+			}
+		}
+		if match {
+			found = append(found, item)
+		}
+	}
+	return found
+}
+
+// hclAttribute returns the value of the attribute name, or the empty string.
+func hclAttribute(items []hclItem, name string) string {
+	for _, item := range items {
+		if !item.Block && item.Name == name {
+			return item.Value
+		}
+	}
+	return ""
+}
+
+type hclParser struct {
+	src string
+	pos int
+}
+
+// body reads items until the end of the source, or the closing brace of
+// the enclosing block if nested is set.
+func (p *hclParser) body(nested bool) []hclItem {
+	var items []hclItem
+	for {
+		p.skipSpace(true)
+		if p.pos >= len(p.src) {
+			return items
+		}
+		c := p.src[p.pos]
+		if c == '}' {
+			p.pos++
+			if nested {
+				return items
+			}
+			continue
+		}
+		if c == ',' {
+			p.pos++
+			continue
+		}
+		start := p.pos
+		name := p.key()
+		if name == "" {
+			p.pos = hclNextLine(p.src, p.pos)
+			continue
+		}
+		item := hclItem{Name: name, Line: strings.Count(p.src[:start], "\n") + 1}
+		p.skipSpace(false)
+		if p.pos < len(p.src) && (p.src[p.pos] == '=' || p.src[p.pos] == ':') && !strings.HasPrefix(p.src[p.pos:], "==") {
+			p.pos++
+			p.skipSpace(false)
+			end := p.expressionEnd()
+			item.Value = strings.TrimSpace(p.src[p.pos:end])
+			p.pos = end
+			items = append(items, item)
+			continue
+		}
+		for p.pos < len(p.src) && p.src[p.pos] != '{' && p.src[p.pos] != '\n' {
+			label := p.key()
+			if label == "" {
+				break
+			}
+			item.Labels = append(item.Labels, label)
+			p.skipSpace(false)
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == '{' {
+			p.pos++
+			item.Block = true
+			item.Body = p.body(true)
+			items = append(items, item)
+			continue
+		}
+		p.pos = hclNextLine(p.src, p.pos)
+	}
+}
+
+// key reads an identifier or a quoted string.
+func (p *hclParser) key() string {
+	if p.pos < len(p.src) && p.src[p.pos] == '"' {
+		end := p.stringEnd(p.pos)
+		key := strings.TrimSuffix(p.src[p.pos+1:end], `"`)
+		p.pos = end
+		return key
+	}
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '_' || c == '-' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+// expressionEnd returns the end of the expression at the current position:
+// the end of its line or a comma or closing brace of the enclosing object,
+// unless brackets are open, or the end of a heredoc.
+func (p *hclParser) expressionEnd() int {
+	if m := hclHeredoc.FindStringSubmatch(p.src[p.pos:]); m != nil {
+		line := p.pos + len(m[0])
+		for line < len(p.src) {
+			next := hclNextLine(p.src, line)
+			if strings.TrimSpace(p.src[line:next]) == m[1] {
+				return line + len(strings.TrimRight(p.src[line:next], "\r\n"))
+			}
+			line = next
+		}
+		return len(p.src)
+	}
+	depth := 0
+	for i := p.pos; i < len(p.src); i++ {
+		switch c := p.src[i]; {
+		case c == '"':
+			i = p.stringEnd(i) - 1
+		case c == '[' || c == '{' || c == '(':
+			depth++
+		case c == ']' || c == '}' || c == ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case depth == 0 && (c == '\n' || c == ',' || c == '#' || strings.HasPrefix(p.src[i:], "//")):
+			return i
+		}
+	}
+	return len(p.src)
+}
+
+// stringEnd returns the position after the closing quote of the string
+// literal starting at start, skipping escapes and interpolations.
+func (p *hclParser) stringEnd(start int) int {
+	depth := 0
+	for i := start + 1; i < len(p.src); i++ {
+		switch c := p.src[i]; {
+		case c == '\\':
+			i++
+		case (c == '$' || c == '%') && i+1 < len(p.src) && p.src[i+1] == '{':
+			depth++
+			i++
+		case depth > 0 && c == '}':
+			depth--
+		case depth > 0 && c == '"':
+			i = p.stringEnd(i) - 1
+		case depth == 0 && c == '"':
+			return i + 1
+		case c == '\n':
+			return i
+		}
+	}
+	return len(p.src)
+}
+
+// skipSpace skips spaces and comments, and newlines if lines is set.
+func (p *hclParser) skipSpace(lines bool) {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n':
+			if !lines {
+				return
+			}
+			p.pos++
+		case c == '#' || strings.HasPrefix(p.src[p.pos:], "//"):
+			end := strings.IndexByte(p.src[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.src)
+				return
+			}
+			p.pos += end
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			end := strings.Index(p.src[p.pos+2:], "*/")
+			if end < 0 {
+				p.pos = len(p.src)
+				return
+			}
+			p.pos += end + 4
+		default:
+			return
+		}
+	}
+}
+
+// hclNextLine returns the start of the line after pos.
+func hclNextLine(src string, pos int) int {
+	end := strings.IndexByte(src[pos:], '\n')
+	if end < 0 {
+		return len(src)
+	}
+	return pos + end + 1
+}
@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"testing"
+)
+
+func TestParseHCL(t *testing.T) {
+	src := `# Root configuration
+locals {
+  env = "prod" // trailing comment
+}
+
+include "root" {
+  path           = find_in_parent_folders("root.hcl")
+  merge_strategy = "no_merge"
+}
+
+/* inputs of the stack */
+inputs = {
+  name    = "web-${local.env}"
+  "tags"  = { team = "platform", cost = "42" }
+  subnets = [
+    "a",
+    "b",
+  ]
+  policy = <<EOT
+{"Statement": []}
+EOT
+}
+`
+	items := parseHCL(src)
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %+v", items)
+	}
+
+	include := hclFind(items, "include", "root")
+	if len(include) != 1 || !include[0].Block || include[0].Line != 6 {
+		t.Fatalf("Expected the include block on line 6, got %+v", include)
+	}
+	if got := hclAttribute(include[0].Body, "path"); got != `find_in_parent_folders("root.hcl")` {
+		t.Errorf("Expected the path expression, got %q", got)
+	}
+	if got, ok := hclString(hclAttribute(include[0].Body, "merge_strategy")); !ok || got != "no_merge" {
+		t.Errorf("Expected the merge strategy no_merge, got %q", got)
+	}
+	if got := hclAttribute(hclFind(items, "locals")[0].Body, "env"); got != `"prod"` {
+		t.Errorf("Expected the comment to be left out of the value, got %q", got)
+	}
+
+	inputs := hclObject(hclAttribute(items, "inputs"))
+	var names []string
+	for _, input := range inputs {
+		names = append(names, input.Name)
+	}
+	if len(inputs) != 4 || names[1] != "tags" || names[3] != "policy" {
+		t.Fatalf("Expected the inputs name, tags, subnets, and policy, got %v", names)
+	}
+	if _, ok := hclString(inputs[0].Value); ok {
+		t.Errorf("Expected an interpolated string not to be a literal, got %q", inputs[0].Value)
+	}
+	if tags := hclObject(inputs[1].Value); len(tags) != 2 || tags[1].Name != "cost" || tags[1].Value != `"42"` {
+		t.Errorf("Expected the comma-separated object to be read, got %+v", tags)
+	}
+	if inputs[2].Value != "[\n    \"a\",\n    \"b\",\n  ]" {
+		t.Errorf("Expected the multi-line list, got %q", inputs[2].Value)
+	}
+	if inputs[3].Value != "<<EOT\n{\"Statement\": []}\nEOT" {
+		t.Errorf("Expected the heredoc, got %q", inputs[3].Value)
+	}
+}
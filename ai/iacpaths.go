@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// scanIaCPaths adds the content of each of the IaC paths to result, in
+// order, labeling every file with the IaC path it was found under. Each is
+// scanned like an IaC path of its own, with its own layout and ignore
+// files, and with discovery enabled, since code directly in it is rarely
+// laid out in terraform/ and ansible/. File paths stay relative to the
+// primary IaC path, the root of result.
+func (c *AIClient) scanIaCPaths(ctx context.Context, result *ScanResult, synth bool) error {
+	c.scanMu.Lock()
+	defer c.scanMu.Unlock()
+	primary, discover := c.iacPath, c.discover
+	defer func() {
+		c.iacPath, c.discover = primary, discover
+	}()
+
+	for _, label := range c.iacPaths {
+		path := label
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(primary, path)
+		}
+		start := len(result.Files)
+		c.iacPath, c.discover = filepath.Clean(path), true
+		if err := c.scanContent(ctx, result, synth); err != nil {
+			return fmt.Errorf("failed to scan %s: %v", label, err)
+		}
+		for i := range result.Files[start:] {
+			result.Files[start+i].IaCPath = label
+		}
+	}
+	return nil
+}
+
+// validIaCPaths checks that the IaC paths are under the primary IaC path,
+// so that every file has a path relative to it.
+func (c *AIClient) validIaCPaths() error {
+	for _, path := range c.iacPaths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.iacPath, path)
+		}
+		rel, err := filepath.Rel(c.iacPath, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("IaC path %s is outside of %s", path, c.iacPath)
+		}
+	}
+	return nil
+}
+
+// forIaCPath returns the part of scan found under the IaC path label.
+func (r *ScanResult) forIaCPath(label string) *ScanResult {
+	part := &ScanResult{Root: r.Root}
+	for _, f := range r.Files {
+		if f.IaCPath == label {
+			part.Files = append(part.Files, f)
+		}
+	}
+	return part
+}
+
+// perPathPrompt renders a prompt for each IaC path of scan, to be analyzed
+// separately and reported on under its own heading. It returns nil if any
+// of them does not fit in the context window, so that the scan is analyzed
+// as a whole and chunked instead.
+func (c *AIClient) perPathPrompt(template string, question string, scan *ScanResult) (*preparedPrompt, error) {
+	var parts []*ScanResult
+	for _, label := range c.iacPaths {
+		parts = append(parts, scan.forIaCPath(label))
+	}
+	return c.partsPrompt(template, question, c.iacPaths, parts, "IaC paths")
+}
+
+// partsPrompt renders a prompt for each of parts, reported on under the
+// heading of the same index, or returns nil if any of them does not fit in
+// the context window. What names the parts in the message saying so.
+func (c *AIClient) partsPrompt(template string, question string, headings []string, parts []*ScanResult, what string) (*preparedPrompt, error) {
+	// The standards are sanitized again with every chunk, which reports
+	// their redactions.
+	base, _, err := c.basePromptData(question)
+	if err != nil {
+		return nil, err
+	}
+	p := &preparedPrompt{base: base}
+	p.overhead, err = c.renderPrompt(template, p.base)
+	if err != nil {
+		return nil, err
+	}
+	for i, part := range parts {
+		data, found := c.promptData(part)
+		data.Question = question
+		input, err := c.renderPrompt(template, data)
+		if err != nil {
+			return nil, err
+		}
+		if c.contextWindow > 0 && estimateTokens(input) > c.contextWindow {
+			fmt.Fprintf(c.messages(), "The AI input of %s is too large for one request, so the %s are analyzed together.\n", headings[i], what)
+			return nil, nil
+		}
+		p.chunks = append(p.chunks, input)
+		p.redactions = append(p.redactions, found...)
+		p.headings = append(p.headings, headings[i])
+	}
+	return p, nil
+}
@@ -0,0 +1,123 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func writeIaCPathsFixture(t *testing.T) string {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	writeTestFiles(t, tmpDir, map[string]string{
+		"infra/aws/main.tf":          "resource \"aws_s3_bucket\" \"logs\" {}\n",
+		"infra/gcp/main.tf":          "resource \"google_storage_bucket\" \"logs\" {}\n",
+		"platform/k8s/.kadoignore":   "legacy/\n",
+		"platform/k8s/deploy.yaml":   "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+		"platform/k8s/legacy/old.tf": "resource \"aws_instance\" \"old\" {}\n",
+		"unrelated/main.tf":          "resource \"aws_instance\" \"unrelated\" {}\n",
+	})
+	return tmpDir
+}
+
+func TestScanIaCPaths(t *testing.T) {
+	tmpDir := writeIaCPathsFixture(t)
+	defer os.RemoveAll(tmpDir)
+
+	client := &AIClient{iacPath: tmpDir, iacPaths: []string{"infra/aws", "infra/gcp", "platform/k8s"}}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var files []string
+	for _, f := range result.Files {
+		files = append(files, filepath.ToSlash(f.Path)+" ("+f.IaCPath+")")
+	}
+	expected := "infra/aws/main.tf (infra/aws), infra/gcp/main.tf (infra/gcp), platform/k8s/deploy.yaml (platform/k8s)"
+	if got := strings.Join(files, ", "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	if client.iacPath != tmpDir || client.discover {
+		t.Errorf("Expected the IaC path and discovery to be restored, got %s and %v", client.iacPath, client.discover)
+	}
+
+	content := result.Content(SectionTerraform)
+	if !strings.Contains(content, "IaC path: infra/aws\n\nFile: "+filepath.Join(tmpDir, "infra", "aws", "main.tf")) ||
+		!strings.Contains(content, "IaC path: infra/gcp\n\n") {
+		t.Errorf("Expected the files to be labeled with their IaC path, got %q", content)
+	}
+
+	if _, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithIaCPaths("infra/aws", "../elsewhere"),
+	); err == nil || !strings.Contains(err.Error(), "outside of") {
+		t.Errorf("Expected an IaC path outside of the IaC path to be rejected, got %v", err)
+	}
+}
+
+func TestPerPathReports(t *testing.T) {
+	tmpDir := writeIaCPathsFixture(t)
+	defer os.RemoveAll(tmpDir)
+
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content json.RawMessage `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		input := string(body.Messages[0].Content)
+		switch {
+		case strings.Contains(input, "google_storage_bucket") && !strings.Contains(input, "aws_s3_bucket"):
+			fmt.Fprint(w, `{"content":[{"type":"text","text":"Enable uniform bucket-level access."}]}`)
+		case strings.Contains(input, "aws_s3_bucket") && !strings.Contains(input, "google_storage_bucket"):
+			fmt.Fprint(w, `{"content":[{"type":"text","text":"Enable versioning."}]}`)
+		default:
+			fmt.Fprint(w, `{"content":[{"type":"text","text":"Set resource limits."}]}`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+		WithIaCPaths("infra/aws", "infra/gcp", "platform/k8s"),
+		WithPerPathReports(true),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	recommendations, err := client.RunAI()
+	if err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	expected := "## infra/aws\n\nEnable versioning.\n\n## infra/gcp\n\nEnable uniform bucket-level access.\n\n## platform/k8s\n\nSet resource limits."
+	if recommendations != expected {
+		t.Errorf("Expected a report for each IaC path, got %q", recommendations)
+	}
+	if requests != 3 {
+		t.Errorf("Expected a request for each IaC path and none to merge them, got %d", requests)
+	}
+}
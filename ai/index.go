@@ -0,0 +1,257 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+const (
+	indexChunkLines = 40
+	indexBatchSize  = 64
+)
+
+var indexExtensions = []string{".tf", ".rego", ".yml", ".yaml"}
+
+// indexSkipDirs are never indexed: VCS metadata, provider caches, and
+// kado-ai's own state.
+var indexSkipDirs = map[string]bool{
+	".git":         true,
+	".terraform":   true,
+	"node_modules": true,
+	".kado":        true,
+}
+
+// IndexChunk is a span of lines of an IaC file with its embedding. The text
+// is sanitized before it is embedded or stored.
+type IndexChunk struct {
+	Path      string    `json:"path"`
+	StartLine int       `json:"start_line"`
+	EndLine   int       `json:"end_line"`
+	Text      string    `json:"text"`
+	Vector    []float32 `json:"vector"`
+}
+
+// Index is a local retrieval index over the IaC code under the IaC path.
+// Provider and Model record the embedder that built it, since queries must be
+// embedded by the same model to be comparable.
+type Index struct {
+	Provider string       `json:"provider,omitempty"`
+	Model    string       `json:"model,omitempty"`
+	Chunks   []IndexChunk `json:"chunks"`
+}
+
+// IndexPath returns where the client's index is stored: .kado/index.json
+// under the IaC path.
+func (c *AIClient) IndexPath() string {
+	return c.projectPath("index.json")
+}
+
+// describeEmbedder returns the provider and model of the built-in embedders.
+func describeEmbedder(e Embedder) (string, string) {
+	switch e := e.(type) {
+	case *OpenAIEmbedder:
+		return EmbeddingOpenAI, e.Model
+	case *VertexEmbedder:
+		return EmbeddingVertex, e.Model
+	case *OllamaEmbedder:
+		return EmbeddingOllama, e.Model
+	}
+	return "", ""
+}
+
+// BuildIndex splits the IaC code into chunks, sanitizes them, and embeds
+// them with the configured Embedder. The chunks are sanitized at the
+// stricter of the levels of the completion provider and of the embedder.
+// Unless the embedder runs locally, the consent policy applies before any
+// code is sent.
+func (c *AIClient) BuildIndex(ctx context.Context) (*Index, error) {
+	if c.embedder == nil {
+		return nil, fmt.Errorf("no embedding provider is configured")
+	}
+	redact := c.redact
+	if level := c.indexSanitizeLevel(); level != c.effectiveSanitizeLevel() {
+		s, err := c.levelSanitizer(level)
+		if err != nil {
+			return nil, err
+		}
+		redact = func(content string) (string, []sanitize.Redaction) {
+			return c.redactWith(s, content)
+		}
+	}
+
+	var chunks []IndexChunk
+	var redactions []sanitize.Redaction
+	ignore := c.ignoreRules()
+	err := c.walk(nil, c.iacPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != c.iacPath && (indexSkipDirs[info.Name()] || ignore.ignored(c.iacPath, path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !hasExtension(info.Name(), indexExtensions) || ignore.ignored(c.iacPath, path, false) {
+			return nil
+		}
+		content, err := c.extractFileContent(path)
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(c.iacPath, path)
+		if err != nil {
+			rel = path
+		}
+		for _, chunk := range chunkLines(rel, content, indexChunkLines) {
+			sanitized, found := redact(chunk.Text)
+			for i := range found {
+				found[i].File = rel
+				found[i].Line += chunk.StartLine - 1
+			}
+			redactions = append(redactions, found...)
+			chunk.Text = sanitized
+			chunks = append(chunks, chunk)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %v", c.iacPath, err)
+	}
+
+	if _, local := c.embedder.(*OllamaEmbedder); !local {
+		if err := c.blockSecrets(redactions); err != nil {
+			return nil, err
+		}
+		if !c.consent(redactions) {
+			return nil, fmt.Errorf("operation cancelled by user")
+		}
+	}
+
+	for start := 0; start < len(chunks); start += indexBatchSize {
+		end := start + indexBatchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		var texts []string
+		for _, chunk := range chunks[start:end] {
+			texts = append(texts, chunk.Text)
+		}
+		vectors, err := c.embedder.Embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed chunks: %v", err)
+		}
+		for i, vector := range vectors {
+			chunks[start+i].Vector = vector
+		}
+	}
+	provider, model := describeEmbedder(c.embedder)
+	return &Index{Provider: provider, Model: model, Chunks: chunks}, nil
+}
+
+func hasExtension(name string, extensions []string) bool {
+	for _, ext := range extensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// chunkLines splits content into chunks of at most size lines.
+func chunkLines(path string, content string, size int) []IndexChunk {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	var chunks []IndexChunk
+	for start := 0; start < len(lines); start += size {
+		end := start + size
+		if end > len(lines) {
+			end = len(lines)
+		}
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		chunks = append(chunks, IndexChunk{Path: path, StartLine: start + 1, EndLine: end, Text: text})
+	}
+	return chunks
+}
+
+// Search returns the k chunks most similar to query, embedding the query
+// with e, which must be the embedder the index was built with.
+func (ix *Index) Search(ctx context.Context, e Embedder, query string, k int) ([]IndexChunk, error) {
+	vectors, err := e.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %v", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("expected 1 query embedding, got %d", len(vectors))
+	}
+
+	type scored struct {
+		chunk IndexChunk
+		score float64
+	}
+	var results []scored
+	for _, chunk := range ix.Chunks {
+		results = append(results, scored{chunk: chunk, score: cosineSimilarity(vectors[0], chunk.Vector)})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].score > results[j].score })
+
+	if k > len(results) {
+		k = len(results)
+	}
+	matches := make([]IndexChunk, k)
+	for i := range matches {
+		matches[i] = results[i].chunk
+	}
+	return matches, nil
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Save writes the index to path as JSON.
+func (ix *Index) Save(path string) error {
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// LoadIndex reads an index written by Save.
+func LoadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ix := &Index{}
+	if err := json.Unmarshal(data, ix); err != nil {
+		return nil, fmt.Errorf("failed to parse index %s: %v", path, err)
+	}
+	return ix, nil
+}
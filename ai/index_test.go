@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+// keywordEmbedder embeds text as a vector of keyword occurrences, which is
+// enough to exercise retrieval without a real model.
+type keywordEmbedder struct {
+	keywords []string
+	texts    []string
+}
+
+func (e *keywordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.texts = append(e.texts, texts...)
+	var vectors [][]float32
+	for _, text := range texts {
+		vector := make([]float32, len(e.keywords))
+		for i, keyword := range e.keywords {
+			vector[i] = float32(strings.Count(text, keyword))
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
+
+func TestBuildAndSearchIndex(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/network.tf":                 "resource \"aws_nat_gateway\" \"main\" {\n  subnet_id = aws_subnet.public.id\n}\n",
+		"terraform/storage.tf":                 "resource \"aws_s3_bucket\" \"logs\" {\n  bucket = \"logs\"\n  password = \"hunter2\"\n}\n",
+		"terraform/.terraform/modules/x/io.tf": "resource \"aws_nat_gateway\" \"vendored\" {}\n",
+		"ansible/site.yml":                     "- hosts: web\n",
+	})
+
+	embedder := &keywordEmbedder{keywords: []string{"nat_gateway", "s3_bucket", "hosts"}}
+	client := &AIClient{iacPath: tmpDir, embedder: embedder, consentPolicy: ConsentAutoApprove}
+	index, err := client.BuildIndex(context.Background())
+	if err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	if len(index.Chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", len(index.Chunks))
+	}
+	for _, text := range embedder.texts {
+		if strings.Contains(text, "hunter2") {
+			t.Errorf("Expected chunks to be sanitized before embedding, got %q", text)
+		}
+	}
+
+	indexPath := client.IndexPath()
+	if err := index.Save(indexPath); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := LoadIndex(indexPath)
+	if err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+
+	matches, err := loaded.Search(context.Background(), embedder, "why is my nat_gateway bill so high?", 1)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Path != filepath.Join("terraform", "network.tf") {
+		t.Errorf("Expected the NAT gateway chunk to match best, got %+v", matches)
+	}
+}
+
+func TestBuildIndexSanitizesForEmbedder(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_instance\" \"web\" {\n  private_ip = \"10.0.0.5\"\n}\n",
+	})
+
+	// The completion model is local and gets minimal sanitization, but the
+	// embedder is remote, so the chunks get the default level.
+	embedder := &keywordEmbedder{keywords: []string{"aws_instance"}}
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("ollama"),
+		WithModel("llama3"),
+		WithProvider("chatgpt"),
+		WithBaseURL("http://localhost:11434/v1"),
+		WithIaCPath(tmpDir),
+		WithSanitizeProfile(DestinationLocal, sanitize.LevelMinimal),
+		WithEmbedder(embedder),
+		WithConsentPolicy(ConsentAutoApprove),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+	if prompt, err := client.BuildPrompt(); err != nil || !strings.Contains(prompt, "10.0.0.5") {
+		t.Fatalf("Expected minimal sanitization of the prompt, got %v:\n%s", err, prompt)
+	}
+	if _, err := client.BuildIndex(context.Background()); err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	for _, text := range embedder.texts {
+		if strings.Contains(text, "10.0.0.5") {
+			t.Errorf("Expected chunks to be sanitized for the remote embedder, got %q", text)
+		}
+	}
+}
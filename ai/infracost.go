@@ -0,0 +1,191 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SectionCosts holds the cost estimates of Infracost for each Terraform
+// root.
+const SectionCosts = "infracost"
+
+// infracostFile is the name of the output of infracost breakdown --format
+// json saved in a Terraform root, used when kado-ai does not run infracost.
+const infracostFile = "infracost.json"
+
+// infracostBreakdown is the output of infracost breakdown --format json.
+// Costs are decimal strings, or null when they are unknown.
+type infracostBreakdown struct {
+	Currency             string  `json:"currency"`
+	TotalMonthlyCost     *string `json:"totalMonthlyCost"`
+	PastTotalMonthlyCost *string `json:"pastTotalMonthlyCost"`
+	DiffTotalMonthlyCost *string `json:"diffTotalMonthlyCost"`
+	Projects             []struct {
+		Breakdown struct {
+			Resources []infracostResource `json:"resources"`
+		} `json:"breakdown"`
+		Diff struct {
+			Resources []infracostResource `json:"resources"`
+		} `json:"diff"`
+	} `json:"projects"`
+}
+
+type infracostResource struct {
+	Name        string  `json:"name"`
+	MonthlyCost *string `json:"monthlyCost"`
+}
+
+// scanCosts adds the cost estimate of every Terraform root with Terraform
+// code to result: the output of infracost breakdown when enabled, run on
+// the root's plan if there is one, and otherwise an infracost.json saved in
+// the root.
+func (c *AIClient) scanCosts(ctx context.Context, result *ScanResult) {
+	plans := map[string]ScannedFile{}
+	for _, plan := range result.Section(SectionTerraformPlan) {
+		plans[filepath.Dir(plan.Path)] = plan
+	}
+	for _, root := range c.terraformRoots() {
+		if !hasTerraformCode(result, root) {
+			continue
+		}
+		path := filepath.Join(root, infracostFile)
+		var content string
+		if c.infracost {
+			out, err := c.runInfracost(ctx, root, plans[result.rel(root)])
+			if err != nil {
+				fmt.Fprintf(c.messages(), "Warning: failed to estimate the costs of %s: %v\n", root, err)
+				continue
+			}
+			content = out
+		} else {
+			out, err := c.extractFileContent(path)
+			if err != nil {
+				continue
+			}
+			content = out
+		}
+		costs, err := renderCosts(content)
+		if err != nil {
+			result.skip(path, err.Error())
+			fmt.Fprintf(c.messages(), "Warning: %s is left out: %v\n", path, err)
+			continue
+		}
+		addOutput(result, SectionCosts, path, costs)
+		result.Files[len(result.Files)-1].Language = "markdown"
+	}
+}
+
+// runInfracost runs infracost breakdown on plan, so the estimate includes
+// the change in cost the plan makes, or on the code of root when there is
+// no plan.
+func (c *AIClient) runInfracost(ctx context.Context, root string, plan ScannedFile) (string, error) {
+	if _, err := lookPath("infracost"); err != nil {
+		return "", fmt.Errorf("infracost is not on the PATH")
+	}
+	path := root
+	if plan.Content != "" && !plan.Summarized {
+		tmpDir, err := os.MkdirTemp("", "kado-ai-infracost")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temporary directory: %v", err)
+		}
+		defer os.RemoveAll(tmpDir)
+		path = filepath.Join(tmpDir, "plan.json")
+		if err := os.WriteFile(path, []byte(plan.Content), 0600); err != nil {
+			return "", fmt.Errorf("failed to write plan: %v", err)
+		}
+	}
+	out, err := runCommand(ctx, root, nil, "infracost", "breakdown", "--path", path, "--format", "json", "--no-color")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// renderCosts renders the output of infracost breakdown as the total
+// monthly cost, with its change when it was estimated from a plan, and a
+// table of the resources that cost something or whose cost changes, the
+// largest change first.
+func renderCosts(content string) (string, error) {
+	var breakdown infracostBreakdown
+	if err := json.Unmarshal([]byte(content), &breakdown); err != nil {
+		return "", fmt.Errorf("not the output of infracost breakdown --format json: %v", err)
+	}
+	if breakdown.TotalMonthlyCost == nil && len(breakdown.Projects) == 0 {
+		return "", fmt.Errorf("not the output of infracost breakdown --format json")
+	}
+	currency := breakdown.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+
+	type resourceCost struct {
+		name   string
+		cost   float64
+		change float64
+	}
+	costs := map[string]*resourceCost{}
+	var names []string
+	resource := func(name string) *resourceCost {
+		if costs[name] == nil {
+			costs[name] = &resourceCost{name: name}
+			names = append(names, name)
+		}
+		return costs[name]
+	}
+	for _, project := range breakdown.Projects {
+		for _, r := range project.Breakdown.Resources {
+			resource(r.Name).cost += parseCost(r.MonthlyCost)
+		}
+		for _, r := range project.Diff.Resources {
+			resource(r.Name).change += parseCost(r.MonthlyCost)
+		}
+	}
+	var rows []*resourceCost
+	for _, name := range names {
+		if r := costs[name]; r.cost != 0 || r.change != 0 {
+			rows = append(rows, r)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if a, b := math.Abs(rows[i].change), math.Abs(rows[j].change); a != b {
+			return a > b
+		}
+		if rows[i].cost != rows[j].cost {
+			return rows[i].cost > rows[j].cost
+		}
+		return rows[i].name < rows[j].name
+	})
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Total monthly cost: %.2f %s", parseCost(breakdown.TotalMonthlyCost), currency)
+	if breakdown.DiffTotalMonthlyCost != nil && breakdown.PastTotalMonthlyCost != nil {
+		fmt.Fprintf(&out, " (previously %.2f %s, %+.2f %s)", parseCost(breakdown.PastTotalMonthlyCost), currency, parseCost(breakdown.DiffTotalMonthlyCost), currency)
+	}
+	out.WriteString("\n")
+	if len(rows) > 0 {
+		fmt.Fprintf(&out, "\n| Resource | Monthly cost (%s) | Monthly change (%s) |\n| --- | --- | --- |\n", currency, currency)
+		for _, r := range rows {
+			fmt.Fprintf(&out, "| %s | %.2f | %+.2f |\n", r.name, r.cost, r.change)
+		}
+	}
+	return out.String(), nil
+}
+
+// parseCost parses a cost of infracost, which is 0 when it is unknown.
+func parseCost(cost *string) float64 {
+	if cost == nil {
+		return 0
+	}
+	value, err := strconv.ParseFloat(*cost, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
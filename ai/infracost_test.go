@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testInfracost = `{
+  "currency": "USD",
+  "totalMonthlyCost": "150.5",
+  "pastTotalMonthlyCost": "100",
+  "diffTotalMonthlyCost": "50.5",
+  "projects": [{
+    "breakdown": {"resources": [
+      {"name": "aws_instance.web", "monthlyCost": "120"},
+      {"name": "aws_s3_bucket.logs", "monthlyCost": "30.5"},
+      {"name": "aws_iam_role.app", "monthlyCost": null}
+    ]},
+    "diff": {"resources": [
+      {"name": "aws_instance.web", "monthlyCost": "60"},
+      {"name": "aws_nat_gateway.old", "monthlyCost": "-9.5"}
+    ]}
+  }]
+}`
+
+func TestRenderCosts(t *testing.T) {
+	costs, err := renderCosts(testInfracost)
+	if err != nil {
+		t.Fatalf("renderCosts failed: %v", err)
+	}
+	expected := `Total monthly cost: 150.50 USD (previously 100.00 USD, +50.50 USD)
+
+| Resource | Monthly cost (USD) | Monthly change (USD) |
+| --- | --- | --- |
+| aws_instance.web | 120.00 | +60.00 |
+| aws_nat_gateway.old | 0.00 | -9.50 |
+| aws_s3_bucket.logs | 30.50 | +0.00 |
+`
+	if costs != expected {
+		t.Errorf("Expected costs:\n%s\ngot:\n%s", expected, costs)
+	}
+
+	if _, err := renderCosts(`{"resource_changes":[]}`); err == nil {
+		t.Errorf("Expected an error for a file that is not an Infracost breakdown")
+	}
+}
+
+func TestScanCosts(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf":        "resource \"aws_instance\" \"web\" {}\n",
+		"terraform/plan.json":      `{"resource_changes":[{"address":"aws_instance.web"}]}`,
+		"terraform/infracost.json": testInfracost,
+	})
+
+	// A saved breakdown is used unless infracost runs.
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	costs := result.Section(SectionCosts)
+	if len(costs) != 1 || !strings.HasPrefix(costs[0].Content, "Total monthly cost: 150.50 USD") {
+		t.Fatalf("Expected the saved costs, got %+v", costs)
+	}
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.Costs, "| aws_instance.web | 120.00 | +60.00 |") {
+		t.Errorf("Expected the costs in the prompt, got %q", data.Costs)
+	}
+
+	// infracost runs on the plan.
+	var planned string
+	calls := fakeRunCommand(t, `{"currency":"EUR","totalMonthlyCost":"10","projects":[]}`)
+	originalRunCommand := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		if name == "infracost" {
+			data, _ := os.ReadFile(args[2])
+			planned = string(data)
+		}
+		return originalRunCommand(ctx, dir, env, name, args...)
+	}
+	originalLookPath := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { lookPath = originalLookPath }()
+
+	client.infracost = true
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	costs = result.Section(SectionCosts)
+	if len(costs) != 1 || costs[0].Content != "Total monthly cost: 10.00 EUR\n" || costs[0].Path != filepath.Join("terraform", "infracost.json") {
+		t.Fatalf("Expected the costs infracost estimated, got %+v", costs)
+	}
+	var infracost []string
+	for _, call := range *calls {
+		if call[0] == "infracost" {
+			infracost = call
+		}
+	}
+	if len(infracost) == 0 || infracost[1] != "breakdown" || !strings.Contains(planned, "aws_instance.web") {
+		t.Errorf("Expected infracost to run on the plan, got %v", *calls)
+	}
+}
@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+// AIInputPath returns the path the sanitized AI input is saved to:
+// ai_input.txt in the IaC path unless WithInputPath says otherwise, with an
+// .age extension when it is encrypted.
+func (c *AIClient) AIInputPath() string {
+	path := filepath.Join(c.iacPath, "ai_input.txt")
+	if c.inputPath != "" {
+		path = c.inputPath
+	}
+	if len(c.inputRecipients) > 0 {
+		path += ".age"
+	}
+	return path
+}
+
+// saveAIInput writes input to the AI input file, readable only by its owner
+// and encrypted if recipients are set, and the report of the redactions made
+// in it to ai_redactions.json.
+func (c *AIClient) saveAIInput(input string, redactions []sanitize.Redaction) error {
+	path := c.AIInputPath()
+	data := []byte(input)
+	if len(c.inputRecipients) > 0 {
+		encrypted, err := encryptWithAge(data, c.inputRecipients)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt AI input: %v", err)
+		}
+		data = encrypted
+		// Do not leave the plaintext of an earlier unencrypted run behind.
+		if err := os.Remove(strings.TrimSuffix(path, ".age")); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove plaintext AI input: %v", err)
+		}
+	}
+	if err := writePrivateFile(path, data); err != nil {
+		return fmt.Errorf("failed to save AI input to file: %v", err)
+	}
+	if err := sanitize.NewReport(redactions).Save(c.RedactionReportPath()); err != nil {
+		return fmt.Errorf("failed to save redaction report: %v", err)
+	}
+	return nil
+}
+
+// removeAIInput deletes the AI input file at the end of a run.
+func (c *AIClient) removeAIInput() {
+	if err := os.Remove(c.AIInputPath()); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(c.messages(), "Warning: failed to delete AI input: %v\n", err)
+	}
+}
+
+// writePrivateFile writes data to path with mode 0600. The data goes to a
+// new temporary file in the same directory, which is then renamed over
+// path, so it is never readable through the mode of a file that already
+// exists there.
+func writePrivateFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// encryptWithAge encrypts data to recipients with the age command.
+var encryptWithAge = func(data []byte, recipients []string) ([]byte, error) {
+	var args []string
+	for _, recipient := range recipients {
+		args = append(args, "-r", recipient)
+	}
+	cmd := exec.Command("age", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("age failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runInputTest(t *testing.T, tmpDir string, opts ...Option) *AIClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Looks fine."}]}`)
+	}))
+	defer server.Close()
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_vpc\" \"main\" {}\n",
+	})
+	client, err := NewAIClientWithOptions(append([]Option{
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+	}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+	if _, err := client.RunAI(); err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	return client
+}
+
+func TestAIInputPermissions(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// An input left by an older version keeps its mode unless it is tightened.
+	if err := os.WriteFile(filepath.Join(tmpDir, "ai_input.txt"), []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write the old AI input: %v", err)
+	}
+	client := runInputTest(t, tmpDir)
+
+	info, err := os.Stat(client.AIInputPath())
+	if err != nil {
+		t.Fatalf("Failed to stat the AI input: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestAIInputPath(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "private", "input.txt")
+	runInputTest(t, tmpDir, WithInputPath(path))
+
+	input, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(input), "aws_vpc") {
+		t.Errorf("Expected the AI input at %s, got %q, %v", path, input, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "ai_input.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected no ai_input.txt in the IaC path")
+	}
+}
+
+func TestAIInputEncryption(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var recipients []string
+	original := encryptWithAge
+	encryptWithAge = func(data []byte, to []string) ([]byte, error) {
+		recipients = to
+		return []byte("age-encryption.org/v1\nciphertext"), nil
+	}
+	defer func() { encryptWithAge = original }()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "ai_input.txt"), []byte("plaintext"), 0644); err != nil {
+		t.Fatalf("Failed to write the old AI input: %v", err)
+	}
+	client := runInputTest(t, tmpDir, WithInputEncryption(splitList("age1abc, age1def")...))
+
+	if client.AIInputPath() != filepath.Join(tmpDir, "ai_input.txt.age") {
+		t.Errorf("Expected an .age input path, got %s", client.AIInputPath())
+	}
+	if strings.Join(recipients, " ") != "age1abc age1def" {
+		t.Errorf("Expected both recipients, got %v", recipients)
+	}
+	input, err := os.ReadFile(client.AIInputPath())
+	if err != nil || string(input) != "age-encryption.org/v1\nciphertext" {
+		t.Errorf("Expected the encrypted AI input, got %q, %v", input, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "ai_input.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected the plaintext AI input to be removed")
+	}
+}
+
+func TestAIInputDeletion(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	client := runInputTest(t, tmpDir, WithInputDeletion(true))
+
+	if _, err := os.Stat(client.AIInputPath()); !os.IsNotExist(err) {
+		t.Errorf("Expected the AI input to be deleted after the run")
+	}
+	if _, err := os.Stat(client.RedactionReportPath()); err != nil {
+		t.Errorf("Expected the redaction report to be kept: %v", err)
+	}
+}
+
+func TestAIInputDeletionOnManifestError(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// A directory in the way of the scan manifest makes saving it fail.
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf":             "resource \"aws_vpc\" \"main\" {}\n",
+		"ai_scan_manifest.json/blocker": "",
+	})
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithInputDeletion(true),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+	if _, err := client.RunAI(); err == nil {
+		t.Fatalf("Expected RunAI to fail when the scan manifest cannot be saved")
+	}
+	if _, err := os.Stat(client.AIInputPath()); !os.IsNotExist(err) {
+		t.Errorf("Expected the AI input to be deleted after the failed run")
+	}
+	entries, _ := os.ReadDir(tmpDir)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".ai_") {
+			t.Errorf("Expected no temporary file to be left, got %s", entry.Name())
+		}
+	}
+}
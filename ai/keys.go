@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Key rotation strategies for clients configured with several API keys.
+const (
+	// KeyRotationRoundRobin uses each key in turn for successive requests.
+	KeyRotationRoundRobin = "round-robin"
+	// KeyRotationOn429 keeps using one key until the provider rate-limits it.
+	KeyRotationOn429 = "on-429"
+)
+
+// keyRing hands out API keys from a comma-separated list. Whatever the
+// strategy, a request that is rate-limited is retried with the next key.
+type keyRing struct {
+	mu       sync.Mutex
+	keys     []string
+	strategy string
+	next     int
+}
+
+// splitAPIKeys splits a comma-separated API key setting into its keys.
+// Spaces around the commas are ignored, but a single key is returned as is so
+// that Validate can still report stray whitespace in it.
+func splitAPIKeys(apiKey string) []string {
+	if !strings.Contains(apiKey, ",") {
+		return []string{apiKey}
+	}
+	var keys []string
+	for _, key := range strings.Split(apiKey, ",") {
+		keys = append(keys, strings.TrimSpace(key))
+	}
+	return keys
+}
+
+func newKeyRing(apiKey string, strategy string) (*keyRing, error) {
+	if strategy == "" {
+		strategy = KeyRotationRoundRobin
+	}
+	if strategy != KeyRotationRoundRobin && strategy != KeyRotationOn429 {
+		return nil, fmt.Errorf("unknown key rotation strategy %q; expected %s or %s", strategy, KeyRotationRoundRobin, KeyRotationOn429)
+	}
+	return &keyRing{keys: splitAPIKeys(apiKey), strategy: strategy}, nil
+}
+
+func (r *keyRing) len() int {
+	return len(r.keys)
+}
+
+// pick returns the key to use for the next request.
+func (r *keyRing) pick() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := r.keys[r.next]
+	if r.strategy == KeyRotationRoundRobin {
+		r.next = (r.next + 1) % len(r.keys)
+	}
+	return key
+}
+
+// rateLimited moves past key after the provider rejected it with a 429.
+func (r *keyRing) rateLimited(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.strategy == KeyRotationOn429 && r.keys[r.next] == key {
+		r.next = (r.next + 1) % len(r.keys)
+	}
+}
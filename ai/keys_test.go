@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKeyRing(t *testing.T) {
+	roundRobin, err := newKeyRing("key-a, key-b,key-c", KeyRotationRoundRobin)
+	if err != nil {
+		t.Fatalf("newKeyRing failed: %v", err)
+	}
+	var picked []string
+	for i := 0; i < 4; i++ {
+		picked = append(picked, roundRobin.pick())
+	}
+	if got := strings.Join(picked, ","); got != "key-a,key-b,key-c,key-a" {
+		t.Errorf("Expected round-robin order key-a,key-b,key-c,key-a, got %s", got)
+	}
+
+	on429, err := newKeyRing("key-a,key-b", KeyRotationOn429)
+	if err != nil {
+		t.Fatalf("newKeyRing failed: %v", err)
+	}
+	if on429.pick() != "key-a" || on429.pick() != "key-a" {
+		t.Errorf("Expected on-429 rotation to keep using the first key")
+	}
+	on429.rateLimited("key-a")
+	if got := on429.pick(); got != "key-b" {
+		t.Errorf("Expected key-b after key-a was rate-limited, got %s", got)
+	}
+
+	if _, err := newKeyRing("key-a", "random"); err == nil {
+		t.Errorf("Expected an error for an unknown rotation strategy")
+	}
+}
+
+func TestRateLimitedKeyIsRotated(t *testing.T) {
+	var usedKeys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("x-api-key")
+		usedKeys = append(usedKeys, key)
+		if key == "sk-ant-limited" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"type":"error","error":{"type":"rate_limit_error","message":"rate limited"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"All good."}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-limited,sk-ant-spare"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithKeyRotation(KeyRotationOn429),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	recommendations, err := client.getRecommendations("analyze this")
+	if err != nil {
+		t.Fatalf("getRecommendations failed: %v", err)
+	}
+	if recommendations != "All good." {
+		t.Errorf("Expected 'All good.', got '%s'", recommendations)
+	}
+	if got := strings.Join(usedKeys, ","); got != "sk-ant-limited,sk-ant-spare" {
+		t.Errorf("Expected the request to be retried with the spare key, got %s", got)
+	}
+}
@@ -0,0 +1,189 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sections of Kubernetes content: manifests as they are in the repository,
+// and the output of rendering Helm charts and Kustomize overlays.
+const (
+	SectionKubernetes         = "kubernetes manifests"
+	SectionKubernetesRendered = "kubernetes rendered"
+)
+
+// Kubernetes manifests have apiVersion and kind at the top level.
+var (
+	kubernetesAPIVersion = regexp.MustCompile(`(?m)^apiVersion:[ \t]*\S`)
+	kubernetesKind       = regexp.MustCompile(`(?m)^kind:[ \t]*\S`)
+)
+
+// kustomizationFiles are the names kustomize looks for in a directory.
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// kubernetesProjects are the Helm charts and Kustomize directories found
+// under the IaC path.
+type kubernetesProjects struct {
+	charts         []string
+	kustomizations []string
+}
+
+func isKubernetesManifest(content string) bool {
+	return kubernetesAPIVersion.MatchString(content) && kubernetesKind.MatchString(content)
+}
+
+// findKubernetesProjects finds the directories with a Chart.yaml, without
+// looking inside them, since subcharts are rendered with their parent, and
+// the directories with a kustomization file.
+func findKubernetesProjects(root string) (kubernetesProjects, error) {
+	var projects kubernetesProjects
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != root && discoverySkipDirs[info.Name()] {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "Chart.yaml")); err == nil {
+			projects.charts = append(projects.charts, path)
+			return filepath.SkipDir
+		}
+		if kustomizationFile(path) != "" {
+			projects.kustomizations = append(projects.kustomizations, path)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return kubernetesProjects{}, nil
+	}
+	return projects, err
+}
+
+// kustomizationFile returns the path of the kustomization file in dir, or
+// the empty string if there is none.
+func kustomizationFile(dir string) string {
+	for _, name := range kustomizationFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// kustomizeOverlays returns the kustomizations that no other kustomization
+// uses as a resource, base, or component, which are the ones to build.
+func kustomizeOverlays(kustomizations []string) []string {
+	used := map[string]bool{}
+	for _, dir := range kustomizations {
+		data, err := os.ReadFile(kustomizationFile(dir))
+		if err != nil {
+			continue
+		}
+		var k struct {
+			Resources  []string `yaml:"resources"`
+			Bases      []string `yaml:"bases"`
+			Components []string `yaml:"components"`
+		}
+		if err := yaml.Unmarshal(data, &k); err != nil {
+			continue
+		}
+		for _, ref := range append(append(k.Resources, k.Bases...), k.Components...) {
+			if !strings.Contains(ref, "://") {
+				used[filepath.Clean(filepath.Join(dir, ref))] = true
+			}
+		}
+	}
+	var overlays []string
+	for _, dir := range kustomizations {
+		if !used[dir] {
+			overlays = append(overlays, dir)
+		}
+	}
+	return overlays
+}
+
+// scanKubernetes adds the Kubernetes manifests under the IaC path to
+// result, other than Helm templates, which are not valid YAML, and the
+// files of the Ansible roots. Manifests that define or use Crossplane APIs
+// go to their own section. With rendering enabled, it also adds the
+// output of helm template for every chart and of kustomize build for every
+// overlay.
+func (c *AIClient) scanKubernetes(ctx context.Context, result *ScanResult) error {
+	projects, err := findKubernetesProjects(c.iacPath)
+	if err != nil {
+		return fmt.Errorf("failed to look for Kubernetes manifests: %v", err)
+	}
+	collected := map[string]bool{}
+	for _, f := range result.Files {
+		collected[f.Path] = true
+	}
+	skipped := append(projects.charts, c.ansibleRoots()...)
+	ignore := c.ignoreRules()
+	var paths []string
+	manifests := map[string]string{}
+	c.walk(result, c.iacPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != c.iacPath && (discoverySkipDirs[info.Name()] || underAny(path, skipped) || ignore.ignored(c.iacPath, path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !hasExtension(info.Name(), []string{".yml", ".yaml"}) || collected[result.rel(path)] || ignore.ignored(c.iacPath, path, false) {
+			return nil
+		}
+		if content, err := os.ReadFile(path); err == nil && isKubernetesManifest(string(content)) {
+			paths = append(paths, path)
+			manifests[path] = string(content)
+		}
+		return nil
+	})
+	kinds := crossplaneKinds(manifests)
+	for _, path := range paths {
+		section := SectionKubernetes
+		if isCrossplane(manifests[path], kinds) {
+			section = SectionCrossplane
+		}
+		c.addFile(result, section, path)
+	}
+
+	if !c.kubernetesRender {
+		return nil
+	}
+	for _, chart := range projects.charts {
+		out, err := runCommand(ctx, chart, nil, "helm", "template", filepath.Base(chart), ".")
+		if err != nil {
+			fmt.Fprintf(c.messages(), "Warning: failed to render Helm chart %s: %v\n", chart, err)
+			continue
+		}
+		addOutput(result, SectionKubernetesRendered, filepath.Join(chart, "helm-template.yaml"), string(out))
+	}
+	overlays := kustomizeOverlays(projects.kustomizations)
+	sort.Strings(overlays)
+	for _, dir := range overlays {
+		name, args := "kustomize", []string{"build", "."}
+		if _, err := lookPath(name); err != nil {
+			name, args = "kubectl", []string{"kustomize", "."}
+		}
+		out, err := runCommand(ctx, dir, nil, name, args...)
+		if err != nil {
+			fmt.Fprintf(c.messages(), "Warning: failed to build Kustomize overlay %s: %v\n", dir, err)
+			continue
+		}
+		addOutput(result, SectionKubernetesRendered, filepath.Join(dir, "kustomize-build.yaml"), string(out))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanKubernetes(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"k8s/deployment.yaml":                       "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+		"k8s/base/kustomization.yaml":               "resources:\n  - service.yaml\n",
+		"k8s/base/service.yaml":                     "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+		"k8s/overlays/prod/kustomization.yaml":      "resources:\n  - ../../base\n",
+		"charts/api/Chart.yaml":                     "apiVersion: v2\nname: api\n",
+		"charts/api/templates/deployment.yaml":      "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: {{ .Release.Name }}\n",
+		"charts/api/charts/redis/Chart.yaml":        "apiVersion: v2\nname: redis\n",
+		"docker-compose.yml":                        "services:\n  web: {}\n",
+		"ansible/site.yml":                          "apiVersion: v1\nkind: Fake\n",
+		"node_modules/chart/templates/service.yaml": "apiVersion: v1\nkind: Service\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var manifests []string
+	for _, f := range result.Section(SectionKubernetes) {
+		manifests = append(manifests, filepath.ToSlash(f.Path))
+	}
+	if got := strings.Join(manifests, ","); got != "k8s/base/service.yaml,k8s/deployment.yaml" {
+		t.Errorf("Expected only the manifests outside of charts and Ansible roots, got %s", got)
+	}
+	if rendered := result.Section(SectionKubernetesRendered); len(rendered) != 0 {
+		t.Errorf("Expected nothing to be rendered unless enabled, got %+v", rendered)
+	}
+
+	var calls []string
+	original := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		rel, _ := filepath.Rel(tmpDir, dir)
+		calls = append(calls, filepath.ToSlash(rel)+": "+name+" "+strings.Join(args, " "))
+		return []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: " + name + "\ndata:\n  password: aHVudGVyMg==\n"), nil
+	}
+	defer func() { runCommand = original }()
+	originalLookPath := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { lookPath = originalLookPath }()
+
+	client.kubernetesRender = true
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if got := strings.Join(calls, "; "); got != "charts/api: helm template api .; k8s/overlays/prod: kustomize build ." {
+		t.Errorf("Expected the chart and only the overlay to be rendered, got %s", got)
+	}
+	rendered := result.Section(SectionKubernetesRendered)
+	if len(rendered) != 2 || rendered[0].Path != filepath.Join("charts", "api", "helm-template.yaml") {
+		t.Fatalf("Expected the rendered output of the chart and the overlay, got %+v", rendered)
+	}
+
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.KubernetesRendered, "kind: Secret") || strings.Contains(data.KubernetesRendered, "aHVudGVyMg==") {
+		t.Errorf("Expected the rendered Secret data to be redacted, got %q", data.KubernetesRendered)
+	}
+	if !strings.Contains(data.KubernetesManifests, "kind: Deployment") {
+		t.Errorf("Expected the manifests in the prompt data, got %q", data.KubernetesManifests)
+	}
+}
@@ -0,0 +1,192 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// The default layout of the IaC path: Terraform code in terraform/ and
+// Ansible code in ansible/. Plans are looked for in the Terraform roots.
+var (
+	defaultTerraformRoots = []string{"terraform"}
+	defaultAnsibleRoots   = []string{"ansible"}
+)
+
+var (
+	terraformExtensions = []string{".tf", ".rego"}
+	ansibleExtensions   = []string{".yml", ".yaml", ".rego"}
+)
+
+// discoverySkipDirs are never searched for IaC content: version control,
+// provider and package caches, kado-ai's own files, and the output of CDK
+// projects, which scanCDK collects.
+var discoverySkipDirs = map[string]bool{
+	".git": true, ".terraform": true, ".terragrunt-cache": true, "node_modules": true,
+	".venv": true, "venv": true, projectDir: true, "cdk.out": true, "cdktf.out": true,
+}
+
+// ansibleDirs are the directories whose YAML files are Ansible content
+// wherever they are.
+var ansibleDirs = map[string]bool{
+	"roles": true, "playbooks": true, "group_vars": true, "host_vars": true,
+	"tasks": true, "handlers": true, "inventory": true, "inventories": true,
+}
+
+// ansiblePlaybook matches the keys of Ansible plays and tasks at the start
+// of a line.
+var ansiblePlaybook = regexp.MustCompile(`(?m)^\s*-?\s*(hosts|import_playbook|import_tasks|include_tasks|include_role|ansible\.builtin\.[a-z_]+):`)
+
+// terraformRoots returns the directories scanned for Terraform code.
+func (c *AIClient) terraformRoots() []string {
+	return c.layoutPaths(c.terraformDirs, defaultTerraformRoots)
+}
+
+// ansibleRoots returns the directories scanned for Ansible code.
+func (c *AIClient) ansibleRoots() []string {
+	return c.layoutPaths(c.ansibleDirs, defaultAnsibleRoots)
+}
+
+// planFiles returns the Terraform plans to include: the plan files if set,
+// and otherwise the first plan named one of planNames in each Terraform
+// root.
+func (c *AIClient) planFiles() []string {
+	if len(c.planPaths) > 0 {
+		return c.layoutPaths(c.planPaths, nil)
+	}
+	var plans []string
+	for _, root := range c.terraformRoots() {
+		for _, name := range planNames {
+			if info, err := os.Stat(filepath.Join(root, name)); err == nil && !info.IsDir() {
+				plans = append(plans, filepath.Join(root, name))
+				break
+			}
+		}
+	}
+	return plans
+}
+
+// layoutPaths resolves paths, or defaults if there are none, against the
+// IaC path.
+func (c *AIClient) layoutPaths(paths []string, defaults []string) []string {
+	if len(paths) == 0 {
+		paths = defaults
+	}
+	var resolved []string
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.iacPath, path)
+		}
+		resolved = append(resolved, filepath.Clean(path))
+	}
+	return resolved
+}
+
+// scanLayout collects the Terraform and Ansible code in their roots and the
+// plans, and with discovery or project discovery enabled, the Terraform and
+// Ansible content and plans anywhere else under the IaC path.
+func (c *AIClient) scanLayout(ctx context.Context, result *ScanResult) {
+	terraformRoots, ansibleRoots := c.terraformRoots(), c.ansibleRoots()
+	for _, root := range terraformRoots {
+		c.scanDirectory(result, SectionTerraform, root, terraformExtensions)
+	}
+	for _, root := range ansibleRoots {
+		c.scanDirectory(result, SectionAnsible, root, ansibleExtensions)
+	}
+	plans := map[string]bool{}
+	if c.terraformPlan {
+		c.planTerraformRoots(ctx, result)
+	} else {
+		for _, path := range c.planFiles() {
+			plans[path] = true
+			c.addPlan(ctx, result, path)
+		}
+	}
+	for _, path := range c.ansibleCheckFiles() {
+		c.addAnsibleCheck(result, path)
+	}
+	if c.discover || c.projects {
+		c.discoverContent(ctx, result, append(terraformRoots, ansibleRoots...), plans)
+	}
+}
+
+// discoverContent adds the Terraform code, Ansible code, and plans under the
+// IaC path that are outside of roots and not in plans. A directory with an
+// ansible.cfg holds Ansible content, and elsewhere YAML files are Ansible
+// content if they are in a directory such as roles or group_vars, or look
+// like playbooks or task lists. Binary plans are left out next to a plan in
+// JSON format.
+func (c *AIClient) discoverContent(ctx context.Context, result *ScanResult, roots []string, plans map[string]bool) {
+	ignore := c.ignoreRules()
+	var ansibleProjects []string
+	c.walk(result, c.iacPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != c.iacPath && (discoverySkipDirs[info.Name()] || underAny(path, roots)) {
+				return filepath.SkipDir
+			}
+			if path != c.iacPath && ignore.ignored(c.iacPath, path, true) {
+				result.skip(path, ignoredReason)
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "ansible.cfg")); err == nil {
+				ansibleProjects = append(ansibleProjects, path)
+			}
+			return nil
+		}
+		name := info.Name()
+		if plans[path] {
+			return nil
+		}
+		section := ""
+		switch {
+		case isJSONPlanName(name) || isBinaryPlanName(name) && !hasJSONPlan(filepath.Dir(path)):
+			section = SectionTerraformPlan
+		case hasExtension(name, terraformExtensions):
+			section = SectionTerraform
+		case (hasExtension(name, []string{".yml", ".yaml"}) || isINIInventory(path)) && isAnsibleContent(path, ansibleProjects):
+			section = SectionAnsible
+		}
+		if section == "" {
+			return nil
+		}
+		if ignore.ignored(c.iacPath, path, false) {
+			result.skip(path, ignoredReason)
+			return nil
+		}
+		if section == SectionTerraformPlan {
+			c.addPlan(ctx, result, path)
+		} else {
+			c.addFile(result, section, path)
+		}
+		return nil
+	})
+}
+
+// isAnsibleContent reports whether the YAML file at path is Ansible content.
+func isAnsibleContent(path string, ansibleProjects []string) bool {
+	if underAny(path, ansibleProjects) {
+		return true
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		if ansibleDirs[dir] {
+			return true
+		}
+	}
+	content, err := os.ReadFile(path)
+	return err == nil && ansiblePlaybook.Match(content)
+}
+
+// underAny reports whether path is one of dirs or inside one of them.
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
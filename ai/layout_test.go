@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanLayout(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"infra/network/main.tf":      "resource \"aws_vpc\" \"main\" {}\n",
+		"infra/dns/main.tf":          "resource \"aws_route53_zone\" \"main\" {}\n",
+		"config/site.yml":            "- hosts: web\n  roles: [nginx]\n",
+		"plans/network.json":         `{"resource_changes": []}`,
+		"terraform/ignored.tf":       "resource \"aws_instance\" \"old\" {}\n",
+		"modules/vpc/main.tf":        "variable \"cidr\" {}\n",
+		"deploy/roles/web/main.yml":  "- name: Install nginx\n",
+		"deploy/docker-compose.yml":  "services: {}\n",
+		"tools/playbook.yaml":        "- import_playbook: site.yml\n",
+		"envs/prod.tfplan.json":      `{"resource_changes": []}`,
+		".terraform/modules/x/a.tf":  "resource \"null_resource\" \"x\" {}\n",
+		"ops/ansible.cfg":            "[defaults]\n",
+		"ops/vars.yml":               "app_port: 8080\n",
+		"node_modules/pkg/index.yml": "- hosts: all\n",
+	})
+
+	client := &AIClient{
+		iacPath:       tmpDir,
+		terraformDirs: []string{"infra"},
+		ansibleDirs:   []string{"config"},
+		planPaths:     []string{filepath.Join("plans", "network.json")},
+	}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	paths := func(section string) []string {
+		var paths []string
+		for _, f := range result.Section(section) {
+			paths = append(paths, filepath.ToSlash(f.Path))
+		}
+		return paths
+	}
+	if got := strings.Join(paths(SectionTerraform), ","); got != "infra/dns/main.tf,infra/network/main.tf" {
+		t.Errorf("Expected only the Terraform roots to be scanned, got %s", got)
+	}
+	if got := strings.Join(paths(SectionAnsible), ","); got != "config/site.yml" {
+		t.Errorf("Expected only the Ansible roots to be scanned, got %s", got)
+	}
+	if got := strings.Join(paths(SectionTerraformPlan), ","); got != "plans/network.json" {
+		t.Errorf("Expected only the configured plan, got %s", got)
+	}
+	if client.terraform().dir != filepath.Join(tmpDir, "infra") {
+		t.Errorf("Expected terraform to run in the first root, got %s", client.terraform().dir)
+	}
+
+	client.discover = true
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if got := strings.Join(paths(SectionTerraform), ","); got != "infra/dns/main.tf,infra/network/main.tf,modules/vpc/main.tf,terraform/ignored.tf" {
+		t.Errorf("Expected Terraform code to be discovered once, got %s", got)
+	}
+	if got := strings.Join(paths(SectionAnsible), ","); got != "config/site.yml,deploy/roles/web/main.yml,ops/vars.yml,tools/playbook.yaml" {
+		t.Errorf("Expected Ansible content to be discovered and other YAML left out, got %s", got)
+	}
+	if got := strings.Join(paths(SectionTerraformPlan), ","); got != "plans/network.json,envs/prod.tfplan.json" {
+		t.Errorf("Expected plans to be discovered, got %s", got)
+	}
+}
+
+func TestPromptDataPlans(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"network/plan.json": `{"resource_changes": [{"address": "aws_vpc.main"}]}`,
+		"dns/plan.json":     `{"resource_changes": [{"address": "aws_route53_zone.main"}]}`,
+	})
+	client := &AIClient{iacPath: tmpDir, planPaths: []string{"network/plan.json", "dns/plan.json"}}
+	data, _, err := client.collectPromptData(context.Background(), false)
+	if err != nil {
+		t.Fatalf("collectPromptData failed: %v", err)
+	}
+	network := strings.Index(data.TerraformPlan, "File: "+filepath.Join(tmpDir, "network", "plan.json")+"\n")
+	dns := strings.Index(data.TerraformPlan, "File: "+filepath.Join(tmpDir, "dns", "plan.json")+"\n")
+	if network < 0 || dns < network || !strings.Contains(data.TerraformPlan, "aws_route53_zone.main") {
+		t.Errorf("Expected both plans under file headers, got %q", data.TerraformPlan)
+	}
+}
@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// libraryDir is the directory of the question templates library, both among
+// the built-in prompts and in .kado/prompts.
+const libraryDir = "library"
+
+// Template is a reusable analysis in the question templates library.
+type Template struct {
+	Name  string
+	Title string
+	// Source is the path of a custom template, or "built-in".
+	Source string
+}
+
+// templateTitle matches the comment opening a library template, which holds
+// its title.
+var templateTitle = regexp.MustCompile(`^\{\{/\*\s*(.*?)\s*\*/\}\}`)
+
+// Templates lists the question templates library sorted by name: the
+// built-in templates, and the custom ones in .kado/prompts/library, which
+// replace built-in templates of the same name.
+func (c *AIClient) Templates() ([]Template, error) {
+	byName := map[string]Template{}
+	entries, _ := defaultPrompts.ReadDir(path.Join("prompts", libraryDir))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		text, _ := defaultPrompts.ReadFile(path.Join("prompts", libraryDir, entry.Name()))
+		byName[name] = Template{Name: name, Title: libraryTitle(name, string(text)), Source: "built-in"}
+	}
+
+	dir := c.projectPath("prompts", libraryDir)
+	custom, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %v", dir, err)
+	}
+	for _, entry := range custom {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tmpl") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		text, source, err := c.libraryTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		byName[name] = Template{Name: name, Title: libraryTitle(name, text), Source: source}
+	}
+
+	templates := make([]Template, 0, len(byName))
+	for _, t := range byName {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// RunTemplate analyzes the IaC code like RunAIWithQuestion, asking the
+// question of the library template name, such as "production-readiness" or
+// "iam-least-privilege". Templates are Go text/templates with the same
+// functions as prompt templates, such as {{severities}}.
+func (c *AIClient) RunTemplate(name string) (string, error) {
+	question, err := c.templateQuestion(name)
+	if err != nil {
+		return "", err
+	}
+	return c.run("analyze", question)
+}
+
+// templateQuestion renders the library template name into a question.
+func (c *AIClient) templateQuestion(name string) (string, error) {
+	text, _, err := c.libraryTemplate(name)
+	if err != nil {
+		return "", err
+	}
+	question, err := executePrompt(name, text, promptData{})
+	if err != nil {
+		return "", err
+	}
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return "", fmt.Errorf("template %q renders an empty question", name)
+	}
+	return question, nil
+}
+
+// libraryTemplate returns the text of the library template name and where
+// it came from, preferring .kado/prompts/library/<name>.tmpl over the
+// built-in one.
+func (c *AIClient) libraryTemplate(name string) (string, string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", "", fmt.Errorf("invalid template name %q", name)
+	}
+	customPath := c.projectPath("prompts", libraryDir, name+".tmpl")
+	text, err := os.ReadFile(customPath)
+	if err == nil {
+		return string(text), customPath, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read template %s: %v", name, err)
+	}
+	text, err = defaultPrompts.ReadFile(path.Join("prompts", libraryDir, name+".tmpl"))
+	if err != nil {
+		return "", "", fmt.Errorf("unknown template %q", name)
+	}
+	return string(text), "built-in", nil
+}
+
+func libraryTitle(name string, text string) string {
+	if m := templateTitle.FindStringSubmatch(strings.TrimSpace(text)); m != nil && m[1] != "" {
+		return m[1]
+	}
+	return name
+}
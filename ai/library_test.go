@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTemplates(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kado/prompts/library/pci-scope.tmpl":     "{{/* PCI scope review */}}\nWhich resources are in PCI scope?\n",
+		".kado/prompts/library/observability.tmpl": "Do we alert on 5xx errors?\n",
+		".kado/prompts/library/notes.txt":          "not a template",
+	})
+	client := &AIClient{iacPath: tmpDir}
+
+	templates, err := client.Templates()
+	if err != nil {
+		t.Fatalf("Templates failed: %v", err)
+	}
+	byName := map[string]Template{}
+	for i, tmpl := range templates {
+		if i > 0 && templates[i-1].Name >= tmpl.Name {
+			t.Errorf("Expected templates sorted by name, got %s before %s", templates[i-1].Name, tmpl.Name)
+		}
+		byName[tmpl.Name] = tmpl
+	}
+	if tmpl := byName["production-readiness"]; tmpl.Title != "Pre-production readiness review" || tmpl.Source != "built-in" {
+		t.Errorf("Expected the built-in production readiness template, got %+v", tmpl)
+	}
+	if tmpl := byName["pci-scope"]; tmpl.Title != "PCI scope review" || !strings.HasSuffix(tmpl.Source, "pci-scope.tmpl") {
+		t.Errorf("Expected the custom PCI scope template, got %+v", tmpl)
+	}
+	if tmpl := byName["observability"]; tmpl.Title != "observability" || tmpl.Source == "built-in" {
+		t.Errorf("Expected the custom template to replace the built-in one, got %+v", tmpl)
+	}
+	if _, ok := byName["notes"]; ok {
+		t.Errorf("Expected files without the .tmpl extension to be skipped")
+	}
+
+	for _, tmpl := range templates {
+		question, err := client.templateQuestion(tmpl.Name)
+		if err != nil || question == "" || strings.Contains(question, "{{") {
+			t.Errorf("Expected template %s to render a question, got %q, %v", tmpl.Name, question, err)
+		}
+	}
+
+	testCases := []string{"", "../config", "missing"}
+	for _, name := range testCases {
+		if _, err := client.templateQuestion(name); err == nil {
+			t.Errorf("Expected an error for template %q", name)
+		}
+	}
+}
+
+func TestRunTemplate(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_iam_role\" \"app\" {}\n",
+	})
+
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Scope the role to one bucket."}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	answer, err := client.RunTemplate("iam-least-privilege")
+	if err != nil {
+		t.Fatalf("RunTemplate failed: %v", err)
+	}
+	if answer != "Scope the role to one bucket." {
+		t.Errorf("Expected the provider's answer, got %q", answer)
+	}
+	if !strings.Contains(prompt, "answer this question about the infrastructure:\nDo the IAM roles") {
+		t.Errorf("Expected the template's question in the prompt, got %q", prompt)
+	}
+}
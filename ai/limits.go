@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+// defaultMaxFileTokens is the size, in estimated tokens, above which a file
+// is cut down to its beginning and end. A single file that large would take
+// up most of a context window, and cannot be split by chunking.
+const defaultMaxFileTokens = 20000
+
+// OversizedFiles says what an analysis does with files larger than the
+// per-file size limit.
+type OversizedFiles string
+
+const (
+	// OversizedFilesTruncate keeps the beginning and the end of the file,
+	// where variables, providers, and outputs usually are, and replaces the
+	// middle by a note of how many lines were left out.
+	OversizedFilesTruncate OversizedFiles = "truncate"
+	// OversizedFilesSummarize sends each oversized file to the provider to be
+	// summarized before the analysis, and analyzes the summary instead.
+	OversizedFilesSummarize OversizedFiles = "summarize"
+)
+
+func (o OversizedFiles) valid() bool {
+	return o == OversizedFilesTruncate || o == OversizedFilesSummarize
+}
+
+// truncationNote is the room left in a truncated file for the note of how
+// many lines were left out.
+const truncationNote = 80
+
+// truncateHeadTail cuts content down to at most limit bytes of whole lines,
+// half from its beginning and half from its end, with a note of how many
+// lines were left out in between. Content within the limit is returned as
+// is.
+func truncateHeadTail(content string, limit int) string {
+	if len(content) <= limit {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	half := (limit - truncationNote) / 2
+	head, size := 0, 0
+	for head < len(lines) && size+len(lines[head])+1 <= half {
+		size += len(lines[head]) + 1
+		head++
+	}
+	tail, size := len(lines), 0
+	for tail > head && size+len(lines[tail-1])+1 <= half {
+		size += len(lines[tail-1]) + 1
+		tail--
+	}
+	note := fmt.Sprintf("... (%d lines left out: the file exceeds the size limit) ...", tail-head)
+	parts := append(append(append([]string(nil), lines[:head]...), note), lines[tail:]...)
+	return strings.Join(parts, "\n")
+}
+
+// limitSizes enforces the size limits on the files of result: files above
+// the per-file limit are truncated, keeping their full content for
+// summarization, and when the files exceed the total limit, they are ranked
+// by relevance to question and the most relevant that fit are kept. The
+// others are recorded as skipped and listed in result.Omitted. Either limit
+// is off when zero.
+func (c *AIClient) limitSizes(ctx context.Context, result *ScanResult, question string) {
+	total := 0
+	for i := range result.Files {
+		f := &result.Files[i]
+		if c.maxFileTokens > 0 && estimateTokens(f.Content) > c.maxFileTokens {
+			f.full = f.Content
+			f.Content = truncateHeadTail(f.Content, c.maxFileTokens*4)
+			f.Truncated = true
+		}
+		total += estimateTokens(f.Content)
+	}
+	if c.maxScanTokens == 0 || total <= c.maxScanTokens {
+		return
+	}
+
+	keep := make([]bool, len(result.Files))
+	total = 0
+	for _, i := range c.rankFiles(ctx, result, question) {
+		tokens := estimateTokens(result.Files[i].Content)
+		if total+tokens > c.maxScanTokens {
+			result.Omitted = append(result.Omitted, result.Files[i].Path)
+			continue
+		}
+		keep[i] = true
+		total += tokens
+	}
+	var kept []ScannedFile
+	for i, f := range result.Files {
+		if !keep[i] {
+			result.Skipped = append(result.Skipped, SkippedEntry{
+				Path:   f.Path,
+				Reason: fmt.Sprintf("over the total size limit of %d tokens", c.maxScanTokens),
+			})
+			continue
+		}
+		kept = append(kept, f)
+	}
+	fmt.Fprintf(c.messages(), "Analyzing the %d most relevant of %d files to stay within the total size limit of %d tokens.\n", len(kept), len(result.Files), c.maxScanTokens)
+	result.Files = kept
+}
+
+// summarizeOversized replaces the content of the files of scan truncated
+// for their size by a summary written by the provider. Since this sends the
+// sanitized files before the analysis itself is confirmed, it asks for
+// consent of its own. A file that cannot be summarized stays truncated.
+func (c *AIClient) summarizeOversized(scan *ScanResult) error {
+	var indexes []int
+	var redactions []sanitize.Redaction
+	contents := map[int]string{}
+	for i, f := range scan.Files {
+		if !f.Truncated || f.full == "" {
+			continue
+		}
+		sanitized, found := c.redact(f.full)
+		for j := range found {
+			found[j].File = f.Path
+		}
+		redactions = append(redactions, found...)
+		// The summary request has to fit in the context window too.
+		contents[i] = truncateHeadTail(sanitized, c.contextWindow*2)
+		indexes = append(indexes, i)
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(c.messages(), "%d files exceed the size limit of %d tokens and will be sent to the AI to be summarized first.\n", len(indexes), c.maxFileTokens)
+	if err := c.blockSecrets(redactions); err != nil {
+		return err
+	}
+	if !c.consent(redactions) {
+		return fmt.Errorf("operation cancelled by user")
+	}
+	for _, i := range indexes {
+		f := &scan.Files[i]
+		prompt, err := c.renderPrompt("summarize", promptData{FilePath: scan.displayName(*f), Selection: contents[i]})
+		if err != nil {
+			return err
+		}
+		summary, err := c.getResponse(userTurn(prompt))
+		if err != nil {
+			fmt.Fprintf(c.messages(), "Warning: failed to summarize %s, so it is truncated instead: %v\n", f.Path, err)
+			continue
+		}
+		f.Content = fmt.Sprintf("(summary of a file that exceeds the size limit)\n%s", strings.TrimSpace(summary))
+		f.Summarized = true
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTruncateHeadTail(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, fmt.Sprintf("line %03d", i))
+	}
+	content := strings.Join(lines, "\n")
+
+	if got := truncateHeadTail(content, len(content)); got != content {
+		t.Errorf("Expected content within the limit to be kept, got %q", got)
+	}
+	got := truncateHeadTail(content, 170)
+	expected := "line 001\nline 002\nline 003\nline 004\nline 005\n... (90 lines left out: the file exceeds the size limit) ...\nline 096\nline 097\nline 098\nline 099\nline 100"
+	if got != expected {
+		t.Errorf("Expected the first and last lines, got %q", got)
+	}
+}
+
+func TestLimitSizes(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	large := "variable \"region\" {}\n" + strings.Repeat("resource \"aws_instance\" \"web\" {}\n", 100) + "output \"id\" {}\n"
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/a.tf": large,
+		"terraform/b.tf": "resource \"aws_vpc\" \"main\" {}\n",
+		"terraform/c.tf": strings.Repeat("locals {}\n", 50),
+	})
+
+	client := &AIClient{iacPath: tmpDir, maxFileTokens: 100, maxScanTokens: 150}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Files) != 2 {
+		t.Fatalf("Expected 2 files within the total limit, got %+v", result.Files)
+	}
+	a := result.Files[0]
+	if !a.Truncated || a.Size != int64(len(large)) || estimateTokens(a.Content) > 100 {
+		t.Errorf("Expected a.tf to be truncated to the limit, got %+v", a)
+	}
+	if !strings.HasPrefix(a.Content, "variable \"region\"") || !strings.HasSuffix(a.Content, "output \"id\" {}\n") {
+		t.Errorf("Expected the beginning and end of a.tf to be kept, got %q", a.Content)
+	}
+	if result.Files[1].Truncated {
+		t.Errorf("Expected b.tf to be kept as is, got %+v", result.Files[1])
+	}
+	skipped := false
+	for _, entry := range result.Skipped {
+		skipped = skipped || entry.Path == "terraform/c.tf" && strings.Contains(entry.Reason, "total size limit")
+	}
+	if !skipped {
+		t.Errorf("Expected c.tf to be skipped for the total limit, got %+v", result.Skipped)
+	}
+}
+
+func TestSummarizeOversizedFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": strings.Repeat("resource \"aws_instance\" \"web\" {}\n", 100),
+	})
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content json.RawMessage `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, string(body.Messages[len(body.Messages)-1].Content))
+		if len(requests) == 1 {
+			fmt.Fprint(w, `{"content":[{"type":"text","text":"100 aws_instance resources named web."}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Consolidate the instances."}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+		WithMaxFileTokens(100),
+		WithOversizedFiles(OversizedFilesSummarize),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	if _, err := client.RunAI(); err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected a summary request and the analysis, got %d requests", len(requests))
+	}
+	if !strings.Contains(requests[0], "Summarize it") || strings.Count(requests[0], "aws_instance") != 100 {
+		t.Errorf("Expected the whole file to be sent to be summarized, got %s", requests[0])
+	}
+	if !strings.Contains(requests[1], "100 aws_instance resources named web.") || strings.Contains(requests[1], "lines left out") {
+		t.Errorf("Expected the analysis to include the summary in place of the file, got %s", requests[1])
+	}
+}
@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+)
+
+// SectionLintFindings holds the findings of tflint and ansible-lint for
+// each Terraform and Ansible root.
+const SectionLintFindings = "lint findings"
+
+// ansibleLintSeverities maps the severities of ansible-lint's Code Climate
+// output to severities.
+var ansibleLintSeverities = map[string]string{
+	"blocker": SeverityCritical, "critical": SeverityHigh, "major": SeverityMedium, "minor": SeverityLow, "info": SeverityInfo,
+}
+
+// scanLint runs tflint in every Terraform root with Terraform code and
+// ansible-lint in every Ansible root with Ansible content, adding their
+// findings to result, one table per root. A linter that is not on the PATH
+// is skipped with a warning.
+func (c *AIClient) scanLint(ctx context.Context, result *ScanResult) {
+	linters := []struct {
+		name  string
+		roots []string
+		has   func(*ScanResult, string) bool
+		run   func(context.Context, *ScanResult, string) ([]scannerFinding, error)
+	}{
+		{"tflint", c.terraformRoots(), hasTerraformCode, runTflint},
+		{"ansible-lint", c.ansibleRoots(), hasAnsibleCode, runAnsibleLint},
+	}
+	for _, linter := range linters {
+		var roots []string
+		for _, root := range linter.roots {
+			if linter.has(result, root) {
+				roots = append(roots, root)
+			}
+		}
+		if len(roots) == 0 {
+			continue
+		}
+		if _, err := lookPath(linter.name); err != nil {
+			fmt.Fprintf(c.messages(), "Warning: %s is not run since it is not on the PATH\n", linter.name)
+			continue
+		}
+		for _, root := range roots {
+			findings, err := linter.run(ctx, result, root)
+			if err != nil {
+				fmt.Fprintf(c.messages(), "Warning: failed to run %s in %s: %v\n", linter.name, root, err)
+				continue
+			}
+			addOutput(result, SectionLintFindings, filepath.Join(root, linter.name), renderScannerFindings(findings))
+			result.Files[len(result.Files)-1].Language = "markdown"
+		}
+	}
+}
+
+// runTflint runs tflint in root. tflint exits with an error when it finds
+// issues, but still prints them.
+func runTflint(ctx context.Context, result *ScanResult, root string) ([]scannerFinding, error) {
+	out, runErr := runCommand(ctx, root, nil, "tflint", "--format", "json", "--no-color")
+	var report struct {
+		Issues []struct {
+			Rule struct {
+				Name     string `json:"name"`
+				Severity string `json:"severity"`
+			} `json:"rule"`
+			Message string `json:"message"`
+			Range   struct {
+				Filename string `json:"filename"`
+				Start    struct {
+					Line int `json:"line"`
+				} `json:"start"`
+			} `json:"range"`
+		} `json:"issues"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		if runErr != nil {
+			return nil, runErr
+		}
+		return nil, fmt.Errorf("failed to parse the output of tflint: %v", err)
+	}
+	if len(report.Errors) > 0 {
+		return nil, fmt.Errorf("%s", report.Errors[0].Message)
+	}
+	var findings []scannerFinding
+	for _, issue := range report.Issues {
+		severity := issue.Rule.Severity
+		switch severity {
+		case "error":
+			severity = SeverityHigh
+		case "warning":
+			severity = SeverityMedium
+		case "notice":
+			severity = SeverityLow
+		}
+		findings = append(findings, scannerFinding{
+			Tool:     "tflint",
+			Rule:     issue.Rule.Name,
+			Severity: severity,
+			File:     filepath.Join(result.rel(root), issue.Range.Filename),
+			Line:     issue.Range.Start.Line,
+			Message:  issue.Message,
+		})
+	}
+	return findings, nil
+}
+
+// runAnsibleLint runs ansible-lint in root with its Code Climate output.
+// ansible-lint exits with an error when it finds issues, but still prints
+// them.
+func runAnsibleLint(ctx context.Context, result *ScanResult, root string) ([]scannerFinding, error) {
+	out, runErr := runCommand(ctx, root, nil, "ansible-lint", "--format", "codeclimate", "--nocolor", "-q")
+	var issues []struct {
+		CheckName   string `json:"check_name"`
+		Severity    string `json:"severity"`
+		Description string `json:"description"`
+		Location    struct {
+			Path  string `json:"path"`
+			Lines struct {
+				Begin int `json:"begin"`
+			} `json:"lines"`
+		} `json:"location"`
+	}
+	if err := json.Unmarshal(out, &issues); err != nil {
+		if runErr != nil {
+			return nil, runErr
+		}
+		return nil, fmt.Errorf("failed to parse the output of ansible-lint: %v", err)
+	}
+	var findings []scannerFinding
+	for _, issue := range issues {
+		findings = append(findings, scannerFinding{
+			Tool:     "ansible-lint",
+			Rule:     issue.CheckName,
+			Severity: ansibleLintSeverities[issue.Severity],
+			File:     filepath.Join(result.rel(root), issue.Location.Path),
+			Line:     issue.Location.Lines.Begin,
+			Message:  issue.Description,
+		})
+	}
+	return findings, nil
+}
+
+// hasAnsibleCode reports whether result holds Ansible content in dir or
+// below it.
+func hasAnsibleCode(result *ScanResult, dir string) bool {
+	for _, f := range result.Section(SectionAnsible) {
+		if filepath.Ext(f.Path) != ".rego" && underProject(f.Path, result.rel(dir)) {
+			return true
+		}
+	}
+	return false
+}
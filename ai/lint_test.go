@@ -0,0 +1,69 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanLint(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "variable \"unused\" {}\n",
+		"ansible/site.yml":  "- hosts: all\n  tasks:\n    - shell: echo hi\n",
+	})
+	var dirs []string
+	originalRunCommand := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		dirs = append(dirs, name+" "+dir)
+		switch name {
+		case "tflint":
+			return []byte(`{"issues":[{"rule":{"name":"terraform_unused_declarations","severity":"warning"},"message":"variable \"unused\" is declared but not used","range":{"filename":"main.tf","start":{"line":1}}}],"errors":[]}`), fmt.Errorf("tflint failed: exit status 2")
+		case "ansible-lint":
+			return []byte(`[{"type":"issue","check_name":"name[missing]","severity":"minor","description":"All tasks should be named.","location":{"path":"site.yml","lines":{"begin":3}}}]`), fmt.Errorf("ansible-lint failed: exit status 2")
+		}
+		return nil, nil
+	}
+	defer func() { runCommand = originalRunCommand }()
+	originalLookPath := lookPath
+	lookPath = func(file string) (string, error) {
+		if file == "tflint" || file == "ansible-lint" {
+			return "/usr/bin/" + file, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	defer func() { lookPath = originalLookPath }()
+
+	client := &AIClient{iacPath: tmpDir, lint: true}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	findings := result.Section(SectionLintFindings)
+	if len(findings) != 2 {
+		t.Fatalf("Expected the findings of both linters, got %+v", findings)
+	}
+	if !strings.Contains(findings[0].Content, "| tflint | terraform_unused_declarations | medium | "+filepath.Join("terraform", "main.tf")+":1 |") {
+		t.Errorf("Expected the tflint finding, got %q", findings[0].Content)
+	}
+	if !strings.Contains(findings[1].Content, "| ansible-lint | name[missing] | low | "+filepath.Join("ansible", "site.yml")+":3 |") {
+		t.Errorf("Expected the ansible-lint finding, got %q", findings[1].Content)
+	}
+	expected := fmt.Sprintf("tflint %s,ansible-lint %s", filepath.Join(tmpDir, "terraform"), filepath.Join(tmpDir, "ansible"))
+	if got := strings.Join(dirs, ","); got != expected {
+		t.Errorf("Expected the linters to run in their roots, %q, got %q", expected, got)
+	}
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.LintFindings, "All tasks should be named.") {
+		t.Errorf("Expected the findings in the prompt, got %q", data.LintFindings)
+	}
+}
@@ -0,0 +1,283 @@
+package ai
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+// RunManifest records exactly what went into a run: the sanitized input,
+// the prompt template, the non-secret settings, and the model that produced
+// the output. A signed manifest gives recommendations attached to a change
+// approval verifiable provenance.
+type RunManifest struct {
+	Tool                string    `json:"tool"`
+	ToolVersion         string    `json:"tool_version"`
+	CreatedAt           time.Time `json:"created_at"`
+	Provider            string    `json:"provider"`
+	Model               string    `json:"model"`
+	AnalysisType        string    `json:"analysis_type"`
+	ComplianceFramework string    `json:"compliance_framework,omitempty"`
+	PromptTemplate      string    `json:"prompt_template"`
+	PromptSource        string    `json:"prompt_source"`
+	PromptSHA256        string    `json:"prompt_sha256"`
+	Config              RunConfig `json:"config"`
+	InputSHA256         string    `json:"input_sha256"`
+	OutputSHA256        string    `json:"output_sha256"`
+}
+
+// RunConfig is the settings of a run that shape the prompt and the
+// response, besides the template and model. SanitizeLevel is the level
+// applied to the destination of the run, and ExamplesSHA256 covers the
+// sanitized examples sent before the input.
+type RunConfig struct {
+	SanitizeLevel    string            `json:"sanitize_level"`
+	SanitizeProfiles map[string]string `json:"sanitize_profiles,omitempty"`
+	Language         string            `json:"language,omitempty"`
+	Tone             string            `json:"tone,omitempty"`
+	Examples         int               `json:"examples"`
+	ExamplesSHA256   string            `json:"examples_sha256,omitempty"`
+	MaxTokens        int               `json:"max_tokens,omitempty"`
+	Refine           bool              `json:"refine"`
+	Compress         bool              `json:"compress"`
+	Structured       bool              `json:"structured"`
+}
+
+// ManifestSigner signs the run manifest written at a path, storing the
+// signature next to it, and returns the signature's path.
+type ManifestSigner interface {
+	Sign(ctx context.Context, manifestPath string) (string, error)
+}
+
+// Ed25519Signer signs manifests with a local ed25519 key, writing a base64
+// signature to <manifest>.sig.
+type Ed25519Signer struct {
+	PrivateKey ed25519.PrivateKey
+}
+
+// NewEd25519Signer loads a PEM-encoded PKCS #8 ed25519 private key, such as
+// one created by 'openssl genpkey -algorithm ed25519'.
+func NewEd25519Signer(keyPath string) (*Ed25519Signer, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM-encoded", keyPath)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %v", err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s is not an ed25519 key", keyPath)
+	}
+	return &Ed25519Signer{PrivateKey: privateKey}, nil
+}
+
+// Sign implements ManifestSigner.
+func (s *Ed25519Signer) Sign(ctx context.Context, manifestPath string) (string, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return "", err
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(s.PrivateKey, data))
+	sigPath := manifestPath + ".sig"
+	if err := os.WriteFile(sigPath, []byte(signature+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return sigPath, nil
+}
+
+// CosignSigner signs manifests with cosign sign-blob, writing a Sigstore
+// bundle to <manifest>.bundle. Without a Key, cosign signs keyless using the
+// ambient OIDC identity, such as a CI workload identity.
+type CosignSigner struct {
+	Key string
+}
+
+// Sign implements ManifestSigner.
+func (s *CosignSigner) Sign(ctx context.Context, manifestPath string) (string, error) {
+	bundlePath := manifestPath + ".bundle"
+	args := []string{"sign-blob", "--yes", "--bundle", bundlePath}
+	if s.Key != "" {
+		args = append(args, "--key", s.Key)
+	}
+	args = append(args, manifestPath)
+	if output, err := runCommand(ctx, filepath.Dir(manifestPath), nil, "cosign", args...); err != nil {
+		return "", fmt.Errorf("cosign sign-blob failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return bundlePath, nil
+}
+
+// manifestSignerFromConfig creates the signer described by
+// AI_MANIFEST_SIGNER: ed25519:/path/to/key.pem, cosign, or cosign:KEY.
+func manifestSignerFromConfig(value string) (ManifestSigner, error) {
+	scheme := value
+	ref := ""
+	if i := strings.Index(value, ":"); i >= 0 {
+		scheme, ref = value[:i], value[i+1:]
+	}
+	switch scheme {
+	case "ed25519":
+		if ref == "" {
+			return nil, fmt.Errorf("AI_MANIFEST_SIGNER ed25519 requires a key path, as in ed25519:/path/to/key.pem")
+		}
+		return NewEd25519Signer(ref)
+	case "cosign":
+		return &CosignSigner{Key: ref}, nil
+	}
+	return nil, fmt.Errorf("unknown AI_MANIFEST_SIGNER %q; expected ed25519:KEY_PATH, cosign, or cosign:KEY", value)
+}
+
+// VerifyManifest checks the ed25519 signature in <manifest>.sig against the
+// PEM-encoded public key at publicKeyPath and returns the verified manifest.
+func VerifyManifest(manifestPath string, publicKeyPath string) (*RunManifest, error) {
+	data, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("public key %s is not PEM-encoded", publicKeyPath)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %v", err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not an ed25519 key", publicKeyPath)
+	}
+
+	manifestData, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	sigData, err := os.ReadFile(manifestPath + ".sig")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest signature: %v", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigData)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode manifest signature: %v", err)
+	}
+	if !ed25519.Verify(publicKey, manifestData, signature) {
+		return nil, fmt.Errorf("manifest signature does not match %s", manifestPath)
+	}
+
+	manifest := &RunManifest{}
+	if err := json.Unmarshal(manifestData, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+// writeRunManifest writes and signs the manifest of a run to
+// kado-manifest.json in the IaC path.
+func (c *AIClient) writeRunManifest(template string, input string, output string) error {
+	text, source, err := c.promptTemplate(template)
+	if err != nil {
+		return err
+	}
+	examples, err := c.fewShot(template)
+	if err != nil {
+		return err
+	}
+	config := RunConfig{Examples: len(examples) / 2}
+	if len(examples) > 0 {
+		data, err := json.Marshal(examples)
+		if err != nil {
+			return err
+		}
+		config.ExamplesSHA256 = sha256Hex(string(data))
+	}
+
+	c.mu.RLock()
+	config.SanitizeLevel = string(c.effectiveSanitizeLevel())
+	if config.SanitizeLevel == "" {
+		config.SanitizeLevel = string(sanitize.LevelStandard)
+	}
+	for destination, level := range c.sanitizeProfiles {
+		if config.SanitizeProfiles == nil {
+			config.SanitizeProfiles = map[string]string{}
+		}
+		config.SanitizeProfiles[destination] = string(level)
+	}
+	config.Language = c.language
+	config.Tone = string(c.tone)
+	config.MaxTokens = c.maxTokens
+	config.Refine = c.refine
+	config.Compress = c.compress
+	config.Structured = c.structured
+	manifest := RunManifest{
+		Tool:                "kado-ai",
+		ToolVersion:         toolVersion(),
+		CreatedAt:           time.Now().UTC(),
+		Provider:            c.clientType,
+		Model:               c.model,
+		AnalysisType:        string(c.analysisType),
+		ComplianceFramework: c.complianceFramework,
+		PromptTemplate:      template,
+		PromptSource:        source,
+		PromptSHA256:        sha256Hex(text),
+		Config:              config,
+		InputSHA256:         sha256Hex(input),
+		OutputSHA256:        sha256Hex(output),
+	}
+	c.mu.RUnlock()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	manifestPath := filepath.Join(c.iacPath, "kado-manifest.json")
+	if err := os.WriteFile(manifestPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write run manifest: %v", err)
+	}
+	sigPath, err := c.manifestSigner.Sign(context.Background(), manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to sign run manifest: %v", err)
+	}
+	fmt.Fprintf(c.messages(), "Run manifest has been saved to %s and signed in %s\n", manifestPath, sigPath)
+	return nil
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// toolVersion returns the version of the kado-ai module in the running
+// binary, or "(devel)" when it is not known.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	const modulePath = "github.com/janpreet/kado-ai"
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
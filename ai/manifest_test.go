@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+func writeEd25519Keys(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	privatePath := filepath.Join(dir, "kado.key")
+	publicPath := filepath.Join(dir, "kado.pub")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		t.Fatalf("Failed to write private key: %v", err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		t.Fatalf("Failed to write public key: %v", err)
+	}
+	return privatePath, publicPath
+}
+
+func TestSignedRunManifest(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_vpc\" \"main\" {}\n",
+	})
+	privatePath, publicPath := writeEd25519Keys(t, tmpDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Enable flow logs."}]}`)
+	}))
+	defer server.Close()
+
+	signer, err := manifestSignerFromConfig("ed25519:" + privatePath)
+	if err != nil {
+		t.Fatalf("manifestSignerFromConfig failed: %v", err)
+	}
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithAnalysisType(AnalysisSecurity),
+		WithSanitizeLevel(sanitize.LevelParanoid),
+		WithLanguage("German"),
+		WithMaxTokens(2048),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithManifestSigner(signer),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+	if _, err := client.RunAI(); err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+
+	manifestPath := filepath.Join(tmpDir, "kado-manifest.json")
+	manifest, err := VerifyManifest(manifestPath, publicPath)
+	if err != nil {
+		t.Fatalf("VerifyManifest failed: %v", err)
+	}
+	input, err := os.ReadFile(filepath.Join(tmpDir, "ai_input.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read AI input: %v", err)
+	}
+	if manifest.InputSHA256 != sha256Hex(string(input)) {
+		t.Errorf("Expected the manifest to attest to the saved AI input")
+	}
+	if manifest.OutputSHA256 != sha256Hex("Enable flow logs.") {
+		t.Errorf("Expected the manifest to attest to the recommendations")
+	}
+	if manifest.Model != "claude-3-haiku-20240307" || manifest.AnalysisType != "security" || manifest.PromptTemplate != "analyze" || manifest.PromptSource != "built-in" {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+	if config := manifest.Config; config.SanitizeLevel != "paranoid" || config.Language != "German" || config.MaxTokens != 2048 || config.Examples != 0 || config.Refine {
+		t.Errorf("Expected the manifest to record the run's settings, got %+v", config)
+	}
+
+	data, _ := os.ReadFile(manifestPath)
+	tampered := strings.Replace(string(data), "claude-3-haiku-20240307", "claude-3-opus-20240229", 1)
+	if err := os.WriteFile(manifestPath, []byte(tampered), 0644); err != nil {
+		t.Fatalf("Failed to tamper with manifest: %v", err)
+	}
+	if _, err := VerifyManifest(manifestPath, publicPath); err == nil {
+		t.Errorf("Expected a tampered manifest to fail verification")
+	}
+}
+
+func TestCosignSigner(t *testing.T) {
+	calls := fakeRunCommand(t, "")
+	signer, err := manifestSignerFromConfig("cosign")
+	if err != nil {
+		t.Fatalf("manifestSignerFromConfig failed: %v", err)
+	}
+	bundlePath, err := signer.Sign(context.Background(), "/repo/kado-manifest.json")
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if bundlePath != "/repo/kado-manifest.json.bundle" {
+		t.Errorf("Expected the bundle next to the manifest, got %s", bundlePath)
+	}
+	if got := strings.Join((*calls)[0], " "); got != "cosign sign-blob --yes --bundle /repo/kado-manifest.json.bundle /repo/kado-manifest.json" {
+		t.Errorf("Unexpected cosign invocation: %s", got)
+	}
+
+	if _, err := manifestSignerFromConfig("gpg:ABCDEF"); err == nil {
+		t.Errorf("Expected an error for an unknown signer")
+	}
+}
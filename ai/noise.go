@@ -0,0 +1,195 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultNoisePhrases are generic advice phrases that apply to almost any
+// infrastructure and, unless tied to a specific resource, crowd out
+// repository-specific findings.
+var DefaultNoisePhrases = []string{
+	"enable mfa",
+	"multi-factor authentication",
+	"least privilege",
+	"regularly review",
+	"regularly audit",
+	"regular audits",
+	"keep software up to date",
+	"keep dependencies up to date",
+	"rotate credentials",
+	"rotate your keys",
+	"enable logging and monitoring",
+	"use version control",
+	"follow best practices",
+	"infrastructure as code best practices",
+	"document your infrastructure",
+	"implement a backup strategy",
+}
+
+// generalHygieneHeading heads the section that collects generic advice.
+const generalHygieneHeading = "## General hygiene"
+
+var (
+	// specificReference matches what ties advice to the code: a resource
+	// address such as aws_s3_bucket.logs, a code span, or a file name.
+	specificReference = regexp.MustCompile("\\b[a-z][a-z0-9]*_[a-z0-9_]+\\.[A-Za-z0-9_-]+\\b|`[^`]+`|\\S+\\.(tf|ya?ml|rego|json|ts|py)\\b")
+	listItem          = regexp.MustCompile(`^ {0,3}([-*+]|\d+[.)])\s+`)
+	heading           = regexp.MustCompile(`^(#{1,6})\s`)
+)
+
+// noiseFilter recognizes generic advice by its phrases.
+type noiseFilter struct {
+	phrases []string
+}
+
+func newNoiseFilter(phrases []string) *noiseFilter {
+	f := &noiseFilter{}
+	for _, phrase := range phrases {
+		if phrase = strings.ToLower(strings.TrimSpace(phrase)); phrase != "" {
+			f.phrases = append(f.phrases, phrase)
+		}
+	}
+	return f
+}
+
+// generic reports whether text contains a noise phrase.
+func (f *noiseFilter) generic(text string) bool {
+	lower := strings.ToLower(text)
+	for _, phrase := range f.phrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// noise reports whether text is generic advice that is not tied to a
+// specific resource.
+func (f *noiseFilter) noise(text string) bool {
+	return f.generic(text) && !specificReference.MatchString(text)
+}
+
+// filterReport moves the paragraphs and list items of a Markdown report that
+// are generic advice into a single general hygiene section at its end, and
+// drops the headings of sections left empty.
+func (f *noiseFilter) filterReport(report string) string {
+	blocks := markdownBlocks(report)
+	var kept []string
+	var hygiene []string
+	for _, block := range blocks {
+		if !heading.MatchString(block) && f.noise(block) {
+			item := listItem.ReplaceAllString(strings.TrimSpace(block), "")
+			hygiene = append(hygiene, "- "+strings.Join(strings.Fields(item), " "))
+			continue
+		}
+		kept = append(kept, block)
+	}
+	if len(hygiene) == 0 {
+		return report
+	}
+
+	var out []string
+	for i, block := range kept {
+		if m := heading.FindStringSubmatch(block); m != nil && sectionEmpty(kept[i+1:], len(m[1])) {
+			continue
+		}
+		out = append(out, block)
+	}
+	out = append(out, generalHygieneHeading, strings.Join(hygiene, "\n"))
+	return strings.Join(out, "\n\n") + "\n"
+}
+
+// sectionEmpty reports whether the section whose heading of the given level
+// is followed by blocks has no content of its own.
+func sectionEmpty(blocks []string, level int) bool {
+	if len(blocks) == 0 {
+		return true
+	}
+	m := heading.FindStringSubmatch(blocks[0])
+	return m != nil && len(m[1]) <= level
+}
+
+// markdownBlocks splits a Markdown document into headings, top-level list
+// items, and paragraphs, each with its continuation lines.
+func markdownBlocks(text string) []string {
+	var blocks []string
+	var current []string
+	flush := func() {
+		if block := strings.TrimRight(strings.Join(current, "\n"), "\n "); strings.TrimSpace(block) != "" {
+			blocks = append(blocks, block)
+		}
+		current = nil
+	}
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.TrimSpace(line) == "":
+			blank = true
+			if len(current) > 0 {
+				current = append(current, line)
+			}
+			continue
+		case heading.MatchString(line):
+			flush()
+			blocks = append(blocks, strings.TrimSpace(line))
+		case listItem.MatchString(line), blank && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t"):
+			flush()
+			current = append(current, line)
+		default:
+			current = append(current, line)
+		}
+		blank = false
+	}
+	flush()
+	return blocks
+}
+
+// deprioritizeFindings lowers generic findings that do not point at code to
+// SeverityInfo and moves them after the others.
+func (f *noiseFilter) deprioritizeFindings(findings []Finding) []Finding {
+	noisy := make([]bool, len(findings))
+	for i := range findings {
+		finding := &findings[i]
+		if (finding.File == "" || finding.StartLine < 1) && f.noise(finding.Title+"\n"+finding.Message) {
+			finding.Severity = SeverityInfo
+			noisy[i] = true
+		}
+	}
+	order := make([]int, len(findings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return !noisy[order[a]] && noisy[order[b]]
+	})
+	sorted := make([]Finding, len(findings))
+	for i, j := range order {
+		sorted[i] = findings[j]
+	}
+	return sorted
+}
+
+// report is recommend for analysis reports. With a noise filter, the whole
+// report is needed before generic advice can be collapsed, so the sinks are
+// only updated once it is complete.
+func (c *AIClient) report(messages []Message) (string, error) {
+	if c.noise == nil {
+		return c.respond(messages)
+	}
+	var text string
+	var err error
+	if c.streaming {
+		text, err = c.streamRecommendations(messages, func(string) {})
+	} else {
+		text, err = c.getResponse(messages)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get recommendations: %v", err)
+	}
+	text = c.noise.filterReport(text)
+	updateSinks(c.messages(), c.outputSinks(), text, true)
+	return text, nil
+}
@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"testing"
+)
+
+func TestFilterReport(t *testing.T) {
+	filter := newNoiseFilter(append(DefaultNoisePhrases, "tag everything"))
+	report := `# Recommendations
+
+## Security
+
+1. Restrict SSH ingress on aws_security_group.web to the bastion CIDR.
+2. Enable MFA for all IAM users.
+3. Apply least privilege to ` + "`aws_iam_role.deployer`" + `, which has AdministratorAccess.
+
+## Operations
+
+- Follow best practices for infrastructure as code.
+- Tag everything consistently.
+
+Regularly review your infrastructure
+for drift.
+`
+	expected := `# Recommendations
+
+## Security
+
+1. Restrict SSH ingress on aws_security_group.web to the bastion CIDR.
+
+3. Apply least privilege to ` + "`aws_iam_role.deployer`" + `, which has AdministratorAccess.
+
+## General hygiene
+
+- Enable MFA for all IAM users.
+- Follow best practices for infrastructure as code.
+- Tag everything consistently.
+- Regularly review your infrastructure for drift.
+`
+	if filtered := filter.filterReport(report); filtered != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, filtered)
+	}
+
+	specific := "1. Restrict SSH ingress on aws_security_group.web.\n"
+	if filtered := filter.filterReport(specific); filtered != specific {
+		t.Errorf("Expected a report without generic advice to be unchanged, got %q", filtered)
+	}
+}
+
+func TestDeprioritizeFindings(t *testing.T) {
+	filter := newNoiseFilter(DefaultNoisePhrases)
+	findings := filter.deprioritizeFindings([]Finding{
+		{ID: "KADO-101", Severity: SeverityHigh, Title: "Enable MFA", Message: "Enable MFA for all users."},
+		{ID: "KADO-102", File: "iam.tf", StartLine: 3, Severity: SeverityHigh, Title: "Least privilege", Message: "The role grants *:*; apply least privilege."},
+		{ID: "KADO-101", File: "main.tf", StartLine: 1, Severity: SeverityMedium, Title: "Open SSH"},
+	})
+	if findings[0].ID != "KADO-102" || findings[1].Title != "Open SSH" {
+		t.Errorf("Expected generic findings to be moved last, got %+v", findings)
+	}
+	if findings[2].Title != "Enable MFA" || findings[2].Severity != SeverityInfo {
+		t.Errorf("Expected the generic finding to be lowered to info, got %+v", findings[2])
+	}
+	if findings[0].Severity != SeverityHigh {
+		t.Errorf("Expected findings tied to code to keep their severity, got %+v", findings[0])
+	}
+}
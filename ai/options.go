@@ -0,0 +1,946 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/janpreet/kado-ai/sanitize"
+	"net/url"
+)
+
+// Option configures an AIClient created with NewAIClientWithOptions.
+type Option func(*AIClient)
+
+// WithAPIKey sets the API key used to authenticate with the AI provider. The
+// key may also be a reference to where the key is stored: env:NAME,
+// file:PATH, gcp-sm:PROJECT/NAME[/VERSION], or azure-kv:VAULT/NAME[/VERSION].
+// Several comma-separated keys (or references) can be given to spread
+// requests across keys; see WithKeyRotation.
+func WithAPIKey(apiKey string) Option {
+	return func(c *AIClient) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithKeyRotation sets how requests are spread across several API keys:
+// KeyRotationRoundRobin (the default) or KeyRotationOn429. Either way, a
+// rate-limited request is retried with the next key.
+func WithKeyRotation(strategy string) Option {
+	return func(c *AIClient) {
+		c.keyRotation = strategy
+	}
+}
+
+// WithModel sets the model requested from the AI provider.
+func WithModel(model string) Option {
+	return func(c *AIClient) {
+		c.model = model
+	}
+}
+
+// WithProvider sets the AI provider ("chatgpt" or "anthropic_messages").
+func WithProvider(provider string) Option {
+	return func(c *AIClient) {
+		c.clientType = provider
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for provider requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *AIClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithIaCPath sets the root directory containing the terraform and ansible
+// code. It can also be the URL of a remote Git repository, such as
+// https://github.com/org/infra.git//aws?ref=v1.2.0, which is cloned without
+// its history when the client is created; call Close to remove the clone.
+func WithIaCPath(iacPath string) Option {
+	return func(c *AIClient) {
+		c.iacPath = iacPath
+	}
+}
+
+// WithIaCPaths scans several IaC paths, such as infra/aws, infra/gcp, and
+// platform/k8s, in one run instead of the IaC path itself. They are
+// relative to the IaC path unless absolute, and must be under it. Each is
+// scanned like an IaC path of its own, with discovery enabled, and its
+// files are labeled with it in the prompt.
+func WithIaCPaths(paths ...string) Option {
+	return func(c *AIClient) {
+		c.iacPaths = paths
+	}
+}
+
+// WithPerPathReports analyzes each of the IaC paths set with WithIaCPaths
+// separately and returns a report with a section for each, instead of a
+// single report on all of them.
+func WithPerPathReports(perPath bool) Option {
+	return func(c *AIClient) {
+		c.perPathReports = perPath
+	}
+}
+
+// WithProjectReports finds the independent projects of a monorepo, its
+// Terraform root modules and Ansible projects, anywhere under the IaC path,
+// and analyzes each separately, returning a report with a section for each
+// after a roll-up of the projects. Scans list the projects found in
+// ScanResult.Projects.
+func WithProjectReports(projects bool) Option {
+	return func(c *AIClient) {
+		c.projects = projects
+	}
+}
+
+// WithDiff limits the analysis to the files changed in gitRange, such as
+// main...HEAD for the changes of a branch, or a single ref for the changes
+// since it including uncommitted ones, as git diff takes it. The other
+// files of their modules are included too, unless WithDiffContext says
+// otherwise, and the diff is included for the recommendations to focus on.
+func WithDiff(gitRange string) Option {
+	return func(c *AIClient) {
+		c.diffRange = gitRange
+	}
+}
+
+// WithDiffContext sets how much code around the changed files a
+// diff-scoped analysis includes: DiffContextModule, the default, the other
+// files of their directories, and DiffContextFiles none.
+func WithDiffContext(diffContext DiffContext) Option {
+	return func(c *AIClient) {
+		c.diffContext = diffContext
+	}
+}
+
+// WithPrompt replaces the instruction that precedes the IaC content in the prompt.
+func WithPrompt(prompt string) Option {
+	return func(c *AIClient) {
+		c.prompt = prompt
+	}
+}
+
+// WithAnalysisType selects the persona of the analysis: a security review,
+// FinOps cost optimization, SRE reliability review, or compliance mapping.
+// The default is AnalysisComprehensive. WithPrompt still overrides the
+// persona's instruction.
+func WithAnalysisType(t AnalysisType) Option {
+	return func(c *AIClient) {
+		c.analysisType = t
+	}
+}
+
+// WithComplianceFramework targets a compliance analysis at one framework:
+// FrameworkCIS, FrameworkSOC2, FrameworkHIPAA, FrameworkPCIDSS, or
+// FrameworkNIST80053. Findings are mapped to the framework's controls and
+// summarized in a control-by-control gap table. It implies
+// AnalysisCompliance.
+func WithComplianceFramework(framework string) Option {
+	return func(c *AIClient) {
+		c.complianceFramework = framework
+	}
+}
+
+// WithManifestSigner writes a manifest of every run to kado-manifest.json in
+// the IaC path and signs it with signer, such as an Ed25519Signer or a
+// CosignSigner.
+func WithManifestSigner(signer ManifestSigner) Option {
+	return func(c *AIClient) {
+		c.manifestSigner = signer
+	}
+}
+
+// WithQuestion focuses RunAI and RunFindings on answering a free-form
+// question about the infrastructure. RunAIWithQuestion asks a question for
+// a single run instead.
+func WithQuestion(question string) Option {
+	return func(c *AIClient) {
+		c.question = question
+	}
+}
+
+// WithExtraPatterns redacts every match of the given regular expressions
+// as a secret, in addition to the built-in sanitization rules.
+func WithExtraPatterns(patterns ...string) Option {
+	return func(c *AIClient) {
+		c.extraPatterns = append(c.extraPatterns, patterns...)
+	}
+}
+
+// WithPromptContext adds free-form context about the user's stack, such as
+// "we run everything on EKS with Karpenter", to the prompt.
+func WithPromptContext(context string) Option {
+	return func(c *AIClient) {
+		c.promptContext = context
+	}
+}
+
+// WithContextWindow sets the prompt size, in estimated tokens, above which
+// an analysis is split by directory into chunks that are analyzed in
+// parallel and then merged. The default suits current OpenAI and Anthropic
+// models.
+func WithContextWindow(tokens int) Option {
+	return func(c *AIClient) {
+		c.contextWindow = tokens
+	}
+}
+
+// WithMaxTokens sets the maximum number of tokens of each response. Anthropic
+// requires a limit and defaults to 1024; OpenAI uses the model's own limit
+// when none is set. Responses cut off at the limit are continued
+// automatically, so the limit only bounds the length of each request.
+func WithMaxTokens(tokens int) Option {
+	return func(c *AIClient) {
+		c.maxTokens = tokens
+	}
+}
+
+// WithMaxFileTokens sets the size, in estimated tokens, above which a file
+// is truncated to its beginning and end, or summarized with
+// OversizedFilesSummarize. Zero disables the limit.
+func WithMaxFileTokens(tokens int) Option {
+	return func(c *AIClient) {
+		c.maxFileTokens = tokens
+	}
+}
+
+// WithMaxScanTokens sets the combined size, in estimated tokens, of the files
+// a scan collects. Files beyond it are left out and listed as skipped, so a
+// huge repository cannot run up the cost of an analysis. Zero, the default,
+// disables the limit.
+func WithMaxScanTokens(tokens int) Option {
+	return func(c *AIClient) {
+		c.maxScanTokens = tokens
+	}
+}
+
+// WithOversizedFiles sets what an analysis does with files above the
+// per-file size limit: OversizedFilesTruncate, the default, keeps their
+// beginning and end, and OversizedFilesSummarize has the provider summarize
+// them first.
+func WithOversizedFiles(oversized OversizedFiles) Option {
+	return func(c *AIClient) {
+		c.oversizedFiles = oversized
+	}
+}
+
+// WithPlanFormat sets how Terraform plans are presented: PlanFormatFull,
+// the default, as they are, and PlanFormatSummary as a table of the
+// resources they change, which is far smaller.
+func WithPlanFormat(format PlanFormat) Option {
+	return func(c *AIClient) {
+		c.planFormat = format
+	}
+}
+
+// WithAnonymizePaths replaces the directory and file names of the IaC code
+// in the prompt with stable pseudonyms, for organizations whose directory
+// names reveal sensitive project information. The mapping back is kept in
+// .kado/paths.json, and real names are restored in the response.
+func WithAnonymizePaths(anonymize bool) Option {
+	return func(c *AIClient) {
+		c.anonymizePaths = anonymize
+	}
+}
+
+// WithReversibleRedaction replaces every redacted value with a numbered
+// placeholder, such as [REDACTED_IP_1], that is the same for every
+// occurrence of the value, instead of [REDACTED]. The values of placeholders
+// other than credentials are kept in .kado/placeholders.json and restored in
+// the response.
+func WithReversibleRedaction(reversible bool) Option {
+	return func(c *AIClient) {
+		c.reversible = reversible
+	}
+}
+
+// WithPlaceholderFormat sets the form of the text replacing redacted
+// values, such as <{kind}:{rule}> for <SECRET:aws-access-key>, instead of
+// [REDACTED], or {{REDACTED_{n}}} for numbered placeholders with
+// WithReversibleRedaction. See sanitize.PlaceholderFormat for the tokens.
+func WithPlaceholderFormat(format sanitize.PlaceholderFormat) Option {
+	return func(c *AIClient) {
+		c.placeholderFormat = format
+	}
+}
+
+// WithCompression compresses the code before it is placed in the prompt:
+// comments are stripped, whitespace is collapsed, the plan and other JSON is
+// minified, and Terraform blocks repeated across modules are sent once.
+// Findings and fixes, which depend on exact lines, are never compressed.
+func WithCompression(compress bool) Option {
+	return func(c *AIClient) {
+		c.compress = compress
+	}
+}
+
+// WithStructuredTerraform presents each Terraform file block by block under
+// the address of the block, such as aws_s3_bucket.logs or var.region, with
+// the line it starts on and its attributes one per line, so recommendations
+// can name resources precisely. Comments are kept above their blocks unless
+// compression strips them. Findings and fixes see the files as written.
+func WithStructuredTerraform(structured bool) Option {
+	return func(c *AIClient) {
+		c.structured = structured
+	}
+}
+
+// WithRefinement adds a second pass to every analysis: the first response
+// is sent back with instructions to verify each recommendation against the
+// code and drop unsupported claims, which reduces hallucinated resource names
+// and attributes. Only the refined response reaches the output sinks.
+func WithRefinement(refine bool) Option {
+	return func(c *AIClient) {
+		c.refine = refine
+	}
+}
+
+// WithNoiseFilter collapses generic advice, such as "enable MFA", that is
+// not tied to a specific resource into a single "General hygiene" section at
+// the end of the report, and lowers such findings to SeverityInfo. Phrases
+// are matched case-insensitively, in addition to DefaultNoisePhrases.
+func WithNoiseFilter(phrases ...string) Option {
+	return func(c *AIClient) {
+		c.noisePhrases = append(append(c.noisePhrases, DefaultNoisePhrases...), phrases...)
+	}
+}
+
+// WithExamples sets the few-shot examples sent ahead of the prompt. It
+// replaces .kado/examples.json.
+func WithExamples(examples ...Example) Option {
+	return func(c *AIClient) {
+		c.examples = examples
+	}
+}
+
+// WithStandards includes a team standards document in the prompt, so the
+// code is judged against the team's conventions rather than generic best
+// practices. It replaces .kado/standards.md.
+func WithStandards(standards string) Option {
+	return func(c *AIClient) {
+		c.standards = standards
+	}
+}
+
+// WithLanguage writes responses in language, such as "Japanese" or
+// "German", for teams whose reports go to non-English stakeholders.
+func WithLanguage(language string) Option {
+	return func(c *AIClient) {
+		c.language = language
+	}
+}
+
+// WithTone writes responses for an audience: ToneEngineer for
+// engineer-level detail or ToneExecutive for a plain-language summary.
+func WithTone(tone Tone) Option {
+	return func(c *AIClient) {
+		c.tone = tone
+	}
+}
+
+// WithAllowWrites permits terraform commands that can modify state or
+// infrastructure. Without it, kado-ai only runs read-only terraform commands.
+func WithAllowWrites(allow bool) Option {
+	return func(c *AIClient) {
+		c.allowWrites = allow
+	}
+}
+
+// WithTerraformBinary sets the terraform executable used when kado-ai shells
+// out to terraform.
+func WithTerraformBinary(path string) Option {
+	return func(c *AIClient) {
+		c.terraformBinary = path
+	}
+}
+
+// WithTerraformWorkspace pins the terraform workspace used when kado-ai shells
+// out to terraform. The selection is passed through TF_WORKSPACE rather than
+// by running "terraform workspace select".
+func WithTerraformWorkspace(workspace string) Option {
+	return func(c *AIClient) {
+		c.terraformWorkspace = workspace
+	}
+}
+
+// WithTerraformPlan makes kado-ai produce the plan it analyzes itself rather
+// than look for a saved one: in every Terraform root it runs "terraform init
+// -backend=false" and "terraform validate", and when the configuration is
+// valid, "terraform plan -out" and "terraform show -json". Validation errors
+// and warnings are analyzed too.
+func WithTerraformPlan(plan bool) Option {
+	return func(c *AIClient) {
+		c.terraformPlan = plan
+	}
+}
+
+// WithTerraformVarFiles passes variable files to terraform plan when
+// WithTerraformPlan is set, relative to the IaC path unless absolute.
+func WithTerraformVarFiles(paths ...string) Option {
+	return func(c *AIClient) {
+		c.terraformVarFiles = paths
+	}
+}
+
+// WithPolicyEvaluation makes kado-ai evaluate the Rego policies under the
+// IaC path against every Terraform plan with conftest before analysis, so
+// the failures and warnings they report are analyzed rather than guessed
+// from the policy text.
+func WithPolicyEvaluation(evaluate bool) Option {
+	return func(c *AIClient) {
+		c.policyEvaluation = evaluate
+	}
+}
+
+// WithScannerResults includes the findings of static analysis scanners in
+// the prompt, read from their results files, relative to the IaC path
+// unless absolute: SARIF from any scanner, or the JSON output of tfsec,
+// Trivy, or Checkov. The provider is asked to prioritize, deduplicate,
+// explain, and fix these findings rather than look for them again.
+func WithScannerResults(paths ...string) Option {
+	return func(c *AIClient) {
+		c.scannerResults = paths
+	}
+}
+
+// WithInfracost makes kado-ai run "infracost breakdown" for every Terraform
+// root before analysis, on the root's plan when there is one so the
+// estimate includes the change in monthly cost, and on its code otherwise.
+// Without it, an infracost.json saved in the root is used.
+func WithInfracost(run bool) Option {
+	return func(c *AIClient) {
+		c.infracost = run
+	}
+}
+
+// WithLinting makes kado-ai run tflint in every Terraform root and
+// ansible-lint in every Ansible root before analysis, and ask the provider
+// to explain and remediate what they find.
+func WithLinting(lint bool) Option {
+	return func(c *AIClient) {
+		c.lint = lint
+	}
+}
+
+// WithDriftReport includes a drift report in the prompt, relative to the IaC
+// path unless absolute: a refresh-only plan in JSON format, as written by
+// terraform show -json, or the JSON output of driftctl scan. It is most
+// useful with the AnalysisDrift analysis type.
+func WithDriftReport(path string) Option {
+	return func(c *AIClient) {
+		c.driftReport = path
+	}
+}
+
+// WithVersionAudit includes an upgrade matrix of the providers and modules
+// the Terraform code requires in the prompt: their version constraints,
+// the provider versions locked in .terraform.lock.hcl, and their latest
+// versions in the Terraform Registry, which are looked up over the network.
+// The provider is asked to propose an upgrade plan with the breaking changes
+// of each upgrade.
+func WithVersionAudit(audit bool) Option {
+	return func(c *AIClient) {
+		c.versionAudit = audit
+	}
+}
+
+// WithKubernetesCluster includes a read-only snapshot of the live
+// Kubernetes cluster in the prompt, read with kubectl get: its workloads,
+// services, network policies, and RBAC, with only their names, namespaces,
+// and specs, so recommendations about the manifests account for what is
+// actually running.
+func WithKubernetesCluster(include bool) Option {
+	return func(c *AIClient) {
+		c.kubernetesCluster = include
+	}
+}
+
+// WithKubernetesContext selects the kubeconfig context of the cluster read
+// by WithKubernetesCluster, instead of the current context.
+func WithKubernetesContext(kubeContext string) Option {
+	return func(c *AIClient) {
+		c.kubernetesContext = kubeContext
+	}
+}
+
+// WithAWSAccountHints includes the account-level settings of the AWS account
+// of the AWS CLI's credentials in the prompt, read with read-only aws calls:
+// whether GuardDuty, CloudTrail, AWS Config, Security Hub, and IAM Access
+// Analyzer are set up, the IAM password policy, EBS encryption by default,
+// and S3 Block Public Access. It is meant for AnalysisWellArchitected, whose
+// assessment can then account for what the code does not show.
+func WithAWSAccountHints(include bool) Option {
+	return func(c *AIClient) {
+		c.awsAccountHints = include
+	}
+}
+
+// WithCDKSynth makes kado-ai run "cdk synth" or "cdktf synth" for every CDK
+// project under the IaC path before analysis, so the synthesized templates
+// are analyzed alongside the application code.
+func WithCDKSynth(synth bool) Option {
+	return func(c *AIClient) {
+		c.cdkSynth = synth
+	}
+}
+
+// WithAzureResourceGroup makes kado-ai run "az deployment group what-if"
+// against resourceGroup for every Bicep and ARM entry template under the
+// IaC path before analysis, so the changes a deployment would make are
+// analyzed like a Terraform plan.
+func WithAzureResourceGroup(resourceGroup string) Option {
+	return func(c *AIClient) {
+		c.azureResourceGroup = resourceGroup
+	}
+}
+
+// WithPulumiPreview makes kado-ai run "pulumi preview --json" for every
+// Pulumi project under the IaC path before analysis, so the changes the
+// program would make are analyzed like a Terraform plan.
+func WithPulumiPreview(preview bool) Option {
+	return func(c *AIClient) {
+		c.pulumiPreview = preview
+	}
+}
+
+// WithCIPipelines makes kado-ai also analyze the CI/CD pipeline
+// definitions under the IaC path: GitHub Actions workflows, GitLab CI files,
+// Jenkinsfiles, and Azure Pipelines, Bitbucket Pipelines, and CircleCI
+// configurations.
+func WithCIPipelines(include bool) Option {
+	return func(c *AIClient) {
+		c.pipelines = include
+	}
+}
+
+// WithKubernetesRender makes kado-ai run "helm template" for every Helm
+// chart and "kustomize build" for every Kustomize overlay under the IaC path
+// before analysis, so the manifests they produce are analyzed alongside the
+// manifests in the repository.
+func WithKubernetesRender(render bool) Option {
+	return func(c *AIClient) {
+		c.kubernetesRender = render
+	}
+}
+
+// WithConsentPolicy sets whether RunAI asks before sending data to the AI
+// provider. The default is ConsentAlwaysAsk.
+func WithConsentPolicy(policy ConsentPolicy) Option {
+	return func(c *AIClient) {
+		c.consentPolicy = policy
+	}
+}
+
+// WithConsentInput sets where the answer to the consent prompt is read from.
+// The default is standard input.
+func WithConsentInput(in io.Reader) Option {
+	return func(c *AIClient) {
+		c.consentInput = in
+	}
+}
+
+// WithMessageOutput sets where progress and warnings, such as the skipped
+// files of a scan, are printed. The default is standard output.
+func WithMessageOutput(w io.Writer) Option {
+	return func(c *AIClient) {
+		c.messageOutput = w
+	}
+}
+
+// WithSanitizeLevel chooses the built-in sanitizer rules: sanitize.LevelMinimal
+// for the most faithful prompt, sanitize.LevelStandard, the default, or
+// sanitize.LevelParanoid for the most private one.
+func WithSanitizeLevel(level sanitize.Level) Option {
+	return func(c *AIClient) {
+		c.sanitizeLevel = level
+	}
+}
+
+// WithSanitizeProfile sets the sanitization level used when sending to
+// destination, which is a provider such as "anthropic_messages", or
+// DestinationLocal for any provider whose base URL is on this machine. It
+// takes precedence over WithSanitizeLevel, so that, for example, a local
+// Ollama backend gets sanitize.LevelMinimal and public APIs
+// sanitize.LevelParanoid.
+func WithSanitizeProfile(destination string, level sanitize.Level) Option {
+	return func(c *AIClient) {
+		if c.sanitizeProfiles == nil {
+			c.sanitizeProfiles = map[string]sanitize.Level{}
+		}
+		c.sanitizeProfiles[destination] = level
+	}
+}
+
+// WithBaseURL sends completion requests to baseURL instead of the provider's
+// public API, such as http://localhost:11434/v1 for the OpenAI-compatible
+// API of a local Ollama server with the chatgpt provider, or a company
+// gateway.
+func WithBaseURL(baseURL string) Option {
+	return func(c *AIClient) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithTerraformRoots sets the directories scanned for Terraform code,
+// relative to the IaC path unless absolute, instead of terraform/. Terraform
+// commands run in the first of them.
+func WithTerraformRoots(roots ...string) Option {
+	return func(c *AIClient) {
+		c.terraformDirs = roots
+	}
+}
+
+// WithAnsibleRoots sets the directories scanned for Ansible code, relative
+// to the IaC path unless absolute, instead of ansible/.
+func WithAnsibleRoots(roots ...string) Option {
+	return func(c *AIClient) {
+		c.ansibleDirs = roots
+	}
+}
+
+// WithAnsiblePruning leaves out the Ansible YAML files no playbook uses:
+// roles no play applies, task and variable files nothing includes, and
+// unrelated YAML such as CI configuration. The project structure the prompt
+// presents still lists the unused roles.
+func WithAnsiblePruning(prune bool) Option {
+	return func(c *AIClient) {
+		c.pruneAnsible = prune
+	}
+}
+
+// WithSymlinks sets what a scan does with symbolic links: SymlinksWithin,
+// the default, follows links that stay inside the IaC path, SymlinksFollow
+// follows every link, and SymlinksSkip none. Links that lead back to a
+// directory being scanned are never followed.
+func WithSymlinks(symlinks Symlinks) Option {
+	return func(c *AIClient) {
+		c.symlinks = symlinks
+	}
+}
+
+// WithBinaryExtensions adds extensions, such as ".dat", to those of the
+// binary files a scan never reads.
+func WithBinaryExtensions(extensions ...string) Option {
+	return func(c *AIClient) {
+		c.binaryExtensions = extensions
+	}
+}
+
+// WithArchives also collects the Terraform, Rego, and Ansible files inside
+// the zip and tar archives under the IaC path.
+func WithArchives(archives bool) Option {
+	return func(c *AIClient) {
+		c.archives = archives
+	}
+}
+
+// WithPlanFiles sets the Terraform plans to include, in JSON format or
+// binary, relative to the IaC path unless absolute, instead of the plans
+// found in the Terraform roots.
+func WithPlanFiles(paths ...string) Option {
+	return func(c *AIClient) {
+		c.planPaths = paths
+	}
+}
+
+// WithAnsibleCheckFiles sets the saved output of ansible-playbook --check
+// --diff runs to include, relative to the IaC path unless absolute, instead
+// of the ansible-check.txt or ansible-check.log found in the Ansible roots.
+func WithAnsibleCheckFiles(paths ...string) Option {
+	return func(c *AIClient) {
+		c.ansibleCheckPaths = paths
+	}
+}
+
+// WithDiscovery also collects Terraform code, Ansible content, and plans
+// found anywhere under the IaC path outside of the Terraform and Ansible
+// roots. YAML files are Ansible content if they are in a directory with an
+// ansible.cfg or in directories such as roles and group_vars, or if they
+// look like playbooks or task lists.
+func WithDiscovery(discover bool) Option {
+	return func(c *AIClient) {
+		c.discover = discover
+	}
+}
+
+// WithInclude restricts scans to the files matching one of globs, which
+// are matched like the patterns of .kadoignore. Files that no glob matches
+// are recorded as skipped.
+func WithInclude(globs ...string) Option {
+	return func(c *AIClient) {
+		c.includeGlobs = globs
+	}
+}
+
+// WithExclude leaves the files and directories matching one of patterns
+// out of scans, in addition to those of .kadoignore and .kado/ignore.
+// Patterns are gitignore-style, such as "examples/", "**/testdata/", or
+// "*.auto.tfvars".
+func WithExclude(patterns ...string) Option {
+	return func(c *AIClient) {
+		c.excludeGlobs = patterns
+	}
+}
+
+// WithGitignore sets whether scans leave out what the repository's
+// .gitignore files ignore, which they do by default.
+func WithGitignore(respect bool) Option {
+	return func(c *AIClient) {
+		c.ignoreGitignore = !respect
+	}
+}
+
+// WithPIIScrubbing adds a pass that removes personal data before anything
+// is sent: email addresses, phone numbers, the users of Ansible inventories,
+// and people named in Terraform tags and labels, as required by teams
+// subject to GDPR.
+func WithPIIScrubbing(scrub bool) Option {
+	return func(c *AIClient) {
+		c.scrubPII = scrub
+	}
+}
+
+// WithCloudIDMasking replaces AWS account IDs, the accounts and resource
+// names of ARNs, GCP project IDs, and Azure subscription IDs with consistent
+// pseudonyms of the same format, which are restored in the response.
+func WithCloudIDMasking(mask bool) Option {
+	return func(c *AIClient) {
+		c.maskCloudIDs = mask
+	}
+}
+
+// WithBlockOnSecrets makes RunAI refuse to send anything, returning a
+// *SecretsBlockedError, when the sanitizer found high-confidence secrets such
+// as private keys and cloud credentials, whatever the consent policy.
+func WithBlockOnSecrets(block bool) Option {
+	return func(c *AIClient) {
+		c.blockOnSecrets = block
+	}
+}
+
+// WithVaultFiles sets what a scan does with files encrypted with Ansible
+// Vault: VaultFilesMention, the default, includes a note that the file is
+// encrypted instead of its ciphertext, and VaultFilesExclude leaves it out.
+func WithVaultFiles(vaultFiles VaultFiles) Option {
+	return func(c *AIClient) {
+		c.vaultFiles = vaultFiles
+	}
+}
+
+// WithStateInventory includes an inventory of the Terraform state files
+// under the IaC path in the prompt: the number of resources of each type and
+// their addresses, with every attribute value stripped. State files are
+// never sent as they are, whatever this setting.
+func WithStateInventory(include bool) Option {
+	return func(c *AIClient) {
+		c.stateInventory = include
+	}
+}
+
+// WithInputPath saves the sanitized AI input to path instead of ai_input.txt
+// in the IaC path, such as a directory outside the repository that is not
+// synced or backed up.
+func WithInputPath(path string) Option {
+	return func(c *AIClient) {
+		c.inputPath = path
+	}
+}
+
+// WithInputEncryption encrypts the saved AI input to the given age
+// recipients, such as age1... public keys or SSH public keys, with the age
+// command. The file gets an .age extension, and a plaintext input left by an
+// earlier run is removed.
+func WithInputEncryption(recipients ...string) Option {
+	return func(c *AIClient) {
+		c.inputRecipients = recipients
+	}
+}
+
+// WithInputDeletion deletes the saved AI input once the run is over, whether
+// it succeeded or not. The redaction report, which contains no values, is
+// kept.
+func WithInputDeletion(delete bool) Option {
+	return func(c *AIClient) {
+		c.deleteInput = delete
+	}
+}
+
+// WithExplainRedactions makes RunAI print every redaction it made, with the
+// rule ID, location, and length of the matched text, but never the text
+// itself. Use it to find out why legitimate code is being redacted.
+func WithExplainRedactions(explain bool) Option {
+	return func(c *AIClient) {
+		c.explainRedactions = explain
+	}
+}
+
+// WithStreaming requests a streamed response from the provider, so sinks
+// receive each section of the recommendations as soon as it is complete.
+func WithStreaming(stream bool) Option {
+	return func(c *AIClient) {
+		c.streaming = stream
+	}
+}
+
+// WithSinks adds destinations that receive the recommendations as they are
+// produced, in addition to the value returned by RunAI.
+func WithSinks(sinks ...Sink) Option {
+	return func(c *AIClient) {
+		c.sinks = append(c.sinks, sinks...)
+	}
+}
+
+// WithEmbedder sets the embedding provider used to build and query the local
+// RAG index.
+func WithEmbedder(embedder Embedder) Option {
+	return func(c *AIClient) {
+		c.embedder = embedder
+	}
+}
+
+// NewAIClientWithOptions creates an AIClient purely from options, without
+// reading a .kdconfig file. An API key, model, and provider are required.
+func NewAIClientWithOptions(opts ...Option) (*AIClient, error) {
+	c := &AIClient{
+		httpClient:    &http.Client{},
+		consentPolicy: ConsentAlwaysAsk,
+		consentInput:  os.Stdin,
+		contextWindow: defaultContextWindow,
+		maxFileTokens: defaultMaxFileTokens,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.apiKey == "" || c.model == "" || c.clientType == "" {
+		return nil, fmt.Errorf("API key, model, and provider must be set")
+	}
+	var apiKeys []string
+	for _, ref := range splitAPIKeys(c.apiKey) {
+		apiKey, err := resolveSecret(context.Background(), ref)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+	c.apiKey = strings.Join(apiKeys, ",")
+	keys, err := newKeyRing(c.apiKey, c.keyRotation)
+	if err != nil {
+		return nil, err
+	}
+	c.keys = keys
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{}
+	}
+	if len(c.noisePhrases) > 0 {
+		c.noise = newNoiseFilter(c.noisePhrases)
+	}
+	if c.complianceFramework != "" {
+		if _, ok := complianceFrameworks[c.complianceFramework]; !ok {
+			return nil, fmt.Errorf("unknown compliance framework %q; expected one of %s", c.complianceFramework, complianceFrameworkNames())
+		}
+		if c.analysisType == "" {
+			c.analysisType = AnalysisCompliance
+		}
+		if c.analysisType != AnalysisCompliance {
+			return nil, fmt.Errorf("a compliance framework can only be used with the %s analysis type, not %s", AnalysisCompliance, c.analysisType)
+		}
+	}
+	if c.analysisType == "" {
+		c.analysisType = AnalysisComprehensive
+	}
+	if !c.analysisType.valid() {
+		return nil, unknownAnalysisType(c.analysisType)
+	}
+	if c.prompt == "" {
+		c.prompt = c.persona().instruction
+	}
+	if c.tone != "" && !c.tone.valid() {
+		return nil, fmt.Errorf("unknown tone %q; expected %s or %s", c.tone, ToneEngineer, ToneExecutive)
+	}
+	if c.contextWindow <= 0 {
+		return nil, fmt.Errorf("context window must be positive, got %d", c.contextWindow)
+	}
+	if c.maxTokens < 0 {
+		return nil, fmt.Errorf("max tokens must not be negative, got %d", c.maxTokens)
+	}
+	if c.maxFileTokens < 0 || c.maxScanTokens < 0 {
+		return nil, fmt.Errorf("size limits must not be negative, got %d and %d", c.maxFileTokens, c.maxScanTokens)
+	}
+	if c.diffContext == "" {
+		c.diffContext = DiffContextModule
+	}
+	if !c.diffContext.valid() {
+		return nil, fmt.Errorf("unknown diff context %q; expected %s or %s", c.diffContext, DiffContextModule, DiffContextFiles)
+	}
+	if err := c.validIaCPaths(); err != nil {
+		return nil, err
+	}
+	if c.oversizedFiles == "" {
+		c.oversizedFiles = OversizedFilesTruncate
+	}
+	if !c.oversizedFiles.valid() {
+		return nil, fmt.Errorf("unknown oversized files setting %q; expected %s or %s", c.oversizedFiles, OversizedFilesTruncate, OversizedFilesSummarize)
+	}
+	if c.planFormat == "" {
+		c.planFormat = PlanFormatFull
+	}
+	if !c.planFormat.valid() {
+		return nil, fmt.Errorf("unknown plan format %q; expected %s or %s", c.planFormat, PlanFormatFull, PlanFormatSummary)
+	}
+	if _, err := sanitize.LevelRules(c.sanitizeLevel); err != nil {
+		return nil, err
+	}
+	for destination, level := range c.sanitizeProfiles {
+		if _, known := providerCatalog[destination]; !known && destination != DestinationLocal {
+			return nil, fmt.Errorf("unknown sanitization profile destination %q; expected %s or %s",
+				destination, strings.Join(supportedProviders(), ", "), DestinationLocal)
+		}
+		if _, err := sanitize.LevelRules(level); err != nil {
+			return nil, fmt.Errorf("invalid sanitization profile for %s: %v", destination, err)
+		}
+	}
+	if c.baseURL != "" {
+		if u, err := url.Parse(c.baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid base URL %q; expected an http or https URL", c.baseURL)
+		}
+	}
+	if c.symlinks == "" {
+		c.symlinks = SymlinksWithin
+	}
+	if !c.symlinks.valid() {
+		return nil, fmt.Errorf("unknown symlinks setting %q; expected %s, %s, or %s", c.symlinks, SymlinksWithin, SymlinksFollow, SymlinksSkip)
+	}
+	if c.vaultFiles == "" {
+		c.vaultFiles = VaultFilesMention
+	}
+	if !c.vaultFiles.valid() {
+		return nil, fmt.Errorf("unknown vault files setting %q; expected %s or %s", c.vaultFiles, VaultFilesMention, VaultFilesExclude)
+	}
+	if c.consentInput == nil {
+		c.consentInput = os.Stdin
+	}
+	if c.consentPolicy == "" {
+		c.consentPolicy = ConsentAlwaysAsk
+	}
+	if !c.consentPolicy.valid() {
+		return nil, fmt.Errorf("unknown consent policy %q; expected %s, %s, or %s",
+			c.consentPolicy, ConsentAlwaysAsk, ConsentAutoApprove, ConsentAutoApproveIfNoSecrets)
+	}
+	if c.cloneDir, c.iacPath, err = cloneIaCPath(c.iacPath); err != nil {
+		return nil, err
+	}
+	// The sanitizer reads .kado/sanitize.yaml, of the clone for a remote
+	// repository.
+	sanitizer, err := c.newSanitizer()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.sanitizer = sanitizer
+	return c, nil
+}
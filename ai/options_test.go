@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewAIClientWithOptions(t *testing.T) {
+	httpClient := &http.Client{}
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("test-api-key"),
+		WithModel("test-model"),
+		WithProvider("chatgpt"),
+		WithHTTPClient(httpClient),
+		WithIaCPath("/path/to/iac"),
+		WithPrompt("Review the following for security issues:"),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	if client.apiKey != "test-api-key" {
+		t.Errorf("Expected API key 'test-api-key', got '%s'", client.apiKey)
+	}
+	if client.model != "test-model" {
+		t.Errorf("Expected model 'test-model', got '%s'", client.model)
+	}
+	if client.clientType != "chatgpt" {
+		t.Errorf("Expected client type 'chatgpt', got '%s'", client.clientType)
+	}
+	if client.httpClient != httpClient {
+		t.Errorf("Expected the provided HTTP client to be used")
+	}
+	if client.iacPath != "/path/to/iac" {
+		t.Errorf("Expected IAC path '/path/to/iac', got '%s'", client.iacPath)
+	}
+	if client.prompt != "Review the following for security issues:" {
+		t.Errorf("Expected custom prompt, got '%s'", client.prompt)
+	}
+}
+
+func TestNewAIClientWithOptionsDefaults(t *testing.T) {
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("test-api-key"),
+		WithModel("test-model"),
+		WithProvider("anthropic_messages"),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+	if client.httpClient == nil {
+		t.Errorf("Expected a default HTTP client")
+	}
+	if client.prompt != defaultPrompt {
+		t.Errorf("Expected default prompt, got '%s'", client.prompt)
+	}
+}
+
+func TestNewAIClientWithOptionsMissingValues(t *testing.T) {
+	if _, err := NewAIClientWithOptions(WithAPIKey("test-api-key")); err == nil {
+		t.Errorf("Expected an error when model and provider are missing")
+	}
+}
+
+func TestProviderBlocksAndExtraPatterns(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kdconfig": `AI_CLIENT=anthropic_messages
+AI_MODEL=gpt-4
+
+[openai]
+api_key=sk-proj-abc
+model=gpt-4o
+
+[anthropic]
+api_key=sk-ant-abc
+model=claude-3-haiku-20240307
+
+[sanitizer]
+extra_patterns[]=corp-[0-9]{6}
+`,
+	})
+
+	client, err := NewAIClient(tmpDir, filepath.Join(tmpDir, ".kdconfig"))
+	if err != nil {
+		t.Fatalf("NewAIClient failed: %v", err)
+	}
+	if client.apiKey != "sk-ant-abc" || client.model != "claude-3-haiku-20240307" {
+		t.Errorf("Expected the anthropic block to be used, got key %s and model %s", client.apiKey, client.model)
+	}
+
+	sanitized, found := client.redact("account = corp-123456\n")
+	if sanitized != "account = [REDACTED]\n" || len(found) != 1 || found[0].Rule != "extra-pattern-1" || !found[0].Secret {
+		t.Errorf("Expected the extra pattern to redact the account ID as a secret, got %q (%+v)", sanitized, found)
+	}
+
+	if _, err := NewAIClientWithOptions(WithAPIKey("sk-proj-abc"), WithModel("gpt-4"), WithProvider("chatgpt"), WithExtraPatterns("corp-[")); err == nil {
+		t.Errorf("Expected an error for an invalid extra pattern")
+	}
+}
@@ -0,0 +1,134 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AnalysisType selects the persona the analysis is performed as.
+type AnalysisType string
+
+const (
+	// AnalysisComprehensive reviews every aspect of the infrastructure.
+	AnalysisComprehensive AnalysisType = "comprehensive"
+	// AnalysisSecurity reviews the infrastructure as a security engineer.
+	AnalysisSecurity AnalysisType = "security"
+	// AnalysisCost reviews the infrastructure as a FinOps practitioner.
+	AnalysisCost AnalysisType = "cost"
+	// AnalysisReliability reviews the infrastructure as an SRE.
+	AnalysisReliability AnalysisType = "reliability"
+	// AnalysisCompliance maps the infrastructure to compliance controls.
+	AnalysisCompliance AnalysisType = "compliance"
+	// AnalysisDrift explains the drift between the code and the real
+	// infrastructure and how to resolve it.
+	AnalysisDrift AnalysisType = "drift"
+	// AnalysisWellArchitected assesses the infrastructure pillar by pillar
+	// against the AWS Well-Architected Framework.
+	AnalysisWellArchitected AnalysisType = "well-architected"
+)
+
+// persona is the curated instruction that opens the prompt and the guidance
+// that closes it for an analysis type.
+type persona struct {
+	instruction string
+	guidance    string
+}
+
+var personas = map[AnalysisType]persona{
+	AnalysisComprehensive: {
+		instruction: defaultPrompt,
+		guidance:    "Consider all aspects including infrastructure provisioning, configuration management, security policies, and best practices.",
+	},
+	AnalysisSecurity: {
+		instruction: "You are a cloud security engineer performing a security review. Identify vulnerabilities and misconfigurations in the following infrastructure code:",
+		guidance:    "Focus on network exposure, identity and access management, encryption at rest and in transit, secrets handling, logging and auditability, and gaps in the OPA policies. For each issue, explain how it could be exploited and give the fix.",
+	},
+	AnalysisCost: {
+		instruction: "You are a FinOps practitioner reviewing infrastructure spend. Find cost optimization opportunities in the following infrastructure code:",
+		guidance:    "Focus on instance and storage right-sizing, reserved or spot capacity, idle or over-provisioned resources, data transfer and NAT gateway charges, storage lifecycle policies, and autoscaling limits. Estimate the relative impact of each recommendation and note any reliability trade-off.",
+	},
+	AnalysisReliability: {
+		instruction: "You are a site reliability engineer performing a reliability review of the following infrastructure code:",
+		guidance:    "Focus on single points of failure, multi-AZ and multi-region redundancy, backups and restore procedures, health checks, autoscaling, deployment safety, timeouts and retries, and observability. Rank the recommendations by their effect on availability.",
+	},
+	AnalysisCompliance: {
+		instruction: "You are a compliance auditor. Map the following infrastructure code to common compliance controls such as CIS Benchmarks, SOC 2, PCI DSS, and HIPAA:",
+		guidance:    "For each finding, name the control it relates to, state whether the code satisfies or violates it, and describe the change needed to comply. Note controls that OPA policies could enforce automatically.",
+	},
+	AnalysisDrift: {
+		instruction: "You are a platform engineer investigating drift between the following infrastructure code and the real infrastructure, as found by a refresh-only plan or a drift report:",
+		guidance:    "For each drifted resource, explain the most likely cause, such as a change made in the console, another tool or automation, or a default the provider sets, and whether the drift is a risk. Then propose how to resolve it: change the code to adopt the drift, add an import block or a terraform import command for unmanaged resources, or apply the code to revert it. Say which you recommend and why, and how to prevent the drift from recurring.",
+	},
+	AnalysisWellArchitected: {
+		instruction: "You are an AWS solutions architect performing an AWS Well-Architected Framework review of the following infrastructure code:",
+		guidance:    "Assess the infrastructure pillar by pillar: operational excellence, security, reliability, performance efficiency, cost optimization, and sustainability. For each pillar, rate the risk as high, medium, or none found, list the findings with the Well-Architected best practice each relates to, such as SEC 8 or REL 9, and give the remediation in code. Take the account settings into account where they are given, and say which best practices the code and the settings do not show enough of to assess.",
+	},
+}
+
+func (t AnalysisType) valid() bool {
+	_, ok := personas[t]
+	return ok
+}
+
+// analysisTypeNames returns the supported analysis types in a stable order.
+func analysisTypeNames() string {
+	var names []string
+	for t := range personas {
+		names = append(names, string(t))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func unknownAnalysisType(t AnalysisType) error {
+	return fmt.Errorf("unknown analysis type %q; expected one of %s", t, analysisTypeNames())
+}
+
+// Compliance frameworks that a compliance analysis can target.
+const (
+	FrameworkCIS       = "cis"
+	FrameworkSOC2      = "soc2"
+	FrameworkHIPAA     = "hipaa"
+	FrameworkPCIDSS    = "pci-dss"
+	FrameworkNIST80053 = "nist-800-53"
+)
+
+// complianceFrameworks maps each framework to its full name and the control
+// families the model should map findings to.
+var complianceFrameworks = map[string]struct {
+	name     string
+	controls string
+}{
+	FrameworkCIS:       {"the CIS Benchmarks for the cloud providers and operating systems in use", "the numbered CIS recommendations, such as 4.1"},
+	FrameworkSOC2:      {"the SOC 2 Trust Services Criteria", "the criteria identifiers, such as CC6.1"},
+	FrameworkHIPAA:     {"the HIPAA Security Rule", "the implementation specifications of 45 CFR 164.308 to 164.316, such as 164.312(a)(2)(iv)"},
+	FrameworkPCIDSS:    {"PCI DSS v4.0", "the numbered requirements, such as 1.3.1"},
+	FrameworkNIST80053: {"NIST SP 800-53 Rev. 5", "the control identifiers, such as AC-6 or SC-13"},
+}
+
+// complianceFrameworkNames returns the supported frameworks in a stable order.
+func complianceFrameworkNames() string {
+	var names []string
+	for name := range complianceFrameworks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// persona returns the persona for the client's analysis type, targeted at
+// its compliance framework if one is set. The caller must hold c.mu or own
+// the client exclusively.
+func (c *AIClient) persona() persona {
+	framework, ok := complianceFrameworks[c.complianceFramework]
+	if c.analysisType != AnalysisCompliance || !ok {
+		return personas[c.analysisType]
+	}
+	return persona{
+		instruction: fmt.Sprintf("You are a compliance auditor assessing the following infrastructure code against %s:", framework.name),
+		guidance: fmt.Sprintf("Map every finding to %s. Then output a control-by-control gap table for auditors, "+
+			"as a Markdown table with the columns Control, Requirement, Status (Met, Partial, Gap, or Not assessable from code), Evidence (the files and resources involved), and Remediation. "+
+			"Include every control the code is relevant to, not only the gaps.", framework.controls),
+	}
+}
@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAnalysisTypes(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	testCases := []struct {
+		name            string
+		opts            []Option
+		wantInstruction string
+		wantGuidance    string
+		wantErr         string
+	}{
+		{name: "default", wantInstruction: defaultPrompt, wantGuidance: "Consider all aspects"},
+		{name: "security", opts: []Option{WithAnalysisType(AnalysisSecurity)}, wantInstruction: "security review", wantGuidance: "secrets handling"},
+		{name: "cost", opts: []Option{WithAnalysisType(AnalysisCost)}, wantInstruction: "FinOps", wantGuidance: "right-sizing"},
+		{name: "reliability", opts: []Option{WithAnalysisType(AnalysisReliability)}, wantInstruction: "site reliability engineer", wantGuidance: "single points of failure"},
+		{name: "compliance", opts: []Option{WithAnalysisType(AnalysisCompliance)}, wantInstruction: "compliance controls", wantGuidance: "name the control"},
+		{name: "custom prompt", opts: []Option{WithAnalysisType(AnalysisCost), WithPrompt("Only look at NAT gateways:")}, wantInstruction: "Only look at NAT gateways:", wantGuidance: "right-sizing"},
+		{name: "unknown", opts: []Option{WithAnalysisType("finops")}, wantErr: "compliance, comprehensive, cost, drift, reliability, security, well-architected"},
+		{name: "framework", opts: []Option{WithComplianceFramework(FrameworkHIPAA)}, wantInstruction: "HIPAA Security Rule", wantGuidance: "control-by-control gap table"},
+		{name: "framework with compliance", opts: []Option{WithAnalysisType(AnalysisCompliance), WithComplianceFramework(FrameworkNIST80053)}, wantInstruction: "NIST SP 800-53", wantGuidance: "AC-6"},
+		{name: "framework with other persona", opts: []Option{WithAnalysisType(AnalysisCost), WithComplianceFramework(FrameworkSOC2)}, wantErr: "only be used with the compliance analysis type"},
+		{name: "unknown framework", opts: []Option{WithComplianceFramework("iso27001")}, wantErr: "cis, hipaa, nist-800-53, pci-dss, soc2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{WithAPIKey("sk-proj-abc"), WithModel("gpt-4"), WithProvider("chatgpt"), WithIaCPath(tmpDir)}, tc.opts...)
+			client, err := NewAIClientWithOptions(opts...)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Expected an error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewAIClientWithOptions failed: %v", err)
+			}
+			data, _, err := client.collectPromptData(context.Background(), false)
+			if err != nil {
+				t.Fatalf("collectPromptData failed: %v", err)
+			}
+			if !strings.Contains(data.Instruction, tc.wantInstruction) {
+				t.Errorf("Expected instruction containing %q, got %q", tc.wantInstruction, data.Instruction)
+			}
+			if !strings.Contains(data.Guidance, tc.wantGuidance) {
+				t.Errorf("Expected guidance containing %q, got %q", tc.wantGuidance, data.Guidance)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SectionPipelines holds the CI/CD pipeline definitions of a scan.
+const SectionPipelines = "ci pipelines"
+
+// pipelineFiles are the names of pipeline definitions wherever they are.
+var pipelineFiles = map[string]bool{
+	".gitlab-ci.yml":          true,
+	"Jenkinsfile":             true,
+	"azure-pipelines.yml":     true,
+	"azure-pipelines.yaml":    true,
+	"bitbucket-pipelines.yml": true,
+}
+
+// isPipelineFile reports whether the file at rel, relative to the IaC
+// path, is a pipeline definition: a GitHub Actions workflow or composite
+// action, a GitLab CI file, a Jenkinsfile, or an Azure Pipelines, Bitbucket
+// Pipelines, or CircleCI configuration.
+func isPipelineFile(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	name := filepath.Base(rel)
+	yamlFile := hasExtension(name, []string{".yml", ".yaml"})
+	switch {
+	case pipelineFiles[name]:
+		return true
+	case strings.HasPrefix(name, "Jenkinsfile.") || strings.HasSuffix(name, ".jenkinsfile"):
+		return true
+	case strings.HasSuffix(name, ".gitlab-ci.yml"):
+		return true
+	case yamlFile && strings.HasPrefix(rel, ".github/workflows/"):
+		return true
+	case yamlFile && strings.HasPrefix(rel, ".github/actions/") && strings.TrimSuffix(name, filepath.Ext(name)) == "action":
+		return true
+	case yamlFile && strings.HasPrefix(rel, ".gitlab/ci/"):
+		return true
+	case rel == ".circleci/config.yml":
+		return true
+	}
+	return false
+}
+
+// scanPipelines adds the pipeline definitions under the IaC path to result,
+// other than files already collected.
+func (c *AIClient) scanPipelines(result *ScanResult) {
+	collected := map[string]bool{}
+	for _, f := range result.Files {
+		collected[f.Path] = true
+	}
+	ignore := c.ignoreRules()
+	c.walk(result, c.iacPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != c.iacPath && (discoverySkipDirs[info.Name()] || ignore.ignored(c.iacPath, path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel := result.rel(path)
+		if collected[rel] || !isPipelineFile(rel) {
+			return nil
+		}
+		if ignore.ignored(c.iacPath, path, false) {
+			result.skip(path, ignoredReason)
+			return nil
+		}
+		c.addFile(result, SectionPipelines, path)
+		return nil
+	})
+}
@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsPipelineFile(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected bool
+	}{
+		{".github/workflows/deploy.yml", true},
+		{".github/workflows/README.md", false},
+		{".github/actions/setup/action.yaml", true},
+		{".github/dependabot.yml", false},
+		{".gitlab-ci.yml", true},
+		{"services/api/.gitlab-ci.yml", true},
+		{"deploy.gitlab-ci.yml", true},
+		{".gitlab/ci/build.yml", true},
+		{"Jenkinsfile", true},
+		{"Jenkinsfile.release", true},
+		{"ci/deploy.jenkinsfile", true},
+		{"azure-pipelines.yml", true},
+		{"bitbucket-pipelines.yml", true},
+		{".circleci/config.yml", true},
+		{"config.yml", false},
+	}
+	for _, tc := range testCases {
+		if got := isPipelineFile(filepath.FromSlash(tc.path)); got != tc.expected {
+			t.Errorf("Expected isPipelineFile(%q) to be %v, got %v", tc.path, tc.expected, got)
+		}
+	}
+}
+
+func TestScanPipelines(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".github/workflows/deploy.yml": "on: push\njobs:\n  deploy:\n    steps:\n      - uses: actions/checkout@v4\n",
+		"Jenkinsfile":                  "pipeline { agent any }\n",
+		"terraform/main.tf":            "resource \"aws_s3_bucket\" \"logs\" {}\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if pipelines := result.Section(SectionPipelines); len(pipelines) != 0 {
+		t.Errorf("Expected pipelines to be left out unless enabled, got %+v", pipelines)
+	}
+
+	client.pipelines = true
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var paths []string
+	for _, f := range result.Section(SectionPipelines) {
+		paths = append(paths, filepath.ToSlash(f.Path)+" ("+f.Language+")")
+	}
+	if got := strings.Join(paths, ", "); got != ".github/workflows/deploy.yml (yaml), Jenkinsfile (groovy)" {
+		t.Errorf("Expected the workflow and the Jenkinsfile, got %s", got)
+	}
+
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.Pipelines, "actions/checkout@v4") {
+		t.Errorf("Expected the pipelines in the prompt data, got %q", data.Pipelines)
+	}
+}
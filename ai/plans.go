@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// planNames are the names a Terraform plan is looked for under in each
+// Terraform root when no plan files are set, in order of preference: plans
+// in JSON format first, then binary plans, which terraform has to convert.
+var planNames = []string{"plan.json", "tfplan.json", "plan.tfplan.json", "tfplan", "plan.tfplan", "plan.out"}
+
+// isJSONPlanName reports whether name is that of a Terraform plan in JSON
+// format, such as plan.json or prod.tfplan.json.
+func isJSONPlanName(name string) bool {
+	return name == "plan.json" || name == "tfplan.json" || strings.HasSuffix(name, ".tfplan.json") || strings.HasSuffix(name, ".plan.json")
+}
+
+// isBinaryPlanName reports whether name is that of a binary Terraform plan,
+// as written by terraform plan -out, such as tfplan or prod.tfplan.
+func isBinaryPlanName(name string) bool {
+	return name == "tfplan" || name == "plan.out" || strings.HasSuffix(name, ".tfplan")
+}
+
+// hasJSONPlan reports whether dir holds a Terraform plan in JSON format,
+// which a binary plan next to it is most likely the source of.
+func hasJSONPlan(dir string) bool {
+	names, _ := readDirNames(dir)
+	for _, name := range names {
+		if isJSONPlanName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// addPlan adds the Terraform plan at path to result. Binary plans are
+// converted with terraform show -json, in the directory of the plan, when
+// terraform is on the PATH. Files that are not Terraform plans in JSON
+// format, such as the state printed by terraform show -json, are skipped
+// with a warning.
+func (c *AIClient) addPlan(ctx context.Context, result *ScanResult, path string) {
+	content, err := c.extractFileContent(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			result.skip(path, fmt.Sprintf("unreadable: %v", err))
+		}
+		return
+	}
+	binary := strings.HasPrefix(content, "PK\x03\x04")
+	if binary {
+		if content, err = c.showPlan(ctx, path); err != nil {
+			result.skip(path, err.Error())
+			fmt.Fprintf(c.messages(), "Warning: %s is left out: %v\n", path, err)
+			return
+		}
+	}
+	if !isPlanJSON(content) {
+		result.skip(path, "not a Terraform plan in JSON format")
+		fmt.Fprintf(c.messages(), "Warning: %s is left out since it is not a Terraform plan in JSON format\n", path)
+		return
+	}
+	addOutput(result, SectionTerraformPlan, path, content)
+	if binary {
+		result.Files[len(result.Files)-1].Language = "json"
+	}
+}
+
+// showPlan converts the binary plan at path to JSON with terraform show
+// -json, run read-only in the directory of the plan, whose configuration
+// and providers terraform needs.
+func (c *AIClient) showPlan(ctx context.Context, path string) (string, error) {
+	runner := c.terraform()
+	if _, err := lookPath(runner.binary); err != nil {
+		return "", fmt.Errorf("binary plan, and %s is not on the PATH to convert it", runner.binary)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	runner.dir = filepath.Dir(abs)
+	out, err := runner.run(ctx, "show", "-json", abs)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert binary plan: %v", err)
+	}
+	return string(out), nil
+}
+
+// isPlanJSON reports whether content is a Terraform plan in JSON format:
+// an object with planned values or resource changes.
+func isPlanJSON(content string) bool {
+	var plan map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &plan); err != nil {
+		return false
+	}
+	_, planned := plan["planned_values"]
+	_, changes := plan["resource_changes"]
+	return planned || changes
+}
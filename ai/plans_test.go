@@ -0,0 +1,188 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanConvertsBinaryPlans(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_vpc\" \"main\" {}\n",
+		"terraform/tfplan":  "PK\x03\x04\x14\x00binary plan",
+	})
+	calls := fakeRunCommand(t, `{"format_version":"1.2","resource_changes":[{"address":"aws_vpc.main"}]}`)
+	originalLookPath := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { lookPath = originalLookPath }()
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	plans := result.Section(SectionTerraformPlan)
+	if len(plans) != 1 || plans[0].Path != filepath.Join("terraform", "tfplan") || !strings.Contains(plans[0].Content, "aws_vpc.main") || plans[0].Language != "json" {
+		t.Fatalf("Expected the converted plan, got %+v", plans)
+	}
+	expected := fmt.Sprintf("terraform show -json %s", filepath.Join(tmpDir, "terraform", "tfplan"))
+	if len(*calls) != 1 || strings.Join((*calls)[0], " ") != expected {
+		t.Errorf("Expected %q, got %v", expected, *calls)
+	}
+
+	// Without terraform, the binary plan is skipped.
+	lookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Section(SectionTerraformPlan)) != 0 {
+		t.Errorf("Expected no plan without terraform")
+	}
+	if reason := skipReason(result, filepath.Join("terraform", "tfplan")); !strings.Contains(reason, "not on the PATH") {
+		t.Errorf("Expected the binary plan to be skipped, got %+v", result.Skipped)
+	}
+}
+
+func TestScanFindsPlans(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		// A plan in JSON format is preferred to the binary plan it was
+		// converted from.
+		"terraform/tfplan":           "PK\x03\x04\x14\x00binary plan",
+		"terraform/tfplan.json":      `{"planned_values":{}}`,
+		"envs/prod/prod.tfplan":      "PK\x03\x04\x14\x00binary plan",
+		"envs/prod/prod.tfplan.json": `{"resource_changes":[]}`,
+		// The state printed by terraform show -json is not a plan.
+		"envs/dev/plan.json": `{"format_version":"1.0","values":{}}`,
+	})
+	calls := fakeRunCommand(t, "")
+
+	client := &AIClient{iacPath: tmpDir, discover: true}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var paths []string
+	for _, f := range result.Section(SectionTerraformPlan) {
+		paths = append(paths, filepath.ToSlash(f.Path))
+	}
+	if got := strings.Join(paths, ","); got != "terraform/tfplan.json,envs/prod/prod.tfplan.json" {
+		t.Errorf("Expected the plans in JSON format, got %s", got)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("Expected no binary plan to be converted, got %v", *calls)
+	}
+	if reason := skipReason(result, filepath.Join("envs", "dev", "plan.json")); reason != "not a Terraform plan in JSON format" {
+		t.Errorf("Expected the state to be skipped, got %+v", result.Skipped)
+	}
+}
+
+// skipReason returns why result skipped path, or "" if it did not.
+func skipReason(result *ScanResult, path string) string {
+	for _, entry := range result.Skipped {
+		if entry.Path == path {
+			return entry.Reason
+		}
+	}
+	return ""
+}
+
+func TestScanRunsTerraformPlan(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf":     "terraform {\n  backend \"s3\" {}\n}\n\nresource \"aws_vpc\" \"main\" {}\n",
+		"terraform/plan.json":   `{"resource_changes":[{"address":"aws_vpc.saved"}]}`,
+		"terraform/prod.tfvars": "cidr = \"10.0.0.0/16\"\n",
+	})
+	var calls [][]string
+	originalRunCommand := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		switch args[0] {
+		case "validate":
+			return []byte(`{"valid":true,"diagnostics":[{"severity":"warning","summary":"Deprecated attribute","detail":"Use tags_all.","range":{"filename":"main.tf","start":{"line":5}}}]}`), nil
+		case "show":
+			return []byte(`{"resource_changes":[{"address":"aws_vpc.main"}]}`), nil
+		}
+		return nil, nil
+	}
+	defer func() { runCommand = originalRunCommand }()
+	originalLookPath := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { lookPath = originalLookPath }()
+
+	client := &AIClient{iacPath: tmpDir, terraformPlan: true, terraformBinary: "tofu", terraformVarFiles: []string{"terraform/prod.tfvars"}}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	plans := result.Section(SectionTerraformPlan)
+	if len(plans) != 1 || !strings.Contains(plans[0].Content, "aws_vpc.main") {
+		t.Fatalf("Expected the plan terraform produced instead of the saved one, got %+v", plans)
+	}
+	validation := result.Section(SectionTerraformValidation)
+	if len(validation) != 1 || validation[0].Content != "warning: Deprecated attribute (main.tf:5)\n  Use tags_all.\n" {
+		t.Errorf("Expected the validation warning, got %+v", validation)
+	}
+
+	var commands []string
+	for _, call := range calls {
+		command := strings.Join(call, " ")
+		if call[1] == "plan" || call[1] == "show" {
+			command = strings.Join(call[:2], " ")
+		}
+		commands = append(commands, command)
+	}
+	expected := "tofu init -backend=false -input=false,tofu validate -json,tofu init -input=false,tofu plan,tofu show"
+	if got := strings.Join(commands, ","); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if plan := strings.Join(calls[3], " "); !strings.Contains(plan, "-var-file="+filepath.Join(tmpDir, "terraform", "prod.tfvars")) || !strings.Contains(plan, "-lock=false") {
+		t.Errorf("Expected a read-only plan with the variable file, got %q", plan)
+	}
+
+	// An invalid configuration is not planned.
+	calls = nil
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		if args[0] == "validate" {
+			return []byte(`{"valid":false,"diagnostics":[{"severity":"error","summary":"Unsupported argument"}]}`), fmt.Errorf("tofu validate -json failed: exit status 1")
+		}
+		return nil, nil
+	}
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Section(SectionTerraformPlan)) != 0 || len(calls) != 2 {
+		t.Errorf("Expected no plan for an invalid configuration, got %v", calls)
+	}
+	if reason := skipReason(result, filepath.Join("terraform", "plan.json")); reason != "the configuration is not valid" {
+		t.Errorf("Expected the plan to be skipped, got %+v", result.Skipped)
+	}
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.TerraformValidation, "error: Unsupported argument") {
+		t.Errorf("Expected the validation error in the prompt, got %q", data.TerraformValidation)
+	}
+}
@@ -0,0 +1,193 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PlanFormat says how Terraform plans are presented in the prompt.
+type PlanFormat string
+
+const (
+	// PlanFormatFull presents plans as they are, in JSON format.
+	PlanFormatFull PlanFormat = "full"
+	// PlanFormatSummary presents plans as a table of the resources they
+	// create, update, replace, or destroy, with the names of the attributes
+	// that change but not their values. Resources left unchanged and data
+	// sources that are only read are left out.
+	PlanFormatSummary PlanFormat = "summary"
+)
+
+func (f PlanFormat) valid() bool {
+	return f == PlanFormatFull || f == PlanFormatSummary
+}
+
+// planActionOrder orders the rows of a plan summary from the most to the
+// least disruptive action.
+var planActionOrder = map[string]int{"delete": 0, "replace": 1, "update": 2, "create": 3}
+
+// planChange is a resource change of a plan in JSON format.
+type planChange struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Change  struct {
+		Actions      []string                   `json:"actions"`
+		Before       map[string]json.RawMessage `json:"before"`
+		After        map[string]json.RawMessage `json:"after"`
+		AfterUnknown map[string]json.RawMessage `json:"after_unknown"`
+		ReplacePaths [][]interface{}            `json:"replace_paths"`
+	} `json:"change"`
+}
+
+// action returns the action of the change: create, update, delete,
+// replace, read, no-op, or forget.
+func (c planChange) action() string {
+	if len(c.Change.Actions) == 2 {
+		return "replace"
+	}
+	if len(c.Change.Actions) == 1 {
+		return c.Change.Actions[0]
+	}
+	return "no-op"
+}
+
+// attributes returns the names of the top-level attributes an update or
+// replacement changes, marking those that force the replacement and those
+// known only after apply.
+func (c planChange) attributes() []string {
+	forcing := map[string]bool{}
+	for _, path := range c.Change.ReplacePaths {
+		if len(path) > 0 {
+			if name, ok := path[0].(string); ok {
+				forcing[name] = true
+			}
+		}
+	}
+	names := map[string]bool{}
+	for name, before := range c.Change.Before {
+		if after, ok := c.Change.After[name]; !ok || !bytes.Equal(before, after) {
+			names[name] = true
+		}
+	}
+	for name := range c.Change.After {
+		if _, ok := c.Change.Before[name]; !ok {
+			names[name] = true
+		}
+	}
+	unknown := map[string]bool{}
+	for name, value := range c.Change.AfterUnknown {
+		if string(value) != "false" && string(value) != "{}" && string(value) != "[]" {
+			names[name] = true
+			unknown[name] = true
+		}
+	}
+	var attributes []string
+	for name := range names {
+		switch {
+		case forcing[name]:
+			attributes = append(attributes, name+" (forces replacement)")
+		case unknown[name]:
+			attributes = append(attributes, name+" (known after apply)")
+		default:
+			attributes = append(attributes, name)
+		}
+	}
+	sort.Strings(attributes)
+	return attributes
+}
+
+// summarizePlan renders the plan in JSON format as a table of the resources
+// it changes, most disruptive first, and the outputs it changes, reporting
+// whether plan could be parsed.
+func summarizePlan(plan string) (string, bool) {
+	var parsed struct {
+		ResourceChanges []planChange `json:"resource_changes"`
+		OutputChanges   map[string]struct {
+			Change struct {
+				Actions []string `json:"actions"`
+			} `json:"change"`
+		} `json:"output_changes"`
+	}
+	if err := json.Unmarshal([]byte(plan), &parsed); err != nil {
+		return "", false
+	}
+
+	counts := map[string]int{}
+	var changes []planChange
+	unchanged := 0
+	for _, rc := range parsed.ResourceChanges {
+		action := rc.action()
+		if action == "no-op" || action == "read" {
+			unchanged++
+			continue
+		}
+		counts[action]++
+		changes = append(changes, rc)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i].action(), changes[j].action()
+		if a != b {
+			return planActionOrder[a] < planActionOrder[b]
+		}
+		return changes[i].Address < changes[j].Address
+	})
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Plan: %d to add, %d to change, %d to replace, %d to destroy", counts["create"], counts["update"], counts["replace"], counts["delete"])
+	if unchanged > 0 {
+		fmt.Fprintf(&out, "; %d unchanged resources and data sources not shown", unchanged)
+	}
+	out.WriteString(".\n")
+	if len(changes) > 0 {
+		out.WriteString("\n| Action | Resource | Changed attributes |\n| --- | --- | --- |\n")
+		for _, rc := range changes {
+			attributes := ""
+			if action := rc.action(); action == "update" || action == "replace" {
+				attributes = strings.Join(rc.attributes(), ", ")
+			}
+			fmt.Fprintf(&out, "| %s | %s | %s |\n", rc.action(), rc.Address, attributes)
+		}
+	}
+	var outputs []string
+	for name, output := range parsed.OutputChanges {
+		if actions := strings.Join(output.Change.Actions, ","); actions != "no-op" && actions != "" {
+			outputs = append(outputs, name)
+		}
+	}
+	if len(outputs) > 0 {
+		sort.Strings(outputs)
+		fmt.Fprintf(&out, "\nOutputs changed: %s\n", strings.Join(outputs, ", "))
+	}
+	return out.String(), true
+}
+
+// summarizePlans replaces the content of the plans of result with their
+// summaries, recording the resources they change for ranking, since the
+// summaries are no longer plans in JSON format. Plans that cannot be parsed
+// are left as they are.
+func summarizePlans(result *ScanResult) {
+	for i := range result.Files {
+		f := &result.Files[i]
+		if f.Section != SectionTerraformPlan {
+			continue
+		}
+		planned := plannedResources(&ScanResult{Files: []ScannedFile{*f}})
+		summary, ok := summarizePlan(f.Content)
+		if !ok {
+			continue
+		}
+		if result.planned == nil {
+			result.planned = map[string]bool{}
+		}
+		for address := range planned {
+			result.planned[address] = true
+		}
+		f.Content = summary
+		f.Language = "markdown"
+		f.Summarized = true
+	}
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testPlan = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_vpc.main", "type": "aws_vpc", "name": "main", "change": {"actions": ["no-op"], "before": {"cidr_block": "10.0.0.0/16"}, "after": {"cidr_block": "10.0.0.0/16"}}},
+    {"address": "data.aws_ami.ubuntu", "mode": "data", "type": "aws_ami", "name": "ubuntu", "change": {"actions": ["read"]}},
+    {"address": "aws_s3_bucket.logs", "type": "aws_s3_bucket", "name": "logs", "change": {"actions": ["create"], "before": null, "after": {"bucket": "logs"}, "after_unknown": {"arn": true}}},
+    {"address": "aws_security_group.web", "type": "aws_security_group", "name": "web", "change": {"actions": ["update"], "before": {"ingress": [{"cidr_blocks": ["10.0.0.0/8"]}], "name": "web", "tags": {}}, "after": {"ingress": [{"cidr_blocks": ["0.0.0.0/0"]}], "name": "web", "tags": {}}, "after_unknown": {}}},
+    {"address": "aws_instance.web", "type": "aws_instance", "name": "web", "change": {"actions": ["delete", "create"], "before": {"ami": "ami-1", "id": "i-1"}, "after": {"ami": "ami-2"}, "after_unknown": {"id": true}, "replace_paths": [["ami"]]}},
+    {"address": "aws_iam_user.old", "type": "aws_iam_user", "name": "old", "change": {"actions": ["delete"], "before": {"name": "old"}, "after": null}}
+  ],
+  "output_changes": {
+    "vpc_id": {"change": {"actions": ["no-op"]}},
+    "web_ip": {"change": {"actions": ["update"]}}
+  }
+}`
+
+func TestSummarizePlan(t *testing.T) {
+	summary, ok := summarizePlan(testPlan)
+	if !ok {
+		t.Fatalf("Expected the plan to be parsed")
+	}
+	expected := `Plan: 1 to add, 1 to change, 1 to replace, 1 to destroy; 2 unchanged resources and data sources not shown.
+
+| Action | Resource | Changed attributes |
+| --- | --- | --- |
+| delete | aws_iam_user.old |  |
+| replace | aws_instance.web | ami (forces replacement), id (known after apply) |
+| update | aws_security_group.web | ingress |
+| create | aws_s3_bucket.logs |  |
+
+Outputs changed: web_ip
+`
+	if summary != expected {
+		t.Errorf("Expected summary:\n%s\ngot:\n%s", expected, summary)
+	}
+
+	if _, ok := summarizePlan("not json"); ok {
+		t.Errorf("Expected an unparseable plan to be reported")
+	}
+}
+
+func TestScanSummarizesPlans(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/plan.json": testPlan,
+		"terraform/web.tf":    "resource \"aws_security_group\" \"web\" {}\n",
+		"terraform/other.tf":  "resource \"aws_sqs_queue\" \"jobs\" {}\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir, planFormat: PlanFormatSummary}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	plans := result.Section(SectionTerraformPlan)
+	if len(plans) != 1 || !plans[0].Summarized || !strings.HasPrefix(plans[0].Content, "Plan: 1 to add") {
+		t.Fatalf("Expected the plan to be summarized, got %+v", plans)
+	}
+	if !plannedResources(result)["aws_security_group.web"] {
+		t.Errorf("Expected the summarized plan's changes to be known for ranking")
+	}
+
+	data, redactions := client.promptData(result)
+	if !strings.Contains(data.TerraformPlan, "| update | aws_security_group.web | ingress |") {
+		t.Errorf("Expected the summary in the prompt, got %q", data.TerraformPlan)
+	}
+	if strings.Contains(data.TerraformPlan, "0.0.0.0/0") {
+		t.Errorf("Expected no values in the summary, got %q", data.TerraformPlan)
+	}
+	for _, r := range redactions {
+		if r.Section == SectionTerraformPlan {
+			t.Errorf("Expected nothing redacted from the summary, got %+v", r)
+		}
+	}
+}
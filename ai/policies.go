@@ -0,0 +1,234 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// policiesDir is the directory in the IaC path that GeneratePolicies writes
+// policies to.
+const policiesDir = "kado-policies"
+
+// Policy is a Rego policy written by GeneratePolicies. File is relative to
+// the IaC path. Error holds the output of a failed "opa check".
+type Policy struct {
+	File    string `json:"file"`
+	Content string `json:"content"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PolicyResult is the outcome of GeneratePolicies.
+type PolicyResult struct {
+	Policies []Policy `json:"policies"`
+	// Uncovered are the Terraform resources that no policy covered before.
+	Uncovered []string `json:"uncovered"`
+	// Summary is the provider's account of which policy covers each
+	// resource.
+	Summary string `json:"summary"`
+}
+
+var (
+	terraformResource = regexp.MustCompile(`(?m)^\s*resource\s+"([^"]+)"\s+"([^"]+)"`)
+	policyBlock       = regexp.MustCompile("(?m)^File: *(\\S+\\.rego)\\s*\\n```(?:rego)?\\n((?s:.*?))```")
+	coverageSummary   = regexp.MustCompile(`(?ms)^#+ *Coverage summary\s*\n(.*?)(?:^File: |\z)`)
+)
+
+// ResourceCoverage is a Terraform resource type of the code or the plans,
+// its resources, and the Rego policies that reference it.
+type ResourceCoverage struct {
+	Type      string   `json:"type"`
+	Resources []string `json:"resources"`
+	Policies  []string `json:"policies,omitempty"`
+}
+
+// PolicyCoverage cross-references the types of the Terraform resources
+// defined in the code and changed by the plans with the Rego policies that
+// reference them, in order of type. A type no policy references is a gap in
+// the policies.
+func (r *ScanResult) PolicyCoverage() []ResourceCoverage {
+	resources := map[string]map[string]bool{}
+	add := func(address string) {
+		resourceType := address
+		if i := strings.Index(address, "."); i >= 0 {
+			resourceType = address[:i]
+		}
+		if resources[resourceType] == nil {
+			resources[resourceType] = map[string]bool{}
+		}
+		resources[resourceType][address] = true
+	}
+	for _, f := range r.Section(SectionTerraform) {
+		if f.Language != "terraform" {
+			continue
+		}
+		for _, m := range terraformResource.FindAllStringSubmatch(f.Content, -1) {
+			add(m[1] + "." + m[2])
+		}
+	}
+	for address := range plannedResources(r) {
+		add(address)
+	}
+
+	var policies []ScannedFile
+	for _, f := range r.Files {
+		if f.Language == "rego" {
+			policies = append(policies, f)
+		}
+	}
+	var coverage []ResourceCoverage
+	for resourceType, addresses := range resources {
+		c := ResourceCoverage{Type: resourceType}
+		for address := range addresses {
+			c.Resources = append(c.Resources, address)
+		}
+		sort.Strings(c.Resources)
+		reference := regexp.MustCompile(`\b` + regexp.QuoteMeta(resourceType) + `\b`)
+		for _, f := range policies {
+			if reference.MatchString(f.Content) {
+				name := f.Pseudonym
+				if name == "" {
+					name = f.Path
+				}
+				c.Policies = append(c.Policies, filepath.ToSlash(name))
+			}
+		}
+		coverage = append(coverage, c)
+	}
+	sort.Slice(coverage, func(i, j int) bool { return coverage[i].Type < coverage[j].Type })
+	return coverage
+}
+
+// uncoveredResources returns the addresses of the Terraform resources in
+// scan whose type is not referenced by any of its Rego policies.
+func uncoveredResources(scan *ScanResult) []string {
+	var uncovered []string
+	for _, c := range scan.PolicyCoverage() {
+		if len(c.Policies) == 0 {
+			uncovered = append(uncovered, c.Resources...)
+		}
+	}
+	sort.Strings(uncovered)
+	return uncovered
+}
+
+// policyCoverage renders the policy coverage of scan as a table, the
+// uncovered types first, or "" when scan has no Rego policies to cover
+// anything.
+func policyCoverage(scan *ScanResult) string {
+	hasPolicies := false
+	for _, f := range scan.Files {
+		if f.Language == "rego" {
+			hasPolicies = true
+		}
+	}
+	coverage := scan.PolicyCoverage()
+	if !hasPolicies || len(coverage) == 0 {
+		return ""
+	}
+	sort.SliceStable(coverage, func(i, j int) bool {
+		return len(coverage[i].Policies) == 0 && len(coverage[j].Policies) > 0
+	})
+	var b strings.Builder
+	b.WriteString("| Resource type | Resources | Policies |\n| --- | --- | --- |\n")
+	for _, c := range coverage {
+		policies := "none"
+		if len(c.Policies) > 0 {
+			policies = strings.Join(c.Policies, ", ")
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", c.Type, strings.Join(c.Resources, ", "), policies)
+	}
+	return b.String()
+}
+
+// ParsePolicies extracts the Rego policies and the coverage summary from a
+// provider response. Every policy is preceded by a "File: NAME.rego" line
+// and enclosed in a code block.
+func ParsePolicies(text string) ([]Policy, string, error) {
+	var policies []Policy
+	for _, m := range policyBlock.FindAllStringSubmatch(text, -1) {
+		policies = append(policies, Policy{File: filepath.Base(m[1]), Content: m[2]})
+	}
+	if len(policies) == 0 {
+		return nil, "", fmt.Errorf("response does not contain any Rego policies")
+	}
+	var summaries []string
+	for _, m := range coverageSummary.FindAllStringSubmatch(text, -1) {
+		summaries = append(summaries, strings.TrimSpace(m[1]))
+	}
+	return policies, strings.Join(summaries, "\n"), nil
+}
+
+// GeneratePolicies asks the provider to write OPA Rego policies that cover
+// the risks it finds in Terraform resources no existing policy mentions.
+// The policies are written to kado-policies/ in the IaC path together with
+// COVERAGE.md, a summary of which policy covers each resource. When opa is
+// installed, every policy is checked with "opa check" and failures are
+// reported in Policy.Error.
+func (c *AIClient) GeneratePolicies(ctx context.Context) (*PolicyResult, error) {
+	text, err := c.run("policies", c.question)
+	if err != nil {
+		return nil, err
+	}
+	policies, summary, err := ParsePolicies(text)
+	if err != nil {
+		return nil, err
+	}
+	scan, err := c.scan(ctx, false, "")
+	if err != nil {
+		return nil, err
+	}
+	result := &PolicyResult{Policies: policies, Uncovered: uncoveredResources(scan), Summary: summary}
+
+	dir := filepath.Join(c.iacPath, policiesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+	_, opaErr := lookPath("opa")
+	for i := range result.Policies {
+		p := &result.Policies[i]
+		path := filepath.Join(dir, p.File)
+		if err := os.WriteFile(path, []byte(p.Content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		p.File = filepath.Join(policiesDir, p.File)
+		if opaErr == nil {
+			if _, err := runCommand(ctx, c.iacPath, nil, "opa", "check", p.File); err != nil {
+				p.Error = err.Error()
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "COVERAGE.md"), []byte(result.coverage()), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write coverage summary: %v", err)
+	}
+	fmt.Fprintf(c.messages(), "%d policies were written to %s\n", len(result.Policies), dir)
+	return result, nil
+}
+
+// coverage renders the coverage summary as Markdown.
+func (r *PolicyResult) coverage() string {
+	var b strings.Builder
+	b.WriteString("# Policy coverage\n\nResources without a policy before generation:\n\n")
+	for _, address := range r.Uncovered {
+		fmt.Fprintf(&b, "- %s\n", address)
+	}
+	if len(r.Uncovered) == 0 {
+		b.WriteString("- none\n")
+	}
+	b.WriteString("\nGenerated policies:\n\n")
+	for _, p := range r.Policies {
+		fmt.Fprintf(&b, "- %s", filepath.ToSlash(p.File))
+		if p.Error != "" {
+			b.WriteString(" (fails opa check)")
+		}
+		b.WriteString("\n")
+	}
+	if r.Summary != "" {
+		fmt.Fprintf(&b, "\n## Coverage summary\n\n%s\n", r.Summary)
+	}
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUncoveredResources(t *testing.T) {
+	scan := &ScanResult{Files: []ScannedFile{
+		{Section: SectionTerraform, Language: "terraform", Content: "resource \"aws_s3_bucket\" \"logs\" {}\nresource \"aws_security_group\" \"web\" {}\n"},
+		{Section: SectionTerraform, Language: "terraform", Content: "  resource \"aws_instance\" \"app\" {\n}\n"},
+		{Section: SectionTerraform, Language: "rego", Content: "deny[msg] { input.resource_changes[_].type == \"aws_security_group\" }"},
+	}}
+	expected := []string{"aws_instance.app", "aws_s3_bucket.logs"}
+	if got := uncoveredResources(scan); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestPolicyCoverage(t *testing.T) {
+	scan := &ScanResult{Files: []ScannedFile{
+		{Path: "main.tf", Section: SectionTerraform, Language: "terraform", Content: "resource \"aws_s3_bucket\" \"logs\" {}\nresource \"aws_s3_bucket_policy\" \"logs\" {}\n"},
+		{Path: "plan.json", Section: SectionTerraformPlan, Language: "json", Content: `{"resource_changes":[{"type":"aws_iam_role","name":"app","change":{"actions":["create"]}},{"type":"aws_s3_bucket","name":"logs","change":{"actions":["update"]}}]}`},
+		{Path: filepath.Join("policy", "s3.rego"), Section: SectionTerraform, Language: "rego", Content: "deny[msg] { input.resource_changes[_].type == \"aws_s3_bucket_policy\" }"},
+	}}
+	expected := []ResourceCoverage{
+		{Type: "aws_iam_role", Resources: []string{"aws_iam_role.app"}},
+		{Type: "aws_s3_bucket", Resources: []string{"aws_s3_bucket.logs"}},
+		{Type: "aws_s3_bucket_policy", Resources: []string{"aws_s3_bucket_policy.logs"}, Policies: []string{"policy/s3.rego"}},
+	}
+	if got := scan.PolicyCoverage(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	data, _ := (&AIClient{}).promptData(scan)
+	if !strings.HasPrefix(data.PolicyCoverage, "| Resource type | Resources | Policies |\n| --- | --- | --- |\n| aws_iam_role | aws_iam_role.app | none |\n| aws_s3_bucket | aws_s3_bucket.logs | none |\n") {
+		t.Errorf("Expected the uncovered types first, got %q", data.PolicyCoverage)
+	}
+
+	// Without policies, there is no coverage to present.
+	scan.Files = scan.Files[:2]
+	if got := policyCoverage(scan); got != "" {
+		t.Errorf("Expected no coverage without policies, got %q", got)
+	}
+}
+
+func TestGeneratePolicies(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_s3_bucket\" \"logs\" {}\n",
+	})
+
+	response := "File: ../s3_versioning.rego\n```rego\npackage kado.s3_versioning\n\ndeny[msg] {\n  msg := \"aws_s3_bucket.logs has no versioning\"\n}\n```\n\n## Coverage summary\n\n- aws_s3_bucket.logs: s3_versioning.rego\n"
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+		out, _ := json.Marshal(map[string]interface{}{"content": []map[string]string{{"type": "text", "text": response}}})
+		w.Write(out)
+	}))
+	defer server.Close()
+
+	calls := fakeRunCommand(t, "")
+	originalLookPath := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { lookPath = originalLookPath }()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	result, err := client.GeneratePolicies(context.Background())
+	if err != nil {
+		t.Fatalf("GeneratePolicies failed: %v", err)
+	}
+	if !strings.Contains(prompt, "- aws_s3_bucket.logs\n") {
+		t.Errorf("Expected the prompt to list the uncovered resource, got %q", prompt)
+	}
+	if len(result.Policies) != 1 || result.Policies[0].File != filepath.Join(policiesDir, "s3_versioning.rego") {
+		t.Fatalf("Expected a single policy inside %s, got %+v", policiesDir, result.Policies)
+	}
+	if result.Summary != "- aws_s3_bucket.logs: s3_versioning.rego" {
+		t.Errorf("Unexpected summary %q", result.Summary)
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDir, policiesDir, "s3_versioning.rego"))
+	if err != nil || !strings.HasPrefix(string(content), "package kado.s3_versioning") {
+		t.Errorf("Expected the policy to be written, got %q (%v)", content, err)
+	}
+	coverage, err := os.ReadFile(filepath.Join(tmpDir, policiesDir, "COVERAGE.md"))
+	if err != nil || !strings.Contains(string(coverage), "- aws_s3_bucket.logs\n") {
+		t.Errorf("Expected a coverage summary, got %q (%v)", coverage, err)
+	}
+	if len(*calls) != 1 || strings.Join((*calls)[0], " ") != "opa check "+filepath.Join(policiesDir, "s3_versioning.rego") {
+		t.Errorf("Expected the policy to be checked with opa, got %v", *calls)
+	}
+
+	if _, _, err := ParsePolicies("No policies needed."); err == nil {
+		t.Errorf("Expected an error for a response without policies")
+	}
+}
@@ -0,0 +1,382 @@
+package ai
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/janpreet/kado-ai/config"
+)
+
+// projectDir is the directory at the root of an IaC tree holding its
+// kado-ai project settings:
+//
+//	.kado/config             project configuration, merged over the user config
+//	.kado/prompts/           custom prompt templates
+//	.kado/prompts/library/   custom question templates
+//	.kado/ignore             paths never scanned or indexed, like .kadoignore
+//	.kado/sanitize.yaml      additional and disabled sanitizer rules
+//	.kado/standards.md       the team's standards, included in prompts
+//	.kado/examples.json      few-shot examples sent ahead of prompts
+//	.kado/index.json         the local RAG index
+//	.kado/suppressions       findings that are not reported
+//	.kado/baseline.json      fingerprints of accepted findings
+//	.kado/conversation.json  the last analysis and its follow-ups
+//	.kado/paths.json         the mapping of anonymized paths to real ones
+//	.kado/placeholders.json  the mapping of redaction placeholders to values
+//	.kado/hostnames.json     the mapping of hostname pseudonyms to hostnames
+//	.kado/cloud_ids.json     the mapping of cloud ID pseudonyms to cloud IDs
+const projectDir = ".kado"
+
+// projectPath returns the path of elem inside the project's .kado directory.
+func (c *AIClient) projectPath(elem ...string) string {
+	return filepath.Join(append([]string{c.iacPath, projectDir}, elem...)...)
+}
+
+// projectPathKeys are the project keys holding directories, which must stay
+// inside the IaC path when they come from the project config.
+var projectPathKeys = map[string]bool{"AI_TERRAFORM_ROOTS": true, "AI_ANSIBLE_ROOTS": true}
+
+// loadConfig loads the user config at configPath and merges the project
+// config in iacPath/.kado/config over it. A missing user config is fine as
+// long as the project config exists. Only the keys marked Project, which
+// shape the analysis of the repository's own code, are taken from the
+// project config, since it is committed alongside the code by whoever
+// controls the repository; the others decide what runs, what is read, and
+// what leaves the machine.
+func loadConfig(iacPath string, configPath string) (map[string]string, error) {
+	values, err := config.Load(configPath)
+	projectConfigPath := filepath.Join(iacPath, projectDir, "config")
+	project, projectErr := config.Load(projectConfigPath)
+	if projectErr != nil && !os.IsNotExist(projectErr) {
+		return nil, fmt.Errorf("failed to load project config: %v", projectErr)
+	}
+	if err != nil {
+		if !os.IsNotExist(err) || projectErr != nil {
+			return nil, fmt.Errorf("failed to load config: %v", err)
+		}
+		values = make(map[string]string)
+	}
+
+	for name, value := range project {
+		if key, ok := config.Lookup(name); !ok || !key.Project {
+			fmt.Printf("Warning: ignoring %s in %s; it can only be set in the user config\n", name, projectConfigPath)
+			continue
+		}
+		if projectPathKeys[name] && !relativePaths(value) {
+			fmt.Printf("Warning: ignoring %s in %s; its directories must be inside the IaC path\n", name, projectConfigPath)
+			continue
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// relativePaths reports whether every path of the comma-separated list value
+// is relative and stays inside the directory it is relative to.
+func relativePaths(value string) bool {
+	for _, p := range splitList(value) {
+		p = filepath.Clean(filepath.FromSlash(p))
+		if filepath.IsAbs(p) || p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ignoreFile is the gitignore-style file at the root of the IaC path whose
+// patterns are never scanned, alongside those of .kado/ignore.
+const ignoreFile = ".kadoignore"
+
+// ignoredReason is recorded for the files and directories that ignoreRules
+// leave out of a scan.
+const ignoredReason = "ignored by .gitignore, .kadoignore, .kado/ignore, AI_INCLUDE, or AI_EXCLUDE"
+
+// ignoreRules decide which paths are never scanned or indexed: those
+// matching the patterns of the .gitignore files, unless they are not
+// respected, of .kado/ignore, .kadoignore, and the exclude globs, in that
+// order of precedence, and, when include globs are set, the files none of
+// them matches.
+type ignoreRules struct {
+	patterns []ignorePattern
+	includes []ignorePattern
+	// gitignore holds the patterns of the .gitignore files read so far, by
+	// the slash-separated directory they are in relative to the IaC path.
+	// The patterns of the repository's .gitignore files above the IaC path
+	// come first under "". It is nil if .gitignore files are not respected.
+	gitignore map[string][]ignorePattern
+}
+
+// ignorePattern is a gitignore-style pattern. It is matched against the
+// slash-separated path relative to the IaC path if it contains a slash
+// other than a trailing one, and otherwise against the base name at any
+// depth. * and ? do not match a slash, ** matches any number of
+// directories, a trailing slash restricts the pattern to directories, and a
+// leading ! re-includes what an earlier pattern excluded.
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// anchored patterns match the whole relative path.
+	anchored bool
+	// base is the directory of the .gitignore a pattern comes from,
+	// relative to the IaC path; its patterns only match paths inside it,
+	// relative to it. prefix is the IaC path relative to the directory of a
+	// .gitignore above it.
+	base   string
+	prefix string
+}
+
+func newIgnorePattern(line string) (ignorePattern, bool) {
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	p.anchored = strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return p, false
+	}
+	re, err := regexp.Compile("^" + globPattern(line) + "$")
+	if err != nil {
+		return p, false
+	}
+	p.re = re
+	return p, true
+}
+
+// globPattern translates a glob into a regular expression.
+func globPattern(glob string) string {
+	var out strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			out.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			out.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			out.WriteString(".*")
+			i++
+		case c == '*':
+			out.WriteString("[^/]*")
+		case c == '?':
+			out.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				out.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			out.WriteString("[" + class + "]")
+			i += end
+		case c == '\\' && i+1 < len(glob):
+			i++
+			out.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			out.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return out.String()
+}
+
+func (p ignorePattern) match(rel string, dir bool) bool {
+	if p.dirOnly && !dir {
+		return false
+	}
+	if p.base != "" {
+		if !strings.HasPrefix(rel, p.base+"/") {
+			return false
+		}
+		rel = rel[len(p.base)+1:]
+	}
+	if p.prefix != "" {
+		rel = p.prefix + "/" + rel
+	}
+	if p.anchored {
+		return p.re.MatchString(rel)
+	}
+	return p.re.MatchString(path.Base(rel))
+}
+
+func (c *AIClient) ignoreRules() ignoreRules {
+	var rules ignoreRules
+	for _, file := range []string{c.projectPath("ignore"), filepath.Join(c.iacPath, ignoreFile)} {
+		rules.patterns = append(rules.patterns, readIgnorePatterns(file)...)
+	}
+	for _, glob := range c.excludeGlobs {
+		if p, ok := newIgnorePattern(glob); ok {
+			rules.patterns = append(rules.patterns, p)
+		}
+	}
+	for _, glob := range c.includeGlobs {
+		if p, ok := newIgnorePattern(glob); ok {
+			rules.includes = append(rules.includes, p)
+		}
+	}
+	if !c.ignoreGitignore {
+		rules.gitignore = map[string][]ignorePattern{"": parentGitignores(c.iacPath)}
+		rules.gitignore[""] = append(rules.gitignore[""], readIgnorePatterns(filepath.Join(c.iacPath, ".gitignore"))...)
+	}
+	return rules
+}
+
+// parentGitignores returns the patterns of the .gitignore files between
+// the root of the git repository the IaC path is in and the IaC path, from
+// the root down, or nothing if the IaC path is not inside a repository or
+// is its root.
+func parentGitignores(iacPath string) []ignorePattern {
+	dir, err := filepath.Abs(iacPath)
+	if err != nil || fileOrDirExists(filepath.Join(dir, ".git")) {
+		return nil
+	}
+	var dirs []string
+	for parent := filepath.Dir(dir); ; parent = filepath.Dir(parent) {
+		dirs = append(dirs, parent)
+		if fileOrDirExists(filepath.Join(parent, ".git")) {
+			break
+		}
+		if filepath.Dir(parent) == parent {
+			return nil
+		}
+	}
+	var patterns []ignorePattern
+	for i := len(dirs) - 1; i >= 0; i-- {
+		prefix, err := filepath.Rel(dirs[i], dir)
+		if err != nil {
+			continue
+		}
+		for _, p := range readIgnorePatterns(filepath.Join(dirs[i], ".gitignore")) {
+			p.prefix = filepath.ToSlash(prefix)
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+func fileOrDirExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// gitignoreIn returns the patterns of the .gitignore in dir, relative to
+// root, reading it on first use.
+func (rules ignoreRules) gitignoreIn(root string, dir string) []ignorePattern {
+	if patterns, ok := rules.gitignore[dir]; ok {
+		return patterns
+	}
+	patterns := readIgnorePatterns(filepath.Join(root, filepath.FromSlash(dir), ".gitignore"))
+	for i := range patterns {
+		patterns[i].base = dir
+	}
+	rules.gitignore[dir] = patterns
+	return patterns
+}
+
+// readIgnorePatterns reads the patterns of an ignore file, one per line,
+// skipping blank lines and lines starting with #.
+func readIgnorePatterns(name string) []ignorePattern {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if p, ok := newIgnorePattern(line); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// ignored reports whether the file or directory at p, under root, is left
+// out: if it or a directory it is in is excluded, the last pattern matching
+// it deciding, or if it is a file that no include glob matches.
+func (rules ignoreRules) ignored(root string, p string, dir bool) bool {
+	if len(rules.patterns) == 0 && len(rules.includes) == 0 && rules.gitignore == nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if rules.excluded(root, strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	if rules.excluded(root, rel, dir) {
+		return true
+	}
+	if dir || len(rules.includes) == 0 {
+		return false
+	}
+	for _, include := range rules.includes {
+		if include.match(rel, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// excluded reports whether the last pattern matching rel excludes it, the
+// patterns of deeper .gitignore files coming after those of shallower ones.
+func (rules ignoreRules) excluded(root string, rel string, dir bool) bool {
+	excluded := false
+	apply := func(patterns []ignorePattern) {
+		for _, p := range patterns {
+			if p.match(rel, dir) {
+				excluded = !p.negate
+			}
+		}
+	}
+	if rules.gitignore != nil {
+		apply(rules.gitignore[""])
+		parts := strings.Split(rel, "/")
+		for i := 1; i < len(parts); i++ {
+			apply(rules.gitignoreIn(root, strings.Join(parts[:i], "/")))
+		}
+	}
+	apply(rules.patterns)
+	return excluded
+}
+
+// standardsDocument returns the standards set with WithStandards, or else
+// the content of .kado/standards.md, which is optional.
+func (c *AIClient) standardsDocument() (string, error) {
+	if c.standards != "" {
+		return c.standards, nil
+	}
+	path := c.projectPath("standards.md")
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
@@ -0,0 +1,328 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProjectConfigMergesOverUserConfig(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	kdconfigPath := filepath.Join(tmpDir, ".kdconfig")
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kdconfig":    "AI_API_KEY=sk-proj-abc\nAI_MODEL=gpt-4\nAI_CLIENT=chatgpt\nAI_CONSENT_POLICY=always-ask\n",
+		".kado/config": "AI_MODEL=gpt-4o\nAI_PROMPT=Focus on cost.\nAI_CONSENT_POLICY=auto-approve\n",
+	})
+
+	client, err := NewAIClient(tmpDir, kdconfigPath)
+	if err != nil {
+		t.Fatalf("NewAIClient failed: %v", err)
+	}
+	if client.model != "gpt-4o" {
+		t.Errorf("Expected the project model gpt-4o, got %s", client.model)
+	}
+	if client.prompt != "Focus on cost." {
+		t.Errorf("Expected the project prompt, got %q", client.prompt)
+	}
+	if client.apiKey != "sk-proj-abc" {
+		t.Errorf("Expected the user API key, got %s", client.apiKey)
+	}
+	if client.consentPolicy != ConsentAlwaysAsk {
+		t.Errorf("Expected the project config not to override the consent policy, got %s", client.consentPolicy)
+	}
+
+	projectOnly, err := NewAIClient(tmpDir, filepath.Join(tmpDir, "missing.kdconfig"))
+	if err == nil || !strings.Contains(err.Error(), "AI_API_KEY") {
+		t.Errorf("Expected a missing user config to fall back to the project config alone, got %v (%v)", err, projectOnly)
+	}
+}
+
+func TestProjectConfigCannotRedirectRequests(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	kdconfigPath := filepath.Join(tmpDir, ".kdconfig")
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kdconfig": "AI_API_KEY=sk-proj-abc\nAI_MODEL=gpt-4\nAI_CLIENT=chatgpt\n",
+		".kado/config": "AI_BASE_URL=https://evil.example\nAI_API_KEY=file:" + filepath.Join(tmpDir, ".kdconfig") + "\n" +
+			"[openai]\nbase_url=https://evil.example\napi_key=env:HOME\n",
+	})
+
+	client, err := NewAIClient(tmpDir, kdconfigPath)
+	if err != nil {
+		t.Fatalf("NewAIClient failed: %v", err)
+	}
+	if client.baseURL != "" {
+		t.Errorf("Expected the project config not to set the base URL, got %s", client.baseURL)
+	}
+	if client.apiKey != "sk-proj-abc" {
+		t.Errorf("Expected the user API key, got %s", client.apiKey)
+	}
+}
+
+func TestProjectConfigAllowlist(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	kdconfigPath := filepath.Join(tmpDir, ".kdconfig")
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kdconfig": "AI_API_KEY=sk-proj-abc\nAI_MODEL=gpt-4\nAI_CLIENT=chatgpt\nAI_SANITIZE_LEVEL=paranoid\n",
+		".kado/config": "ANALYSIS_TYPE=security\nAI_TERRAFORM_ROOTS=infra/tf\nAI_ANSIBLE_ROOTS=/etc/ansible\n" +
+			"AI_SANITIZE_LEVEL=minimal\nAI_INPUT_PATH=/tmp/leak.txt\nAI_UNKNOWN_KEY=1\n",
+	})
+
+	values, err := loadConfig(tmpDir, kdconfigPath)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	expected := map[string]string{
+		"AI_API_KEY":         "sk-proj-abc",
+		"AI_MODEL":           "gpt-4",
+		"AI_CLIENT":          "chatgpt",
+		"AI_SANITIZE_LEVEL":  "paranoid",
+		"ANALYSIS_TYPE":      "security",
+		"AI_TERRAFORM_ROOTS": "infra/tf",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected only the allowed project keys to be merged, got %v", values)
+	}
+}
+
+func TestProjectConfigCannotRunTools(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	kdconfigPath := filepath.Join(tmpDir, ".kdconfig")
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kdconfig": "AI_API_KEY=sk-proj-abc\nAI_MODEL=gpt-4\nAI_CLIENT=chatgpt\n",
+		".kado/config": "AI_TERRAFORM_BINARY=./pwn.sh\nAI_TERRAFORM_PLAN=true\nAI_CDK_SYNTH=true\nAI_PULUMI_PREVIEW=true\n" +
+			"AI_KUBERNETES_RENDER=true\nAI_LINT=true\nAI_INFRACOST=true\nAI_POLICY_EVALUATION=true\n" +
+			"AI_AZURE_RESOURCE_GROUP=rg\nAI_KUBERNETES_CLUSTER=true\nAI_AWS_ACCOUNT_HINTS=true\n",
+	})
+
+	client, err := NewAIClient(tmpDir, kdconfigPath)
+	if err != nil {
+		t.Fatalf("NewAIClient failed: %v", err)
+	}
+	if client.terraformBinary != "" || client.terraformPlan || client.cdkSynth || client.pulumiPreview ||
+		client.kubernetesRender || client.lint || client.infracost || client.policyEvaluation ||
+		client.azureResourceGroup != "" || client.kubernetesCluster || client.awsAccountHints {
+		t.Errorf("Expected the project config not to enable running tools")
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kado/ignore":                   "# generated code\ngenerated/\nterraform/legacy/*.tf\n*.rego\n",
+		"terraform/main.tf":              "resource \"aws_vpc\" \"main\" {}\n",
+		"terraform/legacy/old.tf":        "resource \"aws_instance\" \"old\" {}\n",
+		"terraform/generated/gen.tf":     "resource \"aws_iam_role\" \"gen\" {}\n",
+		"terraform/policies/deny.rego":   "package deny\n",
+		"terraform/generated.tf/keep.tf": "resource \"aws_s3_bucket\" \"keep\" {}\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result := &ScanResult{Root: tmpDir}
+	client.scanDirectory(result, SectionTerraform, filepath.Join(tmpDir, "terraform"), []string{".tf", ".rego"})
+	scanned := result.Content(SectionTerraform)
+	for _, want := range []string{"aws_vpc", "aws_s3_bucket"} {
+		if !strings.Contains(scanned, want) {
+			t.Errorf("Expected %s to be scanned, got %q", want, scanned)
+		}
+	}
+	for _, unwanted := range []string{"aws_instance", "aws_iam_role", "package deny"} {
+		if strings.Contains(scanned, unwanted) {
+			t.Errorf("Expected %s to be ignored, got %q", unwanted, scanned)
+		}
+	}
+}
+
+func TestStandardsDocument(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_instance\" \"app\" {}\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	data, _, err := client.collectPromptData(context.Background(), false)
+	if err != nil {
+		t.Fatalf("collectPromptData failed: %v", err)
+	}
+	if data.Standards != "" {
+		t.Errorf("Expected no standards without .kado/standards.md, got %q", data.Standards)
+	}
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kado/standards.md": "# Standards\n\n- Only t3 instance types are approved.\n- api_key = \"abc123\" is the shared key.\n",
+	})
+	data, _, err = client.collectPromptData(context.Background(), false)
+	if err != nil {
+		t.Fatalf("collectPromptData failed: %v", err)
+	}
+	if !strings.Contains(data.Standards, "Only t3 instance types") || strings.Contains(data.Standards, "abc123") {
+		t.Errorf("Expected the sanitized standards document, got %q", data.Standards)
+	}
+
+	client.standards = "Tag everything."
+	if data, _, _ = client.collectPromptData(context.Background(), false); data.Standards != "Tag everything." {
+		t.Errorf("Expected WithStandards to replace the document, got %q", data.Standards)
+	}
+}
+
+func TestIgnorePatterns(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		path     string
+		dir      bool
+		expected bool
+	}{
+		{"*.rego", "terraform/policies/deny.rego", false, true},
+		{"generated/", "terraform/generated", true, true},
+		{"generated/", "terraform/generated", false, false},
+		{"terraform/legacy/*.tf", "terraform/legacy/old.tf", false, true},
+		{"terraform/legacy/*.tf", "terraform/legacy/sub/old.tf", false, false},
+		{"/examples", "examples", true, true},
+		{"/examples", "modules/vpc/examples", true, false},
+		{"**/testdata/", "modules/vpc/testdata", true, true},
+		{"**/testdata/", "testdata", true, true},
+		{"modules/**/*.tf", "modules/vpc/nested/main.tf", false, true},
+		{"modules/**", "modules/vpc/main.tf", false, true},
+		{"vendor/**", "vendor", true, true},
+		{"?.tf", "a.tf", false, true},
+		{"[abc].tf", "d.tf", false, false},
+		{"[!abc].tf", "d.tf", false, true},
+	}
+	for _, tc := range testCases {
+		p, ok := newIgnorePattern(tc.pattern)
+		if !ok {
+			t.Fatalf("Expected %q to be a valid pattern", tc.pattern)
+		}
+		if got := p.match(tc.path, tc.dir); got != tc.expected {
+			t.Errorf("Expected %q matching %q (dir: %v) to be %v, got %v", tc.pattern, tc.path, tc.dir, tc.expected, got)
+		}
+	}
+}
+
+func TestKadoIgnoreAndGlobs(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kadoignore":                            "# fixtures and examples\n**/testdata/\nexamples/\n*.tf\n!main.tf\n!network.tf\n",
+		"terraform/main.tf":                      "resource \"aws_vpc\" \"main\" {}\n",
+		"terraform/network.tf":                   "resource \"aws_subnet\" \"a\" {}\n",
+		"terraform/outputs.tf":                   "output \"vpc\" {}\n",
+		"terraform/examples/main.tf":             "resource \"aws_instance\" \"example\" {}\n",
+		"terraform/modules/vpc/testdata/main.tf": "resource \"aws_instance\" \"fixture\" {}\n",
+		"terraform/.terraform/modules/x/main.tf": "resource \"aws_instance\" \"cached\" {}\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var paths []string
+	for _, f := range result.Section(SectionTerraform) {
+		paths = append(paths, filepath.ToSlash(f.Path))
+	}
+	if got := strings.Join(paths, ","); got != "terraform/main.tf,terraform/network.tf" {
+		t.Errorf("Expected the .kadoignore patterns, negations, and the .terraform cache to be applied, got %s", got)
+	}
+
+	client.includeGlobs = []string{"terraform/*.tf"}
+	client.excludeGlobs = []string{"network.tf"}
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	paths = nil
+	for _, f := range result.Section(SectionTerraform) {
+		paths = append(paths, filepath.ToSlash(f.Path))
+	}
+	if got := strings.Join(paths, ","); got != "terraform/main.tf" {
+		t.Errorf("Expected the include and exclude globs to be applied, got %s", got)
+	}
+	skipped := map[string]string{}
+	for _, s := range result.Skipped {
+		skipped[filepath.ToSlash(s.Path)] = s.Reason
+	}
+	if skipped["terraform/network.tf"] != ignoredReason {
+		t.Errorf("Expected the excluded file to be reported as skipped, got %v", skipped)
+	}
+}
+
+func TestGitignore(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".git/HEAD":                          "ref: refs/heads/main\n",
+		".gitignore":                         "infra/terraform/vendor/\n*.generated.tf\n",
+		"infra/.gitignore":                   "terraform/scratch.tf\n",
+		"infra/terraform/.gitignore":         "!keep.generated.tf\n",
+		"infra/terraform/main.tf":            "resource \"aws_vpc\" \"main\" {}\n",
+		"infra/terraform/scratch.tf":         "resource \"aws_instance\" \"scratch\" {}\n",
+		"infra/terraform/drop.generated.tf":  "resource \"aws_instance\" \"drop\" {}\n",
+		"infra/terraform/keep.generated.tf":  "resource \"aws_instance\" \"keep\" {}\n",
+		"infra/terraform/vendor/mod/main.tf": "resource \"aws_instance\" \"vendored\" {}\n",
+	})
+
+	scan := func(client *AIClient) string {
+		result, err := client.Scan(context.Background())
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		var paths []string
+		for _, f := range result.Section(SectionTerraform) {
+			paths = append(paths, filepath.ToSlash(f.Path))
+		}
+		return strings.Join(paths, ",")
+	}
+
+	// The IaC path is a subdirectory of the repository, so the .gitignore
+	// at the repository root applies with paths relative to it.
+	client := &AIClient{iacPath: filepath.Join(tmpDir, "infra")}
+	if got := scan(client); got != "terraform/keep.generated.tf,terraform/main.tf" {
+		t.Errorf("Expected the .gitignore files of the repository to be respected, got %s", got)
+	}
+
+	WithGitignore(false)(client)
+	if got := scan(client); got != "terraform/drop.generated.tf,terraform/keep.generated.tf,terraform/main.tf,terraform/scratch.tf,terraform/vendor/mod/main.tf" {
+		t.Errorf("Expected .gitignore to be disregarded when disabled, got %s", got)
+	}
+}
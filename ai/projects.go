@@ -0,0 +1,218 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kinds of the projects of a monorepo.
+const (
+	ProjectTerraform = "terraform"
+	ProjectAnsible   = "ansible"
+	// ProjectOther holds the files of the IaC path that belong to no
+	// project, such as Kubernetes manifests or policies.
+	ProjectOther = "other"
+)
+
+// Project is an independent project found in a monorepo: a Terraform root
+// module, or an Ansible project. Path is its directory relative to the IaC
+// path. Files are the paths of the files it is made of: those in its
+// directory and not in a project nested in it, and for a Terraform root
+// module, those of the local modules it calls, directly or not.
+type Project struct {
+	Path  string   `json:"path"`
+	Kind  string   `json:"kind"`
+	Files []string `json:"files"`
+}
+
+// findProjects finds the projects of the code in result. A directory of
+// Terraform code is a root module if it configures a backend or HCP
+// Terraform, or has a terraform or provider block and is not called as a
+// module by another directory. A directory with an ansible.cfg is an
+// Ansible project. Files in no project are gathered in one of ProjectOther.
+func findProjects(result *ScanResult) []Project {
+	roots := map[string]string{}
+	configured := map[string]bool{}
+	for _, f := range result.Section(SectionTerraform) {
+		if filepath.Ext(f.Path) != ".tf" {
+			continue
+		}
+		dir := filepath.Dir(f.Path)
+		items := parseHCL(f.Content)
+		for _, terraform := range hclFind(items, "terraform") {
+			configured[dir] = true
+			if len(hclFind(terraform.Body, "backend")) > 0 || len(hclFind(terraform.Body, "cloud")) > 0 {
+				roots[dir] = ProjectTerraform
+			}
+		}
+		if len(hclFind(items, "provider")) > 0 {
+			configured[dir] = true
+		}
+	}
+	calls := localModuleCalls(result.Graph)
+	called := map[string]bool{}
+	for _, modules := range calls {
+		for _, module := range modules {
+			called[module] = true
+		}
+	}
+	for dir := range configured {
+		if !called[dir] {
+			roots[dir] = ProjectTerraform
+		}
+	}
+
+	checked := map[string]bool{}
+	for _, f := range result.Section(SectionAnsible) {
+		for dir := filepath.Dir(f.Path); !checked[dir]; dir = filepath.Dir(dir) {
+			checked[dir] = true
+			if _, err := os.Stat(filepath.Join(result.Root, dir, "ansible.cfg")); err == nil {
+				roots[dir] = ProjectAnsible
+			}
+			if dir == "." {
+				break
+			}
+		}
+	}
+	if len(roots) == 0 {
+		return nil
+	}
+
+	var projects []Project
+	for dir, kind := range roots {
+		projects = append(projects, Project{Path: dir, Kind: kind})
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Path < projects[j].Path })
+
+	// The modules each Terraform root module calls, directly or not.
+	modules := map[string][]string{}
+	shared := map[string]bool{}
+	for _, p := range projects {
+		if p.Kind == ProjectTerraform {
+			modules[p.Path] = moduleClosure(calls, p.Path)
+			for _, dir := range modules[p.Path] {
+				shared[dir] = true
+			}
+		}
+	}
+
+	var other []string
+	for _, f := range result.Files {
+		owner := -1
+		for i, p := range projects {
+			if underProject(f.Path, p.Path) && (owner < 0 || len(p.Path) > len(projects[owner].Path)) {
+				owner = i
+			}
+		}
+		if owner >= 0 {
+			projects[owner].Files = append(projects[owner].Files, f.Path)
+		}
+		dir := filepath.Dir(f.Path)
+		for i, p := range projects {
+			if i != owner && containsString(modules[p.Path], dir) {
+				projects[i].Files = append(projects[i].Files, f.Path)
+			}
+		}
+		if owner < 0 && !shared[dir] {
+			other = append(other, f.Path)
+		}
+	}
+	if len(other) > 0 {
+		projects = append(projects, Project{Path: ".", Kind: ProjectOther, Files: other})
+	}
+	return projects
+}
+
+// localModuleCalls returns the directories of the local modules each
+// directory of graph calls.
+func localModuleCalls(graph *ModuleGraph) map[string][]string {
+	calls := map[string][]string{}
+	if graph == nil {
+		return calls
+	}
+	kinds := map[string]string{}
+	for _, node := range graph.Nodes {
+		kinds[node.ID] = node.Kind
+	}
+	for _, edge := range graph.Edges {
+		if edge.Kind == GraphEdgeModule && kinds[edge.To] == GraphNodeModule {
+			calls[edge.From] = append(calls[edge.From], edge.To)
+		}
+	}
+	return calls
+}
+
+// moduleClosure returns the directories of the local modules dir calls,
+// directly or through other modules.
+func moduleClosure(calls map[string][]string, dir string) []string {
+	var closure []string
+	seen := map[string]bool{dir: true}
+	queue := []string{dir}
+	for len(queue) > 0 {
+		for _, module := range calls[queue[0]] {
+			if !seen[module] {
+				seen[module] = true
+				closure = append(closure, module)
+				queue = append(queue, module)
+			}
+		}
+		queue = queue[1:]
+	}
+	return closure
+}
+
+// underProject reports whether path is in the project directory dir.
+func underProject(path string, dir string) bool {
+	return dir == "." || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// forProject returns the part of scan made of the files of p.
+func (r *ScanResult) forProject(p Project) *ScanResult {
+	files := map[string]bool{}
+	for _, path := range p.Files {
+		files[path] = true
+	}
+	part := &ScanResult{Root: r.Root}
+	for _, f := range r.Files {
+		if files[f.Path] {
+			part.Files = append(part.Files, f)
+		}
+	}
+	return part
+}
+
+// projectPrompt renders a prompt for each project of scan, to be analyzed
+// separately and reported on under its own heading after a roll-up of the
+// projects. Like perPathPrompt, it returns nil if any of them does not fit
+// in the context window.
+func (c *AIClient) projectPrompt(template string, question string, scan *ScanResult) (*preparedPrompt, error) {
+	var headings []string
+	var parts []*ScanResult
+	for _, project := range scan.Projects {
+		headings = append(headings, project.heading())
+		parts = append(parts, scan.forProject(project))
+	}
+	p, err := c.partsPrompt(template, question, headings, parts, "projects")
+	if p == nil || err != nil {
+		return p, err
+	}
+
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "## Projects\n\n| Project | Type | Files | Size |\n| --- | --- | --- | --- |\n")
+	for i, project := range scan.Projects {
+		fmt.Fprintf(&summary, "| %s | %s | %d | %d bytes |\n", project.Path, project.Kind, len(parts[i].Files), parts[i].TotalSize())
+	}
+	p.summary = summary.String()
+	return p, nil
+}
+
+// heading returns the heading of the report of the project.
+func (p Project) heading() string {
+	if p.Kind == ProjectOther {
+		return "Other files"
+	}
+	return fmt.Sprintf("%s (%s)", p.Path, p.Kind)
+}
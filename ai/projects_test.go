@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var monorepoFiles = map[string]string{
+	"envs/prod/main.tf":        "terraform {\n  backend \"s3\" {\n    bucket = \"state\"\n    key    = \"prod\"\n  }\n}\n\nmodule \"vpc\" {\n  source = \"../../modules/vpc\"\n}\n",
+	"envs/dev/main.tf":         "provider \"aws\" {}\n\nmodule \"vpc\" {\n  source = \"../../modules/vpc\"\n}\n",
+	"modules/vpc/main.tf":      "terraform {\n  required_providers {}\n}\n\nmodule \"subnets\" {\n  source = \"../subnets\"\n}\n",
+	"modules/subnets/main.tf":  "resource \"aws_subnet\" \"a\" {}\n",
+	"ansible/site/ansible.cfg": "[defaults]\n",
+	"ansible/site/site.yml":    "- hosts: web\n  roles:\n    - nginx\n",
+	"policies/deny.rego":       "package terraform\n",
+}
+
+func TestScanFindsProjects(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	writeTestFiles(t, tmpDir, monorepoFiles)
+
+	client := &AIClient{iacPath: tmpDir, projects: true}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	expected := []Project{
+		{Path: filepath.Join("ansible", "site"), Kind: ProjectAnsible, Files: []string{filepath.Join("ansible", "site", "site.yml")}},
+		{Path: filepath.Join("envs", "dev"), Kind: ProjectTerraform, Files: []string{
+			filepath.Join("envs", "dev", "main.tf"),
+			filepath.Join("modules", "subnets", "main.tf"),
+			filepath.Join("modules", "vpc", "main.tf"),
+		}},
+		{Path: filepath.Join("envs", "prod"), Kind: ProjectTerraform, Files: []string{
+			filepath.Join("envs", "prod", "main.tf"),
+			filepath.Join("modules", "subnets", "main.tf"),
+			filepath.Join("modules", "vpc", "main.tf"),
+		}},
+		{Path: ".", Kind: ProjectOther, Files: []string{filepath.Join("policies", "deny.rego")}},
+	}
+	for i := range result.Projects {
+		sort.Strings(result.Projects[i].Files)
+	}
+	if !reflect.DeepEqual(result.Projects, expected) {
+		t.Errorf("Expected projects %+v, got %+v", expected, result.Projects)
+	}
+}
+
+func TestRunAIReportsPerProject(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	writeTestFiles(t, tmpDir, monorepoFiles)
+
+	var mu sync.Mutex
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		prompts = append(prompts, body.Messages[0].Content)
+		mu.Unlock()
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Looks fine."}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+		WithProjectReports(true),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+	report, err := client.RunAI()
+	if err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+
+	if len(prompts) != 4 {
+		t.Fatalf("Expected a request per project, got %d", len(prompts))
+	}
+	for _, prompt := range prompts {
+		if strings.Contains(prompt, "envs/prod") && strings.Contains(prompt, "envs/dev") {
+			t.Errorf("Expected each project analyzed on its own, got %q", prompt)
+		}
+	}
+	if !strings.HasPrefix(report, "## Projects\n\n| Project | Type | Files | Size |") {
+		t.Errorf("Expected the report to start with the roll-up, got %q", report)
+	}
+	for _, heading := range []string{"## ansible/site (ansible)", "## envs/dev (terraform)", "## envs/prod (terraform)", "## Other files"} {
+		if !strings.Contains(report, heading+"\n\nLooks fine.") {
+			t.Errorf("Expected a section %q, got %q", heading, report)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package ai
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// defaultPrompts holds the built-in prompt templates, one prompts/<name>.tmpl
+// file per template. A project can override any of them with a file of the
+// same name in .kado/prompts. prompts/library holds the question templates
+// library, and prompts/partials the templates every prompt can call.
+//
+//go:embed prompts/*.tmpl prompts/library/*.tmpl prompts/partials/*.tmpl
+var defaultPrompts embed.FS
+
+// promptData holds everything a prompt template can reference. All code is
+// expected to be sanitized before it is placed here.
+type promptData struct {
+	Instruction   string
+	TerraformCode string
+	AnsibleCode   string
+	TerraformPlan string
+	CDKCode       string
+	CDKTemplates  string
+	// TerraformValidation is the errors and warnings terraform validate
+	// reported when kado-ai runs terraform.
+	TerraformValidation string
+	// PolicyResults is the failures and warnings of the Rego policies of
+	// the IaC path, evaluated against each plan with conftest.
+	PolicyResults string
+	// PolicyCoverage is the table of the Terraform resource types and the
+	// Rego policies that reference each.
+	PolicyCoverage string
+	// ScannerFindings is the findings of static analysis scanners, such as
+	// tfsec, Trivy, and Checkov.
+	ScannerFindings string
+	// Costs is the cost estimate of Infracost for each Terraform root.
+	Costs string
+	// LintFindings is the findings of tflint and ansible-lint.
+	LintFindings string
+	// Drift is the resources that drifted from the Terraform state.
+	Drift string
+	// Versions is the upgrade matrix of the providers and modules.
+	Versions string
+	// AnsibleProject is the structure of the Ansible code: the plays of each
+	// playbook, the roles and the groups they are applied to, the
+	// inventories, and the variables of each group and host.
+	AnsibleProject string
+	// AnsibleCheck is the output of ansible-playbook --check --diff,
+	// condensed to the tasks that would change or fail.
+	AnsibleCheck string
+	// TerragruntCode is the Terragrunt configuration, and TerragruntStacks
+	// the effective configuration of each stack.
+	TerragruntCode   string
+	TerragruntStacks string
+	// BicepCode is the Bicep files and ARM templates, and AzureWhatIf the
+	// output of what-if.
+	BicepCode   string
+	AzureWhatIf string
+	// PulumiCode is the program and stack configuration of Pulumi projects,
+	// and PulumiPreview the output of pulumi preview --json.
+	PulumiCode    string
+	PulumiPreview string
+	// KubernetesManifests are the Kubernetes manifests in the repository,
+	// and KubernetesRendered the output of Helm and Kustomize.
+	KubernetesManifests string
+	KubernetesRendered  string
+	// KubernetesCluster is the snapshot of the objects running in the live
+	// cluster.
+	KubernetesCluster string
+	// AWSAccount is the account-level settings of the AWS account.
+	AWSAccount string
+	// Crossplane is the manifests of Crossplane XRDs, Compositions,
+	// composite resources, and claims.
+	Crossplane string
+	// DockerFiles are the Dockerfiles and Compose files.
+	DockerFiles string
+	// PackerTemplates, NomadJobs, and Vagrantfiles are the image builds,
+	// scheduler jobs, and development machines of the other HashiCorp tools.
+	PackerTemplates string
+	NomadJobs       string
+	Vagrantfiles    string
+	// ModuleGraph lists the module calls and remote state references of
+	// each directory of Terraform code.
+	ModuleGraph string
+	// Pipelines are the CI/CD pipeline definitions.
+	Pipelines string
+	// TerraformState is the resource inventory of the Terraform state
+	// files, without attribute values.
+	TerraformState string
+	// Diff is the diff of a diff-scoped analysis, which the
+	// recommendations focus on.
+	Diff string
+	// Omitted lists the files left out for the total size limit.
+	Omitted string
+	// Standards is the team's standards document, such as naming,
+	// tagging, and approved instance types.
+	Standards string
+	// Context is free-form text supplied by the user, such as conventions
+	// of their stack that the model should take into account.
+	Context string
+	// Guidance closes the prompt with what the analysis should focus on.
+	Guidance string
+	// Question is a free-form question the user wants answered.
+	Question string
+
+	// FilePath, StartLine, EndLine, and Selection describe the code a
+	// review focuses on. StartLine and EndLine are 1-based and zero when the
+	// whole file is selected.
+	FilePath  string
+	StartLine int
+	EndLine   int
+	Selection string
+
+	// UncoveredResources are the addresses of the Terraform resources whose
+	// type no Rego policy mentions.
+	UncoveredResources []string
+
+	// Reports are the partial reports of a chunked analysis, merged by the
+	// reduce template.
+	Reports []string
+}
+
+// promptFuncs are the functions available to prompt templates.
+var promptFuncs = template.FuncMap{
+	"numbered":   numberLines,
+	"categories": categoryList,
+	"severities": severityList,
+	"sectionArgs": func(data promptData, numbered bool) sectionArgs {
+		return sectionArgs{data, numbered}
+	},
+}
+
+// sectionArgs is the argument of the sections partial: the prompt data and
+// whether to prefix every line of code with its line number.
+type sectionArgs struct {
+	promptData
+	Numbered bool
+}
+
+// renderPrompt renders the built-in template name.
+func renderPrompt(name string, data promptData) (string, error) {
+	text, err := defaultPrompts.ReadFile(path.Join("prompts", name+".tmpl"))
+	if err != nil {
+		return "", fmt.Errorf("unknown prompt template %q", name)
+	}
+	return executePrompt(name, string(text), data)
+}
+
+// renderPrompt renders the template name, preferring the project's
+// .kado/prompts/<name>.tmpl over the built-in one.
+func (c *AIClient) renderPrompt(name string, data promptData) (string, error) {
+	text, _, err := c.promptTemplate(name)
+	if err != nil {
+		return "", err
+	}
+	return executePrompt(name, text, data)
+}
+
+// promptTemplate returns the text of the template name and where it came
+// from: the path of the project's override, or "built-in".
+func (c *AIClient) promptTemplate(name string) (string, string, error) {
+	overridePath := c.projectPath("prompts", name+".tmpl")
+	text, err := os.ReadFile(overridePath)
+	if err == nil {
+		return string(text), overridePath, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read prompt template %s: %v", name, err)
+	}
+	text, err = defaultPrompts.ReadFile(path.Join("prompts", name+".tmpl"))
+	if err != nil {
+		return "", "", fmt.Errorf("unknown prompt template %q", name)
+	}
+	return string(text), "built-in", nil
+}
+
+// executePrompt renders text with the partials parsed first, so that a
+// project's template can call them or redefine them.
+func executePrompt(name string, text string, data promptData) (string, error) {
+	tmpl := template.New(name).Option("missingkey=error").Funcs(promptFuncs)
+	partials, _ := defaultPrompts.ReadDir(path.Join("prompts", "partials"))
+	for _, entry := range partials {
+		partial, _ := defaultPrompts.ReadFile(path.Join("prompts", "partials", entry.Name()))
+		if _, err := tmpl.Parse(string(partial)); err != nil {
+			return "", fmt.Errorf("failed to parse prompt partial %s: %v", entry.Name(), err)
+		}
+	}
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt template %s: %v", name, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %v", name, err)
+	}
+	return out.String(), nil
+}
+
+// promptTemplateNames returns the built-in template names in a stable order.
+func promptTemplateNames() []string {
+	entries, _ := defaultPrompts.ReadDir("prompts")
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".tmpl"))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,149 @@
+package ai
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateSnapshots = flag.Bool("update", false, "rewrite prompt snapshots in testdata/snapshots")
+
+// assertSnapshot compares got with the snapshot stored under
+// testdata/snapshots/name, rewriting it instead when -update is set.
+func assertSnapshot(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "snapshots", name)
+	if *updateSnapshots {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("Failed to update snapshot %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot %s (run 'go test ./ai -run TestPromptSnapshots -update' to create it): %v", path, err)
+	}
+	if got != string(want) {
+		t.Errorf("Rendered prompt does not match snapshot %s; if the change is intended, rerun with -update.\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+func TestPromptSnapshots(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "prompts", "*.json"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("Failed to find prompt fixtures: %v", err)
+	}
+
+	for _, name := range promptTemplateNames() {
+		for _, fixture := range fixtures {
+			raw, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatalf("Failed to read fixture %s: %v", fixture, err)
+			}
+			var data promptData
+			if err := json.Unmarshal(raw, &data); err != nil {
+				t.Fatalf("Failed to parse fixture %s: %v", fixture, err)
+			}
+
+			got, err := renderPrompt(name, data)
+			if err != nil {
+				t.Fatalf("Failed to render template %s with %s: %v", name, fixture, err)
+			}
+			fixtureName := strings.TrimSuffix(filepath.Base(fixture), ".json")
+			assertSnapshot(t, name+"_"+fixtureName+".golden", got)
+		}
+	}
+
+	// Library templates render into the question of an analysis, without
+	// prompt data.
+	client := &AIClient{}
+	library, err := defaultPrompts.ReadDir(path.Join("prompts", libraryDir))
+	if err != nil || len(library) == 0 {
+		t.Fatalf("Failed to find library templates: %v", err)
+	}
+	for _, entry := range library {
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		got, err := client.templateQuestion(name)
+		if err != nil {
+			t.Fatalf("Failed to render library template %s: %v", name, err)
+		}
+		assertSnapshot(t, "library_"+name+".golden", got)
+	}
+}
+
+func TestProjectPromptOverride(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	client := &AIClient{iacPath: tmpDir}
+	data := promptData{Instruction: "Review this:", TerraformCode: "resource {}", Context: "We use Karpenter."}
+
+	got, err := client.renderPrompt("analyze", data)
+	if err != nil {
+		t.Fatalf("renderPrompt failed: %v", err)
+	}
+	if want, _ := renderPrompt("analyze", data); got != want {
+		t.Errorf("Expected the built-in template without an override, got %q", got)
+	}
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kado/prompts/analyze.tmpl": "{{.Instruction}} Our stack: {{.Context}}\n{{.TerraformCode}}",
+	})
+	got, err = client.renderPrompt("analyze", data)
+	if err != nil {
+		t.Fatalf("renderPrompt failed: %v", err)
+	}
+	if got != "Review this: Our stack: We use Karpenter.\nresource {}" {
+		t.Errorf("Expected the project template to be used, got %q", got)
+	}
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kado/prompts/analyze.tmpl": "{{.TerraformCod}}",
+	})
+	if _, err := client.renderPrompt("analyze", data); err == nil {
+		t.Errorf("Expected an error for a template referencing an unknown field")
+	}
+}
+
+func TestPromptSections(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	data := promptData{CDKCode: "File: app.ts\nnew Bucket(this, 'Logs');\n", Context: "We use Karpenter."}
+	analyze, err := renderPrompt("analyze", data)
+	if err != nil {
+		t.Fatalf("renderPrompt failed: %v", err)
+	}
+	findings, err := renderPrompt("findings", data)
+	if err != nil {
+		t.Fatalf("renderPrompt failed: %v", err)
+	}
+	if !strings.Contains(analyze, "CDK Application Code:\nFile: app.ts\nnew Bucket") {
+		t.Errorf("Expected the CDK code without line numbers, got:\n%s", analyze)
+	}
+	if !strings.Contains(findings, "CDK Application Code:\nFile: app.ts\n1: new Bucket") {
+		t.Errorf("Expected the CDK code with line numbers, got:\n%s", findings)
+	}
+
+	client := &AIClient{iacPath: tmpDir}
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kado/prompts/analyze.tmpl": "Review this:{{template \"sections\" (sectionArgs . false)}}",
+	})
+	got, err := client.renderPrompt("analyze", data)
+	if err != nil {
+		t.Fatalf("renderPrompt failed: %v", err)
+	}
+	if !strings.Contains(got, "Additional Context:\nWe use Karpenter.") {
+		t.Errorf("Expected the project template to render the sections, got %q", got)
+	}
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sections of Pulumi content: the program and stack configuration of a
+// project, and the output of pulumi preview --json, Pulumi's counterpart of
+// a Terraform plan.
+const (
+	SectionPulumi        = "pulumi code"
+	SectionPulumiPreview = "pulumi preview"
+)
+
+// pulumiPreviewFile is the name a saved preview is read from, and the name
+// a preview run by kado-ai is presented under.
+const pulumiPreviewFile = "pulumi-preview.json"
+
+// pulumiExtensions are the extensions of the program files of the Pulumi
+// runtimes: Node.js, Python, Go, .NET, and Java. YAML programs are in
+// Pulumi.yaml itself.
+var pulumiExtensions = []string{".ts", ".js", ".py", ".go", ".cs", ".fs", ".vb", ".java"}
+
+// pulumiSkipDirs are never descended into while collecting program files:
+// dependencies and build output.
+var pulumiSkipDirs = map[string]bool{
+	"bin":    true,
+	"obj":    true,
+	"target": true,
+	"vendor": true,
+}
+
+// pulumiProject is a directory with a Pulumi.yaml, and the directory of its
+// program, which the main setting can move.
+type pulumiProject struct {
+	dir  string
+	main string
+}
+
+func findPulumiProjects(root string) ([]pulumiProject, error) {
+	var projects []pulumiProject
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && discoverySkipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() != "Pulumi.yaml" && info.Name() != "Pulumi.yml" {
+			return nil
+		}
+		project := pulumiProject{dir: filepath.Dir(path), main: filepath.Dir(path)}
+		if data, err := os.ReadFile(path); err == nil {
+			var settings struct {
+				Main string `yaml:"main"`
+			}
+			if yaml.Unmarshal(data, &settings) == nil && settings.Main != "" {
+				main := filepath.Join(project.dir, settings.Main)
+				if info, err := os.Stat(main); err == nil && info.IsDir() {
+					project.main = main
+				}
+			}
+		}
+		projects = append(projects, project)
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return projects, err
+}
+
+// isPulumiSettings reports whether name is a project file, Pulumi.yaml, or
+// a stack configuration file, such as Pulumi.prod.yaml.
+func isPulumiSettings(name string) bool {
+	return strings.HasPrefix(name, "Pulumi.") && hasExtension(name, []string{".yaml", ".yml"})
+}
+
+// scanPulumiSources collects the project and stack files of a Pulumi
+// project and its program files, skipping type declarations and dependency
+// and build directories.
+func (c *AIClient) scanPulumiSources(result *ScanResult, project pulumiProject) {
+	ignore := c.ignoreRules()
+	collected := map[string]bool{}
+	for _, dir := range []string{project.dir, project.main} {
+		c.walk(result, dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.IsDir() {
+				if path != dir && (cdkSkipDirs[info.Name()] || pulumiSkipDirs[info.Name()]) {
+					return filepath.SkipDir
+				}
+				if path != dir && ignore.ignored(c.iacPath, path, true) {
+					result.skip(path, ignoredReason)
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			name := info.Name()
+			if collected[path] || !(isPulumiSettings(name) || hasExtension(name, pulumiExtensions)) || strings.HasSuffix(name, ".d.ts") {
+				return nil
+			}
+			collected[path] = true
+			if ignore.ignored(c.iacPath, path, false) {
+				result.skip(path, ignoredReason)
+				return nil
+			}
+			c.addFile(result, SectionPulumi, path)
+			return nil
+		})
+	}
+}
+
+// scanPulumi adds the Pulumi projects under the IaC path to result, with
+// the preview of each: the output of pulumi preview --json when previews
+// are enabled, and otherwise a pulumi-preview.json saved in the project.
+func (c *AIClient) scanPulumi(ctx context.Context, result *ScanResult) error {
+	projects, err := findPulumiProjects(c.iacPath)
+	if err != nil {
+		return fmt.Errorf("failed to look for Pulumi projects: %v", err)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].dir < projects[j].dir })
+
+	for _, project := range projects {
+		c.scanPulumiSources(result, project)
+
+		path := filepath.Join(project.dir, pulumiPreviewFile)
+		if !c.pulumiPreview {
+			c.addFile(result, SectionPulumiPreview, path)
+			continue
+		}
+		out, err := runCommand(ctx, project.dir, nil, "pulumi", "preview", "--json", "--non-interactive")
+		if err != nil {
+			fmt.Fprintf(c.messages(), "Warning: failed to preview Pulumi project %s: %v\n", project.dir, err)
+			continue
+		}
+		addOutput(result, SectionPulumiPreview, path, string(out))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanPulumi(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"infra/Pulumi.yaml":                       "name: app\nruntime: nodejs\n",
+		"infra/Pulumi.prod.yaml":                  "config:\n  aws:region: us-east-1\n",
+		"infra/index.ts":                          "new aws.s3.Bucket(\"logs\");\n",
+		"infra/index.d.ts":                        "export {};\n",
+		"infra/node_modules/@pulumi/aws/index.ts": "export {};\n",
+		"infra/pulumi-preview.json":               `{"steps":[{"op":"create"}]}`,
+		"service/Pulumi.yaml":                     "name: service\nruntime: python\nmain: src/\n",
+		"service/src/__main__.py":                 "import pulumi\n",
+		"service/src/bin/tool.py":                 "print()\n",
+		"service/README.md":                       "# service\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var code []string
+	for _, f := range result.Section(SectionPulumi) {
+		code = append(code, filepath.ToSlash(f.Path))
+	}
+	expected := "infra/Pulumi.prod.yaml,infra/Pulumi.yaml,infra/index.ts,service/Pulumi.yaml,service/src/__main__.py"
+	if got := strings.Join(code, ","); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	preview := result.Section(SectionPulumiPreview)
+	if len(preview) != 1 || preview[0].Path != filepath.Join("infra", "pulumi-preview.json") {
+		t.Fatalf("Expected the saved preview to be collected, got %+v", preview)
+	}
+
+	var calls []string
+	original := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		rel, _ := filepath.Rel(tmpDir, dir)
+		calls = append(calls, filepath.ToSlash(rel)+": "+name+" "+strings.Join(args, " "))
+		return []byte(`{"steps":[{"op":"update","urn":"` + rel + `"}]}`), nil
+	}
+	defer func() { runCommand = original }()
+
+	client.pulumiPreview = true
+	result, err = client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if got := strings.Join(calls, "; "); got != "infra: pulumi preview --json --non-interactive; service: pulumi preview --json --non-interactive" {
+		t.Errorf("Expected a preview of every project, got %s", got)
+	}
+	preview = result.Section(SectionPulumiPreview)
+	if len(preview) != 2 || !strings.Contains(preview[0].Content, `"op":"update"`) || preview[1].Path != filepath.Join("service", "pulumi-preview.json") {
+		t.Fatalf("Expected the output of the previews, got %+v", preview)
+	}
+
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.PulumiCode, "aws.s3.Bucket") || !strings.Contains(data.PulumiPreview, `"op":"update"`) {
+		t.Errorf("Expected the Pulumi code and preview in the prompt data, got %q and %q", data.PulumiCode, data.PulumiPreview)
+	}
+}
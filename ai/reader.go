@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The kinds of input AnalyzeReader accepts.
+const (
+	// InputTerraform is a Terraform file.
+	InputTerraform = "terraform"
+	// InputPlan is a Terraform plan in JSON format, as printed by
+	// terraform show -json.
+	InputPlan = "plan"
+	// InputDiff is a unified diff, as printed by git diff.
+	InputDiff = "diff"
+	// InputAnsible is an Ansible playbook or task list.
+	InputAnsible = "ansible"
+)
+
+// readerFiles maps the kinds of input that are files to their section and
+// the name the prompt gives them.
+var readerFiles = map[string]struct{ section, name string }{
+	InputTerraform: {SectionTerraform, "stdin.tf"},
+	InputPlan:      {SectionTerraformPlan, "plan.json"},
+	InputAnsible:   {SectionAnsible, "stdin.yml"},
+}
+
+// AnalyzeReader analyzes a single Terraform file, plan, Ansible playbook,
+// or diff read from r, such as the standard input, for a quick review
+// without the IaC path's layout. Kind is one of InputTerraform, InputPlan,
+// InputDiff, and InputAnsible. The input is sanitized, saved, and sent
+// after consent like the code of RunAI, and nothing under the IaC path is
+// scanned.
+func (c *AIClient) AnalyzeReader(r io.Reader, kind string) (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %v", err)
+	}
+	scan, err := c.readerScan(context.Background(), string(data), kind)
+	if err != nil {
+		return "", err
+	}
+	p, err := c.prepareScan(context.Background(), scan, "analyze", c.question, true)
+	if err != nil {
+		return "", err
+	}
+	return c.send("analyze", c.question, p)
+}
+
+// readerScan returns a scan made of content, of the given kind, with the
+// size limits of the scan of the IaC path.
+func (c *AIClient) readerScan(ctx context.Context, content string, kind string) (*ScanResult, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("input must not be empty")
+	}
+	result := &ScanResult{Root: c.iacPath}
+	if kind == InputDiff {
+		result.Diff = content
+		return result, nil
+	}
+	file, ok := readerFiles[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown input kind %q; expected %s, %s, %s, or %s", kind, InputTerraform, InputPlan, InputDiff, InputAnsible)
+	}
+	if isBinary(file.name, content, nil) {
+		return nil, fmt.Errorf("input is binary; a plan must be converted with terraform show -json")
+	}
+	addOutput(result, file.section, file.name, content)
+	buildModuleGraph(result)
+	buildAnsibleProject(result)
+	if c.planFormat == PlanFormatSummary {
+		summarizePlans(result)
+	}
+	c.limitSizes(ctx, result, c.question)
+	return result, nil
+}
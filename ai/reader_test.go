@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeReader(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Code under the IaC path is not scanned.
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_vpc\" \"scanned\" {}\n",
+	})
+
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Block public access."}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	answer, err := client.AnalyzeReader(strings.NewReader("resource \"aws_s3_bucket\" \"logs\" {}\n"), InputTerraform)
+	if err != nil {
+		t.Fatalf("AnalyzeReader failed: %v", err)
+	}
+	if answer != "Block public access." {
+		t.Errorf("Expected the provider's answer, got %q", answer)
+	}
+	if !strings.Contains(prompt, "stdin.tf\nresource \"aws_s3_bucket\" \"logs\" {}") {
+		t.Errorf("Expected the input in the prompt, got %q", prompt)
+	}
+	if strings.Contains(prompt, "scanned") {
+		t.Errorf("Expected the IaC path not to be scanned, got %q", prompt)
+	}
+
+	diff := "--- a/main.tf\n+++ b/main.tf\n@@ -1 +1 @@\n-acl = \"private\"\n+acl = \"public-read\"\n"
+	if _, err := client.AnalyzeReader(strings.NewReader(diff), InputDiff); err != nil {
+		t.Fatalf("AnalyzeReader failed: %v", err)
+	}
+	if !strings.Contains(prompt, "Changes Under Review") || !strings.Contains(prompt, "+acl = \"public-read\"") {
+		t.Errorf("Expected the diff in the prompt, got %q", prompt)
+	}
+
+	if _, err := client.AnalyzeReader(strings.NewReader(diff), "helm"); err == nil || !strings.Contains(err.Error(), "unknown input kind") {
+		t.Errorf("Expected an error for an unknown kind, got %v", err)
+	}
+	if _, err := client.AnalyzeReader(strings.NewReader(" \n"), InputPlan); err == nil {
+		t.Errorf("Expected an error for an empty input")
+	}
+}
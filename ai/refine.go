@@ -0,0 +1,21 @@
+package ai
+
+import "fmt"
+
+// refineTurns asks the provider for a draft response to messages and returns
+// the conversation that sends the draft back to be verified against the
+// code, so the next response is the refined one. The prompt is marked for
+// caching, since both requests send it.
+func (c *AIClient) refineTurns(messages []Message, question string) ([]Message, error) {
+	messages = append([]Message(nil), messages...)
+	messages[len(messages)-1].Cache = true
+	draft, err := c.getResponse(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the draft to refine: %v", err)
+	}
+	instructions, err := c.renderPrompt("refine", promptData{Question: question})
+	if err != nil {
+		return nil, err
+	}
+	return append(messages, Message{Role: RoleAssistant, Content: draft}, Message{Role: RoleUser, Content: instructions}), nil
+}
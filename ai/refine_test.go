@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRunWithRefinement(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_s3_bucket\" \"logs\" {}\n",
+	})
+
+	var requests [][]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []json.RawMessage `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body.Messages)
+		if len(requests) == 1 {
+			fmt.Fprint(w, `{"content":[{"type":"text","text":"Enable versioning on aws_s3_bucket.logs and encrypt aws_rds_cluster.main."}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Enable versioning on aws_s3_bucket.logs."}]}`)
+	}))
+	defer server.Close()
+
+	sink := &recordingSink{}
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+		WithSinks(sink),
+		WithRefinement(true),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	recommendations, err := client.RunAI()
+	if err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	if recommendations != "Enable versioning on aws_s3_bucket.logs." {
+		t.Errorf("Expected the refined response, got %q", recommendations)
+	}
+	if len(requests) != 2 || len(requests[1]) != 3 {
+		t.Fatalf("Expected a draft request and a refinement request with 3 turns, got %d requests", len(requests))
+	}
+	if !strings.Contains(string(requests[1][1]), "aws_rds_cluster.main") || !strings.Contains(string(requests[1][2]), "Review your previous response") {
+		t.Errorf("Expected the draft to be sent back for review, got %s", requests[1])
+	}
+	if !strings.Contains(string(requests[1][0]), "cache_control") {
+		t.Errorf("Expected the prompt to be marked for caching, got %s", requests[1][0])
+	}
+	for _, update := range sink.updates {
+		if strings.Contains(update, "aws_rds_cluster") {
+			t.Errorf("Expected the draft not to reach the sinks, got %q", update)
+		}
+	}
+	if conversation := client.Conversation(); len(conversation) != 2 || conversation[1].Content != recommendations {
+		t.Errorf("Expected the conversation to hold the refined response, got %+v", conversation)
+	}
+}
@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SectionOmitted names the list of files left out for the total size limit
+// in redaction reports.
+const SectionOmitted = "omitted files"
+
+// The weights of the signals a file is ranked by when the code exceeds the
+// total size limit. Each signal is between 0 and 1: whether the plan
+// changes a resource the file defines, how similar the file is to the
+// question, and how recently the file changed.
+const (
+	planRelevance     = 2
+	questionRelevance = 2
+	recencyRelevance  = 1
+)
+
+// recentCommits is how many commits back a file counts as changed recently.
+const recentCommits = 100
+
+// rankFiles returns the indexes of the files of result from the most to the
+// least relevant. Files the plan changes resources of, the plans
+// themselves, files similar to question in the local index, and files
+// changed in recent commits rank first. Signals that are unavailable, such
+// as recency outside of a Git repository, are left out, and files that rank
+// the same stay in scan order.
+func (c *AIClient) rankFiles(ctx context.Context, result *ScanResult, question string) []int {
+	recent := c.recentlyChanged(ctx)
+	similar := c.questionSimilarity(ctx, question)
+	planned := plannedResources(result)
+
+	scores := make([]float64, len(result.Files))
+	order := make([]int, len(result.Files))
+	for i, f := range result.Files {
+		order[i] = i
+		scores[i] = recencyRelevance*recent[f.Path] + questionRelevance*similar[f.Path]
+		if f.Section == SectionTerraformPlan || definesAny(f, planned) {
+			scores[i] += planRelevance
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	return order
+}
+
+// recentlyChanged scores the files under the IaC path by the most recent of
+// the last recentCommits commits that changed them, from 1 for the latest
+// commit down towards 0. Nothing is scored outside of a Git repository.
+func (c *AIClient) recentlyChanged(ctx context.Context) map[string]float64 {
+	out, err := runCommand(ctx, c.iacPath, nil, "git", "log", "-n", fmt.Sprint(recentCommits), "--relative", "--name-only", "--format=%x1e")
+	if err != nil {
+		return nil
+	}
+	scores := map[string]float64{}
+	for i, commit := range strings.Split(strings.TrimPrefix(string(out), "\x1e"), "\x1e") {
+		for _, line := range strings.Split(commit, "\n") {
+			path := filepath.FromSlash(strings.TrimSpace(line))
+			if _, ok := scores[path]; path != "" && !ok {
+				scores[path] = 1 - float64(i)/recentCommits
+			}
+		}
+	}
+	return scores
+}
+
+// questionSimilarity scores the files in the local index by the highest
+// similarity of their chunks to question. It needs an embedder and an index
+// built with it, and only the sanitized question is embedded.
+func (c *AIClient) questionSimilarity(ctx context.Context, question string) map[string]float64 {
+	if strings.TrimSpace(question) == "" || c.embedder == nil {
+		return nil
+	}
+	ix, err := LoadIndex(c.IndexPath())
+	if os.IsNotExist(err) {
+		fmt.Fprintf(c.messages(), "Warning: files are not ranked by the question, since there is no index at %s\n", c.IndexPath())
+		return nil
+	} else if err != nil {
+		fmt.Fprintf(c.messages(), "Warning: files are not ranked by the question: %v\n", err)
+		return nil
+	}
+	if provider, model := describeEmbedder(c.embedder); provider != "" && (provider != ix.Provider || model != ix.Model) {
+		fmt.Fprintf(c.messages(), "Warning: files are not ranked by the question, since the index was built with %s/%s\n", ix.Provider, ix.Model)
+		return nil
+	}
+	query, _ := c.redact(question)
+	vectors, err := c.embedder.Embed(ctx, []string{query})
+	if err != nil || len(vectors) != 1 {
+		fmt.Fprintf(c.messages(), "Warning: files are not ranked by the question: failed to embed it: %v\n", err)
+		return nil
+	}
+	scores := map[string]float64{}
+	for _, chunk := range ix.Chunks {
+		if score := cosineSimilarity(vectors[0], chunk.Vector); score > scores[chunk.Path] {
+			scores[chunk.Path] = score
+		}
+	}
+	return scores
+}
+
+// plannedResources returns the type and name, such as aws_s3_bucket.logs,
+// of the resources the plans of result create, update, or delete.
+func plannedResources(result *ScanResult) map[string]bool {
+	planned := map[string]bool{}
+	for address := range result.planned {
+		planned[address] = true
+	}
+	for _, f := range result.Section(SectionTerraformPlan) {
+		var plan struct {
+			ResourceChanges []struct {
+				Type   string `json:"type"`
+				Name   string `json:"name"`
+				Change struct {
+					Actions []string `json:"actions"`
+				} `json:"change"`
+			} `json:"resource_changes"`
+		}
+		if err := json.Unmarshal([]byte(f.Content), &plan); err != nil {
+			continue
+		}
+		for _, rc := range plan.ResourceChanges {
+			if actions := strings.Join(rc.Change.Actions, ","); actions != "no-op" && actions != "read" {
+				planned[rc.Type+"."+rc.Name] = true
+			}
+		}
+	}
+	return planned
+}
+
+// definesAny reports whether f is Terraform code defining one of the
+// resources in addresses.
+func definesAny(f ScannedFile, addresses map[string]bool) bool {
+	if f.Language != "terraform" || len(addresses) == 0 {
+		return false
+	}
+	for _, m := range terraformResource.FindAllStringSubmatch(f.Content, -1) {
+		if addresses[m[1]+"."+m[2]] {
+			return true
+		}
+	}
+	return false
+}
+
+// OmittedFiles renders the files left out for the total size limit as the
+// prompt presents them, most relevant first. When paths are anonymized only
+// their number is given, since the files have no pseudonyms.
+func (r *ScanResult) OmittedFiles() string {
+	if len(r.Omitted) == 0 {
+		return ""
+	}
+	for _, f := range r.Files {
+		if f.Pseudonym != "" {
+			return fmt.Sprintf("%d files, whose names are anonymized\n", len(r.Omitted))
+		}
+	}
+	var out strings.Builder
+	for _, path := range r.Omitted {
+		fmt.Fprintf(&out, "%s\n", filepath.Join(r.Root, path))
+	}
+	return out.String()
+}
@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRankFilesOverTheSizeLimit(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pad := strings.Repeat("# padding\n", 10)
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/a.tf":      pad + "resource \"aws_instance\" \"web\" {}\n",
+		"terraform/b.tf":      pad + "resource \"aws_iam_role\" \"ci\" {}\n",
+		"terraform/c.tf":      pad + "resource \"aws_s3_bucket\" \"logs\" {}\n",
+		"terraform/d.tf":      pad + "resource \"aws_nat_gateway\" \"main\" {}\n",
+		"terraform/plan.json": `{"resource_changes":[{"type":"aws_s3_bucket","name":"logs","change":{"actions":["update"]}},{"type":"aws_instance","name":"web","change":{"actions":["no-op"]}}]}`,
+	})
+
+	original := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		return []byte("\x1e\n\nterraform/b.tf\n\x1e\n\nterraform/b.tf\nterraform/a.tf\n"), nil
+	}
+	defer func() { runCommand = original }()
+
+	embedder := &keywordEmbedder{keywords: []string{"nat", "gateway"}}
+	client := &AIClient{iacPath: tmpDir, embedder: embedder, maxScanTokens: 110}
+	index := &Index{Chunks: []IndexChunk{
+		{Path: filepath.Join("terraform", "a.tf"), Vector: []float32{0, 0}},
+		{Path: filepath.Join("terraform", "d.tf"), Vector: []float32{1, 1}},
+	}}
+	if err := index.Save(client.IndexPath()); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	result, err := client.scan(context.Background(), false, "Why is the NAT gateway so expensive?")
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var kept []string
+	for _, f := range result.Files {
+		kept = append(kept, filepath.ToSlash(f.Path))
+	}
+	expected := "terraform/c.tf, terraform/d.tf, terraform/plan.json"
+	if got := strings.Join(kept, ", "); got != expected {
+		t.Errorf("Expected %s to be kept, got %s", expected, got)
+	}
+	omitted := filepath.ToSlash(strings.Join(result.Omitted, ", "))
+	if omitted != "terraform/b.tf, terraform/a.tf" {
+		t.Errorf("Expected b.tf and a.tf to be omitted, most relevant first, got %s", omitted)
+	}
+	if len(embedder.texts) != 1 || !strings.Contains(embedder.texts[0], "NAT gateway") {
+		t.Errorf("Expected the question to be embedded, got %q", embedder.texts)
+	}
+	want := filepath.Join(tmpDir, "terraform", "b.tf") + "\n" + filepath.Join(tmpDir, "terraform", "a.tf") + "\n"
+	if got := result.OmittedFiles(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	result.Files[0].Pseudonym = "dir-1/file-1.tf"
+	if got := result.OmittedFiles(); got != "2 files, whose names are anonymized\n" {
+		t.Errorf("Expected only the number of omitted files when anonymized, got %q", got)
+	}
+}
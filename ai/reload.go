@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/janpreet/kado-ai/config"
+)
+
+// reloadDebounce absorbs the burst of events editors produce when saving a
+// file, so each save results in a single reload.
+const reloadDebounce = 200 * time.Millisecond
+
+// WatchConfig watches the .kdconfig file at configPath (the user's default
+// config when empty) and applies provider, model, API key, and prompt changes
+// to the running client without a restart. Changes that fail to load or
+// validate are rejected and the previous settings are kept. WatchConfig blocks
+// until ctx is cancelled. Reloads and rejected changes are reported with
+// the client's other messages, on standard error while Serve writes to
+// standard output.
+func (c *AIClient) WatchConfig(ctx context.Context, configPath string) error {
+	configPath, err := config.Path(configPath)
+	if err != nil {
+		return err
+	}
+	configPath = filepath.Clean(configPath)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch config: %v", err)
+	}
+	defer watcher.Close()
+
+	// Watch the directory rather than the file, since many editors replace
+	// the file on save and the original watch would be lost.
+	if err := watcher.Add(filepath.Dir(configPath)); err != nil {
+		return fmt.Errorf("failed to watch config: %v", err)
+	}
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != configPath {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				pending = time.After(reloadDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(c.messages(), "Warning: failed to watch %s: %v\n", configPath, err)
+		case <-pending:
+			pending = nil
+			if err := c.reloadConfig(configPath); err != nil {
+				fmt.Fprintf(c.messages(), "Warning: rejected configuration change in %s: %v\n", configPath, err)
+				continue
+			}
+			c.mu.RLock()
+			fmt.Fprintf(c.messages(), "Reloaded configuration from %s (provider %s, model %s)\n", configPath, c.clientType, c.model)
+			c.mu.RUnlock()
+		}
+	}
+}
+
+// reloadConfig loads and validates configPath and, only if it is valid,
+// swaps the reloadable settings into the client, all at once. The sanitizer
+// for them is built before anything is swapped, so a change it rejects
+// leaves the client as it was.
+func (c *AIClient) reloadConfig(configPath string) error {
+	values, err := loadConfig(c.iacPath, configPath)
+	if err != nil {
+		return err
+	}
+	opts, err := optionsFromConfig(values)
+	if err != nil {
+		return err
+	}
+	// The IaC path locates the project's .kado/sanitize.yaml.
+	candidate, err := NewAIClientWithOptions(append(opts, WithIaCPath(c.iacPath))...)
+	if err != nil {
+		return err
+	}
+	if err := candidate.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// A new provider or base URL can change the destination, and with it
+	// how strictly the code is sanitized.
+	if candidate.effectiveSanitizeLevel() != c.effectiveSanitizeLevel() {
+		c.sanitizer = candidate.sanitizer
+		c.placeholders = candidate.placeholders
+		c.hostnames = candidate.hostnames
+		c.cloudIDs = candidate.cloudIDs
+		c.scrubPII = candidate.scrubPII
+		c.maskCloudIDs = candidate.maskCloudIDs
+		c.extraPatterns = candidate.extraPatterns
+		c.reversible = candidate.reversible
+		c.placeholderFormat = candidate.placeholderFormat
+	}
+	c.sanitizeLevel = candidate.sanitizeLevel
+	c.sanitizeProfiles = candidate.sanitizeProfiles
+	c.apiKey = candidate.apiKey
+	c.keys = candidate.keys
+	c.model = candidate.model
+	c.clientType = candidate.clientType
+	c.baseURL = candidate.baseURL
+	c.prompt = candidate.prompt
+	c.promptContext = candidate.promptContext
+	c.analysisType = candidate.analysisType
+	c.complianceFramework = candidate.complianceFramework
+	c.language = candidate.language
+	c.tone = candidate.tone
+	return nil
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gitSource is a remote Git repository to analyze: its URL, the ref to
+// check out, and the subdirectory of it that is the IaC path.
+type gitSource struct {
+	url    string
+	ref    string
+	subdir string
+}
+
+// gitSchemes are the URL schemes of Git repositories. An IaC path with any
+// of them, with the git:: prefix of Terraform module sources, or in the
+// scp-like form git@host:org/repo.git is a remote repository.
+var gitSchemes = []string{"https://", "http://", "ssh://", "git://", "file://"}
+
+// parseGitSource parses iacPath as a Git URL in the style of Terraform
+// module sources, such as https://github.com/org/infra.git//aws?ref=v1.2.0,
+// and reports whether it is one. The ref is a branch, tag, or commit, and
+// the part after a double slash a subdirectory.
+func parseGitSource(iacPath string) (gitSource, bool) {
+	s := strings.TrimPrefix(iacPath, "git::")
+	remote := s != iacPath || strings.HasPrefix(s, "git@")
+	for _, scheme := range gitSchemes {
+		remote = remote || strings.HasPrefix(s, scheme)
+	}
+	if !remote {
+		return gitSource{}, false
+	}
+
+	var src gitSource
+	if i := strings.Index(s, "?"); i >= 0 {
+		query, err := url.ParseQuery(s[i+1:])
+		if err == nil {
+			src.ref = query.Get("ref")
+		}
+		s = s[:i]
+	}
+	start := 0
+	if i := strings.Index(s, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	if i := strings.Index(s[start:], "//"); i >= 0 {
+		src.subdir = strings.Trim(s[start+i+2:], "/")
+		s = s[:start+i]
+	}
+	src.url = s
+	return src, true
+}
+
+// String returns the URL of the repository without the credentials it may
+// hold, such as an access token, for messages.
+func (s gitSource) String() string {
+	u, err := url.Parse(s.url)
+	if err != nil || u.User == nil {
+		return s.url
+	}
+	u.User = nil
+	return u.String()
+}
+
+// cloneRepository fetches the ref of src, or the default branch, without
+// its history into a new temporary directory, and returns the directory.
+// A commit can be fetched as well as a branch or tag, which git clone
+// --branch does not allow.
+func cloneRepository(ctx context.Context, src gitSource) (string, error) {
+	// git reads options even after the URL, so a URL or ref starting with a
+	// dash, such as --upload-pack=..., would run as one.
+	if strings.HasPrefix(src.url, "-") || strings.HasPrefix(src.ref, "-") {
+		return "", fmt.Errorf("failed to clone %s: the URL and ref may not start with -", src)
+	}
+	dir, err := os.MkdirTemp("", "kado-ai-repo")
+	if err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %v", src, err)
+	}
+	ref := src.ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	// Git must fail rather than wait for a password nobody will type.
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"fetch", "--quiet", "--depth", "1", "--", src.url, ref},
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	} {
+		if _, err := runCommand(ctx, dir, env, "git", args...); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("failed to clone %s: %v", src, strings.ReplaceAll(err.Error(), src.url, src.String()))
+		}
+	}
+	return dir, nil
+}
+
+// cloneIaCPath clones iacPath if it is a remote repository, and returns the
+// directory of the clone and the IaC path within it. Local paths are
+// returned as they are, with no clone. The subdirectory must stay inside
+// the clone, also once its symbolic links are resolved.
+func cloneIaCPath(iacPath string) (string, string, error) {
+	src, ok := parseGitSource(iacPath)
+	if !ok {
+		return "", iacPath, nil
+	}
+	subdir := filepath.Clean(filepath.FromSlash(src.subdir))
+	if filepath.IsAbs(subdir) || subdir == ".." || strings.HasPrefix(subdir, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("directory %s is outside of %s", src.subdir, src)
+	}
+	dir, err := cloneRepository(context.Background(), src)
+	if err != nil {
+		return "", "", err
+	}
+	root := filepath.Join(dir, subdir)
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		os.RemoveAll(dir)
+		return "", "", fmt.Errorf("directory %s not found in %s", src.subdir, src)
+	}
+	resolvedDir, err := filepath.EvalSymlinks(dir)
+	if err == nil {
+		var resolved string
+		resolved, err = filepath.EvalSymlinks(root)
+		if err == nil && !underAny(resolved, []string{resolvedDir}) {
+			err = fmt.Errorf("directory %s is outside of %s", src.subdir, src)
+		}
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", err
+	}
+	fmt.Printf("Cloned %s into %s\n", src, dir)
+	return dir, root, nil
+}
+
+// Close removes the clone of the remote repository the client analyzes,
+// together with the outputs written into it, such as ai_input.txt. It does
+// nothing for a local IaC path.
+func (c *AIClient) Close() error {
+	if c.cloneDir == "" {
+		return nil
+	}
+	err := os.RemoveAll(c.cloneDir)
+	c.cloneDir = ""
+	return err
+}
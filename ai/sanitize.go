@@ -0,0 +1,270 @@
+package ai
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+// defaultSanitizer applies the built-in rules for clients that were not
+// built with NewAIClientWithOptions.
+var defaultSanitizer = sanitize.Default()
+
+func (c *AIClient) sanitizeContent(content string) string {
+	sanitized, _ := c.redact(content)
+	return sanitized
+}
+
+// redact applies the client's sanitizer and reports each value it removed,
+// saving any placeholders it assigned.
+func (c *AIClient) redact(content string) (string, []sanitize.Redaction) {
+	return c.redactWith(nil, content)
+}
+
+// redactWith applies s, or the client's sanitizer if s is nil, like redact.
+// A reload swaps the sanitizer and its mappings together, so they are read
+// under the lock.
+func (c *AIClient) redactWith(s *sanitize.Sanitizer, content string) (string, []sanitize.Redaction) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if s == nil {
+		s = c.sanitizer
+	}
+	if s == nil {
+		return defaultSanitizer.Redact(content)
+	}
+	sanitized, found := s.Redact(content)
+	if c.placeholders != nil && len(found) > 0 {
+		if err := c.placeholders.Save(c.PlaceholderMapPath()); err != nil {
+			fmt.Fprintf(c.messages(), "Warning: failed to save placeholder mapping: %v\n", err)
+		}
+	}
+	if c.hostnames != nil && len(found) > 0 {
+		if err := c.hostnames.Save(c.HostnameMapPath()); err != nil {
+			fmt.Fprintf(c.messages(), "Warning: failed to save hostname mapping: %v\n", err)
+		}
+	}
+	if c.cloudIDs != nil && len(found) > 0 {
+		if err := c.cloudIDs.Save(c.CloudIDMapPath()); err != nil {
+			fmt.Fprintf(c.messages(), "Warning: failed to save cloud ID mapping: %v\n", err)
+		}
+	}
+	return sanitized, found
+}
+
+// stripPlanSensitive removes the values a Terraform plan marks as sensitive
+// before the pattern rules see it, and adds the redactions to redactions. A
+// plan that cannot be parsed is left to the pattern rules.
+func (c *AIClient) stripPlanSensitive(plan string, redactions *[]sanitize.Redaction) string {
+	stripped, found, err := sanitize.RedactPlan(plan)
+	if err != nil {
+		fmt.Fprintf(c.messages(), "Warning: failed to remove sensitive values from the plan: %v\n", err)
+		return plan
+	}
+	for i := range found {
+		found[i].Section = SectionTerraformPlan
+	}
+	*redactions = append(*redactions, found...)
+	return stripped
+}
+
+// planContent returns the content of plan with the values it marks as
+// sensitive removed, or its summary as it is, since summaries have no
+// values.
+func (c *AIClient) planContent(plan ScannedFile, redactions *[]sanitize.Redaction) string {
+	if plan.Summarized {
+		return plan.Content
+	}
+	return c.stripPlanSensitive(plan.Content, redactions)
+}
+
+// PlaceholderMapPath returns the path of the local mapping from redaction
+// placeholders back to the values they replace, .kado/placeholders.json in
+// the IaC path.
+func (c *AIClient) PlaceholderMapPath() string {
+	return c.projectPath("placeholders.json")
+}
+
+// DestinationLocal names the sanitization profile of providers whose base
+// URL is on this machine, such as a local Ollama server.
+const DestinationLocal = "local"
+
+// destination returns where requests are sent: DestinationLocal if the base
+// URL is a loopback address, or the provider otherwise.
+func (c *AIClient) destination() string {
+	if c.baseURL != "" {
+		if u, err := url.Parse(c.baseURL); err == nil {
+			host := u.Hostname()
+			if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+				return DestinationLocal
+			}
+		}
+	}
+	return c.clientType
+}
+
+// effectiveSanitizeLevel returns the sanitization level of the profile of
+// the client's destination, falling back to the profile of its provider and
+// then to its sanitization level.
+func (c *AIClient) effectiveSanitizeLevel() sanitize.Level {
+	if level, ok := c.sanitizeProfiles[c.destination()]; ok {
+		return level
+	}
+	if level, ok := c.sanitizeProfiles[c.clientType]; ok {
+		return level
+	}
+	return c.sanitizeLevel
+}
+
+// indexSanitizeLevel returns the sanitization level of the chunks of the
+// index: the stricter of the level of the client's destination and that of
+// the embedder's, so that code sanitized for a local model is never sent to
+// a remote embedding API.
+func (c *AIClient) indexSanitizeLevel() sanitize.Level {
+	level := c.sanitizeLevel
+	if _, local := c.embedder.(*OllamaEmbedder); local {
+		if profile, ok := c.sanitizeProfiles[DestinationLocal]; ok {
+			level = profile
+		}
+	}
+	return stricterLevel(level, c.effectiveSanitizeLevel())
+}
+
+// levelStrictness orders the sanitization levels from the least to the
+// most strict.
+var levelStrictness = map[sanitize.Level]int{
+	sanitize.LevelMinimal:  0,
+	"":                     1,
+	sanitize.LevelStandard: 1,
+	sanitize.LevelParanoid: 2,
+}
+
+// stricterLevel returns the stricter of a and b.
+func stricterLevel(a sanitize.Level, b sanitize.Level) sanitize.Level {
+	if levelStrictness[b] > levelStrictness[a] {
+		return b
+	}
+	return a
+}
+
+// withPIIRules puts the PII rules ahead of rules, so that credential rules
+// never see personal data, and drops the rules of rules they replace.
+func withPIIRules(rules []sanitize.Rule) []sanitize.Rule {
+	pii := sanitize.PIIRules()
+	ids := map[string]bool{}
+	for _, rule := range pii {
+		ids[rule.ID] = true
+	}
+	for _, rule := range rules {
+		if !ids[rule.ID] {
+			pii = append(pii, rule)
+		}
+	}
+	return pii
+}
+
+// HostnameMapPath returns the path of the local mapping from hostname
+// pseudonyms back to the hostnames, .kado/hostnames.json in the IaC path,
+// which also holds the salt of the pseudonyms.
+func (c *AIClient) HostnameMapPath() string {
+	return c.projectPath("hostnames.json")
+}
+
+// CloudIDMapPath returns the path of the local mapping from cloud ID
+// pseudonyms back to the account, project, and subscription IDs,
+// .kado/cloud_ids.json in the IaC path, which also holds the salt of the
+// pseudonyms.
+func (c *AIClient) CloudIDMapPath() string {
+	return c.projectPath("cloud_ids.json")
+}
+
+// newSanitizer returns the sanitizer of the client: the built-in rules of
+// the sanitization level of its destination and the PII rules as customized by
+// .kado/sanitize.yaml, preceded by the hostname pseudonyms of the file and
+// the cloud ID pseudonyms, and followed by the extra patterns,
+// with the allowlist of the file. Since the file is committed with the code,
+// disabling built-in rules in it is called out. With reversible redaction,
+// it also loads the placeholder mapping of the project. Redacted values are
+// replaced in the placeholder format of the client, if it has one.
+func (c *AIClient) newSanitizer() (*sanitize.Sanitizer, error) {
+	return c.levelSanitizer(c.effectiveSanitizeLevel())
+}
+
+// levelSanitizer returns the sanitizer of newSanitizer with the built-in
+// rules of level. The hostname, cloud ID, and placeholder mappings are
+// loaded by the first sanitizer of the client and shared by the others.
+func (c *AIClient) levelSanitizer(level sanitize.Level) (*sanitize.Sanitizer, error) {
+	rules, err := sanitize.LevelRules(level)
+	if err != nil {
+		return nil, err
+	}
+	if c.scrubPII {
+		rules = withPIIRules(rules)
+	}
+	allowlist := sanitize.DefaultAllowlist()
+	path := c.projectPath("sanitize.yaml")
+	cfg, err := sanitize.LoadConfig(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if cfg != nil {
+		if rules, err = cfg.Apply(rules); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", path, err)
+		}
+		if allowlist, err = sanitize.NewAllowlist(cfg.Allowlist); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", path, err)
+		}
+		if len(cfg.Disable) > 0 {
+			fmt.Fprintf(c.messages(), "Warning: %s disables the built-in sanitizer rules %s\n", path, strings.Join(cfg.Disable, ", "))
+		}
+		if len(cfg.Hostnames) > 0 {
+			if c.hostnames == nil {
+				hostnames, err := sanitize.LoadHostnames(c.HostnameMapPath(), cfg.Hostnames)
+				if os.IsNotExist(err) {
+					hostnames, err = sanitize.NewHostnames(cfg.Hostnames)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s: %v", path, err)
+				}
+				c.hostnames = hostnames
+			}
+			rules = append([]sanitize.Rule{c.hostnames.Rule()}, rules...)
+		}
+	}
+	if c.maskCloudIDs {
+		if c.cloudIDs == nil {
+			cloudIDs, err := sanitize.LoadCloudIDs(c.CloudIDMapPath())
+			if os.IsNotExist(err) {
+				cloudIDs, err = sanitize.NewCloudIDs()
+			}
+			if err != nil {
+				return nil, err
+			}
+			c.cloudIDs = cloudIDs
+		}
+		rules = append(c.cloudIDs.Rules(), rules...)
+	}
+	extra, err := sanitize.PatternRules("extra-pattern", c.extraPatterns)
+	if err != nil {
+		return nil, err
+	}
+	s := sanitize.New(append(rules, extra...)...).WithAllowlist(allowlist)
+	if c.reversible {
+		if c.placeholders == nil {
+			placeholders, err := sanitize.LoadPlaceholders(c.PlaceholderMapPath())
+			if os.IsNotExist(err) {
+				placeholders, err = sanitize.NewPlaceholders(), nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			c.placeholders = placeholders
+		}
+		s = s.WithPlaceholders(c.placeholders)
+	}
+	return s.WithPlaceholderFormat(c.placeholderFormat)
+}
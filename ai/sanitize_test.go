@@ -0,0 +1,411 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+func TestSanitizerConfig(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kado/sanitize.yaml": "rules:\n  - id: acme-token\n    pattern: 'acme_[a-z0-9]{8}'\ndisable:\n  - ipv4-address\n",
+	})
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithExtraPatterns(`corp-[0-9]{6}`),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	sanitized, found := client.redact("a = acme_1234abcd\nb = corp-123456\nc = 10.0.0.1\n")
+	expected := "a = [REDACTED]\nb = [REDACTED]\nc = 10.0.0.1\n"
+	if sanitized != expected {
+		t.Errorf("Expected %q, got %q", expected, sanitized)
+	}
+	if len(found) != 2 || found[0].Rule != "acme-token" || found[1].Rule != "extra-pattern-1" {
+		t.Errorf("Expected the custom rule before the extra pattern, got %+v", found)
+	}
+
+	writeTestFiles(t, tmpDir, map[string]string{".kado/sanitize.yaml": "disable: [no-such-rule]\n"})
+	if _, err := NewAIClientWithOptions(WithAPIKey("sk-ant-abc"), WithModel("claude-3-haiku-20240307"), WithProvider("anthropic_messages"), WithIaCPath(tmpDir)); err == nil {
+		t.Errorf("Expected an error for an invalid .kado/sanitize.yaml")
+	}
+}
+
+func TestRunAIReversibleRedaction(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_instance\" \"bastion\" {\n  private_ip = \"10.0.0.5\"\n}\n" +
+			"resource \"aws_instance\" \"db\" {\n  private_ip = \"10.0.0.6\"\n}\n",
+	})
+
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Only allow SSH to [REDACTED_IP_2] from [REDACTED_IP_1]."}]}`)
+	}))
+	defer server.Close()
+
+	sink := &recordingSink{}
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+		WithReversibleRedaction(true),
+		WithSinks(sink),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	recommendations, err := client.RunAI()
+	if err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	if strings.Contains(prompt, "10.0.0.") || !strings.Contains(prompt, "[REDACTED_IP_1]") || !strings.Contains(prompt, "[REDACTED_IP_2]") {
+		t.Errorf("Expected numbered placeholders in the prompt, got %q", prompt)
+	}
+	expected := "Only allow SSH to 10.0.0.6 from 10.0.0.5."
+	if recommendations != expected {
+		t.Errorf("Expected %q, got %q", expected, recommendations)
+	}
+	if len(sink.updates) == 0 || sink.updates[len(sink.updates)-1] != expected {
+		t.Errorf("Expected sinks to receive the restored values, got %v", sink.updates)
+	}
+	if info, err := os.Stat(client.PlaceholderMapPath()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private placeholder mapping, got %v (%v)", info, err)
+	}
+
+	data, err := os.ReadFile(client.RedactionReportPath())
+	if err != nil {
+		t.Fatalf("Failed to read redaction report: %v", err)
+	}
+	var report sanitize.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse redaction report: %v", err)
+	}
+	if report.Total != 2 || report.Rules["ipv4-address"] != 2 || len(report.Redactions) != 2 {
+		t.Errorf("Expected two IP redactions in the report, got %s", data)
+	}
+	if ip := report.Kinds["IP"]; ip == nil || ip.Count != 2 || ip.Removed != 16 || ip.Replaced != 30 {
+		t.Errorf("Expected the report to measure 16 bytes of addresses replaced with 30 bytes of placeholders, got %+v", ip)
+	}
+	if r := report.Redactions[0]; !strings.HasSuffix(r.File, "terraform/main.tf") || r.Line != 2 || len(r.Placeholders) != 1 || r.Placeholders[0] != "[REDACTED_IP_1]" {
+		t.Errorf("Expected the first redaction at terraform/main.tf:2 as [REDACTED_IP_1], got %+v", r)
+	}
+	if strings.Contains(string(data), "10.0.0.") {
+		t.Errorf("Expected the report to omit the redacted values, got %s", data)
+	}
+}
+
+func TestRunAIHostnamePseudonyms(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kado/sanitize.yaml": "hostnames:\n  - .corp.acme.com\n",
+		"terraform/main.tf":   "resource \"aws_route53_record\" \"db\" {\n  name = \"db.prod.corp.acme.com\"\n}\n",
+	})
+
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+		pseudonym := regexp.MustCompile(`h[0-9a-f]{8}\.h[0-9a-f]{8}\.zone1\.internal`).FindString(prompt)
+		fmt.Fprintf(w, `{"content":[{"type":"text","text":"Add a CNAME for %s."}]}`, pseudonym)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	recommendations, err := client.RunAI()
+	if err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	if strings.Contains(prompt, "acme") {
+		t.Errorf("Expected the hostname to be pseudonymized in the prompt, got %q", prompt)
+	}
+	if recommendations != "Add a CNAME for db.prod.corp.acme.com." {
+		t.Errorf("Expected the hostname to be restored, got %q", recommendations)
+	}
+	if info, err := os.Stat(client.HostnameMapPath()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private hostname mapping, got %v (%v)", info, err)
+	}
+}
+
+func TestRunAICloudIDMasking(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_iam_role_policy_attachment\" \"deploy\" {\n  role       = \"arn:aws:iam::123456789012:role/deploy\"\n  policy_arn = \"arn:aws:iam::aws:policy/AdministratorAccess\"\n}\n",
+	})
+
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+		arn := regexp.MustCompile(`arn:aws:iam::0000[0-9]{8}:role/r[0-9a-f]{8}`).FindString(prompt)
+		fmt.Fprintf(w, `{"content":[{"type":"text","text":"Scope down %s."}]}`, arn)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithCloudIDMasking(true),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	recommendations, err := client.RunAI()
+	if err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	if strings.Contains(prompt, "123456789012") || !strings.Contains(prompt, "arn:aws:iam::aws:policy/AdministratorAccess") {
+		t.Errorf("Expected only the account's ARN to be pseudonymized in the prompt, got %q", prompt)
+	}
+	if recommendations != "Scope down arn:aws:iam::123456789012:role/deploy." {
+		t.Errorf("Expected the ARN to be restored, got %q", recommendations)
+	}
+	if info, err := os.Stat(client.CloudIDMapPath()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private cloud ID mapping, got %v (%v)", info, err)
+	}
+}
+
+func TestPlaceholderFormat(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("test-api-key"),
+		WithModel("test-model"),
+		WithProvider("chatgpt"),
+		WithIaCPath(tmpDir),
+		WithPlaceholderFormat("<{kind}:{rule}>"),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+	sanitized := client.sanitizeContent("cidr = \"10.0.0.1\"\n")
+	if sanitized != "cidr = \"<IP:ipv4-address>\"\n" {
+		t.Errorf("Expected a typed placeholder, got %q", sanitized)
+	}
+
+	client, err = NewAIClientWithOptions(
+		WithAPIKey("test-api-key"),
+		WithModel("test-model"),
+		WithProvider("chatgpt"),
+		WithIaCPath(tmpDir),
+		WithReversibleRedaction(true),
+		WithPlaceholderFormat("{{REDACTED_{n}}}"),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+	sanitized = client.sanitizeContent("cidr = \"10.0.0.1\"\n")
+	if sanitized != "cidr = \"{{REDACTED_1}}\"\n" || client.deanonymize(sanitized) != "cidr = \"10.0.0.1\"\n" {
+		t.Errorf("Expected a numbered placeholder that is restored, got %q", sanitized)
+	}
+
+	for _, opts := range [][]Option{
+		{WithPlaceholderFormat("<{kind}_{n}>")},
+		{WithPlaceholderFormat("<{kind}>"), WithReversibleRedaction(true)},
+	} {
+		opts = append(opts, WithAPIKey("test-api-key"), WithModel("test-model"), WithProvider("chatgpt"), WithIaCPath(tmpDir))
+		if _, err := NewAIClientWithOptions(opts...); err == nil {
+			t.Errorf("Expected an error for a placeholder format that does not match reversible redaction")
+		}
+	}
+}
+
+func TestInvalidSanitizeLevel(t *testing.T) {
+	_, err := NewAIClientWithOptions(
+		WithAPIKey("test-api-key"),
+		WithModel("test-model"),
+		WithProvider("chatgpt"),
+		WithSanitizeLevel("strict"),
+	)
+	if err == nil {
+		t.Errorf("Expected an error for an unknown sanitization level")
+	}
+}
+
+func TestSanitizeProfiles(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []Option
+		expected sanitize.Level
+	}{
+		{"no profiles", []Option{WithProvider("chatgpt"), WithSanitizeLevel(sanitize.LevelMinimal)}, sanitize.LevelMinimal},
+		{"provider profile", []Option{
+			WithProvider("anthropic_messages"),
+			WithSanitizeProfile("anthropic_messages", sanitize.LevelParanoid),
+			WithSanitizeProfile(DestinationLocal, sanitize.LevelMinimal),
+		}, sanitize.LevelParanoid},
+		{"local profile", []Option{
+			WithProvider("chatgpt"),
+			WithBaseURL("http://localhost:11434/v1"),
+			WithSanitizeProfile("chatgpt", sanitize.LevelParanoid),
+			WithSanitizeProfile(DestinationLocal, sanitize.LevelMinimal),
+		}, sanitize.LevelMinimal},
+		{"remote gateway", []Option{
+			WithProvider("chatgpt"),
+			WithBaseURL("https://llm.corp.example.com/v1"),
+			WithSanitizeProfile("chatgpt", sanitize.LevelParanoid),
+			WithSanitizeProfile(DestinationLocal, sanitize.LevelMinimal),
+		}, sanitize.LevelParanoid},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{WithAPIKey("test-api-key"), WithModel("test-model")}, tc.opts...)
+			client, err := NewAIClientWithOptions(opts...)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			if level := client.effectiveSanitizeLevel(); level != tc.expected {
+				t.Errorf("Expected level %q, got %q", tc.expected, level)
+			}
+		})
+	}
+}
+
+func TestSanitizeProfileLocalBaseURL(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected a request to the base URL, got %s", r.URL.Path)
+		}
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"Looks fine."}}]}`)
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	writeTestFiles(t, tempDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_instance\" \"web\" {\n  private_ip = \"10.20.30.40\"\n}\n",
+	})
+
+	newClient := func(baseURL string) *AIClient {
+		client, err := NewAIClientWithOptions(
+			WithHTTPClient(testHTTPClient(t, server)),
+			WithAPIKey("sk-abc"),
+			WithModel("llama3"),
+			WithProvider("chatgpt"),
+			WithBaseURL(baseURL),
+			WithIaCPath(tempDir),
+			WithConsentPolicy(ConsentAutoApprove),
+			WithSanitizeProfile("chatgpt", sanitize.LevelParanoid),
+			WithSanitizeProfile(DestinationLocal, sanitize.LevelMinimal),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		return client
+	}
+
+	if _, err := newClient(server.URL + "/v1").RunAI(); err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	if !strings.Contains(body, "10.20.30.40") {
+		t.Errorf("Expected the local profile to keep the address, got %s", body)
+	}
+
+	if _, err := newClient(strings.Replace(server.URL, "127.0.0.1", "llm.example.com", 1) + "/v1").RunAI(); err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+	if strings.Contains(body, "10.20.30.40") {
+		t.Errorf("Expected the provider profile to redact the address, got %s", body)
+	}
+}
+
+func TestInvalidSanitizeProfile(t *testing.T) {
+	testCases := []struct {
+		name string
+		opt  Option
+	}{
+		{"unknown destination", WithSanitizeProfile("ollama", sanitize.LevelMinimal)},
+		{"unknown level", WithSanitizeProfile(DestinationLocal, "strict")},
+		{"invalid base URL", WithBaseURL("localhost:11434")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewAIClientWithOptions(
+				WithAPIKey("test-api-key"),
+				WithModel("test-model"),
+				WithProvider("chatgpt"),
+				tc.opt,
+			)
+			if err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
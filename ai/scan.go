@@ -0,0 +1,368 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sections of a scan. Each corresponds to a section of the prompt.
+const (
+	SectionTerraform     = "terraform code"
+	SectionAnsible       = "ansible code"
+	SectionTerraformPlan = "terraform plan"
+	SectionCDK           = "cdk code"
+	SectionCDKTemplates  = "cdk templates"
+)
+
+// ScannedFile is a file collected by a scan.
+type ScannedFile struct {
+	// Path is relative to the IaC path.
+	Path     string `json:"path"`
+	Section  string `json:"section"`
+	Language string `json:"language"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	// Pseudonym replaces Path in the prompt when paths are anonymized.
+	Pseudonym string `json:"pseudonym,omitempty"`
+	// Encrypted is set for files encrypted with Ansible Vault, whose Content
+	// is a note saying so.
+	Encrypted bool `json:"encrypted,omitempty"`
+	// Truncated is set for files above the per-file size limit, whose
+	// Content is their beginning and end, and Summarized for those whose
+	// Content was then replaced by a summary.
+	Truncated  bool `json:"truncated,omitempty"`
+	Summarized bool `json:"summarized,omitempty"`
+	// IaCPath is the IaC path the file was found under, as configured, when
+	// several are scanned.
+	IaCPath string `json:"iac_path,omitempty"`
+	// Content is the file's unsanitized content.
+	Content string `json:"-"`
+	// full is the content of a truncated file before truncation.
+	full string
+}
+
+// SkippedEntry is a file or directory a scan did not collect, and why.
+type SkippedEntry struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ScanResult describes everything collected from the IaC path for analysis.
+// Unvaulted lists the plaintext secrets in Ansible group_vars and host_vars
+// that should be encrypted with Ansible Vault, and Stacks the Terragrunt
+// stacks.
+type ScanResult struct {
+	Root      string            `json:"root"`
+	Files     []ScannedFile     `json:"files"`
+	Skipped   []SkippedEntry    `json:"skipped,omitempty"`
+	Unvaulted []UnvaultedSecret `json:"unvaulted,omitempty"`
+	Stacks    []TerragruntStack `json:"stacks,omitempty"`
+	// Graph is the dependency graph of the Terraform modules, and Ansible
+	// the structure of the Ansible code.
+	Graph   *ModuleGraph    `json:"graph,omitempty"`
+	Ansible *AnsibleProject `json:"ansible,omitempty"`
+	// Projects are the projects found in a monorepo.
+	Projects []Project `json:"projects,omitempty"`
+	// Omitted lists the files left out for the total size limit, most
+	// relevant first.
+	Omitted []string `json:"omitted,omitempty"`
+	// Changed lists the files changed in the diff range of a diff-scoped
+	// analysis, and Diff is the diff itself, unsanitized.
+	Changed []string `json:"changed,omitempty"`
+	Diff    string   `json:"-"`
+	// planned are the resources changed by the plans that were summarized.
+	planned map[string]bool
+}
+
+// languages maps file extensions to the language reported for them.
+var languages = map[string]string{
+	".tf":         "terraform",
+	".rego":       "rego",
+	".yml":        "yaml",
+	".yaml":       "yaml",
+	".json":       "json",
+	".ts":         "typescript",
+	".py":         "python",
+	".js":         "javascript",
+	".go":         "go",
+	".cs":         "csharp",
+	".fs":         "fsharp",
+	".vb":         "vb",
+	".java":       "java",
+	".hcl":        "hcl",
+	".nomad":      "hcl",
+	".bicep":      "bicep",
+	".bicepparam": "bicep",
+	".ini":        "ini",
+}
+
+// fileLanguage returns the language reported for path, which is known by
+// its extension, or by its name for Dockerfiles, Vagrantfiles, and
+// Jenkinsfiles.
+func fileLanguage(path string) string {
+	if isDockerfile(filepath.Base(path)) {
+		return "dockerfile"
+	}
+	if filepath.Base(path) == "Vagrantfile" {
+		return "ruby"
+	}
+	if name := filepath.Base(path); name == "Jenkinsfile" || strings.HasPrefix(name, "Jenkinsfile.") || strings.HasSuffix(name, ".jenkinsfile") {
+		return "groovy"
+	}
+	return languages[filepath.Ext(path)]
+}
+
+// Scan collects the IaC code under the IaC path without sending anything,
+// so callers can inspect what an analysis would include. CDK projects are
+// synthesized first when enabled.
+func (c *AIClient) Scan(ctx context.Context) (*ScanResult, error) {
+	return c.scan(ctx, c.cdkSynth, c.question)
+}
+
+// scan collects the code to analyze, ranking it by relevance to question
+// when it exceeds the total size limit.
+func (c *AIClient) scan(ctx context.Context, synth bool, question string) (*ScanResult, error) {
+	result := &ScanResult{Root: c.iacPath}
+	var err error
+	if len(c.iacPaths) > 0 {
+		err = c.scanIaCPaths(ctx, result, synth)
+	} else {
+		err = c.scanContent(ctx, result, synth)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.scanScannerResults(result)
+	if c.analysisType == AnalysisDrift {
+		addDrift(result)
+	}
+	if c.driftReport != "" {
+		c.addDriftReport(result, c.layoutPaths([]string{c.driftReport}, nil)[0])
+	}
+	c.scanCosts(ctx, result)
+	if c.lint {
+		c.scanLint(ctx, result)
+	}
+	if c.versionAudit {
+		c.auditVersions(ctx, result)
+	}
+	if c.kubernetesCluster {
+		c.scanCluster(ctx, result)
+	}
+	if c.awsAccountHints {
+		c.scanAWSAccount(ctx, result)
+	}
+	findUnvaulted(result)
+	warnUnvaulted(c.messages(), result)
+	buildModuleGraph(result)
+	buildAnsibleProject(result)
+	if c.policyEvaluation {
+		c.evaluatePolicies(ctx, result)
+	}
+	if c.pruneAnsible {
+		pruneAnsible(result)
+	}
+	if c.projects {
+		result.Projects = findProjects(result)
+	}
+	if c.diffRange != "" {
+		if err := c.scopeToDiff(ctx, result); err != nil {
+			return nil, err
+		}
+	}
+	if c.planFormat == PlanFormatSummary {
+		summarizePlans(result)
+	}
+	c.limitSizes(ctx, result, question)
+	if c.anonymizePaths {
+		if err := c.anonymize(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// scanContent adds everything under the IaC path to result.
+func (c *AIClient) scanContent(ctx context.Context, result *ScanResult, synth bool) error {
+	c.scanLayout(ctx, result)
+	c.scanStateFiles(result)
+	if err := c.scanCDK(ctx, result, synth); err != nil {
+		return err
+	}
+	if err := c.scanTerragrunt(result); err != nil {
+		return err
+	}
+	if err := c.scanBicep(ctx, result); err != nil {
+		return err
+	}
+	if err := c.scanPulumi(ctx, result); err != nil {
+		return err
+	}
+	if err := c.scanKubernetes(ctx, result); err != nil {
+		return err
+	}
+	c.scanDocker(result)
+	c.scanHashiCorp(result)
+	if c.archives {
+		c.scanArchives(result)
+	}
+	if c.pipelines {
+		c.scanPipelines(result)
+	}
+	return nil
+}
+
+func (c *AIClient) scanDirectory(result *ScanResult, section string, dir string, extensions []string) {
+	ignore := c.ignoreRules()
+	err := c.walk(result, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != dir && discoverySkipDirs[info.Name()] {
+			return filepath.SkipDir
+		}
+		if ignore.ignored(c.iacPath, path, info.IsDir()) {
+			result.skip(path, ignoredReason)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && (hasExtension(info.Name(), extensions) || section == SectionAnsible && isINIInventory(path)) {
+			c.addFile(result, section, path)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		result.skip(dir, "not found")
+	} else if err != nil {
+		result.skip(dir, fmt.Sprintf("failed to scan: %v", err))
+	}
+}
+
+// addFile reads path into result, recording it as skipped if it cannot be
+// read, is binary, or is encrypted with Ansible Vault and VaultFilesExclude
+// is set.
+func (c *AIClient) addFile(result *ScanResult, section string, path string) bool {
+	if isBinary(path, "", c.binaryExtensions) {
+		result.skip(path, "binary file")
+		return false
+	}
+	content, err := c.extractFileContent(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			result.skip(path, fmt.Sprintf("unreadable: %v", err))
+		}
+		return false
+	}
+	if isBinary(path, content, nil) {
+		result.skip(path, "binary file")
+		return false
+	}
+	size := int64(len(content))
+	sum := sha256.Sum256([]byte(content))
+	encrypted := isVaultEncrypted(content)
+	if encrypted {
+		if c.vaultFiles == VaultFilesExclude {
+			result.skip(path, "encrypted with Ansible Vault")
+			return false
+		}
+		content = vaultNotice
+	}
+	result.Files = append(result.Files, ScannedFile{
+		Path:      result.rel(path),
+		Section:   section,
+		Language:  fileLanguage(path),
+		Size:      size,
+		SHA256:    hex.EncodeToString(sum[:]),
+		Encrypted: encrypted,
+		Content:   content,
+	})
+	return true
+}
+
+// addOutput adds the output of a command, such as rendered manifests, to
+// result under path, which names the file it would be written to.
+func addOutput(result *ScanResult, section string, path string, content string) {
+	sum := sha256.Sum256([]byte(content))
+	result.Files = append(result.Files, ScannedFile{
+		Path:     result.rel(path),
+		Section:  section,
+		Language: fileLanguage(path),
+		Size:     int64(len(content)),
+		SHA256:   hex.EncodeToString(sum[:]),
+		Content:  content,
+	})
+}
+
+func (r *ScanResult) skip(path string, reason string) {
+	r.Skipped = append(r.Skipped, SkippedEntry{Path: r.rel(path), Reason: reason})
+}
+
+func (r *ScanResult) rel(path string) string {
+	if rel, err := filepath.Rel(r.Root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// Section returns the files collected for section, in scan order.
+func (r *ScanResult) Section(section string) []ScannedFile {
+	var files []ScannedFile
+	for _, f := range r.Files {
+		if f.Section == section {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// Content renders the files of section as the prompt presents them: each
+// file's content preceded by a "File: <path>" header, where the path is the
+// file's pseudonym if it has one. When several IaC paths are scanned, the
+// files of each are preceded by an "IaC path: <path>" label.
+func (r *ScanResult) Content(section string) string {
+	var content strings.Builder
+	iacPath := ""
+	for _, f := range r.Section(section) {
+		// Files are labeled with their IaC path, unless paths are anonymized.
+		if f.IaCPath != iacPath && f.Pseudonym == "" {
+			content.WriteString(fmt.Sprintf("IaC path: %s\n\n", f.IaCPath))
+			iacPath = f.IaCPath
+		}
+		content.WriteString(fmt.Sprintf("File: %s\n%s\n\n", r.displayName(f), f.Content))
+	}
+	return content.String()
+}
+
+// displayName returns the name f is presented under in the prompt.
+func (r *ScanResult) displayName(f ScannedFile) string {
+	if f.Pseudonym != "" {
+		return f.Pseudonym
+	}
+	return filepath.Join(r.Root, f.Path)
+}
+
+// TotalSize returns the combined size of the collected files in bytes.
+func (r *ScanResult) TotalSize() int64 {
+	var total int64
+	for _, f := range r.Files {
+		total += f.Size
+	}
+	return total
+}
+
+// Hash identifies the exact set of collected files and their contents, so
+// callers can tell whether anything changed between two scans.
+func (r *ScanResult) Hash() string {
+	h := sha256.New()
+	for _, f := range r.Files {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\n", f.Section, f.Path, f.SHA256)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		".kado/ignore":            "legacy/\n",
+		"terraform/main.tf":       "resource \"aws_vpc\" \"main\" {}\n",
+		"terraform/policy.rego":   "package deny\n",
+		"terraform/legacy/old.tf": "resource \"aws_instance\" \"old\" {}\n",
+		"terraform/plan.json":     `{"resource_changes": []}`,
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	terraform := result.Section(SectionTerraform)
+	if len(terraform) != 2 {
+		t.Fatalf("Expected 2 terraform files, got %+v", terraform)
+	}
+	main := terraform[0]
+	if main.Path != filepath.Join("terraform", "main.tf") || main.Language != "terraform" || main.Size != int64(len(main.Content)) || main.SHA256 == "" {
+		t.Errorf("Unexpected metadata for main.tf: %+v", main)
+	}
+	if terraform[1].Language != "rego" {
+		t.Errorf("Expected policy.rego to be detected as rego, got %q", terraform[1].Language)
+	}
+	if plan := result.Section(SectionTerraformPlan); len(plan) != 1 || plan[0].Language != "json" {
+		t.Errorf("Expected the plan to be scanned, got %+v", plan)
+	}
+
+	skipped := map[string]string{}
+	for _, s := range result.Skipped {
+		skipped[s.Path] = s.Reason
+	}
+	if !strings.Contains(skipped[filepath.Join("terraform", "legacy")], "ignore") {
+		t.Errorf("Expected terraform/legacy to be skipped as ignored, got %v", skipped)
+	}
+	if skipped["ansible"] != "not found" {
+		t.Errorf("Expected the missing ansible directory to be skipped, got %v", skipped)
+	}
+
+	content := result.Content(SectionTerraform)
+	if !strings.HasPrefix(content, "File: "+filepath.Join(tmpDir, "terraform", "main.tf")+"\n") {
+		t.Errorf("Expected content to start with a file header, got %q", content)
+	}
+
+	hash := result.Hash()
+	if again, _ := client.Scan(context.Background()); again.Hash() != hash {
+		t.Errorf("Expected the hash to be stable across scans")
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "terraform", "main.tf"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if changed, _ := client.Scan(context.Background()); changed.Hash() == hash {
+		t.Errorf("Expected the hash to change with file content")
+	}
+}
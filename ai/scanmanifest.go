@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+// ScanManifest lists every file included in the prompt of a run, so
+// reviewers can audit exactly what is shared before approving the send.
+// Like the redaction report, it never contains the content of the files.
+type ScanManifest struct {
+	Generated time.Time `json:"generated"`
+	Root      string    `json:"root"`
+	// InputSHA256 is the hash of the sanitized input, as saved to
+	// ai_input.txt before any encryption.
+	InputSHA256 string             `json:"input_sha256"`
+	Files       []ScanManifestFile `json:"files"`
+	TotalSize   int64              `json:"total_size"`
+	Redactions  int                `json:"redactions"`
+	Secrets     int                `json:"secrets"`
+	// Unattributed counts by section the redactions made outside of any
+	// file, such as in the standards or the question.
+	Unattributed map[string]int `json:"unattributed,omitempty"`
+	// Omitted lists the files left out for the total size limit, and
+	// Skipped the files and directories the scan did not collect.
+	Omitted []string       `json:"omitted,omitempty"`
+	Skipped []SkippedEntry `json:"skipped,omitempty"`
+}
+
+// ScanManifestFile is a file included in the prompt. Path is relative to the
+// IaC path, and Name is how the prompt refers to the file, its pseudonym
+// when paths are anonymized. Size and SHA256 are of the file as read, before
+// truncation or sanitization.
+type ScanManifestFile struct {
+	Path       string `json:"path"`
+	Name       string `json:"name"`
+	Section    string `json:"section"`
+	Language   string `json:"language"`
+	Size       int64  `json:"size"`
+	SHA256     string `json:"sha256"`
+	Encrypted  bool   `json:"encrypted,omitempty"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	Summarized bool   `json:"summarized,omitempty"`
+	Redactions int    `json:"redactions"`
+	Secrets    int    `json:"secrets"`
+}
+
+// ScanManifestPath returns the path the scan manifest of a run is saved to.
+func (c *AIClient) ScanManifestPath() string {
+	return filepath.Join(c.iacPath, "ai_scan_manifest.json")
+}
+
+// newScanManifest lists the files of scan, which were rendered into input
+// with redactions, with the redactions counted per file.
+func newScanManifest(scan *ScanResult, input string, redactions []sanitize.Redaction) *ScanManifest {
+	manifest := &ScanManifest{
+		Generated:   time.Now().UTC(),
+		Root:        scan.Root,
+		InputSHA256: sha256Hex(input),
+		Files:       []ScanManifestFile{},
+		Omitted:     scan.Omitted,
+		Skipped:     scan.Skipped,
+	}
+	index := map[string]int{}
+	for _, f := range scan.Files {
+		name := scan.displayName(f)
+		index[name] = len(manifest.Files)
+		manifest.Files = append(manifest.Files, ScanManifestFile{
+			Path:       f.Path,
+			Name:       name,
+			Section:    f.Section,
+			Language:   f.Language,
+			Size:       f.Size,
+			SHA256:     f.SHA256,
+			Encrypted:  f.Encrypted,
+			Truncated:  f.Truncated,
+			Summarized: f.Summarized,
+		})
+		manifest.TotalSize += f.Size
+	}
+	for _, r := range redactions {
+		manifest.Redactions++
+		if r.Secret {
+			manifest.Secrets++
+		}
+		i, ok := index[r.File]
+		if !ok {
+			if manifest.Unattributed == nil {
+				manifest.Unattributed = map[string]int{}
+			}
+			manifest.Unattributed[r.Section]++
+			continue
+		}
+		manifest.Files[i].Redactions++
+		if r.Secret {
+			manifest.Files[i].Secrets++
+		}
+	}
+	return manifest
+}
+
+// saveScanManifest writes the manifest of the files of scan, rendered into
+// input with redactions, to ai_scan_manifest.json, readable only by its
+// owner since it names every file.
+func (c *AIClient) saveScanManifest(scan *ScanResult, input string, redactions []sanitize.Redaction) error {
+	data, err := json.MarshalIndent(newScanManifest(scan, input, redactions), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writePrivateFile(c.ScanManifestPath(), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to save scan manifest: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunAISavesScanManifest(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf":    "resource \"aws_db_instance\" \"main\" {\n  password = \"hunter2-correct-horse\"\n}\n",
+		"terraform/network.tf": "resource \"aws_vpc\" \"main\" {}\n",
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Use a secrets manager."}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+	if _, err := client.RunAI(); err != nil {
+		t.Fatalf("RunAI failed: %v", err)
+	}
+
+	data, err := os.ReadFile(client.ScanManifestPath())
+	if err != nil {
+		t.Fatalf("Failed to read scan manifest: %v", err)
+	}
+	var manifest ScanManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Failed to parse scan manifest: %v", err)
+	}
+	input, err := os.ReadFile(client.AIInputPath())
+	if err != nil {
+		t.Fatalf("Failed to read AI input: %v", err)
+	}
+	if manifest.InputSHA256 != sha256Hex(string(input)) {
+		t.Errorf("Expected the hash of the AI input, got %s", manifest.InputSHA256)
+	}
+	if len(manifest.Files) != 2 {
+		t.Fatalf("Expected 2 files, got %+v", manifest.Files)
+	}
+	main, network := manifest.Files[0], manifest.Files[1]
+	if main.Path != filepath.Join("terraform", "main.tf") || main.Section != SectionTerraform || main.SHA256 == "" || main.Size == 0 {
+		t.Errorf("Expected main.tf with its section, size, and hash, got %+v", main)
+	}
+	if main.Redactions != 1 || main.Secrets != 1 {
+		t.Errorf("Expected the password redacted from main.tf, got %+v", main)
+	}
+	if network.Redactions != 0 {
+		t.Errorf("Expected nothing redacted from network.tf, got %+v", network)
+	}
+	if manifest.Redactions != 1 || manifest.TotalSize != main.Size+network.Size {
+		t.Errorf("Expected the totals of the files, got %d redactions and %d bytes", manifest.Redactions, manifest.TotalSize)
+	}
+
+	info, err := os.Stat(client.ScanManifestPath())
+	if err != nil {
+		t.Fatalf("Failed to stat scan manifest: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the scan manifest to be private, got mode %v", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,288 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SectionScannerFindings holds the findings of static analysis scanners,
+// such as tfsec, Trivy, and Checkov, read from their results files.
+const SectionScannerFindings = "scanner findings"
+
+// scannerFinding is a finding of a static analysis scanner, whatever the
+// format it was reported in.
+type scannerFinding struct {
+	Tool     string
+	Rule     string
+	Severity string
+	File     string
+	Line     int
+	Resource string
+	Message  string
+}
+
+// sarifLevels maps the levels of SARIF results to severities.
+var sarifLevels = map[string]string{
+	"error": SeverityHigh, "warning": SeverityMedium, "note": SeverityLow, "none": SeverityInfo,
+}
+
+// scanScannerResults adds the findings of the scanner results files to
+// result, one table per file. Files that cannot be read or parsed are
+// skipped with a warning.
+func (c *AIClient) scanScannerResults(result *ScanResult) {
+	for _, path := range c.layoutPaths(c.scannerResults, nil) {
+		content, err := c.extractFileContent(path)
+		if err != nil {
+			result.skip(path, fmt.Sprintf("unreadable: %v", err))
+			fmt.Fprintf(c.messages(), "Warning: scanner results %s are left out: %v\n", path, err)
+			continue
+		}
+		findings, err := parseScannerResults(content)
+		if err != nil {
+			result.skip(path, err.Error())
+			fmt.Fprintf(c.messages(), "Warning: scanner results %s are left out: %v\n", path, err)
+			continue
+		}
+		addOutput(result, SectionScannerFindings, path, renderScannerFindings(findings))
+		result.Files[len(result.Files)-1].Language = "markdown"
+	}
+}
+
+// parseScannerResults parses the results of a scanner in SARIF, or in the
+// JSON format of tfsec, Trivy, or Checkov.
+func parseScannerResults(content string) ([]scannerFinding, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "[") {
+		// Checkov prints a list of reports when it runs several frameworks.
+		var reports []json.RawMessage
+		if err := json.Unmarshal([]byte(content), &reports); err != nil {
+			return nil, fmt.Errorf("failed to parse scanner results: %v", err)
+		}
+		var findings []scannerFinding
+		for _, report := range reports {
+			found, err := parseScannerResults(string(report))
+			if err != nil {
+				return nil, err
+			}
+			findings = append(findings, found...)
+		}
+		return findings, nil
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse scanner results: %v", err)
+	}
+	switch {
+	case keys["runs"] != nil:
+		return parseSARIF(content)
+	case keys["Results"] != nil || keys["SchemaVersion"] != nil:
+		return parseTrivy(content)
+	case keys["check_type"] != nil:
+		return parseCheckov(content)
+	case keys["results"] != nil:
+		return parseTfsec(content)
+	}
+	return nil, fmt.Errorf("not SARIF or the results of tfsec, Trivy, or Checkov")
+}
+
+func parseSARIF(content string) ([]scannerFinding, error) {
+	var sarif struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Name string `json:"name"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(content), &sarif); err != nil {
+		return nil, fmt.Errorf("failed to parse SARIF: %v", err)
+	}
+	var findings []scannerFinding
+	for _, run := range sarif.Runs {
+		for _, r := range run.Results {
+			level := r.Level
+			if level == "" {
+				level = "warning"
+			}
+			f := scannerFinding{
+				Tool:     run.Tool.Driver.Name,
+				Rule:     r.RuleID,
+				Severity: sarifLevels[level],
+				Message:  r.Message.Text,
+			}
+			if len(r.Locations) > 0 {
+				f.File = r.Locations[0].PhysicalLocation.ArtifactLocation.URI
+				f.Line = r.Locations[0].PhysicalLocation.Region.StartLine
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+func parseTfsec(content string) ([]scannerFinding, error) {
+	var tfsec struct {
+		Results []struct {
+			RuleID      string `json:"rule_id"`
+			LongID      string `json:"long_id"`
+			Severity    string `json:"severity"`
+			Description string `json:"description"`
+			Resource    string `json:"resource"`
+			Location    struct {
+				Filename  string `json:"filename"`
+				StartLine int    `json:"start_line"`
+			} `json:"location"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(content), &tfsec); err != nil {
+		return nil, fmt.Errorf("failed to parse tfsec results: %v", err)
+	}
+	var findings []scannerFinding
+	for _, r := range tfsec.Results {
+		rule := r.LongID
+		if rule == "" {
+			rule = r.RuleID
+		}
+		findings = append(findings, scannerFinding{
+			Tool:     "tfsec",
+			Rule:     rule,
+			Severity: strings.ToLower(r.Severity),
+			File:     r.Location.Filename,
+			Line:     r.Location.StartLine,
+			Resource: r.Resource,
+			Message:  r.Description,
+		})
+	}
+	return findings, nil
+}
+
+func parseTrivy(content string) ([]scannerFinding, error) {
+	var trivy struct {
+		Results []struct {
+			Target            string `json:"Target"`
+			Misconfigurations []struct {
+				ID            string `json:"ID"`
+				AVDID         string `json:"AVDID"`
+				Title         string `json:"Title"`
+				Message       string `json:"Message"`
+				Severity      string `json:"Severity"`
+				Status        string `json:"Status"`
+				CauseMetadata struct {
+					Resource  string `json:"Resource"`
+					StartLine int    `json:"StartLine"`
+				} `json:"CauseMetadata"`
+			} `json:"Misconfigurations"`
+		} `json:"Results"`
+	}
+	if err := json.Unmarshal([]byte(content), &trivy); err != nil {
+		return nil, fmt.Errorf("failed to parse Trivy results: %v", err)
+	}
+	var findings []scannerFinding
+	for _, target := range trivy.Results {
+		for _, m := range target.Misconfigurations {
+			if m.Status == "PASS" {
+				continue
+			}
+			message := m.Title
+			if m.Message != "" {
+				message += ": " + m.Message
+			}
+			findings = append(findings, scannerFinding{
+				Tool:     "trivy",
+				Rule:     m.ID,
+				Severity: strings.ToLower(m.Severity),
+				File:     target.Target,
+				Line:     m.CauseMetadata.StartLine,
+				Resource: m.CauseMetadata.Resource,
+				Message:  message,
+			})
+		}
+	}
+	return findings, nil
+}
+
+func parseCheckov(content string) ([]scannerFinding, error) {
+	var checkov struct {
+		Results struct {
+			FailedChecks []struct {
+				CheckID       string `json:"check_id"`
+				CheckName     string `json:"check_name"`
+				Severity      string `json:"severity"`
+				FilePath      string `json:"file_path"`
+				FileLineRange []int  `json:"file_line_range"`
+				Resource      string `json:"resource"`
+			} `json:"failed_checks"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(content), &checkov); err != nil {
+		return nil, fmt.Errorf("failed to parse Checkov results: %v", err)
+	}
+	var findings []scannerFinding
+	for _, check := range checkov.Results.FailedChecks {
+		f := scannerFinding{
+			Tool:     "checkov",
+			Rule:     check.CheckID,
+			Severity: strings.ToLower(check.Severity),
+			File:     strings.TrimPrefix(check.FilePath, "/"),
+			Resource: check.Resource,
+			Message:  check.CheckName,
+		}
+		if len(check.FileLineRange) > 0 {
+			f.Line = check.FileLineRange[0]
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// renderScannerFindings renders findings as a table, ordered by file and
+// line so the findings several scanners report for the same code are next
+// to each other.
+func renderScannerFindings(findings []scannerFinding) string {
+	if len(findings) == 0 {
+		return "No findings.\n"
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.File != b.File {
+			return filepath.ToSlash(a.File) < filepath.ToSlash(b.File)
+		}
+		return a.Line < b.Line
+	})
+	var out strings.Builder
+	out.WriteString("| Tool | Rule | Severity | Location | Resource | Message |\n| --- | --- | --- | --- | --- | --- |\n")
+	for _, f := range findings {
+		location := f.File
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		severity := f.Severity
+		if severity == "" {
+			severity = "unknown"
+		}
+		message := strings.ReplaceAll(strings.TrimSpace(f.Message), "\n", " ")
+		fmt.Fprintf(&out, "| %s | %s | %s | %s | %s | %s |\n", f.Tool, f.Rule, severity, location, f.Resource, strings.ReplaceAll(message, "|", "\\|"))
+	}
+	return out.String()
+}
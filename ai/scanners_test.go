@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseScannerResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    scannerFinding
+	}{
+		{"sarif", `{"version":"2.1.0","runs":[{"tool":{"driver":{"name":"tfsec"}},"results":[{"ruleId":"aws-s3-enable-versioning","level":"error","message":{"text":"Bucket does not have versioning enabled"},"locations":[{"physicalLocation":{"artifactLocation":{"uri":"terraform/main.tf"},"region":{"startLine":3}}}]}]}]}`,
+			scannerFinding{Tool: "tfsec", Rule: "aws-s3-enable-versioning", Severity: SeverityHigh, File: "terraform/main.tf", Line: 3, Message: "Bucket does not have versioning enabled"}},
+		{"tfsec", `{"results":[{"rule_id":"AVD-AWS-0090","long_id":"aws-s3-enable-versioning","severity":"MEDIUM","description":"Bucket does not have versioning enabled","resource":"aws_s3_bucket.logs","location":{"filename":"main.tf","start_line":3}}]}`,
+			scannerFinding{Tool: "tfsec", Rule: "aws-s3-enable-versioning", Severity: SeverityMedium, File: "main.tf", Line: 3, Resource: "aws_s3_bucket.logs", Message: "Bucket does not have versioning enabled"}},
+		{"trivy", `{"SchemaVersion":2,"Results":[{"Target":"main.tf","Misconfigurations":[{"ID":"AVD-AWS-0090","Title":"S3 Data should be versioned","Message":"Bucket does not have versioning enabled","Severity":"MEDIUM","Status":"FAIL","CauseMetadata":{"Resource":"aws_s3_bucket.logs","StartLine":3}},{"ID":"AVD-AWS-0086","Status":"PASS"}]}]}`,
+			scannerFinding{Tool: "trivy", Rule: "AVD-AWS-0090", Severity: SeverityMedium, File: "main.tf", Line: 3, Resource: "aws_s3_bucket.logs", Message: "S3 Data should be versioned: Bucket does not have versioning enabled"}},
+		{"checkov", `[{"check_type":"terraform","results":{"failed_checks":[{"check_id":"CKV_AWS_21","check_name":"Ensure all data stored in the S3 bucket have versioning enabled","file_path":"/main.tf","file_line_range":[3,5],"resource":"aws_s3_bucket.logs","severity":null}]}}]`,
+			scannerFinding{Tool: "checkov", Rule: "CKV_AWS_21", File: "main.tf", Line: 3, Resource: "aws_s3_bucket.logs", Message: "Ensure all data stored in the S3 bucket have versioning enabled"}},
+	}
+	for _, tt := range tests {
+		findings, err := parseScannerResults(tt.content)
+		if err != nil {
+			t.Errorf("%s: parseScannerResults failed: %v", tt.name, err)
+			continue
+		}
+		if len(findings) != 1 || findings[0] != tt.want {
+			t.Errorf("%s: Expected %+v, got %+v", tt.name, tt.want, findings)
+		}
+	}
+
+	if _, err := parseScannerResults(`{"format_version":"1.0"}`); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
+
+func TestScanScannerResults(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf":  "resource \"aws_s3_bucket\" \"logs\" {}\n",
+		"reports/tfsec.json": `{"results":[{"long_id":"aws-s3-enable-versioning","severity":"MEDIUM","description":"Bucket | versioning","resource":"aws_s3_bucket.logs","location":{"filename":"terraform/main.tf","start_line":1}}]}`,
+		"reports/other.json": `{"format_version":"1.0"}`,
+	})
+
+	client := &AIClient{iacPath: tmpDir, scannerResults: []string{"reports/tfsec.json", "reports/other.json"}}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	findings := result.Section(SectionScannerFindings)
+	if len(findings) != 1 || !strings.Contains(findings[0].Content, "| tfsec | aws-s3-enable-versioning | medium | terraform/main.tf:1 | aws_s3_bucket.logs | Bucket \\| versioning |") {
+		t.Fatalf("Expected the tfsec findings, got %+v", findings)
+	}
+	if skipReason(result, filepath.Join("reports", "other.json")) == "" {
+		t.Errorf("Expected the unknown results to be skipped, got %+v", result.Skipped)
+	}
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.ScannerFindings, "aws-s3-enable-versioning") {
+		t.Errorf("Expected the findings in the prompt, got %q", data.ScannerFindings)
+	}
+}
@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// secretResolvers maps a URI scheme to the function that fetches the secret
+// it refers to. An API key that starts with one of these schemes is resolved
+// before the client is used, so the key itself never has to live in
+// .kdconfig.
+var secretResolvers = map[string]func(ctx context.Context, ref string) (string, error){
+	"env":      resolveEnvSecret,
+	"file":     resolveFileSecret,
+	"gcp-sm":   resolveGCPSecret,
+	"azure-kv": resolveAzureSecret,
+}
+
+// resolveSecret returns value unchanged unless it is a reference such as
+// "gcp-sm:my-project/openai-key", in which case the referenced secret is
+// fetched.
+func resolveSecret(ctx context.Context, value string) (string, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return value, nil
+	}
+	resolve, ok := secretResolvers[parts[0]]
+	if !ok {
+		return value, nil
+	}
+	secret, err := resolve(ctx, parts[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s secret: %v", parts[0], err)
+	}
+	return strings.TrimRight(secret, "\r\n"), nil
+}
+
+// resolveEnvSecret reads env:NAME from the environment.
+func resolveEnvSecret(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// resolveFileSecret reads file:/path/to/key.
+func resolveFileSecret(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// resolveGCPSecret reads a Google Cloud Secret Manager secret through gcloud,
+// referenced either by its resource name
+// (gcp-sm:projects/PROJECT/secrets/NAME/versions/VERSION) or in the short
+// form gcp-sm:PROJECT/NAME[/VERSION]. The version defaults to latest.
+func resolveGCPSecret(ctx context.Context, ref string) (string, error) {
+	parts := strings.Split(strings.Trim(ref, "/"), "/")
+	var project, name, version string
+	switch {
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "secrets" && parts[4] == "versions":
+		project, name, version = parts[1], parts[3], parts[5]
+	case len(parts) == 4 && parts[0] == "projects" && parts[2] == "secrets":
+		project, name, version = parts[1], parts[3], "latest"
+	case len(parts) == 2:
+		project, name, version = parts[0], parts[1], "latest"
+	case len(parts) == 3:
+		project, name, version = parts[0], parts[1], parts[2]
+	default:
+		return "", fmt.Errorf("invalid reference %q; expected gcp-sm:PROJECT/NAME[/VERSION]", ref)
+	}
+	out, err := runCommand(ctx, "", nil, "gcloud", "secrets", "versions", "access", version,
+		"--secret="+name, "--project="+project)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// resolveAzureSecret reads an Azure Key Vault secret through the az CLI,
+// referenced as azure-kv:VAULT/NAME[/VERSION].
+func resolveAzureSecret(ctx context.Context, ref string) (string, error) {
+	parts := strings.Split(strings.Trim(ref, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", fmt.Errorf("invalid reference %q; expected azure-kv:VAULT/NAME[/VERSION]", ref)
+	}
+	args := []string{"keyvault", "secret", "show", "--vault-name", parts[0], "--name", parts[1],
+		"--query", "value", "--output", "tsv"}
+	if len(parts) == 3 {
+		args = append(args, "--version", parts[2])
+	}
+	out, err := runCommand(ctx, "", nil, "az", args...)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
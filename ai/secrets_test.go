@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	keyPath := filepath.Join(tmpDir, "key")
+	if err := os.WriteFile(keyPath, []byte("sk-from-file\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	os.Setenv("KADO_TEST_API_KEY", "sk-from-env")
+	defer os.Unsetenv("KADO_TEST_API_KEY")
+
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{"sk-plain-key", "sk-plain-key"},
+		{"env:KADO_TEST_API_KEY", "sk-from-env"},
+		{"file:" + keyPath, "sk-from-file"},
+	}
+	for _, tc := range testCases {
+		got, err := resolveSecret(context.Background(), tc.value)
+		if err != nil {
+			t.Errorf("For value '%s', unexpected error: %v", tc.value, err)
+		} else if got != tc.expected {
+			t.Errorf("For value '%s', expected '%s', got '%s'", tc.value, tc.expected, got)
+		}
+	}
+
+	if _, err := resolveSecret(context.Background(), "env:KADO_TEST_MISSING_KEY"); err == nil {
+		t.Errorf("Expected an error for an unset environment variable")
+	}
+}
+
+func TestResolveCloudSecrets(t *testing.T) {
+	calls := fakeRunCommand(t, "sk-from-cloud\n")
+
+	testCases := []struct {
+		value   string
+		command string
+	}{
+		{"gcp-sm:infra-prod/openai-key", "gcloud secrets versions access latest --secret=openai-key --project=infra-prod"},
+		{"gcp-sm:projects/infra-prod/secrets/openai-key/versions/3", "gcloud secrets versions access 3 --secret=openai-key --project=infra-prod"},
+		{"azure-kv:platform-kv/anthropic-key", "az keyvault secret show --vault-name platform-kv --name anthropic-key --query value --output tsv"},
+		{"azure-kv:platform-kv/anthropic-key/abc123", "az keyvault secret show --vault-name platform-kv --name anthropic-key --query value --output tsv --version abc123"},
+	}
+	for i, tc := range testCases {
+		got, err := resolveSecret(context.Background(), tc.value)
+		if err != nil {
+			t.Fatalf("For value '%s', unexpected error: %v", tc.value, err)
+		}
+		if got != "sk-from-cloud" {
+			t.Errorf("For value '%s', expected 'sk-from-cloud', got '%s'", tc.value, got)
+		}
+		if command := strings.Join((*calls)[i], " "); command != tc.command {
+			t.Errorf("For value '%s', expected command '%s', got '%s'", tc.value, tc.command, command)
+		}
+	}
+
+	if _, err := resolveSecret(context.Background(), "azure-kv:only-a-vault"); err == nil {
+		t.Errorf("Expected an error for an incomplete Key Vault reference")
+	}
+}
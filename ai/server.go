@@ -0,0 +1,322 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/janpreet/kado-ai/sanitize"
+)
+
+// JSON-RPC error codes returned by Serve.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	// rpcConsentRequired asks the editor to confirm with the user and
+	// retry the request with consent set.
+	rpcConsentRequired = -32001
+)
+
+type rpcRequest struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// AnalyzeParams are the parameters of a kado/analyze request.
+type AnalyzeParams struct {
+	// Path is the file being edited.
+	Path string `json:"path"`
+	// Text is the editor's current content of the file, which may not be
+	// saved yet. The file is read from disk when it is empty.
+	Text string `json:"text,omitempty"`
+	// StartLine and EndLine select a 1-based, inclusive line range to focus
+	// on. The whole file is analyzed when they are zero.
+	StartLine int `json:"startLine,omitempty"`
+	EndLine   int `json:"endLine,omitempty"`
+	// Question optionally asks something specific about the code.
+	Question string `json:"question,omitempty"`
+	// Consent confirms that the user agreed to send the data. It is only
+	// needed when the consent policy does not approve the request itself.
+	Consent bool `json:"consent,omitempty"`
+}
+
+// AnalyzeResult is the result of a kado/analyze request.
+type AnalyzeResult struct {
+	Recommendations string `json:"recommendations"`
+}
+
+// server answers editor requests, caching the sanitized repository context
+// between them.
+type server struct {
+	client     *AIClient
+	cache      *promptData
+	scan       *ScanResult
+	redactions []sanitize.Redaction
+	shutdown   bool
+}
+
+// Serve runs a language-server-style service that editors can query for
+// analysis of the current file or selection. Requests and responses are
+// JSON-RPC 2.0 messages framed with Content-Length headers as in the
+// Language Server Protocol, read from r and written to w; an editor
+// extension typically runs it over the stdio of a child process.
+//
+// The sanitized repository context is built on the first request and reused
+// until a kado/refresh request, so each analysis only scans the focused
+// code. Nothing else is written to w: when w is standard output, progress
+// and warnings are printed to standard error while serving. Each analysis
+// saves its input, redaction report, scan manifest, and run manifest as
+// RunAI does, and CDK projects are never synthesized while serving. The
+// service supports these methods:
+//
+//	initialize     returns the server name and supported methods
+//	kado/analyze   analyzes a file or selection (AnalyzeParams)
+//	kado/refresh   drops the cached repository context
+//	shutdown       acknowledges that the editor is about to exit
+//	exit           stops the service
+//
+// Serve returns when ctx is cancelled, on exit, or when r is exhausted.
+func (c *AIClient) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s := &server{client: c}
+	if f, ok := w.(*os.File); ok && f == os.Stdout {
+		atomic.AddInt32(&c.servingStdout, 1)
+		defer atomic.AddInt32(&c.servingStdout, -1)
+	}
+	reader := bufio.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		body, err := readMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := writeMessage(w, rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(ctx, req)
+		if req.ID == nil {
+			// Notifications get no response.
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+		if err != nil {
+			rpcErr, ok := err.(*rpcError)
+			if !ok {
+				rpcErr = &rpcError{Code: rpcInternalError, Message: err.Error()}
+			}
+			resp.Result = nil
+			resp.Error = rpcErr
+		}
+		if err := writeMessage(w, resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) handle(ctx context.Context, req rpcRequest) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"serverInfo": map[string]string{"name": "kado-ai"},
+			"methods":    []string{"kado/analyze", "kado/refresh"},
+		}, nil
+	case "kado/analyze":
+		var params AnalyzeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Path == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "kado/analyze requires a path"}
+		}
+		return s.analyze(ctx, params)
+	case "kado/refresh":
+		s.cache = nil
+		s.scan = nil
+		s.redactions = nil
+		return struct{}{}, nil
+	case "shutdown":
+		s.shutdown = true
+		return struct{}{}, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+}
+
+func (s *server) analyze(ctx context.Context, params AnalyzeParams) (*AnalyzeResult, error) {
+	c := s.client
+	if s.shutdown {
+		return nil, fmt.Errorf("server is shutting down")
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if s.cache == nil {
+		scan, err := c.scan(ctx, false, c.question)
+		if err != nil {
+			return nil, err
+		}
+		data, redactions := c.promptData(scan)
+		s.cache = &data
+		s.scan = scan
+		s.redactions = redactions
+	}
+
+	// Like the agent's tools, the server only reads files inside the IaC
+	// path that are not hidden, whatever path the client sends.
+	path, err := c.repoPath(params.Path, false)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	text := params.Text
+	if text == "" {
+		content, err := c.extractFileContent(path)
+		if err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("failed to read %s: %v", params.Path, err)}
+		}
+		text = content
+	}
+	if params.StartLine > 0 {
+		lines := strings.Split(text, "\n")
+		if params.EndLine < params.StartLine || params.StartLine > len(lines) {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("invalid line range %d-%d", params.StartLine, params.EndLine)}
+		}
+		if params.EndLine > len(lines) {
+			params.EndLine = len(lines)
+		}
+		text = strings.Join(lines[params.StartLine-1:params.EndLine], "\n")
+	}
+	selection, found := c.redact(text)
+	for i := range found {
+		found[i].Section = "selection"
+	}
+	redactions := append(append([]sanitize.Redaction(nil), s.redactions...), found...)
+
+	data := *s.cache
+	root, _ := filepath.Abs(c.iacPath)
+	data.FilePath, _ = filepath.Rel(root, path)
+	if c.anonymizePaths {
+		a, err := c.pathAnonymizer()
+		if err != nil {
+			return nil, err
+		}
+		data.FilePath = a.pseudonym(data.FilePath)
+		if err := a.save(c.PathMapPath()); err != nil {
+			return nil, fmt.Errorf("failed to save path mapping: %v", err)
+		}
+	}
+	data.StartLine = params.StartLine
+	data.EndLine = params.EndLine
+	data.Selection = selection
+	data.Question = params.Question
+	input, err := c.renderPrompt("review", data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.saveAIInput(input, redactions); err != nil {
+		return nil, fmt.Errorf("failed to save AI input: %v", err)
+	}
+	if c.deleteInput {
+		defer c.removeAIInput()
+	}
+	if err := c.saveScanManifest(s.scan, input, redactions); err != nil {
+		return nil, err
+	}
+	if err := c.blockSecrets(redactions); err != nil {
+		return nil, err
+	}
+	if !params.Consent && !c.autoApproved(redactions) {
+		return nil, &rpcError{
+			Code:    rpcConsentRequired,
+			Message: "sending this data to the AI provider requires the user's consent",
+			Data:    map[string]interface{}{"redactions": len(redactions), "secretsRedacted": sanitize.SecretsFound(redactions)},
+		}
+	}
+
+	examples, err := c.fewShot("review")
+	if err != nil {
+		return nil, err
+	}
+	recommendations, err := c.getResponse(withExamples(examples, input))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recommendations: %v", err)
+	}
+	recommendations = c.deanonymize(recommendations)
+	if c.manifestSigner != nil {
+		if err := c.writeRunManifest("review", input, recommendations); err != nil {
+			return nil, err
+		}
+	}
+	return &AnalyzeResult{Recommendations: recommendations}, nil
+}
+
+// readMessage reads one Content-Length framed message.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || (err == io.ErrUnexpectedEOF && len(header) == 0) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read message header: %v", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %v", err)
+	}
+	return body, nil
+}
+
+// writeMessage writes v as one Content-Length framed message.
+func writeMessage(w io.Writer, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,265 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func rpcMessages(t *testing.T, requests ...string) io.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, req := range requests {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n%s", len(req), req)
+	}
+	return &buf
+}
+
+func readResponses(t *testing.T, r io.Reader) []rpcResponse {
+	t.Helper()
+	var responses []rpcResponse
+	reader := bufio.NewReader(r)
+	for {
+		body, err := readMessage(reader)
+		if err == io.EOF {
+			return responses
+		}
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		var resp rpcResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("Failed to parse response %s: %v", body, err)
+		}
+		responses = append(responses, resp)
+	}
+}
+
+func TestServe(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf":    "resource \"aws_vpc\" \"main\" {}\n",
+		"terraform/network.tf": "resource \"aws_subnet\" \"a\" {\n  vpc_id = aws_vpc.main.id\n  password = \"hunter2\"\n}\n",
+	})
+
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompts = append(prompts, body.Messages[0].Content)
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Tag the subnet."}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApproveIfNoSecrets),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	network := filepath.Join(tmpDir, "terraform", "network.tf")
+	in := rpcMessages(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"kado/analyze","params":{"path":"`+network+`","startLine":1,"endLine":2}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"kado/analyze","params":{"path":"`+network+`","startLine":1,"endLine":2,"consent":true}}`,
+		`{"jsonrpc":"2.0","method":"kado/refresh"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"kado/lint"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"initialize"}`,
+	)
+	var out bytes.Buffer
+	if err := client.Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	responses := readResponses(t, &out)
+	if len(responses) != 4 {
+		t.Fatalf("Expected 4 responses before exit, got %d", len(responses))
+	}
+	if responses[0].Error != nil {
+		t.Errorf("Expected initialize to succeed, got %v", responses[0].Error)
+	}
+	if responses[1].Error == nil || responses[1].Error.Code != rpcConsentRequired {
+		t.Errorf("Expected consent to be required because of the redacted password, got %+v", responses[1])
+	}
+	if responses[2].Error != nil {
+		t.Fatalf("Expected the consented analysis to succeed, got %v", responses[2].Error)
+	}
+	if result, _ := json.Marshal(responses[2].Result); !strings.Contains(string(result), "Tag the subnet.") {
+		t.Errorf("Expected the recommendations in the result, got %s", result)
+	}
+	if responses[3].Error == nil || responses[3].Error.Code != rpcMethodNotFound {
+		t.Errorf("Expected an unknown method error, got %+v", responses[3])
+	}
+
+	if len(prompts) != 1 {
+		t.Fatalf("Expected one provider request, got %d", len(prompts))
+	}
+	prompt := prompts[0]
+	if !strings.Contains(prompt, filepath.Join("terraform", "network.tf")+" (lines 1-2):\nresource \"aws_subnet\" \"a\" {\n  vpc_id = aws_vpc.main.id\n") {
+		t.Errorf("Expected the prompt to focus on the selection, got %q", prompt)
+	}
+	if !strings.Contains(prompt, "aws_vpc\" \"main\"") {
+		t.Errorf("Expected the prompt to include the repository context, got %q", prompt)
+	}
+	if strings.Contains(prompt, "hunter2") {
+		t.Errorf("Expected the repository context to be sanitized, got %q", prompt)
+	}
+	if input, err := os.ReadFile(client.AIInputPath()); err != nil || !strings.Contains(prompt, string(input)) {
+		t.Errorf("Expected the prompt to be saved to the AI input, got %v", err)
+	}
+	for _, path := range []string{client.RedactionReportPath(), client.ScanManifestPath()} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be written: %v", path, err)
+		}
+	}
+}
+
+func TestServeMessagesToStderr(t *testing.T) {
+	var messages bytes.Buffer
+	client := &AIClient{}
+	if client.messages() != io.Writer(os.Stdout) {
+		t.Errorf("Expected messages on standard output by default")
+	}
+	client.servingStdout = 1
+	if client.messages() != io.Writer(os.Stderr) {
+		t.Errorf("Expected messages on standard error while serving on standard output")
+	}
+	client.messageOutput = &messages
+	if client.messages() != io.Writer(&messages) {
+		t.Errorf("Expected messages on the configured output")
+	}
+}
+
+func TestServeAnonymizesPaths(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/payments-prod/main.tf": "resource \"aws_vpc\" \"main\" {}\n",
+	})
+
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompts = append(prompts, body.Messages[0].Content)
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Tag the VPC."}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithAnonymizePaths(true),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	main := filepath.Join(tmpDir, "terraform", "payments-prod", "main.tf")
+	in := rpcMessages(t, `{"jsonrpc":"2.0","id":1,"method":"kado/analyze","params":{"path":"`+main+`"}}`)
+	var out bytes.Buffer
+	if err := client.Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+	responses := readResponses(t, &out)
+	if len(responses) != 1 || responses[0].Error != nil {
+		t.Fatalf("Expected the analysis to succeed, got %+v", responses)
+	}
+	if len(prompts) != 1 {
+		t.Fatalf("Expected one provider request, got %d", len(prompts))
+	}
+	if strings.Contains(prompts[0], "payments-prod") || strings.Contains(prompts[0], "main.tf") {
+		t.Errorf("Expected the path of the file to be anonymized, got %q", prompts[0])
+	}
+}
+
+func TestServeRefusesPathsOutside(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	outside, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(outside)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_vpc\" \"main\" {}\n",
+	})
+	writeTestFiles(t, outside, map[string]string{"id_rsa": "not a real key\n"})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no provider request")
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	in := rpcMessages(t,
+		`{"jsonrpc":"2.0","id":1,"method":"kado/analyze","params":{"path":"`+filepath.Join(outside, "id_rsa")+`"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"kado/analyze","params":{"path":"../id_rsa"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"kado/analyze","params":{"path":".kado/paths.json"}}`,
+	)
+	var out bytes.Buffer
+	if err := client.Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+	responses := readResponses(t, &out)
+	if len(responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %d", len(responses))
+	}
+	for _, resp := range responses {
+		if resp.Error == nil || resp.Error.Code != rpcInvalidParams {
+			t.Errorf("Expected the path to be refused, got %+v", resp)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severities lists the severity scale from most to least severe.
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo}
+
+// severityRubric defines each severity for the prompt, so the provider
+// applies the same scale to every finding.
+var severityRubric = map[string]string{
+	SeverityCritical: "exploitable now or causing an outage or data loss, such as public admin access, plaintext secrets, or deleting stateful resources; fix before merging",
+	SeverityHigh:     "a serious weakness likely to be exploited or to cause an incident, such as unencrypted sensitive data or overly broad IAM permissions; fix soon",
+	SeverityMedium:   "a real weakness with limited impact or that needs other failures to matter, such as missing logging or single-AZ resources; plan a fix",
+	SeverityLow:      "a minor deviation from best practice with little risk, such as missing tags or oversized defaults; fix when convenient",
+	SeverityInfo:     "an observation or improvement with no risk, such as readability or cost hints",
+}
+
+// severityAliases maps other names providers use for a severity onto the
+// scale.
+var severityAliases = map[string]string{
+	"blocker":       SeverityCritical,
+	"error":         SeverityHigh,
+	"major":         SeverityHigh,
+	"moderate":      SeverityMedium,
+	"warning":       SeverityMedium,
+	"minor":         SeverityLow,
+	"informational": SeverityInfo,
+	"note":          SeverityInfo,
+	"none":          SeverityInfo,
+}
+
+// normalizeSeverity maps severity onto the scale, reporting whether it was
+// recognized.
+func normalizeSeverity(severity string) (string, bool) {
+	severity = strings.ToLower(strings.TrimSpace(severity))
+	if _, ok := severityRubric[severity]; ok {
+		return severity, true
+	}
+	if alias, ok := severityAliases[severity]; ok {
+		return alias, true
+	}
+	return SeverityInfo, false
+}
+
+// severityRank orders severities, with 0 for critical. Unknown severities
+// rank below info.
+func severityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return len(Severities)
+}
+
+// severityList describes the severity scale for prompts.
+func severityList() string {
+	var lines []string
+	for _, severity := range Severities {
+		lines = append(lines, fmt.Sprintf("- %s: %s", severity, severityRubric[severity]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CountBySeverity counts findings per severity. Every severity of the scale
+// is present, with zero if no finding has it.
+func CountBySeverity(findings []Finding) map[string]int {
+	counts := make(map[string]int, len(Severities))
+	for _, severity := range Severities {
+		counts[severity] = 0
+	}
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	return counts
+}
+
+// GroupBySeverity groups findings by severity, keeping their order within
+// each group.
+func GroupBySeverity(findings []Finding) map[string][]Finding {
+	groups := make(map[string][]Finding)
+	for _, f := range findings {
+		groups[f.Severity] = append(groups[f.Severity], f)
+	}
+	return groups
+}
+
+// FindingsAtOrAbove returns the findings at least as severe as threshold,
+// for gating a pipeline on, for example, any high or critical finding.
+func FindingsAtOrAbove(findings []Finding, threshold string) ([]Finding, error) {
+	threshold, ok := normalizeSeverity(threshold)
+	if !ok {
+		return nil, fmt.Errorf("unknown severity %q; expected one of %s", threshold, strings.Join(Severities, ", "))
+	}
+	var matched []Finding
+	for _, f := range findings {
+		if severityRank(f.Severity) <= severityRank(threshold) {
+			matched = append(matched, f)
+		}
+	}
+	return matched, nil
+}
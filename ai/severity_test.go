@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"testing"
+)
+
+func TestNormalizeSeverity(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+		known    bool
+	}{
+		{"HIGH", SeverityHigh, true},
+		{" critical ", SeverityCritical, true},
+		{"Moderate", SeverityMedium, true},
+		{"informational", SeverityInfo, true},
+		{"catastrophic", SeverityInfo, false},
+	}
+	for _, tc := range testCases {
+		severity, known := normalizeSeverity(tc.input)
+		if severity != tc.expected || known != tc.known {
+			t.Errorf("normalizeSeverity(%q): expected %s (%v), got %s (%v)", tc.input, tc.expected, tc.known, severity, known)
+		}
+	}
+}
+
+func TestSeverityGrouping(t *testing.T) {
+	findings, err := ParseFindings(`[
+		{"id": "KADO-101", "severity": "critical", "title": "a"},
+		{"id": "KADO-102", "severity": "Moderate", "title": "b"},
+		{"id": "KADO-103", "severity": "high", "title": "c"},
+		{"id": "KADO-104", "severity": "critical", "title": "d"}
+	]`)
+	if err != nil {
+		t.Fatalf("ParseFindings failed: %v", err)
+	}
+
+	counts := CountBySeverity(findings)
+	expected := map[string]int{SeverityCritical: 2, SeverityHigh: 1, SeverityMedium: 1, SeverityLow: 0, SeverityInfo: 0}
+	for severity, count := range expected {
+		if counts[severity] != count {
+			t.Errorf("Expected %d %s findings, got %d", count, severity, counts[severity])
+		}
+	}
+	if groups := GroupBySeverity(findings); len(groups[SeverityCritical]) != 2 || groups[SeverityCritical][1].Title != "d" {
+		t.Errorf("Unexpected critical group %+v", groups[SeverityCritical])
+	}
+
+	gated, err := FindingsAtOrAbove(findings, "HIGH")
+	if err != nil {
+		t.Fatalf("FindingsAtOrAbove failed: %v", err)
+	}
+	if len(gated) != 3 {
+		t.Errorf("Expected 3 findings at or above high, got %+v", gated)
+	}
+	if _, err := FindingsAtOrAbove(findings, "severe"); err == nil {
+		t.Errorf("Expected an error for an unknown threshold")
+	}
+}
@@ -0,0 +1,150 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Sink receives recommendations as they are produced. Update is called with
+// all of the text produced so far each time a section completes, and a last
+// time with final set once the response is complete.
+type Sink interface {
+	Update(text string, final bool) error
+}
+
+// updateSinks updates every sink, warning on out about those that fail.
+func updateSinks(out io.Writer, sinks []Sink, text string, final bool) {
+	for _, sink := range sinks {
+		if err := sink.Update(text, final); err != nil {
+			fmt.Fprintf(out, "Warning: failed to update output sink: %v\n", err)
+		}
+	}
+}
+
+type fileSink struct {
+	path string
+}
+
+// NewFileSink returns a Sink that keeps the file at path up to date with the
+// recommendations produced so far.
+func NewFileSink(path string) Sink {
+	return &fileSink{path: path}
+}
+
+func (s *fileSink) Update(text string, final bool) error {
+	return os.WriteFile(s.path, []byte(text), 0644)
+}
+
+type writerSink struct {
+	w       io.Writer
+	written int
+}
+
+// NewWriterSink returns a Sink that writes each newly completed section to w,
+// such as a terminal.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+func (s *writerSink) Update(text string, final bool) error {
+	if len(text) <= s.written {
+		return nil
+	}
+	_, err := io.WriteString(s.w, text[s.written:])
+	s.written = len(text)
+	return err
+}
+
+// GitHubCommentSink posts the recommendations as a comment on a pull request
+// and edits that same comment in place as more sections complete.
+type GitHubCommentSink struct {
+	Token       string
+	Repository  string
+	PullRequest int
+	APIURL      string
+	HTTPClient  *http.Client
+
+	commentID int64
+}
+
+// NewGitHubCommentSink returns a sink commenting on pull request number pr of
+// repository ("owner/name") using token.
+func NewGitHubCommentSink(token string, repository string, pr int) *GitHubCommentSink {
+	return &GitHubCommentSink{Token: token, Repository: repository, PullRequest: pr}
+}
+
+// githubSinkFromConfig parses an AI_GITHUB_PR value of the form
+// owner/name#123. The token is read from GITHUB_TOKEN.
+func githubSinkFromConfig(value string) (*GitHubCommentSink, error) {
+	parts := strings.SplitN(value, "#", 2)
+	if len(parts) != 2 || strings.Count(parts[0], "/") != 1 {
+		return nil, fmt.Errorf("invalid AI_GITHUB_PR value %q; expected owner/name#number", value)
+	}
+	pr, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid AI_GITHUB_PR value %q; expected owner/name#number", value)
+	}
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("AI_GITHUB_PR is set but GITHUB_TOKEN is not")
+	}
+	return NewGitHubCommentSink(token, parts[0], pr), nil
+}
+
+func (s *GitHubCommentSink) Update(text string, final bool) error {
+	body := text
+	if !final {
+		body += "\n\n_Kado AI analysis in progress..._"
+	}
+
+	apiURL := s.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	method := "POST"
+	url := fmt.Sprintf("%s/repos/%s/issues/%d/comments", apiURL, s.Repository, s.PullRequest)
+	if s.commentID != 0 {
+		method = "PATCH"
+		url = fmt.Sprintf("%s/repos/%s/issues/comments/%d", apiURL, s.Repository, s.commentID)
+	}
+
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("GitHub API returned %s", resp.Status)
+	}
+
+	var comment struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&comment); err != nil {
+		return fmt.Errorf("failed to parse GitHub comment: %v", err)
+	}
+	s.commentID = comment.ID
+	return nil
+}
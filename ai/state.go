@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SectionTerraformState holds the resource inventories derived from
+// Terraform state files with WithStateInventory.
+const SectionTerraformState = "terraform state inventory"
+
+// isStateFile reports whether name is a Terraform state file or one of its
+// backups, such as terraform.tfstate or terraform.tfstate.backup.
+func isStateFile(name string) bool {
+	return strings.HasSuffix(name, ".tfstate") || (strings.Contains(name, ".tfstate.") && strings.HasSuffix(name, ".backup"))
+}
+
+// scanStateFiles finds the Terraform state files under the IaC path. State
+// holds every attribute of every resource in plaintext, including
+// passwords and keys, so it is never sent: each file is recorded as skipped
+// with a warning, and with WithStateInventory only its inventory is
+// collected. The .terraform directories are not searched, since the
+// terraform.tfstate there only caches the backend settings.
+func (c *AIClient) scanStateFiles(result *ScanResult) {
+	// State files are almost always in .gitignore, which should not stop an
+	// inventory that was asked for.
+	ignore := c.ignoreRules()
+	ignore.gitignore = nil
+	c.walk(result, c.iacPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != c.iacPath && (info.Name() == ".git" || info.Name() == ".terraform") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isStateFile(info.Name()) {
+			return nil
+		}
+		result.skip(path, "Terraform state is never sent")
+		fmt.Fprintf(c.messages(), "WARNING: %s is a Terraform state file, which stores secrets in plaintext. "+
+			"It is never sent, but it should not be kept with the code; use a remote backend instead.\n", result.rel(path))
+		if c.stateInventory && !ignore.ignored(c.iacPath, path, false) {
+			c.addStateInventory(result, path)
+		}
+		return nil
+	})
+}
+
+// addStateInventory adds the inventory of the state file at path to
+// result.
+func (c *AIClient) addStateInventory(result *ScanResult, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	inventory, err := stateInventory(data)
+	if err != nil {
+		result.skip(path, fmt.Sprintf("unreadable Terraform state: %v", err))
+		return
+	}
+	result.Files = append(result.Files, ScannedFile{
+		Path:     result.rel(path),
+		Section:  SectionTerraformState,
+		Language: "terraform-state",
+		Size:     int64(len(data)),
+		SHA256:   sha256Hex(string(data)),
+		Content:  inventory,
+	})
+}
+
+// stateInventory lists the managed resources of a Terraform state, by type
+// and by address with their number of instances, without any attribute
+// values. Data sources are left out.
+func stateInventory(data []byte) (string, error) {
+	var state struct {
+		Resources []struct {
+			Module    string            `json:"module"`
+			Mode      string            `json:"mode"`
+			Type      string            `json:"type"`
+			Name      string            `json:"name"`
+			Instances []json.RawMessage `json:"instances"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return "", err
+	}
+
+	types := map[string]int{}
+	var addresses []string
+	for _, r := range state.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		types[r.Type] += len(r.Instances)
+		address := r.Type + "." + r.Name
+		if r.Module != "" {
+			address = r.Module + "." + address
+		}
+		instances := fmt.Sprintf("%d instances", len(r.Instances))
+		if len(r.Instances) == 1 {
+			instances = "1 instance"
+		}
+		addresses = append(addresses, fmt.Sprintf("%s (%s)", address, instances))
+	}
+	var names []string
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.Strings(addresses)
+
+	var out strings.Builder
+	out.WriteString("Resource types:\n")
+	for _, name := range names {
+		fmt.Fprintf(&out, "  %s: %d\n", name, types[name])
+	}
+	out.WriteString("Resources:\n")
+	for _, address := range addresses {
+		fmt.Fprintf(&out, "  %s\n", address)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testState = `{
+  "version": 4,
+  "resources": [
+    {"mode": "managed", "type": "aws_db_instance", "name": "main", "instances": [{"attributes": {"password": "hunter2"}}]},
+    {"module": "module.web", "mode": "managed", "type": "aws_instance", "name": "this", "instances": [{"index_key": 0, "attributes": {"private_ip": "10.0.0.5"}}, {"index_key": 1, "attributes": {}}]},
+    {"mode": "data", "type": "aws_ami", "name": "ubuntu", "instances": [{"attributes": {"id": "ami-123"}}]}
+  ]
+}`
+
+func TestScanStateFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf":                      "resource \"aws_db_instance\" \"main\" {}\n",
+		"terraform/terraform.tfstate":            testState,
+		"terraform/terraform.tfstate.backup":     testState,
+		"terraform/.terraform/terraform.tfstate": `{"backend": {}}`,
+	})
+
+	for _, inventory := range []bool{false, true} {
+		client := &AIClient{iacPath: tmpDir, stateInventory: inventory}
+		result, err := client.Scan(context.Background())
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+
+		var skipped []string
+		for _, s := range result.Skipped {
+			if strings.Contains(s.Reason, "Terraform state") {
+				skipped = append(skipped, s.Path)
+			}
+		}
+		if len(skipped) != 2 {
+			t.Errorf("Expected both state files to be skipped, got %v", skipped)
+		}
+		for _, f := range result.Files {
+			if strings.Contains(f.Content, "hunter2") || strings.Contains(f.Content, "10.0.0.5") {
+				t.Errorf("Expected no state attribute values in %s, got %q", f.Path, f.Content)
+			}
+		}
+
+		states := result.Section(SectionTerraformState)
+		if !inventory {
+			if len(states) != 0 {
+				t.Errorf("Expected no inventory without WithStateInventory, got %+v", states)
+			}
+			continue
+		}
+		if len(states) != 2 || states[0].Path != filepath.Join("terraform", "terraform.tfstate") {
+			t.Fatalf("Expected an inventory of both state files, got %+v", states)
+		}
+		expected := "Resource types:\n" +
+			"  aws_db_instance: 1\n" +
+			"  aws_instance: 2\n" +
+			"Resources:\n" +
+			"  aws_db_instance.main (1 instance)\n" +
+			"  module.web.aws_instance.this (2 instances)\n"
+		if states[0].Content != expected {
+			t.Errorf("Expected inventory %q, got %q", expected, states[0].Content)
+		}
+	}
+}
+
+func TestAgentHidesStateFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf":           "resource \"aws_db_instance\" \"main\" {}\n",
+		"terraform/terraform.tfstate": testState,
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	if _, err := client.readRepoFile("terraform/terraform.tfstate"); err == nil {
+		t.Errorf("Expected the state file to be hidden from read_file")
+	}
+	if listing, _ := client.listDir("terraform"); strings.Contains(listing, "tfstate") {
+		t.Errorf("Expected the state file to be hidden from list_dir, got %q", listing)
+	}
+	if matches, _ := client.grepRepo("hunter2", "."); strings.Contains(matches, "hunter2") {
+		t.Errorf("Expected the state file to be hidden from grep, got %q", matches)
+	}
+}
@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// streamRecommendations sends messages with streaming enabled and calls onText
+// with each piece of generated text as it arrives. It returns the complete
+// text once the provider finishes, continuing the response when it is cut
+// off at the output token limit.
+func (c *AIClient) streamRecommendations(messages []Message, onText func(string)) (string, error) {
+	return continueResponse(c.messages(), messages, func(messages []Message) (string, bool, error) {
+		return c.streamPart(messages, onText)
+	})
+}
+
+// streamPart streams a single response like streamRecommendations, and
+// reports whether it was cut off at the output token limit.
+func (c *AIClient) streamPart(messages []Message, onText func(string)) (string, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	resp, err := c.doProviderRequest(messages, true)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		if _, err := extractText(c.clientType, body); err != nil {
+			return "", false, err
+		}
+		return "", false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var text strings.Builder
+	truncated := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+		delta, cutOff, err := streamDelta(c.clientType, data)
+		if err != nil {
+			return "", false, err
+		}
+		truncated = truncated || cutOff
+		if delta != "" {
+			text.WriteString(delta)
+			onText(delta)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", false, fmt.Errorf("failed to read response stream: %v", err)
+	}
+	return text.String(), truncated, nil
+}
+
+// streamDelta extracts the generated text from one server-sent event, and
+// whether the event reports that the output token limit was reached.
+func streamDelta(clientType string, data string) (string, bool, error) {
+	if clientType == "chatgpt" {
+		var event struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return "", false, fmt.Errorf("failed to parse stream event: %v", err)
+		}
+		if event.Error != nil {
+			return "", false, fmt.Errorf("provider returned an error: %s", event.Error.Message)
+		}
+		if len(event.Choices) == 0 {
+			return "", false, nil
+		}
+		return event.Choices[0].Delta.Content, event.Choices[0].FinishReason == "length", nil
+	}
+
+	var event struct {
+		Type  string `json:"type"`
+		Delta struct {
+			Type       string `json:"type"`
+			Text       string `json:"text"`
+			StopReason string `json:"stop_reason"`
+		} `json:"delta"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return "", false, fmt.Errorf("failed to parse stream event: %v", err)
+	}
+	if event.Type == "error" && event.Error != nil {
+		return "", false, fmt.Errorf("provider returned an error: %s", event.Error.Message)
+	}
+	if event.Type == "content_block_delta" && event.Delta.Type == "text_delta" {
+		return event.Delta.Text, false, nil
+	}
+	return "", event.Type == "message_delta" && event.Delta.StopReason == "max_tokens", nil
+}
+
+// sectionFlusher accumulates streamed text and updates the sinks every time
+// a markdown section is complete, which is when the next heading starts.
+type sectionFlusher struct {
+	sinks   []Sink
+	out     io.Writer
+	text    strings.Builder
+	flushed int
+}
+
+func (f *sectionFlusher) write(delta string) {
+	f.text.WriteString(delta)
+	text := f.text.String()
+	heading := strings.LastIndex(text[f.flushed:], "\n#")
+	if heading >= 0 {
+		f.flushed += heading + 1
+		updateSinks(f.out, f.sinks, text[:f.flushed], false)
+	}
+}
+
+func (f *sectionFlusher) finish() {
+	updateSinks(f.out, f.sinks, f.text.String(), true)
+}
@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// redirectTransport sends every request to a test server, whatever host the
+// client asked for.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func testHTTPClient(t *testing.T, server *httptest.Server) *http.Client {
+	t.Helper()
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse test server URL: %v", err)
+	}
+	return &http.Client{Transport: &redirectTransport{target: target}}
+}
+
+type recordingSink struct {
+	updates []string
+	final   bool
+}
+
+func (s *recordingSink) Update(text string, final bool) error {
+	s.updates = append(s.updates, text)
+	s.final = final
+	return nil
+}
+
+func TestStreamRecommendations(t *testing.T) {
+	testCases := []struct {
+		clientType string
+		events     []string
+	}{
+		{
+			clientType: "chatgpt",
+			events: []string{
+				`{"choices":[{"delta":{"content":"# Summary\n"}}]}`,
+				`{"choices":[{"delta":{"content":"Looks fine.\n"}}]}`,
+				`{"choices":[{"delta":{"content":"# Security\nOpen SSH."}}]}`,
+				`[DONE]`,
+			},
+		},
+		{
+			clientType: "anthropic_messages",
+			events: []string{
+				`{"type":"message_start","message":{}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"# Summary\n"}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Looks fine.\n"}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"# Security\nOpen SSH."}}`,
+				`{"type":"message_stop"}`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, event := range tc.events {
+				fmt.Fprintf(w, "data: %s\n\n", event)
+			}
+		}))
+
+		sink := &recordingSink{}
+		client := &AIClient{
+			apiKey:     "test-api-key",
+			model:      "test-model",
+			clientType: tc.clientType,
+			httpClient: testHTTPClient(t, server),
+			streaming:  true,
+			sinks:      []Sink{sink},
+		}
+		recommendations, err := client.recommend("analyze this")
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: recommend failed: %v", tc.clientType, err)
+		}
+
+		expected := "# Summary\nLooks fine.\n# Security\nOpen SSH."
+		if recommendations != expected {
+			t.Errorf("%s: expected %q, got %q", tc.clientType, expected, recommendations)
+		}
+		if len(sink.updates) != 2 || sink.updates[0] != "# Summary\nLooks fine.\n" || !sink.final {
+			t.Errorf("%s: expected one partial update with the first section and a final update, got %q", tc.clientType, sink.updates)
+		}
+	}
+}
+
+func TestGitHubCommentSink(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		fmt.Fprint(w, `{"id": 42}`)
+	}))
+	defer server.Close()
+
+	sink := NewGitHubCommentSink("test-token", "acme/infra", 7)
+	sink.APIURL = server.URL
+	if err := sink.Update("# Summary\n", false); err != nil {
+		t.Fatalf("First update failed: %v", err)
+	}
+	if err := sink.Update("# Summary\n# Security\n", true); err != nil {
+		t.Fatalf("Second update failed: %v", err)
+	}
+
+	expected := "POST /repos/acme/infra/issues/7/comments,PATCH /repos/acme/infra/issues/comments/42"
+	if got := strings.Join(requests, ","); got != expected {
+		t.Errorf("Expected requests %s, got %s", expected, got)
+	}
+}
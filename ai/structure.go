@@ -0,0 +1,164 @@
+package ai
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// structureScan returns a copy of scan with every Terraform file presented
+// in a normalized form: each top-level block under its address, such as
+// aws_s3_bucket.logs, var.region, or module.vpc, with the line it starts
+// on, its attributes one per line with the whitespace and alignment
+// collapsed, and the comments above it. Comments elsewhere are dropped.
+// Files that are not valid HCL are left as they are, as is the original
+// scan.
+func structureScan(scan *ScanResult) *ScanResult {
+	structured := *scan
+	structured.Files = make([]ScannedFile, len(scan.Files))
+	for i, f := range scan.Files {
+		if f.Language == "terraform" && filepath.Ext(f.Path) == ".tf" && !f.Encrypted && !f.Truncated && !f.Summarized {
+			if content := structureTerraform(f.Content); content != "" {
+				f.Content = content
+			}
+		}
+		structured.Files[i] = f
+	}
+	return &structured
+}
+
+// structureTerraform renders Terraform source in the normalized form of
+// structureScan, or returns the empty string if it is not valid HCL or has
+// no blocks.
+func structureTerraform(content string) string {
+	file, diags := hclsyntax.ParseConfig([]byte(content), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return ""
+	}
+	lines := strings.Split(content, "\n")
+	var out strings.Builder
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		for _, comment := range leadingComments(lines, block.TypeRange.Start.Line) {
+			out.WriteString(comment + "\n")
+		}
+		if block.Type == "locals" {
+			for _, item := range hclBodyItems(block.Body) {
+				if local, ok := item.(*hclsyntax.Attribute); ok {
+					fmt.Fprintf(&out, "local.%s = %s  (line %d)\n", local.Name, normalizeExpression(hclSource(content, local.Expr), ""), local.SrcRange.Start.Line)
+				}
+			}
+			continue
+		}
+		if len(block.Body.Attributes) == 0 && len(block.Body.Blocks) == 0 {
+			fmt.Fprintf(&out, "%s  (line %d) {}\n", terraformAddress(block), block.TypeRange.Start.Line)
+			continue
+		}
+		fmt.Fprintf(&out, "%s  (line %d) {\n", terraformAddress(block), block.TypeRange.Start.Line)
+		writeHCLBody(&out, content, block.Body, "  ")
+		out.WriteString("}\n")
+	}
+	return out.String()
+}
+
+// terraformAddress returns the address of a top-level Terraform block.
+func terraformAddress(block *hclsyntax.Block) string {
+	switch {
+	case block.Type == "resource" && len(block.Labels) == 2:
+		return block.Labels[0] + "." + block.Labels[1]
+	case block.Type == "data" && len(block.Labels) == 2:
+		return "data." + block.Labels[0] + "." + block.Labels[1]
+	case block.Type == "module" && len(block.Labels) == 1:
+		return "module." + block.Labels[0]
+	case block.Type == "variable" && len(block.Labels) == 1:
+		return "var." + block.Labels[0]
+	case block.Type == "output" && len(block.Labels) == 1:
+		return "output." + block.Labels[0]
+	}
+	return strings.Join(append([]string{block.Type}, block.Labels...), " ")
+}
+
+// hclBodyItems returns the attributes and nested blocks of body in the
+// order they are written.
+func hclBodyItems(body *hclsyntax.Body) []hclsyntax.Node {
+	var items []hclsyntax.Node
+	for _, attribute := range body.Attributes {
+		items = append(items, attribute)
+	}
+	for _, block := range body.Blocks {
+		items = append(items, block)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Range().Start.Byte < items[j].Range().Start.Byte
+	})
+	return items
+}
+
+// hclSource returns the source of expr as written in content.
+func hclSource(content string, expr hclsyntax.Expression) string {
+	r := expr.Range()
+	return strings.TrimSpace(content[r.Start.Byte:r.End.Byte])
+}
+
+// writeHCLBody writes the attributes and nested blocks of body, one per
+// line at indent.
+func writeHCLBody(out *strings.Builder, content string, body *hclsyntax.Body, indent string) {
+	for _, item := range hclBodyItems(body) {
+		if attribute, ok := item.(*hclsyntax.Attribute); ok {
+			fmt.Fprintf(out, "%s%s = %s\n", indent, attribute.Name, normalizeExpression(hclSource(content, attribute.Expr), indent))
+			continue
+		}
+		block := item.(*hclsyntax.Block)
+		header := strings.Join(append([]string{block.Type}, block.Labels...), " ")
+		if len(block.Body.Attributes) == 0 && len(block.Body.Blocks) == 0 {
+			fmt.Fprintf(out, "%s%s {}\n", indent, header)
+			continue
+		}
+		fmt.Fprintf(out, "%s%s {\n", indent, header)
+		writeHCLBody(out, content, block.Body, indent+"  ")
+		fmt.Fprintf(out, "%s}\n", indent)
+	}
+}
+
+// normalizeExpression re-indents the lines of a multi-line expression, such
+// as an object or list, under indent, keeping their indentation relative to
+// the last line, which closes it. Heredocs are left as they are.
+func normalizeExpression(value string, indent string) string {
+	lines := strings.Split(value, "\n")
+	if len(lines) == 1 || strings.HasPrefix(value, "<<") {
+		return value
+	}
+	last := lines[len(lines)-1]
+	base := last[:len(last)-len(strings.TrimLeft(last, " \t"))]
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t\r")
+		if strings.HasPrefix(line, base) {
+			line = line[len(base):]
+		} else {
+			line = strings.TrimLeft(line, " \t")
+		}
+		lines[i] = indent + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// leadingComments returns the comment lines directly above the 1-based
+// line, trimmed, in order.
+func leadingComments(lines []string, line int) []string {
+	start := line - 1
+	for start > 0 {
+		text := strings.TrimSpace(lines[start-1])
+		if !strings.HasPrefix(text, "#") && !strings.HasPrefix(text, "//") {
+			break
+		}
+		start--
+	}
+	var comments []string
+	for _, text := range lines[start : line-1] {
+		comments = append(comments, strings.TrimSpace(text))
+	}
+	return comments
+}
@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStructureTerraform(t *testing.T) {
+	content := `terraform {
+  required_version = ">= 1.5"
+}
+
+# Logs of every service.
+# Kept for 90 days.
+resource "aws_s3_bucket"   "logs" {
+  bucket        = "acme-logs"   # shared
+  tags          = {
+      Team = "platform"
+    }
+
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+
+data "aws_iam_policy_document" "read" {}
+
+variable "region" {
+  default = "us-east-1"
+}
+
+locals {
+  name   = "acme"
+}
+
+module "vpc" {
+  source = "./modules/vpc"
+}
+
+output "bucket" {
+  value = aws_s3_bucket.logs.id
+}
+`
+	want := `terraform  (line 1) {
+  required_version = ">= 1.5"
+}
+# Logs of every service.
+# Kept for 90 days.
+aws_s3_bucket.logs  (line 7) {
+  bucket = "acme-logs"
+  tags = {
+    Team = "platform"
+  }
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+data.aws_iam_policy_document.read  (line 18) {}
+var.region  (line 20) {
+  default = "us-east-1"
+}
+local.name = "acme"  (line 25)
+module.vpc  (line 28) {
+  source = "./modules/vpc"
+}
+output.bucket  (line 32) {
+  value = aws_s3_bucket.logs.id
+}
+`
+	if got := structureTerraform(content); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+	if got := structureTerraform("# only a comment\n"); got != "" {
+		t.Errorf("Expected no output without blocks, got %q", got)
+	}
+}
+
+func TestStructureScan(t *testing.T) {
+	scan := &ScanResult{Files: []ScannedFile{
+		{Path: "main.tf", Language: "terraform", Content: "resource \"aws_vpc\" \"main\" {\n  cidr_block    = \"10.0.0.0/16\"\n}\n"},
+		{Path: "big.tf", Language: "terraform", Content: "resource \"aws_vpc\" \"big\" {}\n", Truncated: true},
+		{Path: "prod.tfvars", Language: "terraform", Content: "region = \"us-east-1\"\n"},
+		{Path: "site.yml", Language: "yaml", Content: "- hosts: all\n"},
+	}}
+
+	structured := structureScan(scan)
+
+	if got := structured.Files[0].Content; !strings.HasPrefix(got, "aws_vpc.main  (line 1) {\n  cidr_block = \"10.0.0.0/16\"\n") {
+		t.Errorf("Expected main.tf to be structured, got %q", got)
+	}
+	for i := 1; i < len(scan.Files); i++ {
+		if structured.Files[i].Content != scan.Files[i].Content {
+			t.Errorf("Expected %s to be left as written, got %q", scan.Files[i].Path, structured.Files[i].Content)
+		}
+	}
+	if !strings.Contains(scan.Files[0].Content, "cidr_block    =") {
+		t.Errorf("Expected the original scan to be left untouched")
+	}
+}
+
+func TestStructureTerraformExpressions(t *testing.T) {
+	content := `resource "aws_instance" "web" {
+  user_data = base64encode(<<-EOT
+    #!/bin/bash
+    case "$1" in
+      start) systemctl start nginx ;;
+    esac
+  EOT
+  )
+  tags = { /* } */ Name = "web" }
+  ami  = "ami-123"
+}
+`
+	want := `aws_instance.web  (line 1) {
+  user_data = base64encode(<<-EOT
+    #!/bin/bash
+    case "$1" in
+      start) systemctl start nginx ;;
+    esac
+  EOT
+  )
+  tags = { /* } */ Name = "web" }
+  ami = "ami-123"
+}
+`
+	if got := structureTerraform(content); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+	if got := structureTerraform("resource \"aws_vpc\" \"main\" {\n  cidr_block = \n}\n"); got != "" {
+		t.Errorf("Expected no output for invalid HCL, got %q", got)
+	}
+}
@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tone selects who responses are written for.
+type Tone string
+
+const (
+	// ToneEngineer writes engineer-level detail for the people who maintain
+	// the code.
+	ToneEngineer Tone = "engineer"
+	// ToneExecutive writes a brief, plain-language summary for
+	// non-technical stakeholders.
+	ToneExecutive Tone = "executive"
+)
+
+var tones = map[Tone]string{
+	ToneEngineer:  "Write for the engineers who maintain this code: be specific and technical, reference the exact resources and settings involved, and include code where it helps.",
+	ToneExecutive: "Write for non-technical stakeholders: open with a short executive summary of the overall risk and cost, explain the business impact of each issue in plain language, avoid jargon and code, and keep the response brief.",
+}
+
+func (t Tone) valid() bool {
+	_, ok := tones[t]
+	return ok
+}
+
+// systemPrompt returns the instructions on the language and tone of every
+// response, or an empty string when neither is configured. The caller must
+// hold c.mu.
+func (c *AIClient) systemPrompt() string {
+	var parts []string
+	if c.tone != "" {
+		parts = append(parts, tones[c.tone])
+	}
+	if c.language != "" {
+		parts = append(parts, fmt.Sprintf("Write the response in %s. Keep code, resource names, file paths, and JSON field names unchanged.", c.language))
+	}
+	return strings.Join(parts, " ")
+}
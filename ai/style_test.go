@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSystemPrompt(t *testing.T) {
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-abc"),
+		WithModel("gpt-4"),
+		WithProvider("chatgpt"),
+		WithLanguage("Japanese"),
+		WithTone(ToneExecutive),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	_, body, err := client.requestBody(userTurn("analyze this"), false)
+	if err != nil {
+		t.Fatalf("requestBody failed: %v", err)
+	}
+	messages := body["messages"].([]map[string]interface{})
+	if len(messages) != 2 || messages[0]["role"] != "system" || messages[1]["content"] != "analyze this" {
+		t.Fatalf("Expected a system message before the prompt, got %v", messages)
+	}
+	for _, want := range []string{"in Japanese", "executive summary"} {
+		if !strings.Contains(messages[0]["content"].(string), want) {
+			t.Errorf("Expected the system prompt to contain %q, got %q", want, messages[0]["content"])
+		}
+	}
+
+	client.clientType = "anthropic_messages"
+	_, body, err = client.requestBody(userTurn("analyze this"), false)
+	if err != nil {
+		t.Fatalf("requestBody failed: %v", err)
+	}
+	if system, _ := body["system"].(string); !strings.Contains(system, "in Japanese") {
+		t.Errorf("Expected the Anthropic system field to be set, got %v", body["system"])
+	}
+
+	client.language, client.tone = "", ""
+	_, body, _ = client.requestBody(userTurn("analyze this"), false)
+	if _, ok := body["system"]; ok {
+		t.Errorf("Expected no system prompt without a language or tone")
+	}
+
+	if _, err := NewAIClientWithOptions(WithAPIKey("k"), WithModel("m"), WithProvider("chatgpt"), WithTone("casual")); err == nil {
+		t.Errorf("Expected an error for an unknown tone")
+	}
+}
@@ -0,0 +1,149 @@
+package ai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Suppression silences findings. Rule is a category ID such as KADO-101 or
+// a finding fingerprint; Path optionally limits it to files matching a
+// path.Match pattern, or to a directory when it ends with a slash.
+type Suppression struct {
+	Rule   string
+	Path   string
+	Reason string
+}
+
+// Matches reports whether the suppression applies to f.
+func (s Suppression) Matches(f Finding) bool {
+	if s.Rule != f.ID && s.Rule != f.Fingerprint {
+		return false
+	}
+	if s.Path == "" {
+		return true
+	}
+	if strings.HasSuffix(s.Path, "/") {
+		return strings.HasPrefix(f.File, s.Path)
+	}
+	matched, _ := path.Match(s.Path, f.File)
+	return matched
+}
+
+// ParseSuppressions reads a suppression file: one "RULE [PATH] [# reason]"
+// entry per line, where blank lines and lines starting with # are skipped.
+func ParseSuppressions(r io.Reader) ([]Suppression, error) {
+	var suppressions []Suppression
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var s Suppression
+		if i := strings.Index(line, "#"); i >= 0 {
+			s.Reason = strings.TrimSpace(line[i+1:])
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: expected RULE [PATH], got %q", lineNumber, line)
+		}
+		s.Rule = fields[0]
+		if strings.HasPrefix(strings.ToUpper(s.Rule), "KADO-") {
+			s.Rule = strings.ToUpper(s.Rule)
+			if _, ok := LookupFindingCategory(s.Rule); !ok {
+				return nil, fmt.Errorf("line %d: unknown finding category %s", lineNumber, s.Rule)
+			}
+		}
+		if len(fields) == 2 {
+			s.Path = fields[1]
+		}
+		suppressions = append(suppressions, s)
+	}
+	return suppressions, scanner.Err()
+}
+
+// Baseline is the set of findings accepted at some point in time, so that
+// later runs only report new ones.
+type Baseline struct {
+	Fingerprints []string `json:"fingerprints"`
+}
+
+// BaselinePath returns where the project's baseline is stored:
+// .kado/baseline.json under the IaC path.
+func (c *AIClient) BaselinePath() string {
+	return c.projectPath("baseline.json")
+}
+
+// WriteBaseline records the fingerprints of findings as the baseline at
+// path.
+func WriteBaseline(path string, findings []Finding) error {
+	baseline := Baseline{Fingerprints: []string{}}
+	for _, f := range findings {
+		if f.Fingerprint != "" {
+			baseline.Fingerprints = append(baseline.Fingerprints, f.Fingerprint)
+		}
+	}
+	sort.Strings(baseline.Fingerprints)
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// filterFindings removes the findings suppressed by .kado/suppressions or
+// recorded in .kado/baseline.json.
+func (c *AIClient) filterFindings(findings []Finding) ([]Finding, error) {
+	var suppressions []Suppression
+	suppressionsPath := c.projectPath("suppressions")
+	if file, err := os.Open(suppressionsPath); err == nil {
+		suppressions, err = ParseSuppressions(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", suppressionsPath, err)
+		}
+	}
+
+	baselined := make(map[string]bool)
+	if data, err := os.ReadFile(c.BaselinePath()); err == nil {
+		var baseline Baseline
+		if err := json.Unmarshal(data, &baseline); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", c.BaselinePath(), err)
+		}
+		for _, fp := range baseline.Fingerprints {
+			baselined[fp] = true
+		}
+	}
+
+	var kept []Finding
+	dropped := 0
+	for _, f := range findings {
+		suppressed := baselined[f.Fingerprint]
+		for _, s := range suppressions {
+			if suppressed {
+				break
+			}
+			suppressed = s.Matches(f)
+		}
+		if suppressed {
+			dropped++
+			continue
+		}
+		kept = append(kept, f)
+	}
+	if dropped > 0 {
+		fmt.Fprintf(c.messages(), "%d findings were suppressed or are in the baseline\n", dropped)
+	}
+	return kept, nil
+}
@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseSuppressions(t *testing.T) {
+	suppressions, err := ParseSuppressions(strings.NewReader(`
+# Legacy stack is being decommissioned.
+kado-101 terraform/legacy/   # accepted until Q3
+KADO-501 terraform/*.tf
+3f9a1c2e4b5d6e7f8a9b0c1d2e3f4a5b
+`))
+	if err != nil {
+		t.Fatalf("ParseSuppressions failed: %v", err)
+	}
+	if len(suppressions) != 3 {
+		t.Fatalf("Expected 3 suppressions, got %d", len(suppressions))
+	}
+	if suppressions[0].Rule != "KADO-101" || suppressions[0].Path != "terraform/legacy/" || suppressions[0].Reason != "accepted until Q3" {
+		t.Errorf("Unexpected suppression: %+v", suppressions[0])
+	}
+
+	testCases := []struct {
+		finding Finding
+		want    bool
+	}{
+		{Finding{ID: "KADO-101", File: "terraform/legacy/sg.tf"}, true},
+		{Finding{ID: "KADO-101", File: "terraform/sg.tf"}, false},
+		{Finding{ID: "KADO-501", File: "terraform/versions.tf"}, true},
+		{Finding{ID: "KADO-501", File: "terraform/modules/vpc/versions.tf"}, false},
+		{Finding{ID: "KADO-103", File: "any.tf", Fingerprint: "3f9a1c2e4b5d6e7f8a9b0c1d2e3f4a5b"}, true},
+	}
+	for _, tc := range testCases {
+		matched := false
+		for _, s := range suppressions {
+			matched = matched || s.Matches(tc.finding)
+		}
+		if matched != tc.want {
+			t.Errorf("Expected suppressed=%v for %+v, got %v", tc.want, tc.finding, matched)
+		}
+	}
+
+	if _, err := ParseSuppressions(strings.NewReader("KADO-777\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected an unknown category error with a line number, got %v", err)
+	}
+}
+
+func TestFilterFindingsWithBaseline(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	client := &AIClient{iacPath: tmpDir}
+	old := Finding{ID: "KADO-102", File: "terraform/iam.tf", Fingerprint: "aaaa"}
+	suppressed := Finding{ID: "KADO-205", File: "terraform/s3.tf", Fingerprint: "bbbb"}
+	fresh := Finding{ID: "KADO-101", File: "terraform/sg.tf", Fingerprint: "cccc"}
+
+	if err := WriteBaseline(client.BaselinePath(), []Finding{old}); err != nil {
+		t.Fatalf("WriteBaseline failed: %v", err)
+	}
+	writeTestFiles(t, tmpDir, map[string]string{".kado/suppressions": "KADO-205\n"})
+
+	kept, err := client.filterFindings([]Finding{old, suppressed, fresh})
+	if err != nil {
+		t.Fatalf("filterFindings failed: %v", err)
+	}
+	if len(kept) != 1 || kept[0].Fingerprint != "cccc" {
+		t.Errorf("Expected only the new finding to be kept, got %+v", kept)
+	}
+}
@@ -0,0 +1,218 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultTerraformBinary = "terraform"
+
+// TerraformLockInfo mirrors the lock metadata terraform writes next to a
+// locally stored state file.
+type TerraformLockInfo struct {
+	ID        string `json:"ID"`
+	Operation string `json:"Operation"`
+	Info      string `json:"Info"`
+	Who       string `json:"Who"`
+	Version   string `json:"Version"`
+	Created   string `json:"Created"`
+	Path      string `json:"Path"`
+}
+
+// TerraformStatus describes the workspace and lock state of a terraform
+// working directory as seen before kado-ai runs anything in it.
+type TerraformStatus struct {
+	Dir       string
+	Workspace string
+	Locked    bool
+	Lock      *TerraformLockInfo
+}
+
+// terraformRunner shells out to terraform with safety interlocks: commands
+// that can modify state or infrastructure are refused unless writes were
+// explicitly allowed, and read-only commands never take the state lock.
+type terraformRunner struct {
+	binary      string
+	dir         string
+	workspace   string
+	allowWrites bool
+	// out is where warnings are printed.
+	out io.Writer
+}
+
+func (c *AIClient) terraform() *terraformRunner {
+	binary := c.terraformBinary
+	if binary == "" {
+		binary = defaultTerraformBinary
+	}
+	return &terraformRunner{
+		binary:      binary,
+		dir:         c.terraformRoots()[0],
+		workspace:   c.terraformWorkspace,
+		allowWrites: c.allowWrites,
+		out:         c.messages(),
+	}
+}
+
+// TerraformStatus reports the selected workspace and any existing state lock
+// for the first Terraform root under the IaC path, terraform/ by default.
+func (c *AIClient) TerraformStatus() (*TerraformStatus, error) {
+	return c.terraform().status()
+}
+
+func (r *terraformRunner) status() (*TerraformStatus, error) {
+	workspace, err := r.currentWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	lock, err := r.lockInfo(workspace)
+	if err != nil {
+		return nil, err
+	}
+	return &TerraformStatus{
+		Dir:       r.dir,
+		Workspace: workspace,
+		Locked:    lock != nil,
+		Lock:      lock,
+	}, nil
+}
+
+// currentWorkspace resolves the workspace terraform would use, honouring an
+// explicitly configured workspace, then TF_WORKSPACE, then the workspace
+// recorded by "terraform workspace select".
+func (r *terraformRunner) currentWorkspace() (string, error) {
+	if r.workspace != "" {
+		return r.workspace, nil
+	}
+	if ws := os.Getenv("TF_WORKSPACE"); ws != "" {
+		return ws, nil
+	}
+	data, err := os.ReadFile(filepath.Join(r.dir, ".terraform", "environment"))
+	if os.IsNotExist(err) {
+		return "default", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read terraform workspace: %v", err)
+	}
+	if ws := strings.TrimSpace(string(data)); ws != "" {
+		return ws, nil
+	}
+	return "default", nil
+}
+
+// lockInfo returns the lock held on the local state of workspace, or nil when
+// the state is not locked. Locks held by remote backends are only visible to
+// terraform itself, which is why read-only commands always run with
+// -lock=false.
+func (r *terraformRunner) lockInfo(workspace string) (*TerraformLockInfo, error) {
+	lockPath := filepath.Join(r.dir, ".terraform.tfstate.lock.info")
+	if workspace != "default" {
+		lockPath = filepath.Join(r.dir, "terraform.tfstate.d", workspace, ".terraform.tfstate.lock.info")
+	}
+	data, err := os.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terraform lock info: %v", err)
+	}
+	lock := &TerraformLockInfo{}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse terraform lock info %s: %v", lockPath, err)
+	}
+	return lock, nil
+}
+
+// isTerraformWrite reports whether a terraform invocation can modify state,
+// workspaces, or real infrastructure.
+func isTerraformWrite(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "apply", "destroy", "import", "refresh", "taint", "untaint", "force-unlock":
+		return true
+	case "state":
+		if len(args) < 2 {
+			return false
+		}
+		switch args[1] {
+		case "list", "show", "pull":
+			return false
+		}
+		return true
+	case "workspace":
+		if len(args) < 2 {
+			return false
+		}
+		switch args[1] {
+		case "list", "show":
+			return false
+		}
+		return true
+	case "init":
+		// init writes state only when it moves it to another backend or
+		// drops the record of the old one.
+		for _, arg := range args[1:] {
+			name := strings.TrimLeft(arg, "-")
+			value := ""
+			if i := strings.Index(name, "="); i >= 0 {
+				name, value = name[:i], name[i+1:]
+			}
+			switch name {
+			case "migrate-state", "force-copy", "reconfigure":
+				if value != "false" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (r *terraformRunner) run(ctx context.Context, args ...string) ([]byte, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no terraform command given")
+	}
+	write := isTerraformWrite(args)
+	if write && !r.allowWrites {
+		return nil, fmt.Errorf("refusing to run 'terraform %s': it can modify state or infrastructure and writes are not allowed (enable allow-writes to permit it)", strings.Join(args, " "))
+	}
+
+	status, err := r.status()
+	if err != nil {
+		return nil, err
+	}
+	if status.Locked {
+		if write {
+			return nil, fmt.Errorf("terraform state for workspace %q is locked by %s (operation %s, lock ID %s, created %s)",
+				status.Workspace, status.Lock.Who, status.Lock.Operation, status.Lock.ID, status.Lock.Created)
+		}
+		fmt.Fprintf(r.out, "Warning: terraform state for workspace %q is locked by %s; running read-only 'terraform %s' without taking the lock\n",
+			status.Workspace, status.Lock.Who, args[0])
+	}
+
+	if !write && args[0] == "plan" && !hasFlag(args, "-lock") {
+		args = append(append([]string{}, args...), "-lock=false")
+	}
+
+	env := []string{"TF_IN_AUTOMATION=1", "TF_INPUT=0"}
+	if r.workspace != "" {
+		env = append(env, "TF_WORKSPACE="+r.workspace)
+	}
+	return runCommand(ctx, r.dir, env, r.binary, args...)
+}
+
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag || strings.HasPrefix(arg, flag+"=") {
+			return true
+		}
+	}
+	return false
+}
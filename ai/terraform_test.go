@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func fakeRunCommand(t *testing.T, output string) *[][]string {
+	t.Helper()
+	var calls [][]string
+	original := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		return []byte(output), nil
+	}
+	t.Cleanup(func() { runCommand = original })
+	return &calls
+}
+
+func TestTerraformStatus(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tfDir := filepath.Join(tmpDir, "terraform")
+	if err := os.MkdirAll(filepath.Join(tfDir, ".terraform"), 0755); err != nil {
+		t.Fatalf("Failed to create terraform directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tfDir, ".terraform", "environment"), []byte("staging\n"), 0644); err != nil {
+		t.Fatalf("Failed to write environment file: %v", err)
+	}
+
+	client := &AIClient{iacPath: tmpDir}
+	status, err := client.TerraformStatus()
+	if err != nil {
+		t.Fatalf("TerraformStatus failed: %v", err)
+	}
+	if status.Workspace != "staging" {
+		t.Errorf("Expected workspace 'staging', got '%s'", status.Workspace)
+	}
+	if status.Locked {
+		t.Errorf("Expected state to be unlocked")
+	}
+
+	lockDir := filepath.Join(tfDir, "terraform.tfstate.d", "staging")
+	if err := os.MkdirAll(lockDir, 0755); err != nil {
+		t.Fatalf("Failed to create workspace state directory: %v", err)
+	}
+	lock := `{"ID":"abc-123","Operation":"OperationTypeApply","Who":"alice@laptop","Created":"2024-01-01T00:00:00Z"}`
+	if err := os.WriteFile(filepath.Join(lockDir, ".terraform.tfstate.lock.info"), []byte(lock), 0644); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+
+	status, err = client.TerraformStatus()
+	if err != nil {
+		t.Fatalf("TerraformStatus failed: %v", err)
+	}
+	if !status.Locked || status.Lock.Who != "alice@laptop" {
+		t.Errorf("Expected state locked by 'alice@laptop', got %+v", status.Lock)
+	}
+
+	// Read-only commands still run, without taking the lock.
+	calls := fakeRunCommand(t, "{}")
+	if _, err := client.terraform().run(context.Background(), "plan", "-out=tfplan"); err != nil {
+		t.Fatalf("Expected read-only plan to run on a locked state: %v", err)
+	}
+	if got := strings.Join((*calls)[0], " "); got != "terraform plan -out=tfplan -lock=false" {
+		t.Errorf("Unexpected terraform invocation: %s", got)
+	}
+
+	// Write commands are refused while the state is locked, even if allowed.
+	client.allowWrites = true
+	if _, err := client.terraform().run(context.Background(), "apply", "tfplan"); err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("Expected apply on a locked state to fail, got %v", err)
+	}
+}
+
+func TestTerraformWriteInterlock(t *testing.T) {
+	calls := fakeRunCommand(t, "")
+	client := &AIClient{iacPath: "/nonexistent"}
+
+	testCases := []struct {
+		args  []string
+		write bool
+	}{
+		{[]string{"plan"}, false},
+		{[]string{"validate"}, false},
+		{[]string{"show", "-json", "tfplan"}, false},
+		{[]string{"state", "list"}, false},
+		{[]string{"state", "rm", "aws_instance.web"}, true},
+		{[]string{"workspace", "select", "prod"}, true},
+		{[]string{"apply", "tfplan"}, true},
+		{[]string{"import", "aws_s3_bucket.b", "bucket"}, true},
+		{[]string{"init", "-backend=false", "-input=false"}, false},
+		{[]string{"init", "-migrate-state"}, true},
+		{[]string{"init", "-force-copy"}, true},
+		{[]string{"init", "-reconfigure"}, true},
+		{[]string{"init", "-reconfigure=false"}, false},
+	}
+
+	for _, tc := range testCases {
+		if got := isTerraformWrite(tc.args); got != tc.write {
+			t.Errorf("For args %v, expected write=%v, got %v", tc.args, tc.write, got)
+		}
+		_, err := client.terraform().run(context.Background(), tc.args...)
+		if tc.write && err == nil {
+			t.Errorf("Expected %v to be refused without allow-writes", tc.args)
+		}
+		if !tc.write && err != nil {
+			t.Errorf("Expected %v to run, got %v", tc.args, err)
+		}
+	}
+	if len(*calls) != 6 {
+		t.Errorf("Expected only the 6 read-only commands to run, got %d", len(*calls))
+	}
+}
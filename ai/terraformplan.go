@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SectionTerraformValidation holds the errors and warnings terraform
+// validate reported for each Terraform root when kado-ai runs terraform.
+const SectionTerraformValidation = "terraform validate"
+
+// terraformValidation is the output of terraform validate -json.
+type terraformValidation struct {
+	Valid       bool `json:"valid"`
+	Diagnostics []struct {
+		Severity string `json:"severity"`
+		Summary  string `json:"summary"`
+		Detail   string `json:"detail"`
+		Range    *struct {
+			Filename string `json:"filename"`
+			Start    struct {
+				Line int `json:"line"`
+			} `json:"start"`
+		} `json:"range"`
+	} `json:"diagnostics"`
+}
+
+// String renders the diagnostics one per line, with the file and line they
+// point at, followed by their detail.
+func (v terraformValidation) String() string {
+	var out strings.Builder
+	for _, d := range v.Diagnostics {
+		fmt.Fprintf(&out, "%s: %s", d.Severity, d.Summary)
+		if d.Range != nil {
+			fmt.Fprintf(&out, " (%s:%d)", d.Range.Filename, d.Range.Start.Line)
+		}
+		out.WriteString("\n")
+		if detail := strings.TrimSpace(d.Detail); detail != "" {
+			fmt.Fprintf(&out, "  %s\n", strings.ReplaceAll(detail, "\n", "\n  "))
+		}
+	}
+	return out.String()
+}
+
+// planTerraformRoots runs terraform in every Terraform root that holds
+// Terraform code, adding the plan of each to result in place of a saved
+// plan.
+func (c *AIClient) planTerraformRoots(ctx context.Context, result *ScanResult) {
+	for _, root := range c.terraformRoots() {
+		if !hasTerraformCode(result, root) {
+			continue
+		}
+		if err := c.planTerraformRoot(ctx, result, root); err != nil {
+			result.skip(filepath.Join(root, "plan.json"), err.Error())
+			fmt.Fprintf(c.messages(), "Warning: no plan for %s: %v\n", root, err)
+		}
+	}
+}
+
+// planTerraformRoot runs terraform init -backend=false and terraform
+// validate in root, and when the configuration is valid, terraform plan -out
+// and terraform show -json, adding the validation diagnostics and the plan
+// to result. The backend is only initialized for the plan, which needs the
+// state, and the plan is written to a temporary directory, never next to
+// the code.
+func (c *AIClient) planTerraformRoot(ctx context.Context, result *ScanResult, root string) error {
+	runner := c.terraform()
+	runner.dir = root
+	if _, err := lookPath(runner.binary); err != nil {
+		return fmt.Errorf("%s is not on the PATH", runner.binary)
+	}
+
+	if _, err := runner.run(ctx, "init", "-backend=false", "-input=false"); err != nil {
+		return fmt.Errorf("failed to initialize: %v", err)
+	}
+	// terraform validate exits with an error when the configuration is
+	// invalid, but still prints its diagnostics.
+	out, runErr := runner.run(ctx, "validate", "-json")
+	var validation terraformValidation
+	if err := json.Unmarshal(out, &validation); err != nil {
+		if runErr != nil {
+			return fmt.Errorf("failed to validate: %v", runErr)
+		}
+		return fmt.Errorf("failed to parse the output of terraform validate: %v", err)
+	}
+	if len(validation.Diagnostics) > 0 {
+		addOutput(result, SectionTerraformValidation, filepath.Join(root, "validate.txt"), validation.String())
+	}
+	if !validation.Valid {
+		return fmt.Errorf("the configuration is not valid")
+	}
+
+	if hasBackend(result, root) {
+		if _, err := runner.run(ctx, "init", "-input=false"); err != nil {
+			return fmt.Errorf("failed to initialize the backend: %v", err)
+		}
+	}
+	tmpDir, err := os.MkdirTemp("", "kado-ai-plan")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	planPath := filepath.Join(tmpDir, "tfplan")
+	args := []string{"plan", "-input=false", "-out=" + planPath}
+	if c.analysisType == AnalysisDrift {
+		args = append(args, "-refresh-only")
+	}
+	for _, path := range c.layoutPaths(c.terraformVarFiles, nil) {
+		args = append(args, "-var-file="+path)
+	}
+	if _, err := runner.run(ctx, args...); err != nil {
+		return fmt.Errorf("failed to plan: %v", err)
+	}
+	plan, err := runner.run(ctx, "show", "-json", planPath)
+	if err != nil {
+		return fmt.Errorf("failed to show the plan: %v", err)
+	}
+	addOutput(result, SectionTerraformPlan, filepath.Join(root, "plan.json"), string(plan))
+	return nil
+}
+
+// hasTerraformCode reports whether result holds Terraform code directly in
+// dir.
+func hasTerraformCode(result *ScanResult, dir string) bool {
+	for _, f := range result.Section(SectionTerraform) {
+		if filepath.Ext(f.Path) == ".tf" && filepath.Dir(f.Path) == result.rel(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBackend reports whether the Terraform code of result directly in dir
+// configures a backend or HCP Terraform.
+func hasBackend(result *ScanResult, dir string) bool {
+	for _, f := range result.Section(SectionTerraform) {
+		if filepath.Ext(f.Path) != ".tf" || filepath.Dir(f.Path) != result.rel(dir) {
+			continue
+		}
+		for _, terraform := range hclFind(parseHCL(f.Content), "terraform") {
+			if len(hclFind(terraform.Body, "backend")) > 0 || len(hclFind(terraform.Body, "cloud")) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,322 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Sections of Terragrunt content: the configuration files, and the
+// effective configuration of every stack.
+const (
+	SectionTerragrunt       = "terragrunt code"
+	SectionTerragruntStacks = "terragrunt stacks"
+)
+
+// terragruntFile is the configuration file of a Terragrunt stack.
+const terragruntFile = "terragrunt.hcl"
+
+// TerragruntStack is a directory with a terragrunt.hcl that no other
+// configuration includes, with its configuration merged with the files it
+// includes. Paths are relative to the IaC path.
+type TerragruntStack struct {
+	Dir string `json:"dir"`
+	// Source is the expression of terraform.source, from the stack or the
+	// last include that sets it.
+	Source       string                 `json:"source,omitempty"`
+	Includes     []string               `json:"includes,omitempty"`
+	Dependencies []TerragruntDependency `json:"dependencies,omitempty"`
+	// Inputs are the effective inputs, where the stack's own override those
+	// of its includes.
+	Inputs []TerragruntInput `json:"inputs,omitempty"`
+}
+
+// TerragruntDependency is a dependency block of a stack. Path is the
+// directory of the stack depended on, or empty if the config_path could not
+// be resolved.
+type TerragruntDependency struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+}
+
+// TerragruntInput is an effective input of a stack and the file that sets
+// it. Value is the source of its expression, which is not recorded outside
+// the prompt since it may hold secrets.
+type TerragruntInput struct {
+	Name  string `json:"name"`
+	Value string `json:"-"`
+	From  string `json:"from"`
+}
+
+var (
+	// terragruntParentFolders matches find_in_parent_folders(), with or
+	// without the name of the file to look for.
+	terragruntParentFolders = regexp.MustCompile(`^find_in_parent_folders\(\s*(?:"([^"]*)")?\s*\)$`)
+	// terragruntReadConfig matches the files read by read_terragrunt_config.
+	terragruntReadConfig = regexp.MustCompile(`read_terragrunt_config\(\s*(find_in_parent_folders\([^)]*\)|"[^"]*")`)
+)
+
+// terragruntConfig is a parsed Terragrunt configuration file.
+type terragruntConfig struct {
+	path  string
+	items []hclItem
+}
+
+// scanTerragrunt adds the Terragrunt configuration under the IaC path to
+// result, with the files the configurations include or read, and resolves
+// the effective configuration of every stack.
+func (c *AIClient) scanTerragrunt(result *ScanResult) error {
+	var paths []string
+	ignore := c.ignoreRules()
+	err := c.walk(result, c.iacPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != c.iacPath && (discoverySkipDirs[info.Name()] || ignore.ignored(c.iacPath, path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() == terragruntFile && !ignore.ignored(c.iacPath, path, false) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to look for Terragrunt configuration: %v", err)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	configs := map[string]*terragruntConfig{}
+	var read []string
+	load := func(path string) *terragruntConfig {
+		if config, ok := configs[path]; ok {
+			return config
+		}
+		config := &terragruntConfig{path: path}
+		configs[path] = config
+		if data, err := os.ReadFile(path); err == nil {
+			config.items = parseHCL(string(data))
+			for _, m := range terragruntReadConfig.FindAllStringSubmatch(string(data), -1) {
+				if target := c.resolveTerragruntPath(filepath.Dir(path), m[1]); target != "" {
+					read = append(read, target)
+				}
+			}
+		}
+		return config
+	}
+
+	included := map[string]bool{}
+	for _, path := range paths {
+		for _, include := range hclFind(load(path).items, "include") {
+			if target := c.resolveTerragruntPath(filepath.Dir(path), hclAttribute(include.Body, "path")); target != "" {
+				included[target] = true
+				load(target)
+			}
+		}
+	}
+	// Files read with read_terragrunt_config are collected as well.
+	for len(read) > 0 {
+		path := read[0]
+		read = read[1:]
+		load(path)
+	}
+
+	var files []string
+	for path := range configs {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	for _, path := range files {
+		if !ignore.ignored(c.iacPath, path, false) {
+			c.addFile(result, SectionTerragrunt, path)
+		}
+	}
+
+	for _, path := range paths {
+		if included[path] {
+			continue
+		}
+		result.Stacks = append(result.Stacks, c.terragruntStack(result, configs, path))
+	}
+	return nil
+}
+
+// terragruntStack merges the configuration at path with its includes the
+// way Terragrunt's default shallow merge does: attributes of the stack
+// override those of the includes, and inputs are merged key by key.
+// Includes with merge_strategy = "no_merge" are left out.
+func (c *AIClient) terragruntStack(result *ScanResult, configs map[string]*terragruntConfig, path string) TerragruntStack {
+	dir := filepath.Dir(path)
+	stack := TerragruntStack{Dir: result.rel(dir)}
+	inputs := map[string]int{}
+	merge := func(config *terragruntConfig) {
+		if config == nil {
+			return
+		}
+		for _, terraform := range hclFind(config.items, "terraform") {
+			if source := hclAttribute(terraform.Body, "source"); source != "" {
+				stack.Source = source
+			}
+		}
+		for _, dependency := range hclFind(config.items, "dependency") {
+			if len(dependency.Labels) == 0 {
+				continue
+			}
+			d := TerragruntDependency{Name: dependency.Labels[0]}
+			if target := c.resolveTerragruntPath(dir, hclAttribute(dependency.Body, "config_path")); target != "" {
+				d.Path = result.rel(filepath.Dir(target))
+			}
+			stack.Dependencies = append(stack.Dependencies, d)
+		}
+		for _, input := range hclObject(hclAttribute(config.items, "inputs")) {
+			if input.Block {
+				continue
+			}
+			in := TerragruntInput{Name: input.Name, Value: input.Value, From: result.rel(config.path)}
+			if i, ok := inputs[input.Name]; ok {
+				stack.Inputs[i] = in
+				continue
+			}
+			inputs[input.Name] = len(stack.Inputs)
+			stack.Inputs = append(stack.Inputs, in)
+		}
+	}
+
+	config := configs[path]
+	for _, include := range hclFind(config.items, "include") {
+		target := c.resolveTerragruntPath(dir, hclAttribute(include.Body, "path"))
+		if target == "" {
+			continue
+		}
+		stack.Includes = append(stack.Includes, result.rel(target))
+		if strategy, _ := hclString(hclAttribute(include.Body, "merge_strategy")); strategy != "no_merge" {
+			merge(configs[target])
+		}
+	}
+	merge(config)
+	return stack
+}
+
+// resolveTerragruntPath resolves the path expression of an include or
+// dependency of the configuration in dir: a string, which may interpolate
+// get_terragrunt_dir() and get_repo_root(), or find_in_parent_folders(),
+// which searches the parent directories up to the IaC path. It returns the
+// empty string if the expression cannot be resolved or names nothing that
+// exists under the IaC path.
+func (c *AIClient) resolveTerragruntPath(dir string, expr string) string {
+	expr = strings.TrimSpace(expr)
+	if m := terragruntParentFolders.FindStringSubmatch(expr); m != nil {
+		names := []string{m[1]}
+		if m[1] == "" {
+			names = []string{terragruntFile, "root.hcl"}
+		}
+		root := filepath.Clean(c.iacPath)
+		for parent := filepath.Dir(dir); underAny(parent, []string{root}); parent = filepath.Dir(parent) {
+			for _, name := range names {
+				if path := filepath.Join(parent, name); fileExists(path) {
+					return path
+				}
+			}
+			if parent == root {
+				break
+			}
+		}
+		return ""
+	}
+	if !strings.HasPrefix(expr, `"`) {
+		return ""
+	}
+	expr = strings.NewReplacer(
+		"${get_terragrunt_dir()}", dir,
+		"${get_original_terragrunt_dir()}", dir,
+		"${get_repo_root()}", c.iacPath,
+	).Replace(expr)
+	value, ok := hclString(expr)
+	if !ok {
+		return ""
+	}
+	if !filepath.IsAbs(value) {
+		value = filepath.Join(dir, value)
+	}
+	value = filepath.Clean(value)
+	if !underAny(value, []string{filepath.Clean(c.iacPath)}) {
+		return ""
+	}
+	if info, err := os.Stat(value); err == nil && info.IsDir() {
+		if path := filepath.Join(value, terragruntFile); fileExists(path) {
+			return path
+		}
+		return value
+	}
+	if !fileExists(value) {
+		return ""
+	}
+	return value
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// TerragruntStacks renders the effective configuration of every stack as
+// the prompt presents it, labeling each stack with its directory.
+func (r *ScanResult) TerragruntStacks() string {
+	var out strings.Builder
+	for _, stack := range r.Stacks {
+		fmt.Fprintf(&out, "Stack: %s\n", r.displayDir(stack.Dir))
+		if stack.Source != "" {
+			fmt.Fprintf(&out, "  Terraform source: %s\n", stack.Source)
+		}
+		if len(stack.Includes) > 0 {
+			var includes []string
+			for _, include := range stack.Includes {
+				includes = append(includes, r.displayPath(include))
+			}
+			fmt.Fprintf(&out, "  Includes: %s\n", strings.Join(includes, ", "))
+		}
+		if len(stack.Dependencies) > 0 {
+			var dependencies []string
+			for _, d := range stack.Dependencies {
+				if d.Path == "" {
+					dependencies = append(dependencies, d.Name)
+					continue
+				}
+				dependencies = append(dependencies, fmt.Sprintf("%s (%s)", d.Name, r.displayDir(d.Path)))
+			}
+			fmt.Fprintf(&out, "  Dependencies: %s\n", strings.Join(dependencies, ", "))
+		}
+		if len(stack.Inputs) > 0 {
+			out.WriteString("  Effective inputs:\n")
+			for _, input := range stack.Inputs {
+				fmt.Fprintf(&out, "    %s = %s  (from %s)\n", input.Name, input.Value, r.displayPath(input.From))
+			}
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// displayPath returns the name a collected file is presented under, or
+// path itself if it was not collected.
+func (r *ScanResult) displayPath(path string) string {
+	for _, f := range r.Files {
+		if f.Path == path {
+			return r.displayName(f)
+		}
+	}
+	return filepath.Join(r.Root, path)
+}
+
+// displayDir returns the name a stack directory is presented under, which
+// is anonymized along with its terragrunt.hcl.
+func (r *ScanResult) displayDir(dir string) string {
+	return filepath.Dir(r.displayPath(filepath.Join(dir, terragruntFile)))
+}
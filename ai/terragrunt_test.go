@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanTerragrunt(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"live/root.hcl": `
+terraform {
+  source = "git::https://example.com/modules.git//default"
+}
+inputs = {
+  region        = "us-east-1"
+  instance_type = "t3.micro"
+}
+`,
+		"live/prod/env.hcl": "locals {\n  env = \"prod\"\n}\n",
+		"live/prod/app/terragrunt.hcl": `
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+locals {
+  env = read_terragrunt_config(find_in_parent_folders("env.hcl"))
+}
+terraform {
+  source = "../../../modules//app"
+}
+dependency "vpc" {
+  config_path = "../vpc"
+}
+dependency "dns" {
+  config_path = "${get_terragrunt_dir()}/../../shared/dns"
+}
+inputs = {
+  instance_type = "m6i.large"
+  vpc_id        = dependency.vpc.outputs.vpc_id
+}
+`,
+		"live/prod/vpc/terragrunt.hcl": `
+include "root" {
+  path           = find_in_parent_folders("root.hcl")
+  merge_strategy = "no_merge"
+}
+`,
+		"live/.terragrunt-cache/abc/terragrunt.hcl": "inputs = {}\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	var files []string
+	for _, f := range result.Section(SectionTerragrunt) {
+		files = append(files, filepath.ToSlash(f.Path))
+	}
+	if got := strings.Join(files, ","); got != "live/prod/app/terragrunt.hcl,live/prod/env.hcl,live/prod/vpc/terragrunt.hcl,live/root.hcl" {
+		t.Errorf("Expected the stacks and the files they include or read, got %s", got)
+	}
+
+	if len(result.Stacks) != 2 {
+		t.Fatalf("Expected 2 stacks, got %+v", result.Stacks)
+	}
+	app := result.Stacks[0]
+	if app.Dir != filepath.Join("live", "prod", "app") || app.Source != `"../../../modules//app"` {
+		t.Errorf("Expected the stack's own source to override the included one, got %+v", app)
+	}
+	if len(app.Includes) != 1 || app.Includes[0] != filepath.Join("live", "root.hcl") {
+		t.Errorf("Expected the root include, got %v", app.Includes)
+	}
+	if len(app.Dependencies) != 2 || app.Dependencies[0].Path != filepath.Join("live", "prod", "vpc") || app.Dependencies[1].Path != "" {
+		t.Errorf("Expected the vpc dependency to be resolved and the missing dns one not, got %+v", app.Dependencies)
+	}
+	var inputs []string
+	for _, input := range app.Inputs {
+		inputs = append(inputs, input.Name+"="+input.Value+" from "+filepath.ToSlash(input.From))
+	}
+	expected := `region="us-east-1" from live/root.hcl, instance_type="m6i.large" from live/prod/app/terragrunt.hcl, vpc_id=dependency.vpc.outputs.vpc_id from live/prod/app/terragrunt.hcl`
+	if got := strings.Join(inputs, ", "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	if vpc := result.Stacks[1]; vpc.Source != "" || len(vpc.Inputs) != 0 || len(vpc.Includes) != 1 {
+		t.Errorf("Expected a no_merge include to be listed but not merged, got %+v", vpc)
+	}
+
+	stacks := result.TerragruntStacks()
+	for _, want := range []string{
+		"Stack: " + filepath.Join(tmpDir, "live", "prod", "app") + "\n",
+		"  Dependencies: vpc (" + filepath.Join(tmpDir, "live", "prod", "vpc") + "), dns\n",
+		`    instance_type = "m6i.large"  (from ` + filepath.Join(tmpDir, "live", "prod", "app", "terragrunt.hcl") + ")\n",
+	} {
+		if !strings.Contains(stacks, want) {
+			t.Errorf("Expected the stacks to contain %q, got:\n%s", want, stacks)
+		}
+	}
+}
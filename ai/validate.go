@@ -0,0 +1,161 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/janpreet/kado-ai/config"
+)
+
+// providerInfo describes what a supported provider accepts, so obviously
+// mismatched configuration can be rejected before any request is made.
+type providerInfo struct {
+	name          string
+	keyPrefix     string
+	modelPrefixes []string
+}
+
+var providerCatalog = map[string]providerInfo{
+	"chatgpt": {
+		name:          "OpenAI",
+		keyPrefix:     "sk-",
+		modelPrefixes: []string{"gpt-", "chatgpt-", "o1", "o3", "o4"},
+	},
+	"anthropic_messages": {
+		name:          "Anthropic",
+		keyPrefix:     "sk-ant-",
+		modelPrefixes: []string{"claude-"},
+	},
+}
+
+// ConfigError aggregates every problem found while validating a client's
+// configuration.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func supportedProviders() []string {
+	var names []string
+	for name := range providerCatalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// providerForModel returns the provider whose catalog claims model, if any.
+func providerForModel(model string) (string, bool) {
+	for name, info := range providerCatalog {
+		for _, prefix := range info.modelPrefixes {
+			if strings.HasPrefix(model, prefix) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// providerForKey returns the provider whose key prefix matches apiKey most
+// specifically; Anthropic keys also start with the OpenAI prefix.
+func providerForKey(apiKey string) (string, bool) {
+	owner, longest := "", 0
+	for name, info := range providerCatalog {
+		if strings.HasPrefix(apiKey, info.keyPrefix) && len(info.keyPrefix) > longest {
+			owner, longest = name, len(info.keyPrefix)
+		}
+	}
+	return owner, longest > 0
+}
+
+// Validate checks the client configuration for common mistakes and returns a
+// *ConfigError listing all of them, or nil if the configuration looks usable.
+func (c *AIClient) Validate() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var problems []string
+
+	info, knownProvider := providerCatalog[c.clientType]
+	if !knownProvider {
+		problems = append(problems, fmt.Sprintf("unknown provider AI_CLIENT=%q; supported providers are %s",
+			c.clientType, strings.Join(supportedProviders(), ", ")))
+	}
+
+	keys := splitAPIKeys(c.apiKey)
+	for i, apiKey := range keys {
+		name := "AI_API_KEY"
+		if len(keys) > 1 {
+			name = fmt.Sprintf("AI_API_KEY entry %d", i+1)
+		}
+		switch {
+		case apiKey == "":
+			problems = append(problems, name+" is empty")
+		case strings.TrimSpace(apiKey) != apiKey:
+			problems = append(problems, name+" has leading or trailing whitespace; remove it")
+		case strings.ContainsAny(apiKey, " \t\r\n"):
+			problems = append(problems, name+" contains whitespace; check that it was copied correctly")
+		case strings.HasPrefix(apiKey, `"`) || strings.HasPrefix(apiKey, "'"):
+			problems = append(problems, name+" is wrapped in quotes; .kdconfig values must not be quoted")
+		}
+		if owner, known := providerForKey(apiKey); knownProvider && known && owner != c.clientType {
+			problems = append(problems, fmt.Sprintf("%s looks like an %s key but AI_CLIENT is %q; did you mean AI_CLIENT=%s?",
+				name, providerCatalog[owner].name, c.clientType, owner))
+		}
+	}
+
+	switch {
+	case c.model == "":
+		problems = append(problems, "AI_MODEL is empty")
+	case strings.TrimSpace(c.model) != c.model:
+		problems = append(problems, "AI_MODEL has leading or trailing whitespace; remove it")
+	case knownProvider && c.baseURL == "":
+		// Gateways and compatible servers, such as Ollama, serve models of
+		// their own, so models are only checked against the public APIs.
+		owner, known := providerForModel(c.model)
+		if !known {
+			problems = append(problems, fmt.Sprintf("AI_MODEL=%q is not a known %s model; expected a name starting with %s",
+				c.model, info.name, strings.Join(info.modelPrefixes, ", ")))
+		} else if owner != c.clientType {
+			problems = append(problems, fmt.Sprintf("AI_MODEL=%q is an %s model but AI_CLIENT is %q; did you mean AI_CLIENT=%s?",
+				c.model, providerCatalog[owner].name, c.clientType, owner))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// configProblems returns the problems with a loaded .kdconfig that prevent it
+// from being used. Unrecognized keys are only problems in strict mode.
+func configProblems(values map[string]string) []string {
+	if !config.Strict(values) {
+		return nil
+	}
+	return config.Check(values)
+}
+
+// misspelledKeys describes unrecognized keys in values that look like typos
+// of recognized ones, for appending to a "not set" error.
+func misspelledKeys(values map[string]string) string {
+	var hints []string
+	for name := range values {
+		if _, known := config.Lookup(name); known {
+			continue
+		}
+		if suggestion, found := config.Suggest(name); found {
+			hints = append(hints, fmt.Sprintf("found %s, did you mean %s?", name, suggestion))
+		}
+	}
+	if len(hints) == 0 {
+		return ""
+	}
+	sort.Strings(hints)
+	return " (" + strings.Join(hints, "; ") + ")"
+}
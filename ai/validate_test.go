@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		client   *AIClient
+		problems []string
+	}{
+		{
+			name:   "valid openai",
+			client: &AIClient{apiKey: "sk-proj-abc", model: "gpt-4", clientType: "chatgpt"},
+		},
+		{
+			name:   "valid anthropic",
+			client: &AIClient{apiKey: "sk-ant-abc", model: "claude-3-sonnet-20240229", clientType: "anthropic_messages"},
+		},
+		{
+			name:     "anthropic key with chatgpt client",
+			client:   &AIClient{apiKey: "sk-ant-abc", model: "gpt-4", clientType: "chatgpt"},
+			problems: []string{"looks like an Anthropic key"},
+		},
+		{
+			name:     "openai key with anthropic client",
+			client:   &AIClient{apiKey: "sk-proj-abc", model: "claude-3-haiku-20240307", clientType: "anthropic_messages"},
+			problems: []string{"looks like an OpenAI key"},
+		},
+		{
+			name:     "trailing whitespace in key",
+			client:   &AIClient{apiKey: "sk-ant-abc\n", model: "claude-3-haiku-20240307", clientType: "anthropic_messages"},
+			problems: []string{"trailing whitespace"},
+		},
+		{
+			name:     "model from the other provider",
+			client:   &AIClient{apiKey: "sk-proj-abc", model: "claude-3-opus-20240229", clientType: "chatgpt"},
+			problems: []string{"is an Anthropic model"},
+		},
+		{
+			name:     "unknown provider and model reported together",
+			client:   &AIClient{apiKey: "'sk-abc'", model: "llama3", clientType: "openai"},
+			problems: []string{"unknown provider", "wrapped in quotes"},
+		},
+	}
+
+	for _, tc := range testCases {
+		err := tc.client.Validate()
+		if len(tc.problems) == 0 {
+			if err != nil {
+				t.Errorf("%s: expected no error, got %v", tc.name, err)
+			}
+			continue
+		}
+
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Errorf("%s: expected a *ConfigError, got %v", tc.name, err)
+			continue
+		}
+		if len(configErr.Problems) != len(tc.problems) {
+			t.Errorf("%s: expected %d problems, got %v", tc.name, len(tc.problems), configErr.Problems)
+		}
+		for _, want := range tc.problems {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: expected error to mention '%s', got '%s'", tc.name, want, err)
+			}
+		}
+	}
+}
+
+func TestConfigKeyTypos(t *testing.T) {
+	values := map[string]string{
+		"AI_APIKEY": "sk-proj-abc",
+		"AI_MODEL":  "gpt-4",
+		"AI_CLIENT": "chatgpt",
+	}
+	_, err := optionsFromConfig(values)
+	if err == nil || !strings.Contains(err.Error(), "found AI_APIKEY, did you mean AI_API_KEY?") {
+		t.Errorf("Expected the missing key error to point out the typo, got %v", err)
+	}
+
+	values["AI_API_KEY"] = "sk-proj-abc"
+	if _, err := optionsFromConfig(values); err != nil {
+		t.Errorf("Expected unrecognized keys to be ignored outside strict mode, got %v", err)
+	}
+
+	values["AI_STRICT_CONFIG"] = "true"
+	_, err = optionsFromConfig(values)
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || len(configErr.Problems) != 1 {
+		t.Errorf("Expected strict mode to reject the unrecognized key, got %v", err)
+	}
+}
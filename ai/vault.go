@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VaultFiles says what a scan does with files encrypted with Ansible Vault,
+// whose ciphertext only wastes tokens.
+type VaultFiles string
+
+const (
+	// VaultFilesMention keeps the file in the prompt, with a note that it is
+	// encrypted in place of its content, so the AI knows the variables it
+	// defines exist.
+	VaultFilesMention VaultFiles = "mention"
+	// VaultFilesExclude leaves the file out of the prompt entirely.
+	VaultFilesExclude VaultFiles = "exclude"
+)
+
+func (v VaultFiles) valid() bool {
+	return v == VaultFilesMention || v == VaultFilesExclude
+}
+
+// vaultNotice replaces the content of an encrypted file in the prompt.
+const vaultNotice = "# This file is encrypted with Ansible Vault; its content is not included."
+
+// isVaultEncrypted reports whether content is a file encrypted with
+// ansible-vault encrypt.
+func isVaultEncrypted(content string) bool {
+	return strings.HasPrefix(strings.TrimLeft(content, "\ufeff \t\r\n"), "$ANSIBLE_VAULT;")
+}
+
+// UnvaultedSecret is a variable in group_vars or host_vars holding a
+// plaintext value that looks like a secret and should be encrypted with
+// Ansible Vault.
+type UnvaultedSecret struct {
+	// Path is relative to the IaC path.
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Variable string `json:"variable"`
+}
+
+// vaultCandidate matches the names of variables that should be vaulted.
+var vaultCandidate = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|private_?key|access_?key|credentials?)$`)
+
+// findUnvaulted records the plaintext secrets in the group_vars and
+// host_vars files of the Ansible section. Values that are vaulted, empty, or
+// Jinja2 expressions such as "{{ vault_db_password }}" are fine.
+func findUnvaulted(result *ScanResult) {
+	for _, f := range result.Section(SectionAnsible) {
+		if f.Encrypted || !isInventoryVars(f.Path) {
+			continue
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(f.Content), &doc); err != nil {
+			continue
+		}
+		walkUnvaulted(&doc, func(key *yaml.Node, value *yaml.Node) {
+			result.Unvaulted = append(result.Unvaulted, UnvaultedSecret{Path: f.Path, Line: key.Line, Variable: key.Value})
+		})
+	}
+}
+
+// walkUnvaulted calls fn with every key of n named like a secret whose value
+// is a plaintext scalar.
+func walkUnvaulted(n *yaml.Node, fn func(key *yaml.Node, value *yaml.Node)) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if value.Kind == yaml.ScalarNode && vaultCandidate.MatchString(key.Value) && plaintextSecret(value) {
+				fn(key, value)
+			}
+		}
+	}
+	for _, child := range n.Content {
+		walkUnvaulted(child, fn)
+	}
+}
+
+func plaintextSecret(value *yaml.Node) bool {
+	if value.Tag == "!vault" || value.Tag == "!!null" || value.Tag == "!!bool" {
+		return false
+	}
+	v := strings.TrimSpace(value.Value)
+	return v != "" && !strings.Contains(v, "{{")
+}
+
+// isInventoryVars reports whether path is in a group_vars or host_vars
+// directory.
+func isInventoryVars(path string) bool {
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		if dir == "group_vars" || dir == "host_vars" {
+			return true
+		}
+	}
+	return false
+}
+
+// warnUnvaulted prints a warning on out for every plaintext secret found by
+// a scan.
+func warnUnvaulted(out io.Writer, result *ScanResult) {
+	for _, s := range result.Unvaulted {
+		fmt.Fprintf(out, "Warning: %s:%d: %s is not encrypted with Ansible Vault\n", s.Path, s.Line, s.Variable)
+	}
+}
@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanVaultFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"ansible/site.yml":                 "- hosts: all\n",
+		"ansible/group_vars/all/vault.yml": "$ANSIBLE_VAULT;1.1;AES256\n6231336539666234306139346433\n",
+	})
+	vault := filepath.Join("ansible", "group_vars", "all", "vault.yml")
+
+	testCases := []struct {
+		vaultFiles VaultFiles
+		included   bool
+	}{
+		{VaultFilesMention, true},
+		{VaultFilesExclude, false},
+	}
+	for _, tc := range testCases {
+		client := &AIClient{iacPath: tmpDir, vaultFiles: tc.vaultFiles}
+		result, err := client.Scan(context.Background())
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		var found *ScannedFile
+		for i, f := range result.Files {
+			if f.Path == vault {
+				found = &result.Files[i]
+			}
+		}
+		if !tc.included {
+			if found != nil {
+				t.Errorf("Expected the encrypted file to be excluded, got %+v", found)
+			}
+			if len(result.Skipped) == 0 || !strings.Contains(result.Skipped[len(result.Skipped)-1].Reason, "Ansible Vault") {
+				t.Errorf("Expected the encrypted file to be skipped, got %+v", result.Skipped)
+			}
+			continue
+		}
+		if found == nil || !found.Encrypted || found.Content != vaultNotice {
+			t.Errorf("Expected the encrypted file to be mentioned, got %+v", found)
+		}
+		if strings.Contains(result.Content(SectionAnsible), "6231336539666234306139346433") {
+			t.Errorf("Expected no ciphertext in the content")
+		}
+	}
+}
+
+func TestFindUnvaulted(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"ansible/group_vars/db.yml": "db_host: db.internal\n" +
+			"db_password: hunter2\n" +
+			"api_token: \"{{ vault_api_token }}\"\n" +
+			"admin_password: !vault |\n  $ANSIBLE_VAULT;1.1;AES256\n  6231\n" +
+			"users:\n  - name: deploy\n    password: s3cret\n",
+		"ansible/host_vars/web1.yml":          "ansible_become_password: ''\n",
+		"ansible/roles/app/defaults/main.yml": "app_secret: changeme\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var got []string
+	for _, s := range result.Unvaulted {
+		got = append(got, s.Variable)
+	}
+	if strings.Join(got, ",") != "db_password,password" {
+		t.Errorf("Expected db_password and password to be flagged, got %+v", result.Unvaulted)
+	}
+	if len(result.Unvaulted) > 0 && (result.Unvaulted[0].Path != filepath.Join("ansible", "group_vars", "db.yml") || result.Unvaulted[0].Line != 2) {
+		t.Errorf("Unexpected location %+v", result.Unvaulted[0])
+	}
+}
+
+func TestReadRepoFileVault(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"ansible/vault.yml": "$ANSIBLE_VAULT;1.1;AES256\n6231\n",
+	})
+
+	client := &AIClient{iacPath: tmpDir}
+	if content, err := client.readRepoFile("ansible/vault.yml"); err != nil || content != vaultNotice {
+		t.Errorf("Expected the vault notice, got %q, %v", content, err)
+	}
+	client.vaultFiles = VaultFilesExclude
+	if _, err := client.readRepoFile("ansible/vault.yml"); err == nil {
+		t.Errorf("Expected an error for an excluded encrypted file")
+	}
+}
@@ -0,0 +1,238 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SectionVersions holds the upgrade matrix of the providers and modules of
+// the Terraform code.
+const SectionVersions = "version audit"
+
+// terraformRegistryURL is the registry the latest versions of providers and
+// modules are looked up in. Tests replace it.
+var terraformRegistryURL = "https://registry.terraform.io"
+
+// registryModule matches the source of a module in the public registry:
+// namespace/name/provider, optionally preceded by the registry's hostname.
+var registryModule = regexp.MustCompile(`^(registry\.terraform\.io/)?([A-Za-z0-9_-]+)/([A-Za-z0-9_-]+)/([A-Za-z0-9_-]+)$`)
+
+// gitRef matches the ref a Git module source is pinned to.
+var gitRef = regexp.MustCompile(`[?&]ref=([^&]+)`)
+
+// versionNumber matches the first version number of a constraint.
+var versionNumber = regexp.MustCompile(`\d+(\.\d+){0,2}`)
+
+// versionPin is a provider or module that Terraform code requires, with its
+// version constraint, the version locked in .terraform.lock.hcl, and the
+// latest version in the registry.
+type versionPin struct {
+	Dir        string
+	Kind       string
+	Name       string
+	Source     string
+	Constraint string
+	Locked     string
+	Latest     string
+}
+
+// registryPath returns the path of pin in the registry API, or "" if it is
+// not in the public registry.
+func (p versionPin) registryPath() string {
+	if p.Kind == "module" {
+		if m := registryModule.FindStringSubmatch(p.Source); m != nil {
+			return fmt.Sprintf("/v1/modules/%s/%s/%s", m[2], m[3], m[4])
+		}
+		return ""
+	}
+	parts := strings.Split(strings.TrimPrefix(p.Source, "registry.terraform.io/"), "/")
+	if len(parts) != 2 {
+		return ""
+	}
+	return fmt.Sprintf("/v1/providers/%s/%s", parts[0], parts[1])
+}
+
+// upgrade returns how far pin is behind the latest version: major, minor,
+// patch, or none, comparing the locked version, or else the version of the
+// constraint. It is "" when either version is unknown.
+func (p versionPin) upgrade() string {
+	current := p.Locked
+	if current == "" {
+		current = versionNumber.FindString(p.Constraint)
+	}
+	if current == "" || p.Latest == "" {
+		return ""
+	}
+	have, latest := versionParts(current), versionParts(p.Latest)
+	for i, level := range []string{"major", "minor", "patch"} {
+		if latest[i] > have[i] {
+			return level
+		}
+		if latest[i] < have[i] {
+			break
+		}
+	}
+	return "none"
+}
+
+// versionParts returns the major, minor, and patch numbers of version.
+func versionParts(version string) [3]int {
+	var parts [3]int
+	for i, part := range strings.SplitN(versionNumber.FindString(version), ".", 3) {
+		parts[i], _ = strconv.Atoi(part)
+	}
+	return parts
+}
+
+// auditVersions adds the upgrade matrix of the providers and modules the
+// Terraform code of result requires to result, with their latest versions
+// looked up in the Terraform Registry. Only the names and sources of the
+// providers and modules are sent to the registry.
+func (c *AIClient) auditVersions(ctx context.Context, result *ScanResult) {
+	pins := versionPins(result)
+	if len(pins) == 0 {
+		return
+	}
+	httpClient := c.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	latest := map[string]string{}
+	for i := range pins {
+		path := pins[i].registryPath()
+		if path == "" {
+			continue
+		}
+		version, ok := latest[path]
+		if !ok {
+			var err error
+			if version, err = latestVersion(ctx, httpClient, path); err != nil {
+				fmt.Fprintf(c.messages(), "Warning: failed to look up the latest version of %s: %v\n", pins[i].Source, err)
+			}
+			latest[path] = version
+		}
+		pins[i].Latest = version
+	}
+	addOutput(result, SectionVersions, filepath.Join(c.iacPath, "version-audit.md"), renderVersionPins(pins))
+}
+
+// latestVersion returns the latest version of the provider or module at
+// path in the registry API.
+func latestVersion(ctx context.Context, httpClient *http.Client, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, terraformRegistryURL+path, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("the registry returned %s", resp.Status)
+	}
+	var body struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse the registry's response: %v", err)
+	}
+	return body.Version, nil
+}
+
+// versionPins returns the providers in the required_providers blocks and
+// the remote modules called by the Terraform code of result, with the
+// versions locked in the .terraform.lock.hcl of each directory.
+func versionPins(result *ScanResult) []versionPin {
+	pins := map[string]*versionPin{}
+	dirs := map[string]bool{}
+	for _, f := range result.Section(SectionTerraform) {
+		if filepath.Ext(f.Path) != ".tf" {
+			continue
+		}
+		dir := filepath.Dir(f.Path)
+		dirs[dir] = true
+		items := parseHCL(f.Content)
+		for _, terraform := range hclFind(items, "terraform") {
+			for _, required := range hclFind(terraform.Body, "required_providers") {
+				for _, provider := range required.Body {
+					pin := versionPin{Dir: dir, Kind: "provider", Name: provider.Name, Source: "hashicorp/" + provider.Name}
+					if constraint, ok := hclString(provider.Value); ok {
+						pin.Constraint = constraint
+					} else if object := hclObject(provider.Value); object != nil {
+						if source, ok := hclString(hclAttribute(object, "source")); ok {
+							pin.Source = strings.ToLower(source)
+						}
+						pin.Constraint, _ = hclString(hclAttribute(object, "version"))
+					}
+					pins[dir+"\x00provider\x00"+pin.Name] = &pin
+				}
+			}
+		}
+		for _, module := range hclFind(items, "module") {
+			source, ok := hclString(hclAttribute(module.Body, "source"))
+			if !ok || strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") || len(module.Labels) == 0 {
+				continue
+			}
+			pin := versionPin{Dir: dir, Kind: "module", Name: module.Labels[0], Source: source}
+			pin.Constraint, _ = hclString(hclAttribute(module.Body, "version"))
+			if m := gitRef.FindStringSubmatch(source); m != nil {
+				pin.Constraint = "ref=" + m[1]
+			}
+			pins[dir+"\x00module\x00"+pin.Name] = &pin
+		}
+	}
+
+	for dir := range dirs {
+		data, err := os.ReadFile(filepath.Join(result.Root, dir, ".terraform.lock.hcl"))
+		if err != nil {
+			continue
+		}
+		locked := map[string]string{}
+		for _, provider := range hclFind(parseHCL(string(data)), "provider") {
+			if len(provider.Labels) > 0 {
+				version, _ := hclString(hclAttribute(provider.Body, "version"))
+				locked[strings.TrimPrefix(strings.ToLower(provider.Labels[0]), "registry.terraform.io/")] = version
+			}
+		}
+		for _, pin := range pins {
+			if pin.Dir == dir && pin.Kind == "provider" {
+				pin.Locked = locked[strings.TrimPrefix(pin.Source, "registry.terraform.io/")]
+			}
+		}
+	}
+
+	var sorted []versionPin
+	for _, pin := range pins {
+		sorted = append(sorted, *pin)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Dir != b.Dir {
+			return a.Dir < b.Dir
+		}
+		if a.Kind != b.Kind {
+			return a.Kind > b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return sorted
+}
+
+// renderVersionPins renders pins as an upgrade matrix.
+func renderVersionPins(pins []versionPin) string {
+	var out strings.Builder
+	out.WriteString("| Directory | Kind | Name | Source | Constraint | Locked | Latest | Upgrade |\n| --- | --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, p := range pins {
+		fmt.Fprintf(&out, "| %s | %s | %s | %s | %s | %s | %s | %s |\n", p.Dir, p.Kind, p.Name, p.Source, p.Constraint, p.Locked, p.Latest, p.upgrade())
+	}
+	return out.String()
+}
@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAuditVersions(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/versions.tf": `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 4.0"
+    }
+    random = "~> 3.5"
+  }
+}
+`,
+		"terraform/main.tf": `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+}
+
+module "dns" {
+  source = "git::https://example.com/dns.git?ref=v1.2.0"
+}
+
+module "local" {
+  source = "./modules/local"
+}
+`,
+		"terraform/.terraform.lock.hcl": `provider "registry.terraform.io/hashicorp/aws" {
+  version     = "4.67.0"
+  constraints = "~> 4.0"
+}
+`,
+	})
+
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws":
+			fmt.Fprint(w, `{"version":"5.31.0"}`)
+		case "/v1/providers/hashicorp/random":
+			fmt.Fprint(w, `{"version":"3.6.0"}`)
+		case "/v1/modules/terraform-aws-modules/vpc/aws":
+			fmt.Fprint(w, `{"version":"5.1.2"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	originalRegistryURL := terraformRegistryURL
+	terraformRegistryURL = server.URL
+	defer func() { terraformRegistryURL = originalRegistryURL }()
+
+	client := &AIClient{iacPath: tmpDir, versionAudit: true}
+	result, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	versions := result.Section(SectionVersions)
+	if len(versions) != 1 {
+		t.Fatalf("Expected the upgrade matrix, got %+v", versions)
+	}
+	expected := `| Directory | Kind | Name | Source | Constraint | Locked | Latest | Upgrade |
+| --- | --- | --- | --- | --- | --- | --- | --- |
+| terraform | provider | aws | hashicorp/aws | ~> 4.0 | 4.67.0 | 5.31.0 | major |
+| terraform | provider | random | hashicorp/random | ~> 3.5 |  | 3.6.0 | minor |
+| terraform | module | dns | git::https://example.com/dns.git?ref=v1.2.0 | ref=v1.2.0 |  |  |  |
+| terraform | module | vpc | terraform-aws-modules/vpc/aws | 5.1.0 |  | 5.1.2 | patch |
+`
+	if versions[0].Content != expected {
+		t.Errorf("Expected the matrix:\n%s\ngot:\n%s", expected, versions[0].Content)
+	}
+	if len(requested) != 3 {
+		t.Errorf("Expected only the registry's providers and modules to be looked up, got %v", requested)
+	}
+	data, _ := client.promptData(result)
+	if !strings.Contains(data.Versions, "| aws | hashicorp/aws |") {
+		t.Errorf("Expected the matrix in the prompt, got %q", data.Versions)
+	}
+}
@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Symlinks says what a scan does with symbolic links.
+type Symlinks string
+
+const (
+	// SymlinksWithin follows links to files and directories inside the IaC
+	// path, such as a module shared between environments, and skips links
+	// to anything outside of it, which could expose files such as
+	// credentials that were never meant to be analyzed.
+	SymlinksWithin Symlinks = "within"
+	// SymlinksFollow follows every link.
+	SymlinksFollow Symlinks = "follow"
+	// SymlinksSkip skips every link.
+	SymlinksSkip Symlinks = "skip"
+)
+
+func (s Symlinks) valid() bool {
+	return s == SymlinksWithin || s == SymlinksFollow || s == SymlinksSkip
+}
+
+// walker walks a directory tree like filepath.Walk, following symbolic
+// links according to the symlinks setting. Followed links are reported
+// under their own path, so the files they lead to keep a path under the
+// IaC path, and links that lead back to a directory being walked are
+// skipped rather than followed forever.
+type walker struct {
+	symlinks Symlinks
+	root     string
+	result   *ScanResult
+	fn       filepath.WalkFunc
+	// active are the resolved directories being walked, from the root down.
+	active map[string]bool
+}
+
+// walk walks the tree at root, calling fn for every file and directory as
+// filepath.Walk does, and applying the symlink setting of the client. The
+// links it skips are recorded in result, which may be nil.
+func (c *AIClient) walk(result *ScanResult, root string, fn filepath.WalkFunc) error {
+	resolved, err := filepath.EvalSymlinks(c.iacPath)
+	if err != nil {
+		resolved = c.iacPath
+	}
+	w := &walker{symlinks: c.symlinks, root: resolved, result: result, fn: fn, active: map[string]bool{}}
+	// The root itself is walked even if it is a link, since it was asked for.
+	info, err := os.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = w.walk(root, info)
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func (w *walker) walk(path string, info os.FileInfo) error {
+	if !info.IsDir() {
+		return w.fn(path, info, nil)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		resolved = path
+	}
+	if w.active[resolved] {
+		w.skip(path, "symbolic link cycle")
+		return nil
+	}
+	w.active[resolved] = true
+	defer delete(w.active, resolved)
+
+	names, err := readDirNames(path)
+	if err := w.fn(path, info, err); err != nil || names == nil {
+		return err
+	}
+	for _, name := range names {
+		filename := filepath.Join(path, name)
+		fileInfo, err := os.Lstat(filename)
+		if err != nil {
+			if err := w.fn(filename, fileInfo, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+		if fileInfo.Mode()&os.ModeSymlink != 0 {
+			var ok bool
+			if fileInfo, ok = w.follow(filename); !ok {
+				continue
+			}
+		}
+		if err := w.walk(filename, fileInfo); err != nil {
+			if !fileInfo.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// follow returns the file info of the target of the link at path, named
+// after the link, and whether the link is to be followed.
+func (w *walker) follow(path string) (os.FileInfo, bool) {
+	if w.symlinks == SymlinksSkip {
+		w.skip(path, "symbolic link")
+		return nil, false
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		w.skip(path, "broken symbolic link")
+		return nil, false
+	}
+	if w.symlinks != SymlinksFollow && target != w.root && !strings.HasPrefix(target, w.root+string(filepath.Separator)) {
+		w.skip(path, "symbolic link to outside of the IaC path")
+		return nil, false
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		w.skip(path, "broken symbolic link")
+		return nil, false
+	}
+	return linkInfo{FileInfo: info, name: filepath.Base(path)}, true
+}
+
+// skip records a link that is not followed, once, though every scanner
+// that walks the IaC path comes across it.
+func (w *walker) skip(path string, reason string) {
+	if w.result == nil {
+		return
+	}
+	for _, entry := range w.result.Skipped {
+		if entry.Path == w.result.rel(path) && entry.Reason == reason {
+			return
+		}
+	}
+	w.result.skip(path, reason)
+}
+
+// linkInfo is the file info of the target of a link under the name of the
+// link.
+type linkInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i linkInfo) Name() string {
+	return i.name
+}
+
+// readDirNames returns the sorted names of the entries of dir, or nil and
+// the error if it cannot be read.
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
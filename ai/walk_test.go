@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSymlinks(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	outside, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(outside)
+
+	iacPath := filepath.Join(tmpDir, "iac")
+	writeTestFiles(t, iacPath, map[string]string{
+		"shared/vpc/main.tf":  "resource \"aws_vpc\" \"main\" {}\n",
+		"terraform/README.md": "# Environments\n",
+	})
+	writeTestFiles(t, outside, map[string]string{
+		"credentials.tf": "access_key = \"secret\"\n",
+	})
+	for link, target := range map[string]string{
+		"terraform/vpc":            filepath.Join(iacPath, "shared", "vpc"),
+		"terraform/credentials.tf": filepath.Join(outside, "credentials.tf"),
+		"terraform/loop":           filepath.Join(iacPath, "terraform"),
+		"terraform/missing.tf":     filepath.Join(iacPath, "missing.tf"),
+	} {
+		if err := os.Symlink(target, filepath.Join(iacPath, filepath.FromSlash(link))); err != nil {
+			t.Skipf("Symbolic links are not supported: %v", err)
+		}
+	}
+
+	scan := func(symlinks Symlinks) ([]string, []string) {
+		client := &AIClient{iacPath: iacPath, symlinks: symlinks}
+		result, err := client.Scan(context.Background())
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		var files, skipped []string
+		for _, f := range result.Section(SectionTerraform) {
+			files = append(files, filepath.ToSlash(f.Path))
+		}
+		for _, s := range result.Skipped {
+			if strings.Contains(s.Reason, "symbolic link") {
+				skipped = append(skipped, filepath.ToSlash(s.Path)+": "+s.Reason)
+			}
+		}
+		sort.Strings(skipped)
+		return files, skipped
+	}
+
+	files, skipped := scan(SymlinksWithin)
+	if got := strings.Join(files, ", "); got != "terraform/vpc/main.tf" {
+		t.Errorf("Expected the link within the IaC path to be followed, got %s", got)
+	}
+	expected := "terraform/credentials.tf: symbolic link to outside of the IaC path, " +
+		"terraform/loop: symbolic link cycle, " +
+		"terraform/missing.tf: broken symbolic link"
+	if got := strings.Join(skipped, ", "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	files, _ = scan(SymlinksFollow)
+	if got := strings.Join(files, ", "); got != "terraform/credentials.tf, terraform/vpc/main.tf" {
+		t.Errorf("Expected every link to be followed, got %s", got)
+	}
+
+	files, skipped = scan(SymlinksSkip)
+	if len(files) != 0 || len(skipped) != 4 {
+		t.Errorf("Expected every link to be skipped, got %v and %v", files, skipped)
+	}
+}
@@ -0,0 +1,218 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce gathers the changes of a refactoring step, such as a
+// search and replace across files, into a single analysis.
+const watchDebounce = 500 * time.Millisecond
+
+// Watch analyzes the IaC code like RunAI, then again whenever files under
+// the IaC path change, passing each result to fn, until ctx is cancelled.
+// Changes made while an analysis runs are queued, and analyzed together
+// once it is over. Only the files that changed are read again: the content
+// of the others is kept between scans. Changes to kado-ai's own outputs,
+// including the files of file sinks, to ignored files, and to directories
+// scans skip, such as .terraform, do not start an analysis. Every analysis
+// asks for consent as RunAI does, so a consent policy that approves on its
+// own suits unattended sessions. Watch returns once the analysis in
+// progress, if any, is over.
+func (c *AIClient) Watch(ctx context.Context, fn func(recommendations string, err error)) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch IaC path: %v", err)
+	}
+	defer watcher.Close()
+
+	root := c.iacPath
+	outputs := map[string]bool{}
+	for _, path := range append([]string{
+		c.AIInputPath(),
+		c.RedactionReportPath(),
+		c.ScanManifestPath(),
+		filepath.Join(root, "kado-manifest.json"),
+		filepath.Join(root, "kado-chat.md"),
+	}, c.sinkPaths()...) {
+		if abs, err := filepath.Abs(path); err == nil {
+			outputs[abs] = true
+		}
+	}
+	ignore := c.ignoreRules()
+	if err := c.watchTree(watcher, root, ignore); err != nil {
+		return fmt.Errorf("failed to watch IaC path: %v", err)
+	}
+
+	c.files = newFileCache()
+	defer func() { c.files = nil }()
+
+	done := make(chan struct{})
+	running := false
+	analyze := func() {
+		running = true
+		go func() {
+			recommendations, err := c.run("analyze", c.question)
+			fn(recommendations, err)
+			done <- struct{}{}
+		}()
+	}
+	analyze()
+
+	changed := map[string]bool{}
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			if running {
+				<-done
+			}
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			path := filepath.Clean(event.Name)
+			abs, _ := filepath.Abs(path)
+			if event.Op == fsnotify.Chmod || outputs[abs] || !c.watched(root, path, ignore) {
+				continue
+			}
+			cached := c.files.invalidate(path)
+			info, err := os.Stat(path)
+			dir := err == nil && info.IsDir()
+			if dir && event.Op&fsnotify.Create != 0 {
+				if err := c.watchTree(watcher, path, ignore); err != nil {
+					fmt.Fprintf(c.messages(), "Warning: failed to watch %s: %v\n", path, err)
+				}
+			}
+			if cached || dir || fileLanguage(path) != "" {
+				changed[path] = true
+				pending = time.After(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(c.messages(), "Warning: failed to watch %s: %v\n", root, err)
+		case <-pending:
+			pending = nil
+			if !running {
+				fmt.Fprintf(c.messages(), "%d changed files under %s, analyzing again\n", len(changed), root)
+				changed = map[string]bool{}
+				analyze()
+			}
+		case <-done:
+			running = false
+			if len(changed) > 0 && pending == nil {
+				fmt.Fprintf(c.messages(), "%d files changed under %s during the analysis, analyzing again\n", len(changed), root)
+				changed = map[string]bool{}
+				analyze()
+			}
+		}
+	}
+}
+
+// sinkPaths returns the paths of the files the file sinks write to.
+func (c *AIClient) sinkPaths() []string {
+	var paths []string
+	for _, sink := range c.sinks {
+		if file, ok := sink.(*fileSink); ok {
+			paths = append(paths, file.path)
+		}
+	}
+	return paths
+}
+
+// watchTree watches dir and the directories under it that scans walk.
+func (c *AIClient) watchTree(watcher *fsnotify.Watcher, dir string, ignore ignoreRules) error {
+	return c.walk(nil, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != dir && (discoverySkipDirs[info.Name()] || ignore.ignored(c.iacPath, path, true)) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// watched reports whether changes to path, under root, may change what a
+// scan collects: path is neither ignored nor a directory scans skip or
+// under one.
+func (c *AIClient) watched(root string, path string, ignore ignoreRules) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		if discoverySkipDirs[name] {
+			return false
+		}
+	}
+	return !ignore.ignored(root, path, false)
+}
+
+// fileCache holds the content of the files read by scans, so that a rescan
+// only reads the files that changed since the last one.
+type fileCache struct {
+	mu    sync.Mutex
+	files map[string]string
+	// generation counts invalidations, so that a read racing a change does
+	// not cache the content from before it.
+	generation int
+}
+
+func newFileCache() *fileCache {
+	return &fileCache{files: map[string]string{}}
+}
+
+// read returns the content of the file at path, from the cache if it has
+// not changed since it was last read.
+func (f *fileCache) read(path string) (string, error) {
+	f.mu.Lock()
+	content, ok := f.files[path]
+	generation := f.generation
+	f.mu.Unlock()
+	if ok {
+		return content, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	if f.generation == generation {
+		f.files[path] = string(data)
+	}
+	f.mu.Unlock()
+	return string(data), nil
+}
+
+// invalidate forgets the content of path, and of the files under it if it
+// is a directory, and reports whether any was cached.
+func (f *fileCache) invalidate(path string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.generation++
+	cached := false
+	for name := range f.files {
+		if name == path || strings.HasPrefix(name, path+string(filepath.Separator)) {
+			delete(f.files, name)
+			cached = true
+		}
+	}
+	return cached
+}
@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf":    "resource \"aws_vpc\" \"main\" {}\n",
+		"terraform/network.tf": "resource \"aws_subnet\" \"a\" {}\n",
+	})
+
+	var mu sync.Mutex
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		prompts = append(prompts, body.Messages[0].Content)
+		mu.Unlock()
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Enable flow logs."}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewAIClientWithOptions(
+		WithAPIKey("sk-ant-abc"),
+		WithModel("claude-3-haiku-20240307"),
+		WithProvider("anthropic_messages"),
+		WithIaCPath(tmpDir),
+		WithConsentPolicy(ConsentAutoApprove),
+		WithHTTPClient(testHTTPClient(t, server)),
+		WithSinks(NewFileSink(filepath.Join(tmpDir, "recommendations.json"))),
+	)
+	if err != nil {
+		t.Fatalf("NewAIClientWithOptions failed: %v", err)
+	}
+
+	results := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- client.Watch(ctx, func(recommendations string, err error) {
+			if err != nil {
+				t.Errorf("Analysis failed: %v", err)
+			}
+			results <- recommendations
+		})
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch failed: %v", err)
+		}
+	}()
+
+	waitForResult := func() {
+		select {
+		case <-results:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected an analysis")
+		}
+	}
+	waitForResult()
+
+	// Saving a file analyzes the code again, with the change.
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/main.tf": "resource \"aws_vpc\" \"main\" {\n  enable_dns_support = true\n}\n",
+	})
+	waitForResult()
+	mu.Lock()
+	last := prompts[len(prompts)-1]
+	mu.Unlock()
+	if !strings.Contains(last, "enable_dns_support") {
+		t.Errorf("Expected the changed file in the prompt, got %q", last)
+	}
+	if !strings.Contains(last, "aws_subnet") {
+		t.Errorf("Expected the unchanged file in the prompt, got %q", last)
+	}
+
+	// The outputs of the analysis, such as ai_input.txt and the file of the
+	// file sink, and changes to directories scans skip do not start another
+	// one.
+	writeTestFiles(t, tmpDir, map[string]string{
+		"terraform/.terraform/modules.json": "{}",
+	})
+	select {
+	case <-results:
+		t.Errorf("Expected no analysis without a change to the code")
+	case <-time.After(3 * watchDebounce):
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "terraform", "main.tf")
+	writeTestFiles(t, tmpDir, map[string]string{"terraform/main.tf": "one"})
+
+	cache := newFileCache()
+	if content, err := cache.read(path); err != nil || content != "one" {
+		t.Fatalf("Expected the file's content, got %q (%v)", content, err)
+	}
+	writeTestFiles(t, tmpDir, map[string]string{"terraform/main.tf": "two"})
+	if content, _ := cache.read(path); content != "one" {
+		t.Errorf("Expected the cached content until the file is invalidated, got %q", content)
+	}
+	if !cache.invalidate(filepath.Dir(path)) {
+		t.Errorf("Expected the directory's files to be invalidated")
+	}
+	if content, _ := cache.read(path); content != "two" {
+		t.Errorf("Expected the new content once invalidated, got %q", content)
+	}
+	if cache.invalidate(filepath.Join(tmpDir, "other.tf")) {
+		t.Errorf("Expected nothing cached for a file never read")
+	}
+}
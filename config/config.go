@@ -0,0 +1,401 @@
+// Package config loads and describes the .kdconfig files used by kado-ai.
+//
+// A .kdconfig file holds one KEY=value setting per line; blank lines and
+// lines starting with # are ignored. Settings can also be grouped in
+// sections, such as provider blocks, and list settings built up one item per
+// line (see Parse). Every recognized key is listed in Keys, from which
+// Schema derives a JSON Schema for editors and linters.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Value types of configuration keys.
+const (
+	TypeString  = "string"
+	TypeBoolean = "boolean"
+	TypeInteger = "integer"
+	TypeList    = "array"
+)
+
+// Key describes a recognized configuration key.
+type Key struct {
+	Name        string
+	Type        string
+	Description string
+	Enum        []string
+	Required    bool
+	// Project keys may also be set in a project's .kado/config. Every other
+	// key is only read from the user's own config, since the project config
+	// is committed by whoever controls the repository.
+	Project bool
+}
+
+// Keys lists every configuration key kado-ai recognizes.
+var Keys = []Key{
+	{Name: "AI_API_KEY", Type: TypeString,
+		Description: "API key for the AI provider, a comma-separated list of keys, or a reference such as env:NAME, file:PATH, gcp-sm:PROJECT/NAME, or azure-kv:VAULT/NAME. Required unless set in the provider's block."},
+	{Name: "AI_MODEL", Type: TypeString, Project: true,
+		Description: "Model to request from the AI provider. Required unless set in the provider's block."},
+	{Name: "AI_CLIENT", Type: TypeString, Required: true, Enum: []string{"chatgpt", "anthropic_messages"},
+		Description: "AI provider to use."},
+	{Name: "AI_BASE_URL", Type: TypeString,
+		Description: "Base URL of the provider's API, such as http://localhost:11434/v1 for a local Ollama server or a company gateway."},
+	{Name: "AI_KEY_ROTATION", Type: TypeString, Enum: []string{"round-robin", "on-429"},
+		Description: "How requests are spread across several API keys."},
+	{Name: "AI_PROMPT", Type: TypeString, Project: true,
+		Description: "Instruction that precedes the IaC content in the prompt."},
+	{Name: "ANALYSIS_TYPE", Type: TypeString, Project: true, Enum: []string{"comprehensive", "security", "cost", "reliability", "compliance", "drift", "well-architected"},
+		Description: "Persona of the analysis."},
+	{Name: "AI_COMPLIANCE_FRAMEWORK", Type: TypeString, Project: true, Enum: []string{"cis", "soc2", "hipaa", "pci-dss", "nist-800-53"},
+		Description: "Compliance framework a compliance analysis maps findings to, producing a control-by-control gap table."},
+	{Name: "AI_LANGUAGE", Type: TypeString, Project: true,
+		Description: "Language responses are written in, such as Japanese or German."},
+	{Name: "AI_TONE", Type: TypeString, Project: true, Enum: []string{"engineer", "executive"},
+		Description: "Audience of responses: engineer-level detail or an executive summary."},
+	{Name: "AI_CONTEXT", Type: TypeString, Project: true,
+		Description: "Free-form context about your stack that is added to the prompt."},
+	{Name: "AI_CONTEXT_WINDOW", Type: TypeInteger,
+		Description: "Prompt size in tokens above which the analysis is split into chunks and merged."},
+	{Name: "AI_MAX_TOKENS", Type: TypeInteger,
+		Description: "Maximum number of tokens of each response; longer responses are continued automatically."},
+	{Name: "AI_CONSENT_POLICY", Type: TypeString, Enum: []string{"always-ask", "auto-approve", "auto-approve-if-no-secrets-found"},
+		Description: "Whether to ask before sending data to the AI provider."},
+	{Name: "AI_SANITIZE_LEVEL", Type: TypeString, Enum: []string{"minimal", "standard", "paranoid"},
+		Description: "Which values are redacted, trading privacy for prompt fidelity."},
+	{Name: "AI_SCRUB_PII", Type: TypeBoolean,
+		Description: "Remove email addresses, phone numbers, and usernames in inventories and tags before sending."},
+	{Name: "AI_MASK_CLOUD_IDS", Type: TypeBoolean,
+		Description: "Replace AWS account IDs and ARNs, GCP project IDs, and Azure subscription IDs with consistent pseudonyms."},
+	{Name: "AI_BLOCK_ON_SECRETS", Type: TypeBoolean,
+		Description: "Refuse to send anything when private keys, cloud credentials, or other high-confidence secrets are found."},
+	{Name: "AI_PLACEHOLDER_FORMAT", Type: TypeString,
+		Description: "The form of the text replacing redacted values, with {kind}, {rule}, and, with reversible redaction, {n}."},
+	{Name: "AI_INPUT_PATH", Type: TypeString,
+		Description: "Where the sanitized AI input is saved instead of ai_input.txt in the IaC path."},
+	{Name: "AI_INPUT_RECIPIENTS", Type: TypeString,
+		Description: "Comma-separated age recipients to encrypt the saved AI input to."},
+	{Name: "AI_DELETE_INPUT", Type: TypeBoolean,
+		Description: "Delete the saved AI input once the run is over."},
+	{Name: "AI_EXPLAIN_REDACTIONS", Type: TypeBoolean,
+		Description: "Print every redaction with its rule ID, location, and match length."},
+	{Name: "AI_MAX_FILE_TOKENS", Type: TypeInteger,
+		Description: "Size in tokens above which a file is truncated or summarized (default 20000, 0 for no limit)."},
+	{Name: "AI_MAX_SCAN_TOKENS", Type: TypeInteger,
+		Description: "Combined size in tokens of the scanned files; beyond it only the most relevant files are kept (0, the default, for no limit)."},
+	{Name: "AI_OVERSIZED_FILES", Type: TypeString, Enum: []string{"truncate", "summarize"},
+		Description: "Whether files above AI_MAX_FILE_TOKENS are truncated to their beginning and end or summarized by the AI first."},
+	{Name: "AI_PLAN_FORMAT", Type: TypeString, Project: true, Enum: []string{"full", "summary"},
+		Description: "Whether Terraform plans are presented as they are or as a table of the resources they change."},
+	{Name: "AI_VAULT_FILES", Type: TypeString, Enum: []string{"mention", "exclude"},
+		Description: "Whether files encrypted with Ansible Vault are mentioned in the prompt or left out."},
+	{Name: "AI_STATE_INVENTORY", Type: TypeBoolean,
+		Description: "Include a resource inventory of Terraform state files, without attribute values, in the prompt."},
+	{Name: "AI_DIFF", Type: TypeString,
+		Description: "Git range, such as main...HEAD, whose changed files are the only ones analyzed."},
+	{Name: "AI_DIFF_CONTEXT", Type: TypeString, Enum: []string{"module", "files"},
+		Description: "Whether a diff-scoped analysis includes the other files of the changed files' modules or the changed files only."},
+	{Name: "AI_IAC_PATHS", Type: TypeString,
+		Description: "Comma-separated IaC paths, relative to the IaC path, scanned in one run and labeled in the prompt."},
+	{Name: "AI_PER_PATH_REPORTS", Type: TypeBoolean,
+		Description: "Analyze each of AI_IAC_PATHS separately and report on each in its own section."},
+	{Name: "AI_PROJECTS", Type: TypeBoolean,
+		Description: "Find the Terraform root modules and Ansible projects of a monorepo and report on each in its own section."},
+	{Name: "AI_TERRAFORM_ROOTS", Type: TypeString, Project: true,
+		Description: "Comma-separated directories scanned for Terraform code, relative to the IaC path; terraform by default."},
+	{Name: "AI_ANSIBLE_ROOTS", Type: TypeString, Project: true,
+		Description: "Comma-separated directories scanned for Ansible code, relative to the IaC path; ansible by default."},
+	{Name: "AI_ANSIBLE_PRUNE", Type: TypeBoolean, Project: true,
+		Description: "Leave out the Ansible YAML files no playbook uses, such as unapplied roles."},
+	{Name: "AI_SYMLINKS", Type: TypeString, Enum: []string{"within", "follow", "skip"},
+		Description: "Whether symbolic links are followed when they stay inside the IaC path, always, or never."},
+	{Name: "AI_BINARY_EXTENSIONS", Type: TypeString,
+		Description: "Comma-separated extensions of binary files never read, in addition to the built-in list."},
+	{Name: "AI_SCAN_ARCHIVES", Type: TypeBoolean,
+		Description: "Also collect the IaC code inside zip and tar archives under the IaC path."},
+	{Name: "AI_PLAN_FILES", Type: TypeString,
+		Description: "Comma-separated Terraform plans, in JSON format or binary, relative to the IaC path; found in the Terraform roots by default."},
+	{Name: "AI_ANSIBLE_CHECK_FILES", Type: TypeString,
+		Description: "Comma-separated saved outputs of ansible-playbook --check --diff, relative to the IaC path; ansible-check.txt in each Ansible root by default."},
+	{Name: "AI_TERRAFORM_PLAN", Type: TypeBoolean,
+		Description: "Run terraform init, validate, and plan in every Terraform root and analyze the plan instead of a saved one."},
+	{Name: "AI_TERRAFORM_BINARY", Type: TypeString,
+		Description: "Path of the terraform executable kado-ai runs; terraform on the PATH by default."},
+	{Name: "AI_TERRAFORM_WORKSPACE", Type: TypeString,
+		Description: "Terraform workspace kado-ai runs terraform in, passed as TF_WORKSPACE."},
+	{Name: "AI_TERRAFORM_VAR_FILES", Type: TypeString,
+		Description: "Comma-separated variable files passed to terraform plan, relative to the IaC path."},
+	{Name: "AI_POLICY_EVALUATION", Type: TypeBoolean,
+		Description: "Evaluate the Rego policies under the IaC path against every Terraform plan with conftest and analyze the results."},
+	{Name: "AI_SCANNER_RESULTS", Type: TypeString,
+		Description: "Comma-separated results files of tfsec, Trivy, Checkov, or any scanner writing SARIF, relative to the IaC path."},
+	{Name: "AI_INFRACOST", Type: TypeBoolean,
+		Description: "Run infracost breakdown for every Terraform root before analysis instead of using a saved infracost.json."},
+	{Name: "AI_LINT", Type: TypeBoolean,
+		Description: "Run tflint and ansible-lint before analysis and have their findings explained and remediated."},
+	{Name: "AI_DRIFT_REPORT", Type: TypeString,
+		Description: "A refresh-only plan in JSON format or a driftctl report, relative to the IaC path, whose drift is analyzed."},
+	{Name: "AI_VERSION_AUDIT", Type: TypeBoolean,
+		Description: "Compare the provider and module versions of the Terraform code with the latest in the Terraform Registry and propose an upgrade plan."},
+	{Name: "AI_KUBERNETES_CLUSTER", Type: TypeBoolean,
+		Description: "Include a read-only snapshot of the workloads, services, network policies, and RBAC of the live Kubernetes cluster."},
+	{Name: "AI_KUBERNETES_CONTEXT", Type: TypeString,
+		Description: "Kubeconfig context of the cluster read by AI_KUBERNETES_CLUSTER; the current context by default."},
+	{Name: "AI_AWS_ACCOUNT_HINTS", Type: TypeBoolean,
+		Description: "Include the account-level settings of the AWS account, read with read-only aws calls, such as GuardDuty and CloudTrail status."},
+	{Name: "AI_DISCOVER", Type: TypeBoolean,
+		Description: "Also collect Terraform code, Ansible content, and plans anywhere under the IaC path."},
+	{Name: "AI_INCLUDE", Type: TypeString,
+		Description: "Comma-separated globs; when set, only files matching one of them are scanned."},
+	{Name: "AI_EXCLUDE", Type: TypeString, Project: true,
+		Description: "Comma-separated gitignore-style patterns of files and directories never scanned."},
+	{Name: "AI_RESPECT_GITIGNORE", Type: TypeBoolean,
+		Description: "Leave out what the repository's .gitignore files ignore (default true)."},
+	{Name: "AI_CDK_SYNTH", Type: TypeBoolean,
+		Description: "Synthesize CDK and CDKTF projects before analysis."},
+	{Name: "AI_AZURE_RESOURCE_GROUP", Type: TypeString,
+		Description: "Resource group to run what-if against for Bicep and ARM templates before analysis."},
+	{Name: "AI_PULUMI_PREVIEW", Type: TypeBoolean,
+		Description: "Run pulumi preview for every Pulumi project before analysis."},
+	{Name: "AI_CI_PIPELINES", Type: TypeBoolean,
+		Description: "Also analyze GitHub Actions workflows, GitLab CI files, Jenkinsfiles, and other pipeline definitions."},
+	{Name: "AI_KUBERNETES_RENDER", Type: TypeBoolean,
+		Description: "Render Helm charts and build Kustomize overlays before analysis."},
+	{Name: "AI_ANONYMIZE_PATHS", Type: TypeBoolean,
+		Description: "Replace directory and file names in the prompt with stable pseudonyms."},
+	{Name: "AI_REVERSIBLE_REDACTION", Type: TypeBoolean,
+		Description: "Replace redacted values with stable numbered placeholders and restore them in the response."},
+	{Name: "AI_COMPRESS", Type: TypeBoolean,
+		Description: "Strip comments, collapse whitespace, minify JSON, and deduplicate repeated blocks before building the prompt."},
+	{Name: "AI_STRUCTURED_TERRAFORM", Type: TypeBoolean,
+		Description: "Present Terraform files block by block under their resource addresses in a normalized form."},
+	{Name: "AI_REFINE", Type: TypeBoolean,
+		Description: "Send the first response back to be verified against the code before it is reported."},
+	{Name: "AI_NOISE_FILTER", Type: TypeBoolean,
+		Description: "Collapse generic advice not tied to a specific resource into a general hygiene section."},
+	{Name: "AI_STREAM", Type: TypeBoolean,
+		Description: "Stream the response and update output sinks as sections complete."},
+	{Name: "AI_OUTPUT_FILE", Type: TypeString,
+		Description: "File kept up to date with the recommendations."},
+	{Name: "AI_GITHUB_PR", Type: TypeString,
+		Description: "Pull request (owner/name#number) to post the recommendations on, using GITHUB_TOKEN."},
+	{Name: "AI_EMBEDDING_PROVIDER", Type: TypeString, Enum: []string{"openai", "vertex", "ollama"},
+		Description: "Embedding provider used for the local RAG index."},
+	{Name: "AI_EMBEDDING_MODEL", Type: TypeString,
+		Description: "Embedding model; each provider has a default."},
+	{Name: "AI_EMBEDDING_API_KEY", Type: TypeString,
+		Description: "API key for the openai embedding provider; defaults to AI_API_KEY when AI_CLIENT is chatgpt."},
+	{Name: "AI_EMBEDDING_URL", Type: TypeString,
+		Description: "Base URL of the Ollama server for the ollama embedding provider."},
+	{Name: "AI_EMBEDDING_PROJECT", Type: TypeString,
+		Description: "Google Cloud project for the vertex embedding provider."},
+	{Name: "AI_EMBEDDING_LOCATION", Type: TypeString,
+		Description: "Google Cloud location for the vertex embedding provider."},
+	{Name: "AI_MANIFEST_SIGNER", Type: TypeString,
+		Description: "Sign a manifest of every run: ed25519:KEY_PATH for a local key, or cosign / cosign:KEY for Sigstore."},
+	{Name: "openai.api_key", Type: TypeString,
+		Description: "API key used when AI_CLIENT is chatgpt, overriding AI_API_KEY."},
+	{Name: "openai.model", Type: TypeString, Project: true,
+		Description: "Model used when AI_CLIENT is chatgpt, overriding AI_MODEL."},
+	{Name: "anthropic.api_key", Type: TypeString,
+		Description: "API key used when AI_CLIENT is anthropic_messages, overriding AI_API_KEY."},
+	{Name: "anthropic.model", Type: TypeString, Project: true,
+		Description: "Model used when AI_CLIENT is anthropic_messages, overriding AI_MODEL."},
+	{Name: "openai.base_url", Type: TypeString,
+		Description: "Base URL of the OpenAI-compatible API, overriding AI_BASE_URL when AI_CLIENT is chatgpt."},
+	{Name: "openai.sanitize_level", Type: TypeString, Enum: []string{"minimal", "standard", "paranoid"},
+		Description: "Sanitization level when sending to OpenAI, overriding AI_SANITIZE_LEVEL."},
+	{Name: "anthropic.base_url", Type: TypeString,
+		Description: "Base URL of the Anthropic API, overriding AI_BASE_URL when AI_CLIENT is anthropic_messages."},
+	{Name: "anthropic.sanitize_level", Type: TypeString, Enum: []string{"minimal", "standard", "paranoid"},
+		Description: "Sanitization level when sending to Anthropic, overriding AI_SANITIZE_LEVEL."},
+	{Name: "local.sanitize_level", Type: TypeString, Enum: []string{"minimal", "standard", "paranoid"},
+		Description: "Sanitization level when the base URL is on this machine, such as a local Ollama server."},
+	{Name: "sanitizer.extra_patterns", Type: TypeList,
+		Description: "Additional regular expressions whose matches are redacted as secrets, one per sanitizer.extra_patterns[]= line."},
+	{Name: "noise_filter.phrases", Type: TypeList,
+		Description: "Additional generic advice phrases for the noise filter, one per noise_filter.phrases[]= line; setting any enables the filter."},
+	{Name: "AI_STRICT_CONFIG", Type: TypeBoolean,
+		Description: "Reject configuration files containing unrecognized keys."},
+}
+
+// Lookup returns the description of a recognized key.
+func Lookup(name string) (Key, bool) {
+	for _, key := range Keys {
+		if key.Name == name {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// Path returns configPath, or the user's ~/.kdconfig when it is empty.
+func Path(configPath string) (string, error) {
+	if configPath != "" {
+		return configPath, nil
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(usr.HomeDir, ".kdconfig"), nil
+}
+
+// Load reads the settings in the .kdconfig file at configPath, or in the
+// user's ~/.kdconfig when configPath is empty.
+func Load(configPath string) (map[string]string, error) {
+	configPath, err := Path(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(configPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values, err := Parse(file)
+	if parseErr, ok := err.(*ParseError); ok {
+		parseErr.Path = configPath
+	}
+	return values, err
+}
+
+// Strict reports whether values opt in to strict mode.
+func Strict(values map[string]string) bool {
+	strict, _ := strconv.ParseBool(values["AI_STRICT_CONFIG"])
+	return strict
+}
+
+// Check returns a problem for every key in values that is not recognized,
+// with a suggestion when it looks like a typo of a recognized key, and for
+// every recognized key whose value does not match its type.
+func Check(values map[string]string) []string {
+	var names []string
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		key, ok := Lookup(name)
+		if !ok {
+			problem := fmt.Sprintf("unrecognized key %s", name)
+			if suggestion, found := Suggest(name); found {
+				problem += fmt.Sprintf("; did you mean %s?", suggestion)
+			}
+			problems = append(problems, problem)
+			continue
+		}
+		value := values[name]
+		if key.Type == TypeBoolean {
+			if _, err := strconv.ParseBool(value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s must be true or false, got %q", name, value))
+			}
+		}
+		if len(key.Enum) > 0 && !contains(key.Enum, value) {
+			problems = append(problems, fmt.Sprintf("%s must be one of %s, got %q", name, strings.Join(key.Enum, ", "), value))
+		}
+	}
+	return problems
+}
+
+// Suggest returns the recognized key closest to name, if one is close enough
+// to be a likely typo.
+func Suggest(name string) (string, bool) {
+	normalized := normalize(name)
+	best, bestDistance := "", 3
+	for _, key := range Keys {
+		if normalize(key.Name) == normalized {
+			return key.Name, true
+		}
+		if d := distance(strings.ToUpper(name), strings.ToUpper(key.Name)); d < bestDistance {
+			best, bestDistance = key.Name, d
+		}
+	}
+	return best, best != ""
+}
+
+func normalize(name string) string {
+	return strings.ToUpper(strings.NewReplacer("_", "", "-", "", ".", "").Replace(name))
+}
+
+// distance is the Levenshtein edit distance between a and b.
+func distance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema returns a JSON Schema describing a .kdconfig file as an object of
+// its settings. Unrecognized keys are not allowed, matching strict mode.
+func Schema() ([]byte, error) {
+	properties := make(map[string]interface{})
+	var required []string
+	for _, key := range Keys {
+		property := map[string]interface{}{
+			"type":        key.Type,
+			"description": key.Description,
+		}
+		if len(key.Enum) > 0 {
+			property["enum"] = key.Enum
+		}
+		if key.Type == TypeList {
+			property["items"] = map[string]string{"type": TypeString}
+		}
+		properties[key.Name] = property
+		if key.Required {
+			required = append(required, key.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"title":                "kado-ai .kdconfig",
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
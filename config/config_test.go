@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	kdconfigPath := filepath.Join(tmpDir, ".kdconfig")
+	kdconfigContent := `
+# provider settings
+AI_API_KEY = test-api-key
+AI_MODEL=test-model
+AI_PROMPT=Check this: a=b
+`
+	if err := os.WriteFile(kdconfigPath, []byte(kdconfigContent), 0600); err != nil {
+		t.Fatalf("Failed to write test .kdconfig: %v", err)
+	}
+
+	values, err := Load(kdconfigPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	expected := map[string]string{
+		"AI_API_KEY": "test-api-key",
+		"AI_MODEL":   "test-model",
+		"AI_PROMPT":  "Check this: a=b",
+	}
+	for key, want := range expected {
+		if values[key] != want {
+			t.Errorf("Expected %s '%s', got '%s'", key, want, values[key])
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	problems := Check(map[string]string{
+		"AI_APIKEY":         "test-api-key",
+		"AI_MODLE":          "gpt-4",
+		"AI_CLIENT":         "openai",
+		"AI_STREAM":         "yes please",
+		"AI_CONSENT_POLICY": "auto-approve",
+	})
+
+	expected := []string{
+		"unrecognized key AI_APIKEY; did you mean AI_API_KEY?",
+		"AI_CLIENT must be one of chatgpt, anthropic_messages",
+		"unrecognized key AI_MODLE; did you mean AI_MODEL?",
+		"AI_STREAM must be true or false",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %v", len(expected), problems)
+	}
+	for i, want := range expected {
+		if !strings.HasPrefix(problems[i], want) {
+			t.Errorf("Expected problem '%s', got '%s'", want, problems[i])
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	data, err := Schema()
+	if err != nil {
+		t.Fatalf("Schema failed: %v", err)
+	}
+
+	var schema struct {
+		Properties           map[string]map[string]interface{} `json:"properties"`
+		Required             []string                          `json:"required"`
+		AdditionalProperties bool                              `json:"additionalProperties"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+	if len(schema.Properties) != len(Keys) {
+		t.Errorf("Expected %d properties, got %d", len(Keys), len(schema.Properties))
+	}
+	if schema.Properties["AI_STREAM"]["type"] != "boolean" {
+		t.Errorf("Expected AI_STREAM to be a boolean, got %v", schema.Properties["AI_STREAM"]["type"])
+	}
+	if strings.Join(schema.Required, ",") != "AI_CLIENT" {
+		t.Errorf("Unexpected required keys: %v", schema.Required)
+	}
+	if schema.AdditionalProperties {
+		t.Errorf("Expected additional properties to be rejected")
+	}
+}
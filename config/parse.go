@@ -0,0 +1,105 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseError reports a malformed line or a value of the wrong type.
+type ParseError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Message)
+}
+
+var sectionHeader = regexp.MustCompile(`^\[([A-Za-z0-9_-]+)\]$`)
+
+// Parse reads .kdconfig settings from r. Besides flat KEY=value lines, it
+// accepts:
+//
+//   - dotted keys such as openai.api_key=...
+//   - [section] headers, after which key=value means section.key=value
+//   - key[]=value lines, which append value to the list key
+//
+// List values are returned joined with newlines; use List to split them.
+// Recognized keys are checked against their type, and lines that are not
+// settings are ignored as they always were.
+func Parse(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			match := sectionHeader.FindStringSubmatch(line)
+			if match == nil {
+				return nil, &ParseError{Line: lineNumber, Message: fmt.Sprintf("invalid section header %q", line)}
+			}
+			section = strings.ToLower(match[1]) + "."
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		appending := strings.HasSuffix(name, "[]")
+		name = section + strings.TrimSpace(strings.TrimSuffix(name, "[]"))
+
+		key, known := Lookup(name)
+		if appending && known && key.Type != TypeList {
+			return nil, &ParseError{Line: lineNumber, Message: fmt.Sprintf("%s is not a list; use %s=value", name, name)}
+		}
+		if known {
+			if err := checkType(key, value); err != nil {
+				return nil, &ParseError{Line: lineNumber, Message: err.Error()}
+			}
+		}
+		if existing, ok := values[name]; ok && appending {
+			value = existing + "\n" + value
+		}
+		values[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// checkType reports whether value is valid for key's type.
+func checkType(key Key, value string) error {
+	switch key.Type {
+	case TypeBoolean:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", key.Name, value)
+		}
+	case TypeInteger:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s must be an integer, got %q", key.Name, value)
+		}
+	}
+	return nil
+}
+
+// List splits a list value returned by Parse or Load into its items.
+func List(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, "\n")
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSections(t *testing.T) {
+	values, err := Parse(strings.NewReader(`
+AI_CLIENT=anthropic_messages
+AI_STREAM=true
+
+openai.api_key=sk-proj-abc
+openai.model=gpt-4o
+
+[anthropic]
+api_key = sk-ant-abc
+model = claude-3-5-sonnet-20240620
+
+[sanitizer]
+extra_patterns[]=corp-[0-9]{6}
+extra_patterns[]=ticket=[A-Z]+-[0-9]+
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	expected := map[string]string{
+		"AI_CLIENT":         "anthropic_messages",
+		"AI_STREAM":         "true",
+		"openai.api_key":    "sk-proj-abc",
+		"openai.model":      "gpt-4o",
+		"anthropic.api_key": "sk-ant-abc",
+		"anthropic.model":   "claude-3-5-sonnet-20240620",
+	}
+	for key, want := range expected {
+		if values[key] != want {
+			t.Errorf("Expected %s '%s', got '%s'", key, want, values[key])
+		}
+	}
+	patterns := List(values["sanitizer.extra_patterns"])
+	if len(patterns) != 2 || patterns[0] != "corp-[0-9]{6}" || patterns[1] != "ticket=[A-Z]+-[0-9]+" {
+		t.Errorf("Expected two extra patterns, got %q", patterns)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"boolean", "AI_API_KEY=sk-abc\nAI_STREAM=yes please\n", "line 2: AI_STREAM must be true or false"},
+		{"section header", "[openai\napi_key=sk-abc\n", "line 1: invalid section header"},
+		{"append to scalar", "\n\nAI_MODEL[]=gpt-4\n", "line 3: AI_MODEL is not a list"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tc.content))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Expected error containing '%s', got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadReportsPathAndLine(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	kdconfigPath := filepath.Join(tmpDir, ".kdconfig")
+	if err := os.WriteFile(kdconfigPath, []byte("AI_API_KEY=sk-abc\nAI_CDK_SYNTH=maybe\n"), 0600); err != nil {
+		t.Fatalf("Failed to write test .kdconfig: %v", err)
+	}
+	_, err = Load(kdconfigPath)
+	if err == nil || err.Error() != kdconfigPath+`:2: AI_CDK_SYNTH must be true or false, got "maybe"` {
+		t.Errorf("Expected an error with the path and line number, got %v", err)
+	}
+}
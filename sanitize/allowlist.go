@@ -0,0 +1,151 @@
+package sanitize
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Allowlist holds values that are never redacted: exact values, addresses in
+// CIDR ranges, and values matching regular expressions. The value of a match
+// is the part a rule would replace, such as an address or the host of a URL,
+// so allowing 0.0.0.0 keeps the meaning of an ingress rule from 0.0.0.0/0.
+type Allowlist struct {
+	values   map[string]bool
+	networks []*net.IPNet
+	patterns []*regexp.Regexp
+}
+
+// AllowlistConfig describes an Allowlist. Presets name built-in lists:
+//
+//	documentation  the documentation address ranges and example.com domains
+//	private        the RFC 1918, shared, and unique local address ranges
+//	loopback       localhost and the loopback ranges
+//	endpoints      well-known public endpoints, such as the instance metadata
+//	               address, registries, and cloud API hosts
+type AllowlistConfig struct {
+	Presets  []string `yaml:"presets"`
+	Values   []string `yaml:"values"`
+	CIDRs    []string `yaml:"cidrs"`
+	Patterns []string `yaml:"patterns"`
+}
+
+// defaultAllowlist is part of every Allowlist: the unspecified and broadcast
+// addresses say nothing about a network.
+var defaultAllowlist = AllowlistConfig{
+	Values: []string{"0.0.0.0", "::", "255.255.255.255"},
+}
+
+var allowlistPresets = map[string]AllowlistConfig{
+	"documentation": {
+		CIDRs:    []string{"192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "2001:db8::/32"},
+		Patterns: []string{`^([a-z0-9-]+\.)*example\.(com|net|org)$`},
+	},
+	"private": {
+		CIDRs: []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"},
+	},
+	"loopback": {
+		Values: []string{"localhost"},
+		CIDRs:  []string{"127.0.0.0/8", "::1/128"},
+	},
+	"endpoints": {
+		Values: []string{
+			"169.254.169.254", "fd00:ec2::254", "metadata.google.internal",
+			"registry.terraform.io", "releases.hashicorp.com", "galaxy.ansible.com",
+			"github.com", "api.github.com", "raw.githubusercontent.com", "objects.githubusercontent.com",
+			"registry.npmjs.org", "pypi.org", "management.azure.com", "login.microsoftonline.com",
+		},
+		Patterns: []string{
+			`^[a-z0-9-]+(\.[a-z]{2}(-gov)?-[a-z]+-[0-9])?\.amazonaws\.com$`,
+			`^[a-z0-9-]+\.googleapis\.com$`,
+		},
+	},
+}
+
+// DefaultAllowlist returns the allowlist of a Sanitizer that was not given
+// one, which only allows the unspecified and broadcast addresses.
+func DefaultAllowlist() *Allowlist {
+	a, err := NewAllowlist(AllowlistConfig{})
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// NewAllowlist returns an Allowlist of the DefaultAllowlist, the presets of
+// cfg, and its values, ranges, and patterns.
+func NewAllowlist(cfg AllowlistConfig) (*Allowlist, error) {
+	a := &Allowlist{values: map[string]bool{}}
+	if err := a.add(defaultAllowlist); err != nil {
+		return nil, err
+	}
+	for _, name := range cfg.Presets {
+		preset, ok := allowlistPresets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown allowlist preset %q; expected one of %s", name, strings.Join(AllowlistPresets(), ", "))
+		}
+		if err := a.add(preset); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.add(cfg); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// AllowlistPresets returns the names of the built-in allowlists.
+func AllowlistPresets() []string {
+	var names []string
+	for name := range allowlistPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *Allowlist) add(cfg AllowlistConfig) error {
+	for _, value := range cfg.Values {
+		a.values[strings.ToLower(value)] = true
+	}
+	for _, cidr := range cfg.CIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid allowlist range %q: %v", cidr, err)
+		}
+		a.networks = append(a.networks, network)
+	}
+	for _, expr := range cfg.Patterns {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid allowlist pattern %q: %v", expr, err)
+		}
+		a.patterns = append(a.patterns, pattern)
+	}
+	return nil
+}
+
+// Allows reports whether value is on the allowlist.
+func (a *Allowlist) Allows(value string) bool {
+	if a == nil {
+		return false
+	}
+	if a.values[strings.ToLower(value)] {
+		return true
+	}
+	if ip := net.ParseIP(value); ip != nil {
+		for _, network := range a.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+	for _, pattern := range a.patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package sanitize
+
+import "testing"
+
+func TestAllowlist(t *testing.T) {
+	allowlist, err := NewAllowlist(AllowlistConfig{
+		Presets:  []string{"documentation", "endpoints"},
+		Values:   []string{"build.acme.io"},
+		CIDRs:    []string{"10.20.0.0/16"},
+		Patterns: []string{`^dev-[0-9]+$`},
+	})
+	if err != nil {
+		t.Fatalf("NewAllowlist failed: %v", err)
+	}
+
+	testCases := []struct {
+		value   string
+		allowed bool
+	}{
+		{"0.0.0.0", true},
+		{"192.0.2.10", true},
+		{"2001:db8::1", true},
+		{"www.example.com", true},
+		{"169.254.169.254", true},
+		{"ec2.us-east-1.amazonaws.com", true},
+		{"mybucket.s3.amazonaws.com", false},
+		{"Build.Acme.io", true},
+		{"10.20.3.4", true},
+		{"10.21.3.4", false},
+		{"dev-42", true},
+		{"db.acme.io", false},
+	}
+	for _, tc := range testCases {
+		if got := allowlist.Allows(tc.value); got != tc.allowed {
+			t.Errorf("Expected Allows(%q) to be %v, got %v", tc.value, tc.allowed, got)
+		}
+	}
+
+	content := "cidr_blocks = [\"0.0.0.0/0\"]\nsource = \"203.0.113.7\"\ntarget = \"10.1.2.3\"\nurl = \"https://www.example.com/app\"\n"
+	sanitized, found := Default().WithAllowlist(allowlist).Redact(content)
+	expected := "cidr_blocks = [\"0.0.0.0/0\"]\nsource = \"203.0.113.7\"\ntarget = \"[REDACTED]\"\nurl = \"https://www.example.com/app\"\n"
+	if sanitized != expected {
+		t.Errorf("Expected %q, got %q", expected, sanitized)
+	}
+	if len(found) != 1 {
+		t.Errorf("Expected allowed values not to be reported, got %+v", found)
+	}
+
+	for _, cfg := range []AllowlistConfig{
+		{Presets: []string{"no-such-preset"}},
+		{CIDRs: []string{"10.0.0.0"}},
+		{Patterns: []string{"("}},
+	} {
+		if _, err := NewAllowlist(cfg); err == nil {
+			t.Errorf("Expected an error for allowlist %+v", cfg)
+		}
+	}
+}
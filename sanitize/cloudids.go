@@ -0,0 +1,205 @@
+package sanitize
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CloudIDs replaces cloud account identifiers with pseudonyms of the same
+// format: AWS account IDs, the account and resource names of ARNs, GCP
+// project IDs, and Azure subscription IDs. An identifier gets the same
+// pseudonym wherever it appears and in every run that uses the same salt,
+// so the AI can still tell that two resources live in the same account, and
+// the identifiers behind the pseudonyms are kept so that text written about
+// them can be restored.
+type CloudIDs struct {
+	*pseudonyms
+}
+
+// The pseudonyms of CloudIDs have a recognizable form, so that they are not
+// replaced again.
+var (
+	accountPseudonym      = regexp.MustCompile(`^0000[0-9]{8}$`)
+	segmentPseudonym      = regexp.MustCompile(`^r[0-9a-f]{8}$`)
+	cloudIDPseudonym      = regexp.MustCompile(`^(0000[0-9]{8}|project-[0-9a-f]{8}|00000000-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+	awsAccountID          = regexp.MustCompile(`(^|[^0-9.:/-])([0-9]{12})($|[^0-9])`)
+	ecrAccountID          = regexp.MustCompile(`\b([0-9]{12})\.dkr\.ecr\.`)
+	accountContext        = regexp.MustCompile(`(?i)account|owner|principal|identifiers|"aws"`)
+	gcpProjectAssignment  = regexp.MustCompile(`(?i)(^|[^\w])"?(project|project_id|gcp_project|google_project)"?\s*[:=]\s*["']([a-z][a-z0-9-]{4,28}[a-z0-9])["']`)
+	gcpProjectPath        = regexp.MustCompile(`\bprojects/([a-z][a-z0-9-]{4,28}[a-z0-9])\b`)
+	azureSubscriptionGUID = `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+)
+
+// NewCloudIDs returns CloudIDs with a new random salt.
+func NewCloudIDs() (*CloudIDs, error) {
+	p, err := newPseudonyms()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate cloud ID salt: %v", err)
+	}
+	return &CloudIDs{p}, nil
+}
+
+// LoadCloudIDs reads CloudIDs saved with Save, so that they keep the salt
+// and identifiers of earlier runs. The error satisfies os.IsNotExist if
+// there are none yet.
+func LoadCloudIDs(path string) (*CloudIDs, error) {
+	p, err := loadPseudonyms(path, "cloud ID")
+	if err != nil {
+		return nil, err
+	}
+	return &CloudIDs{p}, nil
+}
+
+// Save writes the salt and identifiers to path, readable only by its owner
+// since they reveal the identifiers.
+func (c *CloudIDs) Save(path string) error {
+	return c.save(path)
+}
+
+// Restore replaces every pseudonym in text with the identifier it stands
+// for.
+func (c *CloudIDs) Restore(text string) string {
+	return c.restore(text)
+}
+
+// Rules returns the rules replacing cloud identifiers with their
+// pseudonyms. They should come before the built-in rules, which would
+// otherwise redact the hosts of ECR repository URLs. Account IDs outside of
+// ARNs and ECR hosts are only replaced on lines that talk about accounts,
+// owners, or principals, since other twelve-digit numbers are common.
+func (c *CloudIDs) Rules() []Rule {
+	return []Rule{
+		{ID: "aws-arn", Kind: "ARN", Replacement: redacted, Keywords: []string{"arn:"},
+			Pattern:   regexp.MustCompile(`\barn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9*-]*:([0-9]{12})?:[^\s"',\]]+`),
+			Pseudonym: c.arnPseudonym},
+		{ID: "aws-account-id", Kind: "ACCOUNT", Replacement: redacted, Find: findAccountIDs,
+			Keywords:  []string{"account", "owner", "principal", "identifiers", `"aws"`, ".dkr.ecr."},
+			Pseudonym: c.accountPseudonym},
+		{ID: "gcp-project-id", Kind: "PROJECT", Replacement: redacted, Find: findProjectIDs,
+			Keywords:  []string{"project"},
+			Pseudonym: c.projectPseudonym},
+		{ID: "azure-subscription-id", Kind: "SUBSCRIPTION", Replacement: redacted, SecretGroup: 3,
+			Keywords:  []string{"subscription"},
+			Pattern:   regexp.MustCompile(`(?i)(subscriptions/|subscription(_id)?"?\s*[:=]\s*["'])(` + azureSubscriptionGUID + `)`),
+			Pseudonym: c.subscriptionPseudonym},
+	}
+}
+
+// findAccountIDs finds the AWS account IDs in ECR hosts, and on lines about
+// accounts, owners, and principals.
+func findAccountIDs(content string) [][]int {
+	var spans [][]int
+	for _, m := range ecrAccountID.FindAllStringSubmatchIndex(content, -1) {
+		spans = append(spans, []int{m[2], m[3]})
+	}
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if accountContext.MatchString(line) {
+			for _, m := range awsAccountID.FindAllStringSubmatchIndex(line, -1) {
+				spans = append(spans, []int{offset + m[4], offset + m[5]})
+			}
+		}
+		offset += len(line)
+	}
+	return sortSpans(spans)
+}
+
+// findProjectIDs finds the GCP project IDs assigned to project settings and
+// in resource paths such as projects/<id>/topics/<name>.
+func findProjectIDs(content string) [][]int {
+	var spans [][]int
+	for _, m := range gcpProjectAssignment.FindAllStringSubmatchIndex(content, -1) {
+		spans = append(spans, []int{m[6], m[7]})
+	}
+	for _, m := range gcpProjectPath.FindAllStringSubmatchIndex(content, -1) {
+		spans = append(spans, []int{m[2], m[3]})
+	}
+	return sortSpans(spans)
+}
+
+// accountPseudonym returns a twelve-digit pseudonym of an AWS account ID.
+func (c *CloudIDs) accountPseudonym(id string) string {
+	if accountPseudonym.MatchString(id) {
+		return id
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, _ := strconv.ParseUint(c.hash("account:"+id, 12), 16, 64)
+	pseudonym := fmt.Sprintf("0000%08d", n%100000000)
+	c.names[pseudonym] = id
+	return pseudonym
+}
+
+// arnPseudonym replaces the account of an ARN, and every part of its
+// resource except the resource type, wildcards, and interpolations, such as
+// arn:aws:iam::0000xxxxxxxx:role/rxxxxxxxx for a role. The partition,
+// service, and region are kept.
+func (c *CloudIDs) arnPseudonym(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return arn
+	}
+	if parts[4] != "" {
+		parts[4] = c.accountPseudonym(parts[4])
+	}
+	resource := parts[5]
+	var out strings.Builder
+	segment := 0
+	start := 0
+	for i := 0; i <= len(resource); i++ {
+		if i < len(resource) && resource[i] != '/' && resource[i] != ':' {
+			continue
+		}
+		name := resource[start:i]
+		// S3 ARNs start with the bucket name rather than a resource type.
+		keep := segment == 0 && i < len(resource) && parts[2] != "s3"
+		if !keep && name != "" && name != "*" && !strings.Contains(name, "${") && !segmentPseudonym.MatchString(name) {
+			name = c.segmentPseudonym(name)
+		}
+		out.WriteString(name)
+		if i < len(resource) {
+			out.WriteByte(resource[i])
+		}
+		start = i + 1
+		segment++
+	}
+	parts[5] = out.String()
+	return strings.Join(parts, ":")
+}
+
+func (c *CloudIDs) segmentPseudonym(name string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pseudonym := "r" + c.hash("resource:"+name, 8)
+	c.names[pseudonym] = name
+	return pseudonym
+}
+
+// projectPseudonym returns a pseudonym of a GCP project ID that is itself a
+// valid project ID.
+func (c *CloudIDs) projectPseudonym(id string) string {
+	if cloudIDPseudonym.MatchString(id) {
+		return id
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pseudonym := "project-" + c.hash("project:"+id, 8)
+	c.names[pseudonym] = id
+	return pseudonym
+}
+
+// subscriptionPseudonym returns a GUID pseudonym of an Azure subscription
+// ID.
+func (c *CloudIDs) subscriptionPseudonym(id string) string {
+	if cloudIDPseudonym.MatchString(id) {
+		return id
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.hash("subscription:"+strings.ToLower(id), 24)
+	pseudonym := "00000000-" + h[0:4] + "-" + h[4:8] + "-" + h[8:12] + "-" + h[12:24]
+	c.names[pseudonym] = id
+	return pseudonym
+}
@@ -0,0 +1,97 @@
+package sanitize
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCloudIDs(t *testing.T) {
+	c, err := NewCloudIDs()
+	if err != nil {
+		t.Fatalf("NewCloudIDs failed: %v", err)
+	}
+	content := "File: main.tf\n" +
+		"role_arn  = \"arn:aws:iam::123456789012:role/deploy-prod\"\n" +
+		"bucket    = \"arn:aws:s3:::acme-payments-logs/*\"\n" +
+		"policy    = \"arn:aws:iam::aws:policy/ReadOnlyAccess\"\n" +
+		"owners    = [\"123456789012\"]\n" +
+		"image     = \"123456789012.dkr.ecr.us-east-1.amazonaws.com/api:1.2\"\n" +
+		"port      = 123456789012\n" +
+		"project   = \"acme-payments-prod\"\n" +
+		"topic     = \"projects/acme-payments-prod/topics/events\"\n" +
+		"scope     = \"/subscriptions/6f1c2a3b-4d5e-4f60-8a7b-9c0d1e2f3a4b/resourceGroups/rg\"\n"
+
+	sanitized, found := New(append(c.Rules(), DefaultRules()...)...).Redact(content)
+	for _, id := range []string{"123456789012:", "deploy-prod", "acme-payments", "\"123456789012\"", "123456789012.dkr", "6f1c2a3b"} {
+		if strings.Contains(sanitized, id) {
+			t.Errorf("Expected %s to be pseudonymized, got:\n%s", id, sanitized)
+		}
+	}
+	for _, kept := range []string{"arn:aws:iam::aws:policy/ReadOnlyAccess", "port      = 123456789012", ".dkr.ecr.us-east-1.amazonaws.com/api:1.2", "/resourceGroups/rg", "/topics/events"} {
+		if !strings.Contains(sanitized, kept) {
+			t.Errorf("Expected %q to be kept, got:\n%s", kept, sanitized)
+		}
+	}
+	for _, r := range found {
+		if r.Secret {
+			t.Errorf("Expected cloud IDs not to count as secrets, got %+v", r)
+		}
+	}
+
+	account := c.accountPseudonym("123456789012")
+	role := c.arnPseudonym("arn:aws:iam::123456789012:role/deploy-prod")
+	if !accountPseudonym.MatchString(account) || !strings.HasPrefix(role, "arn:aws:iam::"+account+":role/r") {
+		t.Errorf("Expected the ARN to keep its format and share the account pseudonym, got %s and %s", account, role)
+	}
+	if !strings.Contains(sanitized, role) || !strings.Contains(sanitized, account+".dkr.ecr.") {
+		t.Errorf("Expected the account to get the same pseudonym everywhere, got:\n%s", sanitized)
+	}
+
+	restored := c.Restore("Narrow " + role + " in " + c.projectPseudonym("acme-payments-prod") + ".")
+	if restored != "Narrow arn:aws:iam::123456789012:role/deploy-prod in acme-payments-prod." {
+		t.Errorf("Expected the identifiers to be restored, got %q", restored)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, ".kado", "cloud_ids.json")
+	if err := c.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := LoadCloudIDs(path)
+	if err != nil {
+		t.Fatalf("LoadCloudIDs failed: %v", err)
+	}
+	if got := loaded.accountPseudonym("123456789012"); got != account {
+		t.Errorf("Expected the same pseudonym after loading, got %s and %s", account, got)
+	}
+}
+
+func TestCloudIDsKeepPseudonyms(t *testing.T) {
+	c, err := NewCloudIDs()
+	if err != nil {
+		t.Fatalf("NewCloudIDs failed: %v", err)
+	}
+	sanitizer := New(c.Rules()...)
+	content := "File: main.tf\naccount_id = \"123456789012\"\nrole = \"arn:aws:iam::123456789012:role/app\"\n"
+	once, _ := sanitizer.Redact(content)
+	twice, found := sanitizer.Redact(once)
+	if once != twice || len(found) != 0 {
+		t.Errorf("Expected pseudonyms to be left alone, got %+v:\n%s\n%s", found, once, twice)
+	}
+
+	// Secrets in the form of a pseudonym are still redacted.
+	pin := Rule{ID: "pin", Secret: true, Replacement: "[REDACTED]", SecretGroup: 1, Pattern: regexp.MustCompile(`pin = "([^"]+)"`)}
+	sanitizer = New(append(c.Rules(), pin)...)
+	for _, secret := range []string{"000012345678", "project-1a2b3c4d", "00000000-1a2b-3c4d-5e6f-1a2b3c4d5e6f"} {
+		if sanitized, _ := sanitizer.Redact("pin = \"" + secret + "\"\n"); strings.Contains(sanitized, secret) {
+			t.Errorf("Expected the secret %s to be redacted, got %q", secret, sanitized)
+		}
+	}
+}
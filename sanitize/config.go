@@ -0,0 +1,183 @@
+package sanitize
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config customizes the rules of a Sanitizer. It is usually loaded from a
+// YAML file such as:
+//
+//	rules:
+//	  - id: acme-token
+//	    pattern: 'acme_[A-Za-z0-9]{32}'
+//	    high_confidence: true
+//	  - id: internal-host
+//	    pattern: '[a-z0-9-]+\.corp\.acme\.com'
+//	    secret: false
+//	    kind: HOST
+//	gitleaks:
+//	  - gitleaks.toml
+//	hostnames:
+//	  - .corp.example.com
+//	allowlist:
+//	  presets: [documentation]
+//	  cidrs: [100.64.0.0/10]
+//	disable:
+//	  - user-value
+type Config struct {
+	// Rules are applied after the built-in rules.
+	Rules []RuleConfig `yaml:"rules"`
+	// Gitleaks and Trufflehog list rule files of those scanners, relative
+	// to the configuration file, whose rules are applied after Rules.
+	Gitleaks   []string `yaml:"gitleaks"`
+	Trufflehog []string `yaml:"trufflehog"`
+	// Disable lists the IDs of built-in rules to skip.
+	Disable []string `yaml:"disable"`
+	// Allowlist lists values that are never redacted, in addition to the
+	// DefaultAllowlist.
+	Allowlist AllowlistConfig `yaml:"allowlist"`
+	// Hostnames lists internal DNS suffixes, such as .corp.example.com,
+	// whose hostnames are replaced with pseudonyms by Hostnames.
+	Hostnames []string `yaml:"hostnames"`
+
+	dir string
+}
+
+// RuleConfig is a user-defined rule. Replacement defaults to [REDACTED], and
+// Secret to true. Kind defaults to SECRET for secret rules and VALUE for the
+// others.
+type RuleConfig struct {
+	ID             string   `yaml:"id"`
+	Pattern        string   `yaml:"pattern"`
+	Replacement    string   `yaml:"replacement"`
+	Secret         *bool    `yaml:"secret"`
+	HighConfidence bool     `yaml:"high_confidence"`
+	Kind           string   `yaml:"kind"`
+	SecretGroup    int      `yaml:"secret_group"`
+	Keywords       []string `yaml:"keywords"`
+	Entropy        float64  `yaml:"entropy"`
+}
+
+// validKind matches the kinds that can appear in a placeholder.
+var validKind = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// LoadConfig reads the Config in the YAML file at path. Unknown fields are
+// rejected so that typos do not silently weaken sanitization.
+func LoadConfig(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cfg Config
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	cfg.dir = filepath.Dir(path)
+	return &cfg, nil
+}
+
+// Apply returns rules without the disabled ones, followed by the compiled
+// user-defined rules and the imported gitleaks and trufflehog rules. It fails
+// on an invalid pattern, a duplicate ID, or a disabled ID that matches
+// neither a rule nor a built-in rule of another Level.
+func (cfg *Config) Apply(rules []Rule) ([]Rule, error) {
+	disabled := map[string]bool{}
+	for _, id := range cfg.Disable {
+		disabled[id] = true
+	}
+	var out []Rule
+	ids := map[string]bool{}
+	for _, rule := range rules {
+		ids[rule.ID] = true
+		if !disabled[rule.ID] {
+			out = append(out, rule)
+		}
+	}
+	for _, id := range cfg.Disable {
+		if _, builtin := BuiltinRule(id); !ids[id] && !builtin {
+			return nil, fmt.Errorf("cannot disable unknown rule %q", id)
+		}
+	}
+
+	for i, rc := range cfg.Rules {
+		if rc.ID == "" {
+			return nil, fmt.Errorf("rule %d has no id", i+1)
+		}
+		if ids[rc.ID] {
+			return nil, fmt.Errorf("duplicate rule id %q", rc.ID)
+		}
+		ids[rc.ID] = true
+		if rc.Pattern == "" {
+			return nil, fmt.Errorf("rule %s has no pattern", rc.ID)
+		}
+		pattern, err := regexp.Compile(rc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for rule %s: %v", rc.ID, err)
+		}
+		if rc.Kind != "" && !validKind.MatchString(rc.Kind) {
+			return nil, fmt.Errorf("invalid kind %q for rule %s; expected upper-case letters, digits, and underscores", rc.Kind, rc.ID)
+		}
+		if rc.SecretGroup < 0 || rc.SecretGroup > pattern.NumSubexp() {
+			return nil, fmt.Errorf("rule %s has no group %d", rc.ID, rc.SecretGroup)
+		}
+		rule := Rule{
+			ID:             rc.ID,
+			Pattern:        pattern,
+			Replacement:    rc.Replacement,
+			Secret:         true,
+			HighConfidence: rc.HighConfidence,
+			Kind:           rc.Kind,
+			SecretGroup:    rc.SecretGroup,
+			Keywords:       rc.Keywords,
+			Entropy:        rc.Entropy,
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = redacted
+		}
+		if rc.Secret != nil {
+			rule.Secret = *rc.Secret
+		}
+		out = append(out, rule)
+	}
+
+	var imported []Rule
+	for _, path := range cfg.Gitleaks {
+		rules, err := LoadGitleaksRules(cfg.path(path))
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, rules...)
+	}
+	for _, path := range cfg.Trufflehog {
+		rules, err := LoadTrufflehogRules(cfg.path(path))
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, rules...)
+	}
+	for _, rule := range imported {
+		if ids[rule.ID] {
+			return nil, fmt.Errorf("duplicate rule id %q", rule.ID)
+		}
+		ids[rule.ID] = true
+		out = append(out, rule)
+	}
+	return out, nil
+}
+
+// path resolves a path in the configuration relative to its file.
+func (cfg *Config) path(path string) string {
+	if filepath.IsAbs(path) || cfg.dir == "" {
+		return path
+	}
+	return filepath.Join(cfg.dir, path)
+}
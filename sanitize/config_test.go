@@ -0,0 +1,81 @@
+package sanitize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "sanitize.yaml")
+	err = os.WriteFile(path, []byte(`rules:
+  - id: acme-token
+    pattern: 'acme_[A-Za-z0-9]{8}'
+  - id: internal-host
+    pattern: '[a-z0-9-]+\.corp\.acme\.com'
+    replacement: '[HOST]'
+    secret: false
+disable:
+  - ipv4-address
+`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	rules, err := cfg.Apply(DefaultRules())
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(rules) != len(DefaultRules())+1 {
+		t.Errorf("Expected one built-in rule replaced by two custom ones, got %d rules", len(rules))
+	}
+
+	sanitized, found := New(rules...).Redact("host = db1.corp.acme.com\nid = acme_AbCd1234\nip = 10.0.0.1\n")
+	expected := "host = [HOST]\nid = [REDACTED]\nip = 10.0.0.1\n"
+	if sanitized != expected {
+		t.Errorf("Expected %q, got %q", expected, sanitized)
+	}
+	for _, r := range found {
+		if r.Rule == "internal-host" && r.Secret {
+			t.Errorf("Expected internal-host not to be a secret rule")
+		}
+	}
+
+	testCases := []string{
+		"disable: [no-such-rule]\n",
+		"rules:\n  - id: ipv4-address\n    pattern: 'x'\n",
+		"rules:\n  - id: broken\n    pattern: '('\n",
+		"rules:\n  - pattern: 'x'\n",
+		"rules:\n  - id: empty\n",
+		"rules:\n  - id: lower\n    pattern: 'x'\n    kind: host\n",
+	}
+	for _, content := range testCases {
+		cfg := &Config{}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if cfg, err = LoadConfig(path); err != nil {
+			t.Fatalf("LoadConfig failed for %q: %v", content, err)
+		}
+		if _, err := cfg.Apply(DefaultRules()); err == nil {
+			t.Errorf("Expected an error for config %q", content)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("rulez: []\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Errorf("Expected an error for an unknown field")
+	}
+}
@@ -0,0 +1,64 @@
+package sanitize
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// corpusFile holds the samples of one rule in testdata/corpus.
+type corpusFile struct {
+	Redact []string `yaml:"redact"`
+	Keep   []string `yaml:"keep"`
+}
+
+func loadCorpus(t testing.TB) map[string]corpusFile {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to list the corpus: %v", err)
+	}
+	corpus := map[string]corpusFile{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		var file corpusFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			t.Fatalf("Failed to parse %s: %v", path, err)
+		}
+		corpus[strings.TrimSuffix(filepath.Base(path), ".yaml")] = file
+	}
+	return corpus
+}
+
+func TestCorpus(t *testing.T) {
+	corpus := loadCorpus(t)
+	for id, file := range corpus {
+		rule, ok := BuiltinRule(id)
+		if !ok {
+			t.Errorf("testdata/corpus/%s.yaml does not name a built-in rule", id)
+			continue
+		}
+		for _, sample := range file.Redact {
+			if len(rule.Matches(sample)) == 0 {
+				t.Errorf("Expected %s to redact %q", id, sample)
+			}
+		}
+		for _, sample := range file.Keep {
+			if matches := rule.Matches(sample); len(matches) > 0 {
+				t.Errorf("Expected %s to keep %q, but it redacted %q", id, sample, matches[0].Value)
+			}
+		}
+	}
+
+	for _, rule := range append(append(DefaultRules(), paranoidRules...), piiRules...) {
+		if _, ok := corpus[rule.ID]; !ok {
+			t.Errorf("Expected samples for %s in testdata/corpus/%s.yaml", rule.ID, rule.ID)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+//go:build go1.18
+// +build go1.18
+
+package sanitize
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// The fuzz targets start from the samples of testdata/corpus. Run one with,
+// for example, go test ./sanitize -fuzz FuzzRedact.
+
+func addCorpus(f *testing.F) {
+	for _, file := range loadCorpus(f) {
+		for _, sample := range append(file.Redact, file.Keep...) {
+			f.Add(sample)
+		}
+	}
+}
+
+func FuzzRedact(f *testing.F) {
+	addCorpus(f)
+	rules, err := LevelRules(LevelParanoid)
+	if err != nil {
+		f.Fatalf("LevelRules failed: %v", err)
+	}
+	s := New(append(rules, piiRules...)...)
+	f.Fuzz(func(t *testing.T, content string) {
+		sanitized, found := s.Redact(content)
+		again, _ := s.Redact(content)
+		if again != sanitized {
+			t.Errorf("Expected Redact to be deterministic, got %q and %q", sanitized, again)
+		}
+		matches := s.Preview(content)
+		if len(matches) != len(found) {
+			t.Errorf("Expected Preview to find the %d values Redact removed, got %d", len(found), len(matches))
+		}
+		for _, m := range matches {
+			if !strings.Contains(content, m.Value) {
+				t.Errorf("Preview reported %q, which is not in the content", m.Value)
+			}
+		}
+	})
+}
+
+func FuzzScanHCL(f *testing.F) {
+	addCorpus(f)
+	f.Fuzz(func(t *testing.T, src string) {
+		scanHCL(src, func(path []string, name string, v hclValue) {
+			if v.start > v.innerStart || v.innerStart > v.innerEnd || v.innerEnd > v.end || v.end > len(src) {
+				t.Errorf("Invalid span %+v of %s in %d bytes", v, name, len(src))
+			}
+		})
+	})
+}
+
+func FuzzRedactPlan(f *testing.F) {
+	f.Add(`{"resource_changes":[{"change":{"after":{"password":"x"},"after_sensitive":{"password":true}}}]}`)
+	f.Add(`{"variables":{"token":{"value":"x"}},"configuration":{"root_module":{"variables":{"token":{"sensitive":true}}}}}`)
+	f.Fuzz(func(t *testing.T, plan string) {
+		redacted, _, err := RedactPlan(plan)
+		if err != nil {
+			return
+		}
+		if !json.Valid([]byte(redacted)) {
+			t.Errorf("Expected a valid plan, got %q", redacted)
+		}
+	})
+}
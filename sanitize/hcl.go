@@ -0,0 +1,275 @@
+package sanitize
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The kinds of HCL attribute values.
+const (
+	hclExpression = iota
+	hclString
+	hclHeredoc
+	hclNumber
+	hclBool
+	hclNull
+	hclCollection
+)
+
+// hclValue locates the value of an attribute in HCL source. The inner span
+// is the value without the quotes of a string or the markers of a heredoc.
+type hclValue struct {
+	kind       int
+	start      int
+	end        int
+	innerStart int
+	innerEnd   int
+}
+
+var (
+	hclHeredocStart = regexp.MustCompile(`^<<-?([A-Za-z_][A-Za-z0-9_]*)[ \t]*\r?\n`)
+	hclNumberValue  = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?\b`)
+	hclIdentifier   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*`)
+)
+
+// scanHCL calls visit for every attribute in src with the labels of the
+// blocks it is nested in, such as ["variable", "db_password"], its name, and
+// its value. It is a tolerant scanner rather than a parser: it skips what it
+// does not understand up to the end of the line.
+func scanHCL(src string, visit func(path []string, name string, value hclValue)) {
+	var path []string
+	var depths []int
+	pos := 0
+	for pos < len(src) {
+		pos = skipHCLSpace(src, pos, true)
+		if pos >= len(src) {
+			return
+		}
+		if src[pos] == '}' {
+			if len(depths) > 0 {
+				path = path[:depths[len(depths)-1]]
+				depths = depths[:len(depths)-1]
+			}
+			pos++
+			continue
+		}
+		name := hclIdentifier.FindString(src[pos:])
+		if name == "" {
+			pos = nextLine(src, pos)
+			continue
+		}
+		pos = skipHCLSpace(src, pos+len(name), false)
+		if pos < len(src) && src[pos] == '=' && !strings.HasPrefix(src[pos:], "==") {
+			value := parseHCLValue(src, skipHCLSpace(src, pos+1, false))
+			visit(path, name, value)
+			pos = value.end
+			continue
+		}
+
+		// A block header: the type, then string or identifier labels.
+		labels := []string{name}
+		for pos < len(src) && src[pos] != '{' && src[pos] != '\n' {
+			if src[pos] == '"' {
+				end := strings.IndexByte(src[pos+1:], '"')
+				if end < 0 {
+					break
+				}
+				labels = append(labels, src[pos+1:pos+1+end])
+				pos += end + 2
+			} else if label := hclIdentifier.FindString(src[pos:]); label != "" {
+				labels = append(labels, label)
+				pos += len(label)
+			} else {
+				break
+			}
+			pos = skipHCLSpace(src, pos, false)
+		}
+		if pos < len(src) && src[pos] == '{' {
+			depths = append(depths, len(path))
+			path = append(path, labels...)
+			pos++
+			continue
+		}
+		pos = nextLine(src, pos)
+	}
+}
+
+// parseHCLValue parses the value starting at pos.
+func parseHCLValue(src string, pos int) hclValue {
+	v := hclValue{start: pos, end: pos, innerStart: pos, innerEnd: pos}
+	if pos >= len(src) {
+		return v
+	}
+	rest := src[pos:]
+	switch {
+	case rest[0] == '"':
+		end, interpolated, closed := scanHCLString(src, pos)
+		v.end, v.innerStart, v.innerEnd = end, pos+1, end
+		if closed {
+			v.innerEnd--
+		}
+		v.kind = hclString
+		if interpolated {
+			v.kind = hclExpression
+		}
+	case hclHeredocStart.MatchString(rest):
+		m := hclHeredocStart.FindStringSubmatch(rest)
+		v.kind = hclHeredoc
+		v.innerStart = pos + len(m[0])
+		v.innerEnd, v.end = v.innerStart, len(src)
+		line := v.innerStart
+		for line < len(src) {
+			lineEnd := nextLine(src, line)
+			if strings.TrimSpace(src[line:lineEnd]) == m[1] {
+				v.innerEnd, v.end = line, lineEnd
+				break
+			}
+			line = lineEnd
+		}
+		for v.innerStart < v.innerEnd && (src[v.innerStart] == ' ' || src[v.innerStart] == '\t') {
+			v.innerStart++
+		}
+		for v.innerEnd > v.innerStart && (src[v.innerEnd-1] == '\n' || src[v.innerEnd-1] == '\r') {
+			v.innerEnd--
+		}
+	case rest[0] == '[' || rest[0] == '{':
+		v.kind = hclCollection
+		v.end = scanHCLBrackets(src, pos)
+		v.innerEnd = v.end
+	case hclNumberValue.MatchString(rest):
+		v.kind = hclNumber
+		v.end = pos + len(hclNumberValue.FindString(rest))
+		v.innerEnd = v.end
+	default:
+		v.end = expressionEnd(src, pos)
+		v.innerEnd = v.end
+		switch strings.TrimSpace(src[pos:v.end]) {
+		case "true", "false":
+			v.kind = hclBool
+		case "null":
+			v.kind = hclNull
+		}
+	}
+	// A value followed by more of an expression, such as "a" == var.b, is
+	// an expression.
+	if v.kind != hclHeredoc && v.kind != hclExpression {
+		after := skipHCLSpace(src, v.end, false)
+		if after < len(src) && src[after] != '\n' && src[after] != '}' && src[after] != ',' && src[after] != '#' && !strings.HasPrefix(src[after:], "//") {
+			v.kind = hclExpression
+			v.end = expressionEnd(src, pos)
+		}
+	}
+	return v
+}
+
+// scanHCLString returns the end of the string literal starting at pos, after
+// its closing quote, whether it contains interpolations, and whether it is
+// closed rather than cut off by the end of the line or of src.
+func scanHCLString(src string, pos int) (int, bool, bool) {
+	interpolated := false
+	depth := 0
+	for i := pos + 1; i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			i++
+		case (src[i] == '$' || src[i] == '%') && i+1 < len(src) && src[i+1] == '{':
+			interpolated = true
+			depth++
+			i++
+		case depth > 0 && src[i] == '}':
+			depth--
+		case depth > 0 && src[i] == '"':
+			end, _, _ := scanHCLString(src, i)
+			i = end - 1
+		case depth == 0 && src[i] == '"':
+			return i + 1, interpolated, true
+		case src[i] == '\n':
+			return i, interpolated, false
+		}
+	}
+	return len(src), interpolated, false
+}
+
+// scanHCLBrackets returns the end of the bracketed value starting at pos.
+func scanHCLBrackets(src string, pos int) int {
+	depth := 0
+	for i := pos; i < len(src); i++ {
+		switch src[i] {
+		case '"':
+			end, _, _ := scanHCLString(src, i)
+			i = end - 1
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(src)
+}
+
+// expressionEnd returns the end of an expression starting at pos: the end of
+// the line, unless brackets are open.
+func expressionEnd(src string, pos int) int {
+	depth := 0
+	for i := pos; i < len(src); i++ {
+		switch src[i] {
+		case '"':
+			end, _, _ := scanHCLString(src, i)
+			i = end - 1
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case '\n':
+			if depth == 0 {
+				return i
+			}
+		case '#':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(src)
+}
+
+// skipHCLSpace skips spaces and comments, and newlines if lines is set.
+func skipHCLSpace(src string, pos int, lines bool) int {
+	for pos < len(src) {
+		switch {
+		case src[pos] == ' ' || src[pos] == '\t' || src[pos] == '\r':
+			pos++
+		case src[pos] == '\n' && lines:
+			pos++
+		case src[pos] == '#' || strings.HasPrefix(src[pos:], "//"):
+			pos = nextLine(src, pos)
+			if !lines && pos > 0 {
+				return pos - 1
+			}
+		case strings.HasPrefix(src[pos:], "/*"):
+			end := strings.Index(src[pos+2:], "*/")
+			if end < 0 {
+				return len(src)
+			}
+			pos += end + 4
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// nextLine returns the start of the line after pos.
+func nextLine(src string, pos int) int {
+	end := strings.IndexByte(src[pos:], '\n')
+	if end < 0 {
+		return len(src)
+	}
+	return pos + end + 1
+}
@@ -0,0 +1,121 @@
+package sanitize
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Hostnames replaces internal hostnames, those under one of its DNS
+// suffixes, with pseudonyms that keep their structure: every label becomes
+// a pseudonym derived from a secret salt and the labels above it, and the
+// suffix becomes zone<n>.internal. db1.prod.corp.example.com and
+// db2.prod.corp.example.com thus share the pseudonym of
+// prod.corp.example.com, so the topology can still be analyzed, and the same
+// host gets the same pseudonym in every run that uses the same salt. The
+// names behind the pseudonyms are kept so that text written about them can
+// be restored.
+type Hostnames struct {
+	*pseudonyms
+	suffixes []string
+}
+
+// hostnameLabel matches a DNS label.
+const hostnameLabel = `[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?`
+
+// hostnameSuffix matches a DNS suffix without its leading dot.
+var hostnameSuffix = regexp.MustCompile(`^` + hostnameLabel + `(\.` + hostnameLabel + `)*$`)
+
+// hostnamePseudonym matches the pseudonyms of Hostnames, which later rules
+// leave alone.
+var hostnamePseudonym = regexp.MustCompile(`^(h[0-9a-f]{8}\.)*zone[0-9]+\.internal$`)
+
+// NewHostnames returns Hostnames for suffixes, such as .corp.example.com,
+// with a new random salt.
+func NewHostnames(suffixes []string) (*Hostnames, error) {
+	p, err := newPseudonyms()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate hostname salt: %v", err)
+	}
+	return newHostnames(suffixes, p)
+}
+
+// LoadHostnames reads Hostnames saved with Save, for suffixes, so that they
+// keep the salt and names of earlier runs. The error satisfies
+// os.IsNotExist if there are none yet.
+func LoadHostnames(path string, suffixes []string) (*Hostnames, error) {
+	p, err := loadPseudonyms(path, "hostname")
+	if err != nil {
+		return nil, err
+	}
+	return newHostnames(suffixes, p)
+}
+
+func newHostnames(suffixes []string, p *pseudonyms) (*Hostnames, error) {
+	h := &Hostnames{pseudonyms: p}
+	for _, suffix := range suffixes {
+		suffix = strings.ToLower(strings.Trim(strings.TrimSpace(suffix), "."))
+		if !hostnameSuffix.MatchString(suffix) {
+			return nil, fmt.Errorf("invalid hostname suffix %q", suffix)
+		}
+		h.suffixes = append(h.suffixes, suffix)
+	}
+	if len(h.suffixes) == 0 {
+		return nil, fmt.Errorf("no hostname suffixes are configured")
+	}
+	return h, nil
+}
+
+// Save writes the salt and names to path, readable only by its owner since
+// they reveal the hostnames.
+func (h *Hostnames) Save(path string) error {
+	return h.save(path)
+}
+
+// Rule returns the rule replacing hostnames under the suffixes with their
+// pseudonyms. It should come before the built-in rules, which would
+// otherwise redact the hosts of URLs.
+func (h *Hostnames) Rule() Rule {
+	var suffixes []string
+	for _, suffix := range h.suffixes {
+		suffixes = append(suffixes, regexp.QuoteMeta(suffix))
+	}
+	return Rule{
+		ID:          "internal-hostname",
+		Kind:        "HOST",
+		Replacement: redacted,
+		SecretGroup: 2,
+		Keywords:    h.suffixes,
+		Pattern:     regexp.MustCompile(`(?i)(^|[^A-Za-z0-9.-])((?:` + hostnameLabel + `\.)*(?:` + strings.Join(suffixes, "|") + `))\b`),
+		Pseudonym:   h.Pseudonym,
+	}
+}
+
+// Pseudonym returns the pseudonym of name, which is returned unchanged if it
+// is not under one of the suffixes.
+func (h *Hostnames) Pseudonym(name string) string {
+	lower := strings.ToLower(name)
+	for i, suffix := range h.suffixes {
+		if lower != suffix && !strings.HasSuffix(lower, "."+suffix) {
+			continue
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		parent := suffix
+		pseudonym := fmt.Sprintf("zone%d.internal", i+1)
+		h.names[pseudonym] = suffix
+		labels := strings.Split(strings.TrimSuffix(strings.TrimSuffix(lower, suffix), "."), ".")
+		for j := len(labels) - 1; j >= 0 && labels[j] != ""; j-- {
+			parent = labels[j] + "." + parent
+			pseudonym = "h" + h.hash(parent, 8) + "." + pseudonym
+			h.names[pseudonym] = parent
+		}
+		return pseudonym
+	}
+	return name
+}
+
+// Restore replaces every pseudonym in text with the hostname it stands for.
+func (h *Hostnames) Restore(text string) string {
+	return h.restore(text)
+}
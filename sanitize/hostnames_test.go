@@ -0,0 +1,71 @@
+package sanitize
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHostnames(t *testing.T) {
+	h, err := NewHostnames([]string{".corp.example.com", "acme.net"})
+	if err != nil {
+		t.Fatalf("NewHostnames failed: %v", err)
+	}
+	content := "File: main.tf\n" +
+		"endpoint = \"https://db1.prod.corp.example.com:5432/app\"\n" +
+		"replica  = \"db2.prod.corp.example.com\"\n" +
+		"cache    = \"redis.acme.net\"\n" +
+		"public   = \"www.example.com\"\n" +
+		"lookalike = \"notcorp.example.com\"\n"
+
+	sanitized, found := New(append([]Rule{h.Rule()}, DefaultRules()...)...).Redact(content)
+	for _, name := range []string{"db1.prod", "db2.prod", "redis.acme"} {
+		if strings.Contains(sanitized, name) {
+			t.Errorf("Expected %s to be pseudonymized, got:\n%s", name, sanitized)
+		}
+	}
+	if len(found) != 3 || found[0].Rule != "internal-hostname" || found[0].Secret {
+		t.Errorf("Expected the 3 internal hostnames to be replaced, got %+v", found)
+	}
+
+	db1, db2 := h.Pseudonym("db1.prod.corp.example.com"), h.Pseudonym("DB2.prod.corp.example.com")
+	if !strings.Contains(sanitized, "https://"+db1+":5432/app") {
+		t.Errorf("Expected the URL to keep the pseudonym rather than be redacted, got:\n%s", sanitized)
+	}
+	if !strings.HasSuffix(db1, ".zone1.internal") || db1 == db2 || db1[strings.Index(db1, "."):] != db2[strings.Index(db2, "."):] {
+		t.Errorf("Expected hosts of one subdomain to share its pseudonym, got %s and %s", db1, db2)
+	}
+	if got := h.Pseudonym("cache.acme.net"); !strings.HasSuffix(got, ".zone2.internal") {
+		t.Errorf("Expected the second suffix to be zone2, got %s", got)
+	}
+	if !strings.Contains(sanitized, "notcorp.example.com") {
+		t.Errorf("Expected names that only end like a suffix to be left alone, got:\n%s", sanitized)
+	}
+
+	restored := h.Restore("Move " + db1 + " and everything else in " + db1[strings.Index(db1, ".")+1:] + ".")
+	if restored != "Move db1.prod.corp.example.com and everything else in prod.corp.example.com." {
+		t.Errorf("Expected the hostnames to be restored, got %q", restored)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, ".kado", "hostnames.json")
+	if err := h.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := LoadHostnames(path, []string{"corp.example.com"})
+	if err != nil {
+		t.Fatalf("LoadHostnames failed: %v", err)
+	}
+	if got := loaded.Pseudonym("db1.prod.corp.example.com"); got != db1 {
+		t.Errorf("Expected the same pseudonym after loading, got %s and %s", db1, got)
+	}
+
+	if _, err := NewHostnames([]string{"bad suffix"}); err == nil {
+		t.Errorf("Expected an error for an invalid suffix")
+	}
+}
@@ -0,0 +1,111 @@
+package sanitize
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// gitleaksConfig is the part of a gitleaks configuration file that describes
+// rules.
+type gitleaksConfig struct {
+	Rules []struct {
+		ID          string   `toml:"id"`
+		Regex       string   `toml:"regex"`
+		SecretGroup int      `toml:"secretGroup"`
+		Entropy     float64  `toml:"entropy"`
+		Keywords    []string `toml:"keywords"`
+	} `toml:"rules"`
+}
+
+// LoadGitleaksRules reads the rules of a gitleaks TOML configuration, such as
+// its default gitleaks.toml, as secret rules with IDs prefixed "gitleaks:".
+// Rules that only match file paths are skipped, and so are the allowlists,
+// which kado-ai configures separately.
+func LoadGitleaksRules(path string) ([]Rule, error) {
+	var cfg gitleaksConfig
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse gitleaks config %s: %v", path, err)
+	}
+	var rules []Rule
+	for _, r := range cfg.Rules {
+		if r.Regex == "" {
+			continue
+		}
+		rule, err := importedRule("gitleaks:"+r.ID, r.Regex, r.SecretGroup, r.Entropy, r.Keywords)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// trufflehogConfig is the part of a trufflehog configuration file that
+// describes custom regular expression detectors.
+type trufflehogConfig struct {
+	Detectors []struct {
+		Name     string            `yaml:"name"`
+		Keywords []string          `yaml:"keywords"`
+		Regex    map[string]string `yaml:"regex"`
+		Entropy  float64           `yaml:"entropy"`
+	} `yaml:"detectors"`
+}
+
+// LoadTrufflehogRules reads the custom detectors of a trufflehog YAML
+// configuration as secret rules, one for every regular expression of a
+// detector, with IDs such as "trufflehog:<detector>.<regex>".
+func LoadTrufflehogRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg trufflehogConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse trufflehog config %s: %v", path, err)
+	}
+	var rules []Rule
+	for _, d := range cfg.Detectors {
+		var names []string
+		for name := range d.Regex {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			rule, err := importedRule(fmt.Sprintf("trufflehog:%s.%s", d.Name, name), d.Regex[name], 0, d.Entropy, d.Keywords)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// importedRule compiles a rule of another scanner. As in gitleaks, the
+// secret is the first submatch when no group is given.
+func importedRule(id string, expr string, group int, entropy float64, keywords []string) (Rule, error) {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid pattern for rule %s: %v", id, err)
+	}
+	if group == 0 && pattern.NumSubexp() > 0 {
+		group = 1
+	}
+	if group > pattern.NumSubexp() {
+		return Rule{}, fmt.Errorf("rule %s has no group %d", id, group)
+	}
+	return Rule{
+		ID:          id,
+		Pattern:     pattern,
+		Replacement: redacted,
+		Secret:      true,
+		SecretGroup: group,
+		Entropy:     entropy,
+		Keywords:    keywords,
+	}, nil
+}
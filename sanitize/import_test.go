@@ -0,0 +1,91 @@
+package sanitize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportRules(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"gitleaks.toml": `title = "custom"
+
+[[rules]]
+id = "acme-api-key"
+description = "ACME API key"
+regex = '''acme_key\s*=\s*"([a-z0-9]{16})"'''
+keywords = ["acme_key"]
+
+[[rules]]
+id = "generic-secret"
+regex = '''(?i)secret_value\s*=\s*"([^"]+)"'''
+entropy = 3.5
+
+[[rules]]
+id = "pkcs12-file"
+path = '''\.p12$'''
+`,
+		"trufflehog.yaml": `detectors:
+  - name: HogToken
+    keywords: [hog]
+    regex:
+      token: 'HOG[0-9A-Z]{17}'
+    verify:
+      - endpoint: http://localhost:8000/
+`,
+		"sanitize.yaml": "gitleaks: [gitleaks.toml]\ntrufflehog: [trufflehog.yaml]\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg, err := LoadConfig(filepath.Join(tmpDir, "sanitize.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	rules, err := cfg.Apply(nil)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	var ids []string
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+	}
+	expectedIDs := []string{"gitleaks:acme-api-key", "gitleaks:generic-secret", "trufflehog:HogToken.token"}
+	if len(ids) != len(expectedIDs) {
+		t.Fatalf("Expected rules %v, got %v", expectedIDs, ids)
+	}
+	for i := range ids {
+		if ids[i] != expectedIDs[i] {
+			t.Errorf("Expected rule %s, got %s", expectedIDs[i], ids[i])
+		}
+	}
+
+	content := "acme_key = \"0123456789abcdef\"\n" +
+		"secret_value = \"aaaaaaaa\"\n" +
+		"secret_value = \"Zx81-pQ7s!Lw\"\n" +
+		"hog = HOG0123456789ABCDEFG\n"
+	sanitized, found := New(rules...).Redact(content)
+	expected := "acme_key = \"[REDACTED]\"\n" +
+		"secret_value = \"aaaaaaaa\"\n" +
+		"secret_value = \"[REDACTED]\"\n" +
+		"hog = [REDACTED]\n"
+	if sanitized != expected {
+		t.Errorf("Expected %q, got %q", expected, sanitized)
+	}
+	if len(found) != 3 {
+		t.Errorf("Expected 3 redactions, got %+v", found)
+	}
+
+	if sanitized, _ := New(rules...).Redact("other = \"0123456789abcdef\"\n"); sanitized != "other = \"0123456789abcdef\"\n" {
+		t.Errorf("Expected rules to be skipped without their keywords, got %q", sanitized)
+	}
+}
@@ -0,0 +1,69 @@
+package sanitize
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Level trades privacy for prompt fidelity by choosing which built-in rules
+// apply.
+type Level string
+
+const (
+	// LevelMinimal only redacts credentials recognized by their name or
+	// format, and keeps addresses, hosts, and random-looking values that
+	// might not be secrets, for the most faithful prompt.
+	LevelMinimal Level = "minimal"
+	// LevelStandard applies DefaultRules.
+	LevelStandard Level = "standard"
+	// LevelParanoid applies DefaultRules with lower entropy thresholds, and
+	// also redacts email addresses and long hexadecimal strings.
+	LevelParanoid Level = "paranoid"
+)
+
+// paranoidEntropy is the entropy threshold of the heuristic rules at
+// LevelParanoid, low enough to catch tokens with a small alphabet.
+const paranoidEntropy = 3.5
+
+var paranoidRules = []Rule{
+	emailRule,
+	{ID: "hex-string", Secret: true, Kind: "TOKEN", Replacement: "[REDACTED]",
+		Pattern: regexp.MustCompile(`\b[0-9a-fA-F]{32,}\b`)},
+}
+
+// LevelRules returns the built-in rules of level, in the order they are
+// applied. The empty level is LevelStandard.
+func LevelRules(level Level) ([]Rule, error) {
+	switch level {
+	case "", LevelStandard:
+		return DefaultRules(), nil
+	case LevelMinimal:
+		var rules []Rule
+		for _, rule := range DefaultRules() {
+			if rule.Secret && rule.Entropy == 0 {
+				rules = append(rules, rule)
+			}
+		}
+		return rules, nil
+	case LevelParanoid:
+		rules := DefaultRules()
+		for i := range rules {
+			if rules[i].Entropy > paranoidEntropy {
+				rules[i].Entropy = paranoidEntropy
+			}
+		}
+		return append(rules, paranoidRules...), nil
+	}
+	return nil, fmt.Errorf("unknown sanitization level %q; expected %s, %s, or %s", level, LevelMinimal, LevelStandard, LevelParanoid)
+}
+
+// BuiltinRule returns the built-in rule with the given ID, from any level or
+// PIIRules, so that it can be tested on its own with Rule.Matches.
+func BuiltinRule(id string) (Rule, bool) {
+	for _, rule := range append(append(DefaultRules(), paranoidRules...), piiRules...) {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
@@ -0,0 +1,68 @@
+package sanitize
+
+import (
+	"testing"
+)
+
+func TestLevelRules(t *testing.T) {
+	content := "File: main.tf\n" +
+		"password = \"hunter2\"\n" +
+		"cidr = \"10.1.2.3\"\n" +
+		"owner = \"ops@acme.io\"\n" +
+		"digest = \"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08\"\n" +
+		"seed = \"deadbeefdeadbeefdeadbeefdeadbeef\"\n"
+
+	testCases := []struct {
+		level    Level
+		expected []string
+	}{
+		{LevelMinimal, []string{"terraform-sensitive-attribute"}},
+		{LevelStandard, []string{"terraform-sensitive-attribute", "ipv4-address"}},
+		{LevelParanoid, []string{"terraform-sensitive-attribute", "ipv4-address", "high-entropy-string", "email-address", "hex-string"}},
+	}
+	for _, tc := range testCases {
+		rules, err := LevelRules(tc.level)
+		if err != nil {
+			t.Fatalf("LevelRules(%s) failed: %v", tc.level, err)
+		}
+		_, found := New(rules...).Redact(content)
+		var got []string
+		for _, r := range found {
+			got = append(got, r.Rule)
+		}
+		if len(got) != len(tc.expected) {
+			t.Errorf("For level %s, expected %v, got %v", tc.level, tc.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.expected[i] {
+				t.Errorf("For level %s, expected %v, got %v", tc.level, tc.expected, got)
+				break
+			}
+		}
+	}
+
+	if _, err := LevelRules("strict"); err == nil {
+		t.Errorf("Expected an error for an unknown level")
+	}
+}
+
+func TestPreview(t *testing.T) {
+	content := "File: main.tf\nendpoint = \"https://db.internal.acme.io/v1\"\n"
+	p := NewPlaceholders()
+	s := Default().WithPlaceholders(p)
+
+	matches := s.Preview(content)
+	if len(matches) != 1 || matches[0].Rule != "url-host" || matches[0].Value != "db.internal.acme.io" || matches[0].Line != 1 {
+		t.Fatalf("Expected the host of the URL, got %+v", matches)
+	}
+	if matches[0].Placeholder != "" {
+		t.Errorf("Expected no placeholder to be assigned, got %q", matches[0].Placeholder)
+	}
+	if got := p.Placeholder("HOST", "db.internal.acme.io", false); got != "[REDACTED_HOST_1]" {
+		t.Errorf("Expected Preview to leave the placeholders alone, got %s", got)
+	}
+	if len(Preview(content)) != 1 {
+		t.Errorf("Expected Preview to use the default rules")
+	}
+}
@@ -0,0 +1,60 @@
+package sanitize
+
+import (
+	"regexp"
+)
+
+// emailRule matches email addresses. It is part of both LevelParanoid and
+// PIIRules. Addresses followed by a colon are the user and host of an SSH
+// Git URL, such as git@github.com:acme/modules.git, rather than a person's.
+var emailRule = Rule{ID: "email-address", Kind: "EMAIL", Replacement: "[REDACTED]", SecretGroup: 1,
+	Keywords: []string{"@"},
+	Pattern:  regexp.MustCompile(`\b([A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,})\b(?:[^:]|$)`)}
+
+// piiRules match personal data rather than credentials: email addresses,
+// phone numbers, the users Ansible connects as, and the people named in
+// Terraform tags and labels.
+var piiRules = []Rule{
+	emailRule,
+	{ID: "phone-number", Kind: "PHONE", Replacement: "[REDACTED]", SecretGroup: 2,
+		Pattern: regexp.MustCompile(`(^|[^\w.+-])(\+[1-9][0-9]{0,2}[ .-]?(?:\(?[0-9]{1,4}\)?[ .-]?){2,4}[0-9]{2,4}|\(?[0-9]{3}\)?[ .-][0-9]{3}[ .-][0-9]{4})\b`)},
+	{ID: "ansible-username", Kind: "USER", Replacement: "[REDACTED]", SecretGroup: 3,
+		Keywords: []string{"ansible_user", "ansible_ssh_user", "ansible_become_user", "remote_user"},
+		Pattern:  regexp.MustCompile(`\b(ansible_user|ansible_ssh_user|ansible_become_user|remote_user)(["']?[ \t]*[:=][ \t]*)['"]?([^\s'"#,{}]+)`)},
+	{ID: "terraform-tag-person", Kind: "USER", Replacement: "[REDACTED]", Find: findPersonTags,
+		Keywords: []string{"tags", "labels"}},
+}
+
+// personTag matches the tags and labels that name a person, and their
+// quoted values.
+var personTag = regexp.MustCompile(`(?i)"?\b(owner|owned_?by|created_?by|creator|contact|author|maintainer|user|username|email|requester|requested_?by|team_?lead|manager)"?\s*[=:]\s*"([^"$]+)"`)
+
+// PIIRules returns the rules of an optional pass that removes personal data,
+// for teams that must not send it to third parties: email addresses, phone
+// numbers, the users of Ansible inventories, and tags and labels such as
+// Owner and CreatedBy in Terraform code. They are not secret rules.
+func PIIRules() []Rule {
+	return append([]Rule(nil), piiRules...)
+}
+
+// findPersonTags finds the values of tags and labels naming a person in the
+// tags, tags_all, and labels maps of Terraform files, including those of
+// default_tags blocks.
+func findPersonTags(content string) [][]int {
+	var spans [][]int
+	for _, s := range fileSegments(content) {
+		if !isTerraform(s.name) {
+			continue
+		}
+		src := content[s.start:s.end]
+		scanHCL(src, func(path []string, name string, value hclValue) {
+			if (name != "tags" && name != "tags_all" && name != "labels") || value.kind != hclCollection {
+				return
+			}
+			for _, m := range personTag.FindAllStringSubmatchIndex(src[value.start:value.end], -1) {
+				spans = append(spans, []int{s.start + value.start + m[4], s.start + value.start + m[5]})
+			}
+		})
+	}
+	return sortSpans(spans)
+}
@@ -0,0 +1,75 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPIIRules(t *testing.T) {
+	content := "File: terraform/main.tf\n" +
+		"provider \"aws\" {\n" +
+		"  default_tags {\n" +
+		"    tags = {\n" +
+		"      Owner      = \"jdoe\"\n" +
+		"      CostCenter = \"platform\"\n" +
+		"    }\n" +
+		"  }\n" +
+		"}\n" +
+		"resource \"google_compute_instance\" \"web\" {\n" +
+		"  labels = { created_by = \"asmith\", env = \"prod\" }\n" +
+		"  description = \"On call: +1 415-555-0132 or oncall@acme.io\"\n" +
+		"  owner = var.owner\n" +
+		"}\n" +
+		"File: ansible/inventory/hosts.yml\n" +
+		"all:\n" +
+		"  vars:\n" +
+		"    ansible_user: deploy_bob\n" +
+		"  hosts:\n" +
+		"    web1:\n" +
+		"      ansible_host: web1\n" +
+		"      ansible_become_user=\"root\"\n"
+
+	sanitized, found := New(PIIRules()...).Redact(content)
+	for _, pii := range []string{"jdoe", "asmith", "415-555-0132", "oncall@acme.io", "deploy_bob", "\"root\""} {
+		if strings.Contains(sanitized, pii) {
+			t.Errorf("Expected %s to be removed, got:\n%s", pii, sanitized)
+		}
+	}
+	for _, kept := range []string{"CostCenter = \"platform\"", "env = \"prod\"", "owner = var.owner", "ansible_host: web1"} {
+		if !strings.Contains(sanitized, kept) {
+			t.Errorf("Expected %s to be kept, got:\n%s", kept, sanitized)
+		}
+	}
+	rules := map[string]int{}
+	for _, r := range found {
+		rules[r.Rule]++
+		if r.Secret {
+			t.Errorf("Expected PII redactions not to be secrets, got %+v", r)
+		}
+	}
+	expected := map[string]int{"email-address": 1, "phone-number": 1, "ansible-username": 2, "terraform-tag-person": 2}
+	for rule, count := range expected {
+		if rules[rule] != count {
+			t.Errorf("Expected %d %s redactions, got %v", count, rule, rules)
+		}
+	}
+}
+
+func TestPhoneNumbers(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"call +44 20 7946 0958 now", "call [REDACTED] now"},
+		{"call 415.555.0132", "call [REDACTED]"},
+		{"cidr = 10.0.0.0/16", "cidr = 10.0.0.0/16"},
+		{"version = 1.2.3", "version = 1.2.3"},
+		{"date = 2024-01-15", "date = 2024-01-15"},
+	}
+	s := New(PIIRules()...)
+	for _, tc := range testCases {
+		if got := s.Sanitize(tc.input); got != tc.expected {
+			t.Errorf("For %q, expected %q, got %q", tc.input, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package sanitize
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// redacted is the marker in a rule's replacement that a placeholder takes
+// the place of.
+const redacted = "[REDACTED]"
+
+// Placeholders numbers redacted values per kind, so the same value always
+// gets the same placeholder, such as [REDACTED_IP_1], and different values
+// different ones. It keeps the values of non-secret placeholders so that text
+// written about them can be restored. Credentials are never kept: their
+// placeholders are only stable for the lifetime of the Placeholders and are
+// never restored.
+//
+// Placeholders are numbered per placeholder without its number, so with a
+// PlaceholderFormat of [REDACTED_{kind}_{n}] every kind is numbered on its
+// own, and with {{REDACTED_{n}}} all values share one sequence.
+type Placeholders struct {
+	mu       sync.Mutex
+	values   map[string]string
+	counts   map[string]int
+	keys     map[string]string
+	assigned map[string]string
+}
+
+// placeholderFile is the saved form of Placeholders. Keys holds the
+// placeholder without its number of every value, and is missing from
+// mappings saved before placeholder formats were configurable.
+type placeholderFile struct {
+	Values map[string]string `json:"values"`
+	Counts map[string]int    `json:"counts"`
+	Keys   map[string]string `json:"keys,omitempty"`
+}
+
+// NewPlaceholders returns an empty mapping.
+func NewPlaceholders() *Placeholders {
+	return &Placeholders{
+		values:   map[string]string{},
+		counts:   map[string]int{},
+		keys:     map[string]string{},
+		assigned: map[string]string{},
+	}
+}
+
+// LoadPlaceholders reads a mapping saved with Save. The error satisfies
+// os.IsNotExist if there is none yet.
+func LoadPlaceholders(path string) (*Placeholders, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var saved placeholderFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse placeholder mapping %s: %v", path, err)
+	}
+	p := NewPlaceholders()
+	for placeholder, value := range saved.Values {
+		key, ok := saved.Keys[placeholder]
+		if !ok {
+			key = DefaultPlaceholderFormat.key(placeholderKind(placeholder), "")
+		}
+		p.values[placeholder] = value
+		p.keys[placeholder] = key
+		p.assigned[key+"\x00"+value] = placeholder
+	}
+	for key, count := range saved.Counts {
+		if !strings.Contains(key, "{n}") {
+			// Counts were saved per kind before formats were configurable.
+			key = DefaultPlaceholderFormat.key(key, "")
+		}
+		p.counts[key] = count
+	}
+	return p, nil
+}
+
+// Save writes the mapping to path, readable only by its owner since it
+// reveals the redacted values.
+func (p *Placeholders) Save(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data, err := json.MarshalIndent(placeholderFile{Values: p.values, Counts: p.counts, Keys: p.keys}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Placeholder returns the placeholder of value in the
+// DefaultPlaceholderFormat, assigning the next number of kind the first
+// time value is seen.
+func (p *Placeholders) Placeholder(kind string, value string, secret bool) string {
+	return p.placeholder(DefaultPlaceholderFormat, kind, "", value, secret)
+}
+
+func (p *Placeholders) placeholder(format PlaceholderFormat, kind string, rule string, value string, secret bool) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := format.key(kind, rule)
+	if placeholder, ok := p.assigned[key+"\x00"+value]; ok {
+		return placeholder
+	}
+	p.counts[key]++
+	placeholder := strings.Replace(key, "{n}", strconv.Itoa(p.counts[key]), -1)
+	p.assigned[key+"\x00"+value] = placeholder
+	if !secret {
+		p.values[placeholder] = value
+		p.keys[placeholder] = key
+	}
+	return placeholder
+}
+
+// Restore replaces every non-secret placeholder in text with its value.
+func (p *Placeholders) Restore(text string) string {
+	p.mu.Lock()
+	keys := make([]string, 0, len(p.values))
+	for placeholder := range p.values {
+		keys = append(keys, placeholder)
+	}
+	// Longer placeholders first, so that a placeholder such as IP_10 is not
+	// restored as IP_1 followed by a 0.
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	var pairs []string
+	for _, placeholder := range keys {
+		pairs = append(pairs, placeholder, p.values[placeholder])
+	}
+	p.mu.Unlock()
+	if len(pairs) == 0 {
+		return text
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// replace swaps the [REDACTED] marker in the expansion of rule for the
+// placeholder of value in format, and returns the result and the
+// placeholder.
+func (p *Placeholders) replace(format PlaceholderFormat, rule Rule, value string, expanded []byte) ([]byte, string) {
+	if !bytes.Contains(expanded, []byte(redacted)) {
+		return expanded, ""
+	}
+	placeholder := p.placeholder(format, rule.kind(), rule.ID, value, rule.Secret)
+	return swapMarker(expanded, placeholder), placeholder
+}
+
+// swapMarker replaces the [REDACTED] marker in expanded with text.
+func swapMarker(expanded []byte, text string) []byte {
+	return bytes.Replace(expanded, []byte(redacted), []byte(text), 1)
+}
+
+func placeholderKind(placeholder string) string {
+	kind := strings.TrimSuffix(strings.TrimPrefix(placeholder, "[REDACTED_"), "]")
+	if i := strings.LastIndex(kind, "_"); i >= 0 {
+		kind = kind[:i]
+	}
+	return kind
+}
+
+// PlaceholderFormat is the form of the text that replaces a redacted value,
+// in which {kind} stands for the kind of the rule, such as IP or SECRET,
+// {rule} for its ID, and {n} for the number of the value. Formats with {n}
+// are for Placeholders, which number values; formats without it replace
+// every value of a kind alike. Formats without {kind} or {rule} give the AI
+// no hint of what was redacted. Examples:
+//
+//	[REDACTED_{kind}_{n}]  [REDACTED_IP_1], the default of Placeholders
+//	<{kind}:{rule}>        <SECRET:aws-access-key>
+//	{{REDACTED_{n}}}       {{REDACTED_42}}
+type PlaceholderFormat string
+
+// DefaultPlaceholderFormat is the format of Placeholders unless a Sanitizer
+// sets another.
+const DefaultPlaceholderFormat PlaceholderFormat = "[REDACTED_{kind}_{n}]"
+
+// placeholderToken matches the tokens of a PlaceholderFormat.
+var placeholderToken = regexp.MustCompile(`\{(kind|rule|n)\}`)
+
+// Validate reports whether f is a usable format, numbered for Placeholders
+// or not.
+func (f PlaceholderFormat) Validate(numbered bool) error {
+	numberless := placeholderToken.ReplaceAllString(string(f), "")
+	if strings.TrimSpace(numberless) == "" {
+		return fmt.Errorf("invalid placeholder format %q: it needs text besides {kind}, {rule}, and {n}", f)
+	}
+	if numbered && !strings.Contains(string(f), "{n}") {
+		return fmt.Errorf("invalid placeholder format %q: numbered placeholders need {n}", f)
+	}
+	if !numbered && strings.Contains(string(f), "{n}") {
+		return fmt.Errorf("invalid placeholder format %q: {n} is only available with numbered placeholders", f)
+	}
+	return nil
+}
+
+// key returns the placeholder of a value of kind found by rule, with {n}
+// left in place.
+func (f PlaceholderFormat) key(kind string, rule string) string {
+	return strings.NewReplacer("{kind}", kind, "{rule}", rule).Replace(string(f))
+}
+
+// pattern returns a regular expression matching the placeholders of f.
+func (f PlaceholderFormat) pattern() *regexp.Regexp {
+	var expr strings.Builder
+	last := 0
+	for _, m := range placeholderToken.FindAllStringSubmatchIndex(string(f), -1) {
+		expr.WriteString(regexp.QuoteMeta(string(f[last:m[0]])))
+		switch f[m[2]:m[3]] {
+		case "kind":
+			expr.WriteString(`[A-Za-z0-9_]+`)
+		case "rule":
+			expr.WriteString(`[A-Za-z0-9_.:-]+`)
+		case "n":
+			expr.WriteString(`[0-9]+`)
+		}
+		last = m[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(string(f[last:])))
+	return regexp.MustCompile(expr.String())
+}
@@ -0,0 +1,171 @@
+package sanitize
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlaceholders(t *testing.T) {
+	p := NewPlaceholders()
+	s := Default().WithPlaceholders(p)
+
+	content := "cidr = \"10.0.0.1\"\npeer = \"10.0.0.2\"\nnat = \"10.0.0.1\"\n" +
+		"endpoint = \"https://db.internal.example/health\"\npassword = hunter2\n"
+	sanitized, found := s.Redact(content)
+	expected := "cidr = \"[REDACTED_IP_1]\"\npeer = \"[REDACTED_IP_2]\"\nnat = \"[REDACTED_IP_1]\"\n" +
+		"endpoint = \"https://[REDACTED_HOST_1]/health\"\n[REDACTED_SECRET_1]\n"
+	if sanitized != expected {
+		t.Errorf("Expected %q, got %q", expected, sanitized)
+	}
+	if len(found) != 5 {
+		t.Errorf("Expected 5 redactions, got %d", len(found))
+	}
+
+	restored := p.Restore("Restrict [REDACTED_IP_1] and [REDACTED_HOST_1], and rotate [REDACTED_SECRET_1].")
+	if restored != "Restrict 10.0.0.1 and db.internal.example, and rotate [REDACTED_SECRET_1]." {
+		t.Errorf("Expected non-secret placeholders to be restored, got %q", restored)
+	}
+}
+
+func TestPlaceholdersSave(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	p := NewPlaceholders()
+	p.Placeholder("IP", "10.0.0.1", false)
+	p.Placeholder("SECRET", "hunter2", true)
+	path := filepath.Join(tmpDir, ".kado", "placeholders.json")
+	if err := p.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read mapping: %v", err)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Errorf("Expected secrets to be left out of the mapping, got %s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private mapping, got %v (%v)", info, err)
+	}
+
+	loaded, err := LoadPlaceholders(path)
+	if err != nil {
+		t.Fatalf("LoadPlaceholders failed: %v", err)
+	}
+	if got := loaded.Placeholder("IP", "10.0.0.1", false); got != "[REDACTED_IP_1]" {
+		t.Errorf("Expected the saved placeholder to be reused, got %s", got)
+	}
+	if got := loaded.Placeholder("SECRET", "hunter2", true); got != "[REDACTED_SECRET_2]" {
+		t.Errorf("Expected a new secret placeholder after loading, got %s", got)
+	}
+	if _, err := LoadPlaceholders(filepath.Join(tmpDir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error, got %v", err)
+	}
+}
+
+func TestPlaceholderFormat(t *testing.T) {
+	content := "cidr = \"10.0.0.1\"\npeer = \"10.0.0.2\"\nnat = \"10.0.0.1\"\npassword = hunter2\n"
+	testCases := []struct {
+		format   PlaceholderFormat
+		numbered bool
+		expected string
+	}{
+		{"<{kind}:{rule}>", false, "cidr = \"<IP:ipv4-address>\"\npeer = \"<IP:ipv4-address>\"\nnat = \"<IP:ipv4-address>\"\n<SECRET:credential-assignment>\n"},
+		{"{{REDACTED_{n}}}", true, "cidr = \"{{REDACTED_2}}\"\npeer = \"{{REDACTED_3}}\"\nnat = \"{{REDACTED_2}}\"\n{{REDACTED_1}}\n"},
+		{"<{kind}_{n}>", true, "cidr = \"<IP_1>\"\npeer = \"<IP_2>\"\nnat = \"<IP_1>\"\n<SECRET_1>\n"},
+	}
+	for _, tc := range testCases {
+		s := Default()
+		p := NewPlaceholders()
+		if tc.numbered {
+			s = s.WithPlaceholders(p)
+		}
+		s, err := s.WithPlaceholderFormat(tc.format)
+		if err != nil {
+			t.Fatalf("WithPlaceholderFormat(%q) failed: %v", tc.format, err)
+		}
+		sanitized, found := s.Redact(content)
+		if sanitized != tc.expected {
+			t.Errorf("Expected %q with %s, got %q", tc.expected, tc.format, sanitized)
+		}
+		if len(found) != 4 {
+			t.Errorf("Expected 4 redactions with %s, got %+v", tc.format, found)
+		}
+		if again, found := s.Redact(sanitized); again != sanitized || len(found) != 0 {
+			t.Errorf("Expected placeholders in %s to be left alone, got %q (%+v)", tc.format, again, found)
+		}
+	}
+
+	s, err := Default().WithPlaceholders(NewPlaceholders()).WithPlaceholderFormat("{{REDACTED_{n}}}")
+	if err != nil {
+		t.Fatalf("WithPlaceholderFormat failed: %v", err)
+	}
+	var ips strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&ips, "ip = \"10.0.1.%d\"\n", i)
+	}
+	sanitized, _ := s.Redact(ips.String())
+	if restored := s.placeholders.Restore(sanitized); restored != ips.String() {
+		t.Errorf("Expected %q to be restored, got %q", sanitized, restored)
+	}
+
+	for _, tc := range []struct {
+		format   PlaceholderFormat
+		numbered bool
+	}{
+		{"<{kind}>", true},
+		{"<{kind}_{n}>", false},
+		{"{kind}{n}", true},
+	} {
+		if err := tc.format.Validate(tc.numbered); err == nil {
+			t.Errorf("Expected %q to be invalid (numbered: %v)", tc.format, tc.numbered)
+		}
+	}
+}
+
+func TestLoadPlaceholdersWithFormat(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "placeholders.json")
+
+	p := NewPlaceholders()
+	p.placeholder("{{REDACTED_{n}}}", "IP", "ipv4-address", "10.0.0.1", false)
+	if err := p.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := LoadPlaceholders(path)
+	if err != nil {
+		t.Fatalf("LoadPlaceholders failed: %v", err)
+	}
+	if got := loaded.placeholder("{{REDACTED_{n}}}", "HOST", "url-host", "db.internal", false); got != "{{REDACTED_2}}" {
+		t.Errorf("Expected the shared sequence to continue after loading, got %s", got)
+	}
+	if got := loaded.placeholder("{{REDACTED_{n}}}", "IP", "ipv4-address", "10.0.0.1", false); got != "{{REDACTED_1}}" {
+		t.Errorf("Expected the saved placeholder to be reused, got %s", got)
+	}
+
+	legacy := `{"values": {"[REDACTED_IP_1]": "10.0.0.1"}, "counts": {"IP": 1}}`
+	if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatalf("Failed to write mapping: %v", err)
+	}
+	loaded, err = LoadPlaceholders(path)
+	if err != nil {
+		t.Fatalf("LoadPlaceholders failed: %v", err)
+	}
+	if got := loaded.Placeholder("IP", "10.0.0.1", false); got != "[REDACTED_IP_1]" {
+		t.Errorf("Expected a mapping saved per kind to be reused, got %s", got)
+	}
+	if got := loaded.Placeholder("IP", "10.0.0.2", false); got != "[REDACTED_IP_2]" {
+		t.Errorf("Expected the count saved per kind to continue, got %s", got)
+	}
+}
@@ -0,0 +1,146 @@
+package sanitize
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// planRule is the rule ID of the values removed by RedactPlan.
+const planRule = "terraform-plan-sensitive"
+
+// RedactPlan replaces the values that a Terraform plan in JSON format, as
+// written by terraform show -json, marks as sensitive with [REDACTED]: the
+// attributes flagged in before_sensitive, after_sensitive, and
+// sensitive_values, sensitive outputs, and the values and defaults of
+// sensitive variables. Unlike pattern rules, it relies on Terraform's own
+// metadata, so it neither misses sensitive values of any format nor removes
+// values that are not sensitive. The plan is re-encoded, compactly if it was
+// compact.
+func RedactPlan(plan string) (string, []Redaction, error) {
+	decoder := json.NewDecoder(strings.NewReader(plan))
+	decoder.UseNumber()
+	var root map[string]interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return "", nil, fmt.Errorf("failed to parse plan: %v", err)
+	}
+
+	r := &planRedactor{}
+	for _, key := range []string{"resource_changes", "resource_drift"} {
+		for _, rc := range list(root[key]) {
+			rc := object(rc)
+			if change := object(rc["change"]); change != nil {
+				r.redact(change, "before", change["before_sensitive"])
+				r.redact(change, "after", change["after_sensitive"])
+			}
+		}
+	}
+	for _, oc := range object(root["output_changes"]) {
+		if change := object(oc); change != nil {
+			r.redact(change, "before", change["before_sensitive"])
+			r.redact(change, "after", change["after_sensitive"])
+		}
+	}
+	r.module(object(root["planned_values"]))
+	if prior := object(root["prior_state"]); prior != nil {
+		r.module(object(prior["values"]))
+	}
+
+	rootModule := object(object(root["configuration"])["root_module"])
+	variables := object(root["variables"])
+	for name, v := range object(rootModule["variables"]) {
+		if v := object(v); v != nil && v["sensitive"] == true {
+			r.redact(v, "default", true)
+			if variable := object(variables[name]); variable != nil {
+				r.redact(variable, "value", true)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if strings.Contains(plan, "\n ") {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(root); err != nil {
+		return "", nil, err
+	}
+	return strings.TrimSuffix(out.String(), "\n"), r.found, nil
+}
+
+type planRedactor struct {
+	found []Redaction
+}
+
+// redact replaces parent[key] where mask, which has the shape of the value,
+// is true.
+func (r *planRedactor) redact(parent map[string]interface{}, key string, mask interface{}) {
+	value, ok := parent[key]
+	if !ok || value == nil {
+		return
+	}
+	switch mask := mask.(type) {
+	case bool:
+		if mask {
+			data, _ := json.Marshal(value)
+			parent[key] = redacted
+			replaced, _ := json.Marshal(redacted)
+			r.found = append(r.found, Redaction{Rule: planRule, Kind: "SECRET", Secret: true, Length: len(data), Replaced: len(replaced)})
+		}
+	case map[string]interface{}:
+		if value, ok := value.(map[string]interface{}); ok {
+			for k, m := range mask {
+				r.redact(value, k, m)
+			}
+		}
+	case []interface{}:
+		if value, ok := value.([]interface{}); ok {
+			for i := range value {
+				if i < len(mask) {
+					wrapper := map[string]interface{}{"v": value[i]}
+					r.redact(wrapper, "v", mask[i])
+					value[i] = wrapper["v"]
+				}
+			}
+		}
+	}
+}
+
+// module redacts the resources and outputs of a module of planned_values or
+// prior_state, and of its child modules.
+func (r *planRedactor) module(values map[string]interface{}) {
+	if values == nil {
+		return
+	}
+	for _, o := range object(values["outputs"]) {
+		if o := object(o); o != nil && o["sensitive"] == true {
+			r.redact(o, "value", true)
+		}
+	}
+	var walk func(m map[string]interface{})
+	walk = func(m map[string]interface{}) {
+		for _, res := range list(m["resources"]) {
+			if res := object(res); res != nil {
+				r.redact(res, "values", res["sensitive_values"])
+			}
+		}
+		for _, child := range list(m["child_modules"]) {
+			walk(object(child))
+		}
+	}
+	if root := object(values["root_module"]); root != nil {
+		walk(root)
+	}
+}
+
+func object(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func list(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
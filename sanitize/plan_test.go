@@ -0,0 +1,60 @@
+package sanitize
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedactPlan(t *testing.T) {
+	plan := `{
+  "format_version": "1.2",
+  "variables": {"db_password": {"value": "hunter2"}, "region": {"value": "us-east-1"}},
+  "planned_values": {
+    "outputs": {"endpoint": {"sensitive": false, "value": "db.internal"}, "admin_token": {"sensitive": true, "value": "tok-123"}},
+    "root_module": {
+      "child_modules": [{
+        "resources": [{"address": "module.db.aws_db_instance.main", "values": {"password": "hunter2", "username": "app"}, "sensitive_values": {"password": true}}]
+      }]
+    }
+  },
+  "resource_changes": [{
+    "address": "module.db.aws_db_instance.main",
+    "change": {
+      "before": null,
+      "after": {"password": "hunter2", "username": "app", "tags": [{"key": "a"}, {"key": "b", "secret": "s3"}]},
+      "after_sensitive": {"password": true, "tags": [{}, {"secret": true}]}
+    }
+  }],
+  "configuration": {"root_module": {"variables": {"db_password": {"sensitive": true, "default": "changeme"}, "region": {}}}}
+}`
+	redacted, found, err := RedactPlan(plan)
+	if err != nil {
+		t.Fatalf("RedactPlan failed: %v", err)
+	}
+	for _, secret := range []string{"hunter2", "tok-123", "s3", "changeme"} {
+		if strings.Contains(redacted, `"`+secret+`"`) {
+			t.Errorf("Expected %s to be redacted, got %s", secret, redacted)
+		}
+	}
+	for _, value := range []string{"us-east-1", "db.internal", `"username": "app"`} {
+		if !strings.Contains(redacted, value) {
+			t.Errorf("Expected %s to be kept, got %s", value, redacted)
+		}
+	}
+	if len(found) != 6 || found[0].Rule != "terraform-plan-sensitive" || !found[0].Secret {
+		t.Errorf("Expected 6 secret redactions, got %+v", found)
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(redacted), &parsed); err != nil {
+		t.Errorf("Expected the redacted plan to be valid JSON: %v", err)
+	}
+
+	compact, _, err := RedactPlan(`{"variables":{"a":{"value":1}}}`)
+	if err != nil || compact != `{"variables":{"a":{"value":1}}}` {
+		t.Errorf("Expected a compact plan to stay compact, got %q, %v", compact, err)
+	}
+	if _, _, err := RedactPlan("Terraform plan not found"); err == nil {
+		t.Errorf("Expected an error for a plan that is not JSON")
+	}
+}
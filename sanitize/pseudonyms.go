@@ -0,0 +1,103 @@
+package sanitize
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// pseudonyms derives pseudonyms from a secret salt, so that a value gets the
+// same pseudonym in every run that uses the same salt, and keeps the values
+// behind them so that text written about them can be restored.
+type pseudonyms struct {
+	mu    sync.Mutex
+	salt  string
+	names map[string]string
+}
+
+// pseudonymFile is the saved form of pseudonyms.
+type pseudonymFile struct {
+	Salt  string            `json:"salt"`
+	Names map[string]string `json:"names"`
+}
+
+// newPseudonyms returns pseudonyms with a new random salt.
+func newPseudonyms() (*pseudonyms, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %v", err)
+	}
+	return &pseudonyms{salt: hex.EncodeToString(salt), names: map[string]string{}}, nil
+}
+
+// loadPseudonyms reads pseudonyms saved with save. The error satisfies
+// os.IsNotExist if there are none yet.
+func loadPseudonyms(path string, what string) (*pseudonyms, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var saved pseudonymFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse %s mapping %s: %v", what, path, err)
+	}
+	if saved.Salt == "" {
+		return nil, fmt.Errorf("%s mapping %s has no salt", what, path)
+	}
+	if saved.Names == nil {
+		saved.Names = map[string]string{}
+	}
+	return &pseudonyms{salt: saved.Salt, names: saved.Names}, nil
+}
+
+// save writes the salt and names to path, readable only by its owner since
+// they reveal the values.
+func (p *pseudonyms) save(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data, err := json.MarshalIndent(pseudonymFile{Salt: p.salt, Names: p.names}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// hash returns the first n hex digits of the salted hash of value. The
+// caller holds mu.
+func (p *pseudonyms) hash(value string, n int) string {
+	mac := hmac.New(sha256.New, []byte(p.salt))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:n]
+}
+
+// restore replaces every pseudonym in text with the value it stands for.
+func (p *pseudonyms) restore(text string) string {
+	p.mu.Lock()
+	keys := make([]string, 0, len(p.names))
+	for pseudonym := range p.names {
+		keys = append(keys, pseudonym)
+	}
+	// Longer pseudonyms first, so that a value is restored rather than a
+	// part of it, such as the zone of a host.
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	var pairs []string
+	for _, pseudonym := range keys {
+		pairs = append(pairs, pseudonym, p.names[pseudonym])
+	}
+	p.mu.Unlock()
+	if len(pairs) == 0 {
+		return text
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
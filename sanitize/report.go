@@ -0,0 +1,166 @@
+package sanitize
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Report summarizes redactions for security reviewers: how many values each
+// rule removed, and where, and how much of the content each kind of value
+// took up. Like Redaction, it never contains the removed values.
+type Report struct {
+	Generated  time.Time               `json:"generated"`
+	Total      int                     `json:"total"`
+	Secrets    int                     `json:"secrets"`
+	Rules      map[string]int          `json:"rules"`
+	Kinds      map[string]*KindMetrics `json:"kinds"`
+	Removed    int                     `json:"removed_bytes"`
+	Replaced   int                     `json:"replaced_bytes"`
+	Redactions []ReportEntry           `json:"redactions"`
+}
+
+// KindMetrics measures the values of one kind that were redacted: how many,
+// their bytes, the bytes of what replaced them, and the estimated number of
+// tokens the AI did not see.
+type KindMetrics struct {
+	Count    int `json:"count"`
+	Removed  int `json:"removed_bytes"`
+	Replaced int `json:"replaced_bytes"`
+	Tokens   int `json:"removed_tokens"`
+}
+
+// ReportEntry counts the values a rule removed from one line.
+type ReportEntry struct {
+	Rule         string   `json:"rule"`
+	Secret       bool     `json:"secret"`
+	Section      string   `json:"section,omitempty"`
+	File         string   `json:"file,omitempty"`
+	Line         int      `json:"line"`
+	Count        int      `json:"count"`
+	Placeholders []string `json:"placeholders,omitempty"`
+}
+
+// NewReport groups redactions by rule and location, ordered by location.
+func NewReport(redactions []Redaction) *Report {
+	report := &Report{Generated: time.Now().UTC(), Rules: map[string]int{}, Kinds: Metrics(redactions), Redactions: []ReportEntry{}}
+	index := map[string]int{}
+	for _, r := range redactions {
+		report.Total++
+		if r.Secret {
+			report.Secrets++
+		}
+		report.Rules[r.Rule]++
+		report.Removed += r.Length
+		report.Replaced += r.Replaced
+
+		key := fmt.Sprintf("%s\x00%s\x00%s\x00%d", r.Rule, r.Section, r.File, r.Line)
+		i, ok := index[key]
+		if !ok {
+			i = len(report.Redactions)
+			index[key] = i
+			report.Redactions = append(report.Redactions, ReportEntry{
+				Rule:    r.Rule,
+				Secret:  r.Secret,
+				Section: r.Section,
+				File:    r.File,
+				Line:    r.Line,
+			})
+		}
+		entry := &report.Redactions[i]
+		entry.Count++
+		if r.Placeholder != "" && !contains(entry.Placeholders, r.Placeholder) {
+			entry.Placeholders = append(entry.Placeholders, r.Placeholder)
+		}
+	}
+	sort.SliceStable(report.Redactions, func(i, j int) bool {
+		a, b := report.Redactions[i], report.Redactions[j]
+		if a.Section != b.Section {
+			return a.Section < b.Section
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report
+}
+
+// Metrics groups redactions by the kind of their rule, such as SECRET, IP,
+// or HOST.
+func Metrics(redactions []Redaction) map[string]*KindMetrics {
+	kinds := map[string]*KindMetrics{}
+	for _, r := range redactions {
+		kind := r.Kind
+		if kind == "" {
+			kind = "VALUE"
+		}
+		m := kinds[kind]
+		if m == nil {
+			m = &KindMetrics{}
+			kinds[kind] = m
+		}
+		m.Count++
+		m.Removed += r.Length
+		m.Replaced += r.Replaced
+	}
+	for _, m := range kinds {
+		m.Tokens = estimateTokens(m.Removed)
+	}
+	return kinds
+}
+
+// estimateTokens estimates the number of tokens in n bytes at four bytes per
+// token, which is close enough for code.
+func estimateTokens(n int) int {
+	return (n + 3) / 4
+}
+
+// Summarize writes how many bytes and tokens the redactions took out of the
+// content, in total and per kind with the most removed first, so that users
+// can tell why a response lacks specifics and which allowlists to tune.
+func Summarize(w io.Writer, redactions []Redaction) {
+	if len(redactions) == 0 {
+		return
+	}
+	kinds := Metrics(redactions)
+	var names []string
+	removed, replaced := 0, 0
+	for kind, m := range kinds {
+		names = append(names, kind)
+		removed += m.Removed
+		replaced += m.Replaced
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if kinds[names[i]].Removed != kinds[names[j]].Removed {
+			return kinds[names[i]].Removed > kinds[names[j]].Removed
+		}
+		return names[i] < names[j]
+	})
+	fmt.Fprintf(w, "Sanitization removed %d bytes (about %d tokens) and inserted %d:\n", removed, estimateTokens(removed), replaced)
+	for _, kind := range names {
+		m := kinds[kind]
+		fmt.Fprintf(w, "  %-12s %4d values %7d bytes (about %d tokens)\n", kind, m.Count, m.Removed, m.Tokens)
+	}
+}
+
+// Save writes the report as JSON to path.
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package sanitize
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewReport(t *testing.T) {
+	content := "File: terraform/network.tf\n" +
+		"cidrs = [\"10.0.0.1\", \"10.0.0.2\"]\n\n" +
+		"File: terraform/main.tf\n" +
+		"password = hunter2\n"
+	_, redactions := Default().Redact(content)
+	report := NewReport(redactions)
+
+	if report.Total != 3 || report.Secrets != 1 || report.Rules["ipv4-address"] != 2 {
+		t.Errorf("Expected 3 redactions with 1 secret and 2 addresses, got %+v", report)
+	}
+	if ip := report.Kinds["IP"]; ip == nil || ip.Count != 2 || ip.Removed != 16 || ip.Replaced != 20 || ip.Tokens != 4 {
+		t.Errorf("Expected 2 addresses of 16 bytes replaced with 20, got %+v", ip)
+	}
+	if secret := report.Kinds["SECRET"]; secret == nil || secret.Count != 1 || secret.Removed != 18 {
+		t.Errorf("Expected 1 secret of 18 bytes, got %+v", secret)
+	}
+	if report.Removed != 34 || report.Replaced != 30 {
+		t.Errorf("Expected 34 bytes removed and 30 inserted, got %d and %d", report.Removed, report.Replaced)
+	}
+	testCases := []ReportEntry{
+		{Rule: "credential-assignment", Secret: true, File: "terraform/main.tf", Line: 1, Count: 1},
+		{Rule: "ipv4-address", File: "terraform/network.tf", Line: 1, Count: 2},
+	}
+	if len(report.Redactions) != len(testCases) {
+		t.Fatalf("Expected %d entries, got %+v", len(testCases), report.Redactions)
+	}
+	for i, tc := range testCases {
+		e := report.Redactions[i]
+		if e.Rule != tc.Rule || e.Secret != tc.Secret || e.File != tc.File || e.Line != tc.Line || e.Count != tc.Count {
+			t.Errorf("Expected entry %+v, got %+v", tc, e)
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "kado-ai-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "ai_redactions.json")
+	if err := report.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	if strings.Contains(string(data), "hunter2") || strings.Contains(string(data), "10.0.0.1") {
+		t.Errorf("Expected the report to omit the redacted values, got %s", data)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	redactions := []Redaction{
+		{Rule: "ipv4-address", Kind: "IP", Length: 8, Replaced: 10},
+		{Rule: "credential-assignment", Kind: "SECRET", Secret: true, Length: 40, Replaced: 10},
+		{Rule: "ipv4-address", Kind: "IP", Length: 9, Replaced: 10},
+	}
+	var out strings.Builder
+	Summarize(&out, redactions)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "removed 57 bytes (about 15 tokens) and inserted 30") {
+		t.Fatalf("Expected a total and a line per kind, got:\n%s", out.String())
+	}
+	if !strings.Contains(lines[1], "SECRET") || !strings.Contains(lines[2], "IP") || !strings.Contains(lines[2], "2 values") {
+		t.Errorf("Expected kinds ordered by bytes removed, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	Summarize(&out, nil)
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing without redactions, got %q", out.String())
+	}
+}
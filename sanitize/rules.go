@@ -0,0 +1,76 @@
+package sanitize
+
+import "regexp"
+
+// DefaultRules returns the built-in rules in the order they are applied: the
+// structured rules, which understand Terraform and Ansible code, followed by
+// the pattern rules. Secret rules match credentials, either by the name they
+// are assigned to or by a well-known format such as AWS access key IDs, JWTs,
+// and high-entropy tokens; the others match network identifiers such as
+// addresses and hostnames.
+func DefaultRules() []Rule {
+	return append(append([]Rule(nil), structuredRules...), defaultRules...)
+}
+
+// secretName matches the names of settings holding a secret. A bare key, or
+// one ending a word such as monkey, is more often a tag or object key than a
+// credential, so keys need a prefix such as api_ or ssh-, or a camel-case
+// one such as apiKey.
+const secretName = `([\w-]*(password|passwd|secret|token)|[\w-]*[_-]key|[\w-]*(?-i:[a-z0-9]Key))`
+
+var defaultRules = []Rule{
+	{ID: "credential-assignment", Secret: true, Kind: "SECRET", Replacement: "[REDACTED]",
+		Keywords: []string{"aws_access_key", "password", "token", "secret", "api_key"},
+		Pattern:  regexp.MustCompile(`(?i)(aws_access_key|aws_secret_key|password|token|secret|api_key)(\s*[=:]\s*)['"]?[^\s'",]+['"]?`)},
+	{ID: "private-key", Secret: true, HighConfidence: true, Kind: "KEY", Replacement: "[REDACTED]",
+		Keywords: []string{"private_key"},
+		Pattern:  regexp.MustCompile(`(?i)(private_key)(\s*[=:]\s*)['"]?-----BEGIN[^'",]*-----END[^'",]*['"]?`)},
+	{ID: "connection-string", Secret: true, Kind: "SECRET", Replacement: "[REDACTED]",
+		Keywords: []string{"connection_string"},
+		Pattern:  regexp.MustCompile(`(?i)(connection_string)(\s*[=:]\s*)['"]?[^\s'",]+['"]?`)},
+	{ID: "bearer-token", Secret: true, Kind: "TOKEN", Replacement: "[REDACTED]",
+		Keywords: []string{"bearer"},
+		Pattern:  regexp.MustCompile(`(?i)(bearer\s+)['"]?[^\s'",]+['"]?`)},
+	{ID: "password-value", Secret: true, Kind: "PASSWORD", Replacement: "[REDACTED]",
+		Keywords: []string{"password"},
+		Pattern:  regexp.MustCompile(`(?i)("?\w*password"?\s*[:=]?\s*\{?\s*"?value"?\s*[:=]\s*)['"]?[^\s'",}]+['"]?`)},
+	{ID: "user-value", Secret: true, Kind: "USER", Replacement: "[REDACTED]",
+		Keywords: []string{"user"},
+		Pattern:  regexp.MustCompile(`(?i)("?\w*user(name)?"?\s*[:=]?\s*\{?\s*"?value"?\s*[:=]\s*)['"]?[^\s'",}]+['"]?`)},
+	{ID: "quoted-secret", Secret: true, Kind: "SECRET", Replacement: "[REDACTED]",
+		Keywords: []string{"password", "passwd", "secret", "key", "token"},
+		Pattern:  regexp.MustCompile(`(?i)("?` + secretName + `"?\s*[:=]\s*["'])[^"']+["']`)},
+	{ID: "secret-value", Secret: true, Kind: "SECRET", Replacement: "[REDACTED]",
+		Keywords: []string{"password", "passwd", "secret", "key", "token"},
+		Pattern:  regexp.MustCompile(`(?i)("?` + secretName + `"?\s*[:=]?\s*\{?\s*"?value"?\s*[:=]\s*)['"]?[^\s'",}]+['"]?`)},
+	{ID: "private-key-block", Secret: true, HighConfidence: true, Kind: "KEY", Replacement: "[REDACTED]",
+		Keywords: []string{"private key"},
+		Pattern:  regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?-----END[A-Z ]*PRIVATE KEY( BLOCK)?-----`)},
+	{ID: "aws-access-key-id", Secret: true, HighConfidence: true, Kind: "KEY", Replacement: "[REDACTED]",
+		Keywords: []string{"akia", "asia", "abia", "acca"},
+		Pattern:  regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[A-Z0-9]{16}\b`)},
+	{ID: "github-token", Secret: true, HighConfidence: true, Kind: "TOKEN", Replacement: "[REDACTED]",
+		Keywords: []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_", "github_pat_"},
+		Pattern:  regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`)},
+	{ID: "slack-token", Secret: true, HighConfidence: true, Kind: "TOKEN", Replacement: "[REDACTED]",
+		Keywords: []string{"xox"},
+		Pattern:  regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{ID: "jwt", Secret: true, Kind: "TOKEN", Replacement: "[REDACTED]",
+		Keywords: []string{"eyj"},
+		Pattern:  regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{ID: "ipv4-address", Kind: "IP", Replacement: "[REDACTED]",
+		Pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\b`)},
+	{ID: "ipv6-address", Kind: "IP", Replacement: "[REDACTED]",
+		Pattern: regexp.MustCompile(`\b(?:(?:[0-9a-fA-F]{1,4}:){7,7}[0-9a-fA-F]{1,4}|(?:[0-9a-fA-F]{1,4}:){1,7}:|(?:[0-9a-fA-F]{1,4}:){1,6}:[0-9a-fA-F]{1,4}|(?:[0-9a-fA-F]{1,4}:){1,5}(?::[0-9a-fA-F]{1,4}){1,2}|(?:[0-9a-fA-F]{1,4}:){1,4}(?::[0-9a-fA-F]{1,4}){1,3}|(?:[0-9a-fA-F]{1,4}:){1,3}(?::[0-9a-fA-F]{1,4}){1,4}|(?:[0-9a-fA-F]{1,4}:){1,2}(?::[0-9a-fA-F]{1,4}){1,5}|[0-9a-fA-F]{1,4}:(?:(?::[0-9a-fA-F]{1,4}){1,6})|:(?:(?::[0-9a-fA-F]{1,4}){1,7}|:)|fe80:(?::[0-9a-fA-F]{0,4}){0,4}%[0-9a-zA-Z]{1,}|::(?:ffff(?::0{1,4}){0,1}:){0,1}(?:(?:25[0-5]|(?:2[0-4]|1{0,1}[0-9]){0,1}[0-9])\.){3,3}(?:25[0-5]|(?:2[0-4]|1{0,1}[0-9]){0,1}[0-9])|(?:[0-9a-fA-F]{1,4}:){1,4}:(?:(?:25[0-5]|(?:2[0-4]|1{0,1}[0-9]){0,1}[0-9])\.){3,3}(?:25[0-5]|(?:2[0-4]|1{0,1}[0-9]){0,1}[0-9]))\b`)},
+	{ID: "url-host", Kind: "HOST", Replacement: "${1}[REDACTED]${3}",
+		Keywords: []string{"http"},
+		Pattern:  regexp.MustCompile(`(https?://)([\w.-]+)(\/?\S*)`)},
+	// Random tokens that match no other rule are told apart from
+	// identifiers and hex digests, whose entropy is at most 4 bits per
+	// character, by their entropy. Slashes are left out of generic tokens,
+	// where they would match paths.
+	{ID: "aws-secret-access-key", Secret: true, Kind: "KEY", Replacement: "[REDACTED]", SecretGroup: 2, Entropy: 4.2,
+		Pattern: regexp.MustCompile(`(^|[^A-Za-z0-9+/])([A-Za-z0-9+/]{40})($|[^A-Za-z0-9+/=])`)},
+	{ID: "high-entropy-string", Secret: true, Kind: "TOKEN", Replacement: "[REDACTED]", SecretGroup: 2, Entropy: 4.2,
+		Pattern: regexp.MustCompile(`(^|[^A-Za-z0-9+/_-])([A-Za-z0-9+_-]{32,}={0,2})`)},
+}
@@ -0,0 +1,508 @@
+// Package sanitize removes credentials and network identifiers from
+// infrastructure code before it leaves the machine.
+//
+// A Sanitizer applies an ordered list of Rules, the built-in DefaultRules or
+// the LevelRules of a stricter or looser Level, followed by any the user
+// adds, and reports every value it removed as a Redaction. Preview lists the
+// values without removing them. Teams add rules for their own secret
+// formats, or disable built-in ones, in a YAML file loaded with LoadConfig.
+package sanitize
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Rule is a single sanitization pattern. Every match of Pattern is replaced
+// with Replacement, which can refer to submatches as in
+// regexp.Regexp.Expand. If SecretGroup is set, only that submatch is
+// replaced. Secret rules match credentials. Kind names the placeholders of
+// the rule, such as IP in [REDACTED_IP_1].
+//
+// A rule with Keywords only runs on files containing one of them, in any
+// case, and a rule with Entropy only redacts values whose Shannon entropy,
+// in bits per character, is at least Entropy, which tells random tokens
+// apart from ordinary identifiers.
+//
+// HighConfidence rules match values that are certainly credentials, such as
+// private keys and cloud access keys, rather than values that only look like
+// them.
+//
+// A rule with Pseudonym replaces every value with the pseudonym it returns
+// instead of Replacement, and without placeholders.
+//
+// Rules that understand the structure of the content set Find instead of
+// Pattern. It returns the start and end offsets of every value to replace,
+// in order and without overlaps, and Replacement is used as is.
+type Rule struct {
+	ID          string
+	Pattern     *regexp.Regexp
+	Find        func(content string) [][]int
+	Replacement string
+	Secret      bool
+	Kind        string
+	SecretGroup int
+	Keywords    []string
+	Entropy     float64
+	// HighConfidence is only meaningful for secret rules.
+	HighConfidence bool
+	Pseudonym      func(value string) string
+}
+
+// kind returns the Kind of the rule, which defaults to SECRET for secret
+// rules and VALUE for others.
+func (r Rule) kind() string {
+	if r.Kind != "" {
+		return r.Kind
+	}
+	if r.Secret {
+		return "SECRET"
+	}
+	return "VALUE"
+}
+
+// Redaction records one value removed by a rule. The matched text itself is
+// deliberately not kept. File and Line locate the value when the content uses
+// "File: <path>" headers, and Section is set by callers that sanitize
+// several parts of a prompt separately. Placeholder is the placeholder that
+// replaced the value, if the Sanitizer uses placeholders. HighConfidence is
+// set if a HighConfidence rule removed it. Kind is the kind of the rule, and
+// Length and Replaced are the lengths of the value and of what replaced it.
+type Redaction struct {
+	Rule           string
+	Kind           string
+	Secret         bool
+	HighConfidence bool
+	Length         int
+	Replaced       int
+	Section        string
+	File           string
+	Line           int
+	Placeholder    string
+}
+
+// Sanitizer applies its rules in order, leaving values on its allowlist.
+type Sanitizer struct {
+	rules        []Rule
+	allowlist    *Allowlist
+	placeholders *Placeholders
+	format       PlaceholderFormat
+	formatted    *regexp.Regexp
+}
+
+// New returns a Sanitizer applying rules in order, with the
+// DefaultAllowlist.
+func New(rules ...Rule) *Sanitizer {
+	return &Sanitizer{rules: rules, allowlist: DefaultAllowlist()}
+}
+
+// Default returns a Sanitizer applying DefaultRules.
+func Default() *Sanitizer {
+	return New(DefaultRules()...)
+}
+
+// Rules returns the rules of s in the order they are applied.
+func (s *Sanitizer) Rules() []Rule {
+	return append([]Rule(nil), s.rules...)
+}
+
+// WithPlaceholders returns a Sanitizer with the rules of s that replaces
+// every redacted value with its numbered placeholder in p instead of
+// [REDACTED].
+func (s *Sanitizer) WithPlaceholders(p *Placeholders) *Sanitizer {
+	c := *s
+	c.placeholders = p
+	return &c
+}
+
+// WithAllowlist returns a Sanitizer with the rules of s that leaves the
+// values allowed by a, instead of the DefaultAllowlist.
+func (s *Sanitizer) WithAllowlist(a *Allowlist) *Sanitizer {
+	c := *s
+	c.allowlist = a
+	return &c
+}
+
+// WithPlaceholderFormat returns a Sanitizer with the rules of s that
+// replaces redacted values with placeholders in format f: numbered ones if
+// it has placeholders, and otherwise the same one for every value of a kind
+// instead of [REDACTED]. An empty format restores the default. Set the
+// placeholders first, since they decide whether f needs {n}.
+func (s *Sanitizer) WithPlaceholderFormat(f PlaceholderFormat) (*Sanitizer, error) {
+	c := *s
+	c.format, c.formatted = "", nil
+	if f == "" {
+		return &c, nil
+	}
+	if err := f.Validate(s.placeholders != nil); err != nil {
+		return nil, err
+	}
+	c.format, c.formatted = f, f.pattern()
+	return &c, nil
+}
+
+// Sanitize returns content with every rule applied.
+func (s *Sanitizer) Sanitize(content string) string {
+	sanitized, _ := s.Redact(content)
+	return sanitized
+}
+
+// Redact applies every rule in order and reports each value it removed, with
+// the file and line it was found on when content uses the "File: <path>"
+// headers of a scan.
+func (s *Sanitizer) Redact(content string) (string, []Redaction) {
+	sanitized, found, _ := s.redact(content)
+	return sanitized, found
+}
+
+// Match is a value that Preview found, together with the Redaction that
+// Redact would report for it.
+type Match struct {
+	Redaction
+	// Value is the text that would be replaced, such as the host of a URL.
+	Value string
+}
+
+// Preview returns the values Redact would remove from content, without
+// removing them or assigning placeholders, so that a dry run can show what
+// a level or rule would cost in fidelity. Like Redact, it applies the rules
+// in order, so a value is only reported by the first rule that matches it.
+// The values are secrets and must not leave the machine.
+func (s *Sanitizer) Preview(content string) []Match {
+	preview := &Sanitizer{rules: s.rules, allowlist: s.allowlist}
+	_, found, values := preview.redact(content)
+	var matches []Match
+	for i, r := range found {
+		matches = append(matches, Match{Redaction: r, Value: values[i]})
+	}
+	return matches
+}
+
+// Matches returns the values r alone would remove from content, with the
+// DefaultAllowlist, so that a rule can be tested without the rules applied
+// before it.
+func (r Rule) Matches(content string) []Match {
+	return New(r).Preview(content)
+}
+
+// Preview returns the values the Default sanitizer would remove from
+// content.
+func Preview(content string) []Match {
+	return Default().Preview(content)
+}
+
+// parallelSize is the size of content above which Redact sanitizes its files
+// concurrently.
+const parallelSize = 256 * 1024
+
+// redaction is the result of applying rules to a file.
+type redaction struct {
+	content string
+	found   []Redaction
+	values  []string
+}
+
+// redact implements Redact, also returning the value each redaction
+// removed. Rules up to the last Find rule can relate files to each other,
+// such as a variable declared sensitive in one file and set in another, so
+// they see all of content. The remaining rules apply to each file on its
+// own, concurrently for large content. Placeholders are numbered in the
+// order values are found, so they are only assigned serially.
+func (s *Sanitizer) redact(content string) (string, []Redaction, []string) {
+	whole := 0
+	for i, rule := range s.rules {
+		if rule.Find != nil {
+			whole = i + 1
+		}
+	}
+	result := s.apply(content, s.rules[:whole])
+	rules := s.rules[whole:]
+	if len(rules) == 0 {
+		return result.content, result.found, result.values
+	}
+
+	files := splitFiles(result.content)
+	results := make([]redaction, len(files))
+	if s.placeholders == nil && len(result.content) > parallelSize && len(files) > 1 {
+		var wg sync.WaitGroup
+		workers := make(chan struct{}, runtime.GOMAXPROCS(0))
+		for i := range files {
+			wg.Add(1)
+			workers <- struct{}{}
+			go func(i int) {
+				defer wg.Done()
+				results[i] = s.apply(files[i], rules)
+				<-workers
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range files {
+			results[i] = s.apply(files[i], rules)
+		}
+	}
+
+	var out strings.Builder
+	out.Grow(len(result.content))
+	for _, r := range results {
+		out.WriteString(r.content)
+		result.found = append(result.found, r.found...)
+		result.values = append(result.values, r.values...)
+	}
+	return out.String(), result.found, result.values
+}
+
+// apply applies rules in order to content.
+func (s *Sanitizer) apply(content string, rules []Rule) redaction {
+	var result redaction
+	// Keywords are looked up in the content before any rule applied, which
+	// only ever has more of them.
+	var lower string
+	for _, rule := range rules {
+		if len(rule.Keywords) > 0 {
+			if lower == "" {
+				lower = strings.ToLower(content)
+			}
+			if !hasKeyword(lower, rule.Keywords) {
+				continue
+			}
+		}
+		var matches [][]int
+		if rule.Find != nil {
+			matches = rule.Find(content)
+		} else {
+			matches = rule.Pattern.FindAllStringSubmatchIndex(content, -1)
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		// Placeholders of a custom format can look like values, such as
+		// <SECRET:credential-assignment>, so no part of them is redacted.
+		var placeholders [][]int
+		if s.formatted != nil {
+			placeholders = s.formatted.FindAllStringIndex(content, -1)
+		}
+		var out strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if g := rule.SecretGroup; g > 0 && 2*g+1 < len(m) && m[2*g] >= 0 {
+				start, end = m[2*g], m[2*g+1]
+			}
+			if rule.Entropy > 0 && Entropy(content[start:end]) < rule.Entropy {
+				continue
+			}
+			// Values redacted by an earlier rule, such as the literal of a
+			// sensitive attribute, are not redacted again.
+			if strings.Contains(content[start:end], "[REDACTED") || overlaps(placeholders, start, end) {
+				continue
+			}
+			replacement := []byte(rule.Replacement)
+			if rule.Pattern != nil {
+				replacement = rule.Pattern.ExpandString(nil, rule.Replacement, content, m)
+			}
+			value := redactedValue(content[start:end], replacement)
+			if s.allowlist.Allows(value) || hostnamePseudonym.MatchString(value) {
+				continue
+			}
+			// Only the rules assigning pseudonyms leave the pseudonyms of
+			// cloud IDs alone: a secret can take the same form.
+			if rule.Pseudonym != nil && cloudIDPseudonym.MatchString(value) {
+				continue
+			}
+			placeholder := ""
+			if rule.Pseudonym != nil {
+				// A value that is its own pseudonym, such as an ARN whose
+				// parts were all replaced before, is left alone.
+				if placeholder = rule.Pseudonym(value); placeholder == value {
+					continue
+				}
+				replacement = []byte(placeholder)
+			} else if s.placeholders != nil {
+				format := s.format
+				if format == "" {
+					format = DefaultPlaceholderFormat
+				}
+				replacement, placeholder = s.placeholders.replace(format, rule, value, replacement)
+			} else if s.format != "" {
+				replacement = swapMarker(replacement, s.format.key(rule.kind(), rule.ID))
+			}
+			file, line := locate(content, start)
+			result.found = append(result.found, Redaction{
+				Rule:           rule.ID,
+				Kind:           rule.kind(),
+				Secret:         rule.Secret,
+				HighConfidence: rule.Secret && rule.HighConfidence,
+				Length:         end - start,
+				Replaced:       len(replacement),
+				File:           file,
+				Line:           line,
+				Placeholder:    placeholder,
+			})
+			result.values = append(result.values, value)
+			out.WriteString(content[last:start])
+			out.Write(replacement)
+			last = end
+		}
+		out.WriteString(content[last:])
+		content = out.String()
+	}
+	result.content = content
+	return result
+}
+
+// overlaps reports whether any of spans overlaps start to end.
+func overlaps(spans [][]int, start, end int) bool {
+	for _, span := range spans {
+		if span[0] < end && start < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFiles splits content before each of its "File: <path>" header lines,
+// so that every part but the first starts with its header.
+func splitFiles(content string) []string {
+	var files []string
+	start := 0
+	for offset := 0; offset < len(content); {
+		if offset > start && strings.HasPrefix(content[offset:], "File: ") {
+			files = append(files, content[start:offset])
+			start = offset
+		}
+		next := strings.IndexByte(content[offset:], '\n')
+		if next < 0 {
+			break
+		}
+		offset += next + 1
+	}
+	return append(files, content[start:])
+}
+
+// redactedValue returns the part of match that the [REDACTED] marker in its
+// expansion replaces, such as the host of a URL, or all of match if the
+// expansion keeps none of it.
+func redactedValue(match string, expanded []byte) string {
+	i := strings.Index(string(expanded), redacted)
+	if i < 0 {
+		return match
+	}
+	prefix, suffix := string(expanded[:i]), string(expanded[i+len(redacted):])
+	if len(match) >= len(prefix)+len(suffix) && strings.HasPrefix(match, prefix) && strings.HasSuffix(match, suffix) {
+		return match[len(prefix) : len(match)-len(suffix)]
+	}
+	return match
+}
+
+// Entropy returns the Shannon entropy of s in bits per character.
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// hasKeyword reports whether lower, which is lower case, contains one of
+// keywords in any case.
+func hasKeyword(lower string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(lower, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// PatternRules turns regular expressions into secret rules replacing every
+// match with [REDACTED], with IDs prefix-1, prefix-2, and so on.
+func PatternRules(prefix string, patterns []string) ([]Rule, error) {
+	var rules []Rule
+	for i, expr := range patterns {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sanitizer pattern %q: %v", expr, err)
+		}
+		rules = append(rules, Rule{
+			ID:          fmt.Sprintf("%s-%d", prefix, i+1),
+			Pattern:     pattern,
+			Replacement: redacted,
+			Secret:      true,
+		})
+	}
+	return rules, nil
+}
+
+// locate returns the file header preceding offset in content and the line
+// number of offset within that file (or within content if there is none).
+func locate(content string, offset int) (string, int) {
+	before := content[:offset]
+	header := strings.LastIndex(before, "File: ")
+	for header > 0 && before[header-1] != '\n' {
+		header = strings.LastIndex(before[:header], "File: ")
+	}
+	if header < 0 {
+		return "", strings.Count(before, "\n") + 1
+	}
+	headerEnd := strings.IndexByte(content[header:], '\n')
+	if headerEnd < 0 || header+headerEnd >= offset {
+		return "", strings.Count(before, "\n") + 1
+	}
+	file := content[header+len("File: ") : header+headerEnd]
+	return file, strings.Count(content[header+headerEnd+1:offset], "\n") + 1
+}
+
+// Explain writes one line per redaction with the rule that fired, where it
+// fired, and how long the match was, without the matched content.
+func Explain(w io.Writer, redactions []Redaction) {
+	if len(redactions) == 0 {
+		fmt.Fprintln(w, "No values were redacted.")
+		return
+	}
+	fmt.Fprintf(w, "%d values were redacted:\n", len(redactions))
+	for _, r := range redactions {
+		location := r.Section
+		if r.File != "" {
+			location = r.File
+		}
+		fmt.Fprintf(w, "  %-22s %s:%d (%d characters)\n", r.Rule, location, r.Line, r.Length)
+	}
+}
+
+// SecretsFound reports whether any of the redactions removed a credential.
+func SecretsFound(redactions []Redaction) bool {
+	for _, r := range redactions {
+		if r.Secret {
+			return true
+		}
+	}
+	return false
+}
+
+// HighConfidenceSecrets returns the redactions that removed values certainly
+// being credentials, such as private keys and cloud access keys.
+func HighConfidenceSecrets(redactions []Redaction) []Redaction {
+	var out []Redaction
+	for _, r := range redactions {
+		if r.HighConfidence {
+			out = append(out, r)
+		}
+	}
+	return out
+}
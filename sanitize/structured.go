@@ -0,0 +1,390 @@
+package sanitize
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The structured rules redact values by what the language says about them
+// rather than by their form: the defaults and tfvars values of Terraform
+// variables declared sensitive, literal values of Terraform attributes named
+// like credentials, including multi-line heredocs, the arguments of Ansible
+// tasks marked no_log, Ansible Vault values, and the data of Kubernetes
+// Secrets. They rely on the
+// "File: <path>" headers of a scan to know the language of each file, so
+// they find nothing in content without them.
+var structuredRules = []Rule{
+	{ID: "terraform-sensitive-variable", Secret: true, Kind: "SECRET", Replacement: "[REDACTED]", Find: findSensitiveVariables,
+		Keywords: []string{"sensitive"}},
+	{ID: "terraform-sensitive-attribute", Secret: true, Kind: "SECRET", Replacement: "[REDACTED]", Find: findSensitiveAttributes,
+		Keywords: []string{"password", "passwd", "secret", "token", "key", "connection_string", "credentials"}},
+	{ID: "ansible-no-log", Secret: true, Kind: "SECRET", Replacement: "[REDACTED]", Find: findNoLogArguments,
+		Keywords: []string{"no_log"}},
+	{ID: "ansible-vault", Secret: true, Kind: "SECRET", Replacement: "[REDACTED]", Find: findVaultValues,
+		Keywords: []string{"!vault"}},
+	{ID: "kubernetes-secret", Secret: true, Kind: "SECRET", Replacement: "[REDACTED]", Find: findKubernetesSecrets,
+		Keywords: []string{"secret"}},
+}
+
+// sensitiveAttribute matches the names of Terraform attributes that hold
+// credentials.
+var sensitiveAttribute = regexp.MustCompile(`(?i)(password|passwd|secret|secret_string|token|private_key|api_key|access_key|secret_key|client_secret|connection_string|credentials)$`)
+
+// segment is the content of one file in content with "File: <path>"
+// headers.
+type segment struct {
+	name  string
+	start int
+	end   int
+}
+
+// fileSegments splits content at its "File: <path>" header lines.
+func fileSegments(content string) []segment {
+	var segments []segment
+	offset := 0
+	for offset < len(content) {
+		lineEnd := strings.IndexByte(content[offset:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(content) - offset
+		}
+		line := content[offset : offset+lineEnd]
+		if strings.HasPrefix(line, "File: ") {
+			if len(segments) > 0 {
+				segments[len(segments)-1].end = offset
+			}
+			segments = append(segments, segment{name: strings.TrimPrefix(line, "File: "), start: offset + lineEnd + 1})
+		}
+		offset += lineEnd + 1
+	}
+	if len(segments) > 0 {
+		segments[len(segments)-1].end = len(content)
+	}
+	for i := range segments {
+		if segments[i].start > segments[i].end {
+			segments[i].start = segments[i].end
+		}
+	}
+	return segments
+}
+
+func isTerraform(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".tf" || ext == ".tfvars"
+}
+
+func isYAML(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".yml" || ext == ".yaml"
+}
+
+// findSensitiveVariables finds the default values of Terraform variables
+// declared sensitive, and their values in tfvars files.
+func findSensitiveVariables(content string) [][]int {
+	segments := fileSegments(content)
+	sensitive := map[string]bool{}
+	for _, s := range segments {
+		if filepath.Ext(s.name) != ".tf" {
+			continue
+		}
+		src := content[s.start:s.end]
+		attributes := map[string]map[string]string{}
+		scanHCL(src, func(path []string, name string, value hclValue) {
+			if len(path) == 2 && path[0] == "variable" {
+				if attributes[path[1]] == nil {
+					attributes[path[1]] = map[string]string{}
+				}
+				attributes[path[1]][name] = src[value.start:value.end]
+			}
+		})
+		for variable, attrs := range attributes {
+			if strings.TrimSpace(attrs["sensitive"]) == "true" {
+				sensitive[variable] = true
+			}
+		}
+	}
+	if len(sensitive) == 0 {
+		return nil
+	}
+
+	var spans [][]int
+	for _, s := range segments {
+		if !isTerraform(s.name) {
+			continue
+		}
+		tfvars := filepath.Ext(s.name) == ".tfvars"
+		scanHCL(content[s.start:s.end], func(path []string, name string, value hclValue) {
+			var matches bool
+			if tfvars {
+				matches = len(path) == 0 && sensitive[name]
+			} else {
+				matches = len(path) == 2 && path[0] == "variable" && sensitive[path[1]] && name == "default"
+			}
+			if matches && value.kind != hclNull {
+				spans = append(spans, []int{s.start + value.innerStart, s.start + value.innerEnd})
+			}
+		})
+	}
+	return sortSpans(spans)
+}
+
+// findSensitiveAttributes finds the literal values of Terraform attributes
+// named like credentials. References and other expressions are left to the
+// pattern rules, since they do not contain the credential.
+func findSensitiveAttributes(content string) [][]int {
+	var spans [][]int
+	for _, s := range fileSegments(content) {
+		if !isTerraform(s.name) {
+			continue
+		}
+		scanHCL(content[s.start:s.end], func(path []string, name string, value hclValue) {
+			if !sensitiveAttribute.MatchString(name) {
+				return
+			}
+			if value.kind == hclString || value.kind == hclHeredoc || value.kind == hclNumber {
+				spans = append(spans, []int{s.start + value.innerStart, s.start + value.innerEnd})
+			}
+		})
+	}
+	return sortSpans(spans)
+}
+
+// findNoLogArguments finds the values of Ansible tasks marked no_log, other
+// than their names and task keywords.
+func findNoLogArguments(content string) [][]int {
+	var spans [][]int
+	forEachYAML(content, "no_log", func(src string, offset int, doc *yaml.Node) {
+		walkYAML(doc, func(n *yaml.Node) {
+			if n.Kind != yaml.MappingNode || !noLog(n) {
+				return
+			}
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if ansibleTaskKeywords[n.Content[i].Value] {
+					continue
+				}
+				walkYAMLValues(n.Content[i+1], func(v *yaml.Node) {
+					if start, end, ok := scalarSpan(src, v); ok {
+						spans = append(spans, []int{offset + start, offset + end})
+					}
+				})
+			}
+		})
+	})
+	return sortSpans(spans)
+}
+
+// findVaultValues finds the values tagged !vault, which hold Ansible Vault
+// ciphertext.
+func findVaultValues(content string) [][]int {
+	var spans [][]int
+	forEachYAML(content, "!vault", func(src string, offset int, doc *yaml.Node) {
+		walkYAML(doc, func(n *yaml.Node) {
+			if n.Kind == yaml.ScalarNode && n.Tag == "!vault" {
+				if start, end, ok := scalarSpan(src, n); ok {
+					spans = append(spans, []int{offset + start, offset + end})
+				}
+			}
+		})
+	})
+	return sortSpans(spans)
+}
+
+// findKubernetesSecrets finds the values under data and stringData of
+// Kubernetes Secrets, which are credentials, base64-encoded or not.
+func findKubernetesSecrets(content string) [][]int {
+	var spans [][]int
+	forEachYAML(content, "Secret", func(src string, offset int, doc *yaml.Node) {
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			return
+		}
+		manifest := doc.Content[0]
+		if mappingValue(manifest, "kind") != "Secret" {
+			return
+		}
+		for i := 0; i+1 < len(manifest.Content); i += 2 {
+			if key := manifest.Content[i].Value; key != "data" && key != "stringData" {
+				continue
+			}
+			walkYAMLValues(manifest.Content[i+1], func(v *yaml.Node) {
+				if start, end, ok := scalarSpan(src, v); ok {
+					spans = append(spans, []int{offset + start, offset + end})
+				}
+			})
+		}
+	})
+	return sortSpans(spans)
+}
+
+// mappingValue returns the scalar value of key in the mapping n.
+func mappingValue(n *yaml.Node, key string) string {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key && n.Content[i+1].Kind == yaml.ScalarNode {
+			return n.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// ansibleTaskKeywords are the task keys whose values say nothing secret
+// about a task, even if it is marked no_log.
+var ansibleTaskKeywords = map[string]bool{
+	"name": true, "no_log": true, "when": true, "tags": true, "become": true,
+	"become_user": true, "register": true, "notify": true, "loop": true,
+	"with_items": true, "changed_when": true, "failed_when": true,
+	"ignore_errors": true, "delegate_to": true, "run_once": true,
+}
+
+func noLog(n *yaml.Node) bool {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == "no_log" {
+			switch strings.ToLower(n.Content[i+1].Value) {
+			case "true", "yes", "on":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// forEachYAML calls fn with every document of every YAML file in content
+// that contains keyword and parses, together with the file's source and
+// offset in content.
+func forEachYAML(content string, keyword string, fn func(src string, offset int, doc *yaml.Node)) {
+	for _, s := range fileSegments(content) {
+		if !isYAML(s.name) {
+			continue
+		}
+		src := content[s.start:s.end]
+		if !strings.Contains(src, keyword) {
+			continue
+		}
+		decoder := yaml.NewDecoder(strings.NewReader(src))
+		for {
+			var doc yaml.Node
+			if err := decoder.Decode(&doc); err != nil {
+				break
+			}
+			fn(src, s.start, &doc)
+		}
+	}
+}
+
+func walkYAML(n *yaml.Node, fn func(*yaml.Node)) {
+	fn(n)
+	for _, child := range n.Content {
+		walkYAML(child, fn)
+	}
+}
+
+// walkYAMLValues calls fn with every scalar in n that is not a mapping key.
+func walkYAMLValues(n *yaml.Node, fn func(*yaml.Node)) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		fn(n)
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			walkYAMLValues(n.Content[i], fn)
+		}
+	default:
+		for _, child := range n.Content {
+			walkYAMLValues(child, fn)
+		}
+	}
+}
+
+// scalarSpan returns the span of the value of a scalar node in src, without
+// quotes or block indicators, and for blocks without the indentation of
+// their first line, so that the YAML stays valid once it is replaced.
+func scalarSpan(src string, n *yaml.Node) (int, int, bool) {
+	lines := strings.SplitAfter(src, "\n")
+	if n.Line < 1 || n.Line > len(lines) {
+		return 0, 0, false
+	}
+	lineStart := 0
+	for _, line := range lines[:n.Line-1] {
+		lineStart += len(line)
+	}
+	start := lineStart + n.Column - 1
+	if start >= len(src) {
+		return 0, 0, false
+	}
+	if strings.HasPrefix(src[start:], "!vault") {
+		start += len("!vault")
+		for start < len(src) && src[start] == ' ' {
+			start++
+		}
+	}
+
+	switch n.Style &^ yaml.TaggedStyle {
+	case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+		quote := src[start]
+		for i := start + 1; i < len(src); i++ {
+			switch {
+			case quote == '"' && src[i] == '\\':
+				i++
+			case src[i] == quote && quote == '\'' && i+1 < len(src) && src[i+1] == '\'':
+				i++
+			case src[i] == quote:
+				return start + 1, i, true
+			}
+		}
+		return 0, 0, false
+	case yaml.LiteralStyle, yaml.FoldedStyle:
+		// The block is every following line indented more than the line of
+		// its indicator, or blank.
+		indicatorLine := lines[n.Line-1]
+		indent := len(indicatorLine) - len(strings.TrimLeft(indicatorLine, " "))
+		blockStart := lineStart + len(indicatorLine)
+		end := blockStart
+		for _, line := range lines[n.Line:] {
+			trimmed := strings.TrimSpace(line)
+			if trimmed != "" && len(line)-len(strings.TrimLeft(line, " ")) <= indent {
+				break
+			}
+			end += len(line)
+		}
+		for blockStart < end && (src[blockStart] == ' ' || src[blockStart] == '\n') {
+			blockStart++
+		}
+		for end > blockStart && (src[end-1] == '\n' || src[end-1] == ' ') {
+			end--
+		}
+		if end <= blockStart {
+			return 0, 0, false
+		}
+		return blockStart, end, true
+	default:
+		lineEnd := strings.IndexByte(src[start:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(src) - start
+		}
+		value := src[start : start+lineEnd]
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		value = strings.TrimRight(value, " \t\r")
+		if value == "" {
+			return 0, 0, false
+		}
+		return start, start + len(value), true
+	}
+}
+
+// sortSpans orders spans and drops those overlapping an earlier one, as the
+// matches of a rule must be.
+func sortSpans(spans [][]int) [][]int {
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	var out [][]int
+	last := -1
+	for _, span := range spans {
+		if span[0] < last || span[0] >= span[1] {
+			continue
+		}
+		out = append(out, span)
+		last = span[1]
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package sanitize
+
+import (
+	"testing"
+)
+
+func TestStructuredRules(t *testing.T) {
+	content := "File: terraform/variables.tf\n" +
+		"variable \"db_password\" {\n" +
+		"  type      = string\n" +
+		"  sensitive = true\n" +
+		"  default   = \"correct-horse\"\n" +
+		"}\n" +
+		"variable \"db_user\" {\n" +
+		"  default = \"admin\"\n" +
+		"}\n\n" +
+		"File: terraform/prod.tfvars\n" +
+		"db_password = \"battery-staple\"\n" +
+		"db_user     = \"admin\"\n\n" +
+		"File: terraform/main.tf\n" +
+		"resource \"aws_db_instance\" \"main\" {\n" +
+		"  username = var.db_user\n" +
+		"  password = var.db_password\n" +
+		"  master_password = <<EOT\n" +
+		"first line\n" +
+		"second line\n" +
+		"EOT\n" +
+		"  port = 5432\n" +
+		"}\n\n" +
+		"File: ansible/site.yml\n" +
+		"- name: Create the database user\n" +
+		"  postgresql_user:\n" +
+		"    name: app\n" +
+		"    password: \"s3cret\"\n" +
+		"  no_log: true\n" +
+		"- name: Configure the API\n" +
+		"  template:\n" +
+		"    src: api.j2\n" +
+		"  vars:\n" +
+		"    api_key: !vault |\n" +
+		"      $ANSIBLE_VAULT;1.1;AES256\n" +
+		"      62313365396662343061393464336163383764373764613633653634306231386433626436623361\n" +
+		"\n"
+
+	sanitized, found := New(structuredRules...).Redact(content)
+	expected := "File: terraform/variables.tf\n" +
+		"variable \"db_password\" {\n" +
+		"  type      = string\n" +
+		"  sensitive = true\n" +
+		"  default   = \"[REDACTED]\"\n" +
+		"}\n" +
+		"variable \"db_user\" {\n" +
+		"  default = \"admin\"\n" +
+		"}\n\n" +
+		"File: terraform/prod.tfvars\n" +
+		"db_password = \"[REDACTED]\"\n" +
+		"db_user     = \"admin\"\n\n" +
+		"File: terraform/main.tf\n" +
+		"resource \"aws_db_instance\" \"main\" {\n" +
+		"  username = var.db_user\n" +
+		"  password = var.db_password\n" +
+		"  master_password = <<EOT\n" +
+		"[REDACTED]\n" +
+		"EOT\n" +
+		"  port = 5432\n" +
+		"}\n\n" +
+		"File: ansible/site.yml\n" +
+		"- name: Create the database user\n" +
+		"  postgresql_user:\n" +
+		"    name: [REDACTED]\n" +
+		"    password: \"[REDACTED]\"\n" +
+		"  no_log: true\n" +
+		"- name: Configure the API\n" +
+		"  template:\n" +
+		"    src: api.j2\n" +
+		"  vars:\n" +
+		"    api_key: !vault |\n      [REDACTED]\n" +
+		"\n"
+	if sanitized != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, sanitized)
+	}
+
+	rules := map[string]int{}
+	for _, r := range found {
+		rules[r.Rule]++
+	}
+	if rules["terraform-sensitive-variable"] != 2 || rules["terraform-sensitive-attribute"] != 1 || rules["ansible-no-log"] != 2 || rules["ansible-vault"] != 1 {
+		t.Errorf("Expected redactions by every structured rule, got %v", rules)
+	}
+
+	// Without file headers the language is unknown.
+	if sanitized, found := New(structuredRules...).Redact("master_password = \"x\"\n"); len(found) != 0 {
+		t.Errorf("Expected no structured redactions without headers, got %q", sanitized)
+	}
+}
+
+func TestStructuredBeforePatterns(t *testing.T) {
+	content := "File: main.tf\npassword = \"hunter2\"\ntoken = var.github_token_arn\n"
+	sanitized, found := Default().Redact(content)
+	expected := "File: main.tf\npassword = \"[REDACTED]\"\n[REDACTED]\n"
+	if sanitized != expected {
+		t.Errorf("Expected %q, got %q", expected, sanitized)
+	}
+	if len(found) != 2 || found[0].Rule != "terraform-sensitive-attribute" || found[1].Rule != "credential-assignment" {
+		t.Errorf("Expected the literal to be redacted once by its attribute, got %+v", found)
+	}
+}